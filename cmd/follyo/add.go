@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+// addTypes are the kinds of record the add wizard can enter
+var addTypes = []string{"buy", "sell", "stake", "loan"}
+
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a purchase, sale, stake, or loan step by step",
+	Long: `Add a record by answering prompts, for guided entry instead of
+'follyo buy add' and the other add commands:
+
+  - type: buy, sell, stake, or loan
+  - coin: a ticker from the portfolio or the CoinGecko mappings is
+    completed from its first letters, e.g. "bt" for BTC
+  - amount
+  - price per coin in USD, or the total (for buys and sales)
+  - platform, one of the registered platforms listed or a new one,
+    defaulting to "default_platform" from the config
+  - date, defaulting to today, e.g. 2024-01-05, "yesterday", or "jan 5"
+
+Press Enter to accept the default shown in brackets. The record is
+shown for confirmation before it is added, unless --yes is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if nonInteractive {
+			return usageErrorf("follyo add needs a prompt; use 'follyo buy add' or another add command instead")
+		}
+		err := runAddWizard(&wizard{in: bufio.NewReader(osStdin)})
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(osStdout)
+			return usageErrorf("input ended before the record was complete")
+		}
+		return err
+	},
+}
+
+// wizard asks questions on stdout and reads answers from a shared reader,
+// so that answers typed ahead are not lost between questions
+type wizard struct {
+	in *bufio.Reader
+}
+
+// ask prints a question, with def in brackets when set, and returns the
+// trimmed answer or def if the answer is empty
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(osStdout, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(osStdout, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askUntil asks a question until parse accepts the answer, printing why
+// each rejected answer was rejected
+func askUntil[T any](w *wizard, question, def string, parse func(string) (T, error)) (T, error) {
+	for {
+		answer, err := w.ask(question, def)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		value, err := parse(answer)
+		if err == nil {
+			return value, nil
+		}
+		fmt.Fprintf(osStdout, "  %v\n", err)
+	}
+}
+
+// runAddWizard asks for a record's details and adds it once confirmed
+func runAddWizard(w *wizard) error {
+	kind, err := askUntil(w, "Type ("+strings.Join(addTypes, ", ")+")", "buy", func(s string) (string, error) {
+		s = strings.ToLower(s)
+		if !slices.Contains(addTypes, s) {
+			return "", fmt.Errorf("unknown type %q", s)
+		}
+		return s, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	known, err := knownCoins()
+	if err != nil {
+		return err
+	}
+	coin, err := askUntil(w, "Coin", "", func(s string) (string, error) {
+		return completeCoin(s, known)
+	})
+	if err != nil {
+		return err
+	}
+
+	amount, err := askUntil(w, "Amount", "", parsePositive)
+	if err != nil {
+		return err
+	}
+
+	var price decimal.Decimal
+	if kind == "buy" || kind == "sell" {
+		answer, err := askUntil(w, "Price per coin in USD (or =TOTAL)", "", func(s string) (string, error) {
+			_, err := parsePositive(strings.TrimPrefix(s, "="))
+			return s, err
+		})
+		if err != nil {
+			return err
+		}
+		if total, ok := strings.CutPrefix(answer, "="); ok {
+			value, _ := parsePositive(total)
+			price = perUnit(value, amount)
+		} else {
+			price, _ = parsePositive(answer)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	registry, err := platformRegistry()
+	if err != nil {
+		return err
+	}
+	question := "Platform"
+	if len(registry) > 0 {
+		question += " (" + strings.Join(registry, ", ") + ")"
+	}
+	platform, err := askUntil(w, question, cfg.GetDefaultPlatform(), func(s string) (string, error) {
+		if s == "" && (kind == "stake" || kind == "loan") {
+			return "", fmt.Errorf("a %s needs a platform", kind)
+		}
+		platform, err := resolvePlatform(s)
+		if err == nil && platform != s {
+			fmt.Fprintf(osStdout, "  %s\n", platform)
+		}
+		return platform, err
+	})
+	if err != nil {
+		return err
+	}
+
+	date, err := askUntil(w, "Date", models.Today().String(), func(s string) (string, error) {
+		d, err := parseDate(s, "date")
+		return d.String(), err
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("%s %s %s", kind, formatAmount(amount.InexactFloat64()), coin)
+	if price.IsPositive() {
+		summary += " @ " + formatUSD(price.InexactFloat64())
+	}
+	if platform != "" {
+		summary += " on " + platform
+	}
+	ok := assumeYes
+	if !ok {
+		ok, err = askUntil(w, fmt.Sprintf("Add %s, %s? [y/N]", summary, date), "", func(s string) (bool, error) {
+			return strings.EqualFold(s, "y") || strings.EqualFold(s, "yes"), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !ok {
+		fmt.Fprintln(osStdout, "Nothing added.")
+		return nil
+	}
+
+	switch kind {
+	case "buy":
+		holding, err := p.AddHoldingWithFee(coin, amount, price, decimal.Zero, platform, "", date)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Bought %s %s @ %s (ID: %s)\n", formatAmount(holding.Amount.InexactFloat64()), holding.Coin, formatUSD(holding.PurchasePriceUSD.InexactFloat64()), holding.ID)
+	case "sell":
+		sale, err := p.AddSale(coin, amount, price, platform, "", date)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Sold %s %s @ %s (ID: %s)\n", formatAmount(sale.Amount.InexactFloat64()), sale.Coin, formatUSD(sale.SellPriceUSD.InexactFloat64()), sale.ID)
+	case "stake":
+		stake, err := p.AddStake(coin, amount, platform, nil, "", date)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Staked %s %s on %s (ID: %s)\n", formatAmount(stake.Amount.InexactFloat64()), stake.Coin, stake.Platform, stake.ID)
+	case "loan":
+		loan, err := p.AddLoan(coin, amount, platform, nil, "", date)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Added loan: %s %s on %s (ID: %s)\n", formatAmount(loan.Amount.InexactFloat64()), loan.Coin, loan.Platform, loan.ID)
+	}
+	return nil
+}
+
+// parsePositive parses an amount or price, which must be above zero
+func parsePositive(s string) (decimal.Decimal, error) {
+	value, err := decimal.NewFromString(strings.ReplaceAll(s, ",", ""))
+	if err != nil || !value.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%q is not a number above zero", s)
+	}
+	return value, nil
+}
+
+// knownCoins returns the sorted coins in the portfolio and in the default
+// and custom CoinGecko mappings
+func knownCoins() ([]string, error) {
+	coins, err := p.GetCoins()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	for ticker := range prices.GetDefaultMappings() {
+		coins = append(coins, ticker)
+	}
+	for ticker := range cfg.GetAllTickerMappings() {
+		coins = append(coins, ticker)
+	}
+	for i, coin := range coins {
+		coins[i] = strings.ToUpper(coin)
+	}
+	slices.Sort(coins)
+	return slices.Compact(coins), nil
+}
+
+// completeCoin returns the coin entered: a known coin it names exactly or
+// is the start of, or a new coin. Input starting several known coins is
+// ambiguous, and the matches are listed in the error.
+func completeCoin(input string, known []string) (string, error) {
+	input = strings.ToUpper(input)
+	if input == "" {
+		return "", errors.New("enter a coin, e.g. BTC")
+	}
+	if slices.Contains(known, input) {
+		return input, nil
+	}
+	var matches []string
+	for _, coin := range known {
+		if strings.HasPrefix(coin, input) {
+			matches = append(matches, coin)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return input, nil
+	case 1:
+		fmt.Fprintf(osStdout, "  %s\n", matches[0])
+		return matches[0], nil
+	}
+	if len(matches) > 10 {
+		matches = append(matches[:10], "...")
+	}
+	return "", fmt.Errorf("%s could be %s", input, strings.Join(matches, ", "))
+}
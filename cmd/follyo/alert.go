@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+var alertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Manage price alerts",
+	Long: `Get notified once when a coin reaches a price. Alerts are checked
+whenever a live snapshot is saved (including by the daemon) or with
+'follyo alert check', and removed once they fire. Alerts are stored
+in config.json; see 'follyo notify' for where they are sent.`,
+}
+
+var alertAddCmd = &cobra.Command{
+	Use:   "add TICKER",
+	Short: "Add a price alert",
+	Example: `  follyo alert add BTC --above 100000
+  follyo alert add ETH --below 2000`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		above, _ := cmd.Flags().GetFloat64("above")
+		below, _ := cmd.Flags().GetFloat64("below")
+		if (above > 0) == (below > 0) || above < 0 || below < 0 {
+			return usageErrorf("set either --above or --below to a positive price")
+		}
+		alert := config.PriceAlert{Coin: strings.ToUpper(args[0]), Above: above, Below: below}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.AddPriceAlert(alert); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Added alert: %s\n", describeAlert(alert))
+		return nil
+	},
+}
+
+var alertListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List price alerts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		alerts := cfg.GetPriceAlerts()
+		if len(alerts) == 0 {
+			fmt.Fprintln(osStdout, "No price alerts. Add one with 'follyo alert add TICKER --above PRICE'.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tAlert")
+		for i, alert := range alerts {
+			fmt.Fprintf(w, "%d\t%s\n", i+1, describeAlert(alert))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var alertRemoveCmd = &cobra.Command{
+	Use:   "remove NUMBER",
+	Short: "Remove a price alert by its number in 'alert list'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		alerts := cfg.GetPriceAlerts()
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return usageErrorf("invalid alert number %s", args[0])
+		}
+		if n < 1 || n > len(alerts) {
+			return notFoundErrorf("alert %d not found", n)
+		}
+		if _, err := cfg.RemovePriceAlert(alerts[n-1]); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Removed alert: %s\n", describeAlert(alerts[n-1]))
+		return nil
+	},
+}
+
+var alertCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check price alerts against live prices",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(cfg.GetPriceAlerts()) == 0 {
+			fmt.Fprintln(osStdout, "No price alerts to check")
+			return nil
+		}
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		fired, err := checkPriceAlerts(cfg, newNotifier(cfg.GetNotifications()), ps, nil)
+		if err != nil {
+			return ioError(err)
+		}
+		if fired == 0 {
+			fmt.Fprintln(osStdout, "No price alerts reached")
+		}
+		return nil
+	},
+}
+
+// describeAlert formats an alert, e.g. "BTC above $100,000.00"
+func describeAlert(alert config.PriceAlert) string {
+	if alert.Above > 0 {
+		return fmt.Sprintf("%s above %s", alert.Coin, formatUSD(alert.Above))
+	}
+	return fmt.Sprintf("%s below %s", alert.Coin, formatUSD(alert.Below))
+}
+
+// checkPriceAlerts fires the price alerts reached at livePrices, fetching
+// prices missing from it, and returns how many fired. Fired alerts are
+// printed, sent, and removed; an alert whose notification fails is kept so
+// it fires again next time.
+func checkPriceAlerts(cfg *config.ConfigStore, n notifier, ps *prices.PriceService, livePrices map[string]float64) (int, error) {
+	alerts := cfg.GetPriceAlerts()
+	var missing []string
+	for _, alert := range alerts {
+		if _, ok := livePrices[alert.Coin]; !ok {
+			missing = append(missing, alert.Coin)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := ps.GetPrices(missing)
+		if err != nil {
+			return 0, fmt.Errorf("could not fetch prices: %w", err)
+		}
+		merged := make(map[string]float64)
+		for coin, price := range livePrices {
+			merged[coin] = price
+		}
+		for coin, price := range fetched {
+			merged[coin] = price
+		}
+		livePrices = merged
+	}
+
+	fired := 0
+	for _, alert := range alerts {
+		price, ok := livePrices[alert.Coin]
+		if !ok || !alertReached(alert, price) {
+			continue
+		}
+		fired++
+		fmt.Fprintf(osStdout, "Price alert reached: %s (now %s)\n", describeAlert(alert), formatUSD(price))
+		if err := n.send("Price alert: "+alert.Coin, notifyData{Event: eventPriceAlert, Alert: alert, Price: price}); err != nil {
+			fmt.Fprintf(osStderr, "Warning: Could not send price alert: %v\n", err)
+			continue
+		}
+		if _, err := cfg.RemovePriceAlert(alert); err != nil {
+			return fired, err
+		}
+	}
+	return fired, nil
+}
+
+// alertReached reports whether price meets the alert's threshold
+func alertReached(alert config.PriceAlert, price float64) bool {
+	if alert.Above > 0 {
+		return price >= alert.Above
+	}
+	return price > 0 && price <= alert.Below
+}
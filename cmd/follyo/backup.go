@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/state"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [FILE]",
+	Short: "Back up the portfolio to a single archive",
+	Long: `Back up the portfolio records, snapshots, and configuration to a single
+self-contained archive, in the same format as 'follyo state export'.
+
+Without FILE, the archive is written to the current directory as
+follyo-backup-YYYYMMDD-HHMMSS.tar.gz, so repeated backups are kept as
+versions. With SQLite storage, records and snapshots are written to the
+archive as JSON, so it can be restored with either storage backend.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive := time.Now().Format("follyo-backup-20060102-150405.tar.gz")
+		if len(args) > 0 {
+			archive = args[0]
+		}
+
+		tmpDir, err := os.MkdirTemp("", "follyo-backup")
+		if err != nil {
+			return ioError(err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		entries, err := backupEntries(tmpDir)
+		if err != nil {
+			return err
+		}
+		manifest, err := state.Export(archive, entries)
+		if err != nil {
+			return ioError(err)
+		}
+
+		var names []string
+		for _, f := range manifest.Files {
+			names = append(names, f.Name)
+		}
+		fmt.Fprintf(osStdout, "Backed up %s to %s\n", strings.Join(names, ", "), archive)
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore FILE",
+	Short: "Restore the portfolio from a backup archive",
+	Long: `Restore the portfolio records, snapshots, and configuration from an
+archive created by 'follyo backup' or 'follyo state export'.
+
+Every file is verified against the archive's checksums before anything is
+written. Replaced files are kept with a .bak suffix, and exchange API keys
+are kept from the current configuration. With SQLite storage,
+the database is moved to portfolio.db.bak and rebuilt from the restored
+records the next time follyo runs.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Verify before asking, so a bad archive fails without a prompt
+		if _, _, err := state.Read(args[0]); err != nil {
+			return ioError(err)
+		}
+		ok, err := confirm(fmt.Sprintf("Replace the portfolio in %s with %s?", filepath.Dir(dataPath), args[0]))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(osStdout, "Nothing restored")
+			return nil
+		}
+
+		manifest, restored, err := importArchive(args[0])
+		if err != nil {
+			return err
+		}
+		if len(restored) == 0 {
+			fmt.Fprintln(osStdout, "Archive contained no known files; nothing restored.")
+			return nil
+		}
+		fmt.Fprintf(osStdout, "Restored %s (backed up %s)\n",
+			strings.Join(restored, ", "), manifest.CreatedAt.Local().Format("2006-01-02 15:04"))
+		return nil
+	},
+}
+
+// backupEntries returns the files to back up. With SQLite storage, the
+// records and snapshots are first written as JSON files into tmpDir. The
+// config is copied into tmpDir without exchange API keys.
+func backupEntries(tmpDir string) ([]state.Entry, error) {
+	configFile, err := exportConfig(tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []state.Entry{
+		{Name: "portfolio.json", Path: dataPath, SchemaVersion: storage.SchemaVersion},
+		{Name: "snapshots.json", Path: snapshotsPath(), SchemaVersion: storage.SnapshotSchemaVersion},
+		{Name: "config.json", Path: configFile, SchemaVersion: config.SchemaVersion},
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GetStorage() != "sqlite" {
+		return entries, nil
+	}
+
+	records, err := storage.NewSQLite(sqlitePath())
+	if err != nil {
+		return nil, ioError(err)
+	}
+	defer records.Close()
+	portfolioPath := filepath.Join(tmpDir, "portfolio.json")
+	dst, err := storage.New(portfolioPath)
+	if err != nil {
+		return nil, ioError(err)
+	}
+	if err := storage.Copy(dst, records); err != nil {
+		return nil, ioError(err)
+	}
+
+	snapPath := filepath.Join(tmpDir, "snapshots.json")
+	if _, err := exportSnapshots(snapPath); err != nil {
+		return nil, err
+	}
+	entries[0].Path = portfolioPath
+	entries[1].Path = snapPath
+	return entries, nil
+}
+
+// exportConfig copies the config file into tmpDir without the exchange API
+// keys, so archives can be shared without leaking them. It returns the path
+// of the copy, which is missing if there is no config file.
+func exportConfig(tmpDir string) (string, error) {
+	path := filepath.Join(tmpDir, "config.json")
+	data, err := os.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return path, nil
+	}
+	if err != nil {
+		return "", ioError(err)
+	}
+	data, err = config.StripCredentials(data)
+	if err != nil {
+		return "", ioError(fmt.Errorf("reading %s: %w", configPath(), err))
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", ioError(err)
+	}
+	return path, nil
+}
+
+// importArchive restores the files of a state archive while holding the
+// data locks. Exchange API keys, which archives don't carry, are kept from
+// the current config. With SQLite storage, the database is then retired so
+// it is rebuilt from the restored records.
+func importArchive(archive string) (state.Manifest, []string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return state.Manifest{}, nil, err
+	}
+	keys := make(map[string]config.APIKey)
+	for _, name := range cfg.GetExchangeNames() {
+		keys[name], _ = cfg.GetExchangeKey(name)
+	}
+
+	release, err := lockDataFiles()
+	if err != nil {
+		return state.Manifest{}, nil, err
+	}
+	manifest, restored, err := state.Import(archive, stateEntries())
+	release()
+	if err != nil {
+		return state.Manifest{}, nil, ioError(err)
+	}
+	if len(restored) == 0 {
+		return manifest, nil, nil
+	}
+
+	cfg, err = loadConfig()
+	if err != nil {
+		return state.Manifest{}, nil, err
+	}
+	for name, key := range keys {
+		if _, ok := cfg.GetExchangeKey(name); ok {
+			continue
+		}
+		if err := cfg.SetExchangeKey(name, key); err != nil {
+			return state.Manifest{}, nil, ioError(err)
+		}
+	}
+	if cfg.GetStorage() == "sqlite" {
+		if err := retireSQLite(sqlitePath()); err != nil {
+			return state.Manifest{}, nil, ioError(err)
+		}
+	}
+	return manifest, restored, nil
+}
+
+// lockDataFiles takes the locks storage holds while changing the portfolio
+// and snapshot files, so files replaced wholesale don't race with other
+// commands or the daemon. The returned function releases them.
+func lockDataFiles() (func(), error) {
+	var locks []*storage.Lock
+	release := func() {
+		for _, l := range locks {
+			l.Release()
+		}
+	}
+	for _, path := range []string{dataPath, snapshotsPath()} {
+		l, err := storage.AcquireLock(path + ".lock")
+		if err != nil {
+			release()
+			return nil, ioError(err)
+		}
+		locks = append(locks, l)
+	}
+	return release, nil
+}
+
+// retireSQLite moves a SQLite database aside to path.bak, so it is rebuilt
+// from the JSON files. Its write-ahead log files are removed.
+func retireSQLite(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
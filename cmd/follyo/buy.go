@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
-	"text/tabwriter"
+	"strings"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 )
 
@@ -22,88 +24,136 @@ COIN: The cryptocurrency symbol (e.g., BTC, ETH)
 AMOUNT: Amount of coins bought
 PRICE: Purchase price per coin in USD (optional if --total is used)
 
-Use either PRICE argument or --total flag, not both.`,
+Use either PRICE argument or --total flag, not both.
+
+A purchase with the same coin, amount, price, platform, and date as an
+existing one is refused as a likely duplicate unless --force is given.`,
 	Args: cobra.RangeArgs(2, 3),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		coin := args[0]
-		amount := parseFloat(args[1], "amount")
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
 
-		total, _ := cmd.Flags().GetFloat64("total")
-		var price float64
+		total := decimalFlag(cmd, "total")
+		var price decimal.Decimal
 
-		if len(args) == 3 && total > 0 {
-			fmt.Fprintln(osStderr, "Error: specify either PRICE argument or --total flag, not both")
-			osExit(1)
+		if len(args) == 3 && total.IsPositive() {
+			return usageErrorf("specify either PRICE argument or --total flag, not both")
 		}
 
 		if len(args) == 3 {
-			price = parseFloat(args[2], "price")
-		} else if total > 0 {
-			price = total / amount
+			if price, err = parseDecimal(args[2], "price"); err != nil {
+				return err
+			}
+		} else if total.IsPositive() {
+			price = perUnit(total, amount)
 		} else {
-			fmt.Fprintln(osStderr, "Error: specify either PRICE argument or --total flag")
-			osExit(1)
+			return usageErrorf("specify either PRICE argument or --total flag")
 		}
 
-		platform, _ := cmd.Flags().GetString("platform")
+		platform, err := platformFlag(cmd)
+		if err != nil {
+			return err
+		}
 		notes, _ := cmd.Flags().GetString("notes")
-		date, _ := cmd.Flags().GetString("date")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		fee := decimalFlag(cmd, "fee")
 
-		holding, err := p.AddHolding(coin, amount, price, platform, notes, date)
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			day, err := parseDate(date, "date")
+			if err != nil {
+				return err
+			}
+			dup, found, err := p.FindDuplicateHolding(models.NewHolding(strings.ToUpper(coin), amount, price, platform, notes, day))
+			if err != nil {
+				return err
+			}
+			if found {
+				return fmt.Errorf("purchase %s has the same coin, amount, price, platform, and date; use --force to add it anyway", dup.ID)
+			}
+		}
+
+		holding, err := p.AddHoldingWithFee(coin, amount, price, fee, platform, notes, date, tags...)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
+		}
+		feeText := ""
+		if holding.FeeUSD.IsPositive() {
+			feeText = fmt.Sprintf(" + %s fee", formatUSD(holding.FeeUSD.InexactFloat64()))
 		}
-		fmt.Printf("Bought %s %s @ %s (ID: %s)\n", formatAmount(holding.Amount), holding.Coin, formatUSD(holding.PurchasePriceUSD), holding.ID)
+		fmt.Printf("Bought %s %s @ %s%s (ID: %s)\n", formatAmount(holding.Amount.InexactFloat64()), holding.Coin, formatUSD(holding.PurchasePriceUSD.InexactFloat64()), feeText, holding.ID)
+		return nil
 	},
 }
 
 var buyListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all purchases",
-	Run: func(cmd *cobra.Command, args []string) {
-		holdings, err := p.ListHoldings()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := listOptionsFromFlags(cmd)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
+		}
+		holdings, err := p.ListHoldingsFiltered(opts)
+		if err != nil {
+			return err
 		}
 
 		if len(holdings) == 0 {
 			fmt.Fprintln(osStdout, "No purchases found.")
-			return
+			return nil
 		}
 
-		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCoin\tAmount\tPrice/Unit\tTotal USD\tPlatform\tDate")
-		for _, h := range holdings {
-			platform := h.Platform
-			if platform == "" {
-				platform = "-"
-			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				h.ID, h.Coin, formatAmount(h.Amount),
-				formatUSD(h.PurchasePriceUSD), formatUSD(h.TotalValueUSD()),
-				platform, h.Date)
+		if group, _ := cmd.Flags().GetBool("group"); group {
+			return printGroupedList(cmd, opts, holdings, "purchase", "Cost USD", func(h models.Holding) (string, float64, float64, float64) {
+				return h.Coin, h.Amount.InexactFloat64(), h.TotalValueUSD().InexactFloat64(), h.CostUSD().InexactFloat64()
+			})
 		}
-		w.Flush()
-	},
-}
 
-var buyRemoveCmd = &cobra.Command{
-	Use:   "remove ID",
-	Short: "Remove a purchase by ID",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		id := args[0]
-		removed, err := p.RemoveHolding(id)
+		matching := holdings
+		page, limit := pageFromFlags(cmd)
+		holdings, pageInfo := paginate(holdings, page, limit)
+
+		all, err := p.ListHoldings()
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
-		if removed {
-			fmt.Printf("Removed purchase %s\n", id)
-		} else {
-			fmt.Printf("Purchase %s not found\n", id)
+		short := shortIDs(idsOf(all, func(h models.Holding) string { return h.ID }))
+		if err := printTable(cmd, "buy", holdingColumns(short), holdings, &opts); err != nil {
+			return err
 		}
+		printListTotals(matching, pageInfo != "", func(h models.Holding) (string, float64, float64) {
+			return h.Coin, h.Amount.InexactFloat64(), h.TotalValueUSD().InexactFloat64()
+		})
+		printListFooter(opts, len(matching), "purchase", pageInfo)
+		return nil
+	},
+}
+
+var buyRemoveCmd = &cobra.Command{
+	Use:   "remove [ID...]",
+	Short: "Remove purchases by ID or filter",
+	Long: `Remove one or more purchases.
+
+Instead of IDs, select the purchases to remove with --coin, --platform, --tag,
+--since, --until, and --before, e.g. to clean up a bad import:
+
+  follyo buy remove --coin TEST --platform Demo --before 2023-01-01
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeMatching(cmd, args, "purchase", p.ListHoldingsFiltered, func(h models.Holding) (string, string) {
+			return h.ID, fmt.Sprintf("%s %s bought %s%s", formatAmount(h.Amount.InexactFloat64()), h.Coin, h.Date, onPlatform(h.Platform))
+		}, p.RemoveHolding, func(id string) string {
+			return fmt.Sprintf("Removed purchase %[1]s (restore with 'follyo trash restore %[1]s')", id)
+		})
 	},
 }
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guptarohit/asciigraph"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+// chartHeight is the height of charts in terminal cells; their width follows
+// the terminal's, see chartWidth
+const chartHeight = 10
+
+// renderChart renders values as an ASCII line chart with a caption.
+// Series longer than chartWidth are interpolated down to fit.
+func renderChart(values []float64, caption string) string {
+	opts := []asciigraph.Option{
+		asciigraph.Height(chartHeight),
+		asciigraph.Offset(2),
+		asciigraph.Precision(0),
+		asciigraph.Caption(caption),
+	}
+	if width := chartWidth(); len(values) > width {
+		opts = append(opts, asciigraph.Width(width))
+	}
+	return asciigraph.Plot(values, opts...)
+}
+
+// chartSeries is a series of values charted alongside others, with its
+// legend and color
+type chartSeries struct {
+	legend string
+	color  asciigraph.AnsiColor
+	values []float64
+}
+
+// renderChartSeries renders several series of the same length as one ASCII
+// line chart with a legend, colored when colors are enabled, and a caption.
+func renderChartSeries(series []chartSeries, caption string) string {
+	data := make([][]float64, len(series))
+	legends := make([]string, len(series))
+	colors := make([]asciigraph.AnsiColor, len(series))
+	for i, s := range series {
+		data[i], legends[i], colors[i] = s.values, s.legend, s.color
+	}
+	opts := []asciigraph.Option{
+		asciigraph.Height(chartHeight),
+		asciigraph.Offset(2),
+		asciigraph.Precision(0),
+		asciigraph.Caption(caption),
+		asciigraph.SeriesLegends(legends...),
+	}
+	if colorEnabled() {
+		opts = append(opts, asciigraph.SeriesColors(colors...))
+	}
+	if width := chartWidth(); len(data[0]) > width {
+		opts = append(opts, asciigraph.Width(width))
+	}
+	return asciigraph.PlotMany(data, opts...)
+}
+
+// chartRanges are the time ranges snapshot charts and lists can be limited to
+var chartRanges = []string{"7d", "30d", "90d", "1y", "all"}
+
+// addRangeFlag adds the --range flag limiting a command's snapshots
+func addRangeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("range", "all", "Only use snapshots from the last 7d, 30d, 90d, or 1y, or all")
+	cmd.RegisterFlagCompletionFunc("range", completeFlag(completeValues(chartRanges...)))
+}
+
+// rangeFromFlag returns the start of the time range given by --range,
+// ending now, or a zero time for all
+func rangeFromFlag(cmd *cobra.Command) (time.Time, error) {
+	value, _ := cmd.Flags().GetString("range")
+	now := time.Now()
+	switch strings.ToLower(value) {
+	case "7d":
+		return now.AddDate(0, 0, -7), nil
+	case "30d":
+		return now.AddDate(0, 0, -30), nil
+	case "90d":
+		return now.AddDate(0, 0, -90), nil
+	case "1y":
+		return now.AddDate(-1, 0, 0), nil
+	case "all", "":
+		return time.Time{}, nil
+	}
+	return time.Time{}, usageErrorf("invalid --range %s (use %s)", value, strings.Join(chartRanges, ", "))
+}
+
+// snapshotsSince returns the snapshots, sorted oldest first, taken at or
+// after start
+func snapshotsSince(snapshots []models.Snapshot, start time.Time) []models.Snapshot {
+	i := sort.Search(len(snapshots), func(i int) bool {
+		return !snapshots[i].Timestamp.Before(start)
+	})
+	return snapshots[i:]
+}
+
+// downsampleSnapshots thins snapshots, sorted oldest first, to at most n by
+// splitting the time they span into n equal buckets and keeping the last
+// snapshot in each. Charts of frequent snapshots then plot one point per
+// column, spread evenly over time, rather than being interpolated.
+func downsampleSnapshots(snapshots []models.Snapshot, n int) []models.Snapshot {
+	if len(snapshots) <= n || n <= 0 {
+		return snapshots
+	}
+	first, last := snapshots[0].Timestamp, snapshots[len(snapshots)-1].Timestamp
+	span := last.Sub(first)
+	if span <= 0 {
+		return snapshots[len(snapshots)-1:]
+	}
+
+	sampled := make([]models.Snapshot, 0, n)
+	bucketOf := func(s models.Snapshot) int {
+		return min(n-1, int(float64(s.Timestamp.Sub(first))/float64(span)*float64(n)))
+	}
+	for i, s := range snapshots {
+		if i+1 < len(snapshots) && bucketOf(snapshots[i+1]) == bucketOf(s) {
+			continue
+		}
+		sampled = append(sampled, s)
+	}
+	return sampled
+}
+
+// netValueSeries returns the net values of snapshots in chronological order
+func netValueSeries(snapshots []models.Snapshot) []float64 {
+	return valueSeries(snapshots, func(s models.Snapshot) float64 { return s.NetValue })
+}
+
+// valueSeries returns a value of each snapshot in chronological order
+func valueSeries(snapshots []models.Snapshot, value func(models.Snapshot) float64) []float64 {
+	values := make([]float64, len(snapshots))
+	for i, snap := range snapshots {
+		values[i] = value(snap)
+	}
+	return values
+}
+
+// coinValueSeries returns a coin's value in each snapshot in chronological
+// order, counting snapshots without the coin as zero
+func coinValueSeries(snapshots []models.Snapshot, coin string) []float64 {
+	values := make([]float64, len(snapshots))
+	for i, snap := range snapshots {
+		values[i] = snap.CoinValues[coin].ValueUSD
+	}
+	return values
+}
+
+// findHoldingsChangeIndices returns the indices of the snapshots, in
+// chronological order, in which the amount of coin held differs from the
+// snapshot before
+func findHoldingsChangeIndices(snapshots []models.Snapshot, coin string) []int {
+	var indices []int
+	for i := 1; i < len(snapshots); i++ {
+		if snapshots[i].CoinValues[coin].Amount != snapshots[i-1].CoinValues[coin].Amount {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// holdingsChangeMarker returns ↑ when the amount of coin held rose in the
+// snapshot at index i, ↓ when it fell, and an empty string otherwise
+func holdingsChangeMarker(snapshots []models.Snapshot, coin string, i int) string {
+	if i == 0 {
+		return ""
+	}
+	change := snapshots[i].CoinValues[coin].Amount - snapshots[i-1].CoinValues[coin].Amount
+	switch {
+	case change > 0:
+		return "↑"
+	case change < 0:
+		return "↓"
+	}
+	return ""
+}
+
+// markChart adds a row of markers under a chart from renderChart, above its
+// caption, each under the point of the value at its index. Values must not
+// have been interpolated to fit, so each point is a column.
+func markChart(chart string, markers map[int]string) string {
+	if len(markers) == 0 {
+		return chart
+	}
+	lines := strings.Split(chart, "\n")
+	if len(lines) < 2 {
+		return chart
+	}
+	// Points start at the y-axis, which ends the last plot row's label
+	axis := strings.IndexAny(lines[len(lines)-2], "┤┼")
+	if axis < 0 {
+		return chart
+	}
+	axis = len([]rune(lines[len(lines)-2][:axis]))
+
+	last := 0
+	for i := range markers {
+		last = max(last, i)
+	}
+	row := []rune(strings.Repeat(" ", axis+last+1))
+	for i, m := range markers {
+		if r := []rune(m); len(r) > 0 {
+			row[axis+i] = r[0]
+		}
+	}
+	lines = slices.Insert(lines, len(lines)-1, string(row))
+	return strings.Join(lines, "\n")
+}
+
+// renderCandles renders price candles as an ASCII candlestick chart with a
+// caption. Each candle has a body from its open to its close, solid and
+// green when the price rose and shaded and red when it fell, and a wick
+// from its low to its high. Only the latest candles that fit chartWidth
+// are shown.
+func renderCandles(candles []prices.Candle, caption string) string {
+	maxCandles := chartWidth()
+	if len(candles) > maxCandles {
+		candles = candles[len(candles)-maxCandles:]
+	}
+	low, high := candles[0].Low, candles[0].High
+	for _, c := range candles {
+		low, high = min(low, c.Low), max(high, c.High)
+	}
+	rowOf := func(price float64) int {
+		if high == low {
+			return chartHeight / 2
+		}
+		return int(math.Round((high - price) / (high - low) * (chartHeight - 1)))
+	}
+
+	// Enough decimals to tell the rows apart, as for low-priced coins
+	decimals := 0
+	if step := (high - low) / (chartHeight - 1); step > 0 && step < 1 {
+		decimals = min(8, int(math.Ceil(-math.Log10(step))))
+	}
+	labels := make([]string, chartHeight)
+	width := 0
+	for r := range labels {
+		labels[r] = fmt.Sprintf("%.*f", decimals, high-(high-low)*float64(r)/(chartHeight-1))
+		width = max(width, len(labels[r]))
+	}
+	// Candles are spaced out when there is room
+	gap := ""
+	if 2*len(candles) <= maxCandles {
+		gap = " "
+	}
+
+	var b strings.Builder
+	for r := range chartHeight {
+		fmt.Fprintf(&b, "%*s ┤", width, labels[r])
+		for _, c := range candles {
+			cell := " "
+			switch {
+			case r >= rowOf(max(c.Open, c.Close)) && r <= rowOf(min(c.Open, c.Close)):
+				if c.Close >= c.Open {
+					cell = colorGreenText("█")
+				} else {
+					cell = colorRedText("░")
+				}
+			case r >= rowOf(c.High) && r <= rowOf(c.Low):
+				cell = "│"
+			}
+			b.WriteString(cell + gap)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(strings.Repeat(" ", width+2) + caption)
+	return b.String()
+}
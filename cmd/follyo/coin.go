@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var coinCmd = &cobra.Command{
+	Use:   "coin COIN",
+	Short: "Show everything recorded for a coin",
+	Long: `Show all purchases, sales, stakes, loans, swaps, and transfers of a
+coin, its current positions, average cost, break-even price, and
+realized and unrealized profit/loss.
+
+The coin's name, market cap rank, and categories and its unrealized
+profit/loss at the live price come from CoinGecko; use --no-prices to
+disable fetching them. When at least two snapshots exist, a chart of the
+coin's value over time is shown. Use --range to chart only the snapshots
+of the last 7d, 30d, 90d, or 1y, and --no-chart to hide the charts.
+Points where the amount held changed are marked ↑ when it rose (a
+purchase or unstake) and ↓ when it fell (a sale or stake). Use --history
+to list the coin's amount, price, and value in each snapshot.
+
+With live prices, a candlestick chart of the coin's price over the last
+30 days is shown too, whatever snapshots exist. Use --days to show 7,
+30, 90, or 365 days: up to 30 days, each candle is a day; beyond, each
+is 4 days, as CoinGecko provides them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+		if !slices.Contains(prices.HistoryDays, days) {
+			return usageErrorf("invalid --days %d (use 7, 30, 90, or 365)", days)
+		}
+		chartStart, err := rangeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		detail, err := p.GetCoinDetail(args[0])
+		if err != nil {
+			return err
+		}
+		if len(detail.Transactions) == 0 {
+			fmt.Fprintf(osStdout, "No records found for %s.\n", detail.Coin)
+			return nil
+		}
+
+		// Coin metadata is cached weekly, so this rarely needs the network
+		noPrices, _ := cmd.Flags().GetBool("no-prices")
+		var ps *prices.PriceService
+		if !noPrices {
+			if ps, err = newPriceService(); err != nil {
+				return err
+			}
+		}
+		printCoinHeader(ps, detail.Coin)
+
+		noChart, _ := cmd.Flags().GetBool("no-chart")
+		if !noChart && ps != nil {
+			printPriceHistory(ps, detail.Coin, days)
+		}
+		history, _ := cmd.Flags().GetBool("history")
+		if !noChart || history {
+			snapshots, err := listSnapshots()
+			snapshots = snapshotsSince(snapshots, chartStart)
+			if err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not load snapshots: %v\n", err)
+			} else {
+				if !noChart && len(snapshots) >= 2 {
+					printCoinValueChart(snapshots, detail.Coin)
+				}
+				if history {
+					printCoinHistory(snapshots, detail.Coin)
+				}
+			}
+		}
+
+		fmt.Fprintln(osStdout, "\nTRANSACTIONS:")
+		printLedger(detail.Transactions)
+
+		fmt.Fprintln(osStdout, "\n"+separator())
+		fmt.Fprintf(osStdout, "Held:           %s\n", formatAmount(detail.Held.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Staked:         %s\n", formatAmount(detail.Staked.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Loaned:         %s\n", formatAmount(detail.Loaned.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Average Cost:   %s\n", formatUSD(detail.AverageCostUSD.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Cost Basis:     %s\n", formatUSD(detail.CostBasisUSD.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Realized P/L:   %s\n", colorByValue(formatSignedUSD(detail.RealizedUSD.InexactFloat64()), detail.RealizedUSD.InexactFloat64()))
+
+		if noPrices || !detail.Held.IsPositive() {
+			if detail.Held.IsPositive() {
+				fmt.Fprintf(osStdout, "Break-Even:     %s\n", formatBreakEven(detail.BreakEven, 0, false, 1))
+			}
+			return nil
+		}
+		livePrices, err := ps.GetPrices([]string{detail.Coin})
+		if err != nil {
+			fmt.Fprintf(osStderr, "Warning: Could not fetch prices: %v\n", err)
+			return nil
+		}
+		defer autoSnapshot()
+
+		price, ok := livePrices[detail.Coin]
+		if !ok {
+			fmt.Fprintf(osStdout, "Break-Even:     %s\n", formatBreakEven(detail.BreakEven, 0, false, 1))
+			fmt.Fprintln(osStdout, "Unrealized P/L: N/A")
+			return nil
+		}
+		unrealized := detail.UnrealizedUSD(price)
+		fmt.Fprintf(osStdout, "Current Value:  %s @ %s\n", formatUSD(models.AtPrice(detail.Held, price).InexactFloat64()), formatUSD(price))
+		fmt.Fprintf(osStdout, "Break-Even:     %s\n", formatBreakEven(detail.BreakEven, price, true, 1))
+		fmt.Fprintf(osStdout, "Unrealized P/L: %s\n", colorByValue(formatSignedUSD(unrealized.InexactFloat64()), unrealized.InexactFloat64()))
+		return nil
+	},
+}
+
+var coinMigrateCmd = &cobra.Command{
+	Use:   "migrate OLD NEW",
+	Short: "Move a coin's records to a new ticker after a rebrand",
+	Long: `Move every purchase, sale, loan, stake, swap, and transfer of OLD to
+NEW, e.g. 'follyo coin migrate MATIC POL' or 'follyo coin migrate FTM S'.
+
+Use --ratio when each OLD became more or fewer NEW: amounts are
+multiplied by it and prices per coin divided by it, so dates, cost basis,
+and realized profit/loss are kept. Snapshots keep the ticker they were
+taken under.
+
+A custom CoinGecko mapping of OLD moves to NEW unless NEW already has one;
+use --gecko-id to set NEW's mapping instead. A watched OLD is replaced by
+NEW on the watchlist.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldCoin, newCoin := strings.ToUpper(args[0]), strings.ToUpper(args[1])
+		ratio := decimalFlag(cmd, "ratio")
+		if !ratio.IsPositive() {
+			return usageErrorf("--ratio must be positive")
+		}
+		geckoID, _ := cmd.Flags().GetString("gecko-id")
+
+		coins, err := p.GetCoins()
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(coins, oldCoin) {
+			return notFoundErrorf("no records of %s", oldCoin)
+		}
+		question := fmt.Sprintf("Move all %s records to %s?", oldCoin, newCoin)
+		if !ratio.Equal(decimal.NewFromInt(1)) {
+			question = fmt.Sprintf("Move all %s records to %s at 1 %s = %s %s?", oldCoin, newCoin, oldCoin, formatAmount(ratio.InexactFloat64()), newCoin)
+		}
+		ok, err := confirm(question)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(osStdout, "Cancelled.")
+			return nil
+		}
+
+		changed, err := p.MigrateCoin(oldCoin, newCoin, ratio)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Migrated %d record(s) from %s to %s\n", changed, oldCoin, newCoin)
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		return migrateCoinSettings(cfg, oldCoin, newCoin, geckoID)
+	},
+}
+
+// migrateCoinSettings moves the ticker mapping and watchlist entry of
+// oldCoin to newCoin, or maps newCoin to geckoID when it is given
+func migrateCoinSettings(cfg *config.ConfigStore, oldCoin, newCoin, geckoID string) error {
+	oldID := cfg.GetTickerMapping(oldCoin)
+	if oldID != "" {
+		if err := cfg.RemoveTickerMapping(oldCoin); err != nil {
+			return ioError(err)
+		}
+	}
+	_, hasDefault := prices.GetDefaultMappings()[newCoin]
+	switch {
+	case geckoID != "":
+		if err := cfg.SetTickerMapping(newCoin, geckoID); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Mapped %s -> %s\n", newCoin, geckoID)
+	case oldID != "" && !cfg.HasTickerMapping(newCoin) && !hasDefault:
+		if err := cfg.SetTickerMapping(newCoin, oldID); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Mapped %s -> %s, as %s was; check it with 'follyo ticker search %s'\n", newCoin, oldID, oldCoin, newCoin)
+	case !cfg.HasTickerMapping(newCoin) && !hasDefault:
+		fmt.Fprintf(osStdout, "No CoinGecko ID is known for %s; find one with 'follyo ticker search %s'\n", newCoin, newCoin)
+	}
+
+	if removed, err := cfg.RemoveFromWatchlist(oldCoin); err != nil {
+		return ioError(err)
+	} else if removed {
+		if _, err := cfg.AddToWatchlist(newCoin); err != nil {
+			return ioError(err)
+		}
+	}
+	return nil
+}
+
+// printCoinValueChart prints a chart of the coin's value in snapshots,
+// marking where the amount held changed
+func printCoinValueChart(snapshots []models.Snapshot, coin string) {
+	sampled := downsampleSnapshots(snapshots, chartWidth())
+	markers := make(map[int]string)
+	for _, i := range findHoldingsChangeIndices(sampled, coin) {
+		markers[i] = holdingsChangeMarker(sampled, coin, i)
+	}
+	caption := fmt.Sprintf("%s value (USD), %d snapshots", coin, len(snapshots))
+	if len(markers) > 0 {
+		caption += ", ↑↓ amount held changed"
+	}
+	fmt.Fprintln(osStdout, "\nVALUE HISTORY:")
+	fmt.Fprintln(osStdout, markChart(renderChart(coinValueSeries(sampled, coin), caption), markers))
+}
+
+// printCoinHistory prints the coin's amount, price, and value in each
+// snapshot, noting where the amount held changed from the snapshot before
+func printCoinHistory(snapshots []models.Snapshot, coin string) {
+	fmt.Fprintln(osStdout, "\nSNAPSHOT HISTORY:")
+	if len(snapshots) == 0 {
+		fmt.Fprintln(osStdout, "No snapshots found.")
+		return
+	}
+
+	changed := make(map[int]bool)
+	for _, i := range findHoldingsChangeIndices(snapshots, coin) {
+		changed[i] = true
+	}
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Date\tAmount\tPrice\tValue\tChange")
+	for i, snap := range snapshots {
+		cv := snap.CoinValues[coin]
+		change := ""
+		if changed[i] {
+			diff := models.Sub(cv.Amount, snapshots[i-1].CoinValues[coin].Amount)
+			if diff > 0 {
+				change = colorGreenText(fmt.Sprintf("↑ %s bought/unstaked", formatAmountChange(diff)))
+			} else {
+				change = colorRedText(fmt.Sprintf("↓ %s sold/staked", formatAmountChange(diff)))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			formatSnapshotTime(snap.Timestamp), formatAmount(cv.Amount),
+			formatUSD(cv.PriceUSD), formatUSD(cv.ValueUSD), change)
+	}
+	w.Flush()
+}
+
+// formatBreakEven formats a break-even price in the display currency, at
+// usdRate units per USD, with how far the USD price is above or below it
+// when hasPrice is set
+func formatBreakEven(b portfolio.BreakEven, priceUSD float64, hasPrice bool, usdRate float64) string {
+	if !b.PriceUSD.IsPositive() {
+		return "recovered (sold for more than bought)"
+	}
+	text := formatMoney(models.AtPrice(b.PriceUSD, usdRate).InexactFloat64())
+	if !hasPrice {
+		return text
+	}
+	distance := b.DistancePercent(priceUSD)
+	if distance >= 0 {
+		return text + " " + colorGreenText(fmt.Sprintf("(price %.1f%% above)", distance))
+	}
+	return text + " " + colorRedText(fmt.Sprintf("(price %.1f%% below)", -distance))
+}
+
+// printPriceHistory prints a candlestick chart of the coin's USD price over
+// the last days, if CoinGecko has its price history
+func printPriceHistory(ps *prices.PriceService, coin string, days int) {
+	candles, err := ps.GetPriceHistory(coin, days)
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not fetch price history: %v\n", err)
+		return
+	}
+	if len(candles) == 0 {
+		return
+	}
+	unit := "daily"
+	if days > 30 {
+		unit = "4-day"
+	}
+	caption := fmt.Sprintf("%s price (USD), last %d days, %s candles to %s",
+		coin, days, unit, candles[len(candles)-1].Time.Format("2006-01-02 15:04 UTC"))
+	fmt.Fprintln(osStdout, "\nPRICE HISTORY:")
+	fmt.Fprintln(osStdout, renderCandles(candles, caption))
+}
+
+// printCoinHeader prints the coin's ticker with its name, rank, and
+// categories when ps is set and CoinGecko knows them
+func printCoinHeader(ps *prices.PriceService, coin string) {
+	if ps == nil {
+		fmt.Fprintf(osStdout, "=== %s ===\n", coin)
+		return
+	}
+	metadata, err := ps.GetCoinMetadata([]string{coin})
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not fetch coin info: %v\n", err)
+	}
+	md, ok := metadata[coin]
+	if !ok || md.Name == "" {
+		fmt.Fprintf(osStdout, "=== %s ===\n", coin)
+		return
+	}
+	fmt.Fprintf(osStdout, "=== %s (%s) ===\n", coin, md.Name)
+	if md.Rank > 0 {
+		fmt.Fprintf(osStdout, "Rank:           #%d by market cap\n", md.Rank)
+	}
+	categories, err := ps.GetCoinCategories(coin)
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not fetch coin categories: %v\n", err)
+	}
+	if len(categories) > 0 {
+		fmt.Fprintf(osStdout, "Categories:     %s\n", strings.Join(categories, ", "))
+	}
+}
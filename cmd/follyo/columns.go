@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var columnsCmd = &cobra.Command{
+	Use:   "columns",
+	Short: "Choose the columns of list tables",
+	Long: `Show or choose the columns of the buy, sell, loan, stake, swap, and
+transfer list tables. Choices are stored in config.json; --columns on a
+list command overrides them for one run.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "List\tShown\tHidden")
+		for _, table := range sortedStringKeys(listTables) {
+			all, defaults := listTables[table]()
+			shown, err := chooseColumns(table, cfg.GetListColumns(table), all, defaults)
+			if err != nil {
+				// A column no longer offered falls back to the defaults
+				shown = defaults
+			}
+			hidden := slices.DeleteFunc(slices.Clone(all), func(key string) bool { return slices.Contains(shown, key) })
+			hiddenText := strings.Join(hidden, ", ")
+			if hiddenText == "" {
+				hiddenText = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", table, strings.Join(shown, ", "), hiddenText)
+		}
+		return w.Flush()
+	},
+}
+
+var columnsSetCmd = &cobra.Command{
+	Use:   "set LIST COLUMN...",
+	Short: "Choose the columns a list table shows",
+	Long: `Choose the columns LIST's table shows, in order, e.g.
+'follyo columns set buy id coin amount total date notes'. Columns starting
+with + or - add to or remove from the defaults instead, e.g.
+'follyo columns set buy +notes,-tags'. Put -- before an argument starting
+with -, as in 'follyo columns set buy -- -tags'.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		table := args[0]
+		keys, ok := listTables[table]
+		if !ok {
+			return usageErrorf("unknown list %q (lists: %s)", table, strings.Join(sortedStringKeys(listTables), ", "))
+		}
+		all, defaults := keys()
+		var requested []string
+		for _, arg := range args[1:] {
+			requested = append(requested, strings.Split(arg, ",")...)
+		}
+		chosen, err := chooseColumns(table, requested, all, defaults)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetListColumns(table, chosen); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "%s list shows %s\n", table, strings.Join(chosen, ", "))
+		return nil
+	},
+}
+
+var columnsResetCmd = &cobra.Command{
+	Use:   "reset LIST",
+	Short: "Show a list table's default columns again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		table := args[0]
+		keys, ok := listTables[table]
+		if !ok {
+			return usageErrorf("unknown list %q (lists: %s)", table, strings.Join(sortedStringKeys(listTables), ", "))
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetListColumns(table, nil); err != nil {
+			return ioError(err)
+		}
+		_, defaults := keys()
+		fmt.Fprintf(osStdout, "%s list shows %s\n", table, strings.Join(defaults, ", "))
+		return nil
+	},
+}
@@ -2,14 +2,34 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"text/tabwriter"
+	"time"
 
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/exchange"
+	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/state"
 	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // setupTestEnv creates a temp directory and initializes the portfolio for testing
@@ -28,6 +48,8 @@ func setupTestEnv(t *testing.T) (string, func()) {
 	}
 	p = portfolio.New(s)
 	dataPath = dataFile
+	// Keep the config in the temp directory rather than the user's data directory
+	t.Setenv("FOLLYO_DATA_DIR", filepath.Join(tmpDir, "data"))
 
 	// Setup mock for osStdout/osStderr to capture output
 	oldStdout := osStdout
@@ -63,7 +85,7 @@ func TestBuyCommands(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to set platform flag: %v", err)
 		}
-		buyAddCmd.Run(buyAddCmd, []string{"BTC", "0.5", "50000"})
+		buyAddCmd.RunE(buyAddCmd, []string{"BTC", "0.5", "50000"})
 
 		// Verify the holding was added
 		holdings, err := p.ListHoldings()
@@ -76,11 +98,11 @@ func TestBuyCommands(t *testing.T) {
 		if holdings[0].Coin != "BTC" {
 			t.Errorf("Expected coin BTC, got %s", holdings[0].Coin)
 		}
-		if holdings[0].Amount != 0.5 {
-			t.Errorf("Expected amount 0.5, got %f", holdings[0].Amount)
+		if !holdings[0].Amount.Equal(dec(0.5)) {
+			t.Errorf("Expected amount 0.5, got %v", holdings[0].Amount)
 		}
-		if holdings[0].PurchasePriceUSD != 50000 {
-			t.Errorf("Expected price 50000, got %f", holdings[0].PurchasePriceUSD)
+		if !holdings[0].PurchasePriceUSD.Equal(dec(50000)) {
+			t.Errorf("Expected price 50000, got %v", holdings[0].PurchasePriceUSD)
 		}
 	})
 
@@ -89,7 +111,7 @@ func TestBuyCommands(t *testing.T) {
 		buf, restore := captureOutput()
 		defer restore()
 
-		buyListCmd.Run(buyListCmd, []string{})
+		buyListCmd.RunE(buyListCmd, []string{})
 
 		output := buf.String()
 		if !strings.Contains(output, "BTC") {
@@ -104,7 +126,9 @@ func TestBuyCommands(t *testing.T) {
 			t.Fatal("No holdings to remove")
 		}
 
-		buyRemoveCmd.Run(buyRemoveCmd, []string{holdings[0].ID})
+		assumeYes = true
+		defer func() { assumeYes = false }()
+		buyRemoveCmd.RunE(buyRemoveCmd, []string{holdings[0].ID})
 
 		// Verify removal
 		holdings, _ = p.ListHoldings()
@@ -123,7 +147,7 @@ func TestBuyAddWithTotal(t *testing.T) {
 	buyAddCmd.Flags().Set("total", "10000")
 	buyAddCmd.Flags().Set("platform", "")
 
-	buyAddCmd.Run(buyAddCmd, []string{"ETH", "5"})
+	buyAddCmd.RunE(buyAddCmd, []string{"ETH", "5"})
 
 	holdings, err := p.ListHoldings()
 	if err != nil {
@@ -133,25 +157,207 @@ func TestBuyAddWithTotal(t *testing.T) {
 		t.Fatalf("Expected 1 holding, got %d", len(holdings))
 	}
 	// Price should be 10000 / 5 = 2000
-	if holdings[0].PurchasePriceUSD != 2000 {
-		t.Errorf("Expected price 2000 (10000/5), got %f", holdings[0].PurchasePriceUSD)
+	if !holdings[0].PurchasePriceUSD.Equal(dec(2000)) {
+		t.Errorf("Expected price 2000 (10000/5), got %v", holdings[0].PurchasePriceUSD)
 	}
 
 	// Reset flag
 	buyAddCmd.Flags().Set("total", "0")
 }
 
+func TestBuyAddWithFee(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buyAddCmd.Flags().Set("fee", "25")
+	defer buyAddCmd.Flags().Set("fee", "0")
+	buyAddCmd.RunE(buyAddCmd, []string{"ETH", "5", "2000"})
+
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 1 || !holdings[0].FeeUSD.Equal(dec(25)) {
+		t.Fatalf("Expected 1 holding with a $25 fee, got %+v", holdings)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+	buyListCmd.RunE(buyListCmd, []string{})
+	if !strings.Contains(buf.String(), "$25.00") {
+		t.Errorf("Expected fee in buy list, got: %s", buf.String())
+	}
+}
+
+func TestAddCommands_Duplicate(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-15")
+	existing, _ := p.AddSale("BTC", dec(0.5), dec(60000), "Coinbase", "", "2024-02-01")
+
+	buyAddCmd.Flags().Set("platform", "coinbase")
+	buyAddCmd.Flags().Set("date", "2024-01-15")
+	defer func() {
+		buyAddCmd.Flags().Set("platform", "")
+		buyAddCmd.Flags().Set("date", "")
+		buyAddCmd.Flags().Set("force", "false")
+	}()
+
+	err := buyAddCmd.RunE(buyAddCmd, []string{"btc", "1", "50000"})
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Expected duplicate error, got %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 1 {
+		t.Fatalf("Expected duplicate to be refused, got %d holdings", len(holdings))
+	}
+
+	buyAddCmd.Flags().Set("force", "true")
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"btc", "1", "50000"}); err != nil {
+		t.Fatalf("Expected --force to add duplicate, got %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 2 {
+		t.Errorf("Expected 2 holdings after --force, got %d", len(holdings))
+	}
+
+	sellAddCmd.Flags().Set("platform", "Coinbase")
+	sellAddCmd.Flags().Set("date", "2024-02-01")
+	defer func() {
+		sellAddCmd.Flags().Set("platform", "")
+		sellAddCmd.Flags().Set("date", "")
+	}()
+	err = sellAddCmd.RunE(sellAddCmd, []string{"BTC", "0.5", "60000"})
+	if err == nil || !strings.Contains(err.Error(), existing.ID) {
+		t.Errorf("Expected duplicate error naming sale %s, got %v", existing.ID, err)
+	}
+}
+
+func TestSellAdd_Oversell(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "2024-01-15")
+	defer sellAddCmd.Flags().Set("force", "false")
+
+	err := sellAddCmd.RunE(sellAddCmd, []string{"BTC", "2", "60000"})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "only 1 BTC available") {
+		t.Fatalf("Expected usage error for selling more than held, got %v", err)
+	}
+
+	sellAddCmd.Flags().Set("force", "true")
+	if err := sellAddCmd.RunE(sellAddCmd, []string{"BTC", "2", "60000"}); err != nil {
+		t.Fatalf("Expected --force to add the sale, got %v", err)
+	}
+	if sales, _ := p.ListSales(); len(sales) != 1 {
+		t.Errorf("Expected 1 sale after --force, got %d", len(sales))
+	}
+}
+
+func TestSellAdd_FromLot(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(20000), "", "", "2023-01-15")
+	recent, _ := p.AddHolding("BTC", dec(1.0), dec(60000), "", "", "2024-01-15")
+
+	sellAddCmd.Flags().Set("from-lot", recent.ID)
+	defer sellAddCmd.Flags().Lookup("from-lot").Value.(pflag.SliceValue).Replace(nil)
+	if err := sellAddCmd.RunE(sellAddCmd, []string{"BTC", "0.5", "50000"}); err != nil {
+		t.Fatalf("sell add --from-lot failed: %v", err)
+	}
+	sales, _ := p.ListSales()
+	if len(sales) != 1 || len(sales[0].LotIDs) != 1 || sales[0].LotIDs[0] != recent.ID {
+		t.Fatalf("Expected sale linked to lot %s, got %+v", recent.ID, sales)
+	}
+
+	buf, restore := captureOutput()
+	err := sellLotsCmd.RunE(sellLotsCmd, []string{"btc"})
+	restore()
+	if err != nil {
+		t.Fatalf("sell lots failed: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, recent.ID[:minShortID]+"  0.5") {
+		t.Errorf("Expected lot %s with 0.5 left, got:\n%s", recent.ID, output)
+	}
+}
+
+func TestTags(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buyAddCmd.Flags().Set("tag", "DCA,airdrop")
+	defer buyAddCmd.Flags().Lookup("tag").Value.(pflag.SliceValue).Replace(nil)
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "1", "50000"}); err != nil {
+		t.Fatalf("buy add --tag failed: %v", err)
+	}
+	p.AddHolding("ETH", dec(2), dec(3000), "", "", "")
+
+	buyListCmd.Flags().Set("tag", "airdrop")
+	defer buyListCmd.Flags().Set("tag", "")
+	buf, restore := captureOutput()
+	err := buyListCmd.RunE(buyListCmd, nil)
+	restore()
+	if err != nil {
+		t.Fatalf("buy list --tag failed: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "DCA,airdrop") || strings.Contains(output, "ETH") {
+		t.Errorf("Expected only the tagged BTC purchase, got:\n%s", output)
+	}
+
+	buf, restore = captureOutput()
+	err = tagsCmd.RunE(tagsCmd, nil)
+	restore()
+	if err != nil {
+		t.Fatalf("tags failed: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "airdrop") || !strings.Contains(output, "$50,000.00") {
+		t.Errorf("Expected airdrop tag with $50,000.00 invested, got:\n%s", output)
+	}
+}
+
+func TestBuyAdd_InvalidInput(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buyAddCmd.Flags().Set("date", "2024-13-45")
+	defer buyAddCmd.Flags().Set("date", "")
+	err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "1", "50000"})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "2024-13-45") {
+		t.Errorf("Expected usage error for invalid date, got %v", err)
+	}
+
+	buyAddCmd.Flags().Set("date", "2024-1-5")
+	err = buyAddCmd.RunE(buyAddCmd, []string{"BTC", "-1", "50000"})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "amount must be positive") {
+		t.Errorf("Expected usage error for negative amount, got %v", err)
+	}
+
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "1", "50000"}); err != nil {
+		t.Fatalf("buy add failed: %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 1 || holdings[0].Date.String() != "2024-01-05" {
+		t.Errorf("Expected one holding dated 2024-01-05, got %+v", holdings)
+	}
+
+	// Natural dates are stored as YYYY-MM-DD
+	buyAddCmd.Flags().Set("date", "3 days ago")
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "2", "50000"}); err != nil {
+		t.Fatalf("buy add with a natural date failed: %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 2 || holdings[1].Date != models.Today().AddDays(-3) {
+		t.Errorf("Expected a holding dated 3 days ago, got %+v", holdings)
+	}
+}
+
 // TestSellCommands tests sell add, list, and remove commands
 func TestSellCommands(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
 
 	// First add a holding to sell from
-	p.AddHolding("BTC", 1.0, 50000, "Coinbase", "", "")
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
 
 	// Test sell add
 	t.Run("sell add", func(t *testing.T) {
-		sellAddCmd.Run(sellAddCmd, []string{"BTC", "0.5", "55000"})
+		sellAddCmd.RunE(sellAddCmd, []string{"BTC", "0.5", "55000"})
 
 		sales, err := p.ListSales()
 		if err != nil {
@@ -170,7 +376,7 @@ func TestSellCommands(t *testing.T) {
 		buf, restore := captureOutput()
 		defer restore()
 
-		sellListCmd.Run(sellListCmd, []string{})
+		sellListCmd.RunE(sellListCmd, []string{})
 
 		output := buf.String()
 		if !strings.Contains(output, "BTC") {
@@ -185,7 +391,9 @@ func TestSellCommands(t *testing.T) {
 			t.Fatal("No sales to remove")
 		}
 
-		sellRemoveCmd.Run(sellRemoveCmd, []string{sales[0].ID})
+		assumeYes = true
+		defer func() { assumeYes = false }()
+		sellRemoveCmd.RunE(sellRemoveCmd, []string{sales[0].ID})
 
 		sales, _ = p.ListSales()
 		if len(sales) != 0 {
@@ -194,7 +402,7 @@ func TestSellCommands(t *testing.T) {
 	})
 }
 
-// TestLoanCommands tests loan add, list, and remove commands
+// TestLoanCommands tests loan add, list, repay, and remove commands
 func TestLoanCommands(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -202,7 +410,7 @@ func TestLoanCommands(t *testing.T) {
 	// Test loan add
 	t.Run("loan add", func(t *testing.T) {
 		loanAddCmd.Flags().Set("rate", "5.5")
-		loanAddCmd.Run(loanAddCmd, []string{"USDC", "10000", "Nexo"})
+		loanAddCmd.RunE(loanAddCmd, []string{"USDC", "10000", "Nexo"})
 
 		loans, err := p.ListLoans()
 		if err != nil {
@@ -214,8 +422,8 @@ func TestLoanCommands(t *testing.T) {
 		if loans[0].Coin != "USDC" {
 			t.Errorf("Expected coin USDC, got %s", loans[0].Coin)
 		}
-		if loans[0].Amount != 10000 {
-			t.Errorf("Expected amount 10000, got %f", loans[0].Amount)
+		if !loans[0].Amount.Equal(dec(10000)) {
+			t.Errorf("Expected amount 10000, got %v", loans[0].Amount)
 		}
 		loanAddCmd.Flags().Set("rate", "0")
 	})
@@ -225,7 +433,7 @@ func TestLoanCommands(t *testing.T) {
 		buf, restore := captureOutput()
 		defer restore()
 
-		loanListCmd.Run(loanListCmd, []string{})
+		loanListCmd.RunE(loanListCmd, []string{})
 
 		output := buf.String()
 		if !strings.Contains(output, "USDC") {
@@ -233,6 +441,23 @@ func TestLoanCommands(t *testing.T) {
 		}
 	})
 
+	// Test loan repay
+	t.Run("loan repay", func(t *testing.T) {
+		loans, _ := p.ListLoans()
+		if len(loans) == 0 {
+			t.Fatal("No loans to repay")
+		}
+
+		loanRepayCmd.RunE(loanRepayCmd, []string{loans[0].ID, "4000"})
+
+		buf, restore := captureOutput()
+		defer restore()
+		loanListCmd.RunE(loanListCmd, []string{})
+		if !strings.Contains(buf.String(), "6,000") {
+			t.Errorf("Expected outstanding 6,000 in loan list, got: %s", buf.String())
+		}
+	})
+
 	// Test loan remove
 	t.Run("loan remove", func(t *testing.T) {
 		loans, _ := p.ListLoans()
@@ -240,12 +465,18 @@ func TestLoanCommands(t *testing.T) {
 			t.Fatal("No loans to remove")
 		}
 
-		loanRemoveCmd.Run(loanRemoveCmd, []string{loans[0].ID})
+		assumeYes = true
+		defer func() { assumeYes = false }()
+		loanRemoveCmd.RunE(loanRemoveCmd, []string{loans[0].ID})
 
 		loans, _ = p.ListLoans()
 		if len(loans) != 0 {
 			t.Errorf("Expected 0 loans after removal, got %d", len(loans))
 		}
+		repayments, _ := p.ListRepayments()
+		if len(repayments) != 0 {
+			t.Errorf("Expected repayments to be removed with the loan, got %d", len(repayments))
+		}
 	})
 }
 
@@ -255,12 +486,12 @@ func TestStakeCommands(t *testing.T) {
 	defer cleanup()
 
 	// First add a holding to stake from
-	p.AddHolding("ETH", 10.0, 3000, "Coinbase", "", "")
+	p.AddHolding("ETH", dec(10.0), dec(3000), "Coinbase", "", "")
 
 	// Test stake add
 	t.Run("stake add", func(t *testing.T) {
 		stakeAddCmd.Flags().Set("apy", "4.5")
-		stakeAddCmd.Run(stakeAddCmd, []string{"ETH", "5", "Lido"})
+		stakeAddCmd.RunE(stakeAddCmd, []string{"ETH", "5", "Lido"})
 
 		stakes, err := p.ListStakes()
 		if err != nil {
@@ -280,7 +511,7 @@ func TestStakeCommands(t *testing.T) {
 		buf, restore := captureOutput()
 		defer restore()
 
-		stakeListCmd.Run(stakeListCmd, []string{})
+		stakeListCmd.RunE(stakeListCmd, []string{})
 
 		output := buf.String()
 		if !strings.Contains(output, "ETH") {
@@ -288,6 +519,24 @@ func TestStakeCommands(t *testing.T) {
 		}
 	})
 
+	// Test stake reduce
+	t.Run("stake reduce", func(t *testing.T) {
+		stakes, _ := p.ListStakes()
+		if len(stakes) == 0 {
+			t.Fatal("No stakes to reduce")
+		}
+
+		stakeReduceCmd.RunE(stakeReduceCmd, []string{stakes[0].ID, "2"})
+
+		stakes, _ = p.ListStakes()
+		if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(3)) {
+			t.Errorf("Expected 3 ETH remaining staked, got %+v", stakes)
+		}
+		if stakes[0].APY == nil || *stakes[0].APY != 4.5 {
+			t.Error("Expected APY to be kept after reducing")
+		}
+	})
+
 	// Test stake remove
 	t.Run("stake remove", func(t *testing.T) {
 		stakes, _ := p.ListStakes()
@@ -295,7 +544,9 @@ func TestStakeCommands(t *testing.T) {
 			t.Fatal("No stakes to remove")
 		}
 
-		stakeRemoveCmd.Run(stakeRemoveCmd, []string{stakes[0].ID})
+		assumeYes = true
+		defer func() { assumeYes = false }()
+		stakeRemoveCmd.RunE(stakeRemoveCmd, []string{stakes[0].ID})
 
 		stakes, _ = p.ListStakes()
 		if len(stakes) != 0 {
@@ -310,18 +561,18 @@ func TestSummaryCommand(t *testing.T) {
 	defer cleanup()
 
 	// Add some data
-	p.AddHolding("BTC", 1.0, 50000, "Coinbase", "", "")
-	p.AddHolding("ETH", 10.0, 3000, "Binance", "", "")
-	p.AddSale("BTC", 0.5, 55000, "Coinbase", "", "")
-	p.AddLoan("USDC", 5000, "Nexo", nil, "", "")
-	p.AddStake("ETH", 5.0, "Lido", nil, "", "")
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
+	p.AddHolding("ETH", dec(10.0), dec(3000), "Binance", "", "")
+	p.AddSale("BTC", dec(0.5), dec(55000), "Coinbase", "", "")
+	p.AddLoan("USDC", dec(5000), "Nexo", nil, "", "")
+	p.AddStake("ETH", dec(5.0), "Lido", nil, "", "")
 
 	t.Run("summary without prices", func(t *testing.T) {
 		buf, restore := captureOutput()
 		defer restore()
 
 		summaryCmd.Flags().Set("prices", "false")
-		summaryCmd.Run(summaryCmd, []string{})
+		summaryCmd.RunE(summaryCmd, []string{})
 
 		output := buf.String()
 
@@ -358,7 +609,7 @@ func TestSummaryEmptyPortfolio(t *testing.T) {
 	buf, restore := captureOutput()
 	defer restore()
 
-	summaryCmd.Run(summaryCmd, []string{})
+	summaryCmd.RunE(summaryCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "(none)") {
@@ -366,6 +617,43 @@ func TestSummaryEmptyPortfolio(t *testing.T) {
 	}
 }
 
+// TestSummaryPeriod tests summary scoped with --since and --until
+func TestSummaryPeriod(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-12-01")
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-02-01")
+	p.AddSale("BTC", dec(1), dec(50000), "", "", "2024-03-01")
+
+	summaryCmd.Flags().Set("no-prices", "true")
+	summaryCmd.Flags().Set("since", "2024-01-01")
+	summaryCmd.Flags().Set("until", "2024-06-30")
+	defer func() {
+		summaryCmd.Flags().Set("no-prices", "false")
+		summaryCmd.Flags().Set("since", "")
+		summaryCmd.Flags().Set("until", "")
+	}()
+
+	if err := summaryCmd.RunE(summaryCmd, []string{}); err != nil {
+		t.Fatalf("summary failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Period: 2024-01-01 to 2024-06-30", "Total Holdings: 1", "Total Invested: $40,000.00", "Total Sold: $50,000.00", "Realized P/L: +$30,000.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	summaryCmd.Flags().Set("since", "2024-07-01")
+	if err := summaryCmd.RunE(summaryCmd, []string{}); err == nil || exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for --since after --until, got %v", err)
+	}
+}
+
 // TestBuyListEmpty tests buy list with no holdings
 func TestBuyListEmpty(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
@@ -374,7 +662,7 @@ func TestBuyListEmpty(t *testing.T) {
 	buf, restore := captureOutput()
 	defer restore()
 
-	buyListCmd.Run(buyListCmd, []string{})
+	buyListCmd.RunE(buyListCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "No purchases found") {
@@ -390,7 +678,7 @@ func TestSellListEmpty(t *testing.T) {
 	buf, restore := captureOutput()
 	defer restore()
 
-	sellListCmd.Run(sellListCmd, []string{})
+	sellListCmd.RunE(sellListCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "No sales found") {
@@ -406,7 +694,7 @@ func TestLoanListEmpty(t *testing.T) {
 	buf, restore := captureOutput()
 	defer restore()
 
-	loanListCmd.Run(loanListCmd, []string{})
+	loanListCmd.RunE(loanListCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "No loans found") {
@@ -422,7 +710,7 @@ func TestStakeListEmpty(t *testing.T) {
 	buf, restore := captureOutput()
 	defer restore()
 
-	stakeListCmd.Run(stakeListCmd, []string{})
+	stakeListCmd.RunE(stakeListCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "No stakes found") {
@@ -484,11 +772,11 @@ func TestPrintCoinLine(t *testing.T) {
 			var buf bytes.Buffer
 			w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
 
-			got := printCoinLine(w, tt.coin, tt.amount, tt.prices, tt.showPrefix)
+			got := printCoinLine(w, tt.coin, dec(tt.amount), tt.prices, tt.showPrefix)
 			w.Flush()
 
-			if got != tt.wantValue {
-				t.Errorf("printCoinLine() returned value = %f, want %f", got, tt.wantValue)
+			if !got.Equal(dec(tt.wantValue)) {
+				t.Errorf("printCoinLine() returned value = %v, want %v", got, tt.wantValue)
 			}
 
 			output := buf.String()
@@ -508,7 +796,7 @@ func TestTickerListCommand(t *testing.T) {
 	defer restore()
 
 	tickerListCmd.Flags().Set("all", "false")
-	tickerListCmd.Run(tickerListCmd, []string{})
+	tickerListCmd.RunE(tickerListCmd, []string{})
 
 	output := buf.String()
 	if !strings.Contains(output, "Ticker Mappings") {
@@ -519,6 +807,47 @@ func TestTickerListCommand(t *testing.T) {
 	}
 }
 
+func TestTickerPriceCommands(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	tickerPriceSetCmd.Flags().Set("date", "2024-03-01")
+	defer tickerPriceSetCmd.Flags().Set("date", "")
+	if err := tickerPriceSetCmd.RunE(tickerPriceSetCmd, []string{"xyz", "0.42"}); err != nil {
+		t.Fatalf("price set failed: %v", err)
+	}
+	if err := tickerPriceSetCmd.RunE(tickerPriceSetCmd, []string{"XYZ", "-1"}); exitCode(err) != 2 {
+		t.Errorf("Expected usage error for negative price, got %v", err)
+	}
+
+	buf.Reset()
+	if err := tickerPriceListCmd.RunE(tickerPriceListCmd, nil); err != nil {
+		t.Fatalf("price list failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "XYZ") || !strings.Contains(buf.String(), "$0.42") || !strings.Contains(buf.String(), "2024-03-01") {
+		t.Errorf("Expected XYZ at $0.42 as of 2024-03-01, got: %s", buf.String())
+	}
+
+	ps, err := newPriceService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ps.GetLastKnownPrices([]string{"XYZ"}); got["XYZ"] != 0.42 {
+		t.Errorf("Expected price service to use manual price, got %v", got)
+	}
+
+	if err := tickerPriceRemoveCmd.RunE(tickerPriceRemoveCmd, []string{"xyz"}); err != nil {
+		t.Fatalf("price remove failed: %v", err)
+	}
+	if err := tickerPriceRemoveCmd.RunE(tickerPriceRemoveCmd, []string{"xyz"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
 // TestRootCmd tests that root command exists and has correct info
 func TestRootCmd(t *testing.T) {
 	if rootCmd.Use != "follyo" {
@@ -528,3 +857,3285 @@ func TestRootCmd(t *testing.T) {
 		t.Error("Expected root command Short description to be non-empty")
 	}
 }
+
+// TestParseConversion tests parsing of convert command queries
+func TestParseConversion(t *testing.T) {
+	tests := []struct {
+		query   string
+		amount  float64
+		from    string
+		to      string
+		wantErr bool
+	}{
+		{"0.35 ETH in USD", 0.35, "ETH", "USD", false},
+		{"500 usd to sol", 500, "USD", "SOL", false},
+		{"2 ETH BTC", 2, "ETH", "BTC", false},
+		{"2 ETH into BTC", 0, "", "", true},
+		{"abc ETH in USD", 0, "", "", true},
+		{"-1 ETH in USD", 0, "", "", true},
+		{"ETH in USD", 0, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			amount, from, to, err := parseConversion(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConversion(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if amount != tt.amount || from != tt.from || to != tt.to {
+				t.Errorf("parseConversion(%q) = %v %s %s, want %v %s %s",
+					tt.query, amount, from, to, tt.amount, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+// TestTaxReportCommand tests the tax report command and CSV export
+func TestTaxReportCommand(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(30000), "", "", "2022-01-01")
+	p.AddSale("BTC", dec(0.5), dec(60000), "", "", "2024-02-01")
+
+	t.Run("table output", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		taxReportCmd.Flags().Set("year", "2024")
+		taxReportCmd.RunE(taxReportCmd, []string{})
+
+		output := buf.String()
+		if !strings.Contains(output, "2022-01-01") {
+			t.Errorf("Expected acquisition date in output, got: %s", output)
+		}
+		if !strings.Contains(output, "Long-term gain/loss:  $15,000.00") {
+			t.Errorf("Expected long-term gain of $15,000.00, got: %s", output)
+		}
+	})
+
+	t.Run("csv export", func(t *testing.T) {
+		_, restore := captureOutput()
+		defer restore()
+
+		csvPath := filepath.Join(tmpDir, "tax.csv")
+		taxReportCmd.Flags().Set("csv", csvPath)
+		defer taxReportCmd.Flags().Set("csv", "")
+		taxReportCmd.RunE(taxReportCmd, []string{})
+
+		data, err := os.ReadFile(csvPath)
+		if err != nil {
+			t.Fatalf("Failed to read CSV: %v", err)
+		}
+		if !strings.Contains(string(data), "0.5 BTC,2022-01-01,2024-02-01,30000.00,15000.00,15000.00,long") {
+			t.Errorf("Unexpected CSV content: %s", data)
+		}
+	})
+}
+
+// TestSuggestTickerMappings tests the startup notice for unmapped coins
+func TestSuggestTickerMappings(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")
+	p.AddHolding("ZZNOTACOIN", dec(100), dec(1), "", "", "")
+
+	var buf bytes.Buffer
+	osStderr = &buf
+
+	suggestTickerMappings(buyListCmd)
+	output := buf.String()
+	if !strings.Contains(output, "follyo ticker search zznotacoin ZZNOTACOIN") {
+		t.Errorf("Expected suggestion for unmapped coin, got: %s", output)
+	}
+	if strings.Contains(output, "BTC") {
+		t.Errorf("Expected no suggestion for mapped coin, got: %s", output)
+	}
+
+	buf.Reset()
+	suggestTickerMappings(tickerMapCmd)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no suggestions for ticker commands, got: %s", buf.String())
+	}
+}
+
+// TestSnapshotCommands tests snapshot save, list, show, and remove
+func TestSnapshotCommands(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	t.Run("snapshot list empty", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		snapshotListCmd.RunE(snapshotListCmd, []string{})
+		if !strings.Contains(buf.String(), "No snapshots found") {
+			t.Errorf("Expected 'No snapshots found', got: %s", buf.String())
+		}
+	})
+
+	t.Run("snapshot save", func(t *testing.T) {
+		_, restore := captureOutput()
+		defer restore()
+
+		// Empty portfolio needs no price fetch
+		snapshotSaveCmd.Flags().Set("note", "first")
+		defer snapshotSaveCmd.Flags().Set("note", "")
+		snapshotSaveCmd.RunE(snapshotSaveCmd, []string{})
+
+		snapshots, err := listSnapshots()
+		if err != nil {
+			t.Fatalf("Failed to list snapshots: %v", err)
+		}
+		if len(snapshots) != 1 || snapshots[0].Note != "first" {
+			t.Fatalf("Expected 1 snapshot with note, got %+v", snapshots)
+		}
+	})
+
+	t.Run("snapshot note", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		snapshots, _ := listSnapshots()
+		id := snapshots[0].ID
+		if err := snapshotNoteCmd.RunE(snapshotNoteCmd, []string{id[:minShortID], "after the halving"}); err != nil {
+			t.Fatalf("snapshot note failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), `Set the note of snapshot `+id+` to "after the halving"`) {
+			t.Errorf("Expected confirmation, got: %s", buf.String())
+		}
+		if snapshots, _ := listSnapshots(); snapshots[0].Note != "after the halving" {
+			t.Errorf("Expected the note changed, got %q", snapshots[0].Note)
+		}
+
+		if err := snapshotNoteCmd.RunE(snapshotNoteCmd, []string{id, ""}); err != nil {
+			t.Fatalf("snapshot note clear failed: %v", err)
+		}
+		if snapshots, _ := listSnapshots(); snapshots[0].Note != "" {
+			t.Errorf("Expected the note cleared, got %q", snapshots[0].Note)
+		}
+		if err := snapshotNoteCmd.RunE(snapshotNoteCmd, []string{"zzzz", "x"}); exitCode(err) != exitNotFound {
+			t.Errorf("Expected not found for an unknown snapshot, got %v", err)
+		}
+
+		snapshotNoteCmd.RunE(snapshotNoteCmd, []string{id, "first"})
+	})
+
+	t.Run("snapshot show and remove", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		snapshots, _ := listSnapshots()
+		snapshotShowCmd.RunE(snapshotShowCmd, []string{snapshots[0].ID})
+		if !strings.Contains(buf.String(), "Note: first") {
+			t.Errorf("Expected note in output, got: %s", buf.String())
+		}
+
+		snapshotRemoveCmd.RunE(snapshotRemoveCmd, []string{snapshots[0].ID})
+		snapshots, _ = listSnapshots()
+		if len(snapshots) != 0 {
+			t.Errorf("Expected 0 snapshots after removal, got %d", len(snapshots))
+		}
+	})
+}
+
+func TestSnapshotPin(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer dashboardCmd.Flags().Set("baseline", "")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	// Empty portfolios need no price fetch
+	snapshotSaveCmd.RunE(snapshotSaveCmd, nil)
+	snapshotSaveCmd.RunE(snapshotSaveCmd, nil)
+	snapshots, _ := listSnapshots()
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	}
+	pinned, other := snapshots[0].ID, snapshots[1].ID
+
+	if err := snapshotPinCmd.RunE(snapshotPinCmd, []string{pinned, "pre-halving"}); err != nil {
+		t.Fatalf("snapshot pin failed: %v", err)
+	}
+	if err := snapshotPinCmd.RunE(snapshotPinCmd, []string{other, "Pre-Halving"}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for a name already in use, got %v", err)
+	}
+
+	// The name stands in for the ID, and the list marks the pin
+	buf.Reset()
+	if err := snapshotShowCmd.RunE(snapshotShowCmd, []string{"PRE-HALVING"}); err != nil {
+		t.Fatalf("snapshot show by pin failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Snapshot "+pinned) || !strings.Contains(out, "Pinned as: pre-halving") {
+		t.Errorf("Expected the pinned snapshot shown, got:\n%s", out)
+	}
+	buf.Reset()
+	snapshotListCmd.RunE(snapshotListCmd, nil)
+	if out := buf.String(); !strings.Contains(out, "* pre-halving") {
+		t.Errorf("Expected the pin marked in the list, got:\n%s", out)
+	}
+
+	// Pinned snapshots can't be removed
+	if err := snapshotRemoveCmd.RunE(snapshotRemoveCmd, []string{"pre-halving"}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error removing a pinned snapshot, got %v", err)
+	}
+
+	// The dashboard compares with the pinned baseline
+	buf.Reset()
+	dashboardCmd.Flags().Set("baseline", "pre-halving")
+	if err := dashboardCmd.RunE(dashboardCmd, nil); err != nil {
+		t.Fatalf("dashboard --baseline failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "since pre-halving (") || !strings.Contains(out, "P/L since pre-halving (") {
+		t.Errorf("Expected the change since the baseline, got:\n%s", out)
+	}
+	dashboardCmd.Flags().Set("baseline", "no-such-pin")
+	if err := dashboardCmd.RunE(dashboardCmd, nil); exitCode(err) != exitNotFound {
+		t.Errorf("Expected not found for an unknown baseline, got %v", err)
+	}
+
+	if err := snapshotUnpinCmd.RunE(snapshotUnpinCmd, []string{"pre-halving"}); err != nil {
+		t.Fatalf("snapshot unpin failed: %v", err)
+	}
+	if err := snapshotUnpinCmd.RunE(snapshotUnpinCmd, []string{pinned}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error unpinning an unpinned snapshot, got %v", err)
+	}
+	if err := snapshotRemoveCmd.RunE(snapshotRemoveCmd, []string{pinned}); err != nil {
+		t.Errorf("Expected an unpinned snapshot removed, got %v", err)
+	}
+}
+
+func TestBaselineSnapshot(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer dashboardCmd.Flags().Set("baseline", "")
+	defer summaryCmd.Flags().Set("baseline", "")
+	defer summaryCmd.Flags().Set("no-prices", "false")
+	defer summaryCmd.Flags().Set("all", "false")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	// An empty portfolio needs no prices
+	snapshotSaveCmd.RunE(snapshotSaveCmd, nil)
+	snapshots, _ := listSnapshots()
+	snapshotPinCmd.RunE(snapshotPinCmd, []string{snapshots[0].ID, "jan-1"})
+
+	if err := configSetCmd.RunE(configSetCmd, []string{"baseline_snapshot", "JAN-1"}); err != nil {
+		t.Fatalf("config set baseline_snapshot failed: %v", err)
+	}
+	cfg, _ := loadConfig()
+	if got := cfg.GetBaselineSnapshot(); got != "jan-1" {
+		t.Errorf("Expected the pinned name saved, got %q", got)
+	}
+	if err := configSetCmd.RunE(configSetCmd, []string{"baseline_snapshot", "zzzz"}); exitCode(err) != exitNotFound {
+		t.Errorf("Expected not found for an unknown snapshot, got %v", err)
+	}
+
+	// The configured baseline applies to the dashboard and summary
+	buf.Reset()
+	if err := dashboardCmd.RunE(dashboardCmd, nil); err != nil {
+		t.Fatalf("dashboard failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "P/L since jan-1 (") {
+		t.Errorf("Expected the P/L since the configured baseline, got:\n%s", out)
+	}
+	buf.Reset()
+	summaryCmd.Flags().Set("no-prices", "true")
+	if err := summaryCmd.RunE(summaryCmd, nil); err != nil {
+		t.Fatalf("summary failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Realized P/L since jan-1 (") {
+		t.Errorf("Expected the realized P/L since the baseline, got:\n%s", out)
+	}
+
+	// --baseline none turns it off for one run
+	buf.Reset()
+	dashboardCmd.Flags().Set("baseline", "none")
+	if err := dashboardCmd.RunE(dashboardCmd, nil); err != nil {
+		t.Fatalf("dashboard --baseline none failed: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "P/L since") || !strings.Contains(out, "P/L:") {
+		t.Errorf("Expected the all-time P/L, got:\n%s", out)
+	}
+
+	summaryCmd.Flags().Set("baseline", "jan-1")
+	summaryCmd.Flags().Set("all", "true")
+	if err := summaryCmd.RunE(summaryCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for --baseline with --all, got %v", err)
+	}
+
+	// A configured baseline that was unpinned only warns
+	snapshotUnpinCmd.RunE(snapshotUnpinCmd, []string{"jan-1"})
+	dashboardCmd.Flags().Set("baseline", "")
+	errBuf := &bytes.Buffer{}
+	oldStderr := osStderr
+	osStderr = errBuf
+	defer func() { osStderr = oldStderr }()
+	if err := dashboardCmd.RunE(dashboardCmd, nil); err != nil {
+		t.Fatalf("dashboard with a stale baseline failed: %v", err)
+	}
+	if !strings.Contains(errBuf.String(), "baseline snapshot jan-1 is unavailable") {
+		t.Errorf("Expected a warning for the missing baseline, got: %s", errBuf.String())
+	}
+}
+
+func TestSnapshotSaveManualPrices(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	prices := snapshotSaveCmd.Flags().Lookup("price")
+	defer prices.Value.(pflag.SliceValue).Replace(nil)
+
+	buf, restore := captureOutput()
+	defer restore()
+	errBuf := &bytes.Buffer{}
+	oldStderr := osStderr
+	osStderr = errBuf
+	defer func() { osStderr = oldStderr }()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("ETH", dec(2.0), dec(3000), "Kraken", "", "2024-01-01")
+
+	// Every held coin priced by hand needs no fetch
+	prices.Value.(pflag.SliceValue).Replace([]string{"BTC=97000,ETH=3400", "DOGE=1"})
+	if err := snapshotSaveCmd.RunE(snapshotSaveCmd, nil); err != nil {
+		t.Fatalf("snapshot save --price failed: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "Fetching") {
+		t.Errorf("Expected no price fetch, got:\n%s", out)
+	}
+	if !strings.Contains(errBuf.String(), "DOGE not in the portfolio") {
+		t.Errorf("Expected a warning for DOGE, got: %s", errBuf.String())
+	}
+	snapshots, _ := listSnapshots()
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	snap := snapshots[0]
+	if snap.NetValue != 103800 || snap.ProfitLoss != 47800 {
+		t.Errorf("Expected the snapshot valued at the given prices, got %+v", snap)
+	}
+	if btc := snap.CoinValues["BTC"]; btc.PriceUSD != 97000 || !btc.Manual || btc.GeckoID != "" {
+		t.Errorf("Expected BTC marked as priced by hand, got %+v", btc)
+	}
+
+	prices.Value.(pflag.SliceValue).Replace([]string{"BTC=-1"})
+	if err := snapshotSaveCmd.RunE(snapshotSaveCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for a negative price, got %v", err)
+	}
+	prices.Value.(pflag.SliceValue).Replace([]string{"BTC"})
+	if err := snapshotSaveCmd.RunE(snapshotSaveCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for a price without a value, got %v", err)
+	}
+}
+
+// TestNextSnapshotTime tests daemon schedule computation
+func TestNextSnapshotTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		every   time.Duration
+		at      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"interval", 6 * time.Hour, "", now.Add(6 * time.Hour), false},
+		{"daily later today", 0, "18:00", time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC), false},
+		{"daily tomorrow", 0, "09:00", time.Date(2024, 5, 11, 9, 0, 0, 0, time.UTC), false},
+		{"daily at now rolls over", 0, "14:30", time.Date(2024, 5, 11, 14, 30, 0, 0, time.UTC), false},
+		{"invalid time", 0, "25:99", time.Time{}, true},
+		{"too short", time.Second, "", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextSnapshotTime(now, tt.every, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nextSnapshotTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("nextSnapshotTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSummaryNetValueChart tests the snapshot chart at the top of the summary
+func TestSummaryNetValueChart(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	for i, value := range []float64{1000, 1500, 1200} {
+		snap := models.NewSnapshot(time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC), "")
+		snap.NetValue = value
+		store.Add(snap)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	summaryCmd.RunE(summaryCmd, []string{})
+	output := buf.String()
+	if !strings.Contains(output, "NET VALUE HISTORY") {
+		t.Errorf("Expected net value chart, got: %s", output)
+	}
+	if !strings.Contains(output, "2024-01-01 to 2024-01-03, 3 snapshots") {
+		t.Errorf("Expected chart caption with date range, got: %s", output)
+	}
+
+	buf.Reset()
+	summaryCmd.Flags().Set("no-chart", "true")
+	defer summaryCmd.Flags().Set("no-chart", "false")
+	summaryCmd.RunE(summaryCmd, []string{})
+	if strings.Contains(buf.String(), "NET VALUE HISTORY") {
+		t.Error("Expected chart to be hidden with --no-chart")
+	}
+}
+
+// TestDownsampleSnapshots tests thinning snapshots to one per time bucket
+func TestDownsampleSnapshots(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var hourly []models.Snapshot
+	for i := range 24 * 30 {
+		hourly = append(hourly, models.Snapshot{Timestamp: start.Add(time.Duration(i) * time.Hour), NetValue: float64(i)})
+	}
+
+	sampled := downsampleSnapshots(hourly, 60)
+	if len(sampled) != 60 {
+		t.Fatalf("Expected 60 snapshots, got %d", len(sampled))
+	}
+	if last := sampled[len(sampled)-1]; last.NetValue != hourly[len(hourly)-1].NetValue {
+		t.Errorf("Expected the latest snapshot to be kept, got %v", last.NetValue)
+	}
+	for i := 1; i < len(sampled); i++ {
+		if !sampled[i].Timestamp.After(sampled[i-1].Timestamp) {
+			t.Fatalf("Expected snapshots in order, got %v after %v", sampled[i].Timestamp, sampled[i-1].Timestamp)
+		}
+	}
+
+	// A burst of snapshots within a few minutes counts as one point in time
+	burst := append([]models.Snapshot{hourly[0]}, hourly[len(hourly)-3:]...)
+	if got := downsampleSnapshots(burst, 2); len(got) != 2 || got[1].NetValue != hourly[len(hourly)-1].NetValue {
+		t.Errorf("Expected the first and latest snapshot, got %v", got)
+	}
+
+	if got := downsampleSnapshots(hourly[:10], 60); len(got) != 10 {
+		t.Errorf("Expected few snapshots to be kept, got %d", len(got))
+	}
+}
+
+// TestSnapshotRange tests limiting snapshot charts and lists with --range
+func TestSnapshotRange(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	now := time.Now()
+	for i, age := range []int{400, 60, 20, 3, 1} {
+		snap := models.NewSnapshot(now.AddDate(0, 0, -age), fmt.Sprintf("snap%d", i))
+		snap.NetValue = float64(1000 * (i + 1))
+		store.Add(snap)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	summaryCmd.Flags().Set("no-prices", "true")
+	summaryCmd.Flags().Set("range", "30d")
+	defer func() {
+		summaryCmd.Flags().Set("no-prices", "false")
+		summaryCmd.Flags().Set("range", "all")
+	}()
+	if err := summaryCmd.RunE(summaryCmd, []string{}); err != nil {
+		t.Fatalf("summary failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), ", 3 snapshots") {
+		t.Errorf("Expected the chart to use the last 30 days' 3 snapshots, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	snapshotListCmd.Flags().Set("range", "90d")
+	defer snapshotListCmd.Flags().Set("range", "all")
+	if err := snapshotListCmd.RunE(snapshotListCmd, []string{}); err != nil {
+		t.Fatalf("snapshot list failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "snap0") || !strings.Contains(buf.String(), "snap1") || !strings.Contains(buf.String(), "snap4") {
+		t.Errorf("Expected all but the oldest snapshot, got:\n%s", buf.String())
+	}
+
+	snapshotListCmd.Flags().Set("range", "2w")
+	if err := snapshotListCmd.RunE(snapshotListCmd, []string{}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for --range 2w, got %v", err)
+	}
+}
+
+// TestSnapshotChart tests charting snapshot values with their stats
+func TestSnapshotChart(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	if err := snapshotChartCmd.RunE(snapshotChartCmd, []string{}); err != nil {
+		t.Fatalf("snapshot chart failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "At least two snapshots") {
+		t.Errorf("Expected a hint without snapshots, got:\n%s", buf.String())
+	}
+
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	for i, net := range []float64{1000, 1400, 700, 900} {
+		snap := models.NewSnapshot(start.AddDate(0, 0, i), "")
+		snap.HoldingsValue, snap.LoansValue, snap.NetValue = net+100, 100, net
+		store.Add(snap)
+	}
+
+	buf.Reset()
+	if err := snapshotChartCmd.RunE(snapshotChartCmd, []string{}); err != nil {
+		t.Fatalf("snapshot chart failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Net", "Holdings", "Loans", "4 snapshots",
+		"Best Day:       2024-03-02 +$400.00", "Worst Day:      2024-03-03 -$700.00", "Max Drawdown:   -$700.00 (-50.0%)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+// TestStatsCommand tests risk statistics across snapshots
+func TestStatsCommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	if err := statsCmd.RunE(statsCmd, []string{}); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "At least two snapshots") {
+		t.Errorf("Expected a hint without snapshots, got:\n%s", buf.String())
+	}
+
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	start := time.Now().AddDate(0, 0, -10)
+	for i, net := range []float64{1000, 1100, 990, 1089} {
+		snap := models.NewSnapshot(start.AddDate(0, 0, i), "")
+		snap.NetValue = net
+		store.Add(snap)
+	}
+
+	buf.Reset()
+	if err := statsCmd.RunE(statsCmd, []string{}); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"4 snapshots", "Best Day:", "+$100.00", "Worst Day:", "-$110.00",
+		"Volatility:     11.55% daily, over 3 days", "Max Drawdown:   -$110.00 (-10.0%)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	statsCmd.Flags().Set("range", "7d")
+	defer statsCmd.Flags().Set("range", "all")
+	if err := statsCmd.RunE(statsCmd, []string{}); err != nil {
+		t.Fatalf("stats --range failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "At least two snapshots") {
+		t.Errorf("Expected no snapshots in the last 7 days, got:\n%s", buf.String())
+	}
+}
+
+// TestRenderChart tests chart rendering and width capping
+func TestRenderChart(t *testing.T) {
+	values := make([]float64, 200)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	chart := renderChart(values, "caption")
+	if !strings.Contains(chart, "caption") {
+		t.Error("Expected caption in chart")
+	}
+	for _, line := range strings.Split(chart, "\n") {
+		if len([]rune(line)) > chartWidth()+20 {
+			t.Errorf("Expected chart lines capped near %d columns, got %d", chartWidth(), len([]rune(line)))
+			break
+		}
+	}
+}
+
+// TestRenderCandles tests candlestick rendering of rising and falling candles
+func TestRenderCandles(t *testing.T) {
+	candles := []prices.Candle{
+		{Open: 100, High: 190, Low: 100, Close: 180},
+		{Open: 180, High: 200, Low: 110, Close: 120},
+	}
+	lines := strings.Split(renderCandles(candles, "caption"), "\n")
+	if len(lines) != chartHeight+1 || lines[chartHeight] != "     caption" {
+		t.Fatalf("Expected %d rows and a caption, got:\n%s", chartHeight, strings.Join(lines, "\n"))
+	}
+	// Top row: the second candle's wick; bottom row: the first candle's open
+	if lines[0] != "200 ┤  │ " {
+		t.Errorf("Unexpected top row %q", lines[0])
+	}
+	if lines[chartHeight-1] != "100 ┤█   " {
+		t.Errorf("Unexpected bottom row %q", lines[chartHeight-1])
+	}
+	if !strings.Contains(lines[5], "█ ░") {
+		t.Errorf("Expected both bodies in the middle row, got %q", lines[5])
+	}
+
+	// Low prices get decimals
+	chart := renderCandles([]prices.Candle{{Open: 0.08, High: 0.09, Low: 0.07, Close: 0.085}}, "")
+	if !strings.HasPrefix(chart, "0.090 ┤") {
+		t.Errorf("Expected labels with decimals, got:\n%s", chart)
+	}
+}
+
+// TestCoinPriceHistory tests the coin price history chart
+func TestCoinPriceHistory(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/coins/bitcoin/ohlc" || r.URL.Query().Get("days") != "90" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[[1704067200000, 42000, 43000, 41000, 42500], [1704412800000, 42500, 45000, 42000, 44000]]`))
+	}))
+	defer server.Close()
+	ps := prices.NewWithClient(&http.Client{Transport: serverTransport{server.URL}})
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	printPriceHistory(ps, "BTC", 90)
+	out := buf.String()
+	if !strings.Contains(out, "PRICE HISTORY:") || !strings.Contains(out, "BTC price (USD), last 90 days, 4-day candles to 2024-01-05 00:00 UTC") {
+		t.Errorf("Expected a price history chart, got:\n%s", out)
+	}
+
+	coinCmd.Flags().Set("days", "14")
+	defer coinCmd.Flags().Set("days", "30")
+	if err := coinCmd.RunE(coinCmd, []string{"BTC"}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for --days 14, got %v", err)
+	}
+}
+
+// TestCoinHoldingsChanges tests marking changes in the amount held in the
+// coin value chart and snapshot history
+func TestCoinHoldingsChanges(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	for i, amount := range []float64{1, 1, 1.5, 0.5} {
+		snap := models.NewSnapshot(start.AddDate(0, 0, i), "")
+		snap.CoinValues = map[string]models.CoinSnapshot{"BTC": {Amount: amount, PriceUSD: 40000, ValueUSD: amount * 40000}}
+		store.Add(snap)
+	}
+	snapshots, err := listSnapshots()
+	if err != nil {
+		t.Fatalf("Failed to list snapshots: %v", err)
+	}
+
+	if got := findHoldingsChangeIndices(snapshots, "BTC"); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("Expected changes at 2 and 3, got %v", got)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	printCoinValueChart(snapshots, "BTC")
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	markers := lines[len(lines)-2]
+	axis := strings.IndexAny(lines[len(lines)-3], "┤┼")
+	if prefix := len([]rune(lines[len(lines)-3][:axis])); strings.TrimSpace(markers) != "↑↓" || len([]rune(markers)) != prefix+4 {
+		t.Errorf("Expected markers under the last two points, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printCoinHistory(snapshots, "BTC")
+	out := buf.String()
+	for _, want := range []string{"SNAPSHOT HISTORY:", "↑ +0.5 bought/unstaked", "↓ -1 sold/staked"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "↑")+strings.Count(out, "↓") != 2 {
+		t.Errorf("Expected only changed rows to be annotated, got:\n%s", out)
+	}
+}
+
+// TestBreakEvenDisplay tests break-even prices and average costs in summary
+// and coin detail
+func TestBreakEvenDisplay(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(4), dec(1000), "", "", "2024-01-01")
+	p.AddSale("ETH", dec(2), dec(1500), "", "", "2024-02-01")
+	p.AddHolding("BTC", dec(1), dec(10000), "", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.5), dec(30000), "", "", "2024-02-01")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	summaryCmd.Flags().Set("no-prices", "true")
+	defer summaryCmd.Flags().Set("no-prices", "false")
+	if err := summaryCmd.RunE(summaryCmd, []string{}); err != nil {
+		t.Fatalf("summary failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "break-even $500.00") || !strings.Contains(out, "break-even recovered") {
+		t.Errorf("Expected ETH break-even and BTC recovered, got:\n%s", out)
+	}
+	// FIFO: the $1000 ETH lot's remaining 2 coins, and half the BTC lot
+	if !strings.Contains(out, "avg cost $1,000.00") || !strings.Contains(out, "avg cost $10,000.00") {
+		t.Errorf("Expected average costs of the coins held, got:\n%s", out)
+	}
+
+	buf.Reset()
+	coinCmd.Flags().Set("no-prices", "true")
+	defer coinCmd.Flags().Set("no-prices", "false")
+	if err := coinCmd.RunE(coinCmd, []string{"ETH"}); err != nil {
+		t.Fatalf("coin failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Break-Even:     $500.00") {
+		t.Errorf("Expected ETH break-even in coin detail, got:\n%s", buf.String())
+	}
+
+	b := portfolio.BreakEven{NetInvestedUSD: dec(1000), PriceUSD: dec(500)}
+	if got := formatBreakEven(b, 400, true, 1); got != "$500.00 (price 20.0% below)" {
+		t.Errorf("Unexpected break-even below: %q", got)
+	}
+	if got := formatBreakEven(b, 600, true, 1); got != "$500.00 (price 20.0% above)" {
+		t.Errorf("Unexpected break-even above: %q", got)
+	}
+}
+
+// TestAddWizard tests adding records by answering prompts
+func TestAddWizard(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	oldStdin := osStdin
+	defer func() { osStdin = oldStdin }()
+	buf, restore := captureOutput()
+	defer restore()
+
+	// The default type; "us" could be USDC or USDT, "bt" is completed to BTC
+	osStdin = strings.NewReader("\nus\nbt\n-1\n0.5\n=20000\nLedger\n2024-03-01\ny\n")
+	if err := addCmd.RunE(addCmd, []string{}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"US could be USDC, USDT", "  BTC\n", "is not a number above zero", "Add buy 0.5 BTC @ $40,000.00 on Ledger, 2024-03-01?", "Bought 0.5 BTC @ $40,000.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 1 || holdings[0].Platform != "Ledger" || holdings[0].Date.String() != "2024-03-01" {
+		t.Errorf("Expected the purchase to be added, got %+v", holdings)
+	}
+
+	// Stakes need a platform; the date defaults to today
+	buf.Reset()
+	osStdin = strings.NewReader("stake\nbtc\n0.25\n\nLido\n\ny\n")
+	if err := addCmd.RunE(addCmd, []string{}); err != nil {
+		t.Fatalf("add stake failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "a stake needs a platform") || !strings.Contains(buf.String(), "Staked 0.25 BTC on Lido") {
+		t.Errorf("Unexpected stake output:\n%s", buf.String())
+	}
+
+	// Declining adds nothing; input ending early is an error
+	osStdin = strings.NewReader("sell\nBTC\n0.1\n50000\n\n\nn\n")
+	if err := addCmd.RunE(addCmd, []string{}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if sales, _ := p.ListSales(); len(sales) != 0 {
+		t.Errorf("Expected no sale after declining, got %+v", sales)
+	}
+	osStdin = strings.NewReader("buy\nETH\n")
+	if err := addCmd.RunE(addCmd, []string{}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error when input ends early, got %v", err)
+	}
+
+	nonInteractive = true
+	defer func() { nonInteractive = false }()
+	if err := addCmd.RunE(addCmd, []string{}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error with --non-interactive, got %v", err)
+	}
+}
+
+// TestHistoryCommand tests the unified history ledger and its filters
+func TestHistoryCommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.25), dec(60000), "Coinbase", "", "2024-02-01")
+	p.AddLoan("USDC", dec(5000), "Nexo", nil, "", "2024-01-15")
+
+	t.Run("all transactions", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		historyCmd.RunE(historyCmd, []string{})
+		output := buf.String()
+		for _, want := range []string{"BUY", "SELL", "LOAN", "-0.25", "0.75"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+		if strings.Index(output, "LOAN") > strings.Index(output, "SELL") {
+			t.Error("Expected transactions in chronological order")
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		historyCmd.Flags().Set("coin", "usdc")
+		defer historyCmd.Flags().Set("coin", "")
+		historyCmd.RunE(historyCmd, []string{})
+		output := buf.String()
+		if strings.Contains(output, "BTC") || !strings.Contains(output, "USDC") {
+			t.Errorf("Expected only USDC transactions, got: %s", output)
+		}
+	})
+}
+
+func TestBuyListFilteredAndSorted(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("ETH", dec(10), dec(3000), "Binance", "", "2024-02-01")
+	p.AddHolding("BTC", dec(0.1), dec(60000), "Binance", "", "2024-03-01")
+
+	t.Run("filter by platform", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		buyListCmd.Flags().Set("platform", "binance")
+		defer buyListCmd.Flags().Set("platform", "")
+		buyListCmd.RunE(buyListCmd, []string{})
+		output := buf.String()
+		if strings.Contains(output, "Coinbase") || !strings.Contains(output, "ETH") {
+			t.Errorf("Expected only Binance purchases, got: %s", output)
+		}
+	})
+
+	t.Run("sort by value descending", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		buyListCmd.Flags().Set("sort", "value")
+		buyListCmd.Flags().Set("reverse", "true")
+		defer buyListCmd.Flags().Set("sort", "date")
+		defer buyListCmd.Flags().Set("reverse", "false")
+		buyListCmd.RunE(buyListCmd, []string{})
+		output := buf.String()
+		if strings.Index(output, "$50,000.00") > strings.Index(output, "$30,000.00") ||
+			strings.Index(output, "$30,000.00") > strings.Index(output, "$6,000.00") {
+			t.Errorf("Expected purchases sorted by value descending, got: %s", output)
+		}
+		if !strings.Contains(output, "Total USD ↓") || strings.Contains(output, "Date ↑") {
+			t.Errorf("Expected the value column marked descending, got: %s", output)
+		}
+	})
+
+	t.Run("default sort marked", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		buyListCmd.RunE(buyListCmd, []string{})
+		if !strings.Contains(buf.String(), "Date ↑") {
+			t.Errorf("Expected the date column marked ascending, got: %s", buf.String())
+		}
+	})
+}
+
+func TestStakeListSearch(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(10), dec(3000), "Binance", "", "2024-01-01")
+	p.AddHolding("SOL", dec(100), dec(100), "Binance", "", "2024-01-01")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "2024-02-01")
+	p.AddStake("SOL", dec(50), "Marinade", nil, "", "2024-02-01")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	stakeListCmd.Flags().Set("search", "lid")
+	defer stakeListCmd.Flags().Set("search", "")
+	stakeListCmd.RunE(stakeListCmd, []string{})
+	output := buf.String()
+	if strings.Contains(output, "Marinade") || !strings.Contains(output, "Lido") {
+		t.Errorf("Expected only the Lido stake, got: %s", output)
+	}
+	if !strings.Contains(output, "1 matching stake\n") {
+		t.Errorf("Expected match count, got: %s", output)
+	}
+}
+
+func TestLoanListPaging(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "2024-01-01")
+	p.AddLoan("USDT", dec(2000), "Nexo", nil, "", "2024-02-01")
+	p.AddLoan("DAI", dec(3000), "Aave", nil, "", "2024-03-01")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	loanListCmd.Flags().Set("limit", "2")
+	loanListCmd.Flags().Set("page", "2")
+	defer loanListCmd.Flags().Set("limit", "0")
+	defer loanListCmd.Flags().Set("page", "1")
+	loanListCmd.RunE(loanListCmd, []string{})
+	output := buf.String()
+	if strings.Contains(output, "USDC") || !strings.Contains(output, "DAI") {
+		t.Errorf("Expected only the third loan on page 2, got: %s", output)
+	}
+	if !strings.Contains(output, "Page 2 of 2 (rows 3-3 of 3)") {
+		t.Errorf("Expected page indicator, got: %s", output)
+	}
+}
+
+func TestSummaryProjectedYield(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	apy := 5.0
+	p.AddHolding("ETH", dec(20), dec(3000), "Binance", "", "")
+	p.AddStake("ETH", dec(12), "Lido", &apy, "", "")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	summaryCmd.Flags().Set("no-prices", "true")
+	defer summaryCmd.Flags().Set("no-prices", "false")
+	summaryCmd.RunE(summaryCmd, []string{})
+	output := buf.String()
+	for _, want := range []string{"PROJECTED STAKING YIELD", "5.00% APY", "0.6/yr", "0.05/mo"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+}
+
+func TestTransferCommands(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Binance", "", "2024-01-01")
+
+	transferAddCmd.Flags().Set("fee", "0.001")
+	defer transferAddCmd.Flags().Set("fee", "0")
+	transferAddCmd.RunE(transferAddCmd, []string{"BTC", "0.4", "Binance", "Ledger"})
+
+	t.Run("transfer list", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		transferListCmd.RunE(transferListCmd, []string{})
+		output := buf.String()
+		for _, want := range []string{"BTC", "0.4", "0.001", "Binance", "Ledger"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("platforms", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		platformsCmd.RunE(platformsCmd, []string{})
+		output := buf.String()
+		if !strings.Contains(output, "0.6") || !strings.Contains(output, "0.399") {
+			t.Errorf("Expected 0.6 BTC on Binance and 0.399 on Ledger, got: %s", output)
+		}
+	})
+
+	t.Run("transfer remove", func(t *testing.T) {
+		transfers, _ := p.ListTransfers()
+		if len(transfers) != 1 {
+			t.Fatalf("Expected 1 transfer, got %d", len(transfers))
+		}
+		assumeYes = true
+		defer func() { assumeYes = false }()
+		transferRemoveCmd.RunE(transferRemoveCmd, []string{transfers[0].ID})
+		transfers, _ = p.ListTransfers()
+		if len(transfers) != 0 {
+			t.Errorf("Expected 0 transfers after removal, got %d", len(transfers))
+		}
+	})
+}
+
+func TestSwapCommands(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(10), dec(2000), "Binance", "", "2024-01-01")
+
+	swapAddCmd.Flags().Set("value", "9000")
+	defer swapAddCmd.Flags().Set("value", "0")
+	swapAddCmd.RunE(swapAddCmd, []string{"ETH", "3", "SOL", "60"})
+
+	swaps, _ := p.ListSwaps()
+	if len(swaps) != 1 || !swaps[0].ValueUSD.Equal(dec(9000)) {
+		t.Fatalf("Expected 1 swap worth 9000, got %+v", swaps)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	swapListCmd.RunE(swapListCmd, []string{})
+	output := buf.String()
+	for _, want := range []string{"ETH", "SOL", "60", "$9,000.00"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+
+	assumeYes = true
+	defer func() { assumeYes = false }()
+	swapRemoveCmd.RunE(swapRemoveCmd, []string{swaps[0].ID})
+	swaps, _ = p.ListSwaps()
+	if len(swaps) != 0 {
+		t.Errorf("Expected 0 swaps after removal, got %d", len(swaps))
+	}
+}
+
+func TestTrashCommands(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	h, _ := p.AddHolding("BTC", dec(1.5), dec(50000), "", "", "2024-01-01")
+	p.RemoveHolding(h.ID)
+
+	t.Run("trash list", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		trashListCmd.RunE(trashListCmd, []string{})
+		output := buf.String()
+		for _, want := range []string{h.ID[:minShortID], "holding", "BTC", "1.5"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("trash restore", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		trashRestoreCmd.RunE(trashRestoreCmd, []string{h.ID})
+		if !strings.Contains(buf.String(), "Restored holding "+h.ID) {
+			t.Errorf("Expected restore message, got: %s", buf.String())
+		}
+		holdings, _ := p.ListHoldings()
+		if len(holdings) != 1 || holdings[0].ID != h.ID {
+			t.Errorf("Expected holding restored, got %+v", holdings)
+		}
+	})
+
+	t.Run("trash restore not found", func(t *testing.T) {
+		err := trashRestoreCmd.RunE(trashRestoreCmd, []string{h.ID})
+		if err == nil || !strings.Contains(err.Error(), "not found in trash") {
+			t.Errorf("Expected not found error, got: %v", err)
+		}
+		if code := exitCode(err); code != exitNotFound {
+			t.Errorf("Expected exit code %d, got %d", exitNotFound, code)
+		}
+	})
+
+	t.Run("trash list empty", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		trashListCmd.RunE(trashListCmd, []string{})
+		if !strings.Contains(buf.String(), "Trash is empty") {
+			t.Errorf("Expected empty trash message, got: %s", buf.String())
+		}
+	})
+}
+
+func TestDCACommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "", "", "2024-01-01")
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-02-01")
+
+	dcaCmd.Flags().Set("no-prices", "true")
+	defer dcaCmd.Flags().Set("no-prices", "false")
+
+	t.Run("dca stats", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		dcaCmd.RunE(dcaCmd, []string{"btc"})
+		output := buf.String()
+		for _, want := range []string{"BTC DCA", "2 (2024-01-01 to 2024-02-01)", "$80,000.00", "Average Price:  $40,000.00", "Break-even:     $40,000.00"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("dca no purchases", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		dcaCmd.RunE(dcaCmd, []string{"SOL"})
+		if !strings.Contains(buf.String(), "No purchases of SOL found") {
+			t.Errorf("Expected no purchases message, got: %s", buf.String())
+		}
+	})
+}
+
+func TestCoinCommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "Binance", "", "2024-01-01")
+	p.AddSale("ETH", dec(1), dec(3000), "Binance", "", "2024-02-01")
+
+	coinCmd.Flags().Set("no-prices", "true")
+	coinCmd.Flags().Set("no-chart", "true")
+	defer coinCmd.Flags().Set("no-prices", "false")
+	defer coinCmd.Flags().Set("no-chart", "false")
+
+	t.Run("coin detail", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		coinCmd.RunE(coinCmd, []string{"eth"})
+		output := buf.String()
+		for _, want := range []string{"=== ETH ===", "BUY", "SELL", "Held:           1", "Cost Basis:     $1,000.00", "Realized P/L:   +$2,000.00"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("coin without records", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		coinCmd.RunE(coinCmd, []string{"DOGE"})
+		if !strings.Contains(buf.String(), "No records found for DOGE") {
+			t.Errorf("Expected no records message, got: %s", buf.String())
+		}
+	})
+}
+
+func TestSnapshotCompare(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	older := models.NewSnapshot(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "")
+	older.HoldingsValue, older.NetValue = 1000, 1000
+	older.CoinValues["BTC"] = models.CoinSnapshot{Amount: 0.1, PriceUSD: 10000, ValueUSD: 1000}
+	newer := models.NewSnapshot(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "")
+	newer.HoldingsValue, newer.NetValue = 1500, 1500
+	newer.CoinValues["BTC"] = models.CoinSnapshot{Amount: 0.125, PriceUSD: 12000, ValueUSD: 1500}
+	store.Add(older)
+	store.Add(newer)
+
+	t.Run("snapshot compare", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		snapshotCompareCmd.RunE(snapshotCompareCmd, []string{newer.ID, older.ID})
+		output := buf.String()
+		for _, want := range []string{older.ID + " (2024-01-01) -> " + newer.ID, "+0.025", "+20.0%", "+$500.00", "+$500.00, +50.0%"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("snapshot compare not found", func(t *testing.T) {
+		err := snapshotCompareCmd.RunE(snapshotCompareCmd, []string{older.ID, "missing"})
+		if err == nil || !strings.Contains(err.Error(), "snapshot missing not found") {
+			t.Errorf("Expected not found error, got: %v", err)
+		}
+		if code := exitCode(err); code != exitNotFound {
+			t.Errorf("Expected exit code %d, got %d", exitNotFound, code)
+		}
+	})
+}
+
+func TestSaveDailySnapshot(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	// Empty portfolio needs no price fetch
+	now := time.Now()
+	snap, saved, err := saveDailySnapshot(now)
+	if err != nil || !saved {
+		t.Fatalf("Expected first daily snapshot saved, got %v, %v", saved, err)
+	}
+	if snap.Note != "daily" {
+		t.Errorf("Expected daily note, got %q", snap.Note)
+	}
+
+	if _, saved, err := saveDailySnapshot(now); err != nil || saved {
+		t.Errorf("Expected no second snapshot the same day, got %v, %v", saved, err)
+	}
+	if _, saved, _ := saveDailySnapshot(now.AddDate(0, 0, 1)); !saved {
+		t.Error("Expected a snapshot on the next day")
+	}
+
+	snapshots, _ := listSnapshots()
+	if len(snapshots) != 2 {
+		t.Errorf("Expected 2 snapshots, got %d: %s", len(snapshots), buf.String())
+	}
+}
+
+// fakeExchange is an exchange client returning fixed data
+type fakeExchange struct {
+	balances map[string]float64
+	trades   []exchange.Trade
+}
+
+func (f fakeExchange) Name() string                          { return "Fake" }
+func (f fakeExchange) Balances() (map[string]float64, error) { return f.balances, nil }
+func (f fakeExchange) Trades([]string) ([]exchange.Trade, error) {
+	return f.trades, nil
+}
+
+func TestSyncExchange(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	oldStdin := osStdin
+	defer func() { osStdin = oldStdin }()
+
+	client := fakeExchange{
+		balances: map[string]float64{"BTC": 0.5},
+		trades: []exchange.Trade{
+			{Exchange: "fake", ID: "1", Coin: "BTC", Side: exchange.SideBuy, Amount: dec(1), PriceUSD: dec(40000), Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+			{Exchange: "fake", ID: "2", Coin: "BTC", Side: exchange.SideSell, Amount: dec(0.5), PriceUSD: dec(50000), Time: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	t.Run("sync cancelled", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		osStdin = strings.NewReader("n\n")
+		syncExchange(client)
+		output := buf.String()
+		for _, want := range []string{"FAKE BALANCES", "BTC:", "fake trade 1", "Import 2 records?", "Cancelled."} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected %q in output, got: %s", want, output)
+			}
+		}
+		if holdings, _ := p.ListHoldings(); len(holdings) != 0 {
+			t.Errorf("Expected nothing imported, got %+v", holdings)
+		}
+	})
+
+	t.Run("sync confirmed", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		osStdin = strings.NewReader("y\n")
+		syncExchange(client)
+		if !strings.Contains(buf.String(), "Imported 1 purchases and 1 sales from Fake") {
+			t.Errorf("Expected import message, got: %s", buf.String())
+		}
+		holdings, _ := p.ListHoldings()
+		sales, _ := p.ListSales()
+		if len(holdings) != 1 || len(sales) != 1 || holdings[0].Platform != "Fake" {
+			t.Errorf("Expected imported records, got %+v %+v", holdings, sales)
+		}
+	})
+
+	t.Run("sync again", func(t *testing.T) {
+		buf, restore := captureOutput()
+		defer restore()
+
+		syncExchange(client)
+		if !strings.Contains(buf.String(), "No new trades to import (2 already imported)") {
+			t.Errorf("Expected nothing new, got: %s", buf.String())
+		}
+	})
+}
+
+func TestExitCodes(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"plain error", errors.New("boom"), exitFailure},
+		{"usage", usageErrorf("bad flag"), exitUsage},
+		{"not found", notFoundErrorf("missing"), exitNotFound},
+		{"io", ioError(errors.New("disk full")), exitIO},
+		{"wrapped usage", fmt.Errorf("context: %w", usageErrorf("bad")), exitUsage},
+		{"path error", &fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}, exitIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid amount", func(t *testing.T) {
+		err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "abc", "100"})
+		if code := exitCode(err); code != exitUsage {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitUsage, code, err)
+		}
+	})
+
+	t.Run("portfolio validation", func(t *testing.T) {
+		err := stakeAddCmd.RunE(stakeAddCmd, []string{"ETH", "5", "Lido"})
+		if code := exitCode(err); code != exitUsage {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitUsage, code, err)
+		}
+	})
+
+	t.Run("remove unknown ID", func(t *testing.T) {
+		err := buyRemoveCmd.RunE(buyRemoveCmd, []string{"missing"})
+		if code := exitCode(err); code != exitNotFound {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitNotFound, code, err)
+		}
+	})
+
+	t.Run("wrong argument count", func(t *testing.T) {
+		err := swapRemoveCmd.Args(swapRemoveCmd, []string{})
+		if code := exitCode(err); code != exitUsage {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitUsage, code, err)
+		}
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	oldStdin := osStdin
+	defer func() {
+		osStdin = oldStdin
+		assumeYes, nonInteractive = false, false
+	}()
+	_, restore := captureOutput()
+	defer restore()
+
+	osStdin = strings.NewReader("yes\n")
+	if ok, err := confirm("Proceed?"); !ok || err != nil {
+		t.Errorf("Expected yes from input, got %v, %v", ok, err)
+	}
+
+	osStdin = strings.NewReader("\n")
+	if ok, err := confirm("Proceed?"); ok || err != nil {
+		t.Errorf("Expected no by default, got %v, %v", ok, err)
+	}
+
+	nonInteractive = true
+	if _, err := confirm("Proceed?"); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error in non-interactive mode, got %v", err)
+	}
+
+	assumeYes = true
+	osStdin = strings.NewReader("")
+	if ok, err := confirm("Proceed?"); !ok || err != nil {
+		t.Errorf("Expected --yes to answer yes, got %v, %v", ok, err)
+	}
+}
+
+func TestCompletion_RecordIDs(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	h, _ := p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-15")
+	h2, _ := p.AddHolding("ETH", dec(10.0), dec(3000), "Ledger", "", "")
+
+	comps, directive := buyRemoveCmd.ValidArgsFunction(buyRemoveCmd, nil, h.ID[:4])
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected file completion to be disabled, got %v", directive)
+	}
+	if len(comps) != 1 || !strings.HasPrefix(comps[0], h.ID+"\t") {
+		t.Errorf("Expected completion for %s, got %v", h.ID, comps)
+	}
+	if !strings.Contains(comps[0], "BTC") {
+		t.Errorf("Expected description to mention the coin, got %q", comps[0])
+	}
+
+	// IDs already given are not suggested again
+	comps, _ = buyRemoveCmd.ValidArgsFunction(buyRemoveCmd, []string{h.ID}, "")
+	if len(comps) != 1 || !strings.HasPrefix(comps[0], h2.ID+"\t") {
+		t.Errorf("Expected only %s, got %v", h2.ID, comps)
+	}
+
+	// Only the first argument of repay is an ID
+	comps, _ = loanRepayCmd.ValidArgsFunction(loanRepayCmd, []string{"x"}, "")
+	if len(comps) != 0 {
+		t.Errorf("Expected no completions for the amount, got %v", comps)
+	}
+}
+
+func TestCompletion_CoinsAndPlatforms(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
+	p.AddHolding("ETH", dec(10.0), dec(3000), "Ledger", "", "")
+
+	comps, _ := buyAddCmd.ValidArgsFunction(buyAddCmd, nil, "b")
+	if len(comps) != 1 || comps[0] != "BTC" {
+		t.Errorf("Expected [BTC], got %v", comps)
+	}
+
+	comps, _ = transferAddCmd.ValidArgsFunction(transferAddCmd, []string{"BTC", "0.5"}, "")
+	if len(comps) != 2 || comps[0] != "Coinbase" || comps[1] != "Ledger" {
+		t.Errorf("Expected [Coinbase Ledger], got %v", comps)
+	}
+
+	comps, _ = transferAddCmd.ValidArgsFunction(transferAddCmd, []string{"BTC"}, "")
+	if len(comps) != 0 {
+		t.Errorf("Expected no completions for the amount, got %v", comps)
+	}
+}
+
+func TestIsCompletionCmd(t *testing.T) {
+	if isCompletionCmd(buyRemoveCmd) {
+		t.Error("Expected buy remove not to be a completion command")
+	}
+	complete := &cobra.Command{Use: cobra.ShellCompRequestCmd}
+	if !isCompletionCmd(complete) {
+		t.Error("Expected __complete to be a completion command")
+	}
+	parent := &cobra.Command{Use: "completion"}
+	bash := &cobra.Command{Use: "bash"}
+	parent.AddCommand(bash)
+	if !isCompletionCmd(bash) {
+		t.Error("Expected completion bash to be a completion command")
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	snap := models.Snapshot{
+		HoldingsValue: 60000,
+		LoansValue:    1000,
+		NetValue:      59000,
+		CoinValues: map[string]models.CoinSnapshot{
+			"ETH": {Amount: 2, PriceUSD: 3000, ValueUSD: 6000},
+			"BTC": {Amount: 0.5, PriceUSD: 108000, ValueUSD: 54000},
+		},
+	}
+	var buf bytes.Buffer
+	writeMetrics(&buf, snapshotMetrics(snap, map[string]decimal.Decimal{"USDC": dec(1000)}))
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE follyo_holding_amount gauge\n",
+		"follyo_holding_amount{coin=\"BTC\"} 0.5\nfollyo_holding_amount{coin=\"ETH\"} 2\n",
+		"follyo_price_usd{coin=\"BTC\"} 108000\n",
+		"follyo_loan_amount{coin=\"USDC\"} 1000\n",
+		"follyo_net_value_usd 59000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// An empty portfolio needs no prices
+	rec := httptest.NewRecorder()
+	metricsHandler(&sync.Mutex{})(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected text/plain content type, got %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "follyo_net_value_usd 0\n") {
+		t.Errorf("Expected net value gauge, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestDailyChange(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 5, d, h, 0, 0, 0, time.Local) }
+	snapshots := []models.Snapshot{
+		{ID: "a", Timestamp: day(8, 9), NetValue: 500},
+		{ID: "b", Timestamp: day(9, 9), NetValue: 1000},
+		{ID: "c", Timestamp: day(10, 9), NetValue: 1020},
+		{ID: "d", Timestamp: day(10, 15), NetValue: 1100},
+		{ID: "e", Timestamp: day(10, 21), NetValue: 880},
+	}
+
+	// Compared with the last snapshot of the previous day
+	prev, percent, ok := dailyChange(snapshots, snapshots[3], 5)
+	if !ok || prev.ID != "b" || math.Abs(percent-10) > 1e-9 {
+		t.Errorf("Expected +10%% since b, got %s %v %v", prev.ID, percent, ok)
+	}
+	if _, _, ok := dailyChange(snapshots, snapshots[2], 5); ok {
+		t.Error("Expected +2% to stay below the threshold")
+	}
+	// d already crossed the threshold today
+	if _, _, ok := dailyChange(snapshots, snapshots[4], 5); ok {
+		t.Error("Expected only one notification per day")
+	}
+	if _, _, ok := dailyChange(snapshots, snapshots[0], 5); ok {
+		t.Error("Expected no change without an earlier day")
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	data := notifyData{Event: eventPriceAlert, Alert: config.PriceAlert{Coin: "BTC", Above: 100000}, Price: 101000}
+	body, err := renderNotification("", data)
+	if err != nil || body != "BTC is $101,000.00, above $100,000.00" {
+		t.Errorf("Unexpected default body %q, %v", body, err)
+	}
+
+	body, err = renderNotification("{{.Alert.Coin}} hit {{usd .Price}} ({{percent 2.5}})", data)
+	if err != nil || body != "BTC hit $101,000.00 (+2.5%)" {
+		t.Errorf("Unexpected custom body %q, %v", body, err)
+	}
+
+	if _, err := renderNotification("{{.Missing}}", data); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestCheckPriceAlerts(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, err := config.New(filepath.Join(tmpDir, "config.json"))
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	cfg.AddPriceAlert(config.PriceAlert{Coin: "BTC", Above: 100000})
+	cfg.AddPriceAlert(config.PriceAlert{Coin: "ETH", Below: 2000})
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Message string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		bodies = append(bodies, msg.Message)
+	}))
+	defer server.Close()
+
+	_, restore := captureOutput()
+	defer restore()
+
+	n := newNotifier(config.Notifications{Webhook: server.URL})
+	fired, err := checkPriceAlerts(cfg, n, nil, map[string]float64{"BTC": 105000, "ETH": 2500})
+	if err != nil {
+		t.Fatalf("checkPriceAlerts failed: %v", err)
+	}
+	if fired != 1 || len(bodies) != 1 || bodies[0] != "BTC is $105,000.00, above $100,000.00" {
+		t.Errorf("Expected the BTC alert to fire once, got %d: %v", fired, bodies)
+	}
+	if alerts := cfg.GetPriceAlerts(); len(alerts) != 1 || alerts[0].Coin != "ETH" {
+		t.Errorf("Expected the fired alert to be removed, got %+v", alerts)
+	}
+}
+
+func TestTopMovers(t *testing.T) {
+	market := map[string]prices.MarketData{
+		"BTC": {Change24h: 2.5},
+		"ETH": {Change24h: -8},
+		"SOL": {Change24h: 5},
+		"ADA": {Change24h: 5},
+	}
+	got := topMovers(market, 3)
+	want := []string{"ETH", "ADA", "SOL"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := topMovers(nil, 5); len(got) != 0 {
+		t.Errorf("Expected no movers, got %v", got)
+	}
+}
+
+func TestDashboardCommand_Empty(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	// An empty portfolio needs no prices
+	if err := dashboardCmd.RunE(dashboardCmd, nil); err != nil {
+		t.Fatalf("dashboard failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"=== DASHBOARD ===", "Net value:  $0.00", "TOP MOVERS (24h):\n  (none)", "RECENT TRANSACTIONS:\n  (none)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRemoveCommands_Batch(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer func() { assumeYes, nonInteractive = false, false }()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	h1, _ := p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
+	h2, _ := p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
+	h3, _ := p.AddHolding("ETH", dec(10.0), dec(3000), "Ledger", "", "")
+
+	// An unknown ID removes nothing
+	err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h1.ID, "missing"})
+	if exitCode(err) != exitNotFound {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 3 {
+		t.Errorf("Expected nothing removed, got %d holdings", len(holdings))
+	}
+
+	// Several IDs need confirmation
+	nonInteractive = true
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h1.ID, h2.ID}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error without --yes, got %v", err)
+	}
+
+	assumeYes = true
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h1.ID, h2.ID, h1.ID}); err != nil {
+		t.Fatalf("batch remove failed: %v", err)
+	}
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 1 || holdings[0].ID != h3.ID {
+		t.Errorf("Expected only %s left, got %+v", h3.ID, holdings)
+	}
+	if got := strings.Count(buf.String(), "Removed purchase"); got != 2 {
+		t.Errorf("Expected 2 removal messages, got %d:\n%s", got, buf.String())
+	}
+
+	// A single ID is confirmed with its details too
+	assumeYes, nonInteractive = false, false
+	oldStdin := osStdin
+	defer func() { osStdin = oldStdin }()
+	buf.Reset()
+	osStdin = strings.NewReader("n\n")
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h3.ID}); err != nil {
+		t.Fatalf("declined remove failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Remove purchase 10 ETH bought "+h3.Date.String()+" on Ledger? [y/N]") || !strings.Contains(buf.String(), "Nothing removed") {
+		t.Errorf("Expected a prompt with the record's details, got:\n%s", buf.String())
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 1 {
+		t.Errorf("Expected the declined purchase kept, got %d holdings", len(holdings))
+	}
+	osStdin = strings.NewReader("y\n")
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h3.ID}); err != nil {
+		t.Errorf("single remove failed: %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 0 {
+		t.Errorf("Expected no holdings left, got %d", len(holdings))
+	}
+}
+
+func TestRemoveCommands_Filter(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer func() { assumeYes, nonInteractive = false, false }()
+	defer func() {
+		for _, name := range []string{"coin", "platform", "tag", "since", "until", "before"} {
+			buyRemoveCmd.Flags().Set(name, "")
+		}
+	}()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	old1, _ := p.AddHolding("TEST", dec(1.0), dec(10), "Demo", "", "2022-06-01")
+	old2, _ := p.AddHolding("TEST", dec(2.0), dec(10), "demo", "", "2022-12-31")
+	p.AddHolding("TEST", dec(3.0), dec(10), "Demo", "", "2023-01-01")
+	p.AddHolding("TEST", dec(4.0), dec(10), "Coinbase", "", "2022-06-01")
+	p.AddHolding("BTC", dec(1.0), dec(20000), "Demo", "", "2022-06-01")
+
+	// IDs and filters don't mix, and a filter is needed without IDs
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error without IDs or filters, got %v", err)
+	}
+	buyRemoveCmd.Flags().Set("coin", "TEST")
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{old1.ID}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for IDs with filters, got %v", err)
+	}
+	buyRemoveCmd.Flags().Set("until", "2023-01-01")
+	buyRemoveCmd.Flags().Set("before", "2023-01-01")
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for --until with --before, got %v", err)
+	}
+	buyRemoveCmd.Flags().Set("until", "")
+
+	// The matches are previewed before confirming
+	buyRemoveCmd.Flags().Set("platform", "Demo")
+	nonInteractive = true
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error without --yes, got %v", err)
+	}
+	nonInteractive = false
+	oldStdin := osStdin
+	defer func() { osStdin = oldStdin }()
+	osStdin = strings.NewReader("y\n")
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, nil); err != nil {
+		t.Fatalf("filtered remove failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{old1.ID + "  1 TEST bought 2022-06-01 on Demo", old2.ID + "  2 TEST bought 2022-12-31 on demo", "Remove 2 purchases? [y/N]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "Removed purchase"); got != 2 {
+		t.Errorf("Expected 2 removal messages, got %d:\n%s", got, out)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 3 {
+		t.Errorf("Expected 3 holdings left, got %d", len(holdings))
+	}
+
+	buf.Reset()
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, nil); err != nil {
+		t.Fatalf("remove without matches failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No matching purchases") {
+		t.Errorf("Expected no matches, got:\n%s", buf.String())
+	}
+}
+
+func TestIDPrefixes(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer func() { assumeYes = false }()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	h, _ := p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "")
+	loan, _ := p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "")
+
+	// Tables show short IDs that commands take back
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, h.ID[:minShortID]+"  BTC") || strings.Contains(out, h.ID) {
+		t.Errorf("Expected the short ID %s, got:\n%s", h.ID[:minShortID], out)
+	}
+
+	if err := loanRepayCmd.RunE(loanRepayCmd, []string{loan.ID[:minShortID], "100"}); err != nil {
+		t.Fatalf("loan repay by prefix failed: %v", err)
+	}
+	if outstanding, _ := p.GetOutstandingByLoan(); !outstanding[loan.ID].Equal(dec(900)) {
+		t.Errorf("Expected 900 outstanding, got %v", outstanding[loan.ID])
+	}
+
+	assumeYes = true
+	if err := buyRemoveCmd.RunE(buyRemoveCmd, []string{h.ID[:5]}); err != nil {
+		t.Fatalf("buy remove by prefix failed: %v", err)
+	}
+	if holdings, _ := p.ListHoldings(); len(holdings) != 0 {
+		t.Errorf("Expected the purchase removed, got %+v", holdings)
+	}
+	if err := trashRestoreCmd.RunE(trashRestoreCmd, []string{h.ID[:minShortID]}); err != nil {
+		t.Fatalf("trash restore by prefix failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Restored holding "+h.ID) {
+		t.Errorf("Expected the full ID restored, got:\n%s", buf.String())
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer dashboardCmd.Flags().Set("refresh", "0")
+
+	if err := dashboardCmd.Flags().Set("refresh", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dashboardCmd.RunE(dashboardCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for a short --refresh, got %v", err)
+	}
+
+	// The configured interval applies without the flag, and --refresh 0 turns it off
+	dashboardCmd.Flags().Set("refresh", "0")
+	dashboardCmd.Flags().Lookup("refresh").Changed = false
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetRefreshInterval("2m"); err != nil {
+		t.Fatal(err)
+	}
+	if every, err := refreshInterval(dashboardCmd); err != nil || every != 0 {
+		t.Errorf("Expected the configured interval ignored when stdout isn't a terminal, got %v (%v)", every, err)
+	}
+	oldIsTerminal := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	every, err := refreshInterval(dashboardCmd)
+	stdoutIsTerminal = oldIsTerminal
+	if err != nil || every != 2*time.Minute {
+		t.Errorf("Expected the configured 2m interval on a terminal, got %v (%v)", every, err)
+	}
+	dashboardCmd.Flags().Set("refresh", "0")
+	if every, err := refreshInterval(dashboardCmd); err != nil || every != 0 {
+		t.Errorf("Expected --refresh 0 to turn refreshing off, got %v (%v)", every, err)
+	}
+
+	// Each view ends with when it was drawn, until stopped
+	buf, restore := captureOutput()
+	defer restore()
+	stop := make(chan os.Signal, 1)
+	stop <- os.Interrupt
+	views := 0
+	err = refreshLoop(time.Minute, stop, func() error {
+		views++
+		fmt.Fprintln(osStdout, "=== VIEW ===")
+		return nil
+	})
+	if err != nil || views != 1 {
+		t.Fatalf("Expected one view before stopping, got %d (%v)", views, err)
+	}
+	if out := buf.String(); !strings.Contains(out, "=== VIEW ===\n\nLast updated "+time.Now().Format("2006-01-02")) || !strings.Contains(out, "refreshing every 1m0s (Ctrl-C to stop)") {
+		t.Errorf("Expected a last-updated line, got:\n%s", out)
+	}
+}
+
+func TestListColumns(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	columns := buyListCmd.Flags().Lookup("columns")
+	defer func() {
+		columns.Value.(pflag.SliceValue).Replace(nil)
+		columns.Changed = false
+	}()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "long term", "2024-01-01")
+
+	// Notes are hidden by default and fees shown
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list failed: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "long term") || !strings.Contains(out, "Fee") {
+		t.Errorf("Expected the default columns, got:\n%s", out)
+	}
+
+	// Saved columns apply to later lists
+	buf.Reset()
+	if err := columnsSetCmd.RunE(columnsSetCmd, []string{"buy", "+notes,-fee"}); err != nil {
+		t.Fatalf("columns set failed: %v", err)
+	}
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "long term") || strings.Contains(out, "Fee") {
+		t.Errorf("Expected notes without fees, got:\n%s", out)
+	}
+
+	// --columns overrides them, in the given order
+	buf.Reset()
+	buyListCmd.Flags().Set("columns", "notes,coin")
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list --columns failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) < 2 || lines[0] != "Notes      Coin" || lines[1] != "long term  BTC" {
+		t.Errorf("Expected only notes and coin, got:\n%s", buf.String())
+	}
+
+	columns.Value.(pflag.SliceValue).Replace([]string{"bogus"})
+	if err := buyListCmd.RunE(buyListCmd, nil); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for an unknown column, got %v", err)
+	}
+	if err := columnsSetCmd.RunE(columnsSetCmd, []string{"bogus", "id"}); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error for an unknown list, got %v", err)
+	}
+
+	if err := columnsResetCmd.RunE(columnsResetCmd, []string{"buy"}); err != nil {
+		t.Fatalf("columns reset failed: %v", err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.GetListColumns("buy"); got != nil {
+		t.Errorf("Expected the buy columns reset, got %v", got)
+	}
+}
+
+func TestListTotals(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer buyListCmd.Flags().Set("coin", "")
+	defer buyListCmd.Flags().Set("limit", "0")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("BTC", dec(0.5), dec(60000), "Coinbase", "", "2024-02-01")
+	p.AddHolding("ETH", dec(2.0), dec(3000), "Kraken", "", "2024-03-01")
+	p.AddSale("ETH", dec(1.0), dec(3500), "Kraken", "", "2024-04-01")
+
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Totals", "BTC  1.5  $80,000.00", "ETH  2    $6,000.00", "All       $86,000.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in buy list totals, got:\n%s", want, out)
+		}
+	}
+
+	// Totals follow the filters and cover every page
+	buf.Reset()
+	buyListCmd.Flags().Set("coin", "BTC")
+	buyListCmd.Flags().Set("limit", "1")
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list --coin failed: %v", err)
+	}
+	out = buf.String()
+	if !strings.Contains(out, "Totals (all pages)") || !strings.Contains(out, "All       $80,000.00") || strings.Contains(out, "ETH") {
+		t.Errorf("Expected BTC totals over all pages, got:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := sellListCmd.RunE(sellListCmd, nil); err != nil {
+		t.Fatalf("sell list failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "ETH  1  $3,500.00") {
+		t.Errorf("Expected sell list totals, got:\n%s", out)
+	}
+}
+
+func TestListGroup(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer buyListCmd.Flags().Set("group", "false")
+	defer sellListCmd.Flags().Set("group", "false")
+	defer buyListCmd.Flags().Set("sort", "date")
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1.0), dec(50000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("BTC", dec(0.5), dec(60000), "Coinbase", "", "2024-02-01")
+	p.AddHolding("ETH", dec(2.0), dec(3000), "Kraken", "", "2024-03-01")
+	p.AddSale("ETH", dec(1.0), dec(3500), "Kraken", "", "2024-04-01")
+
+	buyListCmd.Flags().Set("group", "true")
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list --group failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header, two coins, and a total, got:\n%s", buf.String())
+	}
+	for i, want := range []string{"BTC     2     1.5     $80,000.00  $53,333.33", "ETH     1     2       $6,000.00   $3,000.00", "All     3             $86,000.00"} {
+		if !strings.HasPrefix(lines[i+1], want) {
+			t.Errorf("Expected row %q, got %q", want, lines[i+1])
+		}
+	}
+
+	// Groups sort by value
+	buf.Reset()
+	buyListCmd.Flags().Set("sort", "value")
+	if err := buyListCmd.RunE(buyListCmd, nil); err != nil {
+		t.Fatalf("buy list --group --sort value failed: %v", err)
+	}
+	if lines := strings.Split(buf.String(), "\n"); !strings.HasPrefix(lines[1], "ETH") || !strings.HasPrefix(lines[2], "BTC") {
+		t.Errorf("Expected ETH before BTC by value, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	sellListCmd.Flags().Set("group", "true")
+	if err := sellListCmd.RunE(sellListCmd, nil); err != nil {
+		t.Fatalf("sell list --group failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Proceeds USD") || !strings.Contains(out, "ETH ") {
+		t.Errorf("Expected grouped sales, got:\n%s", out)
+	}
+}
+
+func TestPrintGoals(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	goals := config.Goals{
+		TargetValue: 200000,
+		Allocation:  map[string]float64{"BTC": 50, "ETH": 50},
+	}
+	holdings := map[string]decimal.Decimal{"BTC": dec(1), "ETH": dec(10)}
+	prices := map[string]float64{"BTC": 60000, "ETH": 2000}
+	printGoals(goals, 80000, holdings, prices, 1)
+
+	output := buf.String()
+	for _, want := range []string{"GOALS:", "40.0% of $200,000.00", "Sell", "$20,000.00", "Buy", "10 ETH"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	printGoals(config.Goals{}, 80000, holdings, prices, 1)
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without goals, got:\n%s", buf.String())
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := parseTargets([]string{"btc=50, ETH=30%", "SOL=20"})
+	if err != nil {
+		t.Fatalf("parseTargets failed: %v", err)
+	}
+	want := map[string]float64{"BTC": 50, "ETH": 30, "SOL": 20}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("expected %v, got %v", want, targets)
+	}
+
+	if targets, err := parseTargets(nil); err != nil || targets != nil {
+		t.Errorf("expected no targets, got %v, %v", targets, err)
+	}
+	for _, bad := range []string{"BTC", "BTC=half", "=50", "BTC=50,BTC=50"} {
+		if _, err := parseTargets([]string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	// Rejected before prices are fetched
+	defer rebalanceCmd.Flags().Lookup("target").Value.(pflag.SliceValue).Replace(nil)
+	rebalanceCmd.Flags().Set("target", "BTC=50,ETH=30")
+	if err := rebalanceCmd.RunE(rebalanceCmd, nil); err == nil {
+		t.Error("expected an error for targets not adding up to 100")
+	}
+	rebalanceCmd.Flags().Lookup("target").Value.(pflag.SliceValue).Replace(nil)
+	if err := rebalanceCmd.RunE(rebalanceCmd, nil); err == nil {
+		t.Error("expected an error without targets or goals")
+	}
+
+	buf.Reset()
+	osStderr = buf
+	holdings := map[string]decimal.Decimal{"BTC": dec(1), "ETH": dec(10), "DOGE": dec(100)}
+	prices := map[string]float64{"BTC": 60000, "ETH": 2000, "SOL": 100}
+	printRebalanceReport(holdings, prices, map[string]float64{"BTC": 50, "ETH": 30, "SOL": 20})
+
+	output := buf.String()
+	for _, want := range []string{"Total value: $80,000.00", "0.33333333 BTC", "$20,000.00", "160 SOL", "$16,000.00", "No price for DOGE"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+	osStderr = buf
+
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-01-01")
+	pos, err := p.GetPositionsAt(models.Date{})
+	if err != nil {
+		t.Fatalf("GetPositionsAt failed: %v", err)
+	}
+
+	buf.Reset()
+	live := map[string]float64{"BTC": 60000, "ETH": 2000}
+	if err := printSimulation(pos, live, map[string]float64{"BTC": 150000, "DOGE": 1}); err != nil {
+		t.Fatalf("printSimulation failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"DOGE not in the portfolio",
+		"$150,000.00",
+		"+150.0%",
+		"Net Value:      $170,000.00 (+$90,000.00, +112.5%)",
+		"Profit/Loss:    +$100,000.00",
+		"88.2%",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// Stored data is untouched
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 2 {
+		t.Errorf("expected 2 holdings, got %d", len(holdings))
+	}
+
+	defer simulateCmd.Flags().Lookup("price").Value.(pflag.SliceValue).Replace(nil)
+	if err := simulateCmd.RunE(simulateCmd, nil); err == nil {
+		t.Error("expected an error without prices")
+	}
+	simulateCmd.Flags().Set("price", "BTC=-1")
+	if err := simulateCmd.RunE(simulateCmd, nil); err == nil {
+		t.Error("expected an error for a negative price")
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-01")
+	ss, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("loadSnapshotStore failed: %v", err)
+	}
+	snap := models.NewSnapshot(time.Time{}, "before")
+	ss.Add(snap)
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	cfg.SetExchangeKey("binance", config.APIKey{Key: "k", Secret: "hunter2"})
+
+	archive := filepath.Join(tmpDir, "backup.tar.gz")
+	if err := backupCmd.RunE(backupCmd, []string{archive}); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "portfolio.json, snapshots.json, config.json") {
+		t.Errorf("expected portfolio, snapshots and config backed up, got: %s", buf.String())
+	}
+	_, files, err := state.Read(archive)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if strings.Contains(string(files["config.json"]), "hunter2") {
+		t.Errorf("expected exchange keys left out of the backup, got: %s", files["config.json"])
+	}
+
+	// Snapshots round-trip on their own, skipping those already present
+	snapArchive := filepath.Join(tmpDir, "snapshots.tar.gz")
+	if err := snapshotExportCmd.RunE(snapshotExportCmd, []string{snapArchive}); err != nil {
+		t.Fatalf("snapshot export failed: %v", err)
+	}
+	ss.Remove(snap.ID)
+	buf.Reset()
+	for range 2 {
+		if err := snapshotImportCmd.RunE(snapshotImportCmd, []string{snapArchive}); err != nil {
+			t.Fatalf("snapshot import failed: %v", err)
+		}
+	}
+	if !strings.Contains(buf.String(), "Imported 1 snapshots (0 already present)") ||
+		!strings.Contains(buf.String(), "Imported 0 snapshots (1 already present)") {
+		t.Errorf("unexpected import output: %s", buf.String())
+	}
+
+	holdings, _ := p.ListHoldings()
+	p.RemoveHolding(holdings[0].ID)
+
+	oldAssumeYes := assumeYes
+	assumeYes = true
+	defer func() { assumeYes = oldAssumeYes }()
+	if err := restoreCmd.RunE(restoreCmd, []string{archive}); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	holdings, _ = p.ListHoldings()
+	if len(holdings) != 1 || holdings[0].Coin != "BTC" {
+		t.Errorf("expected the BTC purchase restored, got %+v", holdings)
+	}
+	cfg, _ = loadConfig()
+	if key, ok := cfg.GetExchangeKey("binance"); !ok || key.Secret != "hunter2" {
+		t.Errorf("expected the exchange key kept on restore, got %+v, %v", key, ok)
+	}
+
+	if err := restoreCmd.RunE(restoreCmd, []string{filepath.Join(tmpDir, "missing.tar.gz")}); err == nil {
+		t.Error("expected an error for a missing archive")
+	}
+}
+
+func TestStateExportImportSQLite(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	os.MkdirAll("data", 0755)
+	if err := os.WriteFile(configPath(), []byte(`{"storage": "sqlite"}`), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	db, err := storage.NewSQLite(sqlitePath())
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+	portfolio.New(db).AddHolding("ETH", dec(2), dec(3000), "", "", "2024-01-01")
+	db.Close()
+
+	// The archive holds the database records, not the stale JSON file
+	archive := filepath.Join(tmpDir, "state.tar.gz")
+	if err := stateExportCmd.RunE(stateExportCmd, []string{archive}); err != nil {
+		t.Fatalf("state export failed: %v", err)
+	}
+	_, files, err := state.Read(archive)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !strings.Contains(string(files["portfolio.json"]), `"ETH"`) {
+		t.Errorf("expected the SQLite purchase exported, got: %s", files["portfolio.json"])
+	}
+
+	buf.Reset()
+	if err := stateImportCmd.RunE(stateImportCmd, []string{archive}); err != nil {
+		t.Fatalf("state import failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "portfolio.json") {
+		t.Errorf("expected portfolio.json imported, got: %s", buf.String())
+	}
+	if _, err := os.Stat(sqlitePath()); !os.IsNotExist(err) {
+		t.Error("expected the database retired after import")
+	}
+	if _, err := os.Stat(sqlitePath() + ".bak"); err != nil {
+		t.Errorf("expected the database kept as a backup: %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	if err := syncPushCmd.RunE(syncPushCmd, nil); exitCode(err) != exitNotFound {
+		t.Errorf("expected not found without sync settings, got %v", err)
+	}
+
+	bare := filepath.Join(tmpDir, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", bare).CombinedOutput(); err != nil {
+		t.Skipf("git unavailable: %v: %s", err, out)
+	}
+	os.MkdirAll("data", 0755)
+	settings := fmt.Sprintf(`{"sync": {"backend": "git", "url": %q}}`, bare)
+	if err := os.WriteFile(configPath(), []byte(settings), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-01")
+	if err := syncPushCmd.RunE(syncPushCmd, nil); err != nil {
+		t.Fatalf("sync push failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "portfolio.json:  uploaded") {
+		t.Errorf("expected portfolio.json uploaded, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := syncPullCmd.RunE(syncPullCmd, nil); err != nil {
+		t.Fatalf("sync pull failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "portfolio.json:  unchanged") {
+		t.Errorf("expected nothing to pull, got:\n%s", buf.String())
+	}
+}
+
+// failingTransport fails every request, as when offline
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("network is unreachable")
+}
+
+func TestGetPricesOrStale(t *testing.T) {
+	tmpDir := t.TempDir()
+	fetchedAt := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	cache := fmt.Sprintf(`{"usd:bitcoin": {"price": 50000, "fetched_at": %q}}`, fetchedAt.Format(time.RFC3339))
+	cacheFile := filepath.Join(tmpDir, "price-cache.json")
+	if err := os.WriteFile(cacheFile, []byte(cache), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	oldStderr := osStderr
+	osStderr = buf
+	defer func() { osStderr = oldStderr }()
+
+	ps := prices.NewWithClient(&http.Client{Transport: failingTransport{}})
+	if err := ps.SetCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetCacheFile failed: %v", err)
+	}
+
+	got, stale, err := getPricesOrStale(ps, []string{"BTC", "ETH"})
+	if err != nil {
+		t.Fatalf("getPricesOrStale failed: %v", err)
+	}
+	if got["BTC"] != 50000 || len(got) != 1 {
+		t.Errorf("Expected last known BTC price only, got %v", got)
+	}
+	if !stale.Equal(fetchedAt) {
+		t.Errorf("Expected stale since %v, got %v", fetchedAt, stale)
+	}
+	if !strings.Contains(buf.String(), "using last known prices") {
+		t.Errorf("Expected fallback warning, got: %s", buf.String())
+	}
+
+	// No last known price: the fetch error is returned
+	if _, _, err := getPricesOrStale(ps, []string{"ETH"}); err == nil {
+		t.Error("Expected error without last known prices")
+	}
+}
+
+func TestDepeggedStablecoins(t *testing.T) {
+	livePrices := map[string]float64{"USDC": 0.97, "USDT": 1.002, "DAI": 1.01, "BTC": 50000}
+	got := depeggedStablecoins(livePrices, 1, 0.5)
+	if len(got) != 2 || got[0].Coin != "DAI" || got[1].Coin != "USDC" {
+		t.Fatalf("Expected DAI and USDC off peg, got %+v", got)
+	}
+	if math.Abs(got[1].Percent+3) > 1e-9 {
+		t.Errorf("Expected USDC -3%%, got %v", got[1].Percent)
+	}
+
+	// Prices in another currency are converted back to USD
+	eurPrices := map[string]float64{"USDC": 0.9}
+	if got := depeggedStablecoins(eurPrices, 0.9, 0.5); len(got) != 0 {
+		t.Errorf("Expected USDC on peg in EUR, got %+v", got)
+	}
+
+	if got := depeggedStablecoins(livePrices, 1, 0); got != nil {
+		t.Errorf("Expected no warnings when disabled, got %+v", got)
+	}
+}
+
+func TestPrintCoinHeader(t *testing.T) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	cache := fmt.Sprintf(`{"bitcoin": {"id": "bitcoin", "name": "Bitcoin", "symbol": "BTC", "rank": 1,
+		"categories": ["Cryptocurrency", "Layer 1 (L1)"], "fetched_at": %q, "categories_fetched_at": %q}}`, now, now)
+	cacheFile := filepath.Join(t.TempDir(), "coin-metadata.json")
+	if err := os.WriteFile(cacheFile, []byte(cache), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ps := prices.NewWithClient(&http.Client{Transport: failingTransport{}})
+	if err := ps.SetMetadataCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetMetadataCacheFile failed: %v", err)
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	printCoinHeader(ps, "BTC")
+	output := buf.String()
+	for _, want := range []string{"=== BTC (Bitcoin) ===", "#1 by market cap", "Cryptocurrency, Layer 1 (L1)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+
+	buf.Reset()
+	printCoinHeader(nil, "BTC")
+	if buf.String() != "=== BTC ===\n" {
+		t.Errorf("Expected plain header without a price service, got: %s", buf.String())
+	}
+}
+
+// serverTransport sends every request to a test server, keeping its path
+// and query
+type serverTransport struct{ url string }
+
+func (s serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected, err := http.NewRequest(req.Method, s.url+req.URL.Path+"?"+req.URL.RawQuery, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultTransport.RoundTrip(redirected)
+}
+
+func TestAutoMapTickers(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		switch query {
+		case "ZKX":
+			w.Write([]byte(`{"coins": [{"id": "zkx", "name": "ZKX", "symbol": "zkx"}]}`))
+		default:
+			w.Write([]byte(`{"coins": [{"id": "dup-one", "symbol": "dup"}, {"id": "dup-two", "symbol": "dup"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	buf, restore := captureOutput()
+	defer restore()
+	osStderr = buf
+
+	ps := prices.NewWithClient(&http.Client{Transport: serverTransport{server.URL}})
+	ps.SetManualPrice("LP", 1)
+	if err := autoMapTickers(ps, []string{"BTC", "ZKX", "DUP", "LP"}); err != nil {
+		t.Fatalf("autoMapTickers failed: %v", err)
+	}
+
+	if strings.Join(queries, ",") != "ZKX,DUP" {
+		t.Errorf("Expected lookups of ZKX and DUP only, got %v", queries)
+	}
+	if ps.GetCoinGeckoID("ZKX") != "zkx" {
+		t.Errorf("Expected ZKX mapping applied, got %s", ps.GetCoinGeckoID("ZKX"))
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetTickerMapping("ZKX") != "zkx" || cfg.HasTickerMapping("DUP") {
+		t.Errorf("Expected only ZKX mapping saved, got %v", cfg.GetAllTickerMappings())
+	}
+	output := buf.String()
+	if !strings.Contains(output, "Mapped ZKX -> zkx (ZKX)") || !strings.Contains(output, "DUP has no single CoinGecko match") {
+		t.Errorf("Unexpected output: %s", output)
+	}
+}
+
+func TestConfigCommands(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	if err := configSetCmd.RunE(configSetCmd, []string{"display-currency", "eur"}); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "display_currency = EUR") {
+		t.Errorf("Expected new value to be printed, got: %s", buf.String())
+	}
+	if err := configSetCmd.RunE(configSetCmd, []string{"default_platform", "Kraken"}); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if err := configSetCmd.RunE(configSetCmd, []string{"display_currency", "XXX"}); exitCode(err) != 2 {
+		t.Errorf("Expected usage error for unsupported currency, got %v", err)
+	}
+	if err := configSetCmd.RunE(configSetCmd, []string{"price_cache_ttl", "soon"}); exitCode(err) != 2 {
+		t.Errorf("Expected usage error for invalid duration, got %v", err)
+	}
+	if err := configSetCmd.RunE(configSetCmd, []string{"auto_snapshot", "maybe"}); exitCode(err) != 2 {
+		t.Errorf("Expected usage error for invalid bool, got %v", err)
+	}
+	if err := configGetCmd.RunE(configGetCmd, []string{"colour"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found error for unknown setting, got %v", err)
+	}
+
+	buf.Reset()
+	if err := configGetCmd.RunE(configGetCmd, []string{"display_currency"}); err != nil {
+		t.Fatalf("config get failed: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "EUR" {
+		t.Errorf("Expected EUR, got: %s", buf.String())
+	}
+
+	// Records added without --platform use the default platform
+	buyAddCmd.Flags().Set("platform", "")
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"BTC", "1", "50000"}); err != nil {
+		t.Fatalf("buy failed: %v", err)
+	}
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 1 || holdings[0].Platform != "Kraken" {
+		t.Errorf("Expected holding on Kraken, got %+v", holdings)
+	}
+
+	if err := configUnsetCmd.RunE(configUnsetCmd, []string{"display_currency"}); err != nil {
+		t.Fatalf("config unset failed: %v", err)
+	}
+	buf.Reset()
+	if err := configListCmd.RunE(configListCmd, nil); err != nil {
+		t.Fatalf("config list failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "USD (default)") || !strings.Contains(out, "Kraken") || !strings.Contains(out, "price_cache_ttl") {
+		t.Errorf("Unexpected config list output: %s", out)
+	}
+}
+
+// TestDefaultPlatformArg tests loans and stakes falling back to the default
+// platform when PLATFORM is left out
+func TestDefaultPlatformArg(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	_, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-01-01")
+	if err := stakeAddCmd.RunE(stakeAddCmd, []string{"ETH", "1"}); exitCode(err) != 2 || !strings.Contains(err.Error(), "default_platform") {
+		t.Errorf("Expected a usage error without a platform or default, got %v", err)
+	}
+
+	if err := configSetCmd.RunE(configSetCmd, []string{"default_platform", "Kraken"}); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if err := stakeAddCmd.RunE(stakeAddCmd, []string{"ETH", "1"}); err != nil {
+		t.Fatalf("stake add failed: %v", err)
+	}
+	if err := loanAddCmd.RunE(loanAddCmd, []string{"USDC", "500"}); err != nil {
+		t.Fatalf("loan add failed: %v", err)
+	}
+	if err := loanAddCmd.RunE(loanAddCmd, []string{"USDC", "100", "Nexo"}); err != nil {
+		t.Fatalf("loan add failed: %v", err)
+	}
+
+	stakes, _ := p.ListStakes()
+	loans, _ := p.ListLoans()
+	if len(stakes) != 1 || stakes[0].Platform != "Kraken" {
+		t.Errorf("Expected a stake on Kraken, got %+v", stakes)
+	}
+	if len(loans) != 2 || loans[0].Platform != "Kraken" || loans[1].Platform != "Nexo" {
+		t.Errorf("Expected loans on Kraken and Nexo, got %+v", loans)
+	}
+}
+
+func TestPlatformRegistry(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	output, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1), dec(50000), "Binance", "", "2024-01-01")
+	if err := platformAddCmd.RunE(platformAddCmd, []string{"Coinbase"}); err != nil {
+		t.Fatalf("platform add failed: %v", err)
+	}
+	if err := platformAliasCmd.RunE(platformAliasCmd, []string{"CB", "coinbase"}); err != nil {
+		t.Fatalf("platform alias failed: %v", err)
+	}
+
+	for _, platform := range []string{"cb", "COINBASE", "binance"} {
+		if err := stakeAddCmd.RunE(stakeAddCmd, []string{"BTC", "0.1", platform}); err != nil {
+			t.Fatalf("stake add failed: %v", err)
+		}
+	}
+	buyAddCmd.Flags().Set("platform", "Cb")
+	defer buyAddCmd.Flags().Set("platform", "")
+	if err := buyAddCmd.RunE(buyAddCmd, []string{"ETH", "1", "2000"}); err != nil {
+		t.Fatalf("buy add failed: %v", err)
+	}
+
+	stakes, _ := p.ListStakes()
+	if len(stakes) != 3 || stakes[0].Platform != "Coinbase" || stakes[1].Platform != "Coinbase" || stakes[2].Platform != "Binance" {
+		t.Errorf("Expected stakes on Coinbase, Coinbase, Binance, got %+v", stakes)
+	}
+	holdings, _ := p.ListHoldings()
+	if holdings[len(holdings)-1].Platform != "Coinbase" {
+		t.Errorf("Expected the purchase on Coinbase, got %q", holdings[len(holdings)-1].Platform)
+	}
+
+	output.Reset()
+	if err := platformListCmd.RunE(platformListCmd, nil); err != nil {
+		t.Fatalf("platform list failed: %v", err)
+	}
+	out := output.String()
+	if !strings.Contains(out, "Coinbase  3        yes       cb") || !strings.Contains(out, "Binance   2") {
+		t.Errorf("Expected Coinbase as a favorite with alias cb and Binance, got:\n%s", out)
+	}
+	if got := completePlatforms("c"); len(got) != 1 || got[0] != "Coinbase" {
+		t.Errorf("Expected Coinbase completed, got %v", got)
+	}
+
+	if err := platformUnaliasCmd.RunE(platformUnaliasCmd, []string{"cb"}); err != nil {
+		t.Fatalf("platform unalias failed: %v", err)
+	}
+	if err := platformUnaliasCmd.RunE(platformUnaliasCmd, []string{"cb"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found for a removed alias, got %v", err)
+	}
+	if err := platformRemoveCmd.RunE(platformRemoveCmd, []string{"coinbase"}); err != nil {
+		t.Fatalf("platform remove failed: %v", err)
+	}
+	if err := platformRemoveCmd.RunE(platformRemoveCmd, []string{"Ledger"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found for a platform that is not a favorite, got %v", err)
+	}
+}
+
+func TestPlatformRename(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	output, restore := captureOutput()
+	defer restore()
+	assumeYes = true
+	defer func() { assumeYes = false }()
+
+	p.AddHolding("BTC", dec(1), dec(50000), "CB", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.1), dec(60000), "coinbase", "", "2024-02-01")
+	if _, err := p.AddTransfer("BTC", dec(0.2), "CB", "Ledger", dec(0), "", "2024-03-01"); err != nil {
+		t.Fatalf("AddTransfer failed: %v", err)
+	}
+	configSetCmd.RunE(configSetCmd, []string{"default_platform", "CB"})
+	platformAddCmd.RunE(platformAddCmd, []string{"CB"})
+	platformAliasCmd.RunE(platformAliasCmd, []string{"c", "CB"})
+
+	output.Reset()
+	if err := platformRenameCmd.RunE(platformRenameCmd, []string{"cb", "Coinbase"}); err != nil {
+		t.Fatalf("platform rename failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "Renamed cb to Coinbase in 2 record(s)") {
+		t.Errorf("Expected 2 records renamed, got: %s", output.String())
+	}
+	if err := platformRenameCmd.RunE(platformRenameCmd, []string{"coinbase", "Coinbase"}); err != nil {
+		t.Fatalf("platform rename failed: %v", err)
+	}
+
+	counts, _ := p.GetPlatformCounts()
+	if len(counts) != 2 || counts[0] != (portfolio.PlatformCount{Platform: "Coinbase", Records: 3}) {
+		t.Errorf("Expected 3 records on Coinbase and Ledger, got %v", counts)
+	}
+	cfg, _ := loadConfig()
+	if cfg.GetDefaultPlatform() != "Coinbase" || cfg.GetPlatformAliases()["c"] != "Coinbase" {
+		t.Errorf("Expected the default platform and alias renamed, got %q, %v", cfg.GetDefaultPlatform(), cfg.GetPlatformAliases())
+	}
+	if favorites := cfg.GetFavoritePlatforms(); len(favorites) != 1 || favorites[0] != "Coinbase" {
+		t.Errorf("Expected the favorite renamed, got %v", favorites)
+	}
+
+	if err := platformRenameCmd.RunE(platformRenameCmd, []string{"Kraken", "Coinbase"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found for an unused platform, got %v", err)
+	}
+	if err := platformRenameCmd.RunE(platformRenameCmd, []string{"Coinbase", "Coinbase"}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for the same name, got %v", err)
+	}
+}
+
+func TestCoinMigrate(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	output, restore := captureOutput()
+	defer restore()
+	assumeYes = true
+	defer func() { assumeYes = false }()
+
+	p.AddHolding("OLD", dec(100), dec(2), "", "", "2024-01-01")
+	p.AddStake("OLD", dec(40), "Lido", nil, "", "2024-02-01")
+	cfg, _ := loadConfig()
+	cfg.SetTickerMapping("OLD", "old-token")
+	cfg.AddToWatchlist("OLD")
+
+	coinMigrateCmd.Flags().Set("ratio", "10")
+	defer coinMigrateCmd.Flags().Set("ratio", "1")
+	if err := coinMigrateCmd.RunE(coinMigrateCmd, []string{"old", "new"}); err != nil {
+		t.Fatalf("coin migrate failed: %v", err)
+	}
+	out := output.String()
+	for _, want := range []string{"Migrated 2 record(s) from OLD to NEW", "Mapped NEW -> old-token, as OLD was"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	holdings, _ := p.ListHoldings()
+	stakes, _ := p.ListStakes()
+	if holdings[0].Coin != "NEW" || !holdings[0].Amount.Equal(dec(1000)) || !holdings[0].PurchasePriceUSD.Equal(dec(0.2)) || !stakes[0].Amount.Equal(dec(400)) {
+		t.Errorf("Expected 1000 NEW @ $0.20 with 400 staked, got %+v, %+v", holdings, stakes)
+	}
+	cfg, _ = loadConfig()
+	if cfg.HasTickerMapping("OLD") || cfg.GetTickerMapping("NEW") != "old-token" {
+		t.Errorf("Expected the mapping moved to NEW, got %v", cfg.GetAllTickerMappings())
+	}
+	if watchlist := cfg.GetWatchlist(); len(watchlist) != 1 || watchlist[0] != "NEW" {
+		t.Errorf("Expected NEW watched instead of OLD, got %v", watchlist)
+	}
+
+	if err := coinMigrateCmd.RunE(coinMigrateCmd, []string{"OLD", "NEW"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found once OLD has no records, got %v", err)
+	}
+	coinMigrateCmd.Flags().Set("ratio", "0")
+	if err := coinMigrateCmd.RunE(coinMigrateCmd, []string{"NEW", "NEWER"}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for a zero ratio, got %v", err)
+	}
+}
+
+func TestDCAPlanSchedule(t *testing.T) {
+	monthly := config.DCAPlan{Every: "monthly", Start: "2024-01-31"}
+	start, _ := models.ParseDate(monthly.Start)
+	for n, want := range []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"} {
+		if got := dcaDate("monthly", start, n).String(); got != want {
+			t.Errorf("Expected monthly date %d to be %s, got %s", n, want, got)
+		}
+	}
+
+	weekly := config.DCAPlan{Every: "weekly", Start: "2024-01-01"}
+	today, _ := models.ParseDate("2024-01-20")
+	due, missed, ok := dcaDue(weekly, today)
+	if !ok || due.String() != "2024-01-15" || missed != 2 {
+		t.Errorf("Expected 2024-01-15 due with 2 missed, got %s, %d, %v", due, missed, ok)
+	}
+	weekly.LastDue = "2024-01-15"
+	if _, _, ok := dcaDue(weekly, today); ok {
+		t.Error("Expected nothing due after the last purchase")
+	}
+	if next := nextDCADate(weekly); next.String() != "2024-01-22" {
+		t.Errorf("Expected next purchase on 2024-01-22, got %s", next)
+	}
+	if _, _, ok := dcaDue(config.DCAPlan{Every: "daily", Start: "2024-02-01"}, today); ok {
+		t.Error("Expected a plan starting later not to be due")
+	}
+}
+
+func TestDCARun(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"bitcoin": {"usd": 50000}, "ethereum": {"usd": 2000}}`))
+	}))
+	defer server.Close()
+	ps := prices.NewWithClient(&http.Client{Transport: serverTransport{server.URL}})
+
+	output, restore := captureOutput()
+	defer restore()
+
+	dcaPlanAddCmd.Flags().Set("usd", "100")
+	dcaPlanAddCmd.Flags().Set("every", "daily")
+	dcaPlanAddCmd.Flags().Set("platform", "Kraken")
+	if err := dcaPlanAddCmd.RunE(dcaPlanAddCmd, []string{"btc"}); err != nil {
+		t.Fatalf("dca plan add failed: %v", err)
+	}
+	dcaPlanAddCmd.Flags().Set("usd", "0")
+	dcaPlanAddCmd.Flags().Set("amount", "0.5")
+	dcaPlanAddCmd.Flags().Set("every", "monthly")
+	dcaPlanAddCmd.Flags().Set("platform", "")
+	dcaPlanAddCmd.Flags().Set("start", models.Today().AddDays(1).String())
+	if err := dcaPlanAddCmd.RunE(dcaPlanAddCmd, []string{"ETH"}); err != nil {
+		t.Fatalf("dca plan add failed: %v", err)
+	}
+	dcaPlanAddCmd.Flags().Set("amount", "0")
+	dcaPlanAddCmd.Flags().Set("start", "")
+	if err := dcaPlanAddCmd.RunE(dcaPlanAddCmd, []string{"SOL"}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error without --amount or --usd, got %v", err)
+	}
+	dcaPlanAddCmd.Flags().Set("every", "weekly")
+
+	// Runs at once, as by the daemon and 'dca run', buy a due plan only once
+	results := make(chan int, 2)
+	for range 2 {
+		go func() {
+			bought, err := runDCAPlans(ps, models.Today())
+			if err != nil {
+				t.Errorf("runDCAPlans failed: %v", err)
+			}
+			results <- bought
+		}()
+	}
+	if bought := <-results + <-results; bought != 1 {
+		t.Fatalf("Expected 1 purchase, got %d", bought)
+	}
+	holdings, _ := p.ListHoldings()
+	if len(holdings) != 1 || holdings[0].Coin != "BTC" || !holdings[0].Amount.Equal(dec(0.002)) || !holdings[0].PurchasePriceUSD.Equal(dec(50000)) ||
+		holdings[0].Platform != "Kraken" || len(holdings[0].Tags) != 1 || holdings[0].Tags[0] != "DCA" {
+		t.Errorf("Expected 0.002 BTC bought @ $50,000 on Kraken tagged DCA, got %+v", holdings)
+	}
+	if bought, _ := runDCAPlans(ps, models.Today()); bought != 0 {
+		t.Errorf("Expected nothing due on a second run, got %d", bought)
+	}
+
+	output.Reset()
+	if err := dcaPlanListCmd.RunE(dcaPlanListCmd, nil); err != nil {
+		t.Fatalf("dca plan list failed: %v", err)
+	}
+	out := output.String()
+	for _, want := range []string{"$100.00 of BTC daily", "Kraken", models.Today().AddDays(1).String(), "0.5 ETH monthly"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in plan list, got:\n%s", want, out)
+		}
+	}
+
+	if err := dcaPlanRemoveCmd.RunE(dcaPlanRemoveCmd, []string{"3"}); exitCode(err) != 3 {
+		t.Errorf("Expected not found for plan 3, got %v", err)
+	}
+	if err := dcaPlanRemoveCmd.RunE(dcaPlanRemoveCmd, []string{"1"}); err != nil {
+		t.Fatalf("dca plan remove failed: %v", err)
+	}
+	cfg, _ := loadConfig()
+	if plans := cfg.GetDCAPlans(); len(plans) != 1 || plans[0].Coin != "ETH" {
+		t.Errorf("Expected only the ETH plan left, got %+v", plans)
+	}
+}
+
+func TestLegacyConfigMigration(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+
+	legacy := filepath.Join(tmpDir, "home", ".follyo", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte("display_currency: EUR\ncolor: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	oldLegacy := legacyConfigPath
+	legacyConfigPath = func() string { return legacy }
+	defer func() { legacyConfigPath = oldLegacy }()
+
+	var stderr bytes.Buffer
+	osStderr = &stderr
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if got := cfg.GetDisplayCurrency(); got != "EUR" {
+		t.Errorf("Expected EUR from the legacy config, got %s", got)
+	}
+	if !strings.Contains(stderr.String(), "Imported display_currency") || !strings.Contains(stderr.String(), "unknown settings") {
+		t.Errorf("Expected migration notes, got: %s", stderr.String())
+	}
+	if _, err := os.Stat(legacy + ".migrated"); err != nil {
+		t.Errorf("Expected legacy config to be renamed: %v", err)
+	}
+
+	// Environment variables override the file for one run
+	t.Setenv("FOLLYO_DISPLAY_CURRENCY", "GBP")
+	buf, restore := captureOutput()
+	defer restore()
+	if err := configListCmd.RunE(configListCmd, nil); err != nil {
+		t.Fatalf("config list failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "GBP (FOLLYO_DISPLAY_CURRENCY)") {
+		t.Errorf("Expected override to be shown, got: %s", buf.String())
+	}
+}
+
+func TestDataDirResolution(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	t.Setenv("FOLLYO_DATA_DIR", "")
+	t.Setenv("FOLLYO_CONFIG", "")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "xdg-data"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "xdg-config"))
+
+	xdgDir := filepath.Join(tmpDir, "xdg-data", "follyo")
+	if got := defaultDataPath(); got != filepath.Join(xdgDir, "portfolio.json") {
+		t.Errorf("Expected portfolio in the XDG data dir, got %s", got)
+	}
+	if got := configPath(); got != filepath.Join(tmpDir, "xdg-config", "follyo", "config.json") {
+		t.Errorf("Expected config in the XDG config dir, got %s", got)
+	}
+
+	t.Setenv("FOLLYO_DATA_DIR", filepath.Join(tmpDir, "custom"))
+	if got := defaultDataPath(); got != filepath.Join(tmpDir, "custom", "portfolio.json") {
+		t.Errorf("Expected FOLLYO_DATA_DIR to win, got %s", got)
+	}
+	if got := configPath(); got != filepath.Join(tmpDir, "custom", "config.json") {
+		t.Errorf("Expected config in FOLLYO_DATA_DIR, got %s", got)
+	}
+}
+
+func TestLegacyDataMigration(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	t.Setenv("FOLLYO_DATA_DIR", "")
+	t.Setenv("FOLLYO_CONFIG", "")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "xdg-data"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "xdg-config"))
+	dataDir := filepath.Join(tmpDir, "xdg-data", "follyo")
+
+	// An unrelated ./data directory is left alone
+	if err := os.Mkdir("data", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("data", "notes.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("data", "notes.txt")); err != nil {
+		t.Errorf("Expected unrelated ./data to be left alone: %v", err)
+	}
+
+	// One holding a portfolio and config is moved once, with a notice
+	for name, content := range map[string]string{
+		"portfolio.json": `{"holdings": []}`,
+		"config.json":    `{"ticker_mappings": {"FOO": "foo"}}`,
+	} {
+		if err := os.WriteFile(filepath.Join("data", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stderr := &bytes.Buffer{}
+	oldStderr := osStderr
+	osStderr = stderr
+	defer func() { osStderr = oldStderr }()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if cfg.GetTickerMapping("FOO") != "foo" {
+		t.Error("Expected the migrated config to be loaded")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "portfolio.json")); err != nil {
+		t.Errorf("Expected portfolio in the data dir: %v", err)
+	}
+	if _, err := os.Stat(configPath()); err != nil {
+		t.Errorf("Expected config in the config dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no config left in the data dir, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "Moved 3 files") || !strings.Contains(stderr.String(), "now keeps its config") {
+		t.Errorf("Expected migration notices, got: %s", stderr.String())
+	}
+
+	stderr.Reset()
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected the migration to run once, got: %s", stderr.String())
+	}
+}
+
+func TestPortfolioMigrate(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Chdir(tmpDir)
+	dst := filepath.Join(tmpDir, "xdg", "follyo")
+	t.Setenv("FOLLYO_DATA_DIR", dst)
+	assumeYes = true
+	defer func() { assumeYes = false }()
+
+	for name, content := range map[string]string{
+		"portfolio.json":      `{"holdings": []}`,
+		"config.json":         `{"ticker_mappings": {"FOO": "foo"}}`,
+		"portfolio.json.lock": "",
+		".sync/state.json":    "{}",
+	} {
+		path := filepath.Join("data", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf, restore := captureOutput()
+	defer restore()
+	if err := portfolioMigrateCmd.RunE(portfolioMigrateCmd, nil); err != nil {
+		t.Fatalf("portfolio migrate failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Moved 3 files") {
+		t.Errorf("Expected 3 files moved, got: %s", buf.String())
+	}
+	for _, name := range []string{"portfolio.json", "config.json", ".sync/state.json"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); err != nil {
+			t.Errorf("Expected %s in the data directory: %v", name, err)
+		}
+	}
+	if _, err := os.Stat("data"); !os.IsNotExist(err) {
+		t.Errorf("Expected ./data to be removed, got %v", err)
+	}
+	if cfg, err := loadConfig(); err != nil || cfg.GetTickerMapping("FOO") != "foo" {
+		t.Errorf("Expected the moved config to be used, got %v", err)
+	}
+
+	if err := portfolioMigrateCmd.RunE(portfolioMigrateCmd, nil); exitCode(err) != 3 {
+		t.Errorf("Expected not found error with nothing to migrate, got %v", err)
+	}
+
+	// Existing data in the destination is never overwritten
+	if err := os.MkdirAll("old", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("old", "portfolio.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := portfolioMigrateCmd.RunE(portfolioMigrateCmd, []string{"old"}); err == nil {
+		t.Error("Expected error when the data directory already holds data")
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	oldLogger := slog.Default()
+	defer func() {
+		slog.SetDefault(oldLogger)
+		verbose, debug = false, false
+	}()
+
+	var stderr bytes.Buffer
+	osStderr = &stderr
+	verbose, debug = true, true
+	if err := setupLogging(buyAddCmd); err != nil {
+		t.Fatalf("setupLogging failed: %v", err)
+	}
+	if _, err := p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stderr.String(), "wrote file") || strings.Contains(stderr.String(), "command started") {
+		t.Errorf("Expected info records only on stderr, got: %s", stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "data", "logs", "follyo.log"))
+	if err != nil {
+		t.Fatalf("Expected log file: %v", err)
+	}
+	if !strings.Contains(string(data), "command started") || !strings.Contains(string(data), "wrote file") {
+		t.Errorf("Expected debug and info records in the log file, got: %s", data)
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/ping") {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"gecko_says":"(V3) To the Moon!"}`))
+	}))
+	defer server.Close()
+	ps := prices.NewWithClient(&http.Client{Transport: serverTransport{server.URL}})
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-01")
+	p.AddHolding("ZZQX", dec(100), dec(1), "", "", "2024-01-01")
+	if err := runDoctor(ps); err != nil {
+		t.Fatalf("doctor failed on a healthy portfolio: %v\n%s", err, buf.String())
+	}
+	out := buf.String()
+	for _, want := range []string{"schema version 4", "WARN  ZZQX has no CoinGecko price mapping", "follyo ticker search zzqx ZZQX", "records are consistent", "CoinGecko is reachable", "No problems found, 1 warning(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	// Contradicting records fail, with a fix
+	if _, err := p.AddSaleUnchecked("BTC", dec(2), dec(60000), dec(0), "", "", "2024-02-01", nil); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := runDoctor(nil); err == nil || exitCode(err) != 1 {
+		t.Errorf("Expected doctor to fail, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "FAIL  BTC balance is -1") || !strings.Contains(buf.String(), "Fix: record the missing purchase") {
+		t.Errorf("Expected negative balance with fix, got:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "reachable") || strings.Contains(buf.String(), "can't be reached") {
+		t.Errorf("Expected no network check without a price service, got:\n%s", buf.String())
+	}
+
+	// A corrupt data file points at the last good backup
+	if err := os.WriteFile(dataPath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := runDoctor(nil); err == nil {
+		t.Error("Expected doctor to fail on a corrupt data file")
+	}
+	if !strings.Contains(buf.String(), "is not valid JSON") || !strings.Contains(buf.String(), "cp "+dataPath+".bak.1 "+dataPath) {
+		t.Errorf("Expected corrupt file with backup fix, got:\n%s", buf.String())
+	}
+}
+
+func TestFsckCommand(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("ETH", dec(1), dec(2000), "", "", "2024-01-01")
+	if err := runFsck(false); err != nil {
+		t.Fatalf("fsck failed on a healthy portfolio: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No problems found") {
+		t.Errorf("Expected no problems, got:\n%s", buf.String())
+	}
+
+	// A hand-edited file with a NaN fee and a stake exceeding the balance
+	damaged := `{"version": 3,
+  "holdings": [{"id": "h1", "coin": "ETH", "amount": 1, "purchase_price_usd": 2000, "fee_usd": NaN, "date": "2024-01-01"}],
+  "loans": [], "sales": [],
+  "stakes": [{"id": "s1", "coin": "ETH", "amount": 2, "platform": "Lido", "date": "2024-01-02"}]
+}`
+	if err := os.WriteFile(dataPath, []byte(damaged), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declining leaves the file alone
+	nonInteractive = true
+	defer func() { nonInteractive = false }()
+	buf.Reset()
+	err := runFsck(false)
+	if err == nil || !strings.Contains(err.Error(), "follyo fsck --fix") {
+		t.Errorf("Expected a hint to use --fix, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "FAIL  holding h1: fee_usd is NaN") || !strings.Contains(buf.String(), "Fix: remove fee_usd") {
+		t.Errorf("Expected the NaN fee with its repair, got:\n%s", buf.String())
+	}
+	if data, _ := os.ReadFile(dataPath); string(data) != damaged {
+		t.Error("Expected fsck without --fix to leave the file alone")
+	}
+
+	buf.Reset()
+	if err := runFsck(true); err != nil {
+		t.Fatalf("fsck --fix failed: %v\n%s", err, buf.String())
+	}
+	out := buf.String()
+	for _, want := range []string{"Repaired holding h1: fee_usd is NaN", "Reduced stake s1 of 2 ETH", "Repaired"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+	stakes, _ := p.ListStakes()
+	if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(1)) {
+		t.Errorf("Expected the stake reduced to 1 ETH, got %+v", stakes)
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-01-10")
+	p.AddSale("BTC", dec(0.5), dec(30000), "", "", "2023-02-10")
+
+	reportCmd.Flags().Set("year", "2023")
+	defer reportCmd.Flags().Set("year", "0")
+	if err := reportCmd.RunE(reportCmd, []string{}); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"2023-01", "$20,000.00", "2023-02", "$15,000.00", "+$5,000.00", "2023-12", "Total"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "RISK:") {
+		t.Errorf("Expected no risk section without snapshots, got:\n%s", out)
+	}
+
+	csvPath := filepath.Join(tmpDir, "report.csv")
+	reportCmd.Flags().Set("period", "quarterly")
+	reportCmd.Flags().Set("csv", csvPath)
+	defer func() {
+		reportCmd.Flags().Set("period", "monthly")
+		reportCmd.Flags().Set("csv", "")
+	}()
+	if err := reportCmd.RunE(reportCmd, []string{}); err != nil {
+		t.Fatalf("report --csv failed: %v", err)
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "2023-Q1,2023-01-01,2023-03-31,20000.00,15000.00,5000.00,\n") {
+		t.Errorf("Unexpected CSV:\n%s", data)
+	}
+
+	reportCmd.Flags().Set("period", "weekly")
+	if err := reportCmd.RunE(reportCmd, []string{}); exitCode(err) != 2 {
+		t.Errorf("Expected a usage error for an unknown period, got %v", err)
+	}
+
+	// Risk covers only the snapshots in the year reported
+	store, err := loadSnapshotStore()
+	if err != nil {
+		t.Fatalf("Failed to open snapshot store: %v", err)
+	}
+	for i, net := range []float64{500, 1000, 2000, 1500} {
+		snap := models.NewSnapshot(time.Date(2022, 12, 30, 12, 0, 0, 0, time.Local).AddDate(0, 0, i), "")
+		snap.NetValue = net
+		store.Add(snap)
+	}
+	reportCmd.Flags().Set("period", "monthly")
+	reportCmd.Flags().Set("csv", "")
+	buf.Reset()
+	if err := reportCmd.RunE(reportCmd, []string{}); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "RISK:") || !strings.Contains(out, "Worst Day:      2023-01-02 -$500.00") {
+		t.Errorf("Expected a risk section over 2023's snapshots, got:\n%s", out)
+	}
+}
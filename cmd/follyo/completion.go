@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/exchange"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+// completer suggests values for the word being completed
+type completer func(toComplete string) []cobra.Completion
+
+// registerCompletions sets up dynamic shell completion of coins, platforms,
+// and record IDs. Scripts are generated by cobra's 'completion' command.
+func registerCompletions() {
+	// Coins and platforms for add commands
+	buyAddCmd.ValidArgsFunction = completeArgs(completeCoins)
+	sellAddCmd.ValidArgsFunction = completeArgs(completeCoins)
+	loanAddCmd.ValidArgsFunction = completeArgs(completeCoins, nil, completePlatforms)
+	stakeAddCmd.ValidArgsFunction = completeArgs(completeCoins, nil, completePlatforms)
+	swapAddCmd.ValidArgsFunction = completeArgs(completeCoins, nil, completeCoins)
+	transferAddCmd.ValidArgsFunction = completeArgs(completeCoins, nil, completePlatforms, completePlatforms)
+	coinCmd.ValidArgsFunction = completeArgs(completeCoins)
+	coinMigrateCmd.ValidArgsFunction = completeArgs(completeCoins)
+	dcaCmd.ValidArgsFunction = completeArgs(completeCoins)
+	alertAddCmd.ValidArgsFunction = completeArgs(completeCoins)
+	for _, cmd := range []*cobra.Command{buyAddCmd, sellAddCmd, swapAddCmd} {
+		cmd.RegisterFlagCompletionFunc("platform", completeFlag(completePlatforms))
+	}
+
+	// Record IDs
+	buyRemoveCmd.ValidArgsFunction = completeEach(completeHoldingIDs)
+	sellRemoveCmd.ValidArgsFunction = completeEach(completeSaleIDs)
+	loanRepayCmd.ValidArgsFunction = completeArgs(completeLoanIDs)
+	loanRemoveCmd.ValidArgsFunction = completeEach(completeLoanIDs)
+	stakeReduceCmd.ValidArgsFunction = completeArgs(completeStakeIDs)
+	stakeRemoveCmd.ValidArgsFunction = completeEach(completeStakeIDs)
+	swapRemoveCmd.ValidArgsFunction = completeEach(completeSwapIDs)
+	transferRemoveCmd.ValidArgsFunction = completeEach(completeTransferIDs)
+	trashRestoreCmd.ValidArgsFunction = completeArgs(completeTrashIDs)
+	snapshotShowCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs)
+	snapshotRemoveCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs)
+	snapshotNoteCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs)
+	snapshotPinCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs)
+	snapshotUnpinCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs)
+	snapshotCompareCmd.ValidArgsFunction = completeArgs(completeSnapshotIDs, completeSnapshotIDs)
+
+	// Settings
+	exchangeSetCmd.ValidArgsFunction = completeArgs(completeValues(exchange.Supported...))
+	exchangeRemoveCmd.ValidArgsFunction = completeArgs(completeValues(exchange.Supported...))
+	exchangeSyncCmd.ValidArgsFunction = completeArgs(completeValues(exchange.Supported...))
+	portfolioRemoveCmd.ValidArgsFunction = completeArgs(completePortfolioNames)
+	watchRemoveCmd.ValidArgsFunction = completeArgs(completeWatchlist)
+	platformRemoveCmd.ValidArgsFunction = completeArgs(completeFavoritePlatforms)
+	platformRenameCmd.ValidArgsFunction = completeArgs(completePlatforms, completePlatforms)
+	platformAliasCmd.ValidArgsFunction = completeArgs(nil, completePlatforms)
+	platformUnaliasCmd.ValidArgsFunction = completeArgs(completePlatformAliases)
+	tickerUnmapCmd.ValidArgsFunction = completeArgs(completeCustomTickers)
+	themeSetCmd.ValidArgsFunction = completeArgs(completeValues(themeNames...))
+	columnsSetCmd.ValidArgsFunction = completeArgs(completeValues(sortedStringKeys(listTables)...))
+	columnsResetCmd.ValidArgsFunction = completeArgs(completeValues(sortedStringKeys(listTables)...))
+	rootCmd.RegisterFlagCompletionFunc("portfolio", completeFlag(completePortfolioNames))
+	summaryCmd.RegisterFlagCompletionFunc("currency", completeFlag(completeValues(prices.SupportedCurrencies...)))
+	for _, cmd := range []*cobra.Command{dashboardCmd, summaryCmd} {
+		cmd.RegisterFlagCompletionFunc("baseline", completeFlag(completeSnapshotIDs))
+	}
+}
+
+// isCompletionCmd reports whether cmd generates completion scripts or answers
+// a completion request from the shell
+func isCompletionCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return true
+		}
+	}
+	return false
+}
+
+// completionPortfolio returns the portfolio for completion, opening it on
+// first use since completion skips the root command's initialization.
+// It returns nil if the portfolio cannot be opened.
+func completionPortfolio() *portfolio.Portfolio {
+	if p == nil {
+		if _, err := openPortfolio(); err != nil {
+			return nil
+		}
+	}
+	return p
+}
+
+// completeArgs returns a completion function that completes the positional
+// argument at index i with completers[i]. Arguments without a completer get
+// no suggestions, and file completion is disabled.
+func completeArgs(completers ...completer) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= len(completers) || completers[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completers[len(args)](toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeEach returns a completion function that completes every
+// positional argument with c, leaving out values already given
+func completeEach(c completer) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		given := make(map[string]bool)
+		for _, arg := range args {
+			given[arg] = true
+		}
+		var out []cobra.Completion
+		for _, comp := range c(toComplete) {
+			value, _, _ := strings.Cut(comp, "\t")
+			if !given[value] {
+				out = append(out, comp)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeFlag returns a flag completion function for c
+func completeFlag(c completer) cobra.CompletionFunc {
+	return completeArgs(c)
+}
+
+// completeValues returns a completer for a fixed set of values
+func completeValues(values ...string) completer {
+	return func(toComplete string) []cobra.Completion {
+		return matching(values, toComplete)
+	}
+}
+
+// matching returns the values starting with prefix, ignoring case
+func matching(values []string, prefix string) []cobra.Completion {
+	var out []cobra.Completion
+	for _, v := range values {
+		if strings.HasPrefix(strings.ToLower(v), strings.ToLower(prefix)) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// completeCoins suggests coins that appear in the portfolio
+func completeCoins(toComplete string) []cobra.Completion {
+	pf := completionPortfolio()
+	if pf == nil {
+		return nil
+	}
+	coins, err := pf.GetCoins()
+	if err != nil {
+		return nil
+	}
+	return matching(coins, toComplete)
+}
+
+// completePlatforms suggests the favorite platforms and those that appear
+// in the portfolio
+func completePlatforms(toComplete string) []cobra.Completion {
+	if completionPortfolio() == nil {
+		return nil
+	}
+	platforms, err := platformRegistry()
+	if err != nil {
+		return nil
+	}
+	return matching(platforms, toComplete)
+}
+
+// recordIDs returns a completer for the IDs of the records returned by list,
+// described by describe
+func recordIDs[T any](list func(*portfolio.Portfolio) ([]T, error), describe func(T) (id, desc string)) completer {
+	return func(toComplete string) []cobra.Completion {
+		pf := completionPortfolio()
+		if pf == nil {
+			return nil
+		}
+		records, err := list(pf)
+		if err != nil {
+			return nil
+		}
+		var out []cobra.Completion
+		for _, r := range records {
+			id, desc := describe(r)
+			if strings.HasPrefix(id, toComplete) {
+				out = append(out, cobra.CompletionWithDesc(id, desc))
+			}
+		}
+		return out
+	}
+}
+
+var (
+	completeHoldingIDs = recordIDs((*portfolio.Portfolio).ListHoldings, func(h models.Holding) (string, string) {
+		return h.ID, fmt.Sprintf("%s %s @ %s on %s", formatAmount(h.Amount.InexactFloat64()), h.Coin, formatUSD(h.PurchasePriceUSD.InexactFloat64()), h.Date)
+	})
+	completeSaleIDs = recordIDs((*portfolio.Portfolio).ListSales, func(s models.Sale) (string, string) {
+		return s.ID, fmt.Sprintf("%s %s @ %s on %s", formatAmount(s.Amount.InexactFloat64()), s.Coin, formatUSD(s.SellPriceUSD.InexactFloat64()), s.Date)
+	})
+	completeLoanIDs = recordIDs((*portfolio.Portfolio).ListLoans, func(l models.Loan) (string, string) {
+		return l.ID, fmt.Sprintf("%s %s on %s", formatAmount(l.Amount.InexactFloat64()), l.Coin, l.Platform)
+	})
+	completeStakeIDs = recordIDs((*portfolio.Portfolio).ListStakes, func(st models.Stake) (string, string) {
+		return st.ID, fmt.Sprintf("%s %s on %s", formatAmount(st.Amount.InexactFloat64()), st.Coin, st.Platform)
+	})
+	completeSwapIDs = recordIDs((*portfolio.Portfolio).ListSwaps, func(sw models.Swap) (string, string) {
+		return sw.ID, fmt.Sprintf("%s %s -> %s %s on %s", formatAmount(sw.FromAmount.InexactFloat64()), sw.FromCoin, formatAmount(sw.ToAmount.InexactFloat64()), sw.ToCoin, sw.Date)
+	})
+	completeTransferIDs = recordIDs((*portfolio.Portfolio).ListTransfers, func(t models.Transfer) (string, string) {
+		return t.ID, fmt.Sprintf("%s %s %s -> %s", formatAmount(t.Amount.InexactFloat64()), t.Coin, platformLabel(t.FromPlatform), platformLabel(t.ToPlatform))
+	})
+	completeTrashIDs = recordIDs((*portfolio.Portfolio).ListTrash, func(item models.TrashItem) (string, string) {
+		return item.ID, fmt.Sprintf("%s %s %s", item.Type, formatAmount(item.Amount.InexactFloat64()), item.Coin)
+	})
+)
+
+// completeSnapshotIDs suggests snapshot IDs, described by date and net value,
+// and the names of pinned snapshots
+func completeSnapshotIDs(toComplete string) []cobra.Completion {
+	// Opening the portfolio resolves the data directory holding the snapshots
+	if completionPortfolio() == nil {
+		return nil
+	}
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return nil
+	}
+	var out []cobra.Completion
+	for _, snap := range snapshots {
+		desc := fmt.Sprintf("%s %s", formatSnapshotTime(snap.Timestamp), formatUSD(snap.NetValue))
+		if strings.HasPrefix(snap.ID, toComplete) {
+			out = append(out, cobra.CompletionWithDesc(snap.ID, desc))
+		}
+		if snap.Pin != "" && strings.HasPrefix(strings.ToLower(snap.Pin), strings.ToLower(toComplete)) {
+			out = append(out, cobra.CompletionWithDesc(snap.Pin, desc))
+		}
+	}
+	return out
+}
+
+// completePortfolioNames suggests registered portfolio names
+func completePortfolioNames(toComplete string) []cobra.Completion {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	return matching(sortedStringKeys(cfg.GetAllPortfolios()), toComplete)
+}
+
+// completeWatchlist suggests watched tickers
+func completeWatchlist(toComplete string) []cobra.Completion {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	return matching(cfg.GetWatchlist(), toComplete)
+}
+
+// completeFavoritePlatforms suggests favorite platforms
+func completeFavoritePlatforms(toComplete string) []cobra.Completion {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	return matching(cfg.GetFavoritePlatforms(), toComplete)
+}
+
+// completePlatformAliases suggests platform aliases, described by platform
+func completePlatformAliases(toComplete string) []cobra.Completion {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	aliases := cfg.GetPlatformAliases()
+	var out []cobra.Completion
+	for _, alias := range matching(sortedStringKeys(aliases), toComplete) {
+		out = append(out, cobra.CompletionWithDesc(alias, aliases[alias]))
+	}
+	return out
+}
+
+// completeCustomTickers suggests tickers with a custom CoinGecko mapping
+func completeCustomTickers(toComplete string) []cobra.Completion {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	return matching(sortedStringKeys(cfg.GetAllTickerMappings()), toComplete)
+}
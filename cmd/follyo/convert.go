@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:     "convert AMOUNT FROM [in|to] TO",
+	Aliases: []string{"cv"},
+	Short:   "Convert between coins and USD using live prices",
+	Long: `Convert an amount between coins or USD using live CoinGecko prices.
+
+Examples:
+  follyo convert 0.35 ETH in USD
+  follyo convert 500 USD in SOL
+  follyo convert "2 ETH to BTC"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		amount, from, to, err := parseConversion(strings.Join(args, " "))
+		if err != nil {
+			return &exitError{code: exitUsage, err: err}
+		}
+
+		// Only fetch prices for the non-USD sides of the conversion
+		var coins []string
+		for _, c := range []string{from, to} {
+			if c != "USD" {
+				coins = append(coins, c)
+			}
+		}
+
+		livePrices := map[string]float64{"USD": 1}
+		if len(coins) > 0 {
+			ps, err := newPriceService()
+			if err != nil {
+				return err
+			}
+			fetched, err := ps.GetPrices(coins)
+			if err != nil {
+				return ioError(fmt.Errorf("could not fetch prices: %w", err))
+			}
+			for coin, price := range fetched {
+				livePrices[coin] = price
+			}
+		}
+
+		for _, c := range coins {
+			if _, ok := livePrices[c]; !ok {
+				return notFoundErrorf("no price available for %s", c)
+			}
+		}
+
+		result := amount * safeDivide(livePrices[from], livePrices[to])
+		fmt.Fprintf(osStdout, "%s %s = %s\n", formatAmount(amount), from, formatConverted(result, to))
+		return nil
+	},
+}
+
+// parseConversion parses queries like "0.35 ETH in USD" or "500 USD to SOL"
+func parseConversion(query string) (float64, string, string, error) {
+	fields := strings.Fields(query)
+	if len(fields) == 4 {
+		sep := strings.ToLower(fields[2])
+		if sep != "in" && sep != "to" {
+			return 0, "", "", fmt.Errorf("invalid conversion %q: expected 'in' or 'to' between currencies", query)
+		}
+		fields = append(fields[:2], fields[3])
+	}
+	if len(fields) != 3 {
+		return 0, "", "", fmt.Errorf("invalid conversion %q: expected AMOUNT FROM in TO", query)
+	}
+
+	amount, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("invalid amount: %s", fields[0])
+	}
+	if amount <= 0 {
+		return 0, "", "", fmt.Errorf("amount must be positive: %s", fields[0])
+	}
+
+	return amount, strings.ToUpper(fields[1]), strings.ToUpper(fields[2]), nil
+}
+
+// formatConverted formats a conversion result in the target currency
+func formatConverted(amount float64, currency string) string {
+	if currency == "USD" {
+		return formatUSD(amount)
+	}
+	return formatAmount(amount) + " " + currency
+}
+
+// newPriceService creates a PriceService with the custom ticker mappings and
+// manual prices applied, disk-backed price and coin metadata caches stored
+// next to the portfolio data, and retries reported on stderr
+func newPriceService() (*prices.PriceService, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	ps := prices.New()
+	if err := ps.SetCacheFile(filepath.Join(filepath.Dir(dataPath), "price-cache.json")); err != nil {
+		fmt.Fprintf(osStderr, "Warning: ignoring price cache: %v\n", err)
+	}
+	if err := ps.SetMetadataCacheFile(filepath.Join(filepath.Dir(dataPath), "coin-metadata.json")); err != nil {
+		fmt.Fprintf(osStderr, "Warning: ignoring coin metadata cache: %v\n", err)
+	}
+	for ticker, geckoID := range cfg.GetAllTickerMappings() {
+		ps.AddCoinMapping(ticker, geckoID)
+	}
+	for ticker, mp := range cfg.GetManualPrices() {
+		ps.SetManualPrice(ticker, mp.Price)
+	}
+	if ttl := cfg.GetPriceCacheTTL(); ttl > 0 {
+		ps.SetCacheTTL(ttl)
+	}
+	if attempts := cfg.GetPriceAttempts(); attempts > 0 {
+		ps.SetRetryPolicy(attempts, prices.DefaultRetryDelay)
+	}
+	ps.SetRetryNotifier(printRetryStatus)
+	return ps, nil
+}
+
+// printRetryStatus tells the user a price request is being retried, so a
+// rate limited fetch doesn't look like a hang
+func printRetryStatus(s prices.RetryStatus) {
+	reason := "CoinGecko request failed"
+	if s.RateLimited {
+		reason = "Rate limited by CoinGecko"
+	}
+	fmt.Fprintf(osStderr, "%s, retrying in %s (attempt %d of %d)...\n",
+		reason, s.Wait.Round(100*time.Millisecond), s.Attempt+1, s.MaxAttempts)
+}
+
+// getPricesOrStale fetches live prices, falling back to the last known
+// prices from the price cache when fetching fails. stale is when the oldest
+// fallback price was fetched, and zero for live prices. The fetch error is
+// only returned when no coin has a last fetched price.
+func getPricesOrStale(ps *prices.PriceService, coins []string) (map[string]float64, time.Time, error) {
+	livePrices, err := ps.GetPrices(coins)
+	if err == nil {
+		return livePrices, time.Time{}, nil
+	}
+	lastKnown, fetchedAt := ps.GetLastKnownPrices(coins)
+	if fetchedAt.IsZero() {
+		return nil, time.Time{}, err // Nothing was ever fetched
+	}
+	fmt.Fprintf(osStderr, "Warning: Could not fetch prices, using last known prices: %v\n", err)
+	return lastKnown, fetchedAt, nil
+}
+
+// printStalePrices notes that the prices shown were fetched at fetchedAt
+func printStalePrices(fetchedAt time.Time) {
+	fmt.Fprintf(osStdout, "Prices: %s, last fetched %s\n",
+		staleLabel(fetchedAt, time.Now()), fetchedAt.Local().Format("2006-01-02 15:04"))
+}
+
+// staleLabel describes prices fetched at fetchedAt, e.g. "stale (3h old)"
+func staleLabel(fetchedAt, now time.Time) string {
+	age := now.Sub(fetchedAt)
+	var ago string
+	switch {
+	case age < time.Hour:
+		ago = fmt.Sprintf("%dm", max(1, int(age.Minutes())))
+	case age < 48*time.Hour:
+		ago = fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		ago = fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+	return fmt.Sprintf("stale (%s old)", ago)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Take portfolio snapshots on a schedule",
+	Long: `Run in the foreground and save a snapshot on a schedule.
+
+Use --every for a fixed interval (e.g. 6h) or --at for a daily time
+(e.g. 09:00). Defaults come from "snapshot_interval" and "snapshot_time"
+in the config, falling back to one snapshot every 24h.
+
+With --metrics-addr (e.g. :9101) the daemon also serves /metrics in the
+Prometheus text format: per-coin amounts, prices, values, and loans, plus
+total holdings, loans, net value, and profit/loss. Values use live prices,
+cached for 2 minutes between scrapes.
+
+Before each snapshot, the purchases of DCA plans that are due are made
+(see 'follyo dca plan'), so the snapshot includes them.
+
+Only one daemon can run per data directory. Snapshot writes are locked,
+so interactive commands can be used safely while the daemon runs.
+Run it in the background with your shell, systemd, or launchd.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		every, _ := cmd.Flags().GetDuration("every")
+		at, _ := cmd.Flags().GetString("at")
+		metricsAddr, _ := cmd.Flags().GetString("metrics-addr")
+
+		if every == 0 && at == "" {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			cfgInterval, cfgAt := cfg.GetSnapshotSchedule()
+			at = cfgAt
+			if cfgInterval != "" {
+				d, err := time.ParseDuration(cfgInterval)
+				if err != nil {
+					return usageErrorf("invalid snapshot_interval in config: %s", cfgInterval)
+				}
+				every = d
+			}
+		}
+		if every == 0 && at == "" {
+			every = 24 * time.Hour
+		}
+		if _, err := nextSnapshotTime(time.Now(), every, at); err != nil {
+			return &exitError{code: exitUsage, err: err}
+		}
+
+		lock, err := storage.TryLock(filepath.Join(filepath.Dir(dataPath), "daemon.lock"))
+		if errors.Is(err, storage.ErrLocked) {
+			return errors.New("another follyo daemon is already running for this data directory")
+		}
+		if err != nil {
+			return ioError(err)
+		}
+		defer lock.Release()
+
+		// Serializes portfolio access between metrics scrapes and snapshots
+		var mu sync.Mutex
+		if metricsAddr != "" {
+			ln, err := net.Listen("tcp", metricsAddr)
+			if err != nil {
+				return ioError(err)
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metricsHandler(&mu))
+			server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			go server.Serve(ln)
+			defer server.Close()
+			fmt.Fprintf(osStdout, "Serving metrics at http://%s/metrics\n", ln.Addr())
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(stop)
+
+		for {
+			next, _ := nextSnapshotTime(time.Now(), every, at)
+			fmt.Fprintf(osStdout, "Next snapshot at %s\n", next.Format("2006-01-02 15:04"))
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-stop:
+				timer.Stop()
+				fmt.Fprintln(osStdout, "Stopping daemon")
+				return nil
+			case <-timer.C:
+			}
+
+			// A failed DCA run or snapshot (e.g. CoinGecko unreachable) is
+			// retried next cycle
+			mu.Lock()
+			runScheduledDCA()
+			snap, err := takeSnapshot("", "scheduled", nil)
+			mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(osStderr, "%s Error taking snapshot: %v\n", time.Now().Format("2006-01-02 15:04"), err)
+				continue
+			}
+			fmt.Fprintf(osStdout, "%s Saved snapshot %s: net value %s\n",
+				time.Now().Format("2006-01-02 15:04"), snap.ID, formatUSD(snap.NetValue))
+		}
+	},
+}
+
+// runScheduledDCA makes the DCA purchases that are due, reporting errors
+// without stopping the daemon
+func runScheduledDCA() {
+	cfg, err := loadConfig()
+	if err == nil && len(cfg.GetDCAPlans()) > 0 {
+		var ps *prices.PriceService
+		if ps, err = newPriceService(); err == nil {
+			_, err = runDCAPlans(ps, models.Today())
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s Error running DCA plans: %v\n", time.Now().Format("2006-01-02 15:04"), err)
+	}
+}
+
+// nextSnapshotTime returns when the next scheduled snapshot is due. A daily
+// time at ("HH:MM") takes precedence over the fixed interval every.
+func nextSnapshotTime(now time.Time, every time.Duration, at string) (time.Time, error) {
+	if at != "" {
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %s (expected HH:MM)", at)
+		}
+		next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, nil
+	}
+	if every < time.Minute {
+		return time.Time{}, fmt.Errorf("interval %s is too short (minimum 1m)", every)
+	}
+	return now.Add(every), nil
+}
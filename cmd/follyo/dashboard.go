@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:     "dashboard",
+	Aliases: []string{"dash"},
+	Short:   "Show net value, top movers, and recent transactions",
+	Long: `Show an overview of the portfolio: net value at live prices with the
+change since the last snapshot, the held coins that moved most over the
+last 24h, and the most recent transactions. When prices can't be fetched,
+the last known prices are used and labeled as stale.
+
+With --baseline, or "baseline_snapshot" set with 'follyo config set', the
+net value change and P/L are measured from that snapshot, given by ID or
+pinned name (see 'follyo snapshot pin'), e.g. to follow the P/L since
+January 1st. --baseline none shows the P/L since the first purchase.
+
+Set "default_view": "dashboard" in config.json to show the
+dashboard when follyo is run without a command.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		moverCount, _ := cmd.Flags().GetInt("movers")
+		recentCount, _ := cmd.Flags().GetInt("recent")
+		if moverCount < 1 || recentCount < 1 {
+			return usageErrorf("--movers and --recent must be at least 1")
+		}
+		baseline, err := baselineSnapshot(cmd)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(osStdout, "Fetching live prices...")
+		snap, _, stale, err := valuePortfolioAllowStale(true)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(osStdout, "\n=== DASHBOARD ===")
+		if !stale.IsZero() {
+			printStalePrices(stale)
+		}
+
+		// Net value, compared with the baseline or else the latest snapshot
+		line := fmt.Sprintf("\nNet value:  %s", formatUSD(snap.NetValue))
+		compared := baseline
+		if compared == nil {
+			snapshots, err := listSnapshots()
+			if err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not load snapshots: %v\n", err)
+			} else if len(snapshots) > 0 {
+				compared = &snapshots[len(snapshots)-1]
+			}
+		}
+		if compared != nil {
+			change := snap.NetValue - compared.NetValue
+			line += fmt.Sprintf("  (%s since %s)", colorByValue(formatSignedUSD(change), change), snapshotLabel(*compared))
+		}
+		fmt.Fprintln(osStdout, line)
+		fmt.Fprintf(osStdout, "Holdings:   %s\n", formatUSD(snap.HoldingsValue))
+		fmt.Fprintf(osStdout, "Loans:      %s\n", formatUSD(snap.LoansValue))
+
+		// P/L since the first purchase, or since the baseline
+		pl, percent, realized, unrealized := snap.ProfitLoss, snap.ProfitLossPercent, snap.RealizedPL, snap.UnrealizedPL
+		label := "P/L:       "
+		if baseline != nil {
+			since := portfolio.GetProfitLossSince(*baseline, snap)
+			pl, percent, realized, unrealized = since.ProfitLoss, since.Percent, since.Realized, since.Unrealized
+			label = "P/L since " + snapshotLabel(*baseline) + ":"
+		}
+		fmt.Fprintf(osStdout, "%s %s\n", label,
+			colorByValue(fmt.Sprintf("%s (%+.2f%%)", formatSignedUSD(pl), percent), pl))
+		fmt.Fprintf(osStdout, "  Realized:   %s\n", colorByValue(formatSignedUSD(realized), realized))
+		fmt.Fprintf(osStdout, "  Unrealized: %s\n", colorByValue(formatSignedUSD(unrealized), unrealized))
+
+		// Top movers among held coins
+		fmt.Fprintln(osStdout, "\nTOP MOVERS (24h):")
+		var market map[string]prices.MarketData
+		if len(snap.CoinValues) > 0 && stale.IsZero() {
+			ps, err := newPriceService()
+			if err != nil {
+				return err
+			}
+			coins := make([]string, 0, len(snap.CoinValues))
+			for coin := range snap.CoinValues {
+				coins = append(coins, coin)
+			}
+			// Movers are informational, so a failure only hides them
+			market, err = ps.GetMarketData(coins)
+			if err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not fetch price changes: %v\n", err)
+			}
+		}
+		movers := topMovers(market, moverCount)
+		if len(movers) > 0 {
+			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+			for _, coin := range movers {
+				md := market[coin]
+				change := fmt.Sprintf("%+.2f%%", md.Change24h)
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n",
+					coin, formatUSD(md.Price), colorByValue(change, md.Change24h), formatUSD(snap.CoinValues[coin].ValueUSD))
+			}
+			w.Flush()
+		} else {
+			fmt.Fprintln(osStdout, "  (none)")
+		}
+
+		// Most recent transactions, oldest first like the history ledger
+		fmt.Fprintln(osStdout, "\nRECENT TRANSACTIONS:")
+		history, err := p.GetHistory(portfolio.Filter{})
+		if err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			fmt.Fprintln(osStdout, "  (none)")
+			return nil
+		}
+		if len(history) > recentCount {
+			history = history[len(history)-recentCount:]
+		}
+		printLedger(history)
+		return nil
+	},
+}
+
+// topMovers returns up to n coins with the largest 24h price change either
+// way, largest first
+func topMovers(market map[string]prices.MarketData, n int) []string {
+	coins := make([]string, 0, len(market))
+	for coin := range market {
+		coins = append(coins, coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		a, b := math.Abs(market[coins[i]].Change24h), math.Abs(market[coins[j]].Change24h)
+		if a != b {
+			return a > b
+		}
+		return coins[i] < coins[j]
+	})
+	if len(coins) > n {
+		coins = coins[:n]
+	}
+	return coins
+}
+
+// runDefaultView shows what follyo displays without a command: the help, or
+// the dashboard when "default_view" is set to it
+func runDefaultView(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.GetDefaultView() == "dashboard" {
+		return dashboardCmd.RunE(dashboardCmd, args)
+	}
+	return cmd.Help()
+}
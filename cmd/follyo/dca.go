@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var dcaCmd = &cobra.Command{
+	Use:   "dca COIN",
+	Short: "Show dollar-cost-average statistics for a coin",
+	Long: `Show how a coin was accumulated: number of buys, total invested,
+average purchase price, and the break-even price of the coins still held.
+
+The current price is fetched from CoinGecko and compared to the average
+entry. Use --no-prices to disable price fetching.
+
+Recurring purchases are set up with 'follyo dca plan add' and made by
+'follyo dca run' or the daemon.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stats, err := p.GetDCAStats(args[0])
+		if err != nil {
+			return err
+		}
+		if stats.Buys == 0 {
+			fmt.Fprintf(osStdout, "No purchases of %s found.\n", stats.Coin)
+			return nil
+		}
+
+		fmt.Fprintf(osStdout, "=== %s DCA ===\n", stats.Coin)
+		buys := fmt.Sprintf("%d", stats.Buys)
+		if stats.Buys > 1 {
+			buys += fmt.Sprintf(" (%s to %s)", stats.FirstBuy, stats.LastBuy)
+		} else {
+			buys += fmt.Sprintf(" (%s)", stats.FirstBuy)
+		}
+		fmt.Fprintf(osStdout, "Buys:           %s\n", buys)
+		fmt.Fprintf(osStdout, "Total Bought:   %s %s\n", formatAmount(stats.Amount.InexactFloat64()), stats.Coin)
+		fmt.Fprintf(osStdout, "Total Invested: %s\n", formatUSD(stats.InvestedUSD.InexactFloat64()))
+		fmt.Fprintf(osStdout, "Average Price:  %s\n", formatUSD(stats.AveragePriceUSD.InexactFloat64()))
+		if stats.SoldAmount.IsPositive() {
+			fmt.Fprintf(osStdout, "Sold:           %s %s for %s\n", formatAmount(stats.SoldAmount.InexactFloat64()), stats.Coin, formatUSD(stats.ProceedsUSD.InexactFloat64()))
+		}
+		fmt.Fprintf(osStdout, "Held:           %s %s\n", formatAmount(stats.Held.InexactFloat64()), stats.Coin)
+		if stats.Held.IsPositive() {
+			fmt.Fprintf(osStdout, "Break-even:     %s\n", formatUSD(stats.BreakEvenUSD.InexactFloat64()))
+		}
+
+		if noPrices, _ := cmd.Flags().GetBool("no-prices"); noPrices {
+			return nil
+		}
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		livePrices, err := ps.GetPrices([]string{stats.Coin})
+		if err != nil {
+			fmt.Fprintf(osStderr, "Warning: Could not fetch prices: %v\n", err)
+			return nil
+		}
+		defer autoSnapshot()
+
+		price, ok := livePrices[stats.Coin]
+		if !ok {
+			fmt.Fprintf(osStdout, "Current Price:  N/A\n")
+			return nil
+		}
+		distance := stats.DistanceFromAverage(price)
+		fmt.Fprintf(osStdout, "Current Price:  %s (%s)\n", formatUSD(price),
+			colorByValue(fmt.Sprintf("%+.1f%% vs average", distance), distance))
+		return nil
+	},
+}
+
+var dcaPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Manage recurring purchases",
+	Long: `A DCA plan buys a coin daily, weekly, or monthly, either a fixed amount
+of the coin or a fixed USD value, at the live price. Purchases are made
+by 'follyo dca run', e.g. from cron, or by the daemon before each
+snapshot. Plans are stored in config.json.`,
+}
+
+var dcaPlanAddCmd = &cobra.Command{
+	Use:   "add COIN",
+	Short: "Add a recurring purchase",
+	Example: `  follyo dca plan add BTC --usd 100 --every weekly
+  follyo dca plan add ETH --amount 0.05 --every monthly --start 2024-07-01 -p Kraken`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		amount, _ := cmd.Flags().GetFloat64("amount")
+		usd, _ := cmd.Flags().GetFloat64("usd")
+		if (amount > 0) == (usd > 0) || amount < 0 || usd < 0 {
+			return usageErrorf("set either --amount or --usd to a positive value")
+		}
+		every, _ := cmd.Flags().GetString("every")
+		if !slices.Contains(config.DCACadences, every) {
+			return usageErrorf("invalid --every %q (use %s)", every, strings.Join(config.DCACadences, ", "))
+		}
+		startFlag, _ := cmd.Flags().GetString("start")
+		start, err := parseDate(startFlag, "start")
+		if err != nil {
+			return err
+		}
+		if start.IsZero() {
+			start = models.Today()
+		}
+		platform, err := platformFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		plan := config.DCAPlan{
+			Coin:     strings.ToUpper(args[0]),
+			Amount:   amount,
+			USD:      usd,
+			Platform: platform,
+			Every:    every,
+			Start:    start.String(),
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.AddDCAPlan(plan); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Added DCA plan: %s, first on %s\n", describeDCAPlan(plan), plan.Start)
+		return nil
+	},
+}
+
+var dcaPlanListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recurring purchases",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		plans := cfg.GetDCAPlans()
+		if len(plans) == 0 {
+			fmt.Fprintln(osStdout, "No DCA plans. Add one with 'follyo dca plan add COIN --usd 100'.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tPlan\tPlatform\tLast\tNext")
+		for i, plan := range plans {
+			last := plan.LastDue
+			if last == "" {
+				last = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, describeDCAPlan(plan), platformLabel(plan.Platform), last, nextDCADate(plan))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var dcaPlanRemoveCmd = &cobra.Command{
+	Use:   "remove NUMBER",
+	Short: "Remove a recurring purchase by its number in 'dca plan list'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		plans := cfg.GetDCAPlans()
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return usageErrorf("invalid plan number %s", args[0])
+		}
+		if n < 1 || n > len(plans) {
+			return notFoundErrorf("DCA plan %d not found", n)
+		}
+		if _, err := cfg.RemoveDCAPlan(plans[n-1]); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Removed DCA plan: %s\n", describeDCAPlan(plans[n-1]))
+		return nil
+	},
+}
+
+var dcaRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Make the recurring purchases that are due",
+	Long: `Record a purchase at the live price for each DCA plan due today or
+earlier and not yet bought. A plan that missed several dates, e.g. while
+the computer was off, is bought once, dated today. Purchases are tagged
+DCA and written to the log.
+
+Use --dry-run to list the due plans without buying.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(cfg.GetDCAPlans()) == 0 {
+			fmt.Fprintln(osStdout, "No DCA plans to run")
+			return nil
+		}
+		if dryRun {
+			due := 0
+			for _, plan := range cfg.GetDCAPlans() {
+				if date, _, ok := dcaDue(plan, models.Today()); ok {
+					fmt.Fprintf(osStdout, "Due %s: %s\n", date, describeDCAPlan(plan))
+					due++
+				}
+			}
+			if due == 0 {
+				fmt.Fprintln(osStdout, "No DCA purchases due")
+			}
+			return nil
+		}
+
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		bought, err := runDCAPlans(ps, models.Today())
+		if err != nil {
+			return err
+		}
+		if bought == 0 {
+			fmt.Fprintln(osStdout, "No DCA purchases due")
+		}
+		return nil
+	},
+}
+
+// describeDCAPlan formats a plan, e.g. "$100.00 of BTC weekly"
+func describeDCAPlan(plan config.DCAPlan) string {
+	if plan.USD > 0 {
+		return fmt.Sprintf("%s of %s %s", formatUSD(plan.USD), plan.Coin, plan.Every)
+	}
+	return fmt.Sprintf("%s %s %s", formatAmount(plan.Amount), plan.Coin, plan.Every)
+}
+
+// dcaDate returns the nth date of a plan's schedule starting on start.
+// Monthly dates fall on the start day, or the month's last day if shorter.
+func dcaDate(every string, start models.Date, n int) models.Date {
+	switch every {
+	case "daily":
+		return start.AddDays(n)
+	case "weekly":
+		return start.AddDays(7 * n)
+	}
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return models.NewDate(first.Year(), first.Month(), min(start.Day(), lastDay))
+}
+
+// dcaDue returns the latest date on or before today that plan is due and
+// not yet bought, and how many earlier unbought dates it passes over
+func dcaDue(plan config.DCAPlan, today models.Date) (models.Date, int, bool) {
+	start, err := models.ParseDate(plan.Start)
+	if err != nil {
+		return models.Date{}, 0, false
+	}
+	var due models.Date
+	count := 0
+	for n := 0; ; n++ {
+		date := dcaDate(plan.Every, start, n)
+		if date.After(today) {
+			break
+		}
+		if date.String() > plan.LastDue {
+			due = date
+			count++
+		}
+	}
+	if count == 0 {
+		return models.Date{}, 0, false
+	}
+	return due, count - 1, true
+}
+
+// nextDCADate returns the first date of plan's schedule not yet bought
+func nextDCADate(plan config.DCAPlan) models.Date {
+	start, err := models.ParseDate(plan.Start)
+	if err != nil {
+		return models.Date{}
+	}
+	for n := 0; ; n++ {
+		if date := dcaDate(plan.Every, start, n); date.String() > plan.LastDue {
+			return date
+		}
+	}
+}
+
+// runDCAPlans buys each plan due by today at the live price and returns
+// how many purchases were made. A plan whose coin has no price is skipped
+// with a warning and tried again next run.
+//
+// The daemon and 'follyo dca run' may run at once, so the plans are read,
+// bought, and marked done under a lock in the data directory. Each plan is
+// marked done before its purchase is recorded, and unmarked if that fails,
+// so a crash in between skips a purchase rather than making it twice.
+func runDCAPlans(ps *prices.PriceService, today models.Date) (int, error) {
+	lock, err := storage.AcquireLock(filepath.Join(filepath.Dir(dataPath), "dca.lock"))
+	if err != nil {
+		return 0, ioError(err)
+	}
+	defer lock.Release()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	type duePlan struct {
+		plan   config.DCAPlan
+		date   models.Date
+		missed int
+	}
+	var due []duePlan
+	var coins []string
+	for _, plan := range cfg.GetDCAPlans() {
+		if date, missed, ok := dcaDue(plan, today); ok {
+			due = append(due, duePlan{plan, date, missed})
+			coins = append(coins, plan.Coin)
+		}
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	livePrices, err := ps.GetPrices(coins)
+	if err != nil {
+		return 0, ioError(fmt.Errorf("could not fetch prices: %w", err))
+	}
+
+	bought := 0
+	for _, d := range due {
+		price, ok := livePrices[d.plan.Coin]
+		if !ok || price <= 0 {
+			fmt.Fprintf(osStderr, "Warning: no price for %s, DCA purchase skipped\n", d.plan.Coin)
+			continue
+		}
+		priceUSD := decimal.NewFromFloat(price)
+		amount := decimal.NewFromFloat(d.plan.Amount)
+		if d.plan.USD > 0 {
+			amount = models.Quo(decimal.NewFromFloat(d.plan.USD), priceUSD)
+		}
+
+		ran := d.plan
+		ran.LastDue = d.date.String()
+		updated, err := cfg.UpdateDCAPlan(d.plan, ran)
+		if err != nil {
+			return bought, ioError(err)
+		}
+		if !updated {
+			continue // The plan was edited or removed since it was read
+		}
+		holding, err := p.AddHolding(d.plan.Coin, amount, priceUSD, d.plan.Platform, "DCA plan", today.String(), "DCA")
+		if err != nil {
+			if _, undoErr := cfg.UpdateDCAPlan(ran, d.plan); undoErr != nil {
+				return bought, errors.Join(err, ioError(undoErr))
+			}
+			return bought, err
+		}
+		bought++
+		slog.Info("dca purchase", "coin", holding.Coin, "amount", holding.Amount, "price", price, "due", d.date.String(), "id", holding.ID)
+		fmt.Fprintf(osStdout, "DCA: bought %s %s @ %s (ID: %s)\n", formatAmount(holding.Amount.InexactFloat64()), holding.Coin, formatUSD(price), holding.ID)
+		if d.missed > 0 {
+			fmt.Fprintf(osStdout, "  %d earlier date(s) of this plan were missed and not bought\n", d.missed)
+		}
+	}
+	return bought, nil
+}
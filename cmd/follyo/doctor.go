@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the data files, config, and records for problems",
+	Long: `Check for problems and print how to fix each one:
+
+  - data files that can't be read, or were written by a newer follyo
+  - settings in config.json with invalid values
+  - coins without a CoinGecko price mapping
+  - records that contradict each other, such as negative balances, more
+    staked than held, or loans repaid more than they were for
+  - whether CoinGecko can be reached (skip with --offline)
+
+Exits with status 1 if a problem is found; warnings alone don't fail.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ps *prices.PriceService
+		if offline, _ := cmd.Flags().GetBool("offline"); !offline {
+			ps = prices.New()
+		}
+		return runDoctor(ps)
+	},
+}
+
+// Levels of doctor findings
+const (
+	levelOK   = "ok"
+	levelWarn = "warn"
+	levelFail = "fail"
+)
+
+// finding is the outcome of a doctor check
+type finding struct {
+	level   string
+	message string
+	fix     string
+}
+
+// runDoctor runs every check and prints its findings. CoinGecko is pinged
+// with ps, unless it is nil.
+func runDoctor(ps *prices.PriceService) error {
+	var findings []finding
+	add := func(level, fix, format string, args ...any) {
+		findings = append(findings, finding{level: level, message: fmt.Sprintf(format, args...), fix: fix})
+	}
+
+	cfg, err := config.New(configPath())
+	if err != nil {
+		add(levelFail, fmt.Sprintf("fix the JSON in %s, or move it aside to start from the defaults", configPath()),
+			"config %s can't be read: %v", configPath(), err)
+	} else {
+		add(levelOK, "", "config %s", configPath())
+		findings = append(findings, checkConfig(cfg)...)
+	}
+
+	if cfg != nil {
+		err = resolveDataPath(cfg)
+	} else if dataPath == "" {
+		dataPath = defaultDataPath()
+	}
+	if err != nil {
+		return err
+	}
+	portfolioOK := true
+	if cfg == nil || cfg.GetStorage() == "json" {
+		f := checkDataFile(dataPath, storage.SchemaVersion)
+		portfolioOK = f.level != levelFail
+		findings = append(findings, f, checkDataFile(snapshotsPath(), storage.SnapshotSchemaVersion))
+	} else {
+		add(levelOK, "", "SQLite storage %s", sqlitePathFor(dataPath))
+	}
+
+	// Open the portfolio only if it exists, so the doctor never creates one
+	if portfolioOK && (portfolioExists(dataPath) || cfg != nil && cfg.GetStorage() == "sqlite") {
+		s, err := openBackend(dataPath)
+		if err != nil {
+			add(levelFail, "", "portfolio can't be opened: %v", err)
+		} else {
+			p = portfolio.New(s)
+			records, err := checkRecords()
+			if err != nil {
+				return err
+			}
+			findings = append(findings, records...)
+		}
+	}
+
+	if ps != nil {
+		if err := ps.Ping(); err != nil {
+			add(levelWarn, "check your internet connection and proxy settings; meanwhile, last known prices are shown",
+				"CoinGecko can't be reached: %v", err)
+		} else {
+			add(levelOK, "", "CoinGecko is reachable")
+		}
+	}
+
+	return printFindings(findings)
+}
+
+// checkConfig checks settings that can be given invalid values by editing
+// config.json or through the environment
+func checkConfig(cfg *config.ConfigStore) []finding {
+	var findings []finding
+	if currency := cfg.GetDisplayCurrency(); !prices.IsSupportedCurrency(currency) {
+		findings = append(findings, finding{levelFail,
+			fmt.Sprintf("display_currency %s is not supported", currency),
+			fmt.Sprintf("follyo config set display_currency USD (supported: %s)", strings.Join(prices.SupportedCurrencies, ", "))})
+	}
+	if storageName := cfg.GetStorage(); storageName != "json" && storageName != "sqlite" {
+		findings = append(findings, finding{levelFail,
+			fmt.Sprintf("storage %q is not a storage backend", storageName),
+			"set \"storage\" to \"json\" or \"sqlite\" in " + configPath()})
+	}
+	if _, err := resolveTheme(cfg.GetTheme(), cfg.GetThemeColors(), true); err != nil {
+		findings = append(findings, finding{levelWarn,
+			fmt.Sprintf("theme: %v", err),
+			"follyo theme list, then follyo theme set NAME"})
+	}
+	if allocation := cfg.GetGoals().Allocation; len(allocation) > 0 {
+		if err := portfolio.ValidateTargets(allocation); err != nil {
+			findings = append(findings, finding{levelWarn,
+				fmt.Sprintf("goals target_allocation: %v", err),
+				"fix \"target_allocation\" under \"goals\" in " + configPath()})
+		}
+	}
+	return findings
+}
+
+// checkDataFile checks that a JSON data file can be read and has a schema
+// version this follyo understands. A missing file is fine.
+func checkDataFile(path string, version int) finding {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return finding{level: levelOK, message: fmt.Sprintf("%s doesn't exist yet", path)}
+	}
+	if err != nil {
+		return finding{levelFail, fmt.Sprintf("%s can't be read: %v", path, err), "check the file's permissions"}
+	}
+
+	var file struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		fix := "restore a backup with 'follyo backup restore FILE'"
+		if backup := storage.BackupPath(path, 1); isValidJSONFile(backup) {
+			fix = fmt.Sprintf("restore the previous version with: cp %s %s", backup, path)
+		}
+		return finding{levelFail, fmt.Sprintf("%s is not valid JSON: %v", path, err), fix}
+	}
+	if file.Version > version {
+		return finding{levelFail,
+			fmt.Sprintf("%s has schema version %d, newer than this follyo supports (%d)", path, file.Version, version),
+			"upgrade follyo"}
+	}
+	if file.Version < version {
+		return finding{level: levelOK, message: fmt.Sprintf("%s has schema version %d; it is upgraded to %d on the next change", path, file.Version, version)}
+	}
+	return finding{level: levelOK, message: fmt.Sprintf("%s (schema version %d)", path, file.Version)}
+}
+
+// isValidJSONFile reports whether path exists and holds valid JSON
+func isValidJSONFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && json.Valid(data)
+}
+
+// checkRecords checks the records in p for coins without a price mapping
+// and for records that contradict each other
+func checkRecords() ([]finding, error) {
+	var findings []finding
+
+	coins, err := p.GetCoins()
+	if err != nil {
+		return nil, err
+	}
+	// Custom mappings and manual prices come from the config, so this needs one that loads
+	if ps, err := newPriceService(); err == nil {
+		for _, coin := range ps.GetUnmappedTickers(coins) {
+			if ps.HasManualPrice(coin) {
+				continue
+			}
+			findings = append(findings, finding{levelWarn,
+				fmt.Sprintf("%s has no CoinGecko price mapping, so it has no live price", coin),
+				fmt.Sprintf("follyo ticker search %s %s, or follyo ticker price set %s PRICE", strings.ToLower(coin), coin, coin)})
+		}
+	}
+
+	problems, err := p.Check()
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range problems {
+		findings = append(findings, finding{levelFail, pr.Message, pr.Fix})
+	}
+	if len(problems) == 0 {
+		findings = append(findings, finding{level: levelOK, message: fmt.Sprintf("records are consistent (%d coins)", len(coins))})
+	}
+	return findings, nil
+}
+
+// printFindings prints findings with their fixes and a summary. It returns
+// an error if any check failed.
+func printFindings(findings []finding) error {
+	failed, warned := 0, 0
+	for _, f := range findings {
+		label := strings.ToUpper(f.level)
+		switch f.level {
+		case levelFail:
+			failed++
+			label = colorRedText(label)
+		case levelWarn:
+			warned++
+		default:
+			label = colorGreenText(label)
+		}
+		// Padded by hand, since color escape codes would throw off %-5s
+		fmt.Fprintf(osStdout, "%s%s %s\n", label, strings.Repeat(" ", 5-len(f.level)), f.message)
+		if f.fix != "" {
+			fmt.Fprintf(osStdout, "      Fix: %s\n", f.fix)
+		}
+	}
+
+	fmt.Fprintln(osStdout)
+	switch {
+	case failed > 0:
+		return fmt.Errorf("found %d problem(s) and %d warning(s)", failed, warned)
+	case warned > 0:
+		fmt.Fprintf(osStdout, "No problems found, %d warning(s)\n", warned)
+	default:
+		fmt.Fprintln(osStdout, "No problems found")
+	}
+	return nil
+}
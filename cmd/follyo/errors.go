@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes. Scripts rely on these, so existing values must not change.
+const (
+	exitOK       = 0 // success, including a prompt answered with no
+	exitFailure  = 1 // any error not covered below
+	exitUsage    = 2 // invalid arguments, flags, or values, or a prompt in non-interactive mode
+	exitNotFound = 3 // the requested record, snapshot, or setting does not exist
+	exitIO       = 4 // reading or writing files, or fetching from an API, failed
+)
+
+// exitError is an error that makes follyo exit with a specific code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf returns an error for invalid arguments or values
+func usageErrorf(format string, args ...any) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// notFoundErrorf returns an error for a record or setting that does not exist
+func notFoundErrorf(format string, args ...any) error {
+	return &exitError{code: exitNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// ioError marks err as a file or network failure. A nil err stays nil.
+func ioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitIO, err: err}
+}
+
+// exitCode returns the code follyo exits with after a command returns err
+func exitCode(err error) int {
+	var ee *exitError
+	var validationErr *portfolio.ValidationError
+	var pathErr *fs.PathError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ee):
+		return ee.code
+	case errors.As(err, &validationErr):
+		return exitUsage
+	case errors.Is(err, portfolio.ErrNotFound):
+		return exitNotFound
+	case errors.As(err, &pathErr), errors.Is(err, storage.ErrConflict), errors.Is(err, storage.ErrLocked):
+		return exitIO
+	}
+	return exitFailure
+}
+
+// markUsageErrors makes argument count errors of cmd and its subcommands
+// exit with exitUsage
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validate(c, args); err != nil {
+				return &exitError{code: exitUsage, err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// confirm asks a yes/no question on stdin. With --yes it answers yes without
+// asking; with --non-interactive it fails instead of waiting for input.
+func confirm(question string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+	if nonInteractive {
+		return false, usageErrorf("confirmation required; pass --yes to proceed without a prompt")
+	}
+
+	fmt.Fprintf(osStdout, "%s [y/N]: ", question)
+	input, _ := bufio.NewReader(osStdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/exchange"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var exchangeCmd = &cobra.Command{
+	Use:   "exchange",
+	Short: "Sync trades from exchange APIs",
+	Long: `Import purchases and sales from exchange trade history.
+
+Supported exchanges: ` + strings.Join(exchange.Supported, ", ") + `
+
+Create a read-only API key on the exchange and store it with
+'follyo exchange set'. Keys are saved in config.json, which is only
+readable by you.`,
+}
+
+var exchangeSetCmd = &cobra.Command{
+	Use:   "set EXCHANGE KEY SECRET",
+	Short: "Store a read-only API key for an exchange",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		if !exchange.IsSupported(name) {
+			return usageErrorf("unsupported exchange %s (supported: %s)",
+				name, strings.Join(exchange.Supported, ", "))
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetExchangeKey(name, config.APIKey{Key: args[1], Secret: args[2]}); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Stored API key for %s\n", name)
+		return nil
+	},
+}
+
+var exchangeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List exchanges with a stored API key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		names := cfg.GetExchangeNames()
+		if len(names) == 0 {
+			fmt.Fprintln(osStdout, "No exchange API keys stored.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Exchange\tAPI Key")
+		for _, name := range names {
+			key, _ := cfg.GetExchangeKey(name)
+			fmt.Fprintf(w, "%s\t%s\n", name, maskKey(key.Key))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var exchangeRemoveCmd = &cobra.Command{
+	Use:   "remove EXCHANGE",
+	Short: "Remove the stored API key for an exchange",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemoveExchangeKey(name)
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("no API key stored for %s", name)
+		}
+		fmt.Fprintf(osStdout, "Removed API key for %s\n", name)
+		return nil
+	},
+}
+
+var exchangeSyncCmd = &cobra.Command{
+	Use:   "sync EXCHANGE",
+	Short: "Propose purchases and sales from an exchange's trade history",
+	Long: `Fetch balances and trade history from an exchange and propose the
+trades as purchases and sales. Nothing is saved until you confirm.
+
+Imported records note the trade they came from, so syncing again only
+proposes new trades.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		key, ok := cfg.GetExchangeKey(name)
+		if !ok {
+			return notFoundErrorf("no API key stored for %s; run 'follyo exchange set %s KEY SECRET'", name, name)
+		}
+		client, err := exchange.New(name, key.Key, key.Secret)
+		if err != nil {
+			return &exitError{code: exitUsage, err: err}
+		}
+
+		return syncExchange(client)
+	},
+}
+
+// syncExchange proposes an exchange's new trades and imports them once confirmed
+func syncExchange(client exchange.Client) error {
+	fmt.Fprintf(osStdout, "Fetching balances and trades from %s...\n", client.Name())
+	balances, err := client.Balances()
+	if err != nil {
+		return ioError(err)
+	}
+
+	// Look up trades for coins held on the exchange or in the portfolio
+	coinSet := make(map[string]bool)
+	for coin := range balances {
+		coinSet[coin] = true
+	}
+	portfolioCoins, err := p.GetCoins()
+	if err != nil {
+		return err
+	}
+	for _, coin := range portfolioCoins {
+		coinSet[coin] = true
+	}
+	coins := make([]string, 0, len(coinSet))
+	for coin := range coinSet {
+		coins = append(coins, coin)
+	}
+	sortStrings(coins)
+
+	trades, err := client.Trades(coins)
+	if err != nil {
+		return ioError(err)
+	}
+	proposal, err := p.ProposeImports(trades, client.Name())
+	if err != nil {
+		return err
+	}
+
+	if len(balances) > 0 {
+		fmt.Fprintf(osStdout, "\n%s BALANCES:\n", strings.ToUpper(client.Name()))
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, coin := range sortedKeys(balances) {
+			fmt.Fprintf(w, "  %-8s\t%s\t\n", coin+":", formatAmountAligned(balances[coin]))
+		}
+		w.Flush()
+	}
+
+	if proposal.IsEmpty() {
+		fmt.Fprintf(osStdout, "\nNo new trades to import (%d already imported).\n", proposal.Skipped)
+		return nil
+	}
+
+	fmt.Fprintln(osStdout, "\nPROPOSED RECORDS:")
+	printProposal(proposal)
+	if proposal.Skipped > 0 {
+		fmt.Fprintf(osStdout, "(%d trades already imported)\n", proposal.Skipped)
+	}
+
+	count := len(proposal.Holdings) + len(proposal.Sales)
+	fmt.Fprintln(osStdout)
+	ok, err := confirm(fmt.Sprintf("Import %d records?", count))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(osStdout, "Cancelled.")
+		return nil
+	}
+
+	if err := p.ImportRecords(proposal); err != nil {
+		return err
+	}
+	fmt.Fprintf(osStdout, "Imported %d purchases and %d sales from %s\n",
+		len(proposal.Holdings), len(proposal.Sales), client.Name())
+	return nil
+}
+
+// printProposal prints proposed records in date order
+func printProposal(proposal portfolio.ImportProposal) {
+	type row struct {
+		date models.Date
+		line string
+	}
+	var rows []row
+	for _, h := range proposal.Holdings {
+		rows = append(rows, row{h.Date, fmt.Sprintf("%s\tBUY\t%s\t%s\t%s\t%s\t%s",
+			h.Date, h.Coin, formatAmount(h.Amount.InexactFloat64()), formatUSD(h.PurchasePriceUSD.InexactFloat64()), formatUSD(h.FeeUSD.InexactFloat64()), h.Notes)})
+	}
+	for _, s := range proposal.Sales {
+		rows = append(rows, row{s.Date, fmt.Sprintf("%s\tSELL\t%s\t%s\t%s\t%s\t%s",
+			s.Date, s.Coin, formatAmount(s.Amount.InexactFloat64()), formatUSD(s.SellPriceUSD.InexactFloat64()), formatUSD(s.FeeUSD.InexactFloat64()), s.Notes)})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].date.Before(rows[j].date) })
+
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Date\tType\tCoin\tAmount\tPrice/Unit\tFee\tTrade")
+	for _, r := range rows {
+		fmt.Fprintln(w, r.line)
+	}
+	w.Flush()
+}
+
+// maskKey shows only the last four characters of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", 8) + key[len(key)-4:]
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check the portfolio data for damage and repair it",
+	Long: `Check the portfolio data for damage and offer to repair it:
+
+  - records sharing an ID: exact copies are removed, others get a new ID
+  - dates that can't be parsed are cleared
+  - NaN or infinite numbers: fees and rates are removed, and records with
+    a NaN amount or price are removed
+  - more of a coin staked than held: the newest stakes are reduced
+  - sales of coins that were never bought and repayments of missing
+    loans, which are shown with how to fix them by hand
+
+The damaged data file is kept as its most recent backup. Asks before
+repairing, unless --fix is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+		return runFsck(fix)
+	},
+}
+
+// runFsck checks the portfolio and repairs it when fix is set or the user
+// agrees
+func runFsck(fix bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := resolveDataPath(cfg); err != nil {
+		return err
+	}
+	// Opening the portfolio creates it, which is no use here
+	if cfg.GetStorage() != "sqlite" && !portfolioExists(dataPath) {
+		fmt.Fprintf(osStdout, "No portfolio at %s\n", dataPath)
+		return nil
+	}
+	backend, err := openBackend(dataPath)
+	if err != nil {
+		return ioError(fmt.Errorf("initializing storage: %w", err))
+	}
+	p = portfolio.New(backend)
+	// Only a JSON data file can hold the damage Fsck finds
+	s, _ := backend.(*storage.Storage)
+
+	findings, err := fsckFindings(s)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(osStdout, "No problems found in %s\n", dataPath)
+		return nil
+	}
+	problemsErr := printFindings(findings)
+	if !fix {
+		if nonInteractive && !assumeYes {
+			return fmt.Errorf("%w; run 'follyo fsck --fix' to repair", problemsErr)
+		}
+		ok, err := confirm("Repair?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return problemsErr
+		}
+	}
+
+	if err := repairPortfolio(s); err != nil {
+		return err
+	}
+	findings, err = fsckFindings(s)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(osStdout, colorGreenText("Repaired"))
+		return nil
+	}
+	fmt.Fprintln(osStdout, "\nLeft to fix by hand:")
+	return printFindings(findings)
+}
+
+// fsckFindings returns the damage in the data file s, if given, or if there
+// is none, the records that contradict each other
+func fsckFindings(s *storage.Storage) ([]finding, error) {
+	var findings []finding
+	if s != nil {
+		damage, err := s.Fsck()
+		if err != nil {
+			return nil, ioError(err)
+		}
+		for _, d := range damage {
+			findings = append(findings, finding{levelFail, d.Message, d.Repair})
+		}
+		// The records can only be checked once the file loads
+		if len(findings) > 0 {
+			return findings, nil
+		}
+	}
+
+	problems, err := p.Check()
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range problems {
+		findings = append(findings, finding{levelFail, pr.Message, pr.Fix})
+	}
+	return findings, nil
+}
+
+// repairPortfolio repairs the data file s, if given, then reduces stakes
+// exceeding their coin's balance
+func repairPortfolio(s *storage.Storage) error {
+	if s != nil {
+		damage, err := s.Repair()
+		if err != nil {
+			return ioError(err)
+		}
+		for _, d := range damage {
+			fmt.Fprintf(osStdout, "Repaired %s: %s\n", d.Message, d.Repair)
+		}
+	}
+
+	trimmed, err := p.TrimStakes()
+	for _, st := range trimmed {
+		fmt.Fprintf(osStdout, "Reduced stake %s of %s %s to the balance held\n", st.ID, st.Amount, st.Coin)
+	}
+	return err
+}
@@ -3,45 +3,58 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
-// ANSI color codes
-const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-)
+// colorReset ends a colored span of text
+const colorReset = "\033[0m"
+
+// colorBold makes text bold, to highlight it
+const colorBold = "\033[1m"
 
 // colorEnabled checks if color output should be used
 func colorEnabled() bool {
-	// Check if stdout is a terminal
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return stdoutIsTerminal()
+}
+
+// stdoutIsTerminal checks if stdout is a terminal; a variable so tests can
+// stand in for one
+var stdoutIsTerminal = func() bool {
 	if f, ok := osStdout.(*os.File); ok {
 		return term.IsTerminal(int(f.Fd()))
 	}
 	return false
 }
 
-// colorize wraps text in ANSI color codes if colors are enabled
+// colorize wraps text in ANSI color codes if colors are enabled.
+// An empty color leaves text unchanged.
 func colorize(text, color string) string {
-	if !colorEnabled() {
+	if color == "" || !colorEnabled() {
 		return text
 	}
 	return color + text + colorReset
 }
 
-// colorGreenText returns green colored text
+// colorGreenText returns text in the theme's gain color (green by default)
 func colorGreenText(text string) string {
-	return colorize(text, colorGreen)
+	return colorize(text, activeTheme.gain)
 }
 
-// colorRedText returns red colored text
+// colorRedText returns text in the theme's loss color (red by default)
 func colorRedText(text string) string {
-	return colorize(text, colorRed)
+	return colorize(text, activeTheme.loss)
 }
 
 // colorByValue returns green for positive, red for negative
@@ -54,15 +67,135 @@ func colorByValue(text string, value float64) string {
 	return text
 }
 
-// parseFloat parses a float64 from a string, exiting on error
-func parseFloat(s, name string) float64 {
-	var f float64
-	_, err := fmt.Sscanf(s, "%f", &f)
+// parseDecimal parses an amount or price exactly, keeping every digit given
+func parseDecimal(s, name string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil {
+		return decimal.Zero, usageErrorf("invalid %s: %s", name, s)
+	}
+	return d, nil
+}
+
+// perUnit returns the price per coin of amount coins costing total, or zero
+// for a zero amount, which is rejected when the record is added
+func perUnit(total, amount decimal.Decimal) decimal.Decimal {
+	if amount.IsZero() {
+		return decimal.Zero
+	}
+	return models.Quo(total, amount)
+}
+
+// decimalValue is a flag value parsed with parseDecimal, for flags giving
+// amounts and prices
+type decimalValue struct {
+	value decimal.Decimal
+}
+
+func (v *decimalValue) String() string { return v.value.String() }
+
+func (v *decimalValue) Set(s string) error {
+	d, err := decimal.NewFromString(strings.TrimSpace(s))
 	if err != nil {
-		fmt.Fprintf(osStderr, "Error: invalid %s: %s\n", name, s)
-		osExit(1)
+		return err
+	}
+	v.value = d
+	return nil
+}
+
+func (v *decimalValue) Type() string { return "decimal" }
+
+// decimalFlag returns the value of a flag added with decimalValue, or zero
+// when cmd has no such flag
+func decimalFlag(cmd *cobra.Command, name string) decimal.Decimal {
+	if f := cmd.Flags().Lookup(name); f != nil {
+		if v, ok := f.Value.(*decimalValue); ok {
+			return v.value
+		}
+	}
+	return decimal.Zero
+}
+
+// parseCoinValues parses flag values of the form COIN=VALUE, each holding
+// one or more pairs separated by commas. name and unit describe the values
+// in errors, e.g. "target" and "PERCENT". A trailing % or leading $ on a
+// value is ignored. No values returns nil.
+func parseCoinValues(values []string, name, unit string) (map[string]float64, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]float64)
+	for _, value := range values {
+		for _, pair := range strings.Split(value, ",") {
+			coin, number, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			coin = strings.ToUpper(strings.TrimSpace(coin))
+			if !ok || coin == "" {
+				return nil, usageErrorf("invalid %s %q: expected COIN=%s", name, pair, unit)
+			}
+			number = strings.TrimPrefix(strings.TrimSuffix(strings.TrimSpace(number), "%"), "$")
+			f, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, usageErrorf("invalid %s %q: expected COIN=%s", name, pair, unit)
+			}
+			if _, dup := parsed[coin]; dup {
+				return nil, usageErrorf("%s for %s given more than once", name, coin)
+			}
+			parsed[coin] = f
+		}
 	}
-	return f
+	return parsed, nil
+}
+
+// platformFlag returns the --platform flag of cmd, or the configured
+// default platform when the flag is not given, resolved against the
+// platform registry
+func platformFlag(cmd *cobra.Command) (string, error) {
+	if platform, _ := cmd.Flags().GetString("platform"); platform != "" {
+		return resolvePlatform(platform)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return resolvePlatform(cfg.GetDefaultPlatform())
+}
+
+// platformArg returns the platform given as args[i], or the configured
+// default platform when there is no such argument. Without either, the
+// platform is missing. The platform is resolved against the registry.
+func platformArg(args []string, i int) (string, error) {
+	if i < len(args) {
+		return resolvePlatform(args[i])
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if platform := cfg.GetDefaultPlatform(); platform != "" {
+		return resolvePlatform(platform)
+	}
+	return "", usageErrorf("PLATFORM is required; give it or set a default with 'follyo config set default_platform NAME'")
+}
+
+// dateFlag returns the --date flag as YYYY-MM-DD, accepting the dates
+// parseDate does, or "" when it is not given
+func dateFlag(cmd *cobra.Command) (string, error) {
+	value, _ := cmd.Flags().GetString("date")
+	d, err := parseDate(value, "date")
+	return d.String(), err
+}
+
+// parseDate parses a date argument or flag value: YYYY-MM-DD or a natural
+// date such as "yesterday", "3 days ago", or "jan 5". An empty string
+// returns the zero Date.
+func parseDate(s, name string) (models.Date, error) {
+	if s == "" {
+		return models.Date{}, nil
+	}
+	d, err := models.ParseNaturalDate(s)
+	if err != nil {
+		return models.Date{}, usageErrorf("invalid %s: %s (expected YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\", \"jan 5\")", name, s)
+	}
+	return d, nil
 }
 
 // addCommas adds thousand separators to a numeric string
@@ -136,6 +269,68 @@ func formatUSD(amount float64) string {
 	return "$" + addCommas(s)
 }
 
+// formatSignedUSD formats a gain or loss with an explicit sign, e.g. +$1.50 or -$1.50
+func formatSignedUSD(amount float64) string {
+	if amount < 0 {
+		return "-" + formatUSD(-amount)
+	}
+	if amount > 0 {
+		return "+" + formatUSD(amount)
+	}
+	return formatUSD(amount)
+}
+
+// formatSignedMoney formats a gain or loss in the display currency with an
+// explicit sign
+func formatSignedMoney(amount float64) string {
+	if amount < 0 {
+		return "-" + formatMoney(-amount)
+	}
+	if amount > 0 {
+		return "+" + formatMoney(amount)
+	}
+	return formatMoney(amount)
+}
+
+// formatCompactUSD formats large USD amounts with a magnitude suffix, e.g. $1.23B
+func formatCompactUSD(amount float64) string {
+	switch {
+	case amount >= 1e12:
+		return fmt.Sprintf("$%.2fT", amount/1e12)
+	case amount >= 1e9:
+		return fmt.Sprintf("$%.2fB", amount/1e9)
+	case amount >= 1e6:
+		return fmt.Sprintf("$%.2fM", amount/1e6)
+	}
+	return formatUSD(amount)
+}
+
+// displayCurrency is the currency formatMoney renders values in
+var displayCurrency = "USD"
+
+// currencySymbols maps currency codes to their display symbols
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CHF": "CHF ",
+	"CAD": "C$",
+	"AUD": "A$",
+}
+
+// formatMoney formats an amount already expressed in the display currency
+func formatMoney(amount float64) string {
+	if displayCurrency == "USD" {
+		return formatUSD(amount)
+	}
+	s := addCommas(fmt.Sprintf("%.2f", amount))
+	if symbol, ok := currencySymbols[displayCurrency]; ok {
+		return symbol + s
+	}
+	return s + " " + displayCurrency
+}
+
 func sortedKeys(m map[string]float64) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -147,29 +342,46 @@ func sortedKeys(m map[string]float64) []string {
 
 // printCoinLine prints a coin line with optional price info and returns the computed value.
 // showPrefix adds +/- prefix for amounts (used in NET HOLDINGS section).
-func printCoinLine(w *tabwriter.Writer, coin string, amount float64, livePrices map[string]float64, showPrefix bool) float64 {
+func printCoinLine(w *tabwriter.Writer, coin string, amount decimal.Decimal, livePrices map[string]float64, showPrefix bool) decimal.Decimal {
+	line, value := coinLine(coin, amount, livePrices, showPrefix)
+	fmt.Fprintln(w, line+"\t")
+	return value
+}
+
+// coinLine formats a coin line for printCoinLine without the trailing cell
+// terminator, so callers can append further columns.
+func coinLine(coin string, amount decimal.Decimal, livePrices map[string]float64, showPrefix bool) (string, decimal.Decimal) {
 	amountPrefix := ""
-	if showPrefix && amount > 0 {
+	if showPrefix && amount.IsPositive() {
 		amountPrefix = "+"
 	}
 
 	if livePrices != nil {
 		if price, ok := livePrices[coin]; ok {
-			value := amount * price
+			value := models.AtPrice(amount, price)
 			valuePrefix := ""
-			if showPrefix && value > 0 {
+			if showPrefix && value.IsPositive() {
 				valuePrefix = "+"
 			}
-			fmt.Fprintf(w, "  %-8s\t%s%s\t@ %s\t= %s%s\t\n",
-				coin+":", amountPrefix, formatAmountAligned(amount), formatUSD(price), valuePrefix, formatUSD(value))
-			return value
+			return fmt.Sprintf("  %-8s\t%s%s\t@ %s\t= %s%s",
+				coin+":", amountPrefix, formatAmountAligned(amount.InexactFloat64()), formatMoney(price), valuePrefix, formatMoney(value.InexactFloat64())), value
 		}
-		fmt.Fprintf(w, "  %-8s\t%s%s\t@ %s\t= %s\t\n",
-			coin+":", amountPrefix, formatAmountAligned(amount), "N/A", "N/A")
-		return 0
+		return fmt.Sprintf("  %-8s\t%s%s\t@ %s\t= %s",
+			coin+":", amountPrefix, formatAmountAligned(amount.InexactFloat64()), "N/A", "N/A"), decimal.Zero
 	}
-	fmt.Fprintf(w, "  %-8s\t%s%s\t\n", coin+":", amountPrefix, formatAmountAligned(amount))
-	return 0
+	return fmt.Sprintf("  %-8s\t%s%s", coin+":", amountPrefix, formatAmountAligned(amount.InexactFloat64())), decimal.Zero
+}
+
+// formatChange formats a labelled percent price change, colored by direction
+func formatChange(label string, percent float64) string {
+	return colorByValue(fmt.Sprintf("%s %+.1f%%", label, percent), percent)
+}
+
+// renderBar renders a horizontal bar of the given width filled to percent
+func renderBar(percent float64, width int) string {
+	filled := int(percent/100*float64(width) + 0.5)
+	filled = max(0, min(filled, width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
 }
 
 // safeDivide performs division with a guard against division by zero
@@ -179,3 +391,129 @@ func safeDivide(numerator, denominator float64) float64 {
 	}
 	return numerator / denominator
 }
+
+// paginate returns the 1-based page of items with limit rows per page, and a
+// position indicator such as "Page 2 of 5 (rows 51-100 of 230)". A limit of 0
+// or less disables paging and returns all items with an empty indicator.
+// Pages past the end are clamped to the last page.
+func paginate[T any](items []T, page, limit int) ([]T, string) {
+	if limit <= 0 || len(items) == 0 {
+		return items, ""
+	}
+
+	pages := (len(items) + limit - 1) / limit
+	page = max(1, min(page, pages))
+	start := (page - 1) * limit
+	end := min(start+limit, len(items))
+	return items[start:end], fmt.Sprintf("Page %d of %d (rows %d-%d of %d)", page, pages, start+1, end, len(items))
+}
+
+// removeRecords removes the records with the given IDs or ID prefixes after
+// showing their details and asking for confirmation, which --yes skips.
+// Every ID is resolved against existing, which maps IDs to details such as
+// "0.5 BTC bought 2024-01-02 on Coinbase", first, so a mistyped ID removes
+// nothing. noun names the record type (e.g. "purchase") and message formats
+// the line printed for each removed ID.
+func removeRecords(ids []string, existing map[string]string, noun string, remove func(id string) (bool, error), message func(id string) string) error {
+	known := sortedStringKeys(existing)
+	seen := make(map[string]bool)
+	var unique []string
+	for _, arg := range ids {
+		id, err := resolveID(noun, arg, known)
+		if err != nil {
+			return err
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	question := fmt.Sprintf("Remove %s %s?", noun, existing[unique[0]])
+	if len(unique) > 1 && !assumeYes {
+		for _, id := range unique {
+			fmt.Fprintf(osStdout, "  %s  %s\n", id, existing[id])
+		}
+		question = fmt.Sprintf("Remove %d %ss?", len(unique), noun)
+	}
+	ok, err := confirm(question)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(osStdout, "Nothing removed")
+		return nil
+	}
+	for _, id := range unique {
+		removed, err := remove(id)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return notFoundErrorf("%s %s not found", noun, id)
+		}
+		fmt.Fprintln(osStdout, message(id))
+	}
+	return nil
+}
+
+// removeMatching removes the records with the given IDs or, when none are
+// given, the records matching the filter flags added by addRemoveFilterFlags,
+// through removeRecords. list returns the records passing a filter and
+// describe returns a record's ID and details.
+func removeMatching[T any](cmd *cobra.Command, ids []string, noun string, list func(portfolio.ListOptions) ([]T, error), describe func(T) (id, details string), remove func(id string) (bool, error), message func(id string) string) error {
+	filter, err := removeFilterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 && !filter.IsZero() {
+		return usageErrorf("give either %s IDs or filter flags, not both", noun)
+	}
+	if len(ids) == 0 && filter.IsZero() {
+		return usageErrorf("give %s IDs or at least one of --coin, --platform, --tag, --since, --until, and --before", noun)
+	}
+
+	records, err := list(portfolio.ListOptions{Filter: filter})
+	if err != nil {
+		return err
+	}
+	existing := recordDetails(records, describe)
+	if len(ids) == 0 {
+		if len(records) == 0 {
+			fmt.Fprintf(osStdout, "No matching %ss\n", noun)
+			return nil
+		}
+		for _, r := range records {
+			id, _ := describe(r)
+			ids = append(ids, id)
+		}
+	}
+	return removeRecords(ids, existing, noun, remove, message)
+}
+
+// tagsLabel returns a record's tags separated by commas, or "-" if it has none
+func tagsLabel(tags []string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	return strings.Join(tags, ",")
+}
+
+// recordDetails maps the IDs of records to their details, as returned by
+// describe
+func recordDetails[T any](records []T, describe func(T) (id, details string)) map[string]string {
+	details := make(map[string]string, len(records))
+	for _, r := range records {
+		id, d := describe(r)
+		details[id] = d
+	}
+	return details
+}
+
+// onPlatform returns " on PLATFORM", or "" when platform is empty
+func onPlatform(platform string) string {
+	if platform == "" {
+		return ""
+	}
+	return " on " + platform
+}
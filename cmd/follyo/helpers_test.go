@@ -1,7 +1,11 @@
 package main
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 func TestFormatAmount(t *testing.T) {
@@ -81,6 +85,53 @@ func TestFormatUSD(t *testing.T) {
 	}
 }
 
+func TestFormatCompactUSD(t *testing.T) {
+	tests := []struct {
+		input float64
+		want  string
+	}{
+		{999.5, "$999.50"},
+		{2500000, "$2.50M"},
+		{70000000000, "$70.00B"},
+		{1900000000000, "$1.90T"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := formatCompactUSD(tt.input)
+			if got != tt.want {
+				t.Errorf("formatCompactUSD(%f) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	defer func() { displayCurrency = "USD" }()
+
+	tests := []struct {
+		currency string
+		input    float64
+		want     string
+	}{
+		{"USD", 1234.56, "$1,234.56"},
+		{"EUR", 1234.56, "€1,234.56"},
+		{"GBP", -5, "£-5.00"},
+		{"CHF", 1000, "CHF 1,000.00"},
+		{"SEK", 1000, "1,000.00 SEK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			displayCurrency = tt.currency
+			got := formatMoney(tt.input)
+			if got != tt.want {
+				t.Errorf("formatMoney(%f) in %s = %s, want %s", tt.input, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSortedKeys(t *testing.T) {
 	tests := []struct {
 		name string
@@ -191,3 +242,325 @@ func TestFormatAmountAligned(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderBar(t *testing.T) {
+	tests := []struct {
+		percent float64
+		want    string
+	}{
+		{0, "░░░░░░░░░░"},
+		{50, "█████░░░░░"},
+		{100, "██████████"},
+		{150, "██████████"},
+		{-5, "░░░░░░░░░░"},
+	}
+
+	for _, tt := range tests {
+		got := renderBar(tt.percent, 10)
+		if got != tt.want {
+			t.Errorf("renderBar(%f, 10) = %s, want %s", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7}
+
+	tests := []struct {
+		name      string
+		page      int
+		limit     int
+		wantItems []int
+		wantInfo  string
+	}{
+		{"no limit", 1, 0, items, ""},
+		{"first page", 1, 3, []int{1, 2, 3}, "Page 1 of 3 (rows 1-3 of 7)"},
+		{"last partial page", 3, 3, []int{7}, "Page 3 of 3 (rows 7-7 of 7)"},
+		{"past the end", 9, 3, []int{7}, "Page 3 of 3 (rows 7-7 of 7)"},
+		{"page zero", 0, 5, []int{1, 2, 3, 4, 5}, "Page 1 of 2 (rows 1-5 of 7)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, info := paginate(items, tt.page, tt.limit)
+			if info != tt.wantInfo {
+				t.Errorf("paginate() info = %q, want %q", info, tt.wantInfo)
+			}
+			if len(got) != len(tt.wantItems) {
+				t.Fatalf("paginate() returned %d items, want %d", len(got), len(tt.wantItems))
+			}
+			for i := range got {
+				if got[i] != tt.wantItems[i] {
+					t.Errorf("paginate()[%d] = %d, want %d", i, got[i], tt.wantItems[i])
+				}
+			}
+		})
+	}
+
+	if got, info := paginate([]int{}, 1, 10); len(got) != 0 || info != "" {
+		t.Errorf("expected empty page without indicator, got %v %q", got, info)
+	}
+}
+
+func TestStaleLabel(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{10 * time.Second, "stale (1m old)"},
+		{45 * time.Minute, "stale (45m old)"},
+		{3*time.Hour + 20*time.Minute, "stale (3h old)"},
+		{30 * time.Hour, "stale (30h old)"},
+		{5 * 24 * time.Hour, "stale (5d old)"},
+	}
+	for _, tt := range tests {
+		if got := staleLabel(now.Add(-tt.age), now); got != tt.want {
+			t.Errorf("staleLabel(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestFormatChange(t *testing.T) {
+	if got := formatChange("24h", 1.234); got != "24h +1.2%" {
+		t.Errorf("formatChange(24h, 1.234) = %s, want 24h +1.2%%", got)
+	}
+	if got := formatChange("7d", -5); got != "7d -5.0%" {
+		t.Errorf("formatChange(7d, -5) = %s, want 7d -5.0%%", got)
+	}
+}
+
+func TestFormatCoinPL(t *testing.T) {
+	if got := formatCoinPL(dec(1500), dec(1000)); got != "P/L +$500.00 (+50.0%)" {
+		t.Errorf("formatCoinPL(1500, 1000) = %s, want P/L +$500.00 (+50.0%%)", got)
+	}
+	if got := formatCoinPL(dec(750), dec(1000)); got != "P/L -$250.00 (-25.0%)" {
+		t.Errorf("formatCoinPL(750, 1000) = %s, want P/L -$250.00 (-25.0%%)", got)
+	}
+}
+
+func TestFormatSignedUSD(t *testing.T) {
+	tests := []struct {
+		input float64
+		want  string
+	}{
+		{0, "$0.00"},
+		{1234.5, "+$1,234.50"},
+		{-20, "-$20.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatSignedUSD(tt.input); got != tt.want {
+				t.Errorf("formatSignedUSD(%f) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnsiColor(t *testing.T) {
+	tests := []struct {
+		spec      string
+		colors256 bool
+		want      string
+	}{
+		{"", true, ""},
+		{"green", true, "\033[32m"},
+		{"Bright-Red", true, "\033[91m"},
+		{"4", true, "\033[34m"},
+		{"9", true, "\033[91m"},
+		{"208", true, "\033[38;5;208m"},
+		{"208", false, "\033[33m"}, // orange -> yellow
+		{"28", false, "\033[32m"},  // dark green -> green
+		{"124", false, "\033[31m"}, // dark red -> red
+		{"250", false, "\033[37m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ansiColor(tt.spec, tt.colors256)
+			if err != nil || got != tt.want {
+				t.Errorf("ansiColor(%q, %v) = %q, %v, want %q", tt.spec, tt.colors256, got, err, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"pink", "256", "-1", "bright-"} {
+		if _, err := ansiColor(bad, true); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestResolveTheme(t *testing.T) {
+	for _, name := range themeNames {
+		if _, err := resolveTheme(name, map[string]string{"gain": "cyan"}, false); err != nil {
+			t.Errorf("resolveTheme(%s) failed: %v", name, err)
+		}
+	}
+
+	custom, _ := resolveTheme("custom", map[string]string{"gain": "cyan", "loss": "magenta"}, false)
+	if custom.gain != "\033[36m" || custom.loss != "\033[35m" {
+		t.Errorf("Unexpected custom theme %q", custom)
+	}
+	if none, _ := resolveTheme("none", nil, true); none.gain != "" || none.loss != "" {
+		t.Errorf("Expected no colors, got %q", none)
+	}
+	if _, err := resolveTheme("solarized", nil, true); err == nil {
+		t.Error("Expected error for unknown theme")
+	}
+	if _, err := resolveTheme("custom", map[string]string{"loss": "pink"}, true); err == nil {
+		t.Error("Expected error for invalid custom color")
+	}
+}
+
+func TestResolveID(t *testing.T) {
+	ids := []string{"a1b2c3d4", "a1b29999", "ffee0011", "ffee"}
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+		code int
+	}{
+		{"full ID", "a1b2c3d4", "a1b2c3d4", 0},
+		{"unique prefix", "a1b2c", "a1b2c3d4", 0},
+		{"upper case", "FFEE00", "ffee0011", 0},
+		{"exact match beats longer IDs", "ffee", "ffee", 0},
+		{"ambiguous prefix", "a1b2", "", exitUsage},
+		{"unknown", "0000", "", exitNotFound},
+		{"empty", "", "", exitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveID("purchase", tt.id, ids)
+			if code := exitCode(err); code != tt.code {
+				t.Fatalf("resolveID(%q) exit code = %d, want %d (%v)", tt.id, code, tt.code, err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveID(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+
+	_, err := resolveID("purchase", "a1", ids)
+	if err == nil || err.Error() != "purchase ID a1 is ambiguous, it matches a1b29999, a1b2c3d4" {
+		t.Errorf("Expected the matches listed, got %v", err)
+	}
+}
+
+func TestShortIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want string
+	}{
+		{"minimum length", []string{"a1b2c3d4", "ffee0011"}, "a1b2"},
+		{"longer to tell apart", []string{"a1b2c3d4", "a1b2c9d4"}, "a1b2c3"},
+		{"no IDs", nil, "a1b2"},
+		{"duplicates keep the full ID", []string{"a1b2c3d4", "a1b2c3d4"}, "a1b2c3d4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shortIDs(tt.ids)("a1b2c3d4"); got != tt.want {
+				t.Errorf("shortIDs(%v)(a1b2c3d4) = %q, want %q", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutWidths(t *testing.T) {
+	_, restore := captureOutput()
+	defer restore()
+
+	tests := []struct {
+		columns   string
+		chart     int
+		bar       int
+		separator int
+	}{
+		{"", 60, 30, 27},
+		{"not a number", 60, 30, 27},
+		{"20", minChartWidth, minBarWidth, 20},
+		{"60", 40, 20, 27},
+		{"300", maxChartWidth, maxBarWidth, 27},
+	}
+
+	for _, tt := range tests {
+		t.Run("COLUMNS="+tt.columns, func(t *testing.T) {
+			t.Setenv("COLUMNS", tt.columns)
+			if got := chartWidth(); got != tt.chart {
+				t.Errorf("chartWidth() = %d, want %d", got, tt.chart)
+			}
+			if got := barWidth(); got != tt.bar {
+				t.Errorf("barWidth() = %d, want %d", got, tt.bar)
+			}
+			if got := len(separator()); got != tt.separator {
+				t.Errorf("separator() is %d wide, want %d", got, tt.separator)
+			}
+		})
+	}
+}
+
+func TestSortSummaryCoins(t *testing.T) {
+	amounts := map[string]decimal.Decimal{"BTC": dec(1), "ETH": dec(10), "SOL": dec(100), "XYZ": dec(5)}
+	livePrices := map[string]float64{"BTC": 60000, "ETH": 3000, "SOL": 150}
+	pl := map[string]float64{"BTC": 10000, "ETH": -5000, "SOL": 2000, "XYZ": 0}
+
+	tests := []struct {
+		sortBy  string
+		reverse bool
+		want    []string
+		label   string
+	}{
+		{"name", false, []string{"BTC", "ETH", "SOL", "XYZ"}, ""},
+		{"name", true, []string{"XYZ", "SOL", "ETH", "BTC"}, " (by name ↓)"},
+		{"value", false, []string{"BTC", "ETH", "SOL", "XYZ"}, " (by value ↓)"},
+		{"value", true, []string{"XYZ", "SOL", "ETH", "BTC"}, " (by value ↑)"},
+		{"pl", false, []string{"BTC", "SOL", "XYZ", "ETH"}, " (by pl ↓)"},
+	}
+
+	for _, tt := range tests {
+		got := sortSummaryCoins(amounts, tt.sortBy, tt.reverse, livePrices, func(coin string) float64 { return pl[coin] })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortSummaryCoins(%s, reverse %v) = %v, want %v", tt.sortBy, tt.reverse, got, tt.want)
+		}
+		if label := summarySortLabel(tt.sortBy, tt.reverse); label != tt.label {
+			t.Errorf("summarySortLabel(%s, %v) = %q, want %q", tt.sortBy, tt.reverse, label, tt.label)
+		}
+	}
+}
+
+func TestChooseColumns(t *testing.T) {
+	all := []string{"id", "coin", "fee", "notes"}
+	defaults := []string{"id", "coin", "fee"}
+	tests := []struct {
+		keys    []string
+		want    []string
+		wantErr bool
+	}{
+		{nil, defaults, false},
+		{[]string{"notes", "ID"}, []string{"notes", "id"}, false},
+		{[]string{"+notes", "-fee"}, []string{"id", "coin", "notes"}, false},
+		{[]string{"+coin"}, defaults, false},
+		{[]string{"bogus"}, nil, true},
+		{[]string{"-id", "-coin", "-fee"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := chooseColumns("buy", tt.keys, all, defaults)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("chooseColumns(%v) error = %v, wantErr %v", tt.keys, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("chooseColumns(%v) = %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+}
+
+// dec returns v as a decimal, for writing record amounts as literals
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Aliases: []string{"hist"},
+	Short:   "Show all transactions in a chronological ledger",
+	Long: `Show purchases, sales, loans, and stakes in a single chronological ledger.
+
+The Balance column is the running holdings balance (purchases - sales) of
+each coin after the transaction, computed over all records even when
+filters are applied.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := filterFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		history, err := p.GetHistory(filter)
+		if err != nil {
+			return err
+		}
+
+		if len(history) == 0 {
+			fmt.Fprintln(osStdout, "No transactions found.")
+			return nil
+		}
+
+		page, limit := pageFromFlags(cmd)
+		history, pageInfo := paginate(history, page, limit)
+
+		printLedger(history)
+		if pageInfo != "" {
+			fmt.Fprintf(osStdout, "\n%s\n", pageInfo)
+		}
+		return nil
+	},
+}
+
+// printLedger prints transactions as a ledger table
+func printLedger(history []portfolio.Transaction) {
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Date\tType\tCoin\tAmount\tPrice/Unit\tPlatform\tBalance\tID")
+	for _, tx := range history {
+		amount := formatAmount(tx.Amount.InexactFloat64())
+		switch tx.Type {
+		case portfolio.TypeBuy, portfolio.TypeSwapIn:
+			amount = "+" + amount
+		case portfolio.TypeSell, portfolio.TypeSwapOut:
+			amount = "-" + amount
+		}
+		price := "-"
+		if !tx.PriceUSD.IsZero() {
+			price = formatUSD(tx.PriceUSD.InexactFloat64())
+		}
+		platform := tx.Platform
+		if platform == "" {
+			platform = "-"
+		}
+		if tx.Type == portfolio.TypeTransfer {
+			platform = fmt.Sprintf("%s -> %s", platformLabel(tx.Platform), platformLabel(tx.ToPlatform))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			tx.Date, strings.ToUpper(tx.Type), tx.Coin, amount, price,
+			platform, formatAmount(tx.Balance.InexactFloat64()), tx.ID)
+	}
+	w.Flush()
+}
+
+// addFilterFlags adds the --coin, --platform, --since, --until, --search, and --tag flags
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("coin", "", "Only show records for this coin")
+	cmd.Flags().String("platform", "", "Only show records on this platform")
+	cmd.Flags().String("since", "", "Only show records on or after this date (YYYY-MM-DD)")
+	cmd.Flags().String("until", "", "Only show records on or before this date (YYYY-MM-DD)")
+	cmd.Flags().String("search", "", "Only show records whose coin or platform contains this text")
+	cmd.Flags().String("tag", "", "Only show records with this tag")
+	cmd.RegisterFlagCompletionFunc("coin", completeFlag(completeCoins))
+	cmd.RegisterFlagCompletionFunc("platform", completeFlag(completePlatforms))
+}
+
+// filterFromFlags builds a portfolio filter from the flags added by addFilterFlags,
+// normalizing dates and rejecting invalid ones
+func filterFromFlags(cmd *cobra.Command) (portfolio.Filter, error) {
+	var f portfolio.Filter
+	f.Coin, _ = cmd.Flags().GetString("coin")
+	f.Platform, _ = cmd.Flags().GetString("platform")
+	f.Search, _ = cmd.Flags().GetString("search")
+	f.Tag, _ = cmd.Flags().GetString("tag")
+
+	for name, date := range map[string]*models.Date{"since": &f.Since, "until": &f.Until} {
+		value, _ := cmd.Flags().GetString(name)
+		var err error
+		if *date, err = parseDate(value, "--"+name+" date"); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// addRemoveFilterFlags adds the --coin, --platform, --tag, --since, --until,
+// and --before flags that select records for a remove command
+func addRemoveFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("coin", "", "Remove records for this coin")
+	cmd.Flags().String("platform", "", "Remove records on this platform")
+	cmd.Flags().String("tag", "", "Remove records with this tag")
+	cmd.Flags().String("since", "", "Remove records on or after this date")
+	cmd.Flags().String("until", "", "Remove records on or before this date")
+	cmd.Flags().String("before", "", "Remove records before this date")
+	cmd.RegisterFlagCompletionFunc("coin", completeFlag(completeCoins))
+	cmd.RegisterFlagCompletionFunc("platform", completeFlag(completePlatforms))
+}
+
+// removeFilterFromFlags builds a portfolio filter from the flags added by
+// addRemoveFilterFlags, turning --before into the day before's --until
+func removeFilterFromFlags(cmd *cobra.Command) (portfolio.Filter, error) {
+	f, err := filterFromFlags(cmd)
+	if err != nil {
+		return f, err
+	}
+	value, _ := cmd.Flags().GetString("before")
+	if value == "" {
+		return f, nil
+	}
+	if !f.Until.IsZero() {
+		return f, usageErrorf("use either --until or --before, not both")
+	}
+	before, err := parseDate(value, "--before date")
+	if err != nil {
+		return f, err
+	}
+	f.Until = before.AddDays(-1)
+	return f, nil
+}
+
+// addListFlags adds the filter flags plus --sort and --reverse to a list command
+func addListFlags(cmd *cobra.Command) {
+	addFilterFlags(cmd)
+	addPageFlags(cmd)
+	cmd.Flags().String("sort", portfolio.SortByDate, "Sort by date, coin, amount, or value")
+	cmd.Flags().BoolP("reverse", "r", false, "Reverse the sort order")
+	cmd.RegisterFlagCompletionFunc("sort", completeFlag(completeValues(
+		portfolio.SortByDate, portfolio.SortByCoin, portfolio.SortByAmount, portfolio.SortByValue)))
+}
+
+// listOptionsFromFlags builds list options from the flags added by addListFlags
+func listOptionsFromFlags(cmd *cobra.Command) (portfolio.ListOptions, error) {
+	filter, err := filterFromFlags(cmd)
+	opts := portfolio.ListOptions{Filter: filter}
+	opts.SortBy, _ = cmd.Flags().GetString("sort")
+	opts.Reverse, _ = cmd.Flags().GetBool("reverse")
+	return opts, err
+}
+
+// sortColumns are the table columns of the list sort fields
+var sortColumns = map[string]string{
+	portfolio.SortByDate:   "Date",
+	portfolio.SortByCoin:   "Coin",
+	portfolio.SortByAmount: "Amount",
+	portfolio.SortByValue:  "Total USD",
+}
+
+// sortHeader marks the column of a tab-separated table header that opts
+// sorts by with ↑ when ascending or ↓ when descending
+func sortHeader(header string, opts portfolio.ListOptions) string {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = portfolio.SortByDate
+	}
+	arrow := " ↑"
+	if opts.Reverse {
+		arrow = " ↓"
+	}
+	columns := strings.Split(header, "\t")
+	for i, column := range columns {
+		if column == sortColumns[sortBy] {
+			columns[i] += arrow
+			break
+		}
+	}
+	return strings.Join(columns, "\t")
+}
+
+// addPageFlags adds the --limit and --page flags
+func addPageFlags(cmd *cobra.Command) {
+	cmd.Flags().IntP("limit", "l", 0, "Show at most this many rows per page (default: all)")
+	cmd.Flags().Int("page", 1, "Page to show when --limit is set")
+}
+
+// pageFromFlags returns the page and limit from the flags added by addPageFlags
+func pageFromFlags(cmd *cobra.Command) (page, limit int) {
+	page, _ = cmd.Flags().GetInt("page")
+	limit, _ = cmd.Flags().GetInt("limit")
+	return page, limit
+}
+
+// printListFooter prints the page position when paging, and how many records
+// matched when a filter is active
+func printListFooter(opts portfolio.ListOptions, count int, noun, pageInfo string) {
+	if pageInfo == "" && opts.Filter.IsZero() {
+		return
+	}
+	fmt.Fprintln(osStdout)
+	if pageInfo != "" {
+		fmt.Fprintln(osStdout, pageInfo)
+	}
+	if !opts.Filter.IsZero() {
+		if count != 1 {
+			noun += "s"
+		}
+		fmt.Fprintf(osStdout, "%d matching %s\n", count, noun)
+	}
+}
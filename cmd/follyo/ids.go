@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// minShortID is the fewest characters of an ID shown in tables
+const minShortID = 4
+
+// resolveID returns the ID in ids that id names: the ID itself, or the only
+// ID starting with it, so records can be named by a prefix as in git. noun
+// names the record type (e.g. "purchase") in errors; an ambiguous prefix
+// lists the IDs it matches.
+func resolveID(noun, id string, ids []string) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	var matches []string
+	for _, candidate := range ids {
+		if candidate == id {
+			return id, nil
+		}
+		if id != "" && strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", notFoundErrorf("%s %s not found", noun, id)
+	case 1:
+		return matches[0], nil
+	}
+	slices.Sort(matches)
+	return "", usageErrorf("%s ID %s is ambiguous, it matches %s", noun, id, strings.Join(matches, ", "))
+}
+
+// idsOf returns the IDs of records
+func idsOf[T any](records []T, id func(T) string) []string {
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = id(r)
+	}
+	return ids
+}
+
+// shortIDs returns a function shortening IDs to the fewest characters, at
+// least minShortID, that tell all of ids apart. ids should be every ID the
+// short ones may be given back for, not only those shown.
+func shortIDs(ids []string) func(id string) string {
+	n := minShortID
+	for ; ; n++ {
+		seen := make(map[string]bool, len(ids))
+		unique, longest := true, 0
+		for _, id := range ids {
+			longest = max(longest, len(id))
+			prefix := id[:min(n, len(id))]
+			if seen[prefix] {
+				unique = false
+			}
+			seen[prefix] = true
+		}
+		if unique || n >= longest {
+			break
+		}
+	}
+	return func(id string) string {
+		return id[:min(n, len(id))]
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Layout limits in terminal cells. Output that is not a terminal, such as a
+// pipe, is laid out for defaultTermWidth unless $COLUMNS says otherwise.
+const (
+	defaultTermWidth = 80
+	minChartWidth    = 20
+	maxChartWidth    = 160
+	separatorWidth   = 27
+	maxBarWidth      = 30
+	minBarWidth      = 5
+)
+
+// termWidth returns the width of the terminal stdout is, $COLUMNS, or
+// defaultTermWidth, in that order
+func termWidth() int {
+	if f, ok := osStdout.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTermWidth
+}
+
+// chartWidth returns how many points a chart plots: the terminal width
+// less room for the axis labels, so charts fill wide terminals and still fit
+// narrow ones
+func chartWidth() int {
+	return max(minChartWidth, min(maxChartWidth, termWidth()-20))
+}
+
+// barWidth returns the width of allocation bars, which share a line with a
+// coin, a percentage, and a value
+func barWidth() int {
+	return max(minBarWidth, min(maxBarWidth, termWidth()-40))
+}
+
+// separator returns the rule printed above totals, shortened to fit narrow
+// terminals
+func separator() string {
+	return strings.Repeat("-", min(separatorWidth, termWidth()))
+}
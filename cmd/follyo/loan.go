@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
-	"text/tabwriter"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/spf13/cobra"
 )
 
@@ -14,18 +14,25 @@ var loanCmd = &cobra.Command{
 }
 
 var loanAddCmd = &cobra.Command{
-	Use:   "add COIN AMOUNT PLATFORM",
+	Use:   "add COIN AMOUNT [PLATFORM]",
 	Short: "Add a loan",
 	Long: `Add a loan.
 
 COIN: The cryptocurrency symbol (e.g., BTC, USDT)
 AMOUNT: Amount borrowed
-PLATFORM: Platform where loan is held (e.g., Nexo, Celsius)`,
-	Args: cobra.ExactArgs(3),
-	Run: func(cmd *cobra.Command, args []string) {
+PLATFORM: Platform where loan is held (e.g., Nexo, Celsius); optional
+when "default_platform" is set in the config`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		coin := args[0]
-		amount := parseFloat(args[1], "amount")
-		platform := args[2]
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
+		platform, err := platformArg(args, 2)
+		if err != nil {
+			return err
+		}
 
 		rate, _ := cmd.Flags().GetFloat64("rate")
 		var ratePtr *float64
@@ -33,62 +40,135 @@ PLATFORM: Platform where loan is held (e.g., Nexo, Celsius)`,
 			ratePtr = &rate
 		}
 		notes, _ := cmd.Flags().GetString("notes")
-		date, _ := cmd.Flags().GetString("date")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
 
-		loan, err := p.AddLoan(coin, amount, platform, ratePtr, notes, date)
+		loan, err := p.AddLoan(coin, amount, platform, ratePtr, notes, date, tags...)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
 		fmt.Printf("Added loan: %v %s on %s (ID: %s)\n", loan.Amount, loan.Coin, loan.Platform, loan.ID)
+		return nil
 	},
 }
 
 var loanListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all loans",
-	Run: func(cmd *cobra.Command, args []string) {
-		loans, err := p.ListLoans()
+	Long: `List all loans with their outstanding balance and the interest accrued
+from the loan date to today. Interest is simple by default; set
+"interest_method": "compound" in config.json to compound it daily.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := listOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		loans, err := p.ListLoansFiltered(opts)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
 
 		if len(loans) == 0 {
 			fmt.Fprintln(osStdout, "No loans found.")
-			return
+			return nil
 		}
 
-		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCoin\tAmount\tPlatform\tRate\tDate")
-		for _, l := range loans {
-			rate := "-"
-			if l.InterestRate != nil {
-				rate = fmt.Sprintf("%.1f%%", *l.InterestRate)
-			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-				l.ID, l.Coin, formatAmount(l.Amount),
-				l.Platform, rate, l.Date)
+		outstanding, err := p.GetOutstandingByLoan()
+		if err != nil {
+			return err
 		}
-		w.Flush()
+		interest, err := p.GetAccruedInterestByLoan(models.Date{})
+		if err != nil {
+			return err
+		}
+
+		total := len(loans)
+		page, limit := pageFromFlags(cmd)
+		loans, pageInfo := paginate(loans, page, limit)
+
+		all, err := p.ListLoans()
+		if err != nil {
+			return err
+		}
+		short := shortIDs(idsOf(all, func(l models.Loan) string { return l.ID }))
+		if err := printTable(cmd, "loan", loanColumns(short, outstanding, interest), loans, &opts); err != nil {
+			return err
+		}
+		printListFooter(opts, total, "loan", pageInfo)
+		return nil
 	},
 }
 
-var loanRemoveCmd = &cobra.Command{
-	Use:   "remove ID",
-	Short: "Remove a loan by ID",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		id := args[0]
-		removed, err := p.RemoveLoan(id)
+var loanRepayCmd = &cobra.Command{
+	Use:   "repay ID AMOUNT",
+	Short: "Record a loan repayment",
+	Long: `Record a partial or full repayment of a loan.
+
+ID: The loan ID (see 'loan list')
+AMOUNT: Amount repaid, in the loan's coin
+
+The repayment cannot exceed the loan's outstanding balance.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loans, err := p.ListLoans()
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
-		if removed {
-			fmt.Printf("Removed loan %s\n", id)
+		id, err := resolveID("loan", args[0], idsOf(loans, func(l models.Loan) string { return l.ID }))
+		if err != nil {
+			return err
+		}
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
+
+		notes, _ := cmd.Flags().GetString("notes")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+
+		repayment, err := p.RepayLoan(id, amount, notes, date, tags...)
+		if err != nil {
+			return err
+		}
+
+		outstanding, err := p.GetOutstandingByLoan()
+		if err != nil {
+			return err
+		}
+		if outstanding[id].IsZero() {
+			fmt.Printf("Repaid %s on loan %s, loan fully repaid (ID: %s)\n", formatAmount(repayment.Amount.InexactFloat64()), id, repayment.ID)
 		} else {
-			fmt.Printf("Loan %s not found\n", id)
+			fmt.Printf("Repaid %s on loan %s, %s outstanding (ID: %s)\n", formatAmount(repayment.Amount.InexactFloat64()), id, formatAmount(outstanding[id].InexactFloat64()), repayment.ID)
 		}
+		return nil
+	},
+}
+
+var loanRemoveCmd = &cobra.Command{
+	Use:   "remove [ID...]",
+	Short: "Remove loans and their repayments by ID or filter",
+	Long: `Remove one or more loans with their repayments.
+
+Instead of IDs, select the loans to remove with --coin, --platform, --tag,
+--since, --until, and --before, e.g. to clean up a bad import:
+
+  follyo loan remove --platform Demo
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeMatching(cmd, args, "loan", p.ListLoansFiltered, func(l models.Loan) (string, string) {
+			return l.ID, fmt.Sprintf("%s %s borrowed %s%s", formatAmount(l.Amount.InexactFloat64()), l.Coin, l.Date, onPlatform(l.Platform))
+		}, p.RemoveLoan, func(id string) string {
+			return fmt.Sprintf("Removed loan %[1]s (restore with 'follyo trash restore %[1]s')", id)
+		})
 	},
 }
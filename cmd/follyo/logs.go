@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/pretty-andrechal/follyo/internal/logging"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verbose bool
+	debug   bool
+)
+
+func init() {
+	// Log records are discarded unless --verbose or --debug is given
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+}
+
+// logDir returns the directory of the debug log
+func logDir() string {
+	return filepath.Join(storage.DataDir(), "logs")
+}
+
+// setupLogging sends log records of API requests and storage writes to
+// stderr with --verbose, and records of everything, including cache hits
+// and misses, to the log file with --debug
+func setupLogging(cmd *cobra.Command) error {
+	var handlers []slog.Handler
+	if verbose {
+		handlers = append(handlers, slog.NewTextHandler(osStderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	}
+	if debug {
+		f, err := logging.OpenFile(logDir())
+		if err != nil {
+			return ioError(fmt.Errorf("opening log file: %w", err))
+		}
+		handlers = append(handlers, slog.NewTextHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+	slog.SetDefault(slog.New(logging.Tee(handlers...)))
+	// Arguments are left out, since some are secrets such as API keys
+	slog.Debug("command started", "command", cmd.CommandPath())
+	return nil
+}
@@ -3,120 +3,552 @@ package main
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 
+	"github.com/pretty-andrechal/follyo/internal/config"
 	"github.com/pretty-andrechal/follyo/internal/portfolio"
 	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 )
 
 var (
-	p        *portfolio.Portfolio
-	dataPath string
+	p              *portfolio.Portfolio
+	dataPath       string
+	portfolioName  string
+	assumeYes      bool
+	nonInteractive bool
 )
 
 // Testable wrappers for os functions
 var (
-	osExit    = os.Exit
-	osStderr  io.Writer = os.Stderr
-	osStdout  io.Writer = os.Stdout
-	osStdin   io.Reader = os.Stdin
-	sortStrings = sort.Strings
+	osStderr    io.Writer = os.Stderr
+	osStdout    io.Writer = os.Stdout
+	osStdin     io.Reader = os.Stdin
+	sortStrings           = sort.Strings
 )
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
 	}
+	slog.Debug("command failed", "err", err)
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	code := exitCode(err)
+	if code == exitUsage && cmd != nil {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+	os.Exit(code)
 }
 
 func init() {
-	cobra.OnInitialize(initPortfolio)
-
 	rootCmd.PersistentFlags().StringVar(&dataPath, "data", "", "path to portfolio data file")
+	rootCmd.PersistentFlags().StringVar(&portfolioName, "portfolio", "", "name of a portfolio registered with 'follyo portfolio add'")
+	rootCmd.PersistentFlags().BoolVar(&assumeYes, "yes", false, "answer yes to confirmation prompts")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "never prompt; fail instead (for scripts)")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "log API requests and storage writes to stderr")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "log API requests, cache hits and misses, and storage writes to the log file")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &exitError{code: exitUsage, err: err}
+	})
 
 	// Add subcommands
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(buyCmd)
+	rootCmd.AddCommand(coinCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(dcaCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(fsckCmd)
+	rootCmd.AddCommand(exchangeCmd)
+	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(loanCmd)
+	rootCmd.AddCommand(platformCmd)
+	rootCmd.AddCommand(platformsCmd)
+	rootCmd.AddCommand(portfolioCmd)
+	rootCmd.AddCommand(rebalanceCmd)
+	rootCmd.AddCommand(restoreCmd)
 	rootCmd.AddCommand(sellCmd)
+	rootCmd.AddCommand(simulateCmd)
+	rootCmd.AddCommand(snapshotCmd)
 	rootCmd.AddCommand(stakeCmd)
+	rootCmd.AddCommand(stateCmd)
 	rootCmd.AddCommand(summaryCmd)
+	rootCmd.AddCommand(swapCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(tagsCmd)
+	rootCmd.AddCommand(taxCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(tickerCmd)
+	rootCmd.AddCommand(transferCmd)
+	rootCmd.AddCommand(trashCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(alertCmd)
+	rootCmd.AddCommand(notifyCmd)
+	rootCmd.AddCommand(dashboardCmd)
+	rootCmd.AddCommand(themeCmd)
+	rootCmd.AddCommand(columnsCmd)
+	rootCmd.RunE = runDefaultView
 
 	// Buy subcommands
 	buyCmd.AddCommand(buyAddCmd)
 	buyCmd.AddCommand(buyListCmd)
 	buyCmd.AddCommand(buyRemoveCmd)
 
+	// Columns subcommands
+	columnsCmd.AddCommand(columnsSetCmd)
+	columnsCmd.AddCommand(columnsResetCmd)
+
+	// Coin subcommands
+	coinCmd.AddCommand(coinMigrateCmd)
+
+	// Config subcommands
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+
+	// DCA subcommands
+	dcaCmd.AddCommand(dcaPlanCmd)
+	dcaCmd.AddCommand(dcaRunCmd)
+	dcaPlanCmd.AddCommand(dcaPlanAddCmd)
+	dcaPlanCmd.AddCommand(dcaPlanListCmd)
+	dcaPlanCmd.AddCommand(dcaPlanRemoveCmd)
+
+	// Exchange subcommands
+	exchangeCmd.AddCommand(exchangeSetCmd)
+	exchangeCmd.AddCommand(exchangeListCmd)
+	exchangeCmd.AddCommand(exchangeRemoveCmd)
+	exchangeCmd.AddCommand(exchangeSyncCmd)
+
 	// Loan subcommands
 	loanCmd.AddCommand(loanAddCmd)
 	loanCmd.AddCommand(loanListCmd)
+	loanCmd.AddCommand(loanRepayCmd)
 	loanCmd.AddCommand(loanRemoveCmd)
 
+	// Platform subcommands
+	platformCmd.AddCommand(platformAddCmd)
+	platformCmd.AddCommand(platformListCmd)
+	platformCmd.AddCommand(platformRemoveCmd)
+	platformCmd.AddCommand(platformRenameCmd)
+	platformCmd.AddCommand(platformAliasCmd)
+	platformCmd.AddCommand(platformUnaliasCmd)
+
+	// Portfolio subcommands
+	portfolioCmd.AddCommand(portfolioAddCmd)
+	portfolioCmd.AddCommand(portfolioListCmd)
+	portfolioCmd.AddCommand(portfolioRemoveCmd)
+	portfolioCmd.AddCommand(portfolioMigrateCmd)
+
 	// Sell subcommands
 	sellCmd.AddCommand(sellAddCmd)
 	sellCmd.AddCommand(sellListCmd)
+	sellCmd.AddCommand(sellLotsCmd)
 	sellCmd.AddCommand(sellRemoveCmd)
 
+	// Snapshot subcommands
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotShowCmd)
+	snapshotCmd.AddCommand(snapshotChartCmd)
+	snapshotCmd.AddCommand(snapshotCompareCmd)
+	snapshotCmd.AddCommand(snapshotRemoveCmd)
+	snapshotCmd.AddCommand(snapshotNoteCmd)
+	snapshotCmd.AddCommand(snapshotPinCmd)
+	snapshotCmd.AddCommand(snapshotUnpinCmd)
+	snapshotCmd.AddCommand(snapshotExportCmd)
+	snapshotCmd.AddCommand(snapshotImportCmd)
+
 	// Stake subcommands
 	stakeCmd.AddCommand(stakeAddCmd)
 	stakeCmd.AddCommand(stakeListCmd)
+	stakeCmd.AddCommand(stakeReduceCmd)
 	stakeCmd.AddCommand(stakeRemoveCmd)
 
+	// State subcommands
+	stateCmd.AddCommand(stateExportCmd)
+	stateCmd.AddCommand(stateImportCmd)
+
+	// Swap subcommands
+	swapCmd.AddCommand(swapAddCmd)
+	swapCmd.AddCommand(swapListCmd)
+	swapCmd.AddCommand(swapRemoveCmd)
+
+	// Tax subcommands
+	taxCmd.AddCommand(taxReportCmd)
+
 	// Ticker subcommands
 	tickerCmd.AddCommand(tickerMapCmd)
 	tickerCmd.AddCommand(tickerUnmapCmd)
 	tickerCmd.AddCommand(tickerListCmd)
 	tickerCmd.AddCommand(tickerSearchCmd)
+	tickerCmd.AddCommand(tickerPriceCmd)
+	tickerPriceCmd.AddCommand(tickerPriceSetCmd)
+	tickerPriceCmd.AddCommand(tickerPriceRemoveCmd)
+	tickerPriceCmd.AddCommand(tickerPriceListCmd)
+
+	// Transfer subcommands
+	transferCmd.AddCommand(transferAddCmd)
+	transferCmd.AddCommand(transferListCmd)
+	transferCmd.AddCommand(transferRemoveCmd)
+
+	// Trash subcommands
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+
+	// Watch subcommands
+	watchCmd.AddCommand(watchAddCmd)
+	watchCmd.AddCommand(watchListCmd)
+	watchCmd.AddCommand(watchRemoveCmd)
+
+	// Alert and notify subcommands
+	alertCmd.AddCommand(alertAddCmd)
+	alertCmd.AddCommand(alertListCmd)
+	alertCmd.AddCommand(alertRemoveCmd)
+	alertCmd.AddCommand(alertCheckCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+
+	// Sync subcommands
+	syncCmd.AddCommand(syncPushCmd)
+	syncCmd.AddCommand(syncPullCmd)
+
+	// Theme subcommands
+	themeCmd.AddCommand(themeListCmd)
+	themeCmd.AddCommand(themeSetCmd)
+
+	// Add flags for doctor command
+	doctorCmd.Flags().Bool("offline", false, "Skip checking that CoinGecko can be reached")
+
+	// Add flags for fsck command
+	fsckCmd.Flags().Bool("fix", false, "Repair without asking")
+
+	// Add flags for dashboard
+	dashboardCmd.Flags().Int("movers", 5, "Number of top movers to show")
+	dashboardCmd.Flags().Int("recent", 5, "Number of recent transactions to show")
+	dashboardCmd.Flags().String("baseline", "", "Measure the change and P/L from this snapshot, by ID or pinned name, or none (default from config)")
+	addRefreshFlag(dashboardCmd)
+
+	// Add flags for alert add
+	alertAddCmd.Flags().Float64("above", 0, "Alert when the USD price rises to this")
+	alertAddCmd.Flags().Float64("below", 0, "Alert when the USD price falls to this")
+
+	// Add flags for rebalance
+	rebalanceCmd.Flags().StringSlice("target", nil, "Target allocation as COIN=PERCENT, e.g. BTC=50,ETH=50 (default: goals in the config)")
+
+	// Add flags for simulate
+	simulateCmd.Flags().StringSlice("price", nil, "Hypothetical USD price as COIN=PRICE, e.g. BTC=150000 (repeatable)")
 
 	// Add flags for ticker list
 	tickerListCmd.Flags().BoolP("all", "a", false, "Show all default mappings")
 
+	// Add flags for ticker price set
+	tickerPriceSetCmd.Flags().StringP("date", "d", "", "Date the price was observed (YYYY-MM-DD, default today)")
+
 	// Add flags for buy add
 	buyAddCmd.Flags().StringP("platform", "p", "", "Platform where held")
 	buyAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
-	buyAddCmd.Flags().StringP("date", "d", "", "Purchase date (YYYY-MM-DD)")
-	buyAddCmd.Flags().Float64P("total", "t", 0, "Total purchase cost in USD (alternative to per-unit price)")
+	buyAddCmd.Flags().StringP("date", "d", "", "Purchase date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+	buyAddCmd.Flags().VarP(&decimalValue{}, "total", "t", "Total purchase cost in USD (alternative to per-unit price)")
+	buyAddCmd.Flags().VarP(&decimalValue{}, "fee", "f", "Purchase fee in USD (added to cost basis)")
+	buyAddCmd.Flags().Bool("force", false, "Add even if an identical purchase exists")
+
+	// Add flags for history
+	addFilterFlags(historyCmd)
+	addPageFlags(historyCmd)
 
 	// Add flags for loan add
 	loanAddCmd.Flags().Float64P("rate", "r", 0, "Annual interest rate (%)")
 	loanAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
-	loanAddCmd.Flags().StringP("date", "d", "", "Loan date (YYYY-MM-DD)")
+	loanAddCmd.Flags().StringP("date", "d", "", "Loan date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+
+	// Add flags for loan repay
+	loanRepayCmd.Flags().StringP("notes", "n", "", "Optional notes")
+	loanRepayCmd.Flags().StringP("date", "d", "", "Repayment date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
 
 	// Add flags for sell add
 	sellAddCmd.Flags().StringP("platform", "p", "", "Platform where sold")
 	sellAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
-	sellAddCmd.Flags().StringP("date", "d", "", "Sale date (YYYY-MM-DD)")
-	sellAddCmd.Flags().Float64P("total", "t", 0, "Total sale amount in USD (alternative to per-unit price)")
+	sellAddCmd.Flags().StringP("date", "d", "", "Sale date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+	sellAddCmd.Flags().VarP(&decimalValue{}, "total", "t", "Total sale amount in USD (alternative to per-unit price)")
+	sellAddCmd.Flags().VarP(&decimalValue{}, "fee", "f", "Sale fee in USD (deducted from proceeds)")
+	sellAddCmd.Flags().Bool("force", false, "Add even if an identical sale exists or more than is available is sold")
+	sellAddCmd.Flags().StringSlice("from-lot", nil, "ID of a purchase or swap to sell from instead of FIFO (repeatable)")
 
 	// Add flags for stake add
 	stakeAddCmd.Flags().Float64P("apy", "a", 0, "Annual percentage yield (%)")
 	stakeAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
-	stakeAddCmd.Flags().StringP("date", "d", "", "Stake date (YYYY-MM-DD)")
+	stakeAddCmd.Flags().StringP("date", "d", "", "Stake date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+
+	// Add flags for swap add
+	swapAddCmd.Flags().VarP(&decimalValue{}, "value", "v", "USD value of the swap (default: FROM_COIN price on the swap date)")
+	swapAddCmd.Flags().StringP("platform", "p", "", "Platform where swapped")
+	swapAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
+	swapAddCmd.Flags().StringP("date", "d", "", "Swap date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+
+	// Add flags for transfer add
+	transferAddCmd.Flags().VarP(&decimalValue{}, "fee", "f", "Fee paid in the transferred coin")
+	transferAddCmd.Flags().StringP("notes", "n", "", "Optional notes")
+	transferAddCmd.Flags().StringP("date", "d", "", "Transfer date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+
+	// Add filter, sort, and paging flags for list commands
+	for _, cmd := range []*cobra.Command{buyListCmd, sellListCmd, loanListCmd, stakeListCmd} {
+		addListFlags(cmd)
+	}
+
+	// Add --group for the lists of purchases and sales
+	buyListCmd.Flags().Bool("group", false, "Show one row per coin with its lot count, totals, and average price")
+	sellListCmd.Flags().Bool("group", false, "Show one row per coin with its lot count, totals, and average price")
+
+	// Add the filter flags that select records for bulk removal
+	for _, cmd := range []*cobra.Command{buyRemoveCmd, sellRemoveCmd, loanRemoveCmd, stakeRemoveCmd} {
+		addRemoveFilterFlags(cmd)
+	}
+	for table, cmd := range map[string]*cobra.Command{
+		"buy": buyListCmd, "sell": sellListCmd, "loan": loanListCmd,
+		"stake": stakeListCmd, "swap": swapListCmd, "transfer": transferListCmd,
+	} {
+		addColumnsFlag(cmd, table)
+	}
+	swapListCmd.Flags().String("tag", "", "Only show swaps with this tag")
+	transferListCmd.Flags().String("tag", "", "Only show transfers with this tag")
+
+	// Add the --tag flag for commands that record transactions
+	for _, cmd := range []*cobra.Command{buyAddCmd, sellAddCmd, loanAddCmd, loanRepayCmd, stakeAddCmd, swapAddCmd, transferAddCmd} {
+		cmd.Flags().StringSlice("tag", nil, "Label the record with a tag, e.g. DCA or airdrop (repeatable or comma-separated)")
+	}
+
+	// Add flags for coin
+	coinCmd.Flags().Bool("no-prices", false, "Disable live price fetching from CoinGecko")
+	coinCmd.Flags().Bool("no-chart", false, "Hide the price and value history charts")
+	coinCmd.Flags().Bool("history", false, "List the coin's amount, price, and value in each snapshot")
+	coinCmd.Flags().Int("days", 30, "Days of price history to chart: 7, 30, 90, or 365")
+	addRangeFlag(coinCmd)
+
+	// Add flags for coin migrate
+	coinMigrateCmd.Flags().Var(&decimalValue{decimal.NewFromInt(1)}, "ratio", "NEW coins received for each OLD coin")
+	coinMigrateCmd.Flags().String("gecko-id", "", "CoinGecko ID of NEW, e.g. polygon-ecosystem-token")
+
+	// Add flags for dca
+	dcaCmd.Flags().Bool("no-prices", false, "Disable live price fetching from CoinGecko")
+	dcaPlanAddCmd.Flags().Float64("amount", 0, "Amount of the coin to buy each time")
+	dcaPlanAddCmd.Flags().Float64("usd", 0, "USD value to buy each time")
+	dcaPlanAddCmd.Flags().String("every", "weekly", "How often to buy: daily, weekly, or monthly")
+	dcaPlanAddCmd.Flags().String("start", "", "Date of the first purchase (default today)")
+	dcaPlanAddCmd.Flags().StringP("platform", "p", "", "Platform to record the purchases on")
+	dcaRunCmd.Flags().Bool("dry-run", false, "List the due plans without buying")
+
+	// Add flags for daemon
+	daemonCmd.Flags().Duration("every", 0, "Snapshot interval (e.g. 6h)")
+	daemonCmd.Flags().String("at", "", "Daily snapshot time (HH:MM)")
+	daemonCmd.Flags().String("metrics-addr", "", "Serve Prometheus metrics at this address (e.g. :9101)")
+
+	// Add flags for snapshot save
+	snapshotSaveCmd.Flags().StringP("date", "d", "", "Backfill a snapshot for a past date (YYYY-MM-DD) using historical prices")
+	snapshotSaveCmd.Flags().StringP("note", "n", "", "Optional note")
+	snapshotSaveCmd.Flags().StringSlice("price", nil, "Value COIN at PRICE USD instead of fetching it, e.g. BTC=97000 (repeatable or comma-separated)")
+
+	// Add flags for snapshot list
+	addRangeFlag(snapshotListCmd)
+
+	// Add flags for snapshot chart
+	addRangeFlag(snapshotChartCmd)
+
+	// Add flags for tax report
+	taxReportCmd.Flags().IntP("year", "y", 0, "Tax year (default: current year)")
+	taxReportCmd.Flags().String("csv", "", "Export report to a CSV file")
+
+	// Add flags for report command
+	reportCmd.Flags().String("period", portfolio.PeriodMonthly, "Period length: monthly or quarterly")
+	reportCmd.Flags().IntP("year", "y", 0, "Only report this year (default: all years)")
+	reportCmd.Flags().String("csv", "", "Export report to a CSV file")
+	reportCmd.RegisterFlagCompletionFunc("period", completeFlag(completeValues(portfolio.PeriodMonthly, portfolio.PeriodQuarterly)))
+
+	// Add flags for stats command
+	addRangeFlag(statsCmd)
 
 	// Add flags for summary
 	summaryCmd.Flags().Bool("no-prices", false, "Disable live price fetching from CoinGecko")
+	summaryCmd.Flags().Bool("no-chart", false, "Hide the net value history chart")
+	summaryCmd.Flags().StringP("currency", "c", "", "Display currency (e.g. EUR, GBP; default from config or USD)")
+	summaryCmd.Flags().BoolP("all", "a", false, "Combine all portfolios")
+	summaryCmd.Flags().String("baseline", "", "Show P/L since this snapshot, by ID or pinned name, or none (default from config)")
+	summaryCmd.Flags().Bool("auto-map", false, "Map unmapped tickers to their single CoinGecko search match")
+	summaryCmd.Flags().String("since", "", "Only count records on or after this date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+	summaryCmd.Flags().String("until", "", "Only count records on or before this date (YYYY-MM-DD, or e.g. \"yesterday\", \"3 days ago\")")
+	addRangeFlag(summaryCmd)
+	summaryCmd.Flags().String("sort", "name", "Order coin tables by name, value, or pl (profit/loss)")
+	summaryCmd.Flags().BoolP("reverse", "r", false, "Reverse the order of coin tables")
+	summaryCmd.RegisterFlagCompletionFunc("sort", completeFlag(completeValues(summarySorts...)))
+	addRefreshFlag(summaryCmd)
+
+	registerCompletions()
+	markUsageErrors(rootCmd)
 }
 
-func initPortfolio() {
-	if dataPath == "" {
-		// Use data directory relative to current working directory
-		dataPath = filepath.Join("data", "portfolio.json")
+// initPortfolio opens the portfolio selected by --data or --portfolio and
+// purges expired trash
+func initPortfolio() error {
+	cfg, err := openPortfolio()
+	if err != nil {
+		return err
 	}
 
-	s, err := storage.New(dataPath)
+	if _, err := p.PurgeTrash(cfg.GetTrashRetention()); err != nil {
+		fmt.Fprintf(osStderr, "Warning: could not purge trash: %v\n", err)
+	}
+	return nil
+}
+
+// openPortfolio resolves the data path from --data and --portfolio, opens the
+// portfolio into p, and returns the loaded config
+func openPortfolio() (*config.ConfigStore, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveDataPath(cfg); err != nil {
+		return nil, err
+	}
+
+	s, err := openBackend(dataPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing storage: %v\n", err)
-		os.Exit(1)
+		return nil, ioError(fmt.Errorf("initializing storage: %w", err))
 	}
 	p = portfolio.New(s)
+	p.SetInterestMethod(cfg.GetInterestMethod())
+	p.SetAllowFutureDates(cfg.GetAllowFutureDates())
+	return cfg, nil
+}
+
+// resolveDataPath sets dataPath from --data and --portfolio, or to the
+// default portfolio
+func resolveDataPath(cfg *config.ConfigStore) error {
+	if portfolioName != "" {
+		if dataPath != "" {
+			return usageErrorf("use either --data or --portfolio, not both")
+		}
+		dir, ok := cfg.GetPortfolioDir(portfolioName)
+		if !ok {
+			return notFoundErrorf("unknown portfolio %s (see 'follyo portfolio list')", portfolioName)
+		}
+		dataPath = filepath.Join(dir, "portfolio.json")
+	}
+	if dataPath == "" {
+		dataPath = defaultDataPath()
+	}
+	return nil
+}
+
+// sqlitePath returns the path of the SQLite database, next to the portfolio data
+func sqlitePath() string {
+	return sqlitePathFor(dataPath)
+}
+
+// sqlitePathFor returns the path of the SQLite database for a portfolio data path
+func sqlitePathFor(path string) string {
+	return filepath.Join(filepath.Dir(path), "portfolio.db")
+}
+
+// openBackend opens the storage backend selected by the "storage" config
+// setting for the portfolio data at path. A new SQLite database is seeded
+// from the existing JSON files.
+func openBackend(path string) (storage.Backend, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GetStorage() != "sqlite" {
+		return storage.New(path)
+	}
+
+	dbPath := sqlitePathFor(path)
+	_, statErr := os.Stat(dbPath)
+	s, err := storage.NewSQLite(dbPath)
+	if err != nil || !os.IsNotExist(statErr) {
+		return s, err
+	}
+	if err := seedSQLite(s, path); err != nil {
+		s.Close()
+		os.Remove(dbPath)
+		return nil, fmt.Errorf("copying JSON data into %s: %w", dbPath, err)
+	}
+	return s, nil
+}
+
+// seedSQLite copies the JSON portfolio at path and its snapshots, if present, into a new database
+func seedSQLite(s *storage.SQLiteStorage, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		src, err := storage.New(path)
+		if err != nil {
+			return err
+		}
+		if err := storage.Copy(s, src); err != nil {
+			return err
+		}
+	}
+
+	snapPath := filepath.Join(filepath.Dir(path), "snapshots.json")
+	if _, err := os.Stat(snapPath); err == nil {
+		src, err := storage.NewSnapshotStore(snapPath)
+		if err != nil {
+			return err
+		}
+		dst, err := storage.NewSQLiteSnapshotStore(sqlitePathFor(path))
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		return storage.CopySnapshots(dst, src)
+	}
+	return nil
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "follyo",
 	Short: "Follyo - Personal Crypto Portfolio Tracker",
-	Long:  "Track your crypto holdings, sales, and loans across platforms.",
+	Long: `Track your crypto holdings, sales, and loans across platforms.
+
+Exit codes:
+  0  success
+  1  other error
+  2  invalid arguments, flags, or values, or a prompt under --non-interactive
+  3  record, snapshot, portfolio, or setting not found
+  4  reading or writing data files, or fetching prices, failed
+
+Use --non-interactive in scripts to fail instead of waiting for input, and
+--yes to accept confirmation prompts.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Completion opens the portfolio lazily, only when it suggests records
+		if isCompletionCmd(cmd) {
+			return nil
+		}
+		if err := setupLogging(cmd); err != nil {
+			return err
+		}
+		// The doctor and fsck open the portfolio themselves, so they can diagnose one that won't open
+		if cmd == doctorCmd || cmd == fsckCmd {
+			return nil
+		}
+		if err := initPortfolio(); err != nil {
+			return err
+		}
+		if cfg, err := loadConfig(); err == nil {
+			applyTheme(cfg)
+		}
+		if !nonInteractive {
+			suggestTickerMappings(cmd)
+		}
+		return nil
+	},
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// metric is a single Prometheus gauge, optionally split by coin
+type metric struct {
+	name   string
+	help   string
+	value  float64            // Used when byCoin is nil
+	byCoin map[string]float64 // Values labelled with coin="..."
+}
+
+// metricsHandler serves portfolio gauges in the Prometheus text format,
+// valued at live prices. mu serializes portfolio access with the daemon's
+// scheduled snapshots.
+func metricsHandler(mu *sync.Mutex) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		metrics, err := collectMetrics()
+		mu.Unlock()
+		if err != nil {
+			fmt.Fprintf(osStderr, "Error collecting metrics: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, metrics)
+	}
+}
+
+// collectMetrics values the current portfolio at live prices
+func collectMetrics() ([]metric, error) {
+	snap, positions, err := valuePortfolio()
+	if err != nil {
+		return nil, err
+	}
+	return snapshotMetrics(snap, positions.LoansByCoin), nil
+}
+
+// snapshotMetrics returns the gauges for a valued portfolio
+func snapshotMetrics(snap models.Snapshot, loansByCoin map[string]decimal.Decimal) []metric {
+	amounts := make(map[string]float64)
+	values := make(map[string]float64)
+	prices := make(map[string]float64)
+	for coin, cv := range snap.CoinValues {
+		amounts[coin] = cv.Amount
+		values[coin] = cv.ValueUSD
+		prices[coin] = cv.PriceUSD
+	}
+	loans := make(map[string]float64)
+	for coin, amount := range loansByCoin {
+		loans[coin] = amount.InexactFloat64()
+	}
+	return []metric{
+		{name: "follyo_holding_amount", help: "Net amount held per coin.", byCoin: amounts},
+		{name: "follyo_holding_value_usd", help: "USD value held per coin.", byCoin: values},
+		{name: "follyo_price_usd", help: "USD price per coin.", byCoin: prices},
+		{name: "follyo_loan_amount", help: "Outstanding loan amount per coin.", byCoin: loans},
+		{name: "follyo_holdings_value_usd", help: "Total USD value of holdings.", value: snap.HoldingsValue},
+		{name: "follyo_loans_value_usd", help: "Total USD value of loans, including accrued interest.", value: snap.LoansValue},
+		{name: "follyo_net_value_usd", help: "Holdings minus loans in USD.", value: snap.NetValue},
+		{name: "follyo_invested_usd", help: "Total USD spent on purchases.", value: snap.TotalInvested},
+		{name: "follyo_sold_usd", help: "Total USD received from sales.", value: snap.TotalSold},
+		{name: "follyo_profit_loss_usd", help: "Profit or loss in USD.", value: snap.ProfitLoss},
+		{name: "follyo_profit_loss_percent", help: "Profit or loss relative to the amount invested.", value: snap.ProfitLossPercent},
+		{name: "follyo_realized_profit_loss_usd", help: "Profit or loss from sales and swaps in USD.", value: snap.RealizedPL},
+		{name: "follyo_unrealized_profit_loss_usd", help: "Profit or loss of the coins still held in USD.", value: snap.UnrealizedPL},
+	}
+}
+
+// writeMetrics writes gauges in the Prometheus text exposition format, with
+// coin labels in sorted order
+func writeMetrics(w io.Writer, metrics []metric) {
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		if m.byCoin == nil {
+			fmt.Fprintf(w, "%s %s\n", m.name, formatMetricValue(m.value))
+			continue
+		}
+		coins := make([]string, 0, len(m.byCoin))
+		for coin := range m.byCoin {
+			coins = append(coins, coin)
+		}
+		sort.Strings(coins)
+		for _, coin := range coins {
+			fmt.Fprintf(w, "%s{coin=\"%s\"} %s\n", m.name, escapeLabel(coin), formatMetricValue(m.byCoin[coin]))
+		}
+	}
+}
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes a label value as required by the text format
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
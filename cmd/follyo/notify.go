@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/notify"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/spf13/cobra"
+)
+
+// Notification events, also the keys of message templates in the config
+const (
+	eventSnapshot    = "snapshot"
+	eventDailyChange = "daily_change"
+	eventPriceAlert  = "price_alert"
+	eventTest        = "test"
+)
+
+// defaultTemplates are the message bodies used when the config has no
+// template for an event
+var defaultTemplates = map[string]string{
+	eventSnapshot:    `Net value {{usd .Snapshot.NetValue}}, P/L {{signedUSD .Snapshot.ProfitLoss}} ({{percent .Snapshot.ProfitLossPercent}})`,
+	eventDailyChange: `Net value {{signedUSD .Change}} ({{percent .ChangePercent}}) since {{time .Previous.Timestamp}}, now {{usd .Snapshot.NetValue}}`,
+	eventPriceAlert:  `{{.Alert.Coin}} is {{usd .Price}}, {{if .Alert.Above}}above {{usd .Alert.Above}}{{else}}below {{usd .Alert.Below}}{{end}}`,
+	eventTest:        `Notifications from follyo are working`,
+}
+
+// templateFuncs format values in message templates
+var templateFuncs = template.FuncMap{
+	"usd":       formatUSD,
+	"signedUSD": formatSignedUSD,
+	"amount":    formatAmount,
+	"time":      formatSnapshotTime,
+	"percent":   func(v float64) string { return fmt.Sprintf("%+.1f%%", v) },
+}
+
+// notifyData is the data available to message templates. Fields not
+// relevant to an event are zero.
+type notifyData struct {
+	Event         string
+	Snapshot      models.Snapshot   // The saved snapshot (snapshot, daily_change)
+	Previous      models.Snapshot   // Last snapshot of an earlier day (daily_change)
+	Change        float64           // Net value change since Previous in USD (daily_change)
+	ChangePercent float64           // Net value change since Previous in percent (daily_change)
+	Alert         config.PriceAlert // The alert that fired (price_alert)
+	Price         float64           // The coin's USD price (price_alert)
+}
+
+// notifier sends event notifications to the destinations in the config
+type notifier struct {
+	senders   []notify.Sender
+	templates map[string]string
+}
+
+func newNotifier(settings config.Notifications) notifier {
+	n := notifier{templates: settings.Templates}
+	if settings.Webhook != "" {
+		n.senders = append(n.senders, notify.NewWebhook(settings.Webhook))
+	}
+	if settings.Ntfy != "" {
+		n.senders = append(n.senders, notify.NewNtfy(settings.Ntfy))
+	}
+	if settings.TelegramToken != "" && settings.TelegramChat != "" {
+		n.senders = append(n.senders, notify.NewTelegram(settings.TelegramToken, settings.TelegramChat))
+	}
+	return n
+}
+
+// enabled reports whether any destination is configured
+func (n notifier) enabled() bool {
+	return len(n.senders) > 0
+}
+
+// send renders the event's template with data and sends it to every destination
+func (n notifier) send(title string, data notifyData) error {
+	if !n.enabled() {
+		return nil
+	}
+	body, err := renderNotification(n.templates[data.Event], data)
+	if err != nil {
+		return err
+	}
+	return notify.SendAll(n.senders, notify.Message{Event: data.Event, Title: title, Body: body})
+}
+
+// renderNotification renders a message template, falling back to the
+// event's default template when text is empty
+func renderNotification(text string, data notifyData) (string, error) {
+	if text == "" {
+		text = defaultTemplates[data.Event]
+	}
+	tmpl, err := template.New(data.Event).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", data.Event, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", data.Event, err)
+	}
+	return b.String(), nil
+}
+
+// notifySnapshot sends the notifications for a newly saved live snapshot:
+// the snapshot itself, a large daily change in net value, and any price
+// alerts reached. Failures are warnings since the snapshot was already saved.
+func notifySnapshot(snap models.Snapshot, ps *prices.PriceService, livePrices map[string]float64) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not send notifications: %v\n", err)
+		return
+	}
+	settings := cfg.GetNotifications()
+	n := newNotifier(settings)
+
+	if err := n.send("Snapshot saved", notifyData{Event: eventSnapshot, Snapshot: snap}); err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not send snapshot notification: %v\n", err)
+	}
+
+	if settings.DailyChange > 0 && n.enabled() {
+		snapshots, err := listSnapshots()
+		if err != nil {
+			fmt.Fprintf(osStderr, "Warning: Could not check daily change: %v\n", err)
+		} else if prev, percent, ok := dailyChange(snapshots, snap, settings.DailyChange); ok {
+			data := notifyData{
+				Event:         eventDailyChange,
+				Snapshot:      snap,
+				Previous:      prev,
+				Change:        models.Sub(snap.NetValue, prev.NetValue),
+				ChangePercent: percent,
+			}
+			if err := n.send("Daily change", data); err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not send daily change notification: %v\n", err)
+			}
+		}
+	}
+
+	if _, err := checkPriceAlerts(cfg, n, ps, livePrices); err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not check price alerts: %v\n", err)
+	}
+}
+
+// dailyChange compares snap with the last snapshot of an earlier day,
+// returning it and the percent change in net value. It reports false unless
+// the change is at least threshold percent either way, and also when an
+// earlier snapshot today already crossed it, so the change is notified once
+// a day.
+func dailyChange(snapshots []models.Snapshot, snap models.Snapshot, threshold float64) (models.Snapshot, float64, bool) {
+	day := snap.Timestamp.Local().Format("2006-01-02")
+	var prev models.Snapshot
+	var today []models.Snapshot
+	for _, s := range snapshots {
+		if s.ID == snap.ID {
+			continue
+		}
+		switch d := s.Timestamp.Local().Format("2006-01-02"); {
+		case d < day:
+			prev = s // Snapshots are sorted, so this ends as the latest
+		case d == day && s.Timestamp.Before(snap.Timestamp):
+			today = append(today, s)
+		}
+	}
+	if prev.ID == "" || prev.NetValue == 0 {
+		return models.Snapshot{}, 0, false
+	}
+
+	change := func(s models.Snapshot) float64 {
+		return (s.NetValue - prev.NetValue) / math.Abs(prev.NetValue) * 100
+	}
+	for _, s := range today {
+		if math.Abs(change(s)) >= threshold {
+			return models.Snapshot{}, 0, false
+		}
+	}
+	percent := change(snap)
+	if math.Abs(percent) < threshold {
+		return models.Snapshot{}, 0, false
+	}
+	return prev, percent, true
+}
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Manage notifications",
+	Long: `Send notifications when a live snapshot is saved, when net value moves
+more than "daily_change_percent" in a day, and when a price alert is
+reached (see 'follyo alert').
+
+Destinations are set under "notifications" in config.json:
+  "webhook"                          URL receiving a JSON POST per event
+  "ntfy"                             ntfy topic URL, e.g. https://ntfy.sh/my-topic
+  "telegram_token", "telegram_chat"  Telegram bot token and chat ID
+
+Message bodies can be customized with Go templates under "templates",
+keyed by event: snapshot, daily_change, price_alert, or test. Templates
+can use .Snapshot, .Previous, .Change, .ChangePercent, .Alert, and .Price,
+formatted with usd, signedUSD, amount, percent, and time.`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test notification to every destination",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		n := newNotifier(cfg.GetNotifications())
+		if !n.enabled() {
+			return notFoundErrorf("no notification destinations configured (see 'follyo notify --help')")
+		}
+		if err := n.send("Test notification", notifyData{Event: eventTest}); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Sent test notification to %d destination(s)\n", len(n.senders))
+		return nil
+	},
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var platformCmd = &cobra.Command{
+	Use:   "platform",
+	Short: "Manage favorite platforms and platform aliases",
+	Long: `The platform registry is the platforms used by records plus favorites
+stored in config.json. New records take the registry's spelling of their
+platform, so "coinbase" is saved as "Coinbase" once Coinbase is known,
+and aliases such as "CB" stand for a full name. Shell completion and
+'follyo add' suggest registered platforms. 'follyo platform rename' fixes
+records entered under other spellings.`,
+}
+
+var platformAddCmd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "Add a favorite platform",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimSpace(args[0])
+		if name == "" {
+			return usageErrorf("platform name is empty")
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		added, err := cfg.AddFavoritePlatform(name)
+		if err != nil {
+			return ioError(err)
+		}
+		if !added {
+			fmt.Fprintf(osStdout, "%s is already a favorite\n", name)
+			return nil
+		}
+		fmt.Fprintf(osStdout, "Added favorite platform %s\n", name)
+		return nil
+	},
+}
+
+var platformListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered platforms and their aliases",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		registry, err := platformRegistry()
+		if err != nil {
+			return err
+		}
+		if len(registry) == 0 {
+			fmt.Fprintln(osStdout, "No platforms yet. Add a favorite with 'follyo platform add NAME'.")
+			return nil
+		}
+
+		counts, err := p.GetPlatformCounts()
+		if err != nil {
+			return err
+		}
+		records := make(map[string]int)
+		for _, c := range counts {
+			records[strings.ToLower(c.Platform)] = c.Records
+		}
+		favorites := cfg.GetFavoritePlatforms()
+		byAlias := cfg.GetPlatformAliases()
+		aliases := make(map[string][]string)
+		for _, alias := range sortedStringKeys(byAlias) {
+			target := strings.ToLower(byAlias[alias])
+			aliases[target] = append(aliases[target], alias)
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Platform\tRecords\tFavorite\tAliases")
+		for _, name := range registry {
+			favorite := ""
+			if slices.ContainsFunc(favorites, func(f string) bool { return strings.EqualFold(f, name) }) {
+				favorite = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", name, records[strings.ToLower(name)], favorite, strings.Join(aliases[strings.ToLower(name)], ", "))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var platformRemoveCmd = &cobra.Command{
+	Use:   "remove NAME",
+	Short: "Remove a favorite platform",
+	Long: `Remove a platform from the favorites. Records on the platform keep it
+in the registry.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemoveFavoritePlatform(args[0])
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("%s is not a favorite platform", args[0])
+		}
+		fmt.Fprintf(osStdout, "Removed favorite platform %s\n", args[0])
+		return nil
+	},
+}
+
+var platformRenameCmd = &cobra.Command{
+	Use:   "rename OLD NEW",
+	Short: "Rename a platform across all records",
+	Long: `Set the platform of every purchase, sale, loan, stake, swap, and
+transfer on OLD, in any capitalization, to NEW. Renaming to a platform
+already in use merges the two, e.g. 'follyo platform rename CB Coinbase'.
+
+A favorite, aliases, and the default platform naming OLD are updated too.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldName, newName := strings.TrimSpace(args[0]), strings.TrimSpace(args[1])
+		if oldName == "" || newName == "" {
+			return usageErrorf("platform names must not be empty")
+		}
+		if oldName == newName {
+			return usageErrorf("%s is already spelled that way", oldName)
+		}
+
+		counts, err := p.GetPlatformCounts()
+		if err != nil {
+			return err
+		}
+		records := 0
+		for _, c := range counts {
+			if strings.EqualFold(c.Platform, oldName) {
+				records = c.Records
+			}
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		favorite := slices.ContainsFunc(cfg.GetFavoritePlatforms(), func(f string) bool { return strings.EqualFold(f, oldName) })
+		if records == 0 && !favorite {
+			return notFoundErrorf("no records or favorite on platform %s", oldName)
+		}
+
+		ok, err := confirm(fmt.Sprintf("Rename %s to %s in %d record(s)?", oldName, newName, records))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(osStdout, "Cancelled.")
+			return nil
+		}
+
+		changed, err := p.RenamePlatform(oldName, newName)
+		if err != nil {
+			return err
+		}
+		if err := renamePlatformSettings(cfg, oldName, newName); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Renamed %s to %s in %d record(s)\n", oldName, newName, changed)
+		return nil
+	},
+}
+
+// renamePlatformSettings points the favorite, aliases, and default
+// platform naming oldName at newName
+func renamePlatformSettings(cfg *config.ConfigStore, oldName, newName string) error {
+	if removed, err := cfg.RemoveFavoritePlatform(oldName); err != nil {
+		return err
+	} else if removed {
+		if _, err := cfg.AddFavoritePlatform(newName); err != nil {
+			return err
+		}
+	}
+	for alias, platform := range cfg.GetPlatformAliases() {
+		if strings.EqualFold(platform, oldName) {
+			if err := cfg.SetPlatformAlias(alias, newName); err != nil {
+				return err
+			}
+		}
+	}
+	if strings.EqualFold(cfg.GetDefaultPlatform(), oldName) {
+		return cfg.SetDefaultPlatform(newName)
+	}
+	return nil
+}
+
+var platformAliasCmd = &cobra.Command{
+	Use:   "alias ALIAS PLATFORM",
+	Short: "Make a short name stand for a platform",
+	Long: `Make ALIAS, in any capitalization, stand for PLATFORM wherever a
+platform is given, e.g. 'follyo platform alias CB Coinbase' records
+'follyo buy add BTC 0.1 50000 -p cb' on Coinbase.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alias, platform := strings.TrimSpace(args[0]), strings.TrimSpace(args[1])
+		if alias == "" || platform == "" {
+			return usageErrorf("alias and platform must not be empty")
+		}
+		if strings.EqualFold(alias, platform) {
+			return usageErrorf("alias %s is the platform's own name", alias)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		// Aliases name a registered spelling, not another alias
+		platform, err = resolvePlatform(platform)
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetPlatformAlias(alias, platform); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "%s now stands for %s\n", alias, platform)
+		return nil
+	},
+}
+
+var platformUnaliasCmd = &cobra.Command{
+	Use:   "unalias ALIAS",
+	Short: "Remove a platform alias",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemovePlatformAlias(args[0])
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("no platform alias %s", args[0])
+		}
+		fmt.Fprintf(osStdout, "Removed platform alias %s\n", args[0])
+		return nil
+	},
+}
+
+// platformRegistry returns the favorite platforms followed by the other
+// platforms used by records, listing names that differ only in case once
+func platformRegistry() ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	registry := cfg.GetFavoritePlatforms()
+	used, err := p.GetPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range used {
+		if !slices.ContainsFunc(registry, func(r string) bool { return strings.EqualFold(r, name) }) {
+			registry = append(registry, name)
+		}
+	}
+	return registry, nil
+}
+
+// resolvePlatform returns the platform name is meant to be: the platform
+// of an alias, or the registry's spelling of a platform differing only in
+// case. Other names, including "", are returned unchanged.
+func resolvePlatform(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if platform, ok := cfg.GetPlatformAliases()[strings.ToLower(name)]; ok {
+		name = platform
+	}
+	registry, err := platformRegistry()
+	if err != nil {
+		return "", err
+	}
+	for _, registered := range registry {
+		if strings.EqualFold(registered, name) {
+			return registered, nil
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var portfolioCmd = &cobra.Command{
+	Use:   "portfolio",
+	Short: "Manage named portfolios",
+	Long: `Manage named portfolios, each stored in its own data directory.
+
+Select a portfolio for any command with --portfolio NAME. Without it, the
+default portfolio in the data directory is used: $FOLLYO_DATA_DIR, or
+~/.local/share/follyo ($XDG_DATA_HOME/follyo). Ticker mappings and other
+settings in its config.json are shared by all portfolios.`,
+}
+
+var portfolioAddCmd = &cobra.Command{
+	Use:   "add NAME DIR",
+	Short: "Register a named portfolio stored in DIR",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		dir, err := filepath.Abs(args[1])
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(name, defaultPortfolioName) {
+			return usageErrorf("%s is reserved for the portfolio in the data directory", defaultPortfolioName)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetPortfolio(name, dir); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Added portfolio %s (%s)\n", name, dir)
+		fmt.Fprintf(osStdout, "Use it with: follyo --portfolio %s summary\n", name)
+		return nil
+	},
+}
+
+var portfolioListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List portfolios",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := portfolioPaths()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Name\tDirectory\tCurrent")
+		for _, pf := range all {
+			current := ""
+			if filepath.Clean(pf.path) == filepath.Clean(dataPath) {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", pf.name, filepath.Dir(pf.path), current)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var portfolioRemoveCmd = &cobra.Command{
+	Use:   "remove NAME",
+	Short: "Unregister a named portfolio (its data is kept)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemovePortfolio(name)
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("portfolio %s not found", name)
+		}
+		fmt.Fprintf(osStdout, "Removed portfolio %s (data files were not deleted)\n", name)
+		return nil
+	},
+}
+
+var portfolioMigrateCmd = &cobra.Command{
+	Use:   "migrate [DIR]",
+	Short: "Move data from ./data to the data directory",
+	Long: `Move the portfolio, snapshots, config, and caches from DIR (./data by
+default), where older releases kept them, to the data directory:
+$FOLLYO_DATA_DIR, or ~/.local/share/follyo ($XDG_DATA_HOME/follyo).
+Afterwards follyo finds your portfolio from any working directory, and the
+config moves on to ~/.config/follyo the next time it is read.
+
+Unless $FOLLYO_DATA_DIR is set, ./data is moved the first time follyo runs
+from its parent directory, so this is only needed for data kept elsewhere.
+Nothing is moved if the data directory already holds a portfolio or config.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := legacyDataDir
+		if len(args) == 1 {
+			src = args[0]
+		}
+		dst := storage.DataDir()
+		if !hasLegacyData(src) {
+			return notFoundErrorf("no portfolio or config in %s", src)
+		}
+		srcAbs, err := filepath.Abs(src)
+		if err != nil {
+			return err
+		}
+		dstAbs, err := filepath.Abs(dst)
+		if err != nil {
+			return err
+		}
+		if srcAbs == dstAbs {
+			return usageErrorf("%s is already the data directory", src)
+		}
+		if hasLegacyData(dst) {
+			return fmt.Errorf("%s already holds a portfolio or config; move the files by hand", dst)
+		}
+
+		ok, err := confirm(fmt.Sprintf("Move the data in %s to %s?", srcAbs, dstAbs))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(osStdout, "Cancelled")
+			return nil
+		}
+
+		moved, err := moveDataDir(srcAbs, dstAbs)
+		if err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Moved %d files from %s to %s\n", moved, srcAbs, dstAbs)
+		return nil
+	},
+}
+
+// defaultPortfolioName is the name shown for the portfolio in the data
+// directory
+const defaultPortfolioName = "default"
+
+// legacyDataDir is where older releases kept all data, relative to the
+// current working directory
+const legacyDataDir = "data"
+
+// migrateLegacyData moves data an older release kept in ./data to the data
+// directory, so it is found from any working directory. Nothing is moved
+// when $FOLLYO_DATA_DIR is set, or, with a warning, when the data directory
+// already holds a portfolio or config.
+func migrateLegacyData() error {
+	if os.Getenv("FOLLYO_DATA_DIR") != "" || !hasLegacyData(legacyDataDir) {
+		return nil
+	}
+	src, err := filepath.Abs(legacyDataDir)
+	if err != nil {
+		return err
+	}
+	dst := storage.DataDir()
+	_, err = os.Stat(filepath.Join(config.Dir(), "config.json"))
+	if hasLegacyData(dst) || err == nil {
+		fmt.Fprintf(osStderr, "Warning: %s is no longer read, and %s or %s already holds data; move what you need by hand\n", src, dst, config.Dir())
+		return nil
+	}
+
+	moved, err := moveDataDir(src, dst)
+	if err != nil {
+		return ioError(fmt.Errorf("moving %s to %s: %w", src, dst, err))
+	}
+	fmt.Fprintf(osStderr, "Moved %d files from %s to %s, where follyo now keeps its data\n", moved, src, dst)
+	return nil
+}
+
+// hasLegacyData reports whether dir holds a portfolio or config, rather
+// than being some unrelated directory named "data"
+func hasLegacyData(dir string) bool {
+	for _, name := range []string{"portfolio.json", "config.json", "portfolio.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultDataPath returns the data file of the default portfolio: in the
+// configured data_dir, or in the data directory
+func defaultDataPath() string {
+	if cfg, err := loadConfig(); err == nil && cfg.GetDataDir() != "" {
+		return filepath.Join(cfg.GetDataDir(), "portfolio.json")
+	}
+	return storage.DefaultDataPath()
+}
+
+// namedPortfolio is a portfolio name and its data file
+type namedPortfolio struct {
+	name string
+	path string
+}
+
+// portfolioPaths returns the default portfolio followed by the registered
+// ones sorted by name
+func portfolioPaths() ([]namedPortfolio, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	all := []namedPortfolio{{name: defaultPortfolioName, path: defaultDataPath()}}
+	registered := cfg.GetAllPortfolios()
+	for _, name := range sortedStringKeys(registered) {
+		all = append(all, namedPortfolio{name: name, path: filepath.Join(registered[name], "portfolio.json")})
+	}
+	return all, nil
+}
+
+// loadAllSummaries merges the summaries and staking yields of every portfolio
+// that has data. It returns the names of the portfolios included.
+func loadAllSummaries(since, until models.Date) (portfolio.Summary, []portfolio.YieldEntry, []string, error) {
+	var summaries []portfolio.Summary
+	var yields [][]portfolio.YieldEntry
+	var names []string
+	cfg, err := loadConfig()
+	if err != nil {
+		return portfolio.Summary{}, nil, nil, err
+	}
+	all, err := portfolioPaths()
+	if err != nil {
+		return portfolio.Summary{}, nil, nil, err
+	}
+	for _, pf := range all {
+		if !portfolioExists(pf.path) {
+			continue
+		}
+		s, err := openBackend(pf.path)
+		if err != nil {
+			return portfolio.Summary{}, nil, nil, fmt.Errorf("portfolio %s: %w", pf.name, err)
+		}
+		other := portfolio.New(s)
+		other.SetInterestMethod(cfg.GetInterestMethod())
+
+		summary, err := other.GetSummaryBetween(since, until)
+		if err != nil {
+			return portfolio.Summary{}, nil, nil, fmt.Errorf("portfolio %s: %w", pf.name, err)
+		}
+		yield, err := other.GetProjectedYield()
+		if err != nil {
+			return portfolio.Summary{}, nil, nil, fmt.Errorf("portfolio %s: %w", pf.name, err)
+		}
+		summaries = append(summaries, summary)
+		yields = append(yields, yield)
+		names = append(names, pf.name)
+	}
+	return portfolio.MergeSummaries(summaries...), portfolio.MergeYield(yields...), names, nil
+}
+
+// portfolioExists reports whether a portfolio has a JSON or SQLite data file
+func portfolioExists(path string) bool {
+	for _, f := range []string{path, sqlitePathFor(path)} {
+		if _, err := os.Stat(f); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// moveDataDir moves the entries of src into dst, which is created if needed,
+// and returns how many were moved. Lock files stay behind and are removed
+// with src once it is empty. Entries are renamed, or copied when src and
+// dst are on different file systems.
+func moveDataDir(src, dst string) (int, error) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".lock") {
+			continue
+		}
+		from, to := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())
+		if err := os.Rename(from, to); err != nil {
+			if err := copyEntry(from, to, e); err != nil {
+				return moved, err
+			}
+			if err := os.RemoveAll(from); err != nil {
+				return moved, err
+			}
+		}
+		moved++
+	}
+
+	// Only lock files are left; removing src is best-effort
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".lock") {
+			os.Remove(filepath.Join(src, e.Name()))
+		}
+	}
+	os.Remove(src)
+	return moved, nil
+}
+
+// copyEntry copies a file, keeping its permissions, or a directory tree
+func copyEntry(from, to string, e os.DirEntry) error {
+	if e.IsDir() {
+		return os.CopyFS(to, os.DirFS(from))
+	}
+	info, err := e.Info()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, info.Mode().Perm())
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var rebalanceCmd = &cobra.Command{
+	Use:     "rebalance",
+	Aliases: []string{"rb"},
+	Short:   "Calculate the trades that reach a target allocation",
+	Long: `Calculate how much of each coin to buy or sell, at live prices, for
+holdings to match a target allocation in percent of total value.
+
+Targets must add up to 100. Held coins without a target are sold. Without
+--target, the "target_allocation" of the goals in config.json is used.
+
+Examples:
+  follyo rebalance --target BTC=50,ETH=30,SOL=20
+  follyo rebalance --target BTC=60 --target ETH=40`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, _ := cmd.Flags().GetStringSlice("target")
+		targets, err := parseTargets(values)
+		if err != nil {
+			return err
+		}
+		if targets == nil {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			targets = cfg.GetGoals().Allocation
+			if len(targets) == 0 {
+				return usageErrorf("no target allocation: use --target or set goals in the config")
+			}
+		}
+		if err := portfolio.ValidateTargets(targets); err != nil {
+			return err
+		}
+
+		summary, err := p.GetSummary()
+		if err != nil {
+			return err
+		}
+		// Held coins and targeted coins, which may not be held yet
+		var coins []string
+		for coin, amount := range summary.HoldingsByCoin {
+			if _, targeted := targets[coin]; amount.IsPositive() && !targeted {
+				coins = append(coins, coin)
+			}
+		}
+		for coin := range targets {
+			coins = append(coins, coin)
+		}
+		sortStrings(coins)
+
+		fmt.Fprintln(osStdout, "Fetching live prices...")
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		livePrices, err := ps.GetPrices(coins)
+		if err != nil {
+			return ioError(fmt.Errorf("could not fetch prices: %w", err))
+		}
+		printRebalanceReport(summary.HoldingsByCoin, livePrices, targets)
+		return nil
+	},
+}
+
+// parseTargets parses target allocations given as COIN=PERCENT. No values
+// returns nil.
+func parseTargets(values []string) (map[string]float64, error) {
+	return parseCoinValues(values, "target", "PERCENT")
+}
+
+// printRebalanceReport prints the trades that bring holdings to the target
+// allocation, warning about coins left out for lack of a price
+func printRebalanceReport(holdingsByCoin map[string]decimal.Decimal, livePrices, targets map[string]float64) {
+	var missing []string
+	for coin, amount := range holdingsByCoin {
+		if _, ok := livePrices[coin]; !ok && amount.IsPositive() {
+			missing = append(missing, coin)
+		}
+	}
+	for coin := range targets {
+		if _, ok := livePrices[coin]; !ok && !holdingsByCoin[coin].IsPositive() {
+			missing = append(missing, coin)
+		}
+	}
+	if len(missing) > 0 {
+		sortStrings(missing)
+		fmt.Fprintf(osStderr, "Warning: No price for %s, left out of the rebalance\n", strings.Join(missing, ", "))
+	}
+
+	entries := portfolio.CalculateRebalance(holdingsByCoin, livePrices, targets)
+	var values []float64
+	for _, e := range entries {
+		values = append(values, e.ValueUSD)
+	}
+	fmt.Fprintln(osStdout, "\n=== REBALANCE ===")
+	fmt.Fprintf(osStdout, "Total value: %s\n\n", formatMoney(models.Add(values...)))
+	printRebalance(entries)
+}
+
+// printGoals shows progress toward the goals in the config: the target net
+// value as a bar, and the trades that reach the target allocation. Values
+// are in the display currency; usdRate converts the USD target value to it.
+func printGoals(goals config.Goals, netValue float64, holdingsByCoin map[string]decimal.Decimal, livePrices map[string]float64, usdRate float64) {
+	if goals.TargetValue <= 0 && len(goals.Allocation) == 0 {
+		return
+	}
+	fmt.Fprintln(osStdout, "\nGOALS:")
+	if goals.TargetValue > 0 {
+		target := goals.TargetValue * usdRate
+		percent := safeDivide(netValue, target) * 100
+		fmt.Fprintf(osStdout, "  Net value:  %s %5.1f%% of %s\n", renderBar(percent, barWidth()), percent, formatMoney(target))
+	}
+	if len(goals.Allocation) > 0 {
+		if err := portfolio.ValidateTargets(goals.Allocation); err != nil {
+			fmt.Fprintf(osStderr, "Warning: Ignoring target_allocation in the config: %v\n", err)
+			return
+		}
+		fmt.Fprintln(osStdout, "  Allocation:")
+		printRebalance(portfolio.CalculateRebalance(holdingsByCoin, livePrices, goals.Allocation))
+	}
+}
+
+// printRebalance prints rebalancing trades as a table, with values in the
+// display currency
+func printRebalance(entries []portfolio.RebalanceEntry) {
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "  Coin\tCurrent\tTarget\tAction\tValue\tAmount\t")
+	for _, e := range entries {
+		action := "Hold"
+		switch {
+		case math.Abs(e.TradeUSD) < 0.01:
+		case e.TradeUSD > 0:
+			action = colorGreenText("Buy")
+		default:
+			action = colorRedText("Sell")
+		}
+		fmt.Fprintf(w, "  %s\t%.1f%%\t%.1f%%\t%s\t%s\t%s\t\n",
+			e.Coin, e.CurrentPercent, e.TargetPercent, action,
+			formatMoney(math.Abs(e.TradeUSD)), formatAmount(math.Abs(e.TradeAmount))+" "+e.Coin)
+	}
+	w.Flush()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// addRefreshFlag adds --refresh to a view command and makes it redraw the
+// view with fresh prices at that interval, or "refresh_interval" from
+// config.json, until interrupted
+func addRefreshFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("refresh", 0, "Redraw with fresh prices at this interval until Ctrl-C, e.g. 5m (default from config on a terminal, off)")
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		every, err := refreshInterval(cmd)
+		if err != nil {
+			return err
+		}
+		if every == 0 {
+			return run(cmd, args)
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(stop)
+		return refreshLoop(every, stop, func() error { return run(cmd, args) })
+	}
+}
+
+// refreshInterval returns the interval given by --refresh, or else by
+// "refresh_interval" in config.json; 0 shows the view once. The configured
+// interval only applies on a terminal, so scripts reading the output aren't
+// left waiting on a view that never ends.
+func refreshInterval(cmd *cobra.Command) (time.Duration, error) {
+	if cmd.Flags().Changed("refresh") {
+		every, _ := cmd.Flags().GetDuration("refresh")
+		if every != 0 && every < config.MinRefreshInterval {
+			return 0, usageErrorf("--refresh %s is too short (minimum %s)", every, config.MinRefreshInterval)
+		}
+		return every, nil
+	}
+	if !stdoutIsTerminal() {
+		return 0, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	return cfg.GetRefreshInterval(), nil
+}
+
+// refreshLoop runs show every interval, clearing the terminal first and
+// ending each view with the time it was drawn, until stop receives
+func refreshLoop(every time.Duration, stop <-chan os.Signal, show func() error) error {
+	for {
+		if stdoutIsTerminal() {
+			fmt.Fprint(osStdout, "\033[H\033[2J")
+		}
+		if err := show(); err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "\nLast updated %s, refreshing every %s (Ctrl-C to stop)\n",
+			time.Now().Format("2006-01-02 15:04:05"), every)
+
+		timer := time.NewTimer(every)
+		select {
+		case <-stop:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show invested, sold, and realized P/L per month or quarter",
+	Long: `Show a performance report per calendar month or quarter: the USD
+invested in purchases, received from sales, and realized P/L of the
+sales and swaps in the period, and the change in net value between
+snapshots.
+
+The value change needs a snapshot in the period and one before it (or
+a second one in it); take them with 'follyo snapshot save' or turn on
+"auto_snapshot". It includes price moves as well as deposits. With at
+least two snapshots, the risk statistics of 'follyo stats' over the
+periods reported follow the table.
+
+Use --year to report a single year, and --csv to export the report to a
+CSV file, e.g.
+  follyo report --period quarterly --year 2024 --csv 2024.csv`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		period, _ := cmd.Flags().GetString("period")
+		year, _ := cmd.Flags().GetInt("year")
+		csvPath, _ := cmd.Flags().GetString("csv")
+
+		snapshots, err := listSnapshots()
+		if err != nil {
+			return err
+		}
+		report, err := p.GetPeriodReport(period, year, snapshots)
+		if err != nil {
+			return err
+		}
+
+		if csvPath != "" {
+			if err := writeReportCSV(csvPath, report); err != nil {
+				return ioError(fmt.Errorf("writing CSV: %w", err))
+			}
+			fmt.Fprintf(osStdout, "Exported %d periods to %s\n", len(report), csvPath)
+			return nil
+		}
+
+		if len(report) == 0 {
+			fmt.Fprintln(osStdout, "No records to report.")
+			return nil
+		}
+
+		var invested, sold, realized []float64
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "Period\tInvested\tSold\tRealized P/L\tValue Change\t")
+		for _, r := range report {
+			valueChange := "-"
+			if r.HasValueChange {
+				valueChange = colorByValue(formatSignedMoney(r.ValueChangeUSD), r.ValueChangeUSD)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n",
+				r.Label, formatUSD(r.InvestedUSD.InexactFloat64()), formatUSD(r.SoldUSD.InexactFloat64()),
+				colorByValue(formatSignedMoney(r.RealizedUSD.InexactFloat64()), r.RealizedUSD.InexactFloat64()), valueChange)
+			invested = append(invested, r.InvestedUSD.InexactFloat64())
+			sold = append(sold, r.SoldUSD.InexactFloat64())
+			realized = append(realized, r.RealizedUSD.InexactFloat64())
+		}
+		totalRealized := models.Add(realized...)
+		fmt.Fprintf(w, "Total\t%s\t%s\t%s\t\t\n",
+			formatUSD(models.Add(invested...)), formatUSD(models.Add(sold...)),
+			colorByValue(formatSignedMoney(totalRealized), totalRealized))
+		w.Flush()
+
+		// Risk over the snapshots the report covers
+		var covered []models.Snapshot
+		for _, s := range snapshots {
+			day := models.DateOf(s.Timestamp.Local())
+			if !day.Before(report[0].Start) && !day.After(report[len(report)-1].End) {
+				covered = append(covered, s)
+			}
+		}
+		if len(covered) >= 2 {
+			fmt.Fprintln(osStdout, "\nRISK:")
+			printSnapshotStats(portfolio.GetSnapshotStats(covered))
+		}
+		return nil
+	},
+}
+
+// writeReportCSV writes a period report to a CSV file. The value change is
+// empty for periods without snapshots to compare.
+func writeReportCSV(path string, report []portfolio.PeriodReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"period", "start", "end", "invested_usd", "sold_usd", "realized_pl_usd", "value_change_usd"})
+	for _, r := range report {
+		valueChange := ""
+		if r.HasValueChange {
+			valueChange = strconv.FormatFloat(r.ValueChangeUSD, 'f', 2, 64)
+		}
+		w.Write([]string{
+			r.Label,
+			r.Start.String(),
+			r.End.String(),
+			r.InvestedUSD.StringFixed(2),
+			r.SoldUSD.StringFixed(2),
+			r.RealizedUSD.StringFixed(2),
+			valueChange,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 )
 
@@ -22,88 +25,215 @@ COIN: The cryptocurrency symbol (e.g., BTC, ETH)
 AMOUNT: Amount of coins sold
 PRICE: Sell price per coin in USD (optional if --total is used)
 
-Use either PRICE argument or --total flag, not both.`,
+Use either PRICE argument or --total flag, not both.
+
+You can only sell what is available (holdings - sales - staked) on the
+sale date, without leaving later sales short. A sale with the same coin,
+amount, price, platform, and date as an existing one is refused as a
+likely duplicate. Use --force to add the sale anyway, e.g.
+for coins held in a wallet you don't track.
+
+Sales are matched against purchases first-in, first-out for cost basis.
+Use --from-lot with the ID of a purchase (or swap) to sell those coins
+instead, e.g. for specific-identification cost basis; repeat it to sell
+from several lots, in order. Run 'follyo sell lots COIN' to list the
+purchases with coins left to sell.`,
 	Args: cobra.RangeArgs(2, 3),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		coin := args[0]
-		amount := parseFloat(args[1], "amount")
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
 
-		total, _ := cmd.Flags().GetFloat64("total")
-		var price float64
+		total := decimalFlag(cmd, "total")
+		var price decimal.Decimal
 
-		if len(args) == 3 && total > 0 {
-			fmt.Fprintln(osStderr, "Error: specify either PRICE argument or --total flag, not both")
-			osExit(1)
+		if len(args) == 3 && total.IsPositive() {
+			return usageErrorf("specify either PRICE argument or --total flag, not both")
 		}
 
 		if len(args) == 3 {
-			price = parseFloat(args[2], "price")
-		} else if total > 0 {
-			price = total / amount
+			if price, err = parseDecimal(args[2], "price"); err != nil {
+				return err
+			}
+		} else if total.IsPositive() {
+			price = perUnit(total, amount)
 		} else {
-			fmt.Fprintln(osStderr, "Error: specify either PRICE argument or --total flag")
-			osExit(1)
+			return usageErrorf("specify either PRICE argument or --total flag")
 		}
 
-		platform, _ := cmd.Flags().GetString("platform")
+		platform, err := platformFlag(cmd)
+		if err != nil {
+			return err
+		}
 		notes, _ := cmd.Flags().GetString("notes")
-		date, _ := cmd.Flags().GetString("date")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		fee := decimalFlag(cmd, "fee")
+		lotIDs, _ := cmd.Flags().GetStringSlice("from-lot")
+		if len(lotIDs) > 0 {
+			known, err := allLotIDs()
+			if err != nil {
+				return err
+			}
+			for i, id := range lotIDs {
+				if lotIDs[i], err = resolveID("lot", id, known); err != nil {
+					return err
+				}
+			}
+		}
 
-		sale, err := p.AddSale(coin, amount, price, platform, notes, date)
+		force, _ := cmd.Flags().GetBool("force")
+		if !force {
+			day, err := parseDate(date, "date")
+			if err != nil {
+				return err
+			}
+			dup, found, err := p.FindDuplicateSale(models.NewSale(strings.ToUpper(coin), amount, price, platform, notes, day))
+			if err != nil {
+				return err
+			}
+			if found {
+				return fmt.Errorf("sale %s has the same coin, amount, price, platform, and date; use --force to add it anyway", dup.ID)
+			}
+		}
+
+		addSale := p.AddSaleWithFee
+		if force {
+			addSale = p.AddSaleUnchecked
+		}
+		sale, err := addSale(coin, amount, price, fee, platform, notes, date, lotIDs, tags...)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
-		fmt.Printf("Sold %s %s @ %s (ID: %s)\n", formatAmount(sale.Amount), sale.Coin, formatUSD(sale.SellPriceUSD), sale.ID)
+		feeText := ""
+		if sale.FeeUSD.IsPositive() {
+			feeText = fmt.Sprintf(" + %s fee", formatUSD(sale.FeeUSD.InexactFloat64()))
+		}
+		fmt.Printf("Sold %s %s @ %s%s (ID: %s)\n", formatAmount(sale.Amount.InexactFloat64()), sale.Coin, formatUSD(sale.SellPriceUSD.InexactFloat64()), feeText, sale.ID)
+		return nil
 	},
 }
 
 var sellListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all sales",
-	Run: func(cmd *cobra.Command, args []string) {
-		sales, err := p.ListSales()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := listOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		sales, err := p.ListSalesFiltered(opts)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
 
 		if len(sales) == 0 {
 			fmt.Fprintln(osStdout, "No sales found.")
-			return
+			return nil
+		}
+
+		if group, _ := cmd.Flags().GetBool("group"); group {
+			return printGroupedList(cmd, opts, sales, "sale", "Proceeds USD", func(s models.Sale) (string, float64, float64, float64) {
+				return s.Coin, s.Amount.InexactFloat64(), s.TotalValueUSD().InexactFloat64(), s.ProceedsUSD().InexactFloat64()
+			})
 		}
 
+		matching := sales
+		page, limit := pageFromFlags(cmd)
+		sales, pageInfo := paginate(sales, page, limit)
+
+		all, err := p.ListSales()
+		if err != nil {
+			return err
+		}
+		short := shortIDs(idsOf(all, func(s models.Sale) string { return s.ID }))
+		if err := printTable(cmd, "sell", saleColumns(short), sales, &opts); err != nil {
+			return err
+		}
+		printListTotals(matching, pageInfo != "", func(s models.Sale) (string, float64, float64) {
+			return s.Coin, s.Amount.InexactFloat64(), s.TotalValueUSD().InexactFloat64()
+		})
+		printListFooter(opts, len(matching), "sale", pageInfo)
+		return nil
+	},
+}
+
+var sellLotsCmd = &cobra.Command{
+	Use:   "lots COIN",
+	Short: "List purchases with coins left to sell",
+	Long: `List the purchases (and swaps into COIN) that have not been sold in
+full, oldest first, with the amount left after matching past sales. Pass
+an ID to 'follyo sell add --from-lot' to sell from that purchase.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lots, err := p.GetOpenLots(args[0])
+		if err != nil {
+			return err
+		}
+		if len(lots) == 0 {
+			fmt.Fprintf(osStdout, "No %s purchases with coins left to sell.\n", strings.ToUpper(args[0]))
+			return nil
+		}
+
+		known, err := allLotIDs()
+		if err != nil {
+			return err
+		}
+		short := shortIDs(known)
 		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCoin\tAmount\tPrice/Unit\tTotal USD\tPlatform\tDate")
-		for _, s := range sales {
-			platform := s.Platform
+		fmt.Fprintln(w, "ID\tRemaining\tAmount\tCost/Unit\tPlatform\tDate")
+		for _, l := range lots {
+			platform := l.Holding.Platform
 			if platform == "" {
 				platform = "-"
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				s.ID, s.Coin, formatAmount(s.Amount),
-				formatUSD(s.SellPriceUSD), formatUSD(s.TotalValueUSD()),
-				platform, s.Date)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				short(l.Holding.ID), formatAmount(l.Remaining.InexactFloat64()), formatAmount(l.Holding.Amount.InexactFloat64()),
+				formatUSD(l.Holding.CostPerUnitUSD().InexactFloat64()), platform, l.Holding.Date)
 		}
 		w.Flush()
+		return nil
 	},
 }
 
 var sellRemoveCmd = &cobra.Command{
-	Use:   "remove ID",
-	Short: "Remove a sale by ID",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		id := args[0]
-		removed, err := p.RemoveSale(id)
-		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
-		}
-		if removed {
-			fmt.Printf("Removed sale %s\n", id)
-		} else {
-			fmt.Printf("Sale %s not found\n", id)
-		}
+	Use:   "remove [ID...]",
+	Short: "Remove sales by ID or filter",
+	Long: `Remove one or more sales.
+
+Instead of IDs, select the sales to remove with --coin, --platform, --tag,
+--since, --until, and --before, e.g. to clean up a bad import:
+
+  follyo sell remove --coin TEST --before 2023-01-01
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeMatching(cmd, args, "sale", p.ListSalesFiltered, func(s models.Sale) (string, string) {
+			return s.ID, fmt.Sprintf("%s %s sold %s%s", formatAmount(s.Amount.InexactFloat64()), s.Coin, s.Date, onPlatform(s.Platform))
+		}, p.RemoveSale, func(id string) string {
+			return fmt.Sprintf("Removed sale %[1]s (restore with 'follyo trash restore %[1]s')", id)
+		})
 	},
 }
+
+// allLotIDs returns the IDs of every purchase and swap, which --from-lot
+// may name
+func allLotIDs() ([]string, error) {
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+	ids := idsOf(holdings, func(h models.Holding) string { return h.ID })
+	return append(ids, idsOf(swaps, func(sw models.Swap) string { return sw.ID })...), nil
+}
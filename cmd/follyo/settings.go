@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// setting is a config value editable with 'follyo config'
+type setting struct {
+	key  string
+	help string
+	// get returns the current value and whether it is the default
+	get func(cfg *config.ConfigStore) (string, bool)
+	// set validates and saves a value; "" restores the default
+	set func(cfg *config.ConfigStore, value string) error
+}
+
+// settings are the editable settings, in the order they are listed
+var settings = []setting{
+	{
+		key:  "data_dir",
+		help: "Directory of the default portfolio",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			if dir := cfg.GetDataDir(); dir != "" {
+				return dir, false
+			}
+			return storage.DataDir(), true
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			return cfg.SetDataDir(value)
+		},
+	},
+	{
+		key:  "default_platform",
+		help: "Platform of records added without --platform",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			platform := cfg.GetDefaultPlatform()
+			return platform, platform == ""
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			return cfg.SetDefaultPlatform(value)
+		},
+	},
+	{
+		key:  "display_currency",
+		help: "Currency of summary values, e.g. EUR",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			currency := cfg.GetDisplayCurrency()
+			return currency, currency == "USD"
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			value = strings.ToUpper(value)
+			if value != "" && !prices.IsSupportedCurrency(value) {
+				return usageErrorf("unsupported currency %s (supported: %s)",
+					value, strings.Join(prices.SupportedCurrencies, ", "))
+			}
+			return cfg.SetDisplayCurrency(value)
+		},
+	},
+	{
+		key:  "price_cache_ttl",
+		help: "How long live prices are reused, e.g. 5m",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			if ttl := cfg.GetPriceCacheTTL(); ttl > 0 {
+				return ttl.String(), false
+			}
+			return "2m0s", true
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			return cfg.SetPriceCacheTTL(value)
+		},
+	},
+	{
+		key:  "refresh_interval",
+		help: "How often summary and dashboard redraw on a terminal, e.g. 5m",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			if every := cfg.GetRefreshInterval(); every > 0 {
+				return every.String(), false
+			}
+			return "off", true
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			return cfg.SetRefreshInterval(value)
+		},
+	},
+	{
+		key:  "baseline_snapshot",
+		help: "Snapshot ID or pinned name P/L is shown since, or none",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			if baseline := cfg.GetBaselineSnapshot(); baseline != "" {
+				return baseline, false
+			}
+			return "none", true
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			if value == "" || strings.EqualFold(value, "none") {
+				return cfg.SetBaselineSnapshot("")
+			}
+			snapshots, err := listSnapshots()
+			if err != nil {
+				return err
+			}
+			snap, err := findSnapshot(snapshots, value)
+			if err != nil {
+				return err
+			}
+			// A pinned name follows the pin when it is moved to another snapshot
+			if snap.Pin != "" && strings.EqualFold(snap.Pin, strings.TrimSpace(value)) {
+				return cfg.SetBaselineSnapshot(snap.Pin)
+			}
+			return cfg.SetBaselineSnapshot(snap.ID)
+		},
+	},
+	{
+		key:  "auto_snapshot",
+		help: "Save a daily snapshot whenever prices are fetched (true/false)",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			enabled := cfg.GetAutoSnapshot()
+			return strconv.FormatBool(enabled), !enabled
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			enabled, err := parseBoolSetting(value)
+			if err != nil {
+				return err
+			}
+			return cfg.SetAutoSnapshot(enabled)
+		},
+	},
+	{
+		key:  "theme",
+		help: "Color theme: " + strings.Join(themeNames, ", "),
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			theme := cfg.GetTheme()
+			return theme, theme == "dark"
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			value = strings.ToLower(value)
+			if value != "" {
+				if _, err := resolveTheme(value, cfg.GetThemeColors(), true); err != nil {
+					return usageErrorf("%v", err)
+				}
+			}
+			return cfg.SetTheme(value)
+		},
+	},
+	{
+		key:  "interest_method",
+		help: "Loan interest: simple or compound",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			method := cfg.GetInterestMethod()
+			return method, method == "simple"
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			if value == "" {
+				value = "simple"
+			}
+			return cfg.SetInterestMethod(value)
+		},
+	},
+	{
+		key:  "default_view",
+		help: "Shown without a command: help or dashboard",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			view := cfg.GetDefaultView()
+			return view, view == "help"
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			if value == "" {
+				value = "help"
+			}
+			return cfg.SetDefaultView(value)
+		},
+	},
+	{
+		key:  "allow_future_dates",
+		help: "Accept records dated after today (true/false)",
+		get: func(cfg *config.ConfigStore) (string, bool) {
+			allow := cfg.GetAllowFutureDates()
+			return strconv.FormatBool(allow), !allow
+		},
+		set: func(cfg *config.ConfigStore, value string) error {
+			allow, err := parseBoolSetting(value)
+			if err != nil {
+				return err
+			}
+			return cfg.SetAllowFutureDates(allow)
+		},
+	},
+}
+
+// findSetting returns the setting with the given key
+func findSetting(key string) (setting, error) {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.key
+	}
+	return setting{}, notFoundErrorf("unknown setting %s (available: %s)", key, strings.Join(keys, ", "))
+}
+
+// parseBoolSetting parses a true/false setting; "" is false
+func parseBoolSetting(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, usageErrorf("invalid value %q: use true or false", value)
+	}
+	return b, nil
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change settings",
+	Long: `View and change the settings in config.json without editing it by
+hand. Run 'follyo config list' to see every setting with its value.
+
+Settings are taken from, in order of precedence:
+
+  1. command-line flags, e.g. --data or summary --currency
+  2. FOLLYO_ environment variables, e.g. FOLLYO_DISPLAY_CURRENCY=EUR
+  3. the config file: $FOLLYO_CONFIG, or config.json in the data
+     directory ($FOLLYO_DATA_DIR or ~/.local/share/follyo)
+  4. defaults
+
+Environment variables override a setting for one run without changing the
+file. A ~/.follyo/config.yaml from older releases is imported into the
+config file the first time it is found.
+
+Other configuration, such as ticker mappings, goals, and notifications,
+has commands of its own or is edited in the file.`,
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List settings with their values",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		overrides := cfg.EnvOverrides()
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		for _, s := range settings {
+			value, isDefault := s.get(cfg)
+			if value == "" {
+				value = "(none)"
+			}
+			if _, ok := overrides[s.key]; ok {
+				value += " (" + config.EnvVar(s.key) + ")"
+			} else if isDefault {
+				value += " (default)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.key, value, s.help)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := findSetting(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		value, _ := s.get(cfg)
+		fmt.Fprintln(osStdout, value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Change a setting",
+	Long: `Change a setting, e.g.
+
+  follyo config set display_currency EUR
+  follyo config set price_cache_ttl 10m
+  follyo config set auto_snapshot true`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[1] == "" {
+			return usageErrorf("empty value; use 'follyo config unset %s' to restore the default", args[0])
+		}
+		return updateSetting(args[0], args[1])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Restore the default value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateSetting(args[0], "")
+	},
+}
+
+// updateSetting sets a setting, or restores its default when value is "",
+// and prints the new value
+func updateSetting(key, value string) error {
+	s, err := findSetting(key)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := s.set(cfg, value); err != nil {
+		if exitCode(err) == 1 {
+			return usageErrorf("%v", err)
+		}
+		return err
+	}
+	newValue, isDefault := s.get(cfg)
+	if isDefault {
+		newValue += " (default)"
+	}
+	fmt.Fprintf(osStdout, "%s = %s\n", s.key, newValue)
+	if value, ok := cfg.EnvOverrides()[s.key]; ok {
+		fmt.Fprintf(osStderr, "Note: %s=%s overrides this setting while it is set\n", config.EnvVar(s.key), value)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:     "simulate",
+	Aliases: []string{"sim"},
+	Short:   "Value the portfolio at hypothetical prices",
+	Long: `Recompute portfolio value, profit/loss, and allocation with some coins
+at hypothetical USD prices, compared with live prices. Coins without a
+--price keep their live price. Nothing is saved.
+
+Examples:
+  follyo simulate --price BTC=150000 --price ETH=10000
+  follyo simulate --price BTC=20000,ETH=1000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, _ := cmd.Flags().GetStringSlice("price")
+		overrides, err := parseCoinValues(values, "price", "PRICE")
+		if err != nil {
+			return err
+		}
+		if len(overrides) == 0 {
+			return usageErrorf("no prices given: use --price COIN=PRICE")
+		}
+		for coin, price := range overrides {
+			if price < 0 {
+				return usageErrorf("price for %s cannot be negative", coin)
+			}
+		}
+
+		pos, err := p.GetPositionsAt(models.Date{})
+		if err != nil {
+			return err
+		}
+		var fetch []string
+		for _, coin := range pos.Coins() {
+			if _, ok := overrides[coin]; !ok {
+				fetch = append(fetch, coin)
+			}
+		}
+		livePrices := make(map[string]float64)
+		if len(fetch) > 0 {
+			fmt.Fprintln(osStdout, "Fetching live prices...")
+			ps, err := newPriceService()
+			if err != nil {
+				return err
+			}
+			livePrices, err = ps.GetPrices(fetch)
+			if err != nil {
+				return ioError(fmt.Errorf("could not fetch prices: %w", err))
+			}
+		}
+		return printSimulation(pos, livePrices, overrides)
+	},
+}
+
+// printSimulation prints the portfolio valued at live prices with overrides
+// applied, compared with live prices alone. Overridden coins without a live
+// price are compared against a zero value.
+func printSimulation(pos portfolio.Positions, livePrices, overrides map[string]float64) error {
+	simulated := make(map[string]float64)
+	for coin, price := range livePrices {
+		simulated[coin] = price
+	}
+	var notHeld []string
+	for coin, price := range overrides {
+		simulated[coin] = price
+		if pos.HoldingsByCoin[coin].IsZero() && pos.LoansByCoin[coin].IsZero() {
+			notHeld = append(notHeld, coin)
+		}
+	}
+	if len(notHeld) > 0 {
+		sortStrings(notHeld)
+		fmt.Fprintf(osStderr, "Warning: %s not in the portfolio, price ignored\n", strings.Join(notHeld, ", "))
+	}
+
+	current, err := p.CaptureSnapshot(models.Date{}, livePrices, nil)
+	if err != nil {
+		return err
+	}
+	whatIf, err := p.CaptureSnapshot(models.Date{}, simulated, nil)
+	if err != nil {
+		return err
+	}
+	// Same timestamp, so the live valuation stays the baseline
+	whatIf.Timestamp = current.Timestamp
+	diff := portfolio.CompareSnapshots(current, whatIf)
+
+	fmt.Fprintln(osStdout, "\n=== SIMULATION ===")
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Coin\tAmount\tLive Price\tPrice\tChange\tValue\tChange")
+	for _, cd := range diff.Coins {
+		livePrice, price, priceChange := "-", "-", "-"
+		if cd.From.PriceUSD != 0 {
+			livePrice = formatUSD(cd.From.PriceUSD)
+		}
+		if cd.To.PriceUSD != 0 {
+			price = formatUSD(cd.To.PriceUSD)
+		}
+		if cd.PriceChange != 0 {
+			priceChange = colorByValue(fmt.Sprintf("%+.1f%%", cd.PriceChangePercent()), cd.PriceChange)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			cd.Coin, formatAmount(cd.To.Amount), livePrice, price, priceChange,
+			formatUSD(cd.To.ValueUSD), colorByValue(formatSignedUSD(cd.ValueChange), cd.ValueChange))
+	}
+	w.Flush()
+
+	allocation := portfolio.CalculateAllocation(pos.HoldingsByCoin, simulated)
+	if len(allocation) > 0 {
+		fmt.Fprintln(osStdout, "\nALLOCATION:")
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		for _, a := range allocation {
+			fmt.Fprintf(w, "  %s\t%5.1f%%\t%s\t%s\n",
+				a.Coin+":", a.Percent, renderBar(a.Percent, barWidth()), formatUSD(a.ValueUSD))
+		}
+		w.Flush()
+	}
+
+	unrealizedChange := models.Sub(whatIf.UnrealizedPL, current.UnrealizedPL)
+	fmt.Fprintln(osStdout, "\n"+separator())
+	fmt.Fprintf(osStdout, "Holdings Value: %s (%s)\n", formatUSD(whatIf.HoldingsValue),
+		colorByValue(formatSignedUSD(diff.HoldingsValueChange), diff.HoldingsValueChange))
+	fmt.Fprintf(osStdout, "Loans Value:    %s (%s)\n", formatUSD(whatIf.LoansValue),
+		colorByValue(formatSignedUSD(diff.LoansValueChange), -diff.LoansValueChange))
+	netText := fmt.Sprintf("%s, %+.1f%%", formatSignedUSD(diff.NetValueChange), diff.NetValueChangePercent())
+	fmt.Fprintf(osStdout, "Net Value:      %s (%s)\n", formatUSD(whatIf.NetValue),
+		colorByValue(netText, diff.NetValueChange))
+	plText := fmt.Sprintf("%s (%.1f%%)", formatSignedUSD(whatIf.ProfitLoss), whatIf.ProfitLossPercent)
+	fmt.Fprintf(osStdout, "Profit/Loss:    %s (%s)\n", colorByValue(plText, whatIf.ProfitLoss),
+		colorByValue(formatSignedUSD(diff.ProfitLossChange), diff.ProfitLossChange))
+	fmt.Fprintf(osStdout, "  Unrealized:   %s (%s)\n", colorByValue(formatSignedUSD(whatIf.UnrealizedPL), whatIf.UnrealizedPL),
+		colorByValue(formatSignedUSD(unrealizedChange), unrealizedChange))
+	return nil
+}
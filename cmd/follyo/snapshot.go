@@ -0,0 +1,877 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guptarohit/asciigraph"
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/pretty-andrechal/follyo/internal/state"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snap"},
+	Short:   "Manage portfolio value snapshots",
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save a snapshot of the current portfolio value",
+	Long: `Save a snapshot of the portfolio value using live prices.
+
+Use --date to backfill a snapshot for a past date. Only records dated on
+or before that day are included, and they are valued at CoinGecko's
+historical price for that date.
+
+Use --price to value coins at prices of your own instead, e.g. offline or
+for OTC valuations:
+
+  follyo snapshot save --price BTC=97000 --price ETH=3400
+
+Coins given a price are not fetched, so a snapshot with a price for every
+held coin needs no network. Price alerts are not checked against prices
+given by hand.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		date, _ := cmd.Flags().GetString("date")
+		note, _ := cmd.Flags().GetString("note")
+		values, _ := cmd.Flags().GetStringSlice("price")
+		manual, err := parseCoinValues(values, "price", "PRICE")
+		if err != nil {
+			return err
+		}
+		for coin, price := range manual {
+			if price < 0 {
+				return usageErrorf("price for %s cannot be negative", coin)
+			}
+		}
+
+		snap, err := takeSnapshot(date, note, manual)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Saved snapshot %s: net value %s (ID: %s)\n",
+			formatSnapshotTime(snap.Timestamp), formatUSD(snap.NetValue), snap.ID)
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all snapshots",
+	Long: `List snapshots, oldest first. Use --range to list only those of the
+last 7d, 30d, 90d, or 1y.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		start, err := rangeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		all, err := listSnapshots()
+		if err != nil {
+			return err
+		}
+		snapshots := snapshotsSince(all, start)
+
+		if len(snapshots) == 0 {
+			fmt.Fprintln(osStdout, "No snapshots found.")
+			return nil
+		}
+
+		short := shortIDs(idsOf(all, func(s models.Snapshot) string { return s.ID }))
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDate\tNet Value\tProfit/Loss\tNote\tPinned")
+		for _, snap := range snapshots {
+			note := snap.Note
+			if note == "" {
+				note = "-"
+			}
+			// Pinned snapshots are starred, and bold when colors are on
+			pin := "-"
+			if snap.Pin != "" {
+				pin = colorize("* "+snap.Pin, colorBold)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				short(snap.ID), formatSnapshotTime(snap.Timestamp), formatUSD(snap.NetValue),
+				colorByValue(fmt.Sprintf("%s (%.1f%%)", formatUSD(snap.ProfitLoss), snap.ProfitLossPercent), snap.ProfitLoss),
+				note, pin)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var snapshotChartCmd = &cobra.Command{
+	Use:   "chart",
+	Short: "Chart net, holdings, and loans value across snapshots",
+	Long: `Chart the net value, holdings value, and loans value of every snapshot
+over time, with the risk statistics of 'follyo stats'. Use --range to chart only the snapshots of the last 7d, 30d,
+90d, or 1y.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		start, err := rangeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		snapshots, err := listSnapshots()
+		if err != nil {
+			return err
+		}
+		snapshots = snapshotsSince(snapshots, start)
+
+		if len(snapshots) < 2 {
+			fmt.Fprintln(osStdout, "At least two snapshots are needed for a chart. Take one with 'follyo snapshot save'.")
+			return nil
+		}
+
+		sampled := downsampleSnapshots(snapshots, chartWidth())
+		caption := fmt.Sprintf("Value (USD), %d snapshots from %s to %s", len(snapshots),
+			formatSnapshotTime(snapshots[0].Timestamp), formatSnapshotTime(snapshots[len(snapshots)-1].Timestamp))
+		fmt.Fprintln(osStdout, renderChartSeries([]chartSeries{
+			{"Net", asciigraph.Default, netValueSeries(sampled)},
+			{"Holdings", asciigraph.Green, valueSeries(sampled, func(s models.Snapshot) float64 { return s.HoldingsValue })},
+			{"Loans", asciigraph.Red, valueSeries(sampled, func(s models.Snapshot) float64 { return s.LoansValue })},
+		}, caption))
+
+		fmt.Fprintln(osStdout)
+		printSnapshotStats(portfolio.GetSnapshotStats(snapshots))
+		return nil
+	},
+}
+
+var snapshotShowCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Show a snapshot's details",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		id, err := resolveSnapshotID(ss, args[0])
+		if err != nil {
+			return err
+		}
+		snap, found, err := ss.Get(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+
+		fmt.Fprintf(osStdout, "Snapshot %s (%s)\n", snap.ID, formatSnapshotTime(snap.Timestamp))
+		if snap.Pin != "" {
+			fmt.Fprintf(osStdout, "Pinned as: %s\n", snap.Pin)
+		}
+		if snap.Note != "" {
+			fmt.Fprintf(osStdout, "Note: %s\n", snap.Note)
+		}
+		fmt.Fprintln(osStdout)
+
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		var remapped []string
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Coin\tAmount\tPrice\tValue\tCoinGecko ID")
+		for _, coin := range sortedCoinKeys(snap) {
+			cv := snap.CoinValues[coin]
+			geckoID := cv.GeckoID
+			switch {
+			case cv.Manual:
+				geckoID = "(price given by hand)"
+			case geckoID == "":
+				geckoID = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				coin, formatAmount(cv.Amount), formatUSD(cv.PriceUSD), formatUSD(cv.ValueUSD), geckoID)
+			if current := ps.GetCoinGeckoID(coin); cv.GeckoID != "" && current != cv.GeckoID {
+				remapped = append(remapped, fmt.Sprintf("%s (was %s, now %s)", coin, cv.GeckoID, current))
+			}
+		}
+		w.Flush()
+
+		fmt.Fprintln(osStdout, "\n"+separator())
+		fmt.Fprintf(osStdout, "Holdings Value: %s\n", formatUSD(snap.HoldingsValue))
+		fmt.Fprintf(osStdout, "Loans Value:    %s\n", formatUSD(snap.LoansValue))
+		if snap.InterestValue != 0 {
+			fmt.Fprintf(osStdout, "  incl. Interest: %s\n", formatUSD(snap.InterestValue))
+		}
+		fmt.Fprintf(osStdout, "Net Value:      %s\n", formatUSD(snap.NetValue))
+		fmt.Fprintf(osStdout, "Total Invested: %s\n", formatUSD(snap.TotalInvested))
+		fmt.Fprintf(osStdout, "Total Sold:     %s\n", formatUSD(snap.TotalSold))
+		plText := fmt.Sprintf("%s (%.1f%%)", formatUSD(snap.ProfitLoss), snap.ProfitLossPercent)
+		fmt.Fprintf(osStdout, "Profit/Loss:    %s\n", colorByValue(plText, snap.ProfitLoss))
+		// Snapshots taken before the split was recorded have neither
+		if snap.RealizedPL != 0 || snap.UnrealizedPL != 0 {
+			fmt.Fprintf(osStdout, "  Realized:     %s\n", colorByValue(formatSignedUSD(snap.RealizedPL), snap.RealizedPL))
+			fmt.Fprintf(osStdout, "  Unrealized:   %s\n", colorByValue(formatSignedUSD(snap.UnrealizedPL), snap.UnrealizedPL))
+		}
+
+		for _, r := range remapped {
+			fmt.Fprintf(osStdout, "\nWarning: ticker mapping changed since this snapshot: %s\n", r)
+		}
+		return nil
+	},
+}
+
+var snapshotCompareCmd = &cobra.Command{
+	Use:   "compare ID ID",
+	Short: "Compare two snapshots",
+	Long: `Compare two snapshots, showing the change in portfolio value and each
+coin's amount, price, and value. The earlier snapshot is the baseline
+regardless of argument order.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		var snaps [2]models.Snapshot
+		for i, arg := range args {
+			id, err := resolveSnapshotID(ss, arg)
+			if err != nil {
+				return err
+			}
+			snap, found, err := ss.Get(id)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return notFoundErrorf("snapshot %s not found", id)
+			}
+			snaps[i] = snap
+		}
+
+		diff := portfolio.CompareSnapshots(snaps[0], snaps[1])
+		fmt.Fprintf(osStdout, "Snapshot %s (%s) -> %s (%s)\n\n",
+			diff.From.ID, formatSnapshotTime(diff.From.Timestamp), diff.To.ID, formatSnapshotTime(diff.To.Timestamp))
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Coin\tAmount\tChange\tPrice\tChange\tValue\tChange")
+		for _, cd := range diff.Coins {
+			price := "-"
+			priceChange := "-"
+			if cd.To.PriceUSD != 0 {
+				price = formatUSD(cd.To.PriceUSD)
+			}
+			if cd.PriceChange != 0 {
+				priceChange = colorByValue(fmt.Sprintf("%+.1f%%", cd.PriceChangePercent()), cd.PriceChange)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				cd.Coin, formatAmount(cd.To.Amount), formatAmountChange(cd.AmountChange),
+				price, priceChange,
+				formatUSD(cd.To.ValueUSD), colorByValue(formatSignedUSD(cd.ValueChange), cd.ValueChange))
+		}
+		w.Flush()
+
+		fmt.Fprintln(osStdout, "\n"+separator())
+		fmt.Fprintf(osStdout, "Holdings Value: %s (%s)\n", formatUSD(diff.To.HoldingsValue),
+			colorByValue(formatSignedUSD(diff.HoldingsValueChange), diff.HoldingsValueChange))
+		fmt.Fprintf(osStdout, "Loans Value:    %s (%s)\n", formatUSD(diff.To.LoansValue),
+			colorByValue(formatSignedUSD(diff.LoansValueChange), -diff.LoansValueChange))
+		netText := fmt.Sprintf("%s, %+.1f%%", formatSignedUSD(diff.NetValueChange), diff.NetValueChangePercent())
+		fmt.Fprintf(osStdout, "Net Value:      %s (%s)\n", formatUSD(diff.To.NetValue),
+			colorByValue(netText, diff.NetValueChange))
+		fmt.Fprintf(osStdout, "Profit/Loss:    %s (%s)\n", formatUSD(diff.To.ProfitLoss),
+			colorByValue(formatSignedUSD(diff.ProfitLossChange), diff.ProfitLossChange))
+		return nil
+	},
+}
+
+// formatAmountChange formats a coin amount change with an explicit sign, or "-" when unchanged
+func formatAmountChange(change float64) string {
+	switch {
+	case change > 0:
+		return "+" + formatAmount(change)
+	case change < 0:
+		return "-" + formatAmount(-change)
+	}
+	return "-"
+}
+
+var snapshotRemoveCmd = &cobra.Command{
+	Use:   "remove ID",
+	Short: "Remove a snapshot by ID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		id, err := resolveSnapshotID(ss, args[0])
+		if err != nil {
+			return err
+		}
+		if snap, _, err := ss.Get(id); err != nil {
+			return err
+		} else if snap.Pin != "" {
+			return usageErrorf("snapshot %s is pinned as %q; unpin it first with 'follyo snapshot unpin %s'", id, snap.Pin, snap.Pin)
+		}
+		removed, err := ss.Remove(id)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+		fmt.Printf("Removed snapshot %s\n", id)
+		return nil
+	},
+}
+
+var snapshotNoteCmd = &cobra.Command{
+	Use:   "note ID TEXT",
+	Short: "Set or change a snapshot's note",
+	Long: `Set the note of a snapshot, replacing any it has, e.g.
+'follyo snapshot note 3f2a "after the halving"'. An empty TEXT ("") clears
+the note.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		id, err := resolveSnapshotID(ss, args[0])
+		if err != nil {
+			return err
+		}
+		snap, found, err := ss.Get(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+
+		snap.Note = strings.TrimSpace(args[1])
+		updated, err := ss.Update(snap)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+		if snap.Note == "" {
+			fmt.Fprintf(osStdout, "Cleared the note of snapshot %s\n", id)
+		} else {
+			fmt.Fprintf(osStdout, "Set the note of snapshot %s to %q\n", id, snap.Note)
+		}
+		return nil
+	},
+}
+
+var snapshotPinCmd = &cobra.Command{
+	Use:   "pin ID NAME",
+	Short: "Pin a snapshot under a name",
+	Long: `Pin an important snapshot under a name, e.g.
+'follyo snapshot pin 3f2a pre-halving'. Pinned snapshots are marked in
+'follyo snapshot list' and can't be removed until unpinned. The name can be
+given instead of the ID to any snapshot command, e.g.
+'follyo snapshot compare pre-halving 9c1d', and to 'follyo dashboard
+--baseline' to compare the net value with it. Pinning a pinned snapshot
+renames it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimSpace(args[1])
+		if name == "" {
+			return usageErrorf("pin name cannot be empty")
+		}
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		id, err := resolveSnapshotID(ss, args[0])
+		if err != nil {
+			return err
+		}
+		snapshots, err := ss.List()
+		if err != nil {
+			return err
+		}
+		if other, ok := pinnedSnapshot(snapshots, name); ok && other.ID != id {
+			return usageErrorf("snapshot %s is already pinned as %q", other.ID, other.Pin)
+		}
+		snap, found, err := ss.Get(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+
+		snap.Pin = name
+		if _, err := ss.Update(snap); err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Pinned snapshot %s (%s) as %q\n", id, formatSnapshotTime(snap.Timestamp), name)
+		return nil
+	},
+}
+
+var snapshotUnpinCmd = &cobra.Command{
+	Use:   "unpin ID|NAME",
+	Short: "Unpin a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		id, err := resolveSnapshotID(ss, args[0])
+		if err != nil {
+			return err
+		}
+		snap, found, err := ss.Get(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return notFoundErrorf("snapshot %s not found", id)
+		}
+		if snap.Pin == "" {
+			return usageErrorf("snapshot %s is not pinned", id)
+		}
+
+		name := snap.Pin
+		snap.Pin = ""
+		if _, err := ss.Update(snap); err != nil {
+			return err
+		}
+		fmt.Fprintf(osStdout, "Unpinned snapshot %s (was %q)\n", id, name)
+		return nil
+	},
+}
+
+var snapshotExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Export all snapshots to an archive",
+	Long: `Export all snapshots to a single archive, e.g. to move the value history
+to another machine. The archive uses the format of 'follyo state export'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpDir, err := os.MkdirTemp("", "follyo-snapshots")
+		if err != nil {
+			return ioError(err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		snapPath := filepath.Join(tmpDir, "snapshots.json")
+		count, err := exportSnapshots(snapPath)
+		if err != nil {
+			return err
+		}
+		entry := state.Entry{Name: "snapshots.json", Path: snapPath, SchemaVersion: storage.SnapshotSchemaVersion}
+		if _, err := state.Export(args[0], []state.Entry{entry}); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Exported %d snapshots to %s\n", count, args[0])
+		return nil
+	},
+}
+
+var snapshotImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import snapshots from an archive",
+	Long: `Import the snapshots in an archive created by 'follyo snapshot export',
+'follyo backup', or 'follyo state export'. Snapshots are added to the
+existing ones; those already present (by ID) are skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, contents, err := state.Read(args[0])
+		if err != nil {
+			return ioError(err)
+		}
+		info, ok := manifest.File("snapshots.json")
+		if !ok {
+			return notFoundErrorf("%s contains no snapshots", args[0])
+		}
+		if info.SchemaVersion > storage.SnapshotSchemaVersion {
+			return ioError(fmt.Errorf("snapshots.json has schema version %d, newer than supported version %d",
+				info.SchemaVersion, storage.SnapshotSchemaVersion))
+		}
+
+		// Read through a snapshot store so older formats are migrated
+		tmpDir, err := os.MkdirTemp("", "follyo-snapshots")
+		if err != nil {
+			return ioError(err)
+		}
+		defer os.RemoveAll(tmpDir)
+		snapPath := filepath.Join(tmpDir, "snapshots.json")
+		if err := os.WriteFile(snapPath, contents["snapshots.json"], 0644); err != nil {
+			return ioError(err)
+		}
+		src, err := storage.NewSnapshotStore(snapPath)
+		if err != nil {
+			return ioError(err)
+		}
+		imported, err := src.List()
+		if err != nil {
+			return ioError(fmt.Errorf("reading snapshots: %w", err))
+		}
+
+		ss, err := loadSnapshotStore()
+		if err != nil {
+			return err
+		}
+		var added, skipped int
+		for _, snap := range imported {
+			_, found, err := ss.Get(snap.ID)
+			if err != nil {
+				return err
+			}
+			if found {
+				skipped++
+				continue
+			}
+			if err := ss.Add(snap); err != nil {
+				return err
+			}
+			added++
+		}
+		fmt.Fprintf(osStdout, "Imported %d snapshots (%d already present)\n", added, skipped)
+		return nil
+	},
+}
+
+// exportSnapshots writes every snapshot to a new JSON snapshot file at path
+// and returns how many were written
+func exportSnapshots(path string) (int, error) {
+	snaps, err := listSnapshots()
+	if err != nil {
+		return 0, err
+	}
+	// Written even when empty, so the export always holds the file
+	data, err := json.MarshalIndent(storage.SnapshotData{
+		Version:   storage.SnapshotSchemaVersion,
+		Snapshots: append([]models.Snapshot{}, snaps...),
+	}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, ioError(err)
+	}
+	return len(snaps), nil
+}
+
+// takeSnapshot values the portfolio and saves a snapshot. An empty date uses
+// live prices; otherwise records up to that date (YYYY-MM-DD) are valued at
+// historical prices. Coins in manual are valued at those USD prices instead
+// of fetched ones.
+func takeSnapshot(date, note string, manual map[string]float64) (models.Snapshot, error) {
+	day, err := parseDate(date, "date")
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	if day.After(models.Today()) {
+		return models.Snapshot{}, usageErrorf("date %s is in the future", day)
+	}
+
+	positions, err := p.GetPositionsAt(day)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	// Coins priced by hand are not fetched
+	coins := positions.Coins()
+	var fetch []string
+	for _, coin := range coins {
+		if _, ok := manual[coin]; !ok {
+			fetch = append(fetch, coin)
+		}
+	}
+	ps, err := newPriceService()
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	geckoIDs := make(map[string]string)
+	for _, coin := range fetch {
+		geckoIDs[coin] = ps.GetCoinGeckoID(coin)
+	}
+
+	livePrices := make(map[string]float64)
+	if len(fetch) > 0 {
+		var fetched map[string]float64
+		if !day.IsZero() {
+			fmt.Fprintf(osStdout, "Fetching prices for %s...\n", day)
+			fetched, err = ps.GetHistoricalPrices(day.Time, fetch)
+		} else {
+			fmt.Fprintln(osStdout, "Fetching live prices...")
+			fetched, err = ps.GetPrices(fetch)
+		}
+		if err != nil {
+			if len(manual) > 0 {
+				err = fmt.Errorf("%w (give a --price for %s to save without fetching)", err, strings.Join(fetch, ", "))
+			}
+			return models.Snapshot{}, ioError(fmt.Errorf("could not fetch prices: %w", err))
+		}
+		maps.Copy(livePrices, fetched)
+	}
+	var notHeld []string
+	for _, coin := range sortedStringKeys(manual) {
+		if slices.Contains(coins, coin) {
+			livePrices[coin] = manual[coin]
+		} else {
+			notHeld = append(notHeld, coin)
+		}
+	}
+	if len(notHeld) > 0 {
+		fmt.Fprintf(osStderr, "Warning: %s not in the portfolio, price ignored\n", strings.Join(notHeld, ", "))
+	}
+	for _, coin := range coins {
+		if _, ok := livePrices[coin]; !ok {
+			fmt.Fprintf(osStderr, "Warning: no price for %s, valued at $0.00\n", coin)
+		}
+	}
+
+	snap, err := p.CaptureSnapshot(day, livePrices, geckoIDs)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	if !day.IsZero() {
+		snap.Timestamp = day.Time
+	}
+	snap.Note = note
+	for coin, cv := range snap.CoinValues {
+		if _, ok := manual[coin]; ok {
+			cv.Manual = true
+			snap.CoinValues[coin] = cv
+		}
+	}
+
+	ss, err := loadSnapshotStore()
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	if err := ss.Add(snap); err != nil {
+		return models.Snapshot{}, err
+	}
+	// Alerts are for market prices, not those given by hand
+	if day.IsZero() && len(manual) == 0 {
+		notifySnapshot(snap, ps, livePrices)
+	}
+	return snap, nil
+}
+
+// valuePortfolio values the current portfolio at live prices without saving
+// a snapshot or printing progress. It also returns the positions valued.
+func valuePortfolio() (models.Snapshot, portfolio.Positions, error) {
+	snap, positions, _, err := valuePortfolioAllowStale(false)
+	return snap, positions, err
+}
+
+// valuePortfolioAllowStale is valuePortfolio, falling back to the last known
+// prices when allowStale is set and fetching fails. stale is when the oldest
+// fallback price was fetched, and zero for live prices.
+func valuePortfolioAllowStale(allowStale bool) (snap models.Snapshot, positions portfolio.Positions, stale time.Time, err error) {
+	positions, err = p.GetPositionsAt(models.Date{})
+	if err != nil {
+		return models.Snapshot{}, portfolio.Positions{}, time.Time{}, err
+	}
+	coins := positions.Coins()
+	ps, err := newPriceService()
+	if err != nil {
+		return models.Snapshot{}, portfolio.Positions{}, time.Time{}, err
+	}
+	geckoIDs := make(map[string]string)
+	for _, coin := range coins {
+		geckoIDs[coin] = ps.GetCoinGeckoID(coin)
+	}
+	livePrices := make(map[string]float64)
+	if len(coins) > 0 {
+		if allowStale {
+			livePrices, stale, err = getPricesOrStale(ps, coins)
+		} else {
+			livePrices, err = ps.GetPrices(coins)
+		}
+		if err != nil {
+			return models.Snapshot{}, portfolio.Positions{}, time.Time{}, ioError(fmt.Errorf("could not fetch prices: %w", err))
+		}
+	}
+
+	snap, err = p.CaptureSnapshot(models.Date{}, livePrices, geckoIDs)
+	if err != nil {
+		return models.Snapshot{}, portfolio.Positions{}, time.Time{}, err
+	}
+	return snap, positions, stale, nil
+}
+
+// autoSnapshot saves the day's first snapshot when "auto_snapshot" is
+// enabled in the config. Price-fetching commands call it after they succeed;
+// failures are reported as warnings since the command itself already worked.
+func autoSnapshot() {
+	cfg, err := loadConfig()
+	if err != nil || !cfg.GetAutoSnapshot() {
+		return
+	}
+	snap, saved, err := saveDailySnapshot(time.Now())
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: Could not save daily snapshot: %v\n", err)
+		return
+	}
+	if saved {
+		fmt.Fprintf(osStdout, "Saved daily snapshot: net value %s (ID: %s)\n", formatUSD(snap.NetValue), snap.ID)
+	}
+}
+
+// saveDailySnapshot saves a snapshot with live prices unless one already
+// exists for now's local date
+func saveDailySnapshot(now time.Time) (models.Snapshot, bool, error) {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return models.Snapshot{}, false, err
+	}
+	today := now.Local().Format("2006-01-02")
+	for _, snap := range snapshots {
+		if snap.Timestamp.Local().Format("2006-01-02") == today {
+			return models.Snapshot{}, false, nil
+		}
+	}
+
+	snap, err := takeSnapshot("", "daily", nil)
+	if err != nil {
+		return models.Snapshot{}, false, err
+	}
+	return snap, true, nil
+}
+
+// snapshotsPath returns the path of the snapshots file, next to the portfolio data
+func snapshotsPath() string {
+	return filepath.Join(filepath.Dir(dataPath), "snapshots.json")
+}
+
+// loadSnapshotStore opens the snapshot store for the configured storage backend
+func loadSnapshotStore() (storage.SnapshotBackend, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var ss storage.SnapshotBackend
+	if cfg.GetStorage() == "sqlite" {
+		ss, err = storage.NewSQLiteSnapshotStore(sqlitePath())
+	} else {
+		ss, err = storage.NewSnapshotStore(snapshotsPath())
+	}
+	if err != nil {
+		return nil, ioError(fmt.Errorf("loading snapshots: %w", err))
+	}
+	return ss, nil
+}
+
+// listSnapshots returns all saved snapshots
+func listSnapshots() ([]models.Snapshot, error) {
+	ss, err := loadSnapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	return ss.List()
+}
+
+// resolveSnapshotID returns the ID of the snapshot in ss pinned under the
+// name id, or else that id is or uniquely begins
+func resolveSnapshotID(ss storage.SnapshotBackend, id string) (string, error) {
+	snapshots, err := ss.List()
+	if err != nil {
+		return "", err
+	}
+	snap, err := findSnapshot(snapshots, id)
+	return snap.ID, err
+}
+
+// pinnedSnapshot returns the snapshot pinned under name, which is matched
+// without case
+func pinnedSnapshot(snapshots []models.Snapshot, name string) (models.Snapshot, bool) {
+	name = strings.TrimSpace(name)
+	for _, snap := range snapshots {
+		if snap.Pin != "" && strings.EqualFold(snap.Pin, name) {
+			return snap, true
+		}
+	}
+	return models.Snapshot{}, false
+}
+
+// findSnapshot returns the snapshot pinned under ref, or else that ref is
+// the ID of or uniquely begins
+func findSnapshot(snapshots []models.Snapshot, ref string) (models.Snapshot, error) {
+	if snap, ok := pinnedSnapshot(snapshots, ref); ok {
+		return snap, nil
+	}
+	id, err := resolveID("snapshot", ref, idsOf(snapshots, func(s models.Snapshot) string { return s.ID }))
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	return snapshots[slices.IndexFunc(snapshots, func(s models.Snapshot) bool { return s.ID == id })], nil
+}
+
+// baselineSnapshot returns the snapshot profit/loss is shown relative to:
+// the one given to --baseline by ID or pinned name, else "baseline_snapshot"
+// in config.json, or nil for profit/loss since the first purchase. A
+// baseline of "none" turns the configured one off.
+func baselineSnapshot(cmd *cobra.Command) (*models.Snapshot, error) {
+	name, _ := cmd.Flags().GetString("baseline")
+	configured := name == ""
+	if configured {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		name = cfg.GetBaselineSnapshot()
+	}
+	if name == "" || strings.EqualFold(name, "none") {
+		return nil, nil
+	}
+
+	snapshots, err := listSnapshots()
+	if err == nil {
+		var snap models.Snapshot
+		if snap, err = findSnapshot(snapshots, name); err == nil {
+			return &snap, nil
+		}
+	}
+	if !configured {
+		return nil, err
+	}
+	// A removed or unpinned baseline should not stop the views
+	fmt.Fprintf(osStderr, "Warning: baseline snapshot %s is unavailable (%v); showing P/L since the first purchase\n", name, err)
+	return nil, nil
+}
+
+// snapshotLabel names a snapshot by its time, and pinned name if any
+func snapshotLabel(snap models.Snapshot) string {
+	if snap.Pin == "" {
+		return formatSnapshotTime(snap.Timestamp)
+	}
+	return fmt.Sprintf("%s (%s)", snap.Pin, formatSnapshotTime(snap.Timestamp))
+}
+
+// formatSnapshotTime formats a snapshot timestamp for display.
+// Backfilled snapshots at midnight UTC are shown as a plain date.
+func formatSnapshotTime(t time.Time) string {
+	if t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// sortedCoinKeys returns the snapshot's coins in sorted order
+func sortedCoinKeys(snap models.Snapshot) []string {
+	coins := make([]string, 0, len(snap.CoinValues))
+	for coin := range snap.CoinValues {
+		coins = append(coins, coin)
+	}
+	sortStrings(coins)
+	return coins
+}
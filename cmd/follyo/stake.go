@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
-	"text/tabwriter"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/spf13/cobra"
 )
 
@@ -14,20 +14,27 @@ var stakeCmd = &cobra.Command{
 }
 
 var stakeAddCmd = &cobra.Command{
-	Use:   "add COIN AMOUNT PLATFORM",
+	Use:   "add COIN AMOUNT [PLATFORM]",
 	Short: "Stake crypto on a platform",
 	Long: `Stake crypto on a platform.
 
 COIN: The cryptocurrency symbol (e.g., ETH, SOL)
 AMOUNT: Amount to stake
-PLATFORM: Platform where staking (e.g., Lido, Coinbase)
+PLATFORM: Platform where staking (e.g., Lido, Coinbase); optional when
+"default_platform" is set in the config
 
 Note: You can only stake coins you own (holdings - sales - already staked).`,
-	Args: cobra.ExactArgs(3),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		coin := args[0]
-		amount := parseFloat(args[1], "amount")
-		platform := args[2]
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
+		platform, err := platformArg(args, 2)
+		if err != nil {
+			return err
+		}
 
 		apy, _ := cmd.Flags().GetFloat64("apy")
 		var apyPtr *float64
@@ -35,62 +42,111 @@ Note: You can only stake coins you own (holdings - sales - already staked).`,
 			apyPtr = &apy
 		}
 		notes, _ := cmd.Flags().GetString("notes")
-		date, _ := cmd.Flags().GetString("date")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
 
-		stake, err := p.AddStake(coin, amount, platform, apyPtr, notes, date)
+		stake, err := p.AddStake(coin, amount, platform, apyPtr, notes, date, tags...)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
 		fmt.Printf("Staked %v %s on %s (ID: %s)\n", stake.Amount, stake.Coin, stake.Platform, stake.ID)
+		return nil
 	},
 }
 
 var stakeListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all staked crypto",
-	Run: func(cmd *cobra.Command, args []string) {
-		stakes, err := p.ListStakes()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := listOptionsFromFlags(cmd)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
+		}
+		stakes, err := p.ListStakesFiltered(opts)
+		if err != nil {
+			return err
 		}
 
 		if len(stakes) == 0 {
 			fmt.Fprintln(osStdout, "No stakes found.")
-			return
+			return nil
 		}
 
-		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCoin\tAmount\tPlatform\tAPY\tDate")
-		for _, st := range stakes {
-			apy := "-"
-			if st.APY != nil {
-				apy = fmt.Sprintf("%.1f%%", *st.APY)
-			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-				st.ID, st.Coin, formatAmount(st.Amount),
-				st.Platform, apy, st.Date)
+		total := len(stakes)
+		page, limit := pageFromFlags(cmd)
+		stakes, pageInfo := paginate(stakes, page, limit)
+
+		all, err := p.ListStakes()
+		if err != nil {
+			return err
 		}
-		w.Flush()
+		short := shortIDs(idsOf(all, func(st models.Stake) string { return st.ID }))
+		if err := printTable(cmd, "stake", stakeColumns(short), stakes, &opts); err != nil {
+			return err
+		}
+		printListFooter(opts, total, "stake", pageInfo)
+		return nil
 	},
 }
 
 var stakeRemoveCmd = &cobra.Command{
-	Use:   "remove ID",
-	Short: "Remove a stake by ID (unstake)",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		id := args[0]
-		removed, err := p.RemoveStake(id)
+	Use:   "remove [ID...]",
+	Short: "Remove stakes by ID (unstake) or filter",
+	Long: `Remove (unstake) one or more stakes.
+
+Instead of IDs, select the stakes to remove with --coin, --platform, --tag,
+--since, --until, and --before, e.g. to clean up a bad import:
+
+  follyo stake remove --coin TEST --platform Demo
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeMatching(cmd, args, "stake", p.ListStakesFiltered, func(st models.Stake) (string, string) {
+			return st.ID, fmt.Sprintf("%s %s staked %s%s", formatAmount(st.Amount.InexactFloat64()), st.Coin, st.Date, onPlatform(st.Platform))
+		}, p.RemoveStake, func(id string) string {
+			return fmt.Sprintf("Removed stake %[1]s (unstaked; restore with 'follyo trash restore %[1]s')", id)
+		})
+	},
+}
+
+var stakeReduceCmd = &cobra.Command{
+	Use:   "reduce ID AMOUNT",
+	Short: "Unstake part of a stake",
+	Long: `Unstake part of a stake, keeping its original date and APY.
+
+ID: The stake ID (see 'stake list')
+AMOUNT: Amount to unstake
+
+Reducing by the full amount removes the stake.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stakes, err := p.ListStakes()
+		if err != nil {
+			return err
+		}
+		id, err := resolveID("stake", args[0], idsOf(stakes, func(st models.Stake) string { return st.ID }))
+		if err != nil {
+			return err
+		}
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
+
+		stake, err := p.ReduceStake(id, amount)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
-		if removed {
-			fmt.Printf("Removed stake %s (unstaked)\n", id)
+		if stake.Amount.IsZero() {
+			fmt.Printf("Unstaked %s %s and removed stake %s\n", formatAmount(amount.InexactFloat64()), stake.Coin, id)
 		} else {
-			fmt.Printf("Stake %s not found\n", id)
+			fmt.Printf("Unstaked %s %s from stake %s (%s remaining)\n", formatAmount(amount.InexactFloat64()), stake.Coin, id, formatAmount(stake.Amount.InexactFloat64()))
 		}
+		return nil
 	},
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show risk statistics of the net value across snapshots",
+	Long: `Show risk statistics of the portfolio's net value, taken from
+snapshots:
+
+  - best and worst day: the largest rise and fall in net value
+  - volatility: the standard deviation of the daily returns
+  - max drawdown: the largest fall in net value from a high to a later low
+
+A day's change is measured from the last snapshot of the day before
+with one, so days without snapshots are spread over the next day that
+has one. Net value changes with deposits and sales as well as prices.
+
+Use --range to use only the snapshots of the last 7d, 30d, 90d, or 1y.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		start, err := rangeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		snapshots, err := listSnapshots()
+		if err != nil {
+			return err
+		}
+		snapshots = snapshotsSince(snapshots, start)
+
+		if len(snapshots) < 2 {
+			fmt.Fprintln(osStdout, "At least two snapshots are needed for statistics. Take one with 'follyo snapshot save'.")
+			return nil
+		}
+		fmt.Fprintf(osStdout, "%d snapshots from %s to %s\n\n", len(snapshots),
+			formatSnapshotTime(snapshots[0].Timestamp), formatSnapshotTime(snapshots[len(snapshots)-1].Timestamp))
+		printSnapshotStats(portfolio.GetSnapshotStats(snapshots))
+		return nil
+	},
+}
+
+// printSnapshotStats prints the best and worst day, volatility, and max
+// drawdown of snapshot statistics
+func printSnapshotStats(stats portfolio.SnapshotStats) {
+	if stats.BestDay.Date.IsZero() {
+		fmt.Fprintln(osStdout, "Best Day:       N/A (snapshots on one day)")
+		fmt.Fprintln(osStdout, "Worst Day:      N/A")
+	} else {
+		fmt.Fprintf(osStdout, "Best Day:       %s %s\n", stats.BestDay.Date,
+			colorByValue(formatSignedUSD(stats.BestDay.Change), stats.BestDay.Change))
+		fmt.Fprintf(osStdout, "Worst Day:      %s %s\n", stats.WorstDay.Date,
+			colorByValue(formatSignedUSD(stats.WorstDay.Change), stats.WorstDay.Change))
+	}
+	if stats.ReturnDays < 2 {
+		fmt.Fprintln(osStdout, "Volatility:     N/A (fewer than 2 daily returns)")
+	} else {
+		fmt.Fprintf(osStdout, "Volatility:     %.2f%% daily, over %d days\n", stats.Volatility, stats.ReturnDays)
+	}
+	if stats.MaxDrawdown == 0 {
+		fmt.Fprintln(osStdout, "Max Drawdown:   none")
+	} else {
+		fmt.Fprintf(osStdout, "Max Drawdown:   %s (%.1f%%), %s to %s\n",
+			colorRedText(formatSignedUSD(-stats.MaxDrawdown)), -stats.MaxDrawdownPercent,
+			formatSnapshotTime(stats.DrawdownPeak.Timestamp), formatSnapshotTime(stats.DrawdownTrough.Timestamp))
+	}
+}
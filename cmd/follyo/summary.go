@@ -2,10 +2,17 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
 	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 )
 
@@ -15,21 +22,125 @@ var summaryCmd = &cobra.Command{
 	Short:   "Show portfolio summary",
 	Long: `Show portfolio summary with holdings, stakes, loans, and totals.
 
-Live prices are fetched by default from CoinGecko.
-Use --no-prices to disable price fetching.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		summary, err := p.GetSummary()
+Live prices are fetched by default from CoinGecko, along with each
+held coin's 24h and 7d price change and its name. Stablecoins trading
+more than "peg_threshold_percent" (0.5 by default) from $1 are flagged.
+Use --no-prices to disable price fetching. When prices can't be fetched,
+e.g. offline, the last known prices are used and labeled as stale.
+
+Each held coin shows its unrealized profit/loss, its value less the FIFO
+cost basis of the coins still held, and the average cost of those coins,
+green when the live price is above it and red when below, and its
+break-even price: the price at which it is worth what was put into it,
+purchases less sales with swaps at their USD value, and how far the live
+price is above or below it.
+
+With --auto-map, coins without a CoinGecko mapping are looked up on
+CoinGecko and mapped when exactly one coin has their ticker as its symbol.
+
+When at least two snapshots exist, a chart of net value over time is
+shown at the top. Use --range to chart only the last 7d, 30d, 90d, or 1y,
+and --no-chart to hide it.
+
+Goals set in config.json are shown with live prices: progress toward
+a target net value, and the trades that reach a target allocation, e.g.
+  "goals": {"target_value": 100000,
+            "target_allocation": {"BTC": 60, "ETH": 30, "SOL": 10}}
+
+Coins are listed by name; use --sort value or --sort pl to list those
+worth the most, or with the largest profit/loss, first, and --reverse to
+flip the order.
+
+Use --baseline, or set "baseline_snapshot" with 'follyo config set', to show
+profit/loss since a snapshot, given by ID or pinned name, instead of since
+the first purchase, e.g. to follow this year's performance from a snapshot
+pinned on January 1st. --baseline none turns a configured baseline off.
+
+Use --all to combine the default portfolio and all named portfolios.
+
+Use --since and --until to review a period, e.g. a quarter: record counts,
+invested, sold, and realized P/L then only include records in the range.
+Balances and values are still current, and total profit/loss is left out.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		untilFlag, _ := cmd.Flags().GetString("until")
+		since, err := parseDate(sinceFlag, "--since date")
+		if err != nil {
+			return err
+		}
+		until, err := parseDate(untilFlag, "--until date")
+		if err != nil {
+			return err
+		}
+		if !since.IsZero() && !until.IsZero() && since.After(until) {
+			return usageErrorf("--since %s is after --until %s", since, until)
+		}
+		scoped := !since.IsZero() || !until.IsZero()
+		chartStart, err := rangeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		sortBy, _ := cmd.Flags().GetString("sort")
+		if !slices.Contains(summarySorts, sortBy) {
+			return usageErrorf("invalid sort %q: use %s", sortBy, strings.Join(summarySorts, ", "))
+		}
+		reverse, _ := cmd.Flags().GetBool("reverse")
+
+		all, _ := cmd.Flags().GetBool("all")
+		baseline, err := baselineSnapshot(cmd)
+		if err != nil {
+			return err
+		}
+		// Snapshots are per portfolio and P/L is all-time, so a baseline
+		// only applies to the all-time summary of one portfolio
+		if baseline != nil && (all || scoped) {
+			if name, _ := cmd.Flags().GetString("baseline"); name != "" {
+				return usageErrorf("--baseline cannot be combined with --all, --since, or --until")
+			}
+			baseline = nil
+		}
+
+		var summary portfolio.Summary
+		var yield []portfolio.YieldEntry
+		var combined []string
+		if all {
+			summary, yield, combined, err = loadAllSummaries(since, until)
+		} else {
+			summary, err = p.GetSummaryBetween(since, until)
+			if err == nil {
+				yield, err = p.GetProjectedYield()
+			}
+		}
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return err
 		}
 
 		noPrices, _ := cmd.Flags().GetBool("no-prices")
 		showPrices := !noPrices
 
+		currency, _ := cmd.Flags().GetString("currency")
+		if currency == "" {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			currency = cfg.GetDisplayCurrency()
+		}
+		currency = strings.ToUpper(currency)
+		if !prices.IsSupportedCurrency(currency) {
+			return usageErrorf("unsupported currency %s (supported: %s)",
+				currency, strings.Join(prices.SupportedCurrencies, ", "))
+		}
+		// USD amounts (invested, sold) are multiplied by this rate for display
+		usdRate := 1.0
+		defer func() { displayCurrency = "USD" }()
+
 		// Fetch live prices unless disabled
 		var livePrices map[string]float64
-		var unmappedTickers []string
+		var staleSince time.Time // When fallback prices were fetched; zero for live prices
+		var market map[string]prices.MarketData
+		var metadata map[string]prices.CoinMetadata
+		var duplicateMappings map[string][]string
 		if showPrices {
 			// Collect all unique coins from all sections
 			allCoins := make(map[string]bool)
@@ -48,13 +159,19 @@ Use --no-prices to disable price fetching.`,
 
 			if len(allCoins) > 0 {
 				fmt.Fprintln(osStdout, "Fetching live prices...")
-				ps := prices.New()
-
-				// Load custom mappings
-				cfg := loadConfig()
-				customMappings := cfg.GetAllTickerMappings()
-				for ticker, geckoID := range customMappings {
-					ps.AddCoinMapping(ticker, geckoID)
+				ps, err := newPriceService()
+				if err != nil {
+					return err
+				}
+				if currency != "USD" {
+					rate, err := ps.GetExchangeRate(currency)
+					if err != nil {
+						fmt.Fprintf(osStderr, "Warning: Could not fetch %s exchange rate, showing USD: %v\n", currency, err)
+					} else {
+						ps.SetCurrency(currency)
+						usdRate = rate
+						displayCurrency = currency
+					}
 				}
 
 				// Convert to slice
@@ -64,28 +181,116 @@ Use --no-prices to disable price fetching.`,
 				}
 				sortStrings(coins)
 
-				// Check for unmapped tickers
-				unmappedTickers = ps.GetUnmappedTickers(coins)
+				if autoMap, _ := cmd.Flags().GetBool("auto-map"); autoMap {
+					if err := autoMapTickers(ps, coins); err != nil {
+						return err
+					}
+				}
+
+				// Check for tickers sharing a mapping
+				duplicateMappings = ps.GetDuplicateMappings(coins)
 
-				livePrices, err = ps.GetPrices(coins)
+				livePrices, staleSince, err = getPricesOrStale(ps, coins)
 				if err != nil {
 					fmt.Fprintf(osStderr, "Warning: Could not fetch prices: %v\n", err)
 					livePrices = nil
 				}
+
+				// Price changes are informational, so a failure only hides them
+				if livePrices != nil && staleSince.IsZero() && len(summary.HoldingsByCoin) > 0 {
+					market, err = ps.GetMarketData(sortedStringKeys(summary.HoldingsByCoin))
+					if err != nil {
+						fmt.Fprintf(osStderr, "Warning: Could not fetch price changes: %v\n", err)
+						market = nil
+					}
+					// Names come from a weekly cache; what is cached is shown even if refreshing fails
+					metadata, _ = ps.GetCoinMetadata(sortedStringKeys(summary.HoldingsByCoin))
+				}
 			}
 		}
 
 		fmt.Fprintln(osStdout, "\n=== PORTFOLIO SUMMARY ===")
+		if all {
+			fmt.Fprintf(osStdout, "Portfolios: %s\n", strings.Join(combined, ", "))
+		}
+		if scoped {
+			fmt.Fprintf(osStdout, "Period: %s (counts, invested, sold, and realized P/L)\n", periodLabel(since, until))
+		}
+		if !staleSince.IsZero() {
+			printStalePrices(staleSince)
+		}
+
+		// Net value history from snapshots, which are kept per portfolio
+		if noChart, _ := cmd.Flags().GetBool("no-chart"); !noChart && !all {
+			snapshots, err := listSnapshots()
+			snapshots = snapshotsSince(snapshots, chartStart)
+			if err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not load snapshots: %v\n", err)
+			} else if len(snapshots) >= 2 {
+				first, last := snapshots[0], snapshots[len(snapshots)-1]
+				caption := fmt.Sprintf("Net value (USD), %s to %s, %d snapshots",
+					formatSnapshotTime(first.Timestamp), formatSnapshotTime(last.Timestamp), len(snapshots))
+				fmt.Fprintln(osStdout, "\nNET VALUE HISTORY:")
+				fmt.Fprintln(osStdout, renderChart(netValueSeries(downsampleSnapshots(snapshots, chartWidth())), caption))
+			}
+		}
+
+		// Lot matching and break-even prices are per portfolio, so not
+		// across portfolios
+		var profitLoss portfolio.ProfitLoss
+		var breakEven map[string]portfolio.BreakEven
+		if !all {
+			if profitLoss, err = p.GetProfitLoss(models.Date{}); err != nil {
+				return err
+			}
+			if breakEven, err = p.GetBreakEven(); err != nil {
+				return err
+			}
+		}
+
+		// Coins are ordered by name, value, or the P/L of their holdings
+		coinPL := func(coin string) float64 {
+			value := models.AtPrice(summary.HoldingsByCoin[coin], livePrices[coin])
+			return models.Float(value.Sub(models.AtPrice(profitLoss.CostBasisByCoin[coin], usdRate)))
+		}
+		order := func(amounts map[string]decimal.Decimal) []string {
+			return sortSummaryCoins(amounts, sortBy, reverse, livePrices, coinPL)
+		}
+		sorted := summarySortLabel(sortBy, reverse)
 
 		// Holdings by coin (current holdings = purchases - sales)
-		fmt.Fprintln(osStdout, "\nHOLDINGS BY COIN:")
-		var totalCurrentValue float64
+		fmt.Fprintf(osStdout, "\nHOLDINGS BY COIN%s:\n", sorted)
+		var totalCurrentValue decimal.Decimal
 		if len(summary.HoldingsByCoin) > 0 {
 			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
-			for _, coin := range sortedKeys(summary.HoldingsByCoin) {
+			for _, coin := range order(summary.HoldingsByCoin) {
 				amount := summary.HoldingsByCoin[coin]
-				value := printCoinLine(w, coin, amount, livePrices, false)
-				totalCurrentValue += value
+				line, value := coinLine(coin, amount, livePrices, false)
+				price, hasPrice := livePrices[coin]
+				if basis := models.AtPrice(profitLoss.CostBasisByCoin[coin], usdRate); hasPrice && basis.IsPositive() {
+					line += "\t" + formatCoinPL(value, basis)
+				}
+				if avg := models.Float(models.AtPrice(profitLoss.AverageCostUSD(coin), usdRate)); avg > 0 {
+					avgText := "avg cost " + formatMoney(avg)
+					switch {
+					case hasPrice && price >= avg:
+						avgText = colorGreenText(avgText)
+					case hasPrice:
+						avgText = colorRedText(avgText)
+					}
+					line += "\t" + avgText
+				}
+				if b, ok := breakEven[coin]; ok {
+					line += "\tbreak-even " + formatBreakEven(b, price/usdRate, hasPrice, usdRate)
+				}
+				if md, ok := market[coin]; ok {
+					line += "\t" + formatChange("24h", md.Change24h) + "\t" + formatChange("7d", md.Change7d)
+				}
+				if md, ok := metadata[coin]; ok && md.Name != "" {
+					line += "\t" + md.Name
+				}
+				fmt.Fprintln(w, line+"\t")
+				totalCurrentValue = totalCurrentValue.Add(value)
 			}
 			w.Flush()
 		} else {
@@ -93,10 +298,10 @@ Use --no-prices to disable price fetching.`,
 		}
 
 		// Staked by coin
-		fmt.Fprintln(osStdout, "\nSTAKED BY COIN:")
+		fmt.Fprintf(osStdout, "\nSTAKED BY COIN%s:\n", sorted)
 		if len(summary.StakesByCoin) > 0 {
 			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
-			for _, coin := range sortedKeys(summary.StakesByCoin) {
+			for _, coin := range order(summary.StakesByCoin) {
 				amount := summary.StakesByCoin[coin]
 				printCoinLine(w, coin, amount, livePrices, false)
 			}
@@ -105,11 +310,32 @@ Use --no-prices to disable price fetching.`,
 			fmt.Fprintln(osStdout, "  (none)")
 		}
 
+		// Projected staking yield from each stake's APY
+		if len(yield) > 0 {
+			fmt.Fprintln(osStdout, "\nPROJECTED STAKING YIELD:")
+			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+			var totalAnnual, totalMonthly float64
+			for _, y := range yield {
+				line := fmt.Sprintf("  %-8s\t%.2f%% APY\t%s/yr\t%s/mo",
+					y.Coin+":", y.APY, formatAmount(y.Annual), formatAmount(y.Monthly))
+				if price, ok := livePrices[y.Coin]; ok {
+					line += fmt.Sprintf("\t= %s/yr\t%s/mo", formatMoney(y.Annual*price), formatMoney(y.Monthly*price))
+					totalAnnual += y.Annual * price
+					totalMonthly += y.Monthly * price
+				}
+				fmt.Fprintln(w, line+"\t")
+			}
+			w.Flush()
+			if totalAnnual > 0 {
+				fmt.Fprintf(osStdout, "  Total: %s/yr, %s/mo\n", formatMoney(totalAnnual), formatMoney(totalMonthly))
+			}
+		}
+
 		// Available by coin (holdings - staked)
-		fmt.Fprintln(osStdout, "\nAVAILABLE BY COIN (Holdings - Staked):")
+		fmt.Fprintf(osStdout, "\nAVAILABLE BY COIN (Holdings - Staked)%s:\n", sorted)
 		if len(summary.AvailableByCoin) > 0 {
 			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
-			for _, coin := range sortedKeys(summary.AvailableByCoin) {
+			for _, coin := range order(summary.AvailableByCoin) {
 				amount := summary.AvailableByCoin[coin]
 				printCoinLine(w, coin, amount, livePrices, false)
 			}
@@ -119,14 +345,14 @@ Use --no-prices to disable price fetching.`,
 		}
 
 		// Loans by coin
-		fmt.Fprintln(osStdout, "\nLOANS BY COIN:")
-		var totalLoanValue float64
+		fmt.Fprintf(osStdout, "\nLOANS BY COIN%s:\n", sorted)
+		var totalLoanValue decimal.Decimal
 		if len(summary.LoansByCoin) > 0 {
 			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
-			for _, coin := range sortedKeys(summary.LoansByCoin) {
+			for _, coin := range order(summary.LoansByCoin) {
 				amount := summary.LoansByCoin[coin]
 				value := printCoinLine(w, coin, amount, livePrices, false)
-				totalLoanValue += value
+				totalLoanValue = totalLoanValue.Add(value)
 			}
 			w.Flush()
 		} else {
@@ -134,10 +360,10 @@ Use --no-prices to disable price fetching.`,
 		}
 
 		// Net holdings (holdings - loans)
-		fmt.Fprintln(osStdout, "\nNET HOLDINGS (Holdings - Loans):")
+		fmt.Fprintf(osStdout, "\nNET HOLDINGS (Holdings - Loans)%s:\n", sorted)
 		if len(summary.NetByCoin) > 0 {
 			w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', tabwriter.AlignRight)
-			for _, coin := range sortedKeys(summary.NetByCoin) {
+			for _, coin := range order(summary.NetByCoin) {
 				amount := summary.NetByCoin[coin]
 				printCoinLine(w, coin, amount, livePrices, true)
 			}
@@ -146,40 +372,216 @@ Use --no-prices to disable price fetching.`,
 			fmt.Fprintln(osStdout, "  (none)")
 		}
 
-		fmt.Fprintln(osStdout, "\n---------------------------")
+		// Allocation by share of holdings value
+		if livePrices != nil {
+			allocation := portfolio.CalculateAllocation(summary.HoldingsByCoin, livePrices)
+			if len(allocation) > 0 {
+				fmt.Fprintln(osStdout, "\nALLOCATION:")
+				w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+				for _, a := range allocation {
+					fmt.Fprintf(w, "  %s\t%5.1f%%\t%s\t%s\n",
+						a.Coin+":", a.Percent, renderBar(a.Percent, barWidth()), formatMoney(a.ValueUSD))
+				}
+				w.Flush()
+			}
+		}
+
+		fmt.Fprintln(osStdout, "\n"+separator())
 		fmt.Fprintf(osStdout, "Total Holdings: %d\n", summary.TotalHoldingsCount)
 		fmt.Fprintf(osStdout, "Total Sales: %d\n", summary.TotalSalesCount)
 		fmt.Fprintf(osStdout, "Total Stakes: %d\n", summary.TotalStakesCount)
 		fmt.Fprintf(osStdout, "Total Loans: %d\n", summary.TotalLoansCount)
-		fmt.Fprintf(osStdout, "Total Invested: %s\n", formatUSD(summary.TotalInvestedUSD))
-		fmt.Fprintf(osStdout, "Total Sold: %s\n", formatUSD(summary.TotalSoldUSD))
+		totalInvested := models.AtPrice(summary.TotalInvestedUSD, usdRate)
+		totalSold := models.AtPrice(summary.TotalSoldUSD, usdRate)
+		fmt.Fprintf(osStdout, "Total Invested: %s\n", formatMoney(models.Float(totalInvested)))
+		fmt.Fprintf(osStdout, "Total Sold: %s\n", formatMoney(models.Float(totalSold)))
+
+		// Realized and unrealized P/L come from lot matching, which is per portfolio
+		if !all {
+			realizedUSD := profitLoss.RealizedUSD()
+			if scoped {
+				if realizedUSD, err = p.GetRealizedBetween(since, until); err != nil {
+					return err
+				}
+			}
+			label := "Realized P/L"
+			if baseline != nil {
+				realizedUSD = realizedUSD.Sub(decimal.NewFromFloat(baseline.RealizedPL))
+				label += " since " + snapshotLabel(*baseline)
+			}
+			realized := models.Float(models.AtPrice(realizedUSD, usdRate))
+			fmt.Fprintf(osStdout, "%s: %s\n", label, colorByValue(formatSignedMoney(realized), realized))
+		}
 
 		// Show value summary if prices were fetched
-		if livePrices != nil && totalCurrentValue > 0 {
-			fmt.Fprintln(osStdout, "\n---------------------------")
-			fmt.Fprintf(osStdout, "Holdings Value: %s\n", formatUSD(totalCurrentValue))
-			if totalLoanValue > 0 {
-				fmt.Fprintf(osStdout, "Loans Value:   -%s\n", colorRedText(formatUSD(totalLoanValue)))
+		if livePrices != nil && totalCurrentValue.IsPositive() {
+			fmt.Fprintln(osStdout, "\n"+separator())
+			fmt.Fprintf(osStdout, "Holdings Value: %s\n", formatMoney(models.Float(totalCurrentValue)))
+			if totalLoanValue.IsPositive() {
+				fmt.Fprintf(osStdout, "Loans Value:   -%s\n", colorRedText(formatMoney(models.Float(totalLoanValue))))
+			}
+			netValue := models.Float(totalCurrentValue.Sub(totalLoanValue))
+			fmt.Fprintf(osStdout, "Net Value:      %s\n", formatMoney(netValue))
+			// Profit/loss weighs current value against all-time totals, so
+			// it has no meaning for a period
+			if !scoped {
+				totalProfitLoss := models.Float(totalCurrentValue.Sub(totalLoanValue).Sub(totalInvested).Add(totalSold))
+				profitLossPercent := safeDivide(totalProfitLoss, models.Float(totalInvested)) * 100
+				label := "Profit/Loss:   "
+				if baseline != nil {
+					since := portfolio.GetProfitLossSince(*baseline, models.Snapshot{
+						NetValue:      netValue / usdRate,
+						TotalInvested: summary.TotalInvestedUSD.InexactFloat64(),
+						ProfitLoss:    totalProfitLoss / usdRate,
+					})
+					totalProfitLoss, profitLossPercent = since.ProfitLoss*usdRate, since.Percent
+					label = "Profit/Loss since " + snapshotLabel(*baseline) + ":"
+				}
+				plText := fmt.Sprintf("%s (%.1f%%)", formatSignedMoney(totalProfitLoss), profitLossPercent)
+				fmt.Fprintf(osStdout, "%s %s\n", label, colorByValue(plText, totalProfitLoss))
+			}
+			if !all && !scoped {
+				// Live prices are in the display currency, cost basis in USD
+				usdPrices := make(map[string]float64)
+				for coin, price := range livePrices {
+					usdPrices[coin] = price / usdRate
+				}
+				unrealizedUSD := profitLoss.UnrealizedUSD(usdPrices)
+				if baseline != nil {
+					unrealizedUSD = unrealizedUSD.Sub(decimal.NewFromFloat(baseline.UnrealizedPL))
+				}
+				unrealized := models.Float(models.AtPrice(unrealizedUSD, usdRate))
+				fmt.Fprintf(osStdout, "  Unrealized:   %s\n", colorByValue(formatSignedMoney(unrealized), unrealized))
 			}
-			netValue := totalCurrentValue - totalLoanValue
-			fmt.Fprintf(osStdout, "Net Value:      %s\n", formatUSD(netValue))
-			profitLoss := netValue - summary.TotalInvestedUSD + summary.TotalSoldUSD
-			profitLossPercent := safeDivide(profitLoss, summary.TotalInvestedUSD) * 100
-			prefix := ""
-			if profitLoss > 0 {
-				prefix = "+"
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
 			}
-			plText := fmt.Sprintf("%s%s (%.1f%%)", prefix, formatUSD(profitLoss), profitLossPercent)
-			fmt.Fprintf(osStdout, "Profit/Loss:    %s\n", colorByValue(plText, profitLoss))
+			printGoals(cfg.GetGoals(), netValue, summary.HoldingsByCoin, livePrices, usdRate)
 		}
 
-		// Show warning for unmapped tickers
-		if len(unmappedTickers) > 0 {
-			fmt.Fprintln(osStdout, "\n---------------------------")
-			fmt.Fprintf(osStdout, "Note: No CoinGecko mapping for: %s\n", strings.Join(unmappedTickers, ", "))
-			fmt.Fprintln(osStdout, "Run 'follyo ticker search <query> <TICKER>' to add a mapping")
+		// Stablecoins far from $1 are worth a warning, since their value is
+		// usually taken for granted
+		var depegged []pegDeviation
+		if livePrices != nil {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			depegged = depeggedStablecoins(livePrices, usdRate, cfg.GetPegThreshold())
+		}
+		if len(depegged) > 0 || len(duplicateMappings) > 0 {
+			fmt.Fprintln(osStdout, "\n"+separator())
+		}
+		for _, d := range depegged {
+			fmt.Fprintf(osStdout, "Warning: %s is off its $1 peg at $%.4f (%+.2f%%)\n", d.Coin, d.PriceUSD, d.Percent)
+		}
+
+		// Show warning for tickers sharing a CoinGecko ID
+		if len(duplicateMappings) > 0 {
+			for _, id := range sortedStringKeys(duplicateMappings) {
+				fmt.Fprintf(osStdout, "Warning: %s all map to CoinGecko ID %s and share its price\n",
+					strings.Join(duplicateMappings[id], ", "), id)
+			}
+			fmt.Fprintln(osStdout, "Run 'follyo ticker map <TICKER> <COINGECKO_ID>' to fix incorrect mappings")
 		}
 
 		fmt.Fprintln(osStdout)
+
+		// Combined summaries have no single snapshot store to save into, and
+		// stale prices would misdate the snapshot
+		if livePrices != nil && staleSince.IsZero() && !all {
+			autoSnapshot()
+		}
+		return nil
 	},
 }
+
+// formatCoinPL formats a coin's unrealized profit/loss, its value less its
+// cost basis, with the percent of the basis, colored by sign
+func formatCoinPL(value, basis decimal.Decimal) string {
+	unrealized := models.Float(value.Sub(basis))
+	text := fmt.Sprintf("P/L %s (%+.1f%%)", formatSignedMoney(unrealized), unrealized/models.Float(basis)*100)
+	return colorByValue(text, unrealized)
+}
+
+// periodLabel describes the range from since through until, either of
+// which may be zero for an open end
+func periodLabel(since, until models.Date) string {
+	switch {
+	case until.IsZero():
+		return "since " + since.String()
+	case since.IsZero():
+		return "until " + until.String()
+	default:
+		return since.String() + " to " + until.String()
+	}
+}
+
+// pegDeviation is a stablecoin trading away from its $1 peg
+type pegDeviation struct {
+	Coin     string
+	PriceUSD float64
+	Percent  float64 // Signed deviation from $1
+}
+
+// depeggedStablecoins returns the stablecoins in livePrices, quoted at usdRate
+// per USD, trading more than threshold percent from $1, sorted by coin. A
+// threshold of zero or less reports none.
+func depeggedStablecoins(livePrices map[string]float64, usdRate, threshold float64) []pegDeviation {
+	if threshold <= 0 || usdRate <= 0 {
+		return nil
+	}
+	var depegged []pegDeviation
+	for _, coin := range sortedKeys(livePrices) {
+		if !prices.IsStablecoin(coin) {
+			continue
+		}
+		priceUSD := livePrices[coin] / usdRate
+		percent := (priceUSD - 1) * 100
+		if math.Abs(percent) > threshold {
+			depegged = append(depegged, pegDeviation{Coin: coin, PriceUSD: priceUSD, Percent: percent})
+		}
+	}
+	return depegged
+}
+
+// summarySorts are the orders of the summary's coin tables
+var summarySorts = []string{"name", "value", "pl"}
+
+// sortSummaryCoins returns the coins of amounts by name, or by value or
+// profit/loss, largest first, as sortBy says; reverse flips the order.
+// Coins without a price sort as worth nothing.
+func sortSummaryCoins(amounts map[string]decimal.Decimal, sortBy string, reverse bool, livePrices map[string]float64, pl func(coin string) float64) []string {
+	coins := sortedStringKeys(amounts)
+	var key func(coin string) float64
+	switch sortBy {
+	case "value":
+		key = func(coin string) float64 { return models.Float(models.AtPrice(amounts[coin], livePrices[coin])) }
+	case "pl":
+		key = pl
+	}
+	if key != nil {
+		sort.SliceStable(coins, func(i, j int) bool { return key(coins[i]) > key(coins[j]) })
+	}
+	if reverse {
+		slices.Reverse(coins)
+	}
+	return coins
+}
+
+// summarySortLabel returns the order of the coin tables for their titles,
+// e.g. " (by value ↓)", or "" for the default order by name
+func summarySortLabel(sortBy string, reverse bool) string {
+	if sortBy == "name" && !reverse {
+		return ""
+	}
+	// Names ascend and values descend unless reversed
+	descending := (sortBy != "name") != reverse
+	arrow := "↑"
+	if descending {
+		arrow = "↓"
+	}
+	return fmt.Sprintf(" (by %s %s)", sortBy, arrow)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var swapCmd = &cobra.Command{
+	Use:     "swap",
+	Aliases: []string{"sw"},
+	Short:   "Manage coin-to-coin swaps",
+}
+
+var swapAddCmd = &cobra.Command{
+	Use:   "add FROM_COIN FROM_AMOUNT TO_COIN TO_AMOUNT",
+	Short: "Record a coin-to-coin swap",
+	Long: `Record exchanging one coin directly for another.
+
+FROM_COIN: The coin given up (e.g., ETH)
+FROM_AMOUNT: Amount given up
+TO_COIN: The coin received (e.g., SOL)
+TO_AMOUNT: Amount received
+
+The swap's USD value becomes the proceeds of FROM_COIN and the cost basis
+of TO_COIN. Use --value to set it; otherwise it is looked up from the price
+of FROM_COIN on the swap date.`,
+	Args: cobra.ExactArgs(4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromCoin := args[0]
+		fromAmount, err := parseDecimal(args[1], "from amount")
+		if err != nil {
+			return err
+		}
+		toCoin := args[2]
+		toAmount, err := parseDecimal(args[3], "to amount")
+		if err != nil {
+			return err
+		}
+
+		value := decimalFlag(cmd, "value")
+		platform, err := platformFlag(cmd)
+		if err != nil {
+			return err
+		}
+		notes, _ := cmd.Flags().GetString("notes")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+
+		if value.IsZero() {
+			value, err = swapValueUSD(fromCoin, fromAmount, date)
+			if err != nil {
+				return fmt.Errorf("%w; use --value to set the swap value", err)
+			}
+		}
+
+		swap, err := p.AddSwap(fromCoin, fromAmount, toCoin, toAmount, value, platform, notes, date, tags...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Swapped %s %s for %s %s worth %s (ID: %s)\n",
+			formatAmount(swap.FromAmount.InexactFloat64()), swap.FromCoin, formatAmount(swap.ToAmount.InexactFloat64()), swap.ToCoin,
+			formatUSD(swap.ValueUSD.InexactFloat64()), swap.ID)
+		return nil
+	},
+}
+
+var swapListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all swaps",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := p.ListSwaps()
+		if err != nil {
+			return err
+		}
+		tag, _ := cmd.Flags().GetString("tag")
+		var swaps []models.Swap
+		for _, sw := range all {
+			if tag == "" || models.HasTag(sw.Tags, tag) {
+				swaps = append(swaps, sw)
+			}
+		}
+
+		if len(swaps) == 0 {
+			fmt.Fprintln(osStdout, "No swaps found.")
+			return nil
+		}
+
+		short := shortIDs(idsOf(all, func(sw models.Swap) string { return sw.ID }))
+		return printTable(cmd, "swap", swapColumns(short), swaps, nil)
+	},
+}
+
+var swapRemoveCmd = &cobra.Command{
+	Use:   "remove ID...",
+	Short: "Remove swaps by ID",
+	Long: `Remove one or more swaps.
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		records, err := p.ListSwaps()
+		if err != nil {
+			return err
+		}
+		existing := recordDetails(records, func(sw models.Swap) (string, string) {
+			return sw.ID, fmt.Sprintf("%s %s -> %s %s swapped %s%s", formatAmount(sw.FromAmount.InexactFloat64()), sw.FromCoin, formatAmount(sw.ToAmount.InexactFloat64()), sw.ToCoin, sw.Date, onPlatform(sw.Platform))
+		})
+		return removeRecords(args, existing, "swap", p.RemoveSwap, func(id string) string {
+			return fmt.Sprintf("Removed swap %s", id)
+		})
+	},
+}
+
+// swapValueUSD looks up the USD value of amount of coin on date (YYYY-MM-DD,
+// empty for today) from CoinGecko
+func swapValueUSD(coin string, amount decimal.Decimal, date string) (decimal.Decimal, error) {
+	coin = strings.ToUpper(coin)
+	ps, err := newPriceService()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	ticker := []string{coin}
+
+	var found map[string]float64
+	if date == "" || date == time.Now().Format("2006-01-02") {
+		found, err = ps.GetPrices(ticker)
+	} else {
+		t, parseErr := time.Parse("2006-01-02", date)
+		if parseErr != nil {
+			return decimal.Zero, usageErrorf("invalid date %s (expected YYYY-MM-DD)", date)
+		}
+		found, err = ps.GetHistoricalPrices(t, ticker)
+	}
+	if err != nil {
+		return decimal.Zero, ioError(fmt.Errorf("could not fetch %s price: %w", coin, err))
+	}
+
+	price, ok := found[coin]
+	if !ok {
+		return decimal.Zero, notFoundErrorf("no price found for %s", coin)
+	}
+	return models.AtPrice(amount, price), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pretty-andrechal/follyo/internal/cloudsync"
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the portfolio with a git remote or S3 bucket",
+	Long: `Sync the portfolio records and snapshots with a copy on a git remote or
+an S3-compatible bucket, configured in config.json:
+
+  "sync": {"backend": "git", "url": "git@github.com:me/follyo-data.git"}
+
+  "sync": {"backend": "s3", "endpoint": "https://s3.us-east-1.amazonaws.com",
+           "bucket": "my-bucket", "prefix": "follyo/",
+           "access_key": "...", "secret_key": "..."}
+
+S3 keys default to AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY. Git
+authentication is left to git. The configuration itself is not synced.
+
+Changes are detected by comparing file hashes with the version last
+synced. When both sides changed, records added or removed on either side
+are merged; a record changed on both sides is a conflict, and nothing is
+written until it is resolved. Sync requires JSON storage.`,
+}
+
+var syncPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Merge remote changes, then upload local changes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSync(true)
+	},
+}
+
+var syncPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Download remote changes, merging them with local changes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSync(false)
+	},
+}
+
+// runSync pushes or pulls the data directory and prints what happened to
+// each file
+func runSync(push bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	settings, ok := cfg.GetSync()
+	if !ok {
+		return notFoundErrorf("sync is not configured: add \"sync\" to %s", configPath())
+	}
+	if cfg.GetStorage() == "sqlite" {
+		return usageErrorf("sync requires JSON storage")
+	}
+	dir := filepath.Dir(dataPath)
+	remote, err := newSyncRemote(settings, dir)
+	if err != nil {
+		return err
+	}
+
+	release, err := lockDataFiles()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	syncer := cloudsync.New(remote, dir, []string{filepath.Base(dataPath), filepath.Base(snapshotsPath())})
+	var results []cloudsync.Result
+	if push {
+		results, err = syncer.Push()
+	} else {
+		results, err = syncer.Pull()
+	}
+	var conflict *cloudsync.ConflictError
+	if errors.As(err, &conflict) {
+		return fmt.Errorf("%w; nothing was synced. Remove or restore those records on one side, then sync again", err)
+	}
+	if err != nil {
+		return ioError(err)
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(osStdout, "%-16s %s\n", r.File+":", r.Action)
+	}
+	return nil
+}
+
+// newSyncRemote returns the remote configured for sync. Git keeps its
+// working copy in the sync state directory of dir.
+func newSyncRemote(settings config.Sync, dir string) (cloudsync.Remote, error) {
+	switch settings.Backend {
+	case "git":
+		if settings.URL == "" {
+			return nil, usageErrorf("sync: git backend needs a \"url\"")
+		}
+		return cloudsync.NewGit(settings.URL, settings.Branch, filepath.Join(cloudsync.StateDir(dir), "git")), nil
+	case "s3":
+		if settings.Endpoint == "" || settings.Bucket == "" {
+			return nil, usageErrorf("sync: s3 backend needs an \"endpoint\" and a \"bucket\"")
+		}
+		accessKey, secretKey := settings.AccessKey, settings.SecretKey
+		if accessKey == "" {
+			accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if secretKey == "" {
+			secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if accessKey == "" || secretKey == "" {
+			return nil, usageErrorf("sync: s3 backend needs an access key and secret key")
+		}
+		return cloudsync.NewS3(settings.Endpoint, settings.Region, settings.Bucket, settings.Prefix, accessKey, secretKey), nil
+	}
+	return nil, usageErrorf("sync: unknown backend %q (available: git, s3)", settings.Backend)
+}
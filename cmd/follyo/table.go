@@ -0,0 +1,366 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+// tableColumn is a column of a list table
+type tableColumn[T any] struct {
+	key    string // Name given to --columns, e.g. "total"
+	header string
+	hidden bool // Shown only when chosen with --columns or 'follyo columns set'
+	cell   func(T) string
+}
+
+// columnKeys returns the keys of all columns and of those shown by default
+func columnKeys[T any](columns []tableColumn[T]) (all, defaults []string) {
+	for _, c := range columns {
+		all = append(all, c.key)
+		if !c.hidden {
+			defaults = append(defaults, c.key)
+		}
+	}
+	return all, defaults
+}
+
+// listTables returns the keys of all columns and of the default ones of
+// each list table, by command
+var listTables = map[string]func() (all, defaults []string){
+	"buy":      func() ([]string, []string) { return columnKeys(holdingColumns(nil)) },
+	"sell":     func() ([]string, []string) { return columnKeys(saleColumns(nil)) },
+	"loan":     func() ([]string, []string) { return columnKeys(loanColumns(nil, nil, nil)) },
+	"stake":    func() ([]string, []string) { return columnKeys(stakeColumns(nil)) },
+	"swap":     func() ([]string, []string) { return columnKeys(swapColumns(nil)) },
+	"transfer": func() ([]string, []string) { return columnKeys(transferColumns(nil)) },
+}
+
+// chooseColumns returns the keys of the columns to show out of all: those
+// given, or the defaults when none are. Keys starting with + or - add
+// columns to or remove them from the defaults.
+func chooseColumns(table string, keys, all, defaults []string) ([]string, error) {
+	if len(keys) == 0 {
+		return defaults, nil
+	}
+	relative := true
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "+") && !strings.HasPrefix(key, "-") {
+			relative = false
+		}
+	}
+	var chosen []string
+	if relative {
+		chosen = slices.Clone(defaults)
+	}
+	for _, key := range keys {
+		op, name := byte(0), strings.ToLower(strings.TrimSpace(key))
+		if relative {
+			op, name = name[0], name[1:]
+		}
+		if !slices.Contains(all, name) {
+			return nil, usageErrorf("unknown %s list column %q (columns: %s)", table, name, strings.Join(all, ", "))
+		}
+		switch {
+		case op == '-':
+			chosen = slices.DeleteFunc(chosen, func(k string) bool { return k == name })
+		case !slices.Contains(chosen, name):
+			chosen = append(chosen, name)
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, usageErrorf("no %s list columns left to show", table)
+	}
+	return chosen, nil
+}
+
+// printTable prints rows as a table of the columns chosen with --columns,
+// else with 'follyo columns set', else shown by default. With opts, the
+// sorted column is marked.
+func printTable[T any](cmd *cobra.Command, table string, columns []tableColumn[T], rows []T, opts *portfolio.ListOptions) error {
+	keys, _ := cmd.Flags().GetStringSlice("columns")
+	if !cmd.Flags().Changed("columns") {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		keys = cfg.GetListColumns(table)
+	}
+	all, defaults := columnKeys(columns)
+	chosen, err := chooseColumns(table, keys, all, defaults)
+	if err != nil {
+		return err
+	}
+	shown := make([]tableColumn[T], len(chosen))
+	headers := make([]string, len(chosen))
+	for i, key := range chosen {
+		shown[i] = columns[slices.IndexFunc(columns, func(c tableColumn[T]) bool { return c.key == key })]
+		headers[i] = shown[i].header
+	}
+
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	header := strings.Join(headers, "\t")
+	if opts != nil {
+		header = sortHeader(header, *opts)
+	}
+	fmt.Fprintln(w, header)
+	cells := make([]string, len(shown))
+	for _, row := range rows {
+		for i, c := range shown {
+			cells[i] = c.cell(row)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// printListTotals prints the amount and USD total of records per coin and
+// the USD total of all of them, below a list table. records are all those
+// matching the list's filters, so the totals cover every page.
+func printListTotals[T any](records []T, paged bool, total func(T) (coin string, amount, usd float64)) {
+	amounts := make(map[string]float64)
+	values := make(map[string]float64)
+	var sum float64
+	for _, r := range records {
+		coin, amount, usd := total(r)
+		amounts[coin] += amount
+		values[coin] += usd
+		sum += usd
+	}
+
+	title := "Totals"
+	if paged {
+		title += " (all pages)"
+	}
+	fmt.Fprintf(osStdout, "\n%s\n", title)
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	for _, coin := range sortedStringKeys(amounts) {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", coin, formatAmount(amounts[coin]), formatUSD(values[coin]))
+	}
+	fmt.Fprintf(w, "  All\t\t%s\n", formatUSD(sum))
+	w.Flush()
+}
+
+// coinGroup sums the records of one coin for 'buy list --group' and
+// 'sell list --group'
+type coinGroup struct {
+	coin   string
+	lots   int
+	amount float64
+	value  float64 // Amount times price
+	net    float64 // value with fees added, for purchases, or taken off, for sales
+}
+
+// printGroupedList prints records as one row per coin with the lot count,
+// total amount and USD, average price, and net USD headed netHeader, then a
+// row totalling all coins. Rows are sorted by coin unless opts sorts by
+// amount or value, and paged like the records.
+func printGroupedList[T any](cmd *cobra.Command, opts portfolio.ListOptions, records []T, noun, netHeader string, group func(T) (coin string, amount, value, net float64)) error {
+	if cmd.Flags().Changed("columns") {
+		return usageErrorf("--columns cannot be combined with --group")
+	}
+
+	byCoin := make(map[string]*coinGroup)
+	for _, r := range records {
+		coin, amount, value, net := group(r)
+		g := byCoin[coin]
+		if g == nil {
+			g = &coinGroup{coin: coin}
+			byCoin[coin] = g
+		}
+		g.lots++
+		g.amount += amount
+		g.value += value
+		g.net += net
+	}
+	groups := make([]coinGroup, 0, len(byCoin))
+	for _, coin := range sortedStringKeys(byCoin) {
+		groups = append(groups, *byCoin[coin])
+	}
+	if opts.SortBy != portfolio.SortByAmount && opts.SortBy != portfolio.SortByValue {
+		opts.SortBy = portfolio.SortByCoin
+	}
+	slices.SortStableFunc(groups, func(a, b coinGroup) int {
+		if opts.Reverse {
+			a, b = b, a
+		}
+		switch opts.SortBy {
+		case portfolio.SortByAmount:
+			return cmp.Compare(a.amount, b.amount)
+		case portfolio.SortByValue:
+			return cmp.Compare(a.value, b.value)
+		}
+		return strings.Compare(a.coin, b.coin)
+	})
+
+	page, limit := pageFromFlags(cmd)
+	shown, pageInfo := paginate(groups, page, limit)
+
+	var lots int
+	var value, net float64
+	for _, g := range groups {
+		lots += g.lots
+		value += g.value
+		net += g.net
+	}
+
+	w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, sortHeader("Coin\tLots\tAmount\tTotal USD\tAvg Price\t"+netHeader, opts))
+	for _, g := range shown {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", g.coin, g.lots, formatAmount(g.amount),
+			formatUSD(g.value), formatUSD(g.value/g.amount), formatUSD(g.net))
+	}
+	fmt.Fprintf(w, "All\t%d\t\t%s\t\t%s\n", lots, formatUSD(value), formatUSD(net))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	printListFooter(opts, len(records), noun, pageInfo)
+	return nil
+}
+
+// addColumnsFlag adds the --columns flag to a list command
+func addColumnsFlag(cmd *cobra.Command, table string) {
+	cmd.Flags().StringSlice("columns", nil, "Columns to show, or +COLUMN and -COLUMN to change the defaults (see 'follyo columns')")
+	cmd.RegisterFlagCompletionFunc("columns", func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		all, _ := listTables[table]()
+		return matching(all, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// notesLabel returns a record's notes, or "-" if it has none
+func notesLabel(notes string) string {
+	if notes == "" {
+		return "-"
+	}
+	return notes
+}
+
+// feeLabel returns a USD fee, or "-" if there is none
+func feeLabel(fee float64) string {
+	if fee == 0 {
+		return "-"
+	}
+	return formatUSD(fee)
+}
+
+// holdingColumns returns the columns of 'buy list', showing IDs with short
+func holdingColumns(short func(string) string) []tableColumn[models.Holding] {
+	return []tableColumn[models.Holding]{
+		{"id", "ID", false, func(h models.Holding) string { return short(h.ID) }},
+		{"coin", "Coin", false, func(h models.Holding) string { return h.Coin }},
+		{"amount", "Amount", false, func(h models.Holding) string { return formatAmount(h.Amount.InexactFloat64()) }},
+		{"price", "Price/Unit", false, func(h models.Holding) string { return formatUSD(h.PurchasePriceUSD.InexactFloat64()) }},
+		{"total", "Total USD", false, func(h models.Holding) string { return formatUSD(h.TotalValueUSD().InexactFloat64()) }},
+		{"fee", "Fee", false, func(h models.Holding) string { return feeLabel(h.FeeUSD.InexactFloat64()) }},
+		{"platform", "Platform", false, func(h models.Holding) string { return platformLabel(h.Platform) }},
+		{"date", "Date", false, func(h models.Holding) string { return h.Date.String() }},
+		{"tags", "Tags", false, func(h models.Holding) string { return tagsLabel(h.Tags) }},
+		{"notes", "Notes", true, func(h models.Holding) string { return notesLabel(h.Notes) }},
+	}
+}
+
+// saleColumns returns the columns of 'sell list', showing IDs with short
+func saleColumns(short func(string) string) []tableColumn[models.Sale] {
+	return []tableColumn[models.Sale]{
+		{"id", "ID", false, func(s models.Sale) string { return short(s.ID) }},
+		{"coin", "Coin", false, func(s models.Sale) string { return s.Coin }},
+		{"amount", "Amount", false, func(s models.Sale) string { return formatAmount(s.Amount.InexactFloat64()) }},
+		{"price", "Price/Unit", false, func(s models.Sale) string { return formatUSD(s.SellPriceUSD.InexactFloat64()) }},
+		{"total", "Total USD", false, func(s models.Sale) string { return formatUSD(s.TotalValueUSD().InexactFloat64()) }},
+		{"fee", "Fee", false, func(s models.Sale) string { return feeLabel(s.FeeUSD.InexactFloat64()) }},
+		{"platform", "Platform", false, func(s models.Sale) string { return platformLabel(s.Platform) }},
+		{"date", "Date", false, func(s models.Sale) string { return s.Date.String() }},
+		{"tags", "Tags", false, func(s models.Sale) string { return tagsLabel(s.Tags) }},
+		{"notes", "Notes", true, func(s models.Sale) string { return notesLabel(s.Notes) }},
+	}
+}
+
+// loanColumns returns the columns of 'loan list', showing IDs with short
+// and the outstanding amounts and accrued interest of loans by ID
+func loanColumns(short func(string) string, outstanding map[string]decimal.Decimal, interest map[string]float64) []tableColumn[models.Loan] {
+	return []tableColumn[models.Loan]{
+		{"id", "ID", false, func(l models.Loan) string { return short(l.ID) }},
+		{"coin", "Coin", false, func(l models.Loan) string { return l.Coin }},
+		{"amount", "Amount", false, func(l models.Loan) string { return formatAmount(l.Amount.InexactFloat64()) }},
+		{"outstanding", "Outstanding", false, func(l models.Loan) string { return formatAmount(outstanding[l.ID].InexactFloat64()) }},
+		{"interest", "Interest", false, func(l models.Loan) string {
+			if l.InterestRate == nil {
+				return "-"
+			}
+			return formatAmount(interest[l.ID])
+		}},
+		{"platform", "Platform", false, func(l models.Loan) string { return l.Platform }},
+		{"rate", "Rate", false, func(l models.Loan) string {
+			if l.InterestRate == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f%%", *l.InterestRate)
+		}},
+		{"date", "Date", false, func(l models.Loan) string { return l.Date.String() }},
+		{"tags", "Tags", false, func(l models.Loan) string { return tagsLabel(l.Tags) }},
+		{"notes", "Notes", true, func(l models.Loan) string { return notesLabel(l.Notes) }},
+	}
+}
+
+// stakeColumns returns the columns of 'stake list', showing IDs with short
+func stakeColumns(short func(string) string) []tableColumn[models.Stake] {
+	return []tableColumn[models.Stake]{
+		{"id", "ID", false, func(st models.Stake) string { return short(st.ID) }},
+		{"coin", "Coin", false, func(st models.Stake) string { return st.Coin }},
+		{"amount", "Amount", false, func(st models.Stake) string { return formatAmount(st.Amount.InexactFloat64()) }},
+		{"platform", "Platform", false, func(st models.Stake) string { return st.Platform }},
+		{"apy", "APY", false, func(st models.Stake) string {
+			if st.APY == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f%%", *st.APY)
+		}},
+		{"date", "Date", false, func(st models.Stake) string { return st.Date.String() }},
+		{"tags", "Tags", false, func(st models.Stake) string { return tagsLabel(st.Tags) }},
+		{"notes", "Notes", true, func(st models.Stake) string { return notesLabel(st.Notes) }},
+	}
+}
+
+// swapColumns returns the columns of 'swap list', showing IDs with short
+func swapColumns(short func(string) string) []tableColumn[models.Swap] {
+	return []tableColumn[models.Swap]{
+		{"id", "ID", false, func(sw models.Swap) string { return short(sw.ID) }},
+		{"from", "From", false, func(sw models.Swap) string { return sw.FromCoin }},
+		{"from-amount", "Amount", false, func(sw models.Swap) string { return formatAmount(sw.FromAmount.InexactFloat64()) }},
+		{"to", "To", false, func(sw models.Swap) string { return sw.ToCoin }},
+		{"to-amount", "Amount", false, func(sw models.Swap) string { return formatAmount(sw.ToAmount.InexactFloat64()) }},
+		{"value", "Value USD", false, func(sw models.Swap) string { return formatUSD(sw.ValueUSD.InexactFloat64()) }},
+		{"platform", "Platform", false, func(sw models.Swap) string { return platformLabel(sw.Platform) }},
+		{"date", "Date", false, func(sw models.Swap) string { return sw.Date.String() }},
+		{"tags", "Tags", false, func(sw models.Swap) string { return tagsLabel(sw.Tags) }},
+		{"notes", "Notes", true, func(sw models.Swap) string { return notesLabel(sw.Notes) }},
+	}
+}
+
+// transferColumns returns the columns of 'transfer list', showing IDs with
+// short
+func transferColumns(short func(string) string) []tableColumn[models.Transfer] {
+	return []tableColumn[models.Transfer]{
+		{"id", "ID", false, func(t models.Transfer) string { return short(t.ID) }},
+		{"coin", "Coin", false, func(t models.Transfer) string { return t.Coin }},
+		{"amount", "Amount", false, func(t models.Transfer) string { return formatAmount(t.Amount.InexactFloat64()) }},
+		{"fee", "Fee", false, func(t models.Transfer) string {
+			if t.Fee.IsZero() {
+				return "-"
+			}
+			return formatAmount(t.Fee.InexactFloat64())
+		}},
+		{"from", "From", false, func(t models.Transfer) string { return platformLabel(t.FromPlatform) }},
+		{"to", "To", false, func(t models.Transfer) string { return platformLabel(t.ToPlatform) }},
+		{"date", "Date", false, func(t models.Transfer) string { return t.Date.String() }},
+		{"tags", "Tags", false, func(t models.Transfer) string { return tagsLabel(t.Tags) }},
+		{"notes", "Notes", true, func(t models.Transfer) string { return notesLabel(t.Notes) }},
+	}
+}
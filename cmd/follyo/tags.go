@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Summarize records by tag",
+	Long: `Show each tag in use with the number of records carrying it, the cost
+of tagged purchases, and the proceeds of tagged sales.
+
+Tag records with --tag when adding them (e.g. --tag DCA,airdrop), and
+filter list and history output with --tag. Tags are matched without case.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		summaries, err := p.GetTagSummary()
+		if err != nil {
+			return err
+		}
+		if len(summaries) == 0 {
+			fmt.Fprintln(osStdout, "No tagged records found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Tag\tRecords\tInvested\tSold")
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.Tag, s.Count, formatUSD(s.InvestedUSD), formatUSD(s.SoldUSD))
+		}
+		w.Flush()
+		return nil
+	},
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/portfolio"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var taxCmd = &cobra.Command{
+	Use:   "tax",
+	Short: "Tax reporting",
+}
+
+var taxReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show realized gains per disposal (Form 8949-style)",
+	Long: `Show realized gains for every disposal in a tax year.
+
+Sales are matched against purchases of the same coin bought on or before
+the sale date: first the lots named with 'follyo sell add --from-lot'
+(specific identification), then the rest using FIFO (first-in,
+first-out). Swaps count as a sale of the coin given up and a purchase of
+the coin received. Each matched portion is reported with its acquisition
+date, disposal date, proceeds, cost basis, and whether it was held
+long-term (more than one year) or short-term. An amount sold beyond the
+purchases made by then is reported without an acquisition date or cost
+basis.
+
+Use --csv to export the report to a CSV file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		year, _ := cmd.Flags().GetInt("year")
+		if year == 0 {
+			year = time.Now().Year()
+		}
+		csvPath, _ := cmd.Flags().GetString("csv")
+
+		report, err := p.GetTaxReport(year)
+		if err != nil {
+			return err
+		}
+
+		if csvPath != "" {
+			if err := writeTaxCSV(csvPath, report); err != nil {
+				return ioError(fmt.Errorf("writing CSV: %w", err))
+			}
+			fmt.Fprintf(osStdout, "Exported %d disposals for %d to %s\n", len(report), year, csvPath)
+			return nil
+		}
+
+		if len(report) == 0 {
+			fmt.Fprintf(osStdout, "No disposals found for %d.\n", year)
+			return nil
+		}
+
+		var shortTerm, longTerm decimal.Decimal
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Coin\tAmount\tAcquired\tSold\tProceeds\tCost Basis\tGain/Loss\tTerm")
+		for _, d := range report {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				d.Coin, formatAmount(d.Amount.InexactFloat64()), acquiredLabel(d), d.DisposedDate,
+				formatUSD(d.ProceedsUSD.InexactFloat64()), formatUSD(d.CostBasisUSD.InexactFloat64()),
+				colorByValue(formatUSD(d.GainUSD().InexactFloat64()), d.GainUSD().InexactFloat64()), termLabel(d))
+			if d.LongTerm {
+				longTerm = longTerm.Add(d.GainUSD())
+			} else {
+				shortTerm = shortTerm.Add(d.GainUSD())
+			}
+		}
+		w.Flush()
+
+		fmt.Fprintln(osStdout, "\n"+separator())
+		short, long := shortTerm.InexactFloat64(), longTerm.InexactFloat64()
+		total := shortTerm.Add(longTerm).InexactFloat64()
+		fmt.Fprintf(osStdout, "Short-term gain/loss: %s\n", colorByValue(formatUSD(short), short))
+		fmt.Fprintf(osStdout, "Long-term gain/loss:  %s\n", colorByValue(formatUSD(long), long))
+		fmt.Fprintf(osStdout, "Total gain/loss:      %s\n", colorByValue(formatUSD(total), total))
+		return nil
+	},
+}
+
+// writeTaxCSV writes disposals to a CSV file
+func writeTaxCSV(path string, report []portfolio.Disposal) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"description", "date_acquired", "date_sold", "proceeds_usd", "cost_basis_usd", "gain_usd", "term"})
+	for _, d := range report {
+		w.Write([]string{
+			d.Amount.String() + " " + d.Coin,
+			acquiredLabel(d),
+			d.DisposedDate.String(),
+			d.ProceedsUSD.StringFixed(2),
+			d.CostBasisUSD.StringFixed(2),
+			d.GainUSD().StringFixed(2),
+			termLabel(d),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// acquiredLabel returns the acquisition date, or VARIOUS when no lot was matched
+func acquiredLabel(d portfolio.Disposal) string {
+	if d.HoldingID == "" {
+		return "VARIOUS"
+	}
+	return d.AcquiredDate.String()
+}
+
+// termLabel returns the holding period classification
+func termLabel(d portfolio.Disposal) string {
+	if d.LongTerm {
+		return "long"
+	}
+	return "short"
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// palette is a color theme, with colors given as color specs: a basic ANSI
+// color name ("red", "bright-green"), or a 256-color index ("0"-"255").
+// An empty spec leaves text uncolored.
+type palette struct {
+	gain string
+	loss string
+}
+
+// palettes are the built-in themes
+var palettes = map[string]palette{
+	"dark":       {gain: "green", loss: "red"},
+	"light":      {gain: "28", loss: "124"}, // Darker green and red
+	"colorblind": {gain: "blue", loss: "208"},
+	"none":       {},
+}
+
+// themeNames lists the themes in display order. "custom" uses the
+// "theme_colors" from the config.
+var themeNames = []string{"dark", "light", "colorblind", "none", "custom"}
+
+// basicColors maps color names to their ANSI foreground codes
+var basicColors = map[string]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+}
+
+// theme holds the ANSI sequences text is colored with
+type theme struct {
+	gain string
+	loss string
+}
+
+// activeTheme is the theme in use, set from the config by applyTheme
+var activeTheme = theme{gain: "\033[32m", loss: "\033[31m"}
+
+// applyTheme activates the theme selected in the config. An unknown theme
+// or invalid custom color falls back to the dark theme with a warning.
+func applyTheme(cfg *config.ConfigStore) {
+	t, err := resolveTheme(cfg.GetTheme(), cfg.GetThemeColors(), supports256Colors())
+	if err != nil {
+		fmt.Fprintf(osStderr, "Warning: %v, using the dark theme\n", err)
+		t, _ = resolveTheme("dark", nil, false)
+	}
+	activeTheme = t
+}
+
+// resolveTheme returns the ANSI sequences of a theme. custom holds the colors
+// of the "custom" theme by role. Without 256-color support, 256-color
+// indexes are approximated with basic colors.
+func resolveTheme(name string, custom map[string]string, colors256 bool) (theme, error) {
+	pal, ok := palettes[name]
+	if name == "custom" {
+		pal, ok = palette{gain: custom["gain"], loss: custom["loss"]}, true
+	}
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(themeNames, ", "))
+	}
+
+	gain, err := ansiColor(pal.gain, colors256)
+	if err != nil {
+		return theme{}, fmt.Errorf("invalid gain color: %w", err)
+	}
+	loss, err := ansiColor(pal.loss, colors256)
+	if err != nil {
+		return theme{}, fmt.Errorf("invalid loss color: %w", err)
+	}
+	return theme{gain: gain, loss: loss}, nil
+}
+
+// ansiColor returns the ANSI sequence for a color spec
+func ansiColor(spec string, colors256 bool) (string, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return "", nil
+	}
+	if code, ok := basicColors[spec]; ok {
+		return fmt.Sprintf("\033[%dm", code), nil
+	}
+	if name, ok := strings.CutPrefix(spec, "bright-"); ok {
+		if code, ok := basicColors[name]; ok {
+			return fmt.Sprintf("\033[%dm", code+60), nil
+		}
+	}
+
+	n, err := strconv.Atoi(spec)
+	if err != nil || n < 0 || n > 255 {
+		return "", fmt.Errorf("%q is not a color name or 0-255", spec)
+	}
+	switch {
+	case n < 8:
+		return fmt.Sprintf("\033[%dm", 30+n), nil
+	case n < 16:
+		return fmt.Sprintf("\033[%dm", 90+n-8), nil
+	case colors256:
+		return fmt.Sprintf("\033[38;5;%dm", n), nil
+	}
+	return fmt.Sprintf("\033[%dm", 30+basicApproximation(n)), nil
+}
+
+// basicApproximation returns the basic color (0-7) closest to a 256-color
+// index from the color cube or grayscale ramp
+func basicApproximation(n int) int {
+	if n >= 232 {
+		if n >= 244 {
+			return 7 // white
+		}
+		return 0 // black
+	}
+	n -= 16
+	r, g, b := n/36, n/6%6, n%6
+	color := 0
+	if r >= 2 {
+		color |= 1
+	}
+	if g >= 2 {
+		color |= 2
+	}
+	if b >= 2 {
+		color |= 4
+	}
+	return color
+}
+
+// supports256Colors reports whether the terminal advertises 256 colors
+func supports256Colors() bool {
+	return os.Getenv("COLORTERM") != "" || strings.Contains(os.Getenv("TERM"), "256color")
+}
+
+var themeCmd = &cobra.Command{
+	Use:   "theme",
+	Short: "Manage the color theme",
+	Long: `Choose how gains and losses are colored. Built-in themes are dark
+(default), light for light terminal backgrounds, colorblind (blue and
+orange), and none.
+
+The custom theme uses "theme_colors" in config.json, e.g.
+  "theme_colors": {"gain": "bright-green", "loss": "208"}
+with basic color names (optionally prefixed with bright-) or 256-color
+indexes. On terminals without 256 colors, indexes are approximated.
+
+Colors are only used when output goes to a terminal, and never when the
+NO_COLOR environment variable is set or TERM is dumb.`,
+}
+
+var themeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List themes with a sample",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		current := cfg.GetTheme()
+		for _, name := range themeNames {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			t, err := resolveTheme(name, cfg.GetThemeColors(), supports256Colors())
+			if err != nil {
+				fmt.Fprintf(osStdout, "%s %-11s (%v)\n", marker, name, err)
+				continue
+			}
+			fmt.Fprintf(osStdout, "%s %-11s %s %s\n", marker, name,
+				colorize("+$1,234.56", t.gain), colorize("-$1,234.56", t.loss))
+		}
+		return nil
+	},
+}
+
+var themeSetCmd = &cobra.Command{
+	Use:   "set NAME",
+	Short: "Set the color theme",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if _, err := resolveTheme(name, cfg.GetThemeColors(), true); err != nil {
+			return usageErrorf("%v", err)
+		}
+		if err := cfg.SetTheme(name); err != nil {
+			return ioError(err)
+		}
+		applyTheme(cfg)
+		fmt.Fprintf(osStdout, "Theme set to %s: %s %s\n", name,
+			colorGreenText("gains"), colorRedText("losses"))
+		return nil
+	},
+}
@@ -3,13 +3,16 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/pretty-andrechal/follyo/internal/config"
+	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/pretty-andrechal/follyo/internal/prices"
+	"github.com/pretty-andrechal/follyo/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -33,17 +36,27 @@ Example: follyo ticker map MUTE mute-io
 This creates a custom mapping that overrides any default mapping.
 Use 'follyo ticker search' to find the correct CoinGecko ID.`,
 	Args: cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		ticker := strings.ToUpper(args[0])
 		geckoID := args[1]
 
-		cfg := loadConfig()
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		previousID := ps.GetCoinGeckoID(ticker)
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
 		if err := cfg.SetTickerMapping(ticker, geckoID); err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return ioError(err)
 		}
 
 		fmt.Printf("Mapped %s -> %s\n", ticker, geckoID)
+		warnMappingConflicts(ps, ticker, geckoID, previousID)
+		return nil
 	},
 }
 
@@ -55,19 +68,20 @@ var tickerUnmapCmd = &cobra.Command{
 If a default mapping exists for this ticker, it will be used instead.
 If no default exists, the ticker will show N/A for prices.`,
 	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		ticker := strings.ToUpper(args[0])
 
-		cfg := loadConfig()
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
 
 		if !cfg.HasTickerMapping(ticker) {
-			fmt.Printf("No custom mapping exists for %s\n", ticker)
-			return
+			return notFoundErrorf("no custom mapping exists for %s", ticker)
 		}
 
 		if err := cfg.RemoveTickerMapping(ticker); err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return ioError(err)
 		}
 
 		// Check if there's a default
@@ -77,6 +91,7 @@ If no default exists, the ticker will show N/A for prices.`,
 		} else {
 			fmt.Printf("Removed mapping for %s\n", ticker)
 		}
+		return nil
 	},
 }
 
@@ -84,10 +99,13 @@ var tickerListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all ticker mappings",
 	Long:  `List all ticker mappings (both default and custom).`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		showAll, _ := cmd.Flags().GetBool("all")
 
-		cfg := loadConfig()
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
 		customMappings := cfg.GetAllTickerMappings()
 		defaultMappings := prices.GetDefaultMappings()
 
@@ -121,7 +139,20 @@ var tickerListCmd = &cobra.Command{
 		fmt.Fprintln(osStdout, "Ticker Mappings:")
 		fmt.Fprintln(osStdout)
 
-		// Show custom mappings first
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+
+		// Show custom mappings first, with the coin names CoinGecko gives
+		// them so a wrong mapping stands out
+		var metadata map[string]prices.CoinMetadata
+		if len(customMappings) > 0 {
+			metadata, err = ps.GetCoinMetadata(sortedStringKeys(customMappings))
+			if err != nil {
+				fmt.Fprintf(osStderr, "Warning: Could not fetch coin names: %v\n", err)
+			}
+		}
 		hasCustom := false
 		for _, ticker := range tickers {
 			m := allMappings[ticker]
@@ -130,7 +161,11 @@ var tickerListCmd = &cobra.Command{
 					fmt.Fprintln(osStdout, "Custom mappings:")
 					hasCustom = true
 				}
-				fmt.Fprintf(osStdout, "  %-8s -> %s\n", ticker, m.geckoID)
+				line := fmt.Sprintf("  %-8s -> %s", ticker, m.geckoID)
+				if md, ok := metadata[ticker]; ok && md.Name != "" {
+					line += fmt.Sprintf(" (%s)", md.Name)
+				}
+				fmt.Fprintln(osStdout, line)
 			}
 		}
 
@@ -138,6 +173,16 @@ var tickerListCmd = &cobra.Command{
 			fmt.Fprintln(osStdout)
 		}
 
+		// Warn about tickers that resolve to the same CoinGecko ID
+		duplicates := ps.GetDuplicateMappings(tickers)
+		if len(duplicates) > 0 {
+			fmt.Fprintln(osStdout, "Warning: tickers sharing a CoinGecko ID:")
+			for _, id := range sortedStringKeys(duplicates) {
+				fmt.Fprintf(osStdout, "  %-20s <- %s\n", id, strings.Join(duplicates[id], ", "))
+			}
+			fmt.Fprintln(osStdout)
+		}
+
 		// Show all default mappings if --all flag is set
 		if showAll {
 			fmt.Fprintln(osStdout, "Default mappings:")
@@ -151,6 +196,107 @@ var tickerListCmd = &cobra.Command{
 			fmt.Fprintf(osStdout, "Default mappings: %d built-in\n", len(defaultMappings))
 			fmt.Fprintln(osStdout, "Use 'follyo ticker list --all' to see all default mappings")
 		}
+		return nil
+	},
+}
+
+var tickerPriceCmd = &cobra.Command{
+	Use:   "price",
+	Short: "Manage manual prices for coins without a live price",
+	Long: `Manage manual USD prices for coins CoinGecko has no price for, such as
+pre-launch tokens, LP positions, or delisted assets.
+
+A manual price is used wherever the portfolio is valued (summary,
+snapshots, dashboard, rebalancing, ...) instead of a live price, and the
+coin is not fetched.`,
+}
+
+var tickerPriceSetCmd = &cobra.Command{
+	Use:   "set TICKER PRICE",
+	Short: "Set a manual USD price for a ticker",
+	Long: `Set a manual USD price for a ticker, dated today or --date.
+
+Example: follyo ticker price set XYZ 0.42 --date 2024-03-01`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ticker := strings.ToUpper(args[0])
+		price, err := strconv.ParseFloat(strings.TrimPrefix(args[1], "$"), 64)
+		if err != nil || price < 0 {
+			return usageErrorf("invalid price: %s", args[1])
+		}
+		dateStr, _ := cmd.Flags().GetString("date")
+		date, err := parseDate(dateStr, "date")
+		if err != nil {
+			return err
+		}
+		if date.IsZero() {
+			date = models.Today()
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.SetManualPrice(ticker, config.ManualPrice{Price: price, Date: date.String()}); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(osStdout, "Set manual price of %s to %s (as of %s)\n", ticker, "$"+formatAmount(price), date)
+		return nil
+	},
+}
+
+var tickerPriceRemoveCmd = &cobra.Command{
+	Use:     "remove TICKER",
+	Aliases: []string{"rm", "unset"},
+	Short:   "Remove the manual price of a ticker",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ticker := strings.ToUpper(args[0])
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemoveManualPrice(ticker)
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("no manual price set for %s", ticker)
+		}
+		fmt.Fprintf(osStdout, "Removed manual price of %s\n", ticker)
+		return nil
+	},
+}
+
+var tickerPriceListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List manual prices",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		manual := cfg.GetManualPrices()
+		if len(manual) == 0 {
+			fmt.Fprintln(osStdout, "No manual prices set.")
+			fmt.Fprintln(osStdout, "Set one with: follyo ticker price set <TICKER> <PRICE>")
+			return nil
+		}
+
+		fmt.Fprintln(osStdout, "Manual prices (USD):")
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		for _, ticker := range sortedStringKeys(manual) {
+			mp := manual[ticker]
+			date := mp.Date
+			if date == "" {
+				date = "-"
+			}
+			fmt.Fprintf(w, "  %s\t%s\tas of %s\n", ticker, "$"+formatAmount(mp.Price), date)
+		}
+		w.Flush()
+		return nil
 	},
 }
 
@@ -160,12 +306,13 @@ var tickerSearchCmd = &cobra.Command{
 	Long: `Search CoinGecko for coins matching the query.
 
 If TICKER is provided, you can interactively select a result to map.
+With --yes the top result is mapped without a prompt.
 
 Examples:
   follyo ticker search bitcoin     # Just search
   follyo ticker search mute MUTE   # Search and map result to MUTE`,
 	Args: cobra.RangeArgs(1, 2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
 		var targetTicker string
 		if len(args) > 1 {
@@ -175,15 +322,15 @@ Examples:
 		fmt.Printf("Searching CoinGecko for \"%s\"...\n\n", query)
 
 		ps := prices.New()
+		ps.SetRetryNotifier(printRetryStatus)
 		results, err := ps.SearchCoins(query)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error: %v\n", err)
-			osExit(1)
+			return ioError(err)
 		}
 
 		if len(results) == 0 {
 			fmt.Println("No results found.")
-			return
+			return nil
 		}
 
 		// Display results
@@ -202,50 +349,274 @@ Examples:
 		// If no ticker specified, just show results
 		if targetTicker == "" {
 			fmt.Println("\nTo map a result, run: follyo ticker search <query> <TICKER>")
-			return
+			return nil
 		}
 
-		// Interactive selection
-		fmt.Printf("\nSelect a result (1-%d) to map to %s, or 0 to cancel: ", len(results), targetTicker)
-
-		reader := bufio.NewReader(osStdin)
-		input, err := reader.ReadString('\n')
+		selection, err := selectSearchResult(len(results), targetTicker)
 		if err != nil {
-			fmt.Fprintf(osStderr, "Error reading input: %v\n", err)
-			osExit(1)
+			return err
 		}
-
-		input = strings.TrimSpace(input)
-		selection, err := strconv.Atoi(input)
-		if err != nil || selection < 0 || selection > len(results) {
-			fmt.Println("Invalid selection.")
-			return
-		}
-
 		if selection == 0 {
 			fmt.Println("Cancelled.")
-			return
+			return nil
 		}
 
 		// Map the selected result
 		selected := results[selection-1]
-		cfg := loadConfig()
+		mapped, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		previousID := mapped.GetCoinGeckoID(targetTicker)
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
 		if err := cfg.SetTickerMapping(targetTicker, selected.ID); err != nil {
-			fmt.Fprintf(osStderr, "Error saving mapping: %v\n", err)
-			osExit(1)
+			return ioError(fmt.Errorf("saving mapping: %w", err))
 		}
 
 		fmt.Printf("\nMapped %s -> %s (%s)\n", targetTicker, selected.ID, selected.Name)
+		warnMappingConflicts(mapped, targetTicker, selected.ID, previousID)
+		return nil
 	},
 }
 
+// selectSearchResult asks which of count search results to map to ticker and
+// returns its 1-based number, or 0 if cancelled. With --yes the top result is
+// chosen; with --non-interactive choosing fails.
+func selectSearchResult(count int, ticker string) (int, error) {
+	if assumeYes {
+		fmt.Printf("\nSelected result 1 (--yes)\n")
+		return 1, nil
+	}
+	if nonInteractive {
+		return 0, usageErrorf("choosing a result requires a prompt; pass --yes to map the top result or use 'follyo ticker map %s ID'", ticker)
+	}
+
+	fmt.Printf("\nSelect a result (1-%d) to map to %s, or 0 to cancel: ", count, ticker)
+	input, err := bufio.NewReader(osStdin).ReadString('\n')
+	if err != nil {
+		return 0, ioError(fmt.Errorf("reading input: %w", err))
+	}
+
+	selection, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || selection < 0 || selection > count {
+		return 0, usageErrorf("invalid selection %q (expected 0-%d)", strings.TrimSpace(input), count)
+	}
+	return selection, nil
+}
+
+// suggestTickerMappings prints a one-line notice to stderr for each portfolio
+// coin without a CoinGecko mapping, including the command that resolves it.
+// Ticker commands are skipped since the user is already managing mappings,
+// as are commands run with --auto-map, which resolve mappings themselves.
+// Coins with a manual price don't need a mapping.
+func suggestTickerMappings(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == tickerCmd {
+			return
+		}
+	}
+	if autoMap, err := cmd.Flags().GetBool("auto-map"); err == nil && autoMap {
+		return
+	}
+	if p == nil {
+		return
+	}
+
+	coins, err := p.GetCoins()
+	if err != nil || len(coins) == 0 {
+		return
+	}
+
+	ps, err := newPriceService()
+	if err != nil {
+		return
+	}
+	for _, coin := range ps.GetUnmappedTickers(coins) {
+		if ps.HasManualPrice(coin) {
+			continue
+		}
+		fmt.Fprintf(osStderr, "Note: %s has no CoinGecko price mapping; run 'follyo ticker search %s %s' to add one\n",
+			coin, strings.ToLower(coin), coin)
+	}
+}
+
+// autoMapTickers maps each coin without a CoinGecko mapping or manual price
+// to its single CoinGecko search match, saving the mapping and applying it
+// to ps. Coins without a single match are left for 'follyo ticker search'.
+func autoMapTickers(ps *prices.PriceService, coins []string) error {
+	var unmapped []string
+	for _, coin := range ps.GetUnmappedTickers(coins) {
+		if !ps.HasManualPrice(coin) {
+			unmapped = append(unmapped, coin)
+		}
+	}
+	if len(unmapped) == 0 {
+		return nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, coin := range unmapped {
+		match, ok, err := ps.ResolveTicker(coin)
+		if err != nil {
+			fmt.Fprintf(osStderr, "Warning: Could not look up %s on CoinGecko: %v\n", coin, err)
+			continue
+		}
+		if !ok {
+			fmt.Fprintf(osStderr, "Note: %s has no single CoinGecko match; run 'follyo ticker search %s %s' to choose one\n",
+				coin, strings.ToLower(coin), coin)
+			continue
+		}
+		if err := cfg.SetTickerMapping(coin, match.ID); err != nil {
+			return ioError(fmt.Errorf("saving mapping: %w", err))
+		}
+		warnMappingConflicts(ps, coin, match.ID, "")
+		ps.AddCoinMapping(coin, match.ID)
+		fmt.Fprintf(osStdout, "Mapped %s -> %s (%s)\n", coin, match.ID, match.Name)
+	}
+	return nil
+}
+
+// warnMappingConflicts prints warnings when a new mapping shares its CoinGecko
+// ID with other tickers or replaces a different existing mapping.
+// ps must reflect the mappings in effect before the change.
+func warnMappingConflicts(ps *prices.PriceService, ticker, geckoID, previousID string) {
+	var others []string
+	for _, t := range ps.FindTickersForID(geckoID) {
+		if t != ticker {
+			others = append(others, t)
+		}
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(osStderr, "Warning: %s is also mapped from %s; these tickers will report the same price\n",
+			geckoID, strings.Join(others, ", "))
+	}
+	if previousID != "" && previousID != geckoID {
+		fmt.Fprintf(osStderr, "Warning: %s was previously mapped to %s; valuations will now use %s\n",
+			ticker, previousID, geckoID)
+	}
+}
+
+// sortedStringKeys returns the sorted keys of a string-keyed map
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+	return keys
+}
+
+// configPath returns the path of the configuration file: $FOLLYO_CONFIG,
+// config.json in $FOLLYO_DATA_DIR when set, or config.json in the config
+// directory (~/.config/follyo)
+func configPath() string {
+	if path := os.Getenv("FOLLYO_CONFIG"); path != "" {
+		return path
+	}
+	if dir := os.Getenv("FOLLYO_DATA_DIR"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join(config.Dir(), "config.json")
+}
+
+// legacyConfigPath returns the path of the YAML config file of older
+// releases, imported into the config file the first time it is found
+var legacyConfigPath = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".follyo", "config.yaml")
+}
+
 // loadConfig loads the configuration from the default path
-func loadConfig() *config.ConfigStore {
-	configPath := filepath.Join("data", "config.json")
-	cfg, err := config.New(configPath)
+func loadConfig() (*config.ConfigStore, error) {
+	if err := migrateLegacyData(); err != nil {
+		return nil, err
+	}
+	if err := migrateConfigFile(); err != nil {
+		return nil, err
+	}
+	cfg, err := config.New(configPath())
 	if err != nil {
-		fmt.Fprintf(osStderr, "Error loading config: %v\n", err)
-		osExit(1)
+		return nil, ioError(fmt.Errorf("loading config: %w", err))
+	}
+	if err := migrateLegacyConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// migrateConfigFile moves config.json from the data directory, where
+// earlier releases kept it, to the config directory. It is left alone when
+// $FOLLYO_CONFIG or $FOLLYO_DATA_DIR picks the file, or the config
+// directory already has one.
+func migrateConfigFile() error {
+	if os.Getenv("FOLLYO_CONFIG") != "" || os.Getenv("FOLLYO_DATA_DIR") != "" {
+		return nil
+	}
+	old, path := filepath.Join(storage.DataDir(), "config.json"), configPath()
+	if old == path {
+		return nil
+	}
+	if _, err := os.Stat(old); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return ioError(err)
+	}
+	if err := os.Rename(old, path); err != nil {
+		// Across file systems, copy the file instead
+		data, err := os.ReadFile(old)
+		if err != nil {
+			return ioError(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return ioError(err)
+		}
+		if err := os.Remove(old); err != nil {
+			return ioError(err)
+		}
+	}
+	os.Remove(old + ".lock")
+	fmt.Fprintf(osStderr, "Moved %s to %s, where follyo now keeps its config\n", old, path)
+	return nil
+}
+
+// migrateLegacyConfig imports the legacy YAML config, if there is one, and
+// renames it so it is only imported once
+func migrateLegacyConfig(cfg *config.ConfigStore) error {
+	legacy := legacyConfigPath()
+	if legacy == "" {
+		return nil
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+
+	imported, unknown, err := cfg.ImportYAML(legacy)
+	if err != nil {
+		return fmt.Errorf("importing legacy config: %w", err)
+	}
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		return ioError(err)
+	}
+	if len(imported) > 0 {
+		fmt.Fprintf(osStderr, "Imported %s from %s into %s\n", strings.Join(imported, ", "), legacy, configPath())
+	}
+	if len(unknown) > 0 {
+		fmt.Fprintf(osStderr, "Skipped unknown settings in %s: %s\n", legacy, strings.Join(unknown, ", "))
 	}
-	return cfg
+	fmt.Fprintf(osStderr, "%s is no longer read and was renamed to %s.migrated\n", legacy, filepath.Base(legacy))
+	return nil
 }
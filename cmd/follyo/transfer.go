@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var transferCmd = &cobra.Command{
+	Use:     "transfer",
+	Aliases: []string{"tr"},
+	Short:   "Manage transfers between platforms",
+}
+
+var transferAddCmd = &cobra.Command{
+	Use:   "add COIN AMOUNT FROM TO",
+	Short: "Record a transfer between platforms",
+	Long: `Record moving crypto from one platform to another.
+
+COIN: The cryptocurrency symbol (e.g., BTC, ETH)
+AMOUNT: Amount sent from the source platform
+FROM: Source platform (e.g., Binance)
+TO: Destination platform (e.g., Ledger)
+
+Use --fee for network or withdrawal fees paid in the coin; AMOUNT - fee
+arrives at the destination. Fees reduce your holdings.`,
+	Args: cobra.ExactArgs(4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		coin := args[0]
+		amount, err := parseDecimal(args[1], "amount")
+		if err != nil {
+			return err
+		}
+		from, err := resolvePlatform(args[2])
+		if err != nil {
+			return err
+		}
+		to, err := resolvePlatform(args[3])
+		if err != nil {
+			return err
+		}
+
+		fee := decimalFlag(cmd, "fee")
+		notes, _ := cmd.Flags().GetString("notes")
+		date, err := dateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+
+		transfer, err := p.AddTransfer(coin, amount, from, to, fee, notes, date, tags...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Transferred %s %s from %s to %s (ID: %s)\n",
+			formatAmount(transfer.Amount.InexactFloat64()), transfer.Coin, transfer.FromPlatform, transfer.ToPlatform, transfer.ID)
+		return nil
+	},
+}
+
+var transferListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all transfers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := p.ListTransfers()
+		if err != nil {
+			return err
+		}
+		tag, _ := cmd.Flags().GetString("tag")
+		var transfers []models.Transfer
+		for _, t := range all {
+			if tag == "" || models.HasTag(t.Tags, tag) {
+				transfers = append(transfers, t)
+			}
+		}
+
+		if len(transfers) == 0 {
+			fmt.Fprintln(osStdout, "No transfers found.")
+			return nil
+		}
+
+		short := shortIDs(idsOf(all, func(t models.Transfer) string { return t.ID }))
+		return printTable(cmd, "transfer", transferColumns(short), transfers, nil)
+	},
+}
+
+var transferRemoveCmd = &cobra.Command{
+	Use:   "remove ID...",
+	Short: "Remove transfers by ID",
+	Long: `Remove one or more transfers.
+
+The records are shown and you are asked once to confirm (skip with
+--yes). Nothing is removed if any ID is unknown.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		records, err := p.ListTransfers()
+		if err != nil {
+			return err
+		}
+		existing := recordDetails(records, func(t models.Transfer) (string, string) {
+			return t.ID, fmt.Sprintf("%s %s moved %s from %s to %s", formatAmount(t.Amount.InexactFloat64()), t.Coin, t.Date, platformLabel(t.FromPlatform), platformLabel(t.ToPlatform))
+		})
+		return removeRecords(args, existing, "transfer", p.RemoveTransfer, func(id string) string {
+			return fmt.Sprintf("Removed transfer %s", id)
+		})
+	},
+}
+
+var platformsCmd = &cobra.Command{
+	Use:     "platforms",
+	Aliases: []string{"pf"},
+	Short:   "Show holdings by platform",
+	Long: `Show current holdings (purchases - sales, adjusted for transfers) on each
+platform. Records without a platform are listed under "-".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		byPlatform, err := p.GetHoldingsByPlatform()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Platform\tCoin\tAmount")
+		rows := 0
+		for _, platform := range sortedStringKeys(byPlatform) {
+			coins := byPlatform[platform]
+			for _, coin := range sortedStringKeys(coins) {
+				if coins[coin].IsZero() {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", platformLabel(platform), coin, formatAmount(coins[coin].InexactFloat64()))
+				rows++
+			}
+		}
+		if rows == 0 {
+			fmt.Fprintln(osStdout, "No holdings found.")
+			return nil
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// platformLabel returns the platform name, or "-" if it is empty
+func platformLabel(platform string) string {
+	if platform == "" {
+		return "-"
+	}
+	return platform
+}
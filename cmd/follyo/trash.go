@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage removed records",
+	Long: `Removed purchases, sales, loans, and stakes are moved to the trash and
+can be restored. They are deleted permanently after 30 days; set
+"trash_retention_days" in config.json to change this, or to -1 to
+keep them forever.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List removed records",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		items, err := p.ListTrash()
+		if err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			fmt.Fprintln(osStdout, "Trash is empty.")
+			return nil
+		}
+
+		short := shortIDs(idsOf(items, func(item models.TrashItem) string { return item.ID }))
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tType\tCoin\tAmount\tRemoved")
+		for _, item := range items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				short(item.ID), item.Type, item.Coin, formatAmount(item.Amount.InexactFloat64()),
+				item.DeletedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore ID",
+	Short: "Restore a removed record by ID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trash, err := p.ListTrash()
+		if err != nil {
+			return err
+		}
+		id, err := resolveID("record", args[0], idsOf(trash, func(item models.TrashItem) string { return item.ID }))
+		if exitCode(err) == exitNotFound {
+			return notFoundErrorf("%s not found in trash", args[0])
+		} else if err != nil {
+			return err
+		}
+		item, restored, err := p.RestoreTrash(id)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return notFoundErrorf("%s not found in trash", id)
+		}
+		fmt.Fprintf(osStdout, "Restored %s %s (%s %s)\n", item.Type, id, formatAmount(item.Amount.InexactFloat64()), item.Coin)
+		return nil
+	},
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Manage the watchlist",
+	Long: `Follow coins you don't hold yet. Watched coins are stored in
+config.json and never affect portfolio totals.`,
+}
+
+var watchAddCmd = &cobra.Command{
+	Use:   "add TICKER",
+	Short: "Add a coin to the watchlist",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ticker := strings.ToUpper(args[0])
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		added, err := cfg.AddToWatchlist(ticker)
+		if err != nil {
+			return ioError(err)
+		}
+		if !added {
+			fmt.Fprintf(osStdout, "%s is already on the watchlist\n", ticker)
+			return nil
+		}
+		fmt.Fprintf(osStdout, "Watching %s\n", ticker)
+		return nil
+	},
+}
+
+var watchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List watched coins with live market data",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		tickers := cfg.GetWatchlist()
+		if len(tickers) == 0 {
+			fmt.Fprintln(osStdout, "Watchlist is empty. Add a coin with 'follyo watch add TICKER'.")
+			return nil
+		}
+
+		ps, err := newPriceService()
+		if err != nil {
+			return err
+		}
+		market, err := ps.GetMarketData(tickers)
+		if err != nil {
+			return ioError(err)
+		}
+
+		w := tabwriter.NewWriter(osStdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Coin\tPrice\t24h\tMarket Cap")
+		for _, ticker := range tickers {
+			md, ok := market[ticker]
+			if !ok {
+				fmt.Fprintf(w, "%s\tN/A\tN/A\tN/A\n", ticker)
+				continue
+			}
+			change := fmt.Sprintf("%+.2f%%", md.Change24h)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				ticker, formatUSD(md.Price), colorByValue(change, md.Change24h), formatCompactUSD(md.MarketCap))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var watchRemoveCmd = &cobra.Command{
+	Use:   "remove TICKER",
+	Short: "Remove a coin from the watchlist",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ticker := strings.ToUpper(args[0])
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cfg.RemoveFromWatchlist(ticker)
+		if err != nil {
+			return ioError(err)
+		}
+		if !removed {
+			return notFoundErrorf("%s is not on the watchlist", ticker)
+		}
+		fmt.Fprintf(osStdout, "Stopped watching %s\n", ticker)
+		return nil
+	},
+}
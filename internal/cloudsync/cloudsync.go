@@ -0,0 +1,216 @@
+// Package cloudsync keeps data files in step with a copy on a git remote or
+// an S3-compatible bucket. Changes are detected by comparing file hashes
+// with the version last synced, and files changed on both sides are
+// combined with a three-way merge of their records.
+package cloudsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+)
+
+// Remote stores copies of data files by name.
+type Remote interface {
+	// Fetch returns the remote contents of the named files. Files missing
+	// remotely are left out.
+	Fetch(names []string) (map[string][]byte, error)
+	// Store replaces the remote contents of the given files.
+	Store(files map[string][]byte) error
+}
+
+// Action is what a sync did with a file.
+type Action string
+
+const (
+	Unchanged  Action = "unchanged"  // Same locally and remotely
+	Uploaded   Action = "uploaded"   // Local changes sent to the remote
+	Downloaded Action = "downloaded" // Remote changes written locally
+	Merged     Action = "merged"     // Changed on both sides and merged
+	Ahead      Action = "ahead"      // Local changes not yet pushed
+)
+
+// Result is the outcome of syncing one file.
+type Result struct {
+	File   string
+	Action Action
+}
+
+// Syncer syncs files in a directory with a remote. The version of each file
+// last synced is kept in a .sync subdirectory as the base of merges.
+type Syncer struct {
+	remote Remote
+	dir    string
+	files  []string
+}
+
+// New creates a Syncer for the named files in dir.
+func New(remote Remote, dir string, files []string) *Syncer {
+	return &Syncer{remote: remote, dir: dir, files: files}
+}
+
+// StateDir returns the directory sync state is kept in for a data directory.
+func StateDir(dir string) string {
+	return filepath.Join(dir, ".sync")
+}
+
+// Push brings local and remote files in step: remote changes are merged
+// into the local files first, then local changes are uploaded. Nothing is
+// written if any file has conflicting changes.
+func (s *Syncer) Push() ([]Result, error) {
+	return s.sync(true)
+}
+
+// Pull writes remote changes to the local files, merging them with local
+// changes. The remote is not modified. Nothing is written if any file has
+// conflicting changes.
+func (s *Syncer) Pull() ([]Result, error) {
+	return s.sync(false)
+}
+
+// plan is the resolved content of a file
+type plan struct {
+	result Result
+	local  []byte // New local content; nil leaves the file as is
+	upload []byte // Content to upload; nil uploads nothing
+	base   []byte // New base; nil keeps the old one
+}
+
+func (s *Syncer) sync(push bool) ([]Result, error) {
+	remote, err := s.remote.Fetch(s.files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve every file before writing any, so a conflict changes nothing
+	var plans []plan
+	for _, name := range s.files {
+		local, err := readOptional(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		base, err := readOptional(s.basePath(name))
+		if err != nil {
+			return nil, err
+		}
+		pl, err := resolve(name, base, local, remote[name], push)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, pl)
+	}
+
+	for _, pl := range plans {
+		if pl.local != nil {
+			if err := writeAtomic(filepath.Join(s.dir, pl.result.File), pl.local); err != nil {
+				return nil, err
+			}
+		}
+	}
+	uploads := make(map[string][]byte)
+	for _, pl := range plans {
+		if pl.upload != nil {
+			uploads[pl.result.File] = pl.upload
+		}
+	}
+	if len(uploads) > 0 {
+		if err := s.remote.Store(uploads); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]Result, len(plans))
+	for i, pl := range plans {
+		if pl.base != nil {
+			if err := writeAtomic(s.basePath(pl.result.File), pl.base); err != nil {
+				return nil, err
+			}
+		}
+		results[i] = pl.result
+	}
+	return results, nil
+}
+
+// resolve decides what to do with one file from its base, local, and remote
+// contents, any of which may be nil when missing.
+func resolve(name string, base, local, remote []byte, push bool) (plan, error) {
+	pl := plan{result: Result{File: name, Action: Unchanged}}
+	switch {
+	case local == nil && remote == nil:
+		return pl, nil
+	case sameHash(local, remote):
+		pl.base = local
+		return pl, nil
+	case local == nil:
+		// Missing locally, e.g. on a new machine
+		pl.result.Action = Downloaded
+		pl.local, pl.base = remote, remote
+		return pl, nil
+	case remote == nil || sameHash(remote, base):
+		// Only changed locally
+		if !push {
+			pl.result.Action = Ahead
+			return pl, nil
+		}
+		pl.result.Action = Uploaded
+		pl.upload, pl.base = local, local
+		return pl, nil
+	case sameHash(local, base):
+		// Only changed remotely
+		pl.result.Action = Downloaded
+		pl.local, pl.base = remote, remote
+		return pl, nil
+	}
+
+	merged, err := Merge(name, base, local, remote)
+	if err != nil {
+		return plan{}, err
+	}
+	pl.result.Action = Merged
+	pl.local = merged
+	pl.base = remote
+	if push {
+		pl.upload, pl.base = merged, merged
+	}
+	return pl, nil
+}
+
+func (s *Syncer) basePath(name string) string {
+	return filepath.Join(StateDir(s.dir), "base", name)
+}
+
+// sameHash compares file contents by SHA-256. A missing file only matches
+// another missing file.
+func sameHash(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ha, hb := sha256.Sum256(a), sha256.Sum256(b)
+	return bytes.Equal(ha[:], hb[:])
+}
+
+// readOptional reads a file, returning nil if it does not exist
+func readOptional(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if data == nil && err == nil {
+		data = []byte{}
+	}
+	return data, err
+}
+
+// writeAtomic writes data to path through a temporary file, so readers never
+// see a partial file
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
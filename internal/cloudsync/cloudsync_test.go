@@ -0,0 +1,213 @@
+package cloudsync
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoryRemote keeps files in memory
+type memoryRemote struct {
+	files  map[string][]byte
+	stores int
+}
+
+func (m *memoryRemote) Fetch(names []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, name := range names {
+		if data, ok := m.files[name]; ok {
+			files[name] = data
+		}
+	}
+	return files, nil
+}
+
+func (m *memoryRemote) Store(files map[string][]byte) error {
+	m.stores++
+	for name, data := range files {
+		m.files[name] = data
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func actions(results []Result) map[string]Action {
+	m := make(map[string]Action)
+	for _, r := range results {
+		m[r.File] = r.Action
+	}
+	return m
+}
+
+func TestSyncer_PushPull(t *testing.T) {
+	remote := &memoryRemote{files: make(map[string][]byte)}
+	laptop, desktop := t.TempDir(), t.TempDir()
+	files := []string{"portfolio.json"}
+
+	writeFile(t, laptop, "portfolio.json", `{"holdings": [{"id": "a"}]}`)
+	results, err := New(remote, laptop, files).Push()
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if actions(results)["portfolio.json"] != Uploaded {
+		t.Errorf("expected upload, got %v", results)
+	}
+
+	// A new machine downloads the file
+	results, err = New(remote, desktop, files).Pull()
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if actions(results)["portfolio.json"] != Downloaded || readFile(t, desktop, "portfolio.json") != `{"holdings": [{"id": "a"}]}` {
+		t.Errorf("expected download, got %v", results)
+	}
+
+	// Both add a record; the second push merges them
+	writeFile(t, laptop, "portfolio.json", `{"holdings": [{"id": "a"}, {"id": "b"}]}`)
+	writeFile(t, desktop, "portfolio.json", `{"holdings": [{"id": "a"}, {"id": "c"}]}`)
+	if _, err := New(remote, laptop, files).Push(); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	results, err = New(remote, desktop, files).Push()
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if actions(results)["portfolio.json"] != Merged {
+		t.Errorf("expected merge, got %v", results)
+	}
+	want := map[string][]string{"holdings": {"a", "c", "b"}}
+	if got := ids(t, remote.files["portfolio.json"]); !equalIDs(got, want) {
+		t.Errorf("expected remote %v, got %v", want, got)
+	}
+
+	// Pulling the merge only downloads
+	results, err = New(remote, laptop, files).Pull()
+	if err != nil || actions(results)["portfolio.json"] != Downloaded {
+		t.Fatalf("expected download, got %v, %v", results, err)
+	}
+	stores := remote.stores
+	results, err = New(remote, laptop, files).Push()
+	if err != nil || actions(results)["portfolio.json"] != Unchanged || remote.stores != stores {
+		t.Errorf("expected nothing to push, got %v, %v", results, err)
+	}
+}
+
+func equalIDs(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if strings.Join(v, ",") != strings.Join(b[k], ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSyncer_PullKeepsLocalChanges(t *testing.T) {
+	remote := &memoryRemote{files: make(map[string][]byte)}
+	dir := t.TempDir()
+	files := []string{"portfolio.json"}
+
+	writeFile(t, dir, "portfolio.json", `{"holdings": []}`)
+	New(remote, dir, files).Push()
+	writeFile(t, dir, "portfolio.json", `{"holdings": [{"id": "a"}]}`)
+
+	results, err := New(remote, dir, files).Pull()
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if actions(results)["portfolio.json"] != Ahead {
+		t.Errorf("expected local changes ahead, got %v", results)
+	}
+	if readFile(t, dir, "portfolio.json") != `{"holdings": [{"id": "a"}]}` {
+		t.Error("expected local changes kept")
+	}
+}
+
+func TestSyncer_ConflictWritesNothing(t *testing.T) {
+	remote := &memoryRemote{files: make(map[string][]byte)}
+	dir := t.TempDir()
+	files := []string{"portfolio.json", "snapshots.json"}
+
+	writeFile(t, dir, "portfolio.json", `{"loans": [{"id": "a", "amount": 1}]}`)
+	writeFile(t, dir, "snapshots.json", `{"snapshots": []}`)
+	New(remote, dir, files).Push()
+
+	writeFile(t, dir, "portfolio.json", `{"loans": [{"id": "a", "amount": 2}]}`)
+	remote.files["portfolio.json"] = []byte(`{"loans": [{"id": "a", "amount": 3}]}`)
+	remote.files["snapshots.json"] = []byte(`{"snapshots": [{"id": "s"}]}`)
+
+	_, err := New(remote, dir, files).Pull()
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if readFile(t, dir, "snapshots.json") != `{"snapshots": []}` {
+		t.Error("expected no files written on conflict")
+	}
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+
+	laptop, desktop := t.TempDir(), t.TempDir()
+	files := []string{"portfolio.json"}
+	newGit := func(dir string) *Git {
+		return NewGit(bare, "", filepath.Join(StateDir(dir), "git"))
+	}
+
+	writeFile(t, laptop, "portfolio.json", `{"holdings": [{"id": "a"}]}`)
+	if _, err := New(newGit(laptop), laptop, files).Push(); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	results, err := New(newGit(desktop), desktop, files).Pull()
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if actions(results)["portfolio.json"] != Downloaded {
+		t.Errorf("expected download, got %v", results)
+	}
+
+	writeFile(t, desktop, "portfolio.json", `{"holdings": [{"id": "a"}, {"id": "b"}]}`)
+	if _, err := New(newGit(desktop), desktop, files).Push(); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	results, err = New(newGit(laptop), laptop, files).Pull()
+	if err != nil || actions(results)["portfolio.json"] != Downloaded {
+		t.Fatalf("expected download, got %v, %v", results, err)
+	}
+	if got := ids(t, []byte(readFile(t, laptop, "portfolio.json"))); len(got["holdings"]) != 2 {
+		t.Errorf("expected both holdings, got %v", got)
+	}
+
+	out, err := exec.Command("git", "--git-dir", bare, "log", "--oneline", DefaultBranch).CombinedOutput()
+	if err != nil || strings.Count(string(out), "\n") != 2 {
+		t.Errorf("expected 2 commits on %s, got %v: %s", DefaultBranch, err, out)
+	}
+}
@@ -0,0 +1,160 @@
+package cloudsync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultBranch is the branch files are synced on when none is configured.
+const DefaultBranch = "main"
+
+// Git keeps files in a branch of a git repository, using the git command
+// with a working copy of its own. Authentication is left to git, e.g. SSH
+// keys or a credential helper.
+type Git struct {
+	url     string
+	branch  string
+	workDir string
+}
+
+// NewGit creates a remote for the repository at url (anything git clone
+// accepts) with a working copy in workDir. An empty branch uses
+// DefaultBranch.
+func NewGit(url, branch, workDir string) *Git {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	return &Git{url: url, branch: branch, workDir: workDir}
+}
+
+// Fetch updates the working copy to the remote branch and returns the named
+// files from it. A repository without the branch has no files.
+func (g *Git) Fetch(names []string) (map[string][]byte, error) {
+	if err := g.init(); err != nil {
+		return nil, err
+	}
+	if _, err := g.run("fetch", "origin"); err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	remoteRef := "refs/remotes/origin/" + g.branch
+	if _, err := g.run("rev-parse", "--verify", "--quiet", remoteRef); err != nil {
+		return files, nil // Nothing pushed yet
+	}
+	if _, err := g.run("checkout", "--quiet", "-B", g.branch, remoteRef); err != nil {
+		return nil, err
+	}
+	if _, err := g.run("reset", "--quiet", "--hard", remoteRef); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		data, err := readOptional(filepath.Join(g.workDir, name))
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			files[name] = data
+		}
+	}
+	return files, nil
+}
+
+// Store commits the files and pushes them to the remote branch. The push
+// fails if the branch moved since Fetch.
+func (g *Git) Store(files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name, data := range files {
+		if err := writeAtomic(filepath.Join(g.workDir, name), data); err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if _, err := g.run(append([]string{"add", "--"}, names...)...); err != nil {
+		return err
+	}
+	if _, err := g.run("diff", "--cached", "--quiet"); err == nil {
+		return nil // Already committed
+	}
+	host, _ := os.Hostname()
+	message := fmt.Sprintf("follyo sync: %s", strings.Join(names, ", "))
+	if host != "" {
+		message += " from " + host
+	}
+	if _, err := g.runEnv(g.identity(), "commit", "--quiet", "-m", message); err != nil {
+		return err
+	}
+	_, err := g.run("push", "--quiet", "origin", "HEAD:refs/heads/"+g.branch)
+	return err
+}
+
+// init creates the working copy with the remote as origin if needed
+func (g *Git) init() error {
+	if _, err := os.Stat(filepath.Join(g.workDir, ".git")); err == nil {
+		_, err := g.run("remote", "set-url", "origin", g.url)
+		return err
+	}
+	if err := os.MkdirAll(g.workDir, 0755); err != nil {
+		return err
+	}
+	if _, err := g.run("init", "--quiet"); err != nil {
+		return err
+	}
+	_, err := g.run("remote", "add", "origin", g.url)
+	return err
+}
+
+// run runs git in the working copy, returning its output
+func (g *Git) run(args ...string) (string, error) {
+	return g.runEnv(nil, args...)
+}
+
+// runEnv runs git in the working copy with extra environment variables
+func (g *Git) runEnv(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.workDir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+// identity returns environment variables giving commits a fallback author
+// when git has no identity configured
+func (g *Git) identity() []string {
+	var env []string
+	for _, id := range []struct{ key, fallback, author, committer string }{
+		{"user.name", "follyo", "GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"},
+		{"user.email", "follyo@localhost", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"},
+	} {
+		if gitConfig(g.workDir, id.key) != "" {
+			continue
+		}
+		if os.Getenv(id.author) == "" {
+			env = append(env, id.author+"="+id.fallback)
+		}
+		if os.Getenv(id.committer) == "" {
+			env = append(env, id.committer+"="+id.fallback)
+		}
+	}
+	return env
+}
+
+// gitConfig returns a git setting, or "" when it is not set
+func gitConfig(dir, key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	out, _ := cmd.Output()
+	return strings.TrimSpace(string(out))
+}
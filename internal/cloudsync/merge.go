@@ -0,0 +1,199 @@
+package cloudsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConflictError reports records changed differently on both sides of a
+// merge, which cannot be combined automatically.
+type ConflictError struct {
+	File string
+	IDs  []string // Conflicting record IDs, or field names for non-record values
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s changed on both sides: %s", e.File, strings.Join(e.IDs, ", "))
+}
+
+// Merge combines local and remote versions of a JSON data file that both
+// descend from base. Files are objects whose record lists (arrays of objects
+// with an "id") are merged by ID: records added on either side are kept,
+// records removed on one side and unchanged on the other are dropped. Any
+// other value must change on at most one side. A nil base merges two
+// unrelated files, keeping records from both.
+func Merge(name string, base, local, remote []byte) ([]byte, error) {
+	var b, l, r map[string]json.RawMessage
+	for _, side := range []struct {
+		data []byte
+		into *map[string]json.RawMessage
+	}{{base, &b}, {local, &l}, {remote, &r}} {
+		*side.into = make(map[string]json.RawMessage)
+		if len(side.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(side.data, side.into); err != nil {
+			return nil, fmt.Errorf("%s is not a JSON object: %w", name, err)
+		}
+	}
+
+	merged := make(map[string]json.RawMessage)
+	var conflicts []string
+	for _, key := range unionKeys(b, l, r) {
+		bv, lv, rv := b[key], l[key], r[key]
+		if bl, ll, rl, ok := recordLists(bv, lv, rv); ok {
+			list, ids := mergeRecords(bl, ll, rl)
+			conflicts = append(conflicts, ids...)
+			data, err := json.Marshal(list)
+			if err != nil {
+				return nil, err
+			}
+			merged[key] = data
+			continue
+		}
+
+		value, ok := mergeValue(bv, lv, rv)
+		if !ok {
+			conflicts = append(conflicts, key)
+			continue
+		}
+		if value != nil {
+			merged[key] = value
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{File: name, IDs: conflicts}
+	}
+	return json.MarshalIndent(merged, "", "  ")
+}
+
+// mergeValue merges a single value that changed on at most one side. A nil
+// value is absent on that side.
+func mergeValue(base, local, remote json.RawMessage) (json.RawMessage, bool) {
+	switch {
+	case sameJSON(local, remote), sameJSON(remote, base):
+		return local, true
+	case sameJSON(local, base):
+		return remote, true
+	}
+	return nil, false
+}
+
+// record is an element of a record list with its ID
+type record struct {
+	id   string
+	data json.RawMessage
+}
+
+// recordLists decodes values that are all record lists (or absent). It
+// reports false if any value is something else.
+func recordLists(values ...json.RawMessage) ([]record, []record, []record, bool) {
+	lists := make([][]record, len(values))
+	for i, v := range values {
+		if len(v) == 0 || string(v) == "null" {
+			continue
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(v, &elems); err != nil {
+			return nil, nil, nil, false
+		}
+		for _, e := range elems {
+			var withID struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(e, &withID); err != nil || withID.ID == "" {
+				return nil, nil, nil, false
+			}
+			lists[i] = append(lists[i], record{id: withID.ID, data: e})
+		}
+	}
+	return lists[0], lists[1], lists[2], true
+}
+
+// mergeRecords merges record lists by ID, keeping local order with records
+// added remotely appended. It returns the merged list and the IDs of
+// conflicting records.
+func mergeRecords(base, local, remote []record) ([]json.RawMessage, []string) {
+	byID := func(list []record) map[string]json.RawMessage {
+		m := make(map[string]json.RawMessage)
+		for _, r := range list {
+			m[r.id] = r.data
+		}
+		return m
+	}
+	b, l, r := byID(base), byID(local), byID(remote)
+
+	merged := []json.RawMessage{}
+	var conflicts []string
+	for _, rec := range local {
+		bv, inBase := b[rec.id]
+		rv, inRemote := r[rec.id]
+		switch {
+		case !inRemote && !inBase:
+			merged = append(merged, rec.data) // Added locally
+		case !inRemote:
+			// Removed remotely: dropped unless changed locally
+			if !sameJSON(rec.data, bv) {
+				conflicts = append(conflicts, rec.id)
+			}
+		default:
+			value, ok := mergeValue(bv, rec.data, rv)
+			if !ok {
+				conflicts = append(conflicts, rec.id)
+				continue
+			}
+			merged = append(merged, value)
+		}
+	}
+	for _, rec := range remote {
+		if _, inLocal := l[rec.id]; inLocal {
+			continue
+		}
+		bv, inBase := b[rec.id]
+		switch {
+		case !inBase:
+			merged = append(merged, rec.data) // Added remotely
+		case !sameJSON(rec.data, bv):
+			// Removed locally but changed remotely
+			conflicts = append(conflicts, rec.id)
+		}
+	}
+	return merged, conflicts
+}
+
+// sameJSON reports whether two JSON values are equal, ignoring formatting
+// and object key order. Absent values are only equal to each other.
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	ac, _ := json.Marshal(av)
+	bc, _ := json.Marshal(bv)
+	return bytes.Equal(ac, bc)
+}
+
+// unionKeys returns the keys of all maps, sorted
+func unionKeys(maps ...map[string]json.RawMessage) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
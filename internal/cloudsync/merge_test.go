@@ -0,0 +1,126 @@
+package cloudsync
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// ids returns the record IDs of each list in a merged file
+func ids(t *testing.T, data []byte) map[string][]string {
+	t.Helper()
+	var file map[string][]struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("merged file is not record lists: %v\n%s", err, data)
+	}
+	result := make(map[string][]string)
+	for key, list := range file {
+		for _, r := range list {
+			result[key] = append(result[key], r.ID)
+		}
+	}
+	return result
+}
+
+func TestMerge_AddsAndRemoves(t *testing.T) {
+	base := []byte(`{"holdings": [{"id": "a", "amount": 1}, {"id": "b", "amount": 2}], "sales": []}`)
+	local := []byte(`{"holdings": [{"id": "a", "amount": 1}, {"id": "c", "amount": 3}], "sales": []}`)
+	remote := []byte(`{"holdings": [{"id": "a", "amount": 1}, {"id": "b", "amount": 2}], "sales": [{"id": "s"}]}`)
+
+	merged, err := Merge("portfolio.json", base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	want := map[string][]string{"holdings": {"a", "c"}, "sales": {"s"}}
+	if got := ids(t, merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMerge_ChangedOnOneSide(t *testing.T) {
+	base := []byte(`{"version": 1, "stakes": [{"id": "a", "amount": 5}]}`)
+	local := []byte(`{"version": 1, "stakes": [{"id": "a", "amount": 5}, {"id": "b", "amount": 1}]}`)
+	remote := []byte(`{"version": 2, "stakes": [{"amount": 3, "id": "a"}]}`)
+
+	merged, err := Merge("portfolio.json", base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	var file struct {
+		Version int `json:"version"`
+		Stakes  []struct {
+			ID     string  `json:"id"`
+			Amount float64 `json:"amount"`
+		} `json:"stakes"`
+	}
+	json.Unmarshal(merged, &file)
+	if file.Version != 2 || len(file.Stakes) != 2 || file.Stakes[0].Amount != 3 || file.Stakes[1].ID != "b" {
+		t.Errorf("expected version 2 with the remote change and the local stake, got %s", merged)
+	}
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, local, remote string
+		want                []string
+	}{
+		{
+			"changed on both sides",
+			`{"loans": [{"id": "a", "amount": 1}]}`,
+			`{"loans": [{"id": "a", "amount": 2}]}`,
+			`{"loans": [{"id": "a", "amount": 3}]}`,
+			[]string{"a"},
+		},
+		{
+			"removed locally, changed remotely",
+			`{"loans": [{"id": "a", "amount": 1}]}`,
+			`{"loans": []}`,
+			`{"loans": [{"id": "a", "amount": 3}]}`,
+			[]string{"a"},
+		},
+		{
+			"same ID added differently without a base",
+			``,
+			`{"loans": [{"id": "a", "amount": 2}]}`,
+			`{"loans": [{"id": "a", "amount": 3}]}`,
+			[]string{"a"},
+		},
+		{
+			"other values",
+			`{"version": 1}`,
+			`{"version": 2}`,
+			`{"version": 3}`,
+			[]string{"version"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Merge("portfolio.json", []byte(tt.base), []byte(tt.local), []byte(tt.remote))
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("expected a ConflictError, got %v", err)
+			}
+			if !reflect.DeepEqual(conflict.IDs, tt.want) {
+				t.Errorf("expected conflicts %v, got %v", tt.want, conflict.IDs)
+			}
+		})
+	}
+}
+
+func TestMerge_NoBaseKeepsBoth(t *testing.T) {
+	local := []byte(`{"snapshots": [{"id": "a"}, {"id": "b"}]}`)
+	remote := []byte(`{"snapshots": [{"id": "b"}, {"id": "c"}]}`)
+
+	merged, err := Merge("snapshots.json", nil, local, remote)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	want := map[string][]string{"snapshots": {"a", "b", "c"}}
+	if got := ids(t, merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
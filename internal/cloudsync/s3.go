@@ -0,0 +1,176 @@
+package cloudsync
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is the region requests are signed for when none is
+// configured. Most S3-compatible services accept it.
+const DefaultRegion = "us-east-1"
+
+// S3 keeps files as objects in an S3-compatible bucket, addressed in path
+// style (endpoint/bucket/key) and signed with AWS Signature Version 4.
+type S3 struct {
+	client    *http.Client
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	now       func() time.Time
+}
+
+// NewS3 creates a remote for objects named prefix+file in bucket at
+// endpoint, e.g. https://s3.us-east-1.amazonaws.com. An empty region uses
+// DefaultRegion.
+func NewS3(endpoint, region, bucket, prefix, accessKey, secretKey string) *S3 {
+	if region == "" {
+		region = DefaultRegion
+	}
+	return &S3{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		prefix:    prefix,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		now:       time.Now,
+	}
+}
+
+// Fetch downloads the named objects. Missing objects are left out.
+func (s *S3) Fetch(names []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, name := range names {
+		resp, err := s.do(http.MethodGet, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			files[name] = data
+		case http.StatusNotFound:
+		default:
+			return nil, s3Error("GET", name, resp.StatusCode, data)
+		}
+	}
+	return files, nil
+}
+
+// Store uploads the files as objects.
+func (s *S3) Store(files map[string][]byte) error {
+	for name, data := range files {
+		resp, err := s.do(http.MethodPut, name, data)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return s3Error("PUT", name, resp.StatusCode, body)
+		}
+	}
+	return nil
+}
+
+// do sends a signed request for the object holding a file
+func (s *S3) do(method, name string, body []byte) (*http.Response, error) {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	u = u.JoinPath(s.bucket, s.prefix+name)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, body, s.accessKey, s.secretKey, s.region, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// s3Error describes a failed S3 request, including the error code from the
+// response body when there is one
+func s3Error(method, name string, status int, body []byte) error {
+	msg := http.StatusText(status)
+	if _, rest, ok := strings.Cut(string(body), "<Code>"); ok {
+		if code, _, ok := strings.Cut(rest, "</Code>"); ok {
+			msg = code
+		}
+	}
+	return fmt.Errorf("S3 %s %s: %d %s", method, name, status, msg)
+}
+
+// signV4 signs req for the s3 service with AWS Signature Version 4. Every
+// header already set on req is signed, along with host, x-amz-date, and
+// x-amz-content-sha256.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	day := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,31 +2,154 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/storage"
 )
 
+// SchemaVersion is the version of the config file format
+const SchemaVersion = 1
+
 // Config holds application configuration
 type Config struct {
-	TickerMappings map[string]string `json:"ticker_mappings"`
+	TickerMappings   map[string]string      `json:"ticker_mappings"`
+	DisplayCurrency  string                 `json:"display_currency,omitempty"`
+	SnapshotInterval string                 `json:"snapshot_interval,omitempty"`     // Daemon interval, e.g. "6h"
+	SnapshotTime     string                 `json:"snapshot_time,omitempty"`         // Daemon daily time, "HH:MM"
+	AutoSnapshot     bool                   `json:"auto_snapshot,omitempty"`         // Save a daily snapshot whenever prices are fetched
+	InterestMethod   string                 `json:"interest_method,omitempty"`       // Loan interest: "simple" or "compound"
+	Storage          string                 `json:"storage,omitempty"`               // Storage backend: "json" or "sqlite"
+	TrashRetention   int                    `json:"trash_retention_days,omitempty"`  // Days removed records are kept; negative keeps them forever
+	Portfolios       map[string]string      `json:"portfolios,omitempty"`            // Named portfolios: name -> data directory
+	Watchlist        []string               `json:"watchlist,omitempty"`             // Tickers followed without being held
+	Platforms        []string               `json:"platforms,omitempty"`             // Favorite platforms suggested before those in records
+	PlatformAliases  map[string]string      `json:"platform_aliases,omitempty"`      // Short platform names by lowercase alias, e.g. "cb" -> "Coinbase"
+	Exchanges        map[string]APIKey      `json:"exchanges,omitempty"`             // Read-only exchange API keys by exchange name
+	Notifications    *Notifications         `json:"notifications,omitempty"`         // Where and when to send notifications
+	PriceAlerts      []PriceAlert           `json:"price_alerts,omitempty"`          // One-off price alerts, removed once triggered
+	DCAPlans         []DCAPlan              `json:"dca_plans,omitempty"`             // Recurring purchases made by 'follyo dca run'
+	DefaultView      string                 `json:"default_view,omitempty"`          // Shown by follyo without a command: "help" or "dashboard"
+	Theme            string                 `json:"theme,omitempty"`                 // Color theme name, e.g. "dark", "light", or "custom"
+	ThemeColors      map[string]string      `json:"theme_colors,omitempty"`          // Colors of the custom theme by role ("gain", "loss")
+	AllowFutureDates bool                   `json:"allow_future_dates,omitempty"`    // Accept records dated after today
+	Goals            *Goals                 `json:"goals,omitempty"`                 // Targets the summary tracks progress toward
+	Sync             *Sync                  `json:"sync,omitempty"`                  // Where 'follyo sync' pushes and pulls data
+	PriceAttempts    int                    `json:"price_max_attempts,omitempty"`    // Tries per price request when rate limited or failing
+	ManualPrices     map[string]ManualPrice `json:"manual_prices,omitempty"`         // Prices of coins without a live price, by ticker
+	PegThreshold     float64                `json:"peg_threshold_percent,omitempty"` // Stablecoin deviation from $1 that warns; negative disables
+	DataDir          string                 `json:"data_dir,omitempty"`              // Directory of the default portfolio, "data" by default
+	DefaultPlatform  string                 `json:"default_platform,omitempty"`      // Platform of new records added without --platform
+	PriceCacheTTL    string                 `json:"price_cache_ttl,omitempty"`       // How long live prices are reused, e.g. "5m"
+	RefreshInterval  string                 `json:"refresh_interval,omitempty"`      // How often summary and dashboard redraw, e.g. "5m"; off when empty
+	ListColumns      map[string][]string    `json:"list_columns,omitempty"`          // Columns shown by list commands, by command, e.g. "buy" -> ["id", "coin", "notes"]
+	BaselineSnapshot string                 `json:"baseline_snapshot,omitempty"`     // Snapshot ID or pinned name that summary and dashboard P/L is measured from
+}
+
+// ManualPrice is a USD price set by hand for a coin with no live price,
+// e.g. a pre-launch token or delisted asset
+type ManualPrice struct {
+	Price float64 `json:"price"`
+	Date  string  `json:"date,omitempty"` // When the price was observed, YYYY-MM-DD
+}
+
+// Sync configures the remote the data directory is synced with
+type Sync struct {
+	Backend   string `json:"backend"`              // "git" or "s3"
+	URL       string `json:"url,omitempty"`        // Git remote URL
+	Branch    string `json:"branch,omitempty"`     // Git branch, "main" by default
+	Endpoint  string `json:"endpoint,omitempty"`   // S3 endpoint, e.g. https://s3.us-east-1.amazonaws.com
+	Region    string `json:"region,omitempty"`     // S3 region, "us-east-1" by default
+	Bucket    string `json:"bucket,omitempty"`     // S3 bucket
+	Prefix    string `json:"prefix,omitempty"`     // Prefix of S3 object names, e.g. "follyo/"
+	AccessKey string `json:"access_key,omitempty"` // S3 access key; AWS_ACCESS_KEY_ID when empty
+	SecretKey string `json:"secret_key,omitempty"` // S3 secret key; AWS_SECRET_ACCESS_KEY when empty
+}
+
+// Goals are a target net value and a target allocation of holdings value
+type Goals struct {
+	TargetValue float64            `json:"target_value,omitempty"`      // Net value to reach, in USD
+	Allocation  map[string]float64 `json:"target_allocation,omitempty"` // Percent of holdings value by coin
+}
+
+// Notifications configures where event notifications are sent
+type Notifications struct {
+	Webhook       string            `json:"webhook,omitempty"`              // URL receiving a JSON POST per event
+	Ntfy          string            `json:"ntfy,omitempty"`                 // ntfy topic URL, e.g. https://ntfy.sh/my-topic
+	TelegramToken string            `json:"telegram_token,omitempty"`       // Telegram bot token
+	TelegramChat  string            `json:"telegram_chat,omitempty"`        // Telegram chat ID to message
+	DailyChange   float64           `json:"daily_change_percent,omitempty"` // Notify when net value moves at least this much in a day
+	Templates     map[string]string `json:"templates,omitempty"`            // Message templates by event name
+}
+
+// PriceAlert fires when a coin's USD price rises to Above or falls to Below.
+// Exactly one of the two is set.
+type PriceAlert struct {
+	Coin  string  `json:"coin"`
+	Above float64 `json:"above,omitempty"`
+	Below float64 `json:"below,omitempty"`
+}
+
+// DCAPlan is a recurring purchase of a coin, bought at the live price when
+// due. Exactly one of Amount (in coins) and USD is set.
+type DCAPlan struct {
+	Coin     string  `json:"coin"`
+	Amount   float64 `json:"amount,omitempty"`
+	USD      float64 `json:"usd,omitempty"`
+	Platform string  `json:"platform,omitempty"`
+	Every    string  `json:"every"`              // "daily", "weekly", or "monthly"
+	Start    string  `json:"start"`              // Date of the first purchase, YYYY-MM-DD
+	LastDue  string  `json:"last_due,omitempty"` // Date of the last purchase made
+}
+
+// DCACadences are the supported DCA plan intervals
+var DCACadences = []string{"daily", "weekly", "monthly"}
+
+// APIKey is a read-only exchange API key
+type APIKey struct {
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
 }
 
 // ConfigStore manages configuration persistence
 type ConfigStore struct {
-	path   string
-	config *Config
-	mu     sync.RWMutex
+	path       string
+	config     *Config
+	env        map[string]envOverride     // Settings overridden by FOLLYO_ variables
+	fileFields map[string]json.RawMessage // Top-level values as last read from the file
+	mu         sync.RWMutex
+}
+
+// Dir returns the directory the config file is kept in by default:
+// $XDG_CONFIG_HOME/follyo, which is ~/.config/follyo by default
+func Dir() string {
+	// The XDG spec says relative paths are invalid and should be ignored
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "follyo")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return storage.DataDir()
+	}
+	return filepath.Join(home, ".config", "follyo")
 }
 
-// New creates a new ConfigStore with the given path
+// New creates a new ConfigStore with the given path. Settings in the file
+// are overridden by FOLLYO_ environment variables, e.g.
+// FOLLYO_DISPLAY_CURRENCY=EUR; see EnvKeys.
 func New(path string) (*ConfigStore, error) {
 	cs := &ConfigStore{
 		path: path,
 		config: &Config{
 			TickerMappings: make(map[string]string),
 		},
+		fileFields: make(map[string]json.RawMessage),
 	}
 
 	// Ensure directory exists
@@ -40,6 +163,12 @@ func New(path string) (*ConfigStore, error) {
 		return nil, err
 	}
 
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if err := cs.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
 	return cs, nil
 }
 
@@ -52,30 +181,103 @@ func (cs *ConfigStore) load() error {
 
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	return cs.parse(data)
+}
 
-	if err := json.Unmarshal(data, cs.config); err != nil {
-		return err
+// parse replaces the settings with those in data, the contents of the
+// config file; nil data leaves none set. Must be called with cs.mu held.
+func (cs *ConfigStore) parse(data []byte) error {
+	config := &Config{}
+	fields := make(map[string]json.RawMessage)
+	if data != nil {
+		if err := json.Unmarshal(data, config); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
 	}
 
 	// Ensure map is initialized
-	if cs.config.TickerMappings == nil {
-		cs.config.TickerMappings = make(map[string]string)
+	if config.TickerMappings == nil {
+		config.TickerMappings = make(map[string]string)
 	}
 
+	cs.config, cs.fileFields = config, fields
 	return nil
 }
 
-// save writes config to disk
-func (cs *ConfigStore) save() error {
-	cs.mu.RLock()
-	data, err := json.MarshalIndent(cs.config, "", "  ")
-	cs.mu.RUnlock()
+// update reloads the config from disk, applies change and saves the result,
+// holding a lock on the file throughout, so a store loaded before another
+// process saved doesn't overwrite that process's changes. change reports
+// whether it changed anything; the file is only written if it did.
+func (cs *ConfigStore) update(change func(c *Config) bool) (bool, error) {
+	lock, err := storage.AcquireLock(cs.path + ".lock")
+	if err != nil {
+		return false, err
+	}
+	defer lock.Release()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	data, err := os.ReadFile(cs.path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := cs.parse(data); err != nil {
+		return false, err
+	}
+	for key, o := range cs.env {
+		if err := unmarshalKey(cs.config, key, o.raw); err != nil {
+			return false, err
+		}
+	}
 
+	if !change(cs.config) {
+		return false, nil
+	}
+	data, err = cs.fileData()
+	if err != nil {
+		return false, err
+	}
+	return true, writeFile(cs.path, data)
+}
+
+// set applies change to the config and saves it; see update
+func (cs *ConfigStore) set(change func(c *Config)) error {
+	_, err := cs.update(func(c *Config) bool {
+		change(c)
+		return true
+	})
+	return err
+}
+
+// writeFile replaces the file at path with data by renaming a synced
+// temporary file over it, so readers never see a partly written config.
+// The file is owner-only, since the config can hold exchange API secrets.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
 
-	return os.WriteFile(cs.path, data, 0644)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 // GetTickerMapping returns the CoinGecko ID for a ticker, or empty string if not found
@@ -87,20 +289,16 @@ func (cs *ConfigStore) GetTickerMapping(ticker string) string {
 
 // SetTickerMapping sets a ticker to CoinGecko ID mapping
 func (cs *ConfigStore) SetTickerMapping(ticker, geckoID string) error {
-	cs.mu.Lock()
-	cs.config.TickerMappings[strings.ToUpper(ticker)] = geckoID
-	cs.mu.Unlock()
-
-	return cs.save()
+	return cs.set(func(c *Config) {
+		c.TickerMappings[strings.ToUpper(ticker)] = geckoID
+	})
 }
 
 // RemoveTickerMapping removes a ticker mapping
 func (cs *ConfigStore) RemoveTickerMapping(ticker string) error {
-	cs.mu.Lock()
-	delete(cs.config.TickerMappings, strings.ToUpper(ticker))
-	cs.mu.Unlock()
-
-	return cs.save()
+	return cs.set(func(c *Config) {
+		delete(c.TickerMappings, strings.ToUpper(ticker))
+	})
 }
 
 // GetAllTickerMappings returns all custom ticker mappings
@@ -123,3 +321,707 @@ func (cs *ConfigStore) HasTickerMapping(ticker string) bool {
 	_, ok := cs.config.TickerMappings[strings.ToUpper(ticker)]
 	return ok
 }
+
+// GetPortfolioDir returns the data directory of a named portfolio
+func (cs *ConfigStore) GetPortfolioDir(name string) (string, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	dir, ok := cs.config.Portfolios[strings.ToLower(name)]
+	return dir, ok
+}
+
+// SetPortfolio registers a named portfolio stored in dir
+func (cs *ConfigStore) SetPortfolio(name, dir string) error {
+	name = strings.ToLower(name)
+	if name == "" || strings.ContainsAny(name, " \t/\\") {
+		return fmt.Errorf("invalid portfolio name %q", name)
+	}
+	if dir == "" {
+		return fmt.Errorf("portfolio %s needs a data directory", name)
+	}
+
+	return cs.set(func(c *Config) {
+		if c.Portfolios == nil {
+			c.Portfolios = make(map[string]string)
+		}
+		c.Portfolios[name] = dir
+	})
+}
+
+// RemovePortfolio unregisters a named portfolio. Its data is not deleted.
+func (cs *ConfigStore) RemovePortfolio(name string) (bool, error) {
+	name = strings.ToLower(name)
+	return cs.update(func(c *Config) bool {
+		_, ok := c.Portfolios[name]
+		delete(c.Portfolios, name)
+		return ok
+	})
+}
+
+// GetAllPortfolios returns all named portfolios and their data directories
+func (cs *ConfigStore) GetAllPortfolios() map[string]string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	// Return a copy
+	result := make(map[string]string)
+	for k, v := range cs.config.Portfolios {
+		result[k] = v
+	}
+	return result
+}
+
+// GetWatchlist returns the watched tickers in the order they were added
+func (cs *ConfigStore) GetWatchlist() []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]string(nil), cs.config.Watchlist...)
+}
+
+// GetListColumns returns the columns chosen for a list command's table, or
+// nil to show its default columns
+func (cs *ConfigStore) GetListColumns(table string) []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]string(nil), cs.config.ListColumns[table]...)
+}
+
+// SetListColumns sets the columns a list command's table shows; no columns
+// restores its default columns
+func (cs *ConfigStore) SetListColumns(table string, columns []string) error {
+	return cs.set(func(c *Config) {
+		if len(columns) == 0 {
+			delete(c.ListColumns, table)
+		} else {
+			if c.ListColumns == nil {
+				c.ListColumns = make(map[string][]string)
+			}
+			c.ListColumns[table] = append([]string(nil), columns...)
+		}
+	})
+}
+
+// AddToWatchlist adds a ticker to the watchlist, returning false if it was already there
+func (cs *ConfigStore) AddToWatchlist(ticker string) (bool, error) {
+	ticker = strings.ToUpper(ticker)
+	return cs.update(func(c *Config) bool {
+		if slices.Contains(c.Watchlist, ticker) {
+			return false
+		}
+		c.Watchlist = append(c.Watchlist, ticker)
+		return true
+	})
+}
+
+// RemoveFromWatchlist removes a ticker from the watchlist, returning false if it was not there
+func (cs *ConfigStore) RemoveFromWatchlist(ticker string) (bool, error) {
+	ticker = strings.ToUpper(ticker)
+	return cs.update(func(c *Config) bool {
+		found := false
+		filtered := make([]string, 0, len(c.Watchlist))
+		for _, t := range c.Watchlist {
+			if t == ticker {
+				found = true
+				continue
+			}
+			filtered = append(filtered, t)
+		}
+		c.Watchlist = filtered
+		return found
+	})
+}
+
+// GetFavoritePlatforms returns the favorite platforms in the order they were added
+func (cs *ConfigStore) GetFavoritePlatforms() []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]string(nil), cs.config.Platforms...)
+}
+
+// AddFavoritePlatform adds a favorite platform, returning false if it was
+// already a favorite under any capitalization
+func (cs *ConfigStore) AddFavoritePlatform(name string) (bool, error) {
+	return cs.update(func(c *Config) bool {
+		for _, p := range c.Platforms {
+			if strings.EqualFold(p, name) {
+				return false
+			}
+		}
+		c.Platforms = append(c.Platforms, name)
+		return true
+	})
+}
+
+// RemoveFavoritePlatform removes a favorite platform, ignoring case,
+// returning false if it was not a favorite
+func (cs *ConfigStore) RemoveFavoritePlatform(name string) (bool, error) {
+	return cs.update(func(c *Config) bool {
+		found := false
+		filtered := make([]string, 0, len(c.Platforms))
+		for _, p := range c.Platforms {
+			if strings.EqualFold(p, name) {
+				found = true
+				continue
+			}
+			filtered = append(filtered, p)
+		}
+		c.Platforms = filtered
+		return found
+	})
+}
+
+// GetPlatformAliases returns the platforms by lowercase alias
+func (cs *ConfigStore) GetPlatformAliases() map[string]string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	// Return a copy
+	result := make(map[string]string)
+	for k, v := range cs.config.PlatformAliases {
+		result[k] = v
+	}
+	return result
+}
+
+// SetPlatformAlias makes alias, in any capitalization, stand for platform
+func (cs *ConfigStore) SetPlatformAlias(alias, platform string) error {
+	return cs.set(func(c *Config) {
+		if c.PlatformAliases == nil {
+			c.PlatformAliases = make(map[string]string)
+		}
+		c.PlatformAliases[strings.ToLower(alias)] = platform
+	})
+}
+
+// RemovePlatformAlias removes an alias, returning false if it did not exist
+func (cs *ConfigStore) RemovePlatformAlias(alias string) (bool, error) {
+	alias = strings.ToLower(alias)
+	return cs.update(func(c *Config) bool {
+		_, ok := c.PlatformAliases[alias]
+		delete(c.PlatformAliases, alias)
+		return ok
+	})
+}
+
+// GetManualPrices returns the manual prices by ticker
+func (cs *ConfigStore) GetManualPrices() map[string]ManualPrice {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	// Return a copy
+	result := make(map[string]ManualPrice)
+	for k, v := range cs.config.ManualPrices {
+		result[k] = v
+	}
+	return result
+}
+
+// SetManualPrice sets the manual price of a ticker
+func (cs *ConfigStore) SetManualPrice(ticker string, price ManualPrice) error {
+	if price.Price < 0 {
+		return fmt.Errorf("price cannot be negative")
+	}
+
+	return cs.set(func(c *Config) {
+		if c.ManualPrices == nil {
+			c.ManualPrices = make(map[string]ManualPrice)
+		}
+		c.ManualPrices[strings.ToUpper(ticker)] = price
+	})
+}
+
+// RemoveManualPrice removes the manual price of a ticker, returning false if it had none
+func (cs *ConfigStore) RemoveManualPrice(ticker string) (bool, error) {
+	ticker = strings.ToUpper(ticker)
+	return cs.update(func(c *Config) bool {
+		_, ok := c.ManualPrices[ticker]
+		delete(c.ManualPrices, ticker)
+		return ok
+	})
+}
+
+// GetExchangeKey returns the API key stored for an exchange
+func (cs *ConfigStore) GetExchangeKey(exchange string) (APIKey, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	key, ok := cs.config.Exchanges[strings.ToLower(exchange)]
+	return key, ok
+}
+
+// SetExchangeKey stores the API key for an exchange
+func (cs *ConfigStore) SetExchangeKey(exchange string, key APIKey) error {
+	if key.Key == "" || key.Secret == "" {
+		return fmt.Errorf("API key and secret are required")
+	}
+
+	return cs.set(func(c *Config) {
+		if c.Exchanges == nil {
+			c.Exchanges = make(map[string]APIKey)
+		}
+		c.Exchanges[strings.ToLower(exchange)] = key
+	})
+}
+
+// RemoveExchangeKey removes the API key for an exchange
+func (cs *ConfigStore) RemoveExchangeKey(exchange string) (bool, error) {
+	exchange = strings.ToLower(exchange)
+	return cs.update(func(c *Config) bool {
+		_, ok := c.Exchanges[exchange]
+		delete(c.Exchanges, exchange)
+		return ok
+	})
+}
+
+// StripCredentials returns the contents of a config file with the exchange
+// API keys removed, for copying the config off the machine.
+func StripCredentials(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["exchanges"]; !ok {
+		return data, nil
+	}
+	delete(fields, "exchanges")
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// GetExchangeNames returns the exchanges with a stored API key, sorted
+func (cs *ConfigStore) GetExchangeNames() []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	names := make([]string, 0, len(cs.config.Exchanges))
+	for name := range cs.config.Exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetDataDir returns the directory of the default portfolio, or "" to use
+// the built-in default
+func (cs *ConfigStore) GetDataDir() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.DataDir
+}
+
+// SetDataDir sets the directory of the default portfolio; "" restores the
+// built-in default
+func (cs *ConfigStore) SetDataDir(dir string) error {
+	return cs.set(func(c *Config) {
+		c.DataDir = dir
+	})
+}
+
+// GetDefaultPlatform returns the platform of records added without one
+func (cs *ConfigStore) GetDefaultPlatform() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.DefaultPlatform
+}
+
+// SetDefaultPlatform sets the platform of records added without one; ""
+// leaves such records without a platform
+func (cs *ConfigStore) SetDefaultPlatform(platform string) error {
+	return cs.set(func(c *Config) {
+		c.DefaultPlatform = platform
+	})
+}
+
+// GetPriceCacheTTL returns how long live prices are reused, or 0 to use the
+// price service default
+func (cs *ConfigStore) GetPriceCacheTTL() time.Duration {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	ttl, err := time.ParseDuration(cs.config.PriceCacheTTL)
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// SetPriceCacheTTL sets how long live prices are reused, e.g. "5m"; ""
+// restores the default
+func (cs *ConfigStore) SetPriceCacheTTL(ttl string) error {
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid price cache TTL %q: use a duration such as 2m or 1h", ttl)
+		}
+	}
+
+	return cs.set(func(c *Config) {
+		c.PriceCacheTTL = ttl
+	})
+}
+
+// MinRefreshInterval is the shortest refresh interval, keeping price
+// requests within CoinGecko's rate limit
+const MinRefreshInterval = 30 * time.Second
+
+// GetRefreshInterval returns how often the summary and dashboard redraw
+// with fresh prices, or 0 when they are shown once
+func (cs *ConfigStore) GetRefreshInterval() time.Duration {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	every, err := time.ParseDuration(cs.config.RefreshInterval)
+	if err != nil || every < 0 {
+		return 0
+	}
+	return every
+}
+
+// SetRefreshInterval sets how often the summary and dashboard redraw, e.g.
+// "5m"; "" turns refreshing off
+func (cs *ConfigStore) SetRefreshInterval(every string) error {
+	if every != "" {
+		d, err := time.ParseDuration(every)
+		if err != nil || d < MinRefreshInterval {
+			return fmt.Errorf("invalid refresh interval %q: use a duration of at least %s such as 5m", every, MinRefreshInterval)
+		}
+	}
+
+	return cs.set(func(c *Config) {
+		c.RefreshInterval = every
+	})
+}
+
+// GetBaselineSnapshot returns the ID or pinned name of the snapshot that
+// profit/loss is shown relative to, or "" for profit/loss since the first
+// purchase
+func (cs *ConfigStore) GetBaselineSnapshot() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.BaselineSnapshot
+}
+
+// SetBaselineSnapshot sets the ID or pinned name of the snapshot that
+// profit/loss is shown relative to; "" removes the baseline
+func (cs *ConfigStore) SetBaselineSnapshot(snapshot string) error {
+	return cs.set(func(c *Config) {
+		c.BaselineSnapshot = strings.TrimSpace(snapshot)
+	})
+}
+
+// GetDisplayCurrency returns the currency values are displayed in, defaulting to USD
+func (cs *ConfigStore) GetDisplayCurrency() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.DisplayCurrency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(cs.config.DisplayCurrency)
+}
+
+// SetDisplayCurrency sets the currency values are displayed in
+func (cs *ConfigStore) SetDisplayCurrency(currency string) error {
+	return cs.set(func(c *Config) {
+		c.DisplayCurrency = strings.ToUpper(currency)
+	})
+}
+
+// GetSnapshotSchedule returns the configured daemon snapshot interval and daily time
+func (cs *ConfigStore) GetSnapshotSchedule() (interval, at string) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.SnapshotInterval, cs.config.SnapshotTime
+}
+
+// GetAutoSnapshot reports whether price-fetching commands save a daily snapshot
+func (cs *ConfigStore) GetAutoSnapshot() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.AutoSnapshot
+}
+
+// SetAutoSnapshot sets whether price-fetching commands save a daily snapshot
+func (cs *ConfigStore) SetAutoSnapshot(enabled bool) error {
+	return cs.set(func(c *Config) {
+		c.AutoSnapshot = enabled
+	})
+}
+
+// GetAllowFutureDates reports whether records may be dated after today
+func (cs *ConfigStore) GetAllowFutureDates() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.config.AllowFutureDates
+}
+
+// SetAllowFutureDates sets whether records may be dated after today
+func (cs *ConfigStore) SetAllowFutureDates(allow bool) error {
+	return cs.set(func(c *Config) {
+		c.AllowFutureDates = allow
+	})
+}
+
+// GetInterestMethod returns how loan interest accrues, "simple" (default) or "compound"
+func (cs *ConfigStore) GetInterestMethod() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.InterestMethod == "" {
+		return "simple"
+	}
+	return strings.ToLower(cs.config.InterestMethod)
+}
+
+// SetInterestMethod sets how loan interest accrues
+func (cs *ConfigStore) SetInterestMethod(method string) error {
+	method = strings.ToLower(method)
+	if method != "simple" && method != "compound" {
+		return fmt.Errorf("invalid interest method %q: use simple or compound", method)
+	}
+
+	return cs.set(func(c *Config) {
+		c.InterestMethod = method
+	})
+}
+
+// GetStorage returns the storage backend, "json" (default) or "sqlite"
+func (cs *ConfigStore) GetStorage() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.Storage == "" {
+		return "json"
+	}
+	return strings.ToLower(cs.config.Storage)
+}
+
+// SetStorage sets the storage backend
+func (cs *ConfigStore) SetStorage(backend string) error {
+	backend = strings.ToLower(backend)
+	if backend != "json" && backend != "sqlite" {
+		return fmt.Errorf("invalid storage backend %q: use json or sqlite", backend)
+	}
+
+	return cs.set(func(c *Config) {
+		c.Storage = backend
+	})
+}
+
+// GetDefaultView returns what follyo shows without a command, "help" (default) or "dashboard"
+func (cs *ConfigStore) GetDefaultView() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.DefaultView == "" {
+		return "help"
+	}
+	return strings.ToLower(cs.config.DefaultView)
+}
+
+// SetDefaultView sets what follyo shows without a command
+func (cs *ConfigStore) SetDefaultView(view string) error {
+	view = strings.ToLower(view)
+	if view != "help" && view != "dashboard" {
+		return fmt.Errorf("invalid default view %q: use help or dashboard", view)
+	}
+
+	return cs.set(func(c *Config) {
+		c.DefaultView = view
+	})
+}
+
+// GetTheme returns the color theme name, defaulting to "dark"
+func (cs *ConfigStore) GetTheme() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.Theme == "" {
+		return "dark"
+	}
+	return strings.ToLower(cs.config.Theme)
+}
+
+// SetTheme sets the color theme name
+func (cs *ConfigStore) SetTheme(name string) error {
+	return cs.set(func(c *Config) {
+		c.Theme = strings.ToLower(name)
+	})
+}
+
+// GetThemeColors returns the colors of the custom theme by role
+func (cs *ConfigStore) GetThemeColors() map[string]string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	// Return a copy
+	result := make(map[string]string)
+	for k, v := range cs.config.ThemeColors {
+		result[strings.ToLower(k)] = v
+	}
+	return result
+}
+
+// DefaultTrashRetention is how many days removed records are kept by default
+const DefaultTrashRetention = 30
+
+// GetTrashRetention returns how many days removed records are kept in the
+// trash. Zero or less means they are kept forever.
+func (cs *ConfigStore) GetTrashRetention() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	switch {
+	case cs.config.TrashRetention == 0:
+		return DefaultTrashRetention
+	case cs.config.TrashRetention < 0:
+		return 0
+	}
+	return cs.config.TrashRetention
+}
+
+// DefaultPegThreshold is how far, in percent, a stablecoin may trade from
+// $1 before the summary warns by default
+const DefaultPegThreshold = 0.5
+
+// GetPegThreshold returns how far, in percent, a stablecoin may trade from
+// $1 before it is reported as depegged. Zero or less disables the warning.
+func (cs *ConfigStore) GetPegThreshold() float64 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	switch {
+	case cs.config.PegThreshold == 0:
+		return DefaultPegThreshold
+	case cs.config.PegThreshold < 0:
+		return 0
+	}
+	return cs.config.PegThreshold
+}
+
+// GetPriceAttempts returns how many times a price request is tried before
+// giving up, or 0 to use the price service default.
+func (cs *ConfigStore) GetPriceAttempts() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return max(0, cs.config.PriceAttempts)
+}
+
+// GetNotifications returns the notification settings
+func (cs *ConfigStore) GetNotifications() Notifications {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.Notifications == nil {
+		return Notifications{}
+	}
+	// Return a copy
+	n := *cs.config.Notifications
+	n.Templates = make(map[string]string)
+	for k, v := range cs.config.Notifications.Templates {
+		n.Templates[k] = v
+	}
+	return n
+}
+
+// GetGoals returns the goals, with coins in upper case
+func (cs *ConfigStore) GetGoals() Goals {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.Goals == nil {
+		return Goals{}
+	}
+	// Return a copy
+	g := Goals{TargetValue: cs.config.Goals.TargetValue}
+	if len(cs.config.Goals.Allocation) > 0 {
+		g.Allocation = make(map[string]float64)
+		for coin, percent := range cs.config.Goals.Allocation {
+			g.Allocation[strings.ToUpper(coin)] = percent
+		}
+	}
+	return g
+}
+
+// GetSync returns the sync settings, and false if sync is not configured
+func (cs *ConfigStore) GetSync() (Sync, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cs.config.Sync == nil {
+		return Sync{}, false
+	}
+	return *cs.config.Sync, true
+}
+
+// GetPriceAlerts returns the price alerts in the order they were added
+func (cs *ConfigStore) GetPriceAlerts() []PriceAlert {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]PriceAlert(nil), cs.config.PriceAlerts...)
+}
+
+// AddPriceAlert adds a price alert
+func (cs *ConfigStore) AddPriceAlert(alert PriceAlert) error {
+	alert.Coin = strings.ToUpper(alert.Coin)
+	if alert.Coin == "" {
+		return fmt.Errorf("price alert needs a coin")
+	}
+	if alert.Above < 0 || alert.Below < 0 || (alert.Above > 0) == (alert.Below > 0) {
+		return fmt.Errorf("price alert needs either a positive above or below price")
+	}
+
+	return cs.set(func(c *Config) {
+		c.PriceAlerts = append(c.PriceAlerts, alert)
+	})
+}
+
+// GetDCAPlans returns the DCA plans in the order they were added
+func (cs *ConfigStore) GetDCAPlans() []DCAPlan {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]DCAPlan(nil), cs.config.DCAPlans...)
+}
+
+// AddDCAPlan adds a DCA plan
+func (cs *ConfigStore) AddDCAPlan(plan DCAPlan) error {
+	plan.Coin = strings.ToUpper(plan.Coin)
+	if plan.Coin == "" {
+		return fmt.Errorf("DCA plan needs a coin")
+	}
+	if plan.Amount < 0 || plan.USD < 0 || (plan.Amount > 0) == (plan.USD > 0) {
+		return fmt.Errorf("DCA plan needs either a positive amount or USD value")
+	}
+	if !slices.Contains(DCACadences, plan.Every) {
+		return fmt.Errorf("invalid DCA cadence %q (use %s)", plan.Every, strings.Join(DCACadences, ", "))
+	}
+	if _, err := time.Parse("2006-01-02", plan.Start); err != nil {
+		return fmt.Errorf("invalid DCA start date %q", plan.Start)
+	}
+
+	return cs.set(func(c *Config) {
+		c.DCAPlans = append(c.DCAPlans, plan)
+	})
+}
+
+// UpdateDCAPlan replaces the first plan equal to old with plan, returning
+// false if there is none
+func (cs *ConfigStore) UpdateDCAPlan(old, plan DCAPlan) (bool, error) {
+	return cs.update(func(c *Config) bool {
+		i := slices.Index(c.DCAPlans, old)
+		if i < 0 {
+			return false
+		}
+		c.DCAPlans[i] = plan
+		return true
+	})
+}
+
+// RemoveDCAPlan removes the first plan equal to plan, returning false if there is none
+func (cs *ConfigStore) RemoveDCAPlan(plan DCAPlan) (bool, error) {
+	return cs.update(func(c *Config) bool {
+		i := slices.Index(c.DCAPlans, plan)
+		if i < 0 {
+			return false
+		}
+		c.DCAPlans = slices.Delete(c.DCAPlans, i, i+1)
+		return true
+	})
+}
+
+// RemovePriceAlert removes the first alert equal to alert, returning false if there is none
+func (cs *ConfigStore) RemovePriceAlert(alert PriceAlert) (bool, error) {
+	return cs.update(func(c *Config) bool {
+		i := slices.Index(c.PriceAlerts, alert)
+		if i < 0 {
+			return false
+		}
+		c.PriceAlerts = slices.Delete(c.PriceAlerts, i, i+1)
+		return true
+	})
+}
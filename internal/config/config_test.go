@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfigStore(t *testing.T) {
@@ -124,3 +127,899 @@ func TestConfigNonExistentPath(t *testing.T) {
 		t.Fatalf("Failed to set mapping: %v", err)
 	}
 }
+
+func TestDisplayCurrency(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetDisplayCurrency(); got != "USD" {
+		t.Errorf("Expected default USD, got %s", got)
+	}
+
+	if err := cs.SetDisplayCurrency("eur"); err != nil {
+		t.Fatalf("Failed to set display currency: %v", err)
+	}
+
+	// Reload to verify persistence
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetDisplayCurrency(); got != "EUR" {
+		t.Errorf("Expected EUR after reload, got %s", got)
+	}
+}
+
+func TestInterestMethod(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetInterestMethod(); got != "simple" {
+		t.Errorf("Expected default simple, got %s", got)
+	}
+
+	if err := cs.SetInterestMethod("Compound"); err != nil {
+		t.Fatalf("Failed to set interest method: %v", err)
+	}
+	if err := cs.SetInterestMethod("daily"); err == nil {
+		t.Error("Expected error for invalid interest method")
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetInterestMethod(); got != "compound" {
+		t.Errorf("Expected compound after reload, got %s", got)
+	}
+}
+
+func TestStorageBackend(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetStorage(); got != "json" {
+		t.Errorf("Expected default json, got %s", got)
+	}
+
+	if err := cs.SetStorage("SQLite"); err != nil {
+		t.Fatalf("Failed to set storage: %v", err)
+	}
+	if err := cs.SetStorage("postgres"); err == nil {
+		t.Error("Expected error for invalid storage backend")
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetStorage(); got != "sqlite" {
+		t.Errorf("Expected sqlite after reload, got %s", got)
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	if got := cs.GetTrashRetention(); got != DefaultTrashRetention {
+		t.Errorf("Expected default %d, got %d", DefaultTrashRetention, got)
+	}
+
+	os.WriteFile(configPath, []byte(`{"trash_retention_days": 7}`), 0644)
+	cs, _ = New(configPath)
+	if got := cs.GetTrashRetention(); got != 7 {
+		t.Errorf("Expected 7, got %d", got)
+	}
+
+	os.WriteFile(configPath, []byte(`{"trash_retention_days": -1}`), 0644)
+	cs, _ = New(configPath)
+	if got := cs.GetTrashRetention(); got != 0 {
+		t.Errorf("Expected 0 (keep forever), got %d", got)
+	}
+}
+
+func TestPriceAttempts(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	if got := cs.GetPriceAttempts(); got != 0 {
+		t.Errorf("Expected 0 (default), got %d", got)
+	}
+
+	os.WriteFile(configPath, []byte(`{"price_max_attempts": 2}`), 0644)
+	cs, _ = New(configPath)
+	if got := cs.GetPriceAttempts(); got != 2 {
+		t.Errorf("Expected 2, got %d", got)
+	}
+}
+
+func TestPegThreshold(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	for _, tt := range []struct {
+		json string
+		want float64
+	}{
+		{`{}`, DefaultPegThreshold},
+		{`{"peg_threshold_percent": 2}`, 2},
+		{`{"peg_threshold_percent": -1}`, 0},
+	} {
+		os.WriteFile(configPath, []byte(tt.json), 0644)
+		cs, err := New(configPath)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", tt.json, err)
+		}
+		if got := cs.GetPegThreshold(); got != tt.want {
+			t.Errorf("GetPegThreshold() with %s = %v, want %v", tt.json, got, tt.want)
+		}
+	}
+}
+
+func TestManualPrices(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if err := cs.SetManualPrice("xyz", ManualPrice{Price: 0.42, Date: "2024-03-01"}); err != nil {
+		t.Fatalf("SetManualPrice failed: %v", err)
+	}
+	if err := cs.SetManualPrice("BAD", ManualPrice{Price: -1}); err == nil {
+		t.Error("Expected error for negative price")
+	}
+
+	cs, _ = New(configPath)
+	got := cs.GetManualPrices()
+	if len(got) != 1 || got["XYZ"] != (ManualPrice{Price: 0.42, Date: "2024-03-01"}) {
+		t.Errorf("Unexpected manual prices after reload: %v", got)
+	}
+
+	if removed, err := cs.RemoveManualPrice("xyz"); err != nil || !removed {
+		t.Errorf("RemoveManualPrice = %v, %v; want true, nil", removed, err)
+	}
+	if removed, _ := cs.RemoveManualPrice("XYZ"); removed {
+		t.Error("Expected false removing a missing price")
+	}
+}
+
+func TestPortfolios(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if _, ok := cs.GetPortfolioDir("business"); ok {
+		t.Error("Expected no business portfolio")
+	}
+	if err := cs.SetPortfolio("Business", "/data/business"); err != nil {
+		t.Fatalf("Failed to set portfolio: %v", err)
+	}
+	if err := cs.SetPortfolio("two words", "/data/x"); err == nil {
+		t.Error("Expected error for name with spaces")
+	}
+	if err := cs.SetPortfolio("dca", ""); err == nil {
+		t.Error("Expected error for empty directory")
+	}
+
+	cs2, _ := New(configPath)
+	if dir, ok := cs2.GetPortfolioDir("BUSINESS"); !ok || dir != "/data/business" {
+		t.Errorf("Expected /data/business after reload, got %q, %v", dir, ok)
+	}
+	if all := cs2.GetAllPortfolios(); len(all) != 1 {
+		t.Errorf("Expected 1 portfolio, got %v", all)
+	}
+
+	if removed, _ := cs2.RemovePortfolio("business"); !removed {
+		t.Error("Expected portfolio removed")
+	}
+	if removed, _ := cs2.RemovePortfolio("business"); removed {
+		t.Error("Expected nothing to remove")
+	}
+}
+
+func TestWatchlist(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if added, err := cs.AddToWatchlist("sol"); err != nil || !added {
+		t.Fatalf("Expected SOL added, got %v, %v", added, err)
+	}
+	if added, _ := cs.AddToWatchlist("SOL"); added {
+		t.Error("Expected duplicate not to be added")
+	}
+	cs.AddToWatchlist("tia")
+
+	cs2, _ := New(configPath)
+	list := cs2.GetWatchlist()
+	if len(list) != 2 || list[0] != "SOL" || list[1] != "TIA" {
+		t.Errorf("Expected [SOL TIA] after reload, got %v", list)
+	}
+
+	if removed, _ := cs2.RemoveFromWatchlist("Sol"); !removed {
+		t.Error("Expected SOL removed")
+	}
+	if removed, _ := cs2.RemoveFromWatchlist("SOL"); removed {
+		t.Error("Expected nothing to remove")
+	}
+	if list := cs2.GetWatchlist(); len(list) != 1 || list[0] != "TIA" {
+		t.Errorf("Expected [TIA], got %v", list)
+	}
+}
+
+func TestPlatformRegistry(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if added, err := cs.AddFavoritePlatform("Coinbase"); err != nil || !added {
+		t.Fatalf("Expected Coinbase added, got %v, %v", added, err)
+	}
+	if added, _ := cs.AddFavoritePlatform("coinbase"); added {
+		t.Error("Expected a favorite differing in case not to be added")
+	}
+	cs.AddFavoritePlatform("Ledger")
+	if err := cs.SetPlatformAlias("CB", "Coinbase"); err != nil {
+		t.Fatalf("SetPlatformAlias failed: %v", err)
+	}
+
+	cs2, _ := New(configPath)
+	if list := cs2.GetFavoritePlatforms(); len(list) != 2 || list[0] != "Coinbase" || list[1] != "Ledger" {
+		t.Errorf("Expected [Coinbase Ledger] after reload, got %v", list)
+	}
+	if aliases := cs2.GetPlatformAliases(); aliases["cb"] != "Coinbase" {
+		t.Errorf("Expected cb -> Coinbase, got %v", aliases)
+	}
+
+	if removed, _ := cs2.RemoveFavoritePlatform("LEDGER"); !removed {
+		t.Error("Expected Ledger removed")
+	}
+	if removed, _ := cs2.RemovePlatformAlias("Cb"); !removed {
+		t.Error("Expected alias removed")
+	}
+	if removed, _ := cs2.RemovePlatformAlias("cb"); removed {
+		t.Error("Expected nothing to remove")
+	}
+	if list := cs2.GetFavoritePlatforms(); len(list) != 1 || list[0] != "Coinbase" {
+		t.Errorf("Expected [Coinbase], got %v", list)
+	}
+}
+
+func TestAutoSnapshot(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if cs.GetAutoSnapshot() {
+		t.Error("Expected auto snapshot disabled by default")
+	}
+	if err := cs.SetAutoSnapshot(true); err != nil {
+		t.Fatalf("Failed to enable auto snapshot: %v", err)
+	}
+
+	cs2, _ := New(configPath)
+	if !cs2.GetAutoSnapshot() {
+		t.Error("Expected auto snapshot enabled after reload")
+	}
+}
+
+func TestExchangeKeys(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if err := cs.SetExchangeKey("binance", APIKey{Key: "k"}); err == nil {
+		t.Error("Expected error for missing secret")
+	}
+	if err := cs.SetExchangeKey("Binance", APIKey{Key: "k", Secret: "s"}); err != nil {
+		t.Fatalf("Failed to set exchange key: %v", err)
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected config mode 0600, got %o", perm)
+	}
+
+	cs2, _ := New(configPath)
+	if key, ok := cs2.GetExchangeKey("BINANCE"); !ok || key.Key != "k" || key.Secret != "s" {
+		t.Errorf("Expected key after reload, got %+v, %v", key, ok)
+	}
+	if names := cs2.GetExchangeNames(); len(names) != 1 || names[0] != "binance" {
+		t.Errorf("Expected [binance], got %v", names)
+	}
+	if removed, _ := cs2.RemoveExchangeKey("binance"); !removed {
+		t.Error("Expected key removed")
+	}
+	if removed, _ := cs2.RemoveExchangeKey("binance"); removed {
+		t.Error("Expected nothing to remove")
+	}
+}
+
+func TestStripCredentials(t *testing.T) {
+	data := []byte(`{"ticker_mappings": {"BTC": "bitcoin"}, "exchanges": {"binance": {"key": "k", "secret": "s"}}}`)
+	stripped, err := StripCredentials(data)
+	if err != nil {
+		t.Fatalf("StripCredentials failed: %v", err)
+	}
+	if strings.Contains(string(stripped), "secret") || !strings.Contains(string(stripped), "bitcoin") {
+		t.Errorf("Expected only the exchange keys removed, got: %s", stripped)
+	}
+
+	plain := []byte(`{"ticker_mappings": {}}`)
+	if got, _ := StripCredentials(plain); string(got) != string(plain) {
+		t.Errorf("Expected a config without keys unchanged, got: %s", got)
+	}
+	if _, err := StripCredentials([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	data := `{"notifications":{"ntfy":"https://ntfy.sh/t","daily_change_percent":5,"templates":{"snapshot":"{{.Snapshot.ID}}"}}}`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	n := cs.GetNotifications()
+	if n.Ntfy != "https://ntfy.sh/t" || n.DailyChange != 5 || n.Templates["snapshot"] != "{{.Snapshot.ID}}" {
+		t.Errorf("Unexpected notifications %+v", n)
+	}
+	n.Templates["snapshot"] = "changed"
+	if cs.GetNotifications().Templates["snapshot"] != "{{.Snapshot.ID}}" {
+		t.Error("Expected GetNotifications to return a copy")
+	}
+}
+
+func TestGoals(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	if g := cs.GetGoals(); g.TargetValue != 0 || g.Allocation != nil {
+		t.Errorf("Expected no goals by default, got %+v", g)
+	}
+
+	data := `{"goals":{"target_value":100000,"target_allocation":{"btc":60,"ETH":40}}}`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cs, err = New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	g := cs.GetGoals()
+	if g.TargetValue != 100000 || g.Allocation["BTC"] != 60 || g.Allocation["ETH"] != 40 {
+		t.Errorf("Unexpected goals %+v", g)
+	}
+}
+
+func TestPriceAlerts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	for _, bad := range []PriceAlert{{Coin: "BTC"}, {Coin: "BTC", Above: 1, Below: 1}, {Above: 1}, {Coin: "BTC", Below: -1}} {
+		if err := cs.AddPriceAlert(bad); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+	if err := cs.AddPriceAlert(PriceAlert{Coin: "btc", Above: 100000}); err != nil {
+		t.Fatalf("Failed to add alert: %v", err)
+	}
+	cs.AddPriceAlert(PriceAlert{Coin: "ETH", Below: 2000})
+
+	cs2, _ := New(configPath)
+	alerts := cs2.GetPriceAlerts()
+	if len(alerts) != 2 || alerts[0] != (PriceAlert{Coin: "BTC", Above: 100000}) {
+		t.Fatalf("Expected alerts after reload, got %+v", alerts)
+	}
+	if removed, _ := cs2.RemovePriceAlert(alerts[0]); !removed {
+		t.Error("Expected alert removed")
+	}
+	if removed, _ := cs2.RemovePriceAlert(alerts[0]); removed {
+		t.Error("Expected nothing to remove")
+	}
+	if alerts := cs2.GetPriceAlerts(); len(alerts) != 1 || alerts[0].Coin != "ETH" {
+		t.Errorf("Expected only the ETH alert left, got %+v", alerts)
+	}
+}
+
+func TestDCAPlans(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	for _, bad := range []DCAPlan{
+		{Coin: "BTC", Every: "weekly", Start: "2024-01-01"},
+		{Coin: "BTC", Amount: 1, USD: 100, Every: "weekly", Start: "2024-01-01"},
+		{Coin: "BTC", USD: 100, Every: "hourly", Start: "2024-01-01"},
+		{Coin: "BTC", USD: 100, Every: "weekly", Start: "soon"},
+		{USD: 100, Every: "weekly", Start: "2024-01-01"},
+	} {
+		if err := cs.AddDCAPlan(bad); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+	if err := cs.AddDCAPlan(DCAPlan{Coin: "btc", USD: 100, Every: "weekly", Start: "2024-01-01"}); err != nil {
+		t.Fatalf("Failed to add plan: %v", err)
+	}
+	cs.AddDCAPlan(DCAPlan{Coin: "ETH", Amount: 0.1, Every: "monthly", Start: "2024-01-15", Platform: "Kraken"})
+
+	cs2, _ := New(configPath)
+	plans := cs2.GetDCAPlans()
+	if len(plans) != 2 || plans[0] != (DCAPlan{Coin: "BTC", USD: 100, Every: "weekly", Start: "2024-01-01"}) {
+		t.Fatalf("Expected plans after reload, got %+v", plans)
+	}
+
+	ran := plans[0]
+	ran.LastDue = "2024-01-08"
+	if updated, _ := cs2.UpdateDCAPlan(plans[0], ran); !updated {
+		t.Error("Expected plan updated")
+	}
+	if updated, _ := cs2.UpdateDCAPlan(plans[0], ran); updated {
+		t.Error("Expected the old plan gone")
+	}
+	if removed, _ := cs2.RemoveDCAPlan(plans[1]); !removed {
+		t.Error("Expected plan removed")
+	}
+	if plans := cs2.GetDCAPlans(); len(plans) != 1 || plans[0].LastDue != "2024-01-08" {
+		t.Errorf("Expected only the updated BTC plan left, got %+v", plans)
+	}
+}
+
+func TestStaleStoreKeepsOtherChanges(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	plan := DCAPlan{Coin: "BTC", USD: 100, Every: "weekly", Start: "2024-01-01"}
+	if err := cs.AddDCAPlan(plan); err != nil {
+		t.Fatalf("Failed to add plan: %v", err)
+	}
+
+	// Both stores are loaded before either saves, as in two processes
+	first, _ := New(configPath)
+	stale, _ := New(configPath)
+
+	ran := plan
+	ran.LastDue = "2024-01-08"
+	if updated, err := first.UpdateDCAPlan(plan, ran); err != nil || !updated {
+		t.Fatalf("Expected plan updated, got %v, %v", updated, err)
+	}
+	if _, err := stale.AddToWatchlist("eth"); err != nil {
+		t.Fatalf("Failed to add to watchlist: %v", err)
+	}
+	if updated, _ := stale.UpdateDCAPlan(plan, ran); updated {
+		t.Error("Expected the stale plan not to match after another store ran it")
+	}
+
+	reloaded, _ := New(configPath)
+	if plans := reloaded.GetDCAPlans(); len(plans) != 1 || plans[0].LastDue != "2024-01-08" {
+		t.Errorf("Expected LastDue kept, got %+v", plans)
+	}
+	if watchlist := reloaded.GetWatchlist(); len(watchlist) != 1 || watchlist[0] != "ETH" {
+		t.Errorf("Expected ETH watched, got %v", watchlist)
+	}
+	if plans := stale.GetDCAPlans(); plans[0].LastDue != "2024-01-08" {
+		t.Errorf("Expected saving to reload the other store's changes, got %+v", plans)
+	}
+}
+
+func TestDefaultView(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetDefaultView(); got != "help" {
+		t.Errorf("Expected default help, got %s", got)
+	}
+	if err := cs.SetDefaultView("Dashboard"); err != nil {
+		t.Fatalf("Failed to set default view: %v", err)
+	}
+	if err := cs.SetDefaultView("tui"); err == nil {
+		t.Error("Expected error for invalid default view")
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetDefaultView(); got != "dashboard" {
+		t.Errorf("Expected dashboard after reload, got %s", got)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	data := `{"theme_colors":{"Gain":"bright-green","loss":"208"}}`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetTheme(); got != "dark" {
+		t.Errorf("Expected default dark, got %s", got)
+	}
+	if colors := cs.GetThemeColors(); colors["gain"] != "bright-green" || colors["loss"] != "208" {
+		t.Errorf("Unexpected theme colors %v", colors)
+	}
+	if err := cs.SetTheme("Custom"); err != nil {
+		t.Fatalf("Failed to set theme: %v", err)
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetTheme(); got != "custom" {
+		t.Errorf("Expected custom after reload, got %s", got)
+	}
+}
+
+func TestAllowFutureDates(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if cs.GetAllowFutureDates() {
+		t.Error("Expected future dates to be rejected by default")
+	}
+	if err := cs.SetAllowFutureDates(true); err != nil {
+		t.Fatalf("Failed to allow future dates: %v", err)
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if !cs2.GetAllowFutureDates() {
+		t.Error("Expected future dates to be allowed after reload")
+	}
+}
+
+func TestSettings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if cs.GetDataDir() != "" || cs.GetDefaultPlatform() != "" || cs.GetPriceCacheTTL() != 0 || cs.GetRefreshInterval() != 0 {
+		t.Error("Expected empty defaults")
+	}
+
+	if err := cs.SetDataDir("/tmp/follyo"); err != nil {
+		t.Fatalf("Failed to set data dir: %v", err)
+	}
+	if err := cs.SetDefaultPlatform("Kraken"); err != nil {
+		t.Fatalf("Failed to set default platform: %v", err)
+	}
+	if err := cs.SetPriceCacheTTL("10m"); err != nil {
+		t.Fatalf("Failed to set price cache TTL: %v", err)
+	}
+	for _, invalid := range []string{"soon", "-1m", "0s"} {
+		if err := cs.SetPriceCacheTTL(invalid); err == nil {
+			t.Errorf("Expected error for TTL %q", invalid)
+		}
+	}
+	if err := cs.SetRefreshInterval("5m"); err != nil {
+		t.Fatalf("Failed to set refresh interval: %v", err)
+	}
+	for _, invalid := range []string{"often", "-5m", "10s"} {
+		if err := cs.SetRefreshInterval(invalid); err == nil {
+			t.Errorf("Expected error for refresh interval %q", invalid)
+		}
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetDataDir(); got != "/tmp/follyo" {
+		t.Errorf("Expected /tmp/follyo after reload, got %s", got)
+	}
+	if got := cs2.GetDefaultPlatform(); got != "Kraken" {
+		t.Errorf("Expected Kraken after reload, got %s", got)
+	}
+	if got := cs2.GetPriceCacheTTL(); got != 10*time.Minute {
+		t.Errorf("Expected 10m after reload, got %v", got)
+	}
+
+	if got := cs2.GetRefreshInterval(); got != 5*time.Minute {
+		t.Errorf("Expected a 5m refresh interval after reload, got %v", got)
+	}
+
+	if err := cs2.SetPriceCacheTTL(""); err != nil {
+		t.Fatalf("Failed to reset price cache TTL: %v", err)
+	}
+	if got := cs2.GetPriceCacheTTL(); got != 0 {
+		t.Errorf("Expected TTL reset, got %v", got)
+	}
+}
+
+func TestListColumns(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+
+	if got := cs.GetListColumns("buy"); got != nil {
+		t.Errorf("Expected no columns chosen, got %v", got)
+	}
+	if err := cs.SetListColumns("buy", []string{"id", "coin", "notes"}); err != nil {
+		t.Fatalf("Failed to set list columns: %v", err)
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetListColumns("buy"); !reflect.DeepEqual(got, []string{"id", "coin", "notes"}) {
+		t.Errorf("Expected the chosen columns after reload, got %v", got)
+	}
+	if got := cs2.GetListColumns("sell"); got != nil {
+		t.Errorf("Expected no sell columns chosen, got %v", got)
+	}
+
+	if err := cs2.SetListColumns("buy", nil); err != nil {
+		t.Fatalf("Failed to reset list columns: %v", err)
+	}
+	if got := cs2.GetListColumns("buy"); got != nil {
+		t.Errorf("Expected the columns reset, got %v", got)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"ticker_mappings": {}, "display_currency": "GBP", "price_max_attempts": 2}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FOLLYO_DISPLAY_CURRENCY", "eur")
+	t.Setenv("FOLLYO_AUTO_SNAPSHOT", "true")
+	t.Setenv("FOLLYO_DEFAULT_PLATFORM", "1234") // Text, though it looks like a number
+
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	if got := cs.GetDisplayCurrency(); got != "EUR" {
+		t.Errorf("Expected EUR from the environment, got %s", got)
+	}
+	if !cs.GetAutoSnapshot() {
+		t.Error("Expected auto snapshot from the environment")
+	}
+	if got := cs.GetDefaultPlatform(); got != "1234" {
+		t.Errorf("Expected platform 1234, got %s", got)
+	}
+	if got := cs.EnvOverrides()["display_currency"]; got != "eur" {
+		t.Errorf("Expected display_currency override eur, got %q", got)
+	}
+
+	// Saving keeps the file's values for overridden settings, but a value
+	// changed by a setter is saved
+	if err := cs.SetDefaultPlatform("Kraken"); err != nil {
+		t.Fatalf("Failed to set default platform: %v", err)
+	}
+	os.Unsetenv("FOLLYO_DISPLAY_CURRENCY")
+	os.Unsetenv("FOLLYO_AUTO_SNAPSHOT")
+	os.Unsetenv("FOLLYO_DEFAULT_PLATFORM")
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if got := cs2.GetDisplayCurrency(); got != "GBP" {
+		t.Errorf("Expected GBP from the file, got %s", got)
+	}
+	if cs2.GetAutoSnapshot() {
+		t.Error("Expected auto snapshot override not to be saved")
+	}
+	if got := cs2.GetDefaultPlatform(); got != "Kraken" {
+		t.Errorf("Expected Kraken to be saved, got %s", got)
+	}
+	if got := cs2.GetPriceAttempts(); got != 2 {
+		t.Errorf("Expected other settings to be kept, got %d attempts", got)
+	}
+
+	t.Setenv("FOLLYO_AUTO_SNAPSHOT", "maybe")
+	if _, err := New(configPath); err == nil || !strings.Contains(err.Error(), "FOLLYO_AUTO_SNAPSHOT") {
+		t.Errorf("Expected error naming the invalid variable, got %v", err)
+	}
+}
+
+func TestImportYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+	yamlPath := filepath.Join(tmpDir, "config.yaml")
+	yaml := `# Follyo settings
+display_currency: "EUR"   # shown in summary
+display_currency_ignored_typo: x
+auto_snapshot: true
+price_max_attempts: 3
+ticker_mappings:
+  btc: bitcoin
+  FOO: foo-token
+theme: light
+`
+	if err := os.WriteFile(yamlPath, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create config store: %v", err)
+	}
+	if err := cs.SetTheme("dark"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SetTickerMapping("FOO", "foo-coin"); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, unknown, err := cs.ImportYAML(yamlPath)
+	if err != nil {
+		t.Fatalf("ImportYAML failed: %v", err)
+	}
+	wantImported := []string{"auto_snapshot", "display_currency", "price_max_attempts", "ticker_mappings"}
+	if !reflect.DeepEqual(imported, wantImported) {
+		t.Errorf("Expected imported %v, got %v", wantImported, imported)
+	}
+	if !reflect.DeepEqual(unknown, []string{"display_currency_ignored_typo"}) {
+		t.Errorf("Expected the typo to be reported, got %v", unknown)
+	}
+
+	cs2, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config store: %v", err)
+	}
+	if cs2.GetDisplayCurrency() != "EUR" || !cs2.GetAutoSnapshot() || cs2.GetPriceAttempts() != 3 {
+		t.Errorf("Expected YAML settings to be saved, got %s %v %d",
+			cs2.GetDisplayCurrency(), cs2.GetAutoSnapshot(), cs2.GetPriceAttempts())
+	}
+	if got := cs2.GetTheme(); got != "dark" {
+		t.Errorf("Expected theme already in the config to be kept, got %s", got)
+	}
+	if cs2.GetTickerMapping("BTC") != "bitcoin" || cs2.GetTickerMapping("FOO") != "foo-coin" {
+		t.Errorf("Expected mappings to be merged, got %v", cs2.GetAllTickerMappings())
+	}
+
+	if err := os.WriteFile(yamlPath, []byte("auto_snapshot: maybe\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cs3, err := New(filepath.Join(tmpDir, "other.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cs3.ImportYAML(yamlPath); err == nil {
+		t.Error("Expected error for invalid value")
+	}
+}
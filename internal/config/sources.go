@@ -0,0 +1,362 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix prefixes the environment variables that override settings, e.g.
+// FOLLYO_DISPLAY_CURRENCY overrides "display_currency"
+const EnvPrefix = "FOLLYO_"
+
+// EnvKeys returns the keys of the settings that can be overridden from the
+// environment: the top-level scalar settings, sorted
+func EnvKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch f.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+			keys = append(keys, jsonKey(f))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvVar returns the environment variable overriding a setting
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// jsonKey returns the name of a Config field in the config file
+func jsonKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// configKeys returns the set of top-level keys of the config file
+func configKeys() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		keys[jsonKey(t.Field(i))] = true
+	}
+	return keys
+}
+
+// envOverride is a setting overridden by an environment variable
+type envOverride struct {
+	value string          // The variable's value
+	raw   json.RawMessage // The value as JSON
+}
+
+// scalarValue converts the text value of a scalar setting, e.g. from the
+// environment, to JSON: a number or boolean where the setting takes one,
+// and a string otherwise
+func scalarValue(key, value string) (json.RawMessage, error) {
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err == nil {
+		switch v.(type) {
+		case float64, bool:
+			if err := unmarshalKey(&Config{}, key, json.RawMessage(value)); err == nil {
+				return json.RawMessage(value), nil
+			}
+		}
+	}
+	quoted, _ := json.Marshal(value)
+	if err := unmarshalKey(&Config{}, key, quoted); err != nil {
+		return nil, fmt.Errorf("invalid value %q", value)
+	}
+	return quoted, nil
+}
+
+// applyEnv overrides settings with the FOLLYO_ environment variables that
+// are set. Overrides only apply in memory: saving keeps the file's values
+// for them unless a setter changed them. Must be called with cs.mu held.
+func (cs *ConfigStore) applyEnv(lookup func(string) (string, bool)) error {
+	for _, key := range EnvKeys() {
+		value, ok := lookup(EnvVar(key))
+		if !ok {
+			continue
+		}
+		raw, err := scalarValue(key, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvVar(key), err)
+		}
+		if err := unmarshalKey(cs.config, key, raw); err != nil {
+			return fmt.Errorf("%s: %w", EnvVar(key), err)
+		}
+		if cs.env == nil {
+			cs.env = make(map[string]envOverride)
+		}
+		cs.env[key] = envOverride{value: value, raw: raw}
+	}
+	return nil
+}
+
+// unmarshalKey sets a single setting of c from its JSON value
+func unmarshalKey(c *Config, key string, raw json.RawMessage) error {
+	data, err := json.Marshal(map[string]json.RawMessage{key: raw})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+// EnvOverrides returns the settings overridden from the environment, keyed
+// by setting, with the variables' values
+func (cs *ConfigStore) EnvOverrides() map[string]string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	overrides := make(map[string]string, len(cs.env))
+	for key, o := range cs.env {
+		overrides[key] = o.value
+	}
+	return overrides
+}
+
+// fileData returns the config as written to the file: the settings, with
+// environment overrides that no setter changed replaced by the file's own
+// values. Must be called with cs.mu held.
+func (cs *ConfigStore) fileData() ([]byte, error) {
+	if len(cs.env) == 0 {
+		return json.MarshalIndent(cs.config, "", "  ")
+	}
+
+	data, err := json.Marshal(cs.config)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, o := range cs.env {
+		current, ok := fields[key]
+		if ok && !bytes.Equal(compactJSON(current), compactJSON(o.raw)) {
+			continue // changed by a setter, so it is saved
+		}
+		if original, ok := cs.fileFields[key]; ok {
+			fields[key] = original
+		} else {
+			delete(fields, key)
+		}
+	}
+
+	// Keep the order of Config's fields, as without overrides
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	t := reflect.TypeOf(Config{})
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		key := jsonKey(t.Field(i))
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, raw, "  ", "  "); err != nil {
+			return nil, err
+		}
+		if !first {
+			buf.WriteString(",\n")
+		}
+		first = false
+		name, _ := json.Marshal(key)
+		fmt.Fprintf(&buf, "  %s: %s", name, indented.Bytes())
+	}
+	buf.WriteString("\n}")
+	return buf.Bytes(), nil
+}
+
+// compactJSON returns raw without insignificant whitespace
+func compactJSON(raw json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
+
+// ImportYAML merges settings from a legacy YAML config file into the config
+// and saves it. Only the subset of YAML such files use is read: "key: value"
+// lines, and maps of "key: value" lines indented under a "key:" line, such
+// as ticker_mappings. Settings already in the config are kept. Returns the
+// keys that were imported and the keys that were not recognized.
+func (cs *ConfigStore) ImportYAML(path string) (imported, unknown []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	known := configKeys()
+	var mergeErr error
+	_, err = cs.update(func(c *Config) bool {
+		imported, unknown = nil, nil
+		current, err := json.Marshal(c)
+		if err != nil {
+			mergeErr = err
+			return false
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(current, &fields); err != nil {
+			mergeErr = err
+			return false
+		}
+		for _, key := range sortedKeys(values) {
+			if !known[key] {
+				unknown = append(unknown, key)
+				continue
+			}
+			if _, overridden := cs.env[key]; overridden {
+				// The environment still wins, so only the file's value changes
+				if _, set := cs.fileFields[key]; set {
+					continue
+				}
+				text, _ := values[key].(string)
+				raw, err := scalarValue(key, text)
+				if err != nil {
+					mergeErr = fmt.Errorf("%s: %s: %w", path, key, err)
+					return false
+				}
+				cs.fileFields[key] = raw
+				imported = append(imported, key)
+				continue
+			}
+			if _, set := fields[key]; set && key != "ticker_mappings" {
+				continue
+			}
+			if err := cs.mergeYAMLValue(key, values[key]); err != nil {
+				mergeErr = fmt.Errorf("%s: %s: %w", path, key, err)
+				return false
+			}
+			imported = append(imported, key)
+		}
+		return len(imported) > 0
+	})
+	if mergeErr != nil {
+		return nil, nil, mergeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return imported, unknown, nil
+}
+
+// mergeYAMLValue sets a setting from a parsed YAML value. Ticker mappings
+// are merged, keeping mappings already in the config. Must be called with
+// cs.mu held.
+func (cs *ConfigStore) mergeYAMLValue(key string, value any) error {
+	switch v := value.(type) {
+	case string:
+		raw, err := scalarValue(key, v)
+		if err != nil {
+			return err
+		}
+		return unmarshalKey(cs.config, key, raw)
+	case map[string]string:
+		if key == "ticker_mappings" {
+			for ticker, id := range v {
+				ticker = strings.ToUpper(ticker)
+				if _, ok := cs.config.TickerMappings[ticker]; !ok {
+					cs.config.TickerMappings[ticker] = id
+				}
+			}
+			return nil
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return unmarshalKey(cs.config, key, raw)
+	}
+	return fmt.Errorf("unsupported value")
+}
+
+// parseYAML parses "key: value" lines and one level of indented maps into
+// strings and map[string]string values
+func parseYAML(data []byte) (map[string]any, error) {
+	values := make(map[string]any)
+	var mapKey string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripYAMLComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key, value = unquoteYAML(strings.TrimSpace(key)), unquoteYAML(strings.TrimSpace(value))
+
+		if indented {
+			if mapKey == "" {
+				return nil, fmt.Errorf("line %d: unexpected indentation", n)
+			}
+			values[mapKey].(map[string]string)[key] = value
+			continue
+		}
+		if value == "" {
+			mapKey = key
+			values[key] = make(map[string]string)
+			continue
+		}
+		mapKey = ""
+		values[key] = value
+	}
+	for key, v := range values {
+		if m, ok := v.(map[string]string); ok && len(m) == 0 {
+			delete(values, key) // A key with neither a value nor entries
+		}
+	}
+	return values, scanner.Err()
+}
+
+// stripYAMLComment removes a "#" comment outside of quotes from a line
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
+
+// unquoteYAML removes the quotes around a quoted scalar
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
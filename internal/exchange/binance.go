@@ -0,0 +1,169 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const binanceURL = "https://api.binance.com"
+
+// binanceQuote is the quote asset trades are looked up against.
+const binanceQuote = "USDT"
+
+// binanceInvalidSymbol is the API error code for an unknown trading pair.
+const binanceInvalidSymbol = -1121
+
+// Binance reads spot balances and trades from the Binance API.
+type Binance struct {
+	client    *http.Client
+	baseURL   string
+	apiKey    string
+	apiSecret string
+}
+
+// Name returns the exchange's display name.
+func (b *Binance) Name() string {
+	return "Binance"
+}
+
+// Balances returns free plus locked spot balances.
+func (b *Binance) Balances() (map[string]float64, error) {
+	// Response format: {"balances":[{"asset":"BTC","free":"0.1","locked":"0.0"},...]}
+	var data struct {
+		Balances []struct {
+			Asset  string `json:"asset"`
+			Free   string `json:"free"`
+			Locked string `json:"locked"`
+		} `json:"balances"`
+	}
+	if err := b.get("/api/v3/account", url.Values{}, &data); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64)
+	for _, bal := range data.Balances {
+		free, _ := strconv.ParseFloat(bal.Free, 64)
+		locked, _ := strconv.ParseFloat(bal.Locked, 64)
+		if total := free + locked; total != 0 {
+			result[strings.ToUpper(bal.Asset)] = total
+		}
+	}
+	return result, nil
+}
+
+// Trades returns each coin's trades against USDT. Coins without a USDT
+// pair are skipped. Commissions paid in USDT or in the coin itself are
+// converted to a USD fee; commissions in other assets (e.g. BNB) are not
+// included.
+func (b *Binance) Trades(coins []string) ([]Trade, error) {
+	var trades []Trade
+	for _, coin := range coins {
+		coin = strings.ToUpper(coin)
+		if usdQuotes[coin] {
+			continue
+		}
+
+		// Response format: [{"id":28457,"price":"4.00","qty":"12.00","commission":"0.01",
+		// "commissionAsset":"USDT","time":1499865549590,"isBuyer":true},...]
+		var data []struct {
+			ID              int64  `json:"id"`
+			Price           string `json:"price"`
+			Qty             string `json:"qty"`
+			Commission      string `json:"commission"`
+			CommissionAsset string `json:"commissionAsset"`
+			Time            int64  `json:"time"`
+			IsBuyer         bool   `json:"isBuyer"`
+		}
+		params := url.Values{}
+		params.Set("symbol", coin+binanceQuote)
+		err := b.get("/api/v3/myTrades", params, &data)
+		var apiErr *binanceError
+		if errors.As(err, &apiErr) && apiErr.Code == binanceInvalidSymbol {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range data {
+			price, _ := decimal.NewFromString(d.Price)
+			qty, _ := decimal.NewFromString(d.Qty)
+			commission, _ := decimal.NewFromString(d.Commission)
+
+			trade := Trade{
+				Exchange: "binance",
+				ID:       strconv.FormatInt(d.ID, 10),
+				Coin:     coin,
+				Side:     SideSell,
+				Amount:   qty,
+				PriceUSD: price,
+				Time:     time.UnixMilli(d.Time),
+			}
+			if d.IsBuyer {
+				trade.Side = SideBuy
+			}
+			switch strings.ToUpper(d.CommissionAsset) {
+			case binanceQuote:
+				trade.FeeUSD = commission
+			case coin:
+				trade.FeeUSD = commission.Mul(price)
+			}
+			trades = append(trades, trade)
+		}
+	}
+	sortTrades(trades)
+	return trades, nil
+}
+
+// binanceError is an error response from the Binance API.
+type binanceError struct {
+	Status  int
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+func (e *binanceError) Error() string {
+	return fmt.Sprintf("Binance API returned status %d: %s", e.Status, e.Message)
+}
+
+// get performs a signed GET request and decodes the JSON response into out.
+func (b *Binance) get(path string, params url.Values, out any) error {
+	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(b.apiSecret))
+	mac.Write([]byte(query))
+	query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodGet, b.baseURL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", b.apiKey)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Binance: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &binanceError{Status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Binance response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Package exchange pulls balances and trade history from exchange APIs using
+// read-only API keys, so they can be proposed as portfolio records.
+package exchange
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Supported lists the exchanges that can be synced.
+var Supported = []string{"binance", "coinbase"}
+
+// Trade sides.
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Trade is a single executed trade of a coin against USD (or a USD stablecoin).
+type Trade struct {
+	Exchange string
+	ID       string
+	Coin     string
+	Side     string
+	Amount   decimal.Decimal
+	PriceUSD decimal.Decimal
+	FeeUSD   decimal.Decimal
+	Time     time.Time // When the trade executed
+}
+
+// Ref returns a stable reference to the trade, recorded in the notes of
+// imported records so the same trade is not imported twice.
+func (t Trade) Ref() string {
+	return fmt.Sprintf("%s trade %s", t.Exchange, t.ID)
+}
+
+// Client reads account data from an exchange.
+type Client interface {
+	// Name returns the exchange's display name.
+	Name() string
+	// Balances returns the total balance of each coin held, by ticker.
+	Balances() (map[string]float64, error)
+	// Trades returns executed trades of the given coins against USD,
+	// oldest first. Exchanges that list trades per account may also return
+	// trades of other coins.
+	Trades(coins []string) ([]Trade, error)
+}
+
+// New creates a client for a supported exchange.
+func New(name, apiKey, apiSecret string) (Client, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	switch strings.ToLower(name) {
+	case "binance":
+		return &Binance{client: client, baseURL: binanceURL, apiKey: apiKey, apiSecret: apiSecret}, nil
+	case "coinbase":
+		return &Coinbase{client: client, baseURL: coinbaseURL, apiKey: apiKey, apiSecret: apiSecret}, nil
+	}
+	return nil, fmt.Errorf("unsupported exchange %q (supported: %s)", name, strings.Join(Supported, ", "))
+}
+
+// IsSupported reports whether name is a supported exchange.
+func IsSupported(name string) bool {
+	for _, s := range Supported {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// usdQuotes are the quote currencies treated as USD.
+var usdQuotes = map[string]bool{"USD": true, "USDT": true, "USDC": true, "BUSD": true}
+
+// sortTrades orders trades by date, keeping the exchange's order within a day.
+func sortTrades(trades []Trade) {
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+}
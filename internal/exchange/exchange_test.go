@@ -0,0 +1,149 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"binance", "Coinbase"} {
+		if _, err := New(name, "key", "secret"); err != nil {
+			t.Errorf("New(%s) failed: %v", name, err)
+		}
+	}
+	if _, err := New("kraken", "key", "secret"); err == nil {
+		t.Error("Expected error for unsupported exchange")
+	}
+	if !IsSupported("BINANCE") || IsSupported("kraken") {
+		t.Error("IsSupported returned wrong result")
+	}
+}
+
+func TestBinance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-MBX-APIKEY") != "key" {
+			t.Errorf("Expected API key header")
+		}
+		// The signature covers the query string preceding it
+		query := r.URL.RawQuery
+		i := strings.LastIndex(query, "&signature=")
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(query[:i]))
+		if query[i+len("&signature="):] != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("Invalid signature for %s", query)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path + "?" + r.URL.Query().Get("symbol") {
+		case "/api/v3/account?":
+			w.Write([]byte(`{"balances":[{"asset":"BTC","free":"0.5","locked":"0.25"},{"asset":"ETH","free":"0","locked":"0"},{"asset":"USDT","free":"100","locked":"0"}]}`))
+		case "/api/v3/myTrades?BTCUSDT":
+			w.Write([]byte(`[{"id":2,"price":"50000","qty":"0.25","commission":"0.001","commissionAsset":"BTC","time":1709294400000,"isBuyer":false},` +
+				`{"id":1,"price":"40000","qty":"1","commission":"10","commissionAsset":"USDT","time":1704110400000,"isBuyer":true}]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+		}
+	}))
+	defer server.Close()
+
+	b := &Binance{client: server.Client(), baseURL: server.URL, apiKey: "key", apiSecret: "secret"}
+
+	balances, err := b.Balances()
+	if err != nil {
+		t.Fatalf("Balances failed: %v", err)
+	}
+	if len(balances) != 2 || balances["BTC"] != 0.75 || balances["USDT"] != 100 {
+		t.Errorf("Unexpected balances: %v", balances)
+	}
+
+	trades, err := b.Trades([]string{"btc", "NOPE", "USDT"})
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %+v", trades)
+	}
+	buy, sell := trades[0], trades[1]
+	if buy.Side != SideBuy || !buy.Time.Equal(time.UnixMilli(1704110400000)) || !buy.Amount.Equal(dec(1)) || !buy.PriceUSD.Equal(dec(40000)) || !buy.FeeUSD.Equal(dec(10)) {
+		t.Errorf("Unexpected buy: %+v", buy)
+	}
+	if sell.Side != SideSell || !sell.Time.Equal(time.UnixMilli(1709294400000)) || !sell.FeeUSD.Equal(dec(50)) || sell.Ref() != "binance trade 2" {
+		t.Errorf("Unexpected sell: %+v", sell)
+	}
+}
+
+func TestBinanceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`))
+	}))
+	defer server.Close()
+
+	b := &Binance{client: server.Client(), baseURL: server.URL}
+	if _, err := b.Trades([]string{"BTC"}); err == nil || !strings.Contains(err.Error(), "Invalid API-key") {
+		t.Errorf("Expected API key error, got %v", err)
+	}
+}
+
+func TestCoinbase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("CB-ACCESS-KEY") != "key" || r.Header.Get("CB-ACCESS-SIGN") == "" {
+			t.Errorf("Expected signed request")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.RequestURI() {
+		case "/v2/accounts?limit=100":
+			w.Write([]byte(`{"data":[{"id":"a1","currency":{"code":"ETH"},"balance":{"amount":"1.5","currency":"ETH"}}],` +
+				`"pagination":{"next_uri":"/v2/accounts?limit=100&starting_after=a1"}}`))
+		case "/v2/accounts?limit=100&starting_after=a1":
+			w.Write([]byte(`{"data":[{"id":"a2","currency":{"code":"DOGE"},"balance":{"amount":"0","currency":"DOGE"}}],"pagination":{"next_uri":null}}`))
+		case "/v2/accounts/a1/transactions":
+			w.Write([]byte(`{"data":[` +
+				`{"id":"t2","type":"sell","amount":{"amount":"-0.5","currency":"ETH"},"native_amount":{"amount":"-1500.00","currency":"USD"},"created_at":"2024-02-01T10:00:00Z"},` +
+				`{"id":"t1","type":"buy","amount":{"amount":"2","currency":"ETH"},"native_amount":{"amount":"4000.00","currency":"USD"},"created_at":"2024-01-01T10:00:00Z"},` +
+				`{"id":"t3","type":"send","amount":{"amount":"-0.1","currency":"ETH"},"native_amount":{"amount":"-300.00","currency":"USD"},"created_at":"2024-03-01T10:00:00Z"}]}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Coinbase{client: server.Client(), baseURL: server.URL, apiKey: "key", apiSecret: "secret"}
+
+	balances, err := c.Balances()
+	if err != nil {
+		t.Fatalf("Balances failed: %v", err)
+	}
+	if len(balances) != 1 || balances["ETH"] != 1.5 {
+		t.Errorf("Unexpected balances: %v", balances)
+	}
+
+	trades, err := c.Trades(nil)
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %+v", trades)
+	}
+	if buy := trades[0]; buy.ID != "t1" || buy.Side != SideBuy || !buy.Amount.Equal(dec(2)) || !buy.PriceUSD.Equal(dec(2000)) {
+		t.Errorf("Unexpected buy: %+v", buy)
+	}
+	if sell := trades[1]; sell.ID != "t2" || sell.Side != SideSell || !sell.Amount.Equal(dec(0.5)) || !sell.PriceUSD.Equal(dec(3000)) {
+		t.Errorf("Unexpected sell: %+v", sell)
+	}
+}
+
+// dec returns v as a decimal, for writing record amounts as literals
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
@@ -0,0 +1,92 @@
+// Package logging writes follyo's diagnostic log: API requests, cache hits
+// and misses, and storage writes, logged by the other packages with log/slog.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the name of the log file in the log directory
+const FileName = "follyo.log"
+
+// MaxFileSize is the size at which the log file is rotated when opened
+const MaxFileSize = 1 << 20
+
+// MaxFiles is the number of rotated log files kept as follyo.log.1 (most
+// recent) through follyo.log.N
+const MaxFiles = 3
+
+// OpenFile opens the log file in dir for appending, creating dir if needed.
+// A log file of MaxFileSize or more is rotated first.
+func OpenFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, FileName)
+	if info, err := os.Stat(path); err == nil && info.Size() >= MaxFileSize {
+		if err := rotate(path); err != nil {
+			return nil, fmt.Errorf("rotating log file: %w", err)
+		}
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+// rotate shifts the rotated log files up by one, dropping the oldest, and
+// moves the log file to follyo.log.1
+func rotate(path string) error {
+	for n := MaxFiles - 1; n >= 1; n-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, n), fmt.Sprintf("%s.%d", path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// Tee returns a handler passing each record to all of handlers that are
+// enabled for its level
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(handlers)
+}
+
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
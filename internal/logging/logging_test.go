@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenFileRotates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, FileName)
+
+	// Fill the log past MaxFileSize, MaxFiles+1 times
+	for i := 0; i <= MaxFiles; i++ {
+		f, err := OpenFile(dir)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		f.Write(bytes.Repeat([]byte{byte('a' + i)}, MaxFileSize))
+		f.Close()
+	}
+
+	f, err := OpenFile(dir)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	f.Close()
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("Expected a new empty log file, got %v, %v", info, err)
+	}
+	for n, want := range map[int]byte{1: 'a' + MaxFiles, MaxFiles: 'a' + 1} {
+		data, err := os.ReadFile(fmt.Sprintf("%s.%d", path, n))
+		if err != nil {
+			t.Fatalf("Expected rotated file %d: %v", n, err)
+		}
+		if data[0] != want {
+			t.Errorf("Expected rotated file %d to hold %c, got %c", n, want, data[0])
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, MaxFiles+1)); !os.IsNotExist(err) {
+		t.Errorf("Expected at most %d rotated files", MaxFiles)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var debug, info bytes.Buffer
+	logger := slog.New(Tee(
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+	)).With("component", "test")
+
+	logger.Debug("cache hit")
+	logger.Info("request")
+
+	if !strings.Contains(debug.String(), "cache hit") || !strings.Contains(debug.String(), "request") {
+		t.Errorf("Expected both records at debug level, got: %s", debug.String())
+	}
+	if strings.Contains(info.String(), "cache hit") || !strings.Contains(info.String(), "component=test") {
+		t.Errorf("Expected only the info record with its attrs, got: %s", info.String())
+	}
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format dates are stored and displayed in.
+const DateLayout = "2006-01-02"
+
+// dateLayouts are the formats ParseDate accepts. Single-digit months and
+// days are accepted by the numeric layouts.
+var dateLayouts = []string{"2006-1-2", "2006/1/2", time.RFC3339}
+
+// Date is a calendar day, held as midnight UTC so that it compares and
+// formats the same in every time zone. It is stored in JSON as YYYY-MM-DD.
+// The zero Date means no date.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the given calendar day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the calendar day of t in t's location.
+func DateOf(t time.Time) Date {
+	return NewDate(t.Year(), t.Month(), t.Day())
+}
+
+// Today returns the current calendar day in the local time zone.
+func Today() Date {
+	return DateOf(time.Now())
+}
+
+// ParseDate parses a date given as YYYY-MM-DD, YYYY/MM/DD, or an RFC 3339
+// timestamp, rejecting impossible dates such as 2024-13-45. A timestamp is
+// taken as the calendar day at its own UTC offset.
+func ParseDate(s string) (Date, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return DateOf(t), nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", s)
+}
+
+// ParseNaturalDate parses a date typed by a user: any format accepted by
+// ParseDate, "today", "yesterday", "N days ago" (or weeks, months, years),
+// a month name and day with an optional year such as "jan 5" or "5 January
+// 2024", or MM/DD/YYYY such as "05/01/2024" (DD/MM/YYYY when the first
+// number can't be a month). A month and day without a year is the latest
+// such day up to today.
+func ParseNaturalDate(s string) (Date, error) {
+	return parseNaturalDate(s, Today())
+}
+
+// parseNaturalDate parses a date as ParseNaturalDate, relative to today
+func parseNaturalDate(s string, today Date) (Date, error) {
+	if d, err := ParseDate(s); err == nil {
+		return d, nil
+	}
+	invalid := fmt.Errorf("invalid date %q: expected YYYY-MM-DD, e.g. \"yesterday\", \"3 days ago\", or \"jan 5\"", s)
+
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(s, ",", " ")))
+	switch {
+	case len(fields) == 1 && fields[0] == "today":
+		return today, nil
+	case len(fields) == 1 && fields[0] == "yesterday":
+		return today.AddDays(-1), nil
+	case len(fields) == 3 && fields[2] == "ago":
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			return Date{}, invalid
+		}
+		switch strings.TrimSuffix(fields[1], "s") {
+		case "day":
+			return today.AddDays(-n), nil
+		case "week":
+			return today.AddDays(-7 * n), nil
+		case "month":
+			return Date{today.AddDate(0, -n, 0)}, nil
+		case "year":
+			return Date{today.AddDate(-n, 0, 0)}, nil
+		}
+		return Date{}, invalid
+	case len(fields) == 1 && strings.Count(fields[0], "/") == 2:
+		parts := strings.Split(fields[0], "/")
+		month, err1 := strconv.Atoi(parts[0])
+		day, err2 := strconv.Atoi(parts[1])
+		year, err3 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || err3 != nil || len(parts[2]) != 4 {
+			return Date{}, invalid
+		}
+		if month > 12 {
+			month, day = day, month
+		}
+		return validDate(year, month, day, invalid)
+	case len(fields) == 2 || len(fields) == 3:
+		// "jan 5" or "5 jan", with an optional year
+		month, day := monthNamed(fields[0]), fields[1]
+		if month == 0 {
+			month, day = monthNamed(fields[1]), fields[0]
+		}
+		d, err := strconv.Atoi(day)
+		if month == 0 || err != nil {
+			return Date{}, invalid
+		}
+		if len(fields) == 3 {
+			year, err := strconv.Atoi(fields[2])
+			if err != nil || len(fields[2]) != 4 {
+				return Date{}, invalid
+			}
+			return validDate(year, int(month), d, invalid)
+		}
+		date, err := validDate(today.Year(), int(month), d, invalid)
+		if err == nil && date.After(today) {
+			date, err = validDate(today.Year()-1, int(month), d, invalid)
+		}
+		return date, err
+	}
+	return Date{}, invalid
+}
+
+// validDate returns the given day, or err if there is no such day
+func validDate(year, month, day int, err error) (Date, error) {
+	d := NewDate(year, time.Month(month), day)
+	if month < 1 || month > 12 || d.Day() != day {
+		return Date{}, err
+	}
+	return d, nil
+}
+
+// monthNamed returns the month a name or its first three or more letters
+// names, or 0 if none
+func monthNamed(name string) time.Month {
+	if len(name) < 3 {
+		return 0
+	}
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), name) {
+			return m
+		}
+	}
+	return 0
+}
+
+// String formats the date as YYYY-MM-DD, or "" for the zero Date.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+// Before reports whether d is an earlier day than other.
+func (d Date) Before(other Date) bool {
+	return d.Time.Before(other.Time)
+}
+
+// After reports whether d is a later day than other.
+func (d Date) After(other Date) bool {
+	return d.Time.After(other.Time)
+}
+
+// AddDays returns the date n days after d.
+func (d Date) AddDays(n int) Date {
+	return Date{d.AddDate(0, 0, n)}
+}
+
+// DaysSince returns the number of days from other to d.
+func (d Date) DaysSince(other Date) float64 {
+	return d.Sub(other.Time).Hours() / 24
+}
+
+// MarshalJSON encodes the date as a YYYY-MM-DD string.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a date in any format accepted by ParseDate. An
+// empty string decodes to the zero Date.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
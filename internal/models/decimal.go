@@ -0,0 +1,83 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Record amounts, prices and fees are decimal.Decimal values, stored as
+// decimal strings, so they keep every digit they were entered with. Values
+// derived from market prices arrive as float64; the float helpers below take
+// their operands at their shortest decimal form, compute exactly, and round
+// the result back to the nearest float64, which avoids artifacts such as
+// 0.1 + 0.2 = 0.30000000000000004.
+
+// storedPlaces is the number of decimal places kept when normalizing stored
+// values. It is well beyond the precision of any coin or fiat amount while
+// still discarding float noise in the last few significant digits.
+const storedPlaces = 12
+
+// Add returns the decimal sum of the given values.
+func Add(values ...float64) float64 {
+	sum := decimal.Zero
+	for _, v := range values {
+		sum = sum.Add(decimal.NewFromFloat(v))
+	}
+	return sum.InexactFloat64()
+}
+
+// Sub returns a - b computed in decimal.
+func Sub(a, b float64) float64 {
+	return decimal.NewFromFloat(a).Sub(decimal.NewFromFloat(b)).InexactFloat64()
+}
+
+// Mul returns a * b computed in decimal.
+func Mul(a, b float64) float64 {
+	return decimal.NewFromFloat(a).Mul(decimal.NewFromFloat(b)).InexactFloat64()
+}
+
+// Quo returns a / b rounded to the places kept when storing values.
+func Quo(a, b decimal.Decimal) decimal.Decimal {
+	return a.DivRound(b, storedPlaces)
+}
+
+// AtPrice returns amount valued at a market price or exchange rate, computed
+// in decimal.
+func AtPrice(amount decimal.Decimal, price float64) decimal.Decimal {
+	return amount.Mul(decimal.NewFromFloat(price))
+}
+
+// Float returns d as the nearest float64, for display and for arithmetic
+// with market prices.
+func Float(d decimal.Decimal) float64 {
+	return d.InexactFloat64()
+}
+
+// Normalize rounds away float noise left by earlier binary arithmetic.
+func Normalize(v float64) float64 {
+	return decimal.NewFromFloat(v).Round(storedPlaces).InexactFloat64()
+}
+
+// Totals accumulates per-key amounts in decimal.
+type Totals map[string]decimal.Decimal
+
+// Add adds v to the total for key.
+func (t Totals) Add(key string, v decimal.Decimal) {
+	t[key] = t[key].Add(v)
+}
+
+// Sub subtracts v from the total for key.
+func (t Totals) Sub(key string, v decimal.Decimal) {
+	t[key] = t[key].Sub(v)
+}
+
+// Get returns the total for key as a float64.
+func (t Totals) Get(key string) float64 {
+	return t[key].InexactFloat64()
+}
+
+// Floats returns the totals as float64 values.
+func (t Totals) Floats() map[string]float64 {
+	out := make(map[string]float64, len(t))
+	for key, d := range t {
+		out[key] = d.InexactFloat64()
+	}
+	return out
+}
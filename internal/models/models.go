@@ -1,26 +1,31 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // Holding represents a crypto holding/purchase.
 type Holding struct {
-	ID               string  `json:"id"`
-	Coin             string  `json:"coin"`
-	Amount           float64 `json:"amount"`
-	PurchasePriceUSD float64 `json:"purchase_price_usd"`
-	Date             string  `json:"date"`
-	Platform         string  `json:"platform,omitempty"`
-	Notes            string  `json:"notes,omitempty"`
+	ID               string          `json:"id"`
+	Coin             string          `json:"coin"`
+	Amount           decimal.Decimal `json:"amount"`
+	PurchasePriceUSD decimal.Decimal `json:"purchase_price_usd"`
+	FeeUSD           decimal.Decimal `json:"fee_usd,omitzero"`
+	Date             Date            `json:"date"`
+	Platform         string          `json:"platform,omitempty"`
+	Notes            string          `json:"notes,omitempty"`
+	Tags             []string        `json:"tags,omitempty"`
 }
 
 // NewHolding creates a new holding with auto-generated ID and date.
-func NewHolding(coin string, amount, purchasePriceUSD float64, platform, notes, date string) Holding {
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// A zero date defaults to today.
+func NewHolding(coin string, amount, purchasePriceUSD decimal.Decimal, platform, notes string, date Date) Holding {
+	if date.IsZero() {
+		date = Today()
 	}
 	return Holding{
 		ID:               uuid.New().String()[:8],
@@ -34,25 +39,40 @@ func NewHolding(coin string, amount, purchasePriceUSD float64, platform, notes,
 }
 
 // TotalValueUSD returns the total value at purchase price.
-func (h Holding) TotalValueUSD() float64 {
-	return h.Amount * h.PurchasePriceUSD
+func (h Holding) TotalValueUSD() decimal.Decimal {
+	return h.Amount.Mul(h.PurchasePriceUSD)
+}
+
+// CostUSD returns the total cost of the purchase including fees.
+func (h Holding) CostUSD() decimal.Decimal {
+	return h.TotalValueUSD().Add(h.FeeUSD)
+}
+
+// CostPerUnitUSD returns the cost per coin including fees.
+func (h Holding) CostPerUnitUSD() decimal.Decimal {
+	if h.Amount.IsZero() {
+		return h.PurchasePriceUSD
+	}
+	return Quo(h.CostUSD(), h.Amount)
 }
 
 // Loan represents a crypto loan on a platform.
 type Loan struct {
-	ID           string   `json:"id"`
-	Coin         string   `json:"coin"`
-	Amount       float64  `json:"amount"`
-	Platform     string   `json:"platform"`
-	Date         string   `json:"date"`
-	InterestRate *float64 `json:"interest_rate,omitempty"`
-	Notes        string   `json:"notes,omitempty"`
+	ID           string          `json:"id"`
+	Coin         string          `json:"coin"`
+	Amount       decimal.Decimal `json:"amount"`
+	Platform     string          `json:"platform"`
+	Date         Date            `json:"date"`
+	InterestRate *float64        `json:"interest_rate,omitempty"`
+	Notes        string          `json:"notes,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
 }
 
 // NewLoan creates a new loan with auto-generated ID and date.
-func NewLoan(coin string, amount float64, platform string, interestRate *float64, notes, date string) Loan {
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// A zero date defaults to today.
+func NewLoan(coin string, amount decimal.Decimal, platform string, interestRate *float64, notes string, date Date) Loan {
+	if date.IsZero() {
+		date = Today()
 	}
 	return Loan{
 		ID:           uuid.New().String()[:8],
@@ -65,21 +85,50 @@ func NewLoan(coin string, amount float64, platform string, interestRate *float64
 	}
 }
 
+// Repayment represents a partial or full repayment of a loan.
+type Repayment struct {
+	ID     string          `json:"id"`
+	LoanID string          `json:"loan_id"`
+	Amount decimal.Decimal `json:"amount"`
+	Date   Date            `json:"date"`
+	Notes  string          `json:"notes,omitempty"`
+	Tags   []string        `json:"tags,omitempty"`
+}
+
+// NewRepayment creates a new loan repayment with auto-generated ID and date.
+// A zero date defaults to today.
+func NewRepayment(loanID string, amount decimal.Decimal, notes string, date Date) Repayment {
+	if date.IsZero() {
+		date = Today()
+	}
+	return Repayment{
+		ID:     uuid.New().String()[:8],
+		LoanID: loanID,
+		Amount: amount,
+		Date:   date,
+		Notes:  notes,
+	}
+}
+
 // Sale represents a crypto sale.
 type Sale struct {
-	ID           string  `json:"id"`
-	Coin         string  `json:"coin"`
-	Amount       float64 `json:"amount"`
-	SellPriceUSD float64 `json:"sell_price_usd"`
-	Date         string  `json:"date"`
-	Platform     string  `json:"platform,omitempty"`
-	Notes        string  `json:"notes,omitempty"`
+	ID           string          `json:"id"`
+	Coin         string          `json:"coin"`
+	Amount       decimal.Decimal `json:"amount"`
+	SellPriceUSD decimal.Decimal `json:"sell_price_usd"`
+	FeeUSD       decimal.Decimal `json:"fee_usd,omitzero"`
+	Date         Date            `json:"date"`
+	Platform     string          `json:"platform,omitempty"`
+	Notes        string          `json:"notes,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	LotIDs       []string        `json:"lot_ids,omitempty"` // Purchases or swaps the coins came from, matched before FIFO
 }
 
 // NewSale creates a new sale with auto-generated ID and date.
-func NewSale(coin string, amount, sellPriceUSD float64, platform, notes, date string) Sale {
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// A zero date defaults to today.
+func NewSale(coin string, amount, sellPriceUSD decimal.Decimal, platform, notes string, date Date) Sale {
+	if date.IsZero() {
+		date = Today()
 	}
 	return Sale{
 		ID:           uuid.New().String()[:8],
@@ -93,25 +142,40 @@ func NewSale(coin string, amount, sellPriceUSD float64, platform, notes, date st
 }
 
 // TotalValueUSD returns the total value at sell price.
-func (s Sale) TotalValueUSD() float64 {
-	return s.Amount * s.SellPriceUSD
+func (s Sale) TotalValueUSD() decimal.Decimal {
+	return s.Amount.Mul(s.SellPriceUSD)
+}
+
+// ProceedsUSD returns the total received from the sale after fees.
+func (s Sale) ProceedsUSD() decimal.Decimal {
+	return s.TotalValueUSD().Sub(s.FeeUSD)
+}
+
+// ProceedsPerUnitUSD returns the proceeds per coin after fees.
+func (s Sale) ProceedsPerUnitUSD() decimal.Decimal {
+	if s.Amount.IsZero() {
+		return s.SellPriceUSD
+	}
+	return Quo(s.ProceedsUSD(), s.Amount)
 }
 
 // Stake represents crypto that is staked on a platform.
 type Stake struct {
-	ID       string   `json:"id"`
-	Coin     string   `json:"coin"`
-	Amount   float64  `json:"amount"`
-	Platform string   `json:"platform"`
-	Date     string   `json:"date"`
-	APY      *float64 `json:"apy,omitempty"`
-	Notes    string   `json:"notes,omitempty"`
+	ID       string          `json:"id"`
+	Coin     string          `json:"coin"`
+	Amount   decimal.Decimal `json:"amount"`
+	Platform string          `json:"platform"`
+	Date     Date            `json:"date"`
+	APY      *float64        `json:"apy,omitempty"`
+	Notes    string          `json:"notes,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
 }
 
 // NewStake creates a new stake with auto-generated ID and date.
-func NewStake(coin string, amount float64, platform string, apy *float64, notes, date string) Stake {
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// A zero date defaults to today.
+func NewStake(coin string, amount decimal.Decimal, platform string, apy *float64, notes string, date Date) Stake {
+	if date.IsZero() {
+		date = Today()
 	}
 	return Stake{
 		ID:       uuid.New().String()[:8],
@@ -123,3 +187,152 @@ func NewStake(coin string, amount float64, platform string, apy *float64, notes,
 		Notes:    notes,
 	}
 }
+
+// Transfer represents moving crypto between platforms. Amount leaves the
+// source platform and Amount - Fee arrives at the destination.
+type Transfer struct {
+	ID           string          `json:"id"`
+	Coin         string          `json:"coin"`
+	Amount       decimal.Decimal `json:"amount"`
+	FromPlatform string          `json:"from_platform"`
+	ToPlatform   string          `json:"to_platform"`
+	Fee          decimal.Decimal `json:"fee,omitzero"` // In coin units
+	Date         Date            `json:"date"`
+	Notes        string          `json:"notes,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+}
+
+// NewTransfer creates a new transfer with auto-generated ID and date.
+// A zero date defaults to today.
+func NewTransfer(coin string, amount decimal.Decimal, fromPlatform, toPlatform string, fee decimal.Decimal, notes string, date Date) Transfer {
+	if date.IsZero() {
+		date = Today()
+	}
+	return Transfer{
+		ID:           uuid.New().String()[:8],
+		Coin:         coin,
+		Amount:       amount,
+		FromPlatform: fromPlatform,
+		ToPlatform:   toPlatform,
+		Fee:          fee,
+		Date:         date,
+		Notes:        notes,
+	}
+}
+
+// Received returns the amount that arrives at the destination platform.
+func (t Transfer) Received() decimal.Decimal {
+	return t.Amount.Sub(t.Fee)
+}
+
+// Swap represents exchanging one coin directly for another. ValueUSD is the
+// implied USD value of the exchange at swap time, used as the proceeds of
+// the coin given up and the cost basis of the coin received.
+type Swap struct {
+	ID         string          `json:"id"`
+	FromCoin   string          `json:"from_coin"`
+	FromAmount decimal.Decimal `json:"from_amount"`
+	ToCoin     string          `json:"to_coin"`
+	ToAmount   decimal.Decimal `json:"to_amount"`
+	ValueUSD   decimal.Decimal `json:"value_usd"`
+	Date       Date            `json:"date"`
+	Platform   string          `json:"platform,omitempty"`
+	Notes      string          `json:"notes,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
+}
+
+// NewSwap creates a new swap with auto-generated ID and date.
+// A zero date defaults to today.
+func NewSwap(fromCoin string, fromAmount decimal.Decimal, toCoin string, toAmount, valueUSD decimal.Decimal, platform, notes string, date Date) Swap {
+	if date.IsZero() {
+		date = Today()
+	}
+	return Swap{
+		ID:         uuid.New().String()[:8],
+		FromCoin:   fromCoin,
+		FromAmount: fromAmount,
+		ToCoin:     toCoin,
+		ToAmount:   toAmount,
+		ValueUSD:   valueUSD,
+		Date:       date,
+		Platform:   platform,
+		Notes:      notes,
+	}
+}
+
+// CoinSnapshot captures a coin's position and price at the time of a snapshot.
+type CoinSnapshot struct {
+	Amount   float64 `json:"amount"`
+	PriceUSD float64 `json:"price_usd"`
+	ValueUSD float64 `json:"value_usd"`
+	GeckoID  string  `json:"gecko_id,omitempty"` // Price mapping in effect when taken
+	Manual   bool    `json:"manual,omitempty"`   // Priced by hand rather than fetched
+}
+
+// Snapshot is a point-in-time record of the portfolio's value.
+type Snapshot struct {
+	ID                string                  `json:"id"`
+	Timestamp         time.Time               `json:"timestamp"`
+	HoldingsValue     float64                 `json:"holdings_value"`
+	LoansValue        float64                 `json:"loans_value"`
+	InterestValue     float64                 `json:"interest_value,omitempty"` // Accrued loan interest, included in LoansValue
+	NetValue          float64                 `json:"net_value"`
+	TotalInvested     float64                 `json:"total_invested"`
+	TotalSold         float64                 `json:"total_sold"`
+	ProfitLoss        float64                 `json:"profit_loss"`
+	ProfitLossPercent float64                 `json:"profit_loss_percent"`
+	RealizedPL        float64                 `json:"realized_pl,omitempty"`   // Gain from sales and swaps
+	UnrealizedPL      float64                 `json:"unrealized_pl,omitempty"` // Gain of the coins still held, before loans
+	CoinValues        map[string]CoinSnapshot `json:"coin_values"`
+	Note              string                  `json:"note,omitempty"`
+	Pin               string                  `json:"pin,omitempty"` // Name the snapshot is pinned under, e.g. "pre-halving"
+}
+
+// NewSnapshot creates an empty snapshot with auto-generated ID.
+// A zero timestamp defaults to now.
+func NewSnapshot(timestamp time.Time, note string) Snapshot {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return Snapshot{
+		ID:         uuid.New().String()[:8],
+		Timestamp:  timestamp,
+		CoinValues: make(map[string]CoinSnapshot),
+		Note:       note,
+	}
+}
+
+// TrashItem is a removed record, kept so it can be restored. Record holds
+// the record's JSON; Coin and Amount are copied from it for display.
+type TrashItem struct {
+	ID         string          `json:"id"`   // ID of the removed record
+	Type       string          `json:"type"` // "holding", "sale", "loan", or "stake"
+	Coin       string          `json:"coin"`
+	Amount     decimal.Decimal `json:"amount"`
+	DeletedAt  time.Time       `json:"deleted_at"`
+	Record     json.RawMessage `json:"record"`
+	Repayments []Repayment     `json:"repayments,omitempty"` // A loan's repayments, removed with it
+}
+
+// NewTrashItem wraps a removed record of the given type.
+func NewTrashItem(recordType, id string, record any) (TrashItem, error) {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	var fields struct {
+		Coin   string          `json:"coin"`
+		Amount decimal.Decimal `json:"amount"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return TrashItem{}, err
+	}
+	return TrashItem{
+		ID:        id,
+		Type:      recordType,
+		Coin:      fields.Coin,
+		Amount:    fields.Amount,
+		DeletedAt: time.Now().UTC(),
+		Record:    raw,
+	}, nil
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 func TestNewHolding(t *testing.T) {
@@ -13,7 +17,7 @@ func TestNewHolding(t *testing.T) {
 		purchasePriceUSD float64
 		platform         string
 		notes            string
-		date             string
+		date             Date
 		wantDate         string
 	}{
 		{
@@ -23,7 +27,7 @@ func TestNewHolding(t *testing.T) {
 			purchasePriceUSD: 50000,
 			platform:         "Binance",
 			notes:            "DCA",
-			date:             "2024-01-15",
+			date:             NewDate(2024, 1, 15),
 			wantDate:         "2024-01-15",
 		},
 		{
@@ -33,14 +37,14 @@ func TestNewHolding(t *testing.T) {
 			purchasePriceUSD: 3000,
 			platform:         "",
 			notes:            "",
-			date:             "",
+			date:             Date{},
 			wantDate:         time.Now().Format("2006-01-02"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			h := NewHolding(tt.coin, tt.amount, tt.purchasePriceUSD, tt.platform, tt.notes, tt.date)
+			h := NewHolding(tt.coin, dec(tt.amount), dec(tt.purchasePriceUSD), tt.platform, tt.notes, tt.date)
 
 			if h.ID == "" {
 				t.Error("expected ID to be generated")
@@ -51,11 +55,11 @@ func TestNewHolding(t *testing.T) {
 			if h.Coin != tt.coin {
 				t.Errorf("expected coin %s, got %s", tt.coin, h.Coin)
 			}
-			if h.Amount != tt.amount {
-				t.Errorf("expected amount %f, got %f", tt.amount, h.Amount)
+			if !h.Amount.Equal(dec(tt.amount)) {
+				t.Errorf("expected amount %v, got %v", tt.amount, h.Amount)
 			}
-			if h.PurchasePriceUSD != tt.purchasePriceUSD {
-				t.Errorf("expected price %f, got %f", tt.purchasePriceUSD, h.PurchasePriceUSD)
+			if !h.PurchasePriceUSD.Equal(dec(tt.purchasePriceUSD)) {
+				t.Errorf("expected price %v, got %v", tt.purchasePriceUSD, h.PurchasePriceUSD)
 			}
 			if h.Platform != tt.platform {
 				t.Errorf("expected platform %s, got %s", tt.platform, h.Platform)
@@ -63,7 +67,7 @@ func TestNewHolding(t *testing.T) {
 			if h.Notes != tt.notes {
 				t.Errorf("expected notes %s, got %s", tt.notes, h.Notes)
 			}
-			if h.Date != tt.wantDate {
+			if h.Date.String() != tt.wantDate {
 				t.Errorf("expected date %s, got %s", tt.wantDate, h.Date)
 			}
 		})
@@ -72,15 +76,26 @@ func TestNewHolding(t *testing.T) {
 
 func TestHolding_TotalValueUSD(t *testing.T) {
 	h := Holding{
-		Amount:           2.5,
-		PurchasePriceUSD: 40000,
+		Amount:           dec(2.5),
+		PurchasePriceUSD: dec(40000),
 	}
 
 	want := 100000.0
 	got := h.TotalValueUSD()
 
-	if got != want {
-		t.Errorf("TotalValueUSD() = %f, want %f", got, want)
+	if !got.Equal(dec(want)) {
+		t.Errorf("TotalValueUSD() = %v, want %v", got, want)
+	}
+}
+
+func TestHolding_CostUSD(t *testing.T) {
+	h := Holding{Amount: dec(2), PurchasePriceUSD: dec(1000), FeeUSD: dec(10)}
+
+	if got := h.CostUSD(); !got.Equal(dec(2010)) {
+		t.Errorf("CostUSD() = %v, want 2010", got)
+	}
+	if got := h.CostPerUnitUSD(); !got.Equal(dec(1005)) {
+		t.Errorf("CostPerUnitUSD() = %v, want 1005", got)
 	}
 }
 
@@ -92,7 +107,7 @@ func TestNewLoan(t *testing.T) {
 		platform     string
 		interestRate *float64
 		notes        string
-		date         string
+		date         Date
 		wantDate     string
 	}{
 		{
@@ -102,7 +117,7 @@ func TestNewLoan(t *testing.T) {
 			platform:     "Nexo",
 			interestRate: floatPtr(6.9),
 			notes:        "Credit line",
-			date:         "2024-02-01",
+			date:         NewDate(2024, 2, 1),
 			wantDate:     "2024-02-01",
 		},
 		{
@@ -112,14 +127,14 @@ func TestNewLoan(t *testing.T) {
 			platform:     "Celsius",
 			interestRate: nil,
 			notes:        "",
-			date:         "",
+			date:         Date{},
 			wantDate:     time.Now().Format("2006-01-02"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			l := NewLoan(tt.coin, tt.amount, tt.platform, tt.interestRate, tt.notes, tt.date)
+			l := NewLoan(tt.coin, dec(tt.amount), tt.platform, tt.interestRate, tt.notes, tt.date)
 
 			if l.ID == "" {
 				t.Error("expected ID to be generated")
@@ -130,8 +145,8 @@ func TestNewLoan(t *testing.T) {
 			if l.Coin != tt.coin {
 				t.Errorf("expected coin %s, got %s", tt.coin, l.Coin)
 			}
-			if l.Amount != tt.amount {
-				t.Errorf("expected amount %f, got %f", tt.amount, l.Amount)
+			if !l.Amount.Equal(dec(tt.amount)) {
+				t.Errorf("expected amount %v, got %v", tt.amount, l.Amount)
 			}
 			if l.Platform != tt.platform {
 				t.Errorf("expected platform %s, got %s", tt.platform, l.Platform)
@@ -142,13 +157,35 @@ func TestNewLoan(t *testing.T) {
 			if tt.interestRate == nil && l.InterestRate != nil {
 				t.Errorf("expected nil interest rate, got %v", l.InterestRate)
 			}
-			if l.Date != tt.wantDate {
+			if l.Date.String() != tt.wantDate {
 				t.Errorf("expected date %s, got %s", tt.wantDate, l.Date)
 			}
 		})
 	}
 }
 
+func TestNewRepayment(t *testing.T) {
+	r := NewRepayment("abcd1234", dec(250), "partial", NewDate(2024, 3, 1))
+
+	if len(r.ID) != 8 {
+		t.Errorf("expected ID length 8, got %d", len(r.ID))
+	}
+	if r.LoanID != "abcd1234" {
+		t.Errorf("expected loan ID abcd1234, got %s", r.LoanID)
+	}
+	if !r.Amount.Equal(dec(250)) {
+		t.Errorf("expected amount 250, got %v", r.Amount)
+	}
+	if r.Date.String() != "2024-03-01" {
+		t.Errorf("expected date 2024-03-01, got %s", r.Date)
+	}
+
+	r = NewRepayment("abcd1234", dec(1), "", Date{})
+	if r.Date.String() != time.Now().Format("2006-01-02") {
+		t.Errorf("expected default date today, got %s", r.Date)
+	}
+}
+
 func TestNewSale(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -157,7 +194,7 @@ func TestNewSale(t *testing.T) {
 		sellPriceUSD float64
 		platform     string
 		notes        string
-		date         string
+		date         Date
 		wantDate     string
 	}{
 		{
@@ -167,7 +204,7 @@ func TestNewSale(t *testing.T) {
 			sellPriceUSD: 55000,
 			platform:     "Kraken",
 			notes:        "Taking profits",
-			date:         "2024-03-01",
+			date:         NewDate(2024, 3, 1),
 			wantDate:     "2024-03-01",
 		},
 		{
@@ -177,14 +214,14 @@ func TestNewSale(t *testing.T) {
 			sellPriceUSD: 3500,
 			platform:     "",
 			notes:        "",
-			date:         "",
+			date:         Date{},
 			wantDate:     time.Now().Format("2006-01-02"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewSale(tt.coin, tt.amount, tt.sellPriceUSD, tt.platform, tt.notes, tt.date)
+			s := NewSale(tt.coin, dec(tt.amount), dec(tt.sellPriceUSD), tt.platform, tt.notes, tt.date)
 
 			if s.ID == "" {
 				t.Error("expected ID to be generated")
@@ -195,16 +232,16 @@ func TestNewSale(t *testing.T) {
 			if s.Coin != tt.coin {
 				t.Errorf("expected coin %s, got %s", tt.coin, s.Coin)
 			}
-			if s.Amount != tt.amount {
-				t.Errorf("expected amount %f, got %f", tt.amount, s.Amount)
+			if !s.Amount.Equal(dec(tt.amount)) {
+				t.Errorf("expected amount %v, got %v", tt.amount, s.Amount)
 			}
-			if s.SellPriceUSD != tt.sellPriceUSD {
-				t.Errorf("expected price %f, got %f", tt.sellPriceUSD, s.SellPriceUSD)
+			if !s.SellPriceUSD.Equal(dec(tt.sellPriceUSD)) {
+				t.Errorf("expected price %v, got %v", tt.sellPriceUSD, s.SellPriceUSD)
 			}
 			if s.Platform != tt.platform {
 				t.Errorf("expected platform %s, got %s", tt.platform, s.Platform)
 			}
-			if s.Date != tt.wantDate {
+			if s.Date.String() != tt.wantDate {
 				t.Errorf("expected date %s, got %s", tt.wantDate, s.Date)
 			}
 		})
@@ -213,15 +250,26 @@ func TestNewSale(t *testing.T) {
 
 func TestSale_TotalValueUSD(t *testing.T) {
 	s := Sale{
-		Amount:       0.5,
-		SellPriceUSD: 60000,
+		Amount:       dec(0.5),
+		SellPriceUSD: dec(60000),
 	}
 
 	want := 30000.0
 	got := s.TotalValueUSD()
 
-	if got != want {
-		t.Errorf("TotalValueUSD() = %f, want %f", got, want)
+	if !got.Equal(dec(want)) {
+		t.Errorf("TotalValueUSD() = %v, want %v", got, want)
+	}
+}
+
+func TestSale_ProceedsUSD(t *testing.T) {
+	sale := Sale{Amount: dec(2), SellPriceUSD: dec(1000), FeeUSD: dec(10)}
+
+	if got := sale.ProceedsUSD(); !got.Equal(dec(1990)) {
+		t.Errorf("ProceedsUSD() = %v, want 1990", got)
+	}
+	if got := sale.ProceedsPerUnitUSD(); !got.Equal(dec(995)) {
+		t.Errorf("ProceedsPerUnitUSD() = %v, want 995", got)
 	}
 }
 
@@ -233,7 +281,7 @@ func TestNewStake(t *testing.T) {
 		platform string
 		apy      *float64
 		notes    string
-		date     string
+		date     Date
 		wantDate string
 	}{
 		{
@@ -243,7 +291,7 @@ func TestNewStake(t *testing.T) {
 			platform: "Lido",
 			apy:      floatPtr(4.5),
 			notes:    "Staking rewards",
-			date:     "2024-03-01",
+			date:     NewDate(2024, 3, 1),
 			wantDate: "2024-03-01",
 		},
 		{
@@ -253,14 +301,14 @@ func TestNewStake(t *testing.T) {
 			platform: "Coinbase",
 			apy:      nil,
 			notes:    "",
-			date:     "",
+			date:     Date{},
 			wantDate: time.Now().Format("2006-01-02"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			st := NewStake(tt.coin, tt.amount, tt.platform, tt.apy, tt.notes, tt.date)
+			st := NewStake(tt.coin, dec(tt.amount), tt.platform, tt.apy, tt.notes, tt.date)
 
 			if st.ID == "" {
 				t.Error("expected ID to be generated")
@@ -271,8 +319,8 @@ func TestNewStake(t *testing.T) {
 			if st.Coin != tt.coin {
 				t.Errorf("expected coin %s, got %s", tt.coin, st.Coin)
 			}
-			if st.Amount != tt.amount {
-				t.Errorf("expected amount %f, got %f", tt.amount, st.Amount)
+			if !st.Amount.Equal(dec(tt.amount)) {
+				t.Errorf("expected amount %v, got %v", tt.amount, st.Amount)
 			}
 			if st.Platform != tt.platform {
 				t.Errorf("expected platform %s, got %s", tt.platform, st.Platform)
@@ -283,13 +331,209 @@ func TestNewStake(t *testing.T) {
 			if tt.apy == nil && st.APY != nil {
 				t.Errorf("expected nil APY, got %v", st.APY)
 			}
-			if st.Date != tt.wantDate {
+			if st.Date.String() != tt.wantDate {
 				t.Errorf("expected date %s, got %s", tt.wantDate, st.Date)
 			}
 		})
 	}
 }
 
+func TestNewSnapshot(t *testing.T) {
+	before := time.Now()
+	snap := NewSnapshot(time.Time{}, "weekly")
+
+	if len(snap.ID) != 8 {
+		t.Errorf("expected ID length 8, got %d", len(snap.ID))
+	}
+	if snap.Timestamp.Before(before) {
+		t.Errorf("expected zero timestamp to default to now, got %v", snap.Timestamp)
+	}
+	if snap.CoinValues == nil {
+		t.Error("expected CoinValues to be initialized")
+	}
+	if snap.Note != "weekly" {
+		t.Errorf("expected note weekly, got %s", snap.Note)
+	}
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := NewSnapshot(ts, "").Timestamp; !got.Equal(ts) {
+		t.Errorf("expected timestamp %v, got %v", ts, got)
+	}
+}
+
+// dec returns v as a decimal, for writing record amounts as literals
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
 func floatPtr(f float64) *float64 {
 	return &f
 }
+
+func TestDecimalArithmetic(t *testing.T) {
+	if got := Add(0.1, 0.2); got != 0.3 {
+		t.Errorf("Add(0.1, 0.2) = %v, want 0.3", got)
+	}
+	if got := Sub(0.3, 0.1); got != 0.2 {
+		t.Errorf("Sub(0.3, 0.1) = %v, want 0.2", got)
+	}
+	if got := Mul(1.1, 1.1); got != 1.21 {
+		t.Errorf("Mul(1.1, 1.1) = %v, want 1.21", got)
+	}
+	if got := Normalize(0.30000000000000004); got != 0.3 {
+		t.Errorf("Normalize() = %v, want 0.3", got)
+	}
+
+	totals := make(Totals)
+	for i := 0; i < 10; i++ {
+		totals.Add("BTC", dec(0.1))
+	}
+	totals.Sub("BTC", dec(0.5))
+	if got := totals.Get("BTC"); got != 0.5 {
+		t.Errorf("Totals BTC = %v, want 0.5", got)
+	}
+	if got := totals.Floats()["BTC"]; got != 0.5 {
+		t.Errorf("Floats()[BTC] = %v, want 0.5", got)
+	}
+}
+
+func TestHolding_JSONKeepsDecimalDigits(t *testing.T) {
+	// Token balances to 18 decimals are beyond float64 precision
+	amount := decimal.RequireFromString("1.000000000000000001")
+	h := NewHolding("ETH", amount, dec(2000), "", "", NewDate(2024, 1, 1))
+
+	raw, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(raw), `"amount":"1.000000000000000001"`) {
+		t.Errorf("expected the amount stored as a decimal string, got %s", raw)
+	}
+	var decoded Holding
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.Amount.Equal(amount) {
+		t.Errorf("expected amount %s, got %s", amount, decoded.Amount)
+	}
+
+	// Files from before amounts were strings hold plain numbers
+	if err := json.Unmarshal([]byte(`{"id":"h1","amount":0.5,"purchase_price_usd":100}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal of numbers failed: %v", err)
+	}
+	if !decoded.Amount.Equal(dec(0.5)) || !decoded.PurchasePriceUSD.Equal(dec(100)) {
+		t.Errorf("expected 0.5 at 100, got %+v", decoded)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024-01-15", "2024-01-15"},
+		{"2024-1-5", "2024-01-05"},
+		{"2024/01/15", "2024-01-15"},
+		{"2024-01-15T23:30:00+02:00", "2024-01-15"},
+		{"2024-13-45", ""},
+		{"2023-02-29", ""},
+		{"15/01/2024", ""},
+		{"yesterday", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseDate(%q) = %s, expected error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseDate(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseNaturalDate(t *testing.T) {
+	today := NewDate(2024, time.March, 10)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024-01-15", "2024-01-15"},
+		{"today", "2024-03-10"},
+		{"Yesterday", "2024-03-09"},
+		{"3 days ago", "2024-03-07"},
+		{"1 day ago", "2024-03-09"},
+		{"2 weeks ago", "2024-02-25"},
+		{"1 month ago", "2024-02-10"},
+		{"1 year ago", "2023-03-10"},
+		{"jan 5", "2024-01-05"},
+		{"January 5, 2023", "2023-01-05"},
+		{"5 feb", "2024-02-05"},
+		{"dec 25", "2023-12-25"}, // The latest Dec 25 up to today
+		{"feb 30", ""},
+		{"05/01/2024", "2024-05-01"},
+		{"25/12/2023", "2023-12-25"},
+		{"13/13/2024", ""},
+		{"5/1/24", ""},
+		{"ja 5", ""},
+		{"soon", ""},
+		{"three days ago", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNaturalDate(tt.input, today)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("parseNaturalDate(%q) = %s, expected error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("parseNaturalDate(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestDateOf(t *testing.T) {
+	// 23:30 in New York is already the next day in UTC
+	ny := time.FixedZone("EST", -5*60*60)
+	d := DateOf(time.Date(2024, 1, 15, 23, 30, 0, 0, ny))
+	if d.String() != "2024-01-15" || d.Location() != time.UTC || d.Hour() != 0 {
+		t.Errorf("expected 2024-01-15 at midnight UTC, got %v", d.Time)
+	}
+	if !d.Before(d.AddDays(1)) || d.AddDays(1).DaysSince(d) != 1 {
+		t.Error("expected the next day to be one day later")
+	}
+	if Today().String() != time.Now().Format(DateLayout) {
+		t.Errorf("expected today in local time, got %s", Today())
+	}
+}
+
+func TestDate_JSON(t *testing.T) {
+	h := Holding{ID: "h1", Date: NewDate(2024, 3, 1)}
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"date":"2024-03-01"`) {
+		t.Errorf("expected date stored as YYYY-MM-DD, got %s", data)
+	}
+
+	var decoded Holding
+	if err := json.Unmarshal([]byte(`{"id":"h1","date":"2024/3/1"}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.Date.Equal(h.Date.Time) {
+		t.Errorf("expected 2024-03-01, got %s", decoded.Date)
+	}
+
+	if err := json.Unmarshal([]byte(`{"date":""}`), &decoded); err != nil || !decoded.Date.IsZero() {
+		t.Errorf("expected empty date to decode as zero, got %s, %v", decoded.Date, err)
+	}
+	if err := json.Unmarshal([]byte(`{"date":"2024-13-45"}`), &decoded); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
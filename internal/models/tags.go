@@ -0,0 +1,29 @@
+package models
+
+import "strings"
+
+// ParseTags returns the tags in values, each of which may hold several
+// comma-separated labels. Spaces around labels are trimmed, and empty and
+// repeated labels (compared without case) are dropped.
+func ParseTags(values ...string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, label := range strings.Split(value, ",") {
+			label = strings.TrimSpace(label)
+			if label != "" && !HasTag(tags, label) {
+				tags = append(tags, label)
+			}
+		}
+	}
+	return tags
+}
+
+// HasTag reports whether tags contains tag, ignoring case.
+func HasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{nil, nil},
+		{[]string{""}, nil},
+		{[]string{"DCA"}, []string{"DCA"}},
+		{[]string{" DCA , airdrop,,"}, []string{"DCA", "airdrop"}},
+		{[]string{"DCA", "gift,dca"}, []string{"DCA", "gift"}},
+	}
+	for _, tt := range tests {
+		if got := ParseTags(tt.values...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestHasTag(t *testing.T) {
+	tags := []string{"DCA", "airdrop"}
+	if !HasTag(tags, "dca") || !HasTag(tags, "Airdrop") {
+		t.Error("expected tags to match without case")
+	}
+	if HasTag(tags, "gift") || HasTag(nil, "dca") {
+		t.Error("expected missing tags not to match")
+	}
+}
@@ -0,0 +1,136 @@
+// Package notify delivers short messages to webhooks, ntfy topics, and
+// Telegram chats.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const telegramURL = "https://api.telegram.org"
+
+// Message is a notification about a single event.
+type Message struct {
+	Event string `json:"event"` // e.g. "snapshot", "daily_change", "price_alert"
+	Title string `json:"title"`
+	Body  string `json:"message"`
+}
+
+// Sender delivers messages to one destination.
+type Sender interface {
+	Send(msg Message) error
+}
+
+func newClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// Webhook posts each message as JSON to a URL.
+type Webhook struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhook creates a sender posting to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{client: newClient(), url: url}
+}
+
+// Send posts msg as {"event":..., "title":..., "message":...}.
+func (w *Webhook) Send(msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(w.client, req, "webhook")
+}
+
+// Ntfy publishes each message to an ntfy topic.
+type Ntfy struct {
+	client *http.Client
+	url    string
+}
+
+// NewNtfy creates a sender publishing to a topic URL, e.g. https://ntfy.sh/my-topic.
+func NewNtfy(topicURL string) *Ntfy {
+	return &Ntfy{client: newClient(), url: topicURL}
+}
+
+// Send publishes the message body with its title and event as a tag.
+func (n *Ntfy) Send(msg Message) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, strings.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", msg.Title)
+	req.Header.Set("Tags", msg.Event)
+	return do(n.client, req, "ntfy")
+}
+
+// Telegram sends each message to a chat through a bot.
+type Telegram struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	chatID  string
+}
+
+// NewTelegram creates a sender using a bot token and the chat to message.
+func NewTelegram(token, chatID string) *Telegram {
+	return &Telegram{client: newClient(), baseURL: telegramURL, token: token, chatID: chatID}
+}
+
+// Send sends the title and body as one text message.
+func (t *Telegram) Send(msg Message) error {
+	form := url.Values{}
+	form.Set("chat_id", t.chatID)
+	form.Set("text", msg.Title+"\n"+msg.Body)
+	req, err := http.NewRequest(http.MethodPost, t.baseURL+"/bot"+t.token+"/sendMessage", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(t.client, req, "telegram")
+}
+
+// SendAll sends msg to every sender, returning the failures joined.
+func SendAll(senders []Sender, msg Message) error {
+	var errs []error
+	for _, s := range senders {
+		if err := s.Send(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// do sends req and fails on non-2xx responses. The request URL is left out
+// of errors since it can contain a token.
+func do(client *http.Client, req *http.Request, name string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("%s: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	var got Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	msg := Message{Event: "snapshot", Title: "Snapshot saved", Body: "Net value $1.00"}
+	if err := NewWebhook(server.URL).Send(msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got != msg {
+		t.Errorf("Expected %+v, got %+v", msg, got)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/topic" || string(body) != "BTC is $100,000.00" {
+			t.Errorf("Unexpected request %s: %q", r.URL.Path, body)
+		}
+		if r.Header.Get("Title") != "Price alert" || r.Header.Get("Tags") != "price_alert" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+	}))
+	defer server.Close()
+
+	if err := NewNtfy(server.URL + "/topic").Send(Message{Event: "price_alert", Title: "Price alert", Body: "BTC is $100,000.00"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+}
+
+func TestTelegram(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/sendMessage" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		r.ParseForm()
+		if r.Form.Get("chat_id") != "42" || r.Form.Get("text") != "Title\nBody" {
+			t.Errorf("Unexpected form %v", r.Form)
+		}
+	}))
+	defer server.Close()
+
+	tg := NewTelegram("TOKEN", "42")
+	tg.baseURL = server.URL
+	if err := tg.Send(Message{Title: "Title", Body: "Body"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+}
+
+func TestSendAll_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("bad token"))
+	}))
+	defer server.Close()
+
+	tg := NewTelegram("SECRET", "42")
+	tg.baseURL = server.URL
+	err := SendAll([]Sender{NewWebhook(server.URL), tg}, Message{Body: "hi"})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if !strings.Contains(err.Error(), "webhook: status 403: bad token") || !strings.Contains(err.Error(), "telegram: status 403") {
+		t.Errorf("Expected both failures, got %v", err)
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("Expected token to be kept out of errors, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// AllocationEntry is a coin's share of the total holdings value.
+type AllocationEntry struct {
+	Coin     string
+	ValueUSD float64
+	Percent  float64
+}
+
+// CalculateAllocation returns each coin's share of the total value of the
+// given holdings, sorted by value descending. Coins without a price or with
+// a non-positive value are excluded.
+func CalculateAllocation(holdingsByCoin map[string]decimal.Decimal, prices map[string]float64) []AllocationEntry {
+	var entries []AllocationEntry
+	total := decimal.Zero
+	for coin, amount := range holdingsByCoin {
+		price, ok := prices[coin]
+		if !ok {
+			continue
+		}
+		value := models.AtPrice(amount, price)
+		if !value.IsPositive() {
+			continue
+		}
+		entries = append(entries, AllocationEntry{Coin: coin, ValueUSD: models.Float(value)})
+		total = total.Add(value)
+	}
+
+	for i := range entries {
+		entries[i].Percent = entries[i].ValueUSD / models.Float(total) * 100
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ValueUSD != entries[j].ValueUSD {
+			return entries[i].ValueUSD > entries[j].ValueUSD
+		}
+		return entries[i].Coin < entries[j].Coin
+	})
+	return entries
+}
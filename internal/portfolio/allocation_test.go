@@ -0,0 +1,39 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCalculateAllocation(t *testing.T) {
+	holdings := map[string]decimal.Decimal{
+		"BTC":  dec(1),
+		"ETH":  dec(10),
+		"SOL":  dec(0),
+		"NOPE": dec(100),
+	}
+	prices := map[string]float64{
+		"BTC": 60000,
+		"ETH": 2000,
+		"SOL": 150,
+	}
+
+	entries := CalculateAllocation(holdings, prices)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries (zero and unpriced excluded), got %v", entries)
+	}
+	if entries[0].Coin != "BTC" || entries[1].Coin != "ETH" {
+		t.Errorf("expected BTC then ETH sorted by value, got %v", entries)
+	}
+	if math.Abs(entries[0].Percent-75) > 1e-9 || math.Abs(entries[1].Percent-25) > 1e-9 {
+		t.Errorf("expected 75%%/25%%, got %f/%f", entries[0].Percent, entries[1].Percent)
+	}
+}
+
+func TestCalculateAllocationEmpty(t *testing.T) {
+	if entries := CalculateAllocation(map[string]decimal.Decimal{"BTC": dec(1)}, nil); len(entries) != 0 {
+		t.Errorf("expected no entries without prices, got %v", entries)
+	}
+}
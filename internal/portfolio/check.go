@@ -0,0 +1,174 @@
+package portfolio
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Problem is an inconsistency between records found by Check, such as
+// selling more of a coin than was bought
+type Problem struct {
+	Coin    string // Empty when the problem isn't about one coin
+	ID      string // The record to look at, if there is one
+	Message string
+	Fix     string // What to do about it, as a follyo command where possible
+}
+
+// Check looks for records that contradict each other: coins with a
+// negative balance, more of a coin staked than held, sales naming lots that
+// no longer exist, and repayments of missing loans or of more than was
+// borrowed. Such records can't be added, but can be left behind by removing
+// a record, importing, or editing the data file.
+func (p *Portfolio) Check() ([]Problem, error) {
+	var problems []Problem
+
+	current, err := p.GetCurrentHoldingsByCoin()
+	if err != nil {
+		return nil, err
+	}
+	stakes, err := p.GetStakesByCoin()
+	if err != nil {
+		return nil, err
+	}
+	for _, coin := range slices.Sorted(maps.Keys(current)) {
+		if current[coin].IsNegative() {
+			problems = append(problems, Problem{
+				Coin:    coin,
+				Message: fmt.Sprintf("%s balance is %s: more was sold, swapped, or paid in fees than bought", coin, current[coin]),
+				Fix:     fmt.Sprintf("record the missing purchase with 'follyo buy add %s ...', or remove the extra sale", coin),
+			})
+		}
+	}
+	for _, coin := range slices.Sorted(maps.Keys(stakes)) {
+		if held := decimal.Max(current[coin], decimal.Zero); stakes[coin].GreaterThan(held) {
+			problems = append(problems, Problem{
+				Coin:    coin,
+				Message: fmt.Sprintf("%s %s is staked but only %s is held", stakes[coin], coin, held),
+				Fix:     fmt.Sprintf("reduce the stake with 'follyo stake reduce ID %s', or record the missing purchase", stakes[coin].Sub(held)),
+			})
+		}
+	}
+
+	lotProblems, err := p.checkLots()
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, lotProblems...)
+
+	loanProblems, err := p.checkRepayments()
+	if err != nil {
+		return nil, err
+	}
+	return append(problems, loanProblems...), nil
+}
+
+// checkLots finds sales naming lots that are not purchases of the coin sold
+func (p *Portfolio) checkLots() ([]Problem, error) {
+	_, lotsByCoin, err := p.matchLots()
+	if err != nil {
+		return nil, err
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, s := range sales {
+		for _, id := range s.LotIDs {
+			idx := slices.IndexFunc(lotsByCoin[s.Coin], func(l *lot) bool { return l.holding.ID == id })
+			if idx < 0 {
+				problems = append(problems, Problem{
+					Coin:    s.Coin,
+					ID:      s.ID,
+					Message: fmt.Sprintf("sale %s names lot %s, which is not a %s purchase; it is sold first-in, first-out instead", s.ID, id, s.Coin),
+					Fix:     fmt.Sprintf("restore the purchase with 'follyo trash restore %s' if it was removed by mistake", id),
+				})
+			} else if l := lotsByCoin[s.Coin][idx]; !l.heldBy(s.Date) {
+				problems = append(problems, Problem{
+					Coin:    s.Coin,
+					ID:      s.ID,
+					Message: fmt.Sprintf("sale %s on %s names lot %s, bought later on %s; it is sold first-in, first-out instead", s.ID, s.Date, id, l.holding.Date),
+					Fix:     "check the dates of the sale and the purchase",
+				})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// checkRepayments finds repayments of missing loans and loans repaid more
+// than they were for
+func (p *Portfolio) checkRepayments() ([]Problem, error) {
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := p.GetOutstandingByLoan()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, r := range repayments {
+		if _, ok := outstanding[r.LoanID]; !ok {
+			problems = append(problems, Problem{
+				ID:      r.ID,
+				Message: fmt.Sprintf("repayment %s is for loan %s, which doesn't exist", r.ID, r.LoanID),
+				Fix:     fmt.Sprintf("restore the loan with 'follyo trash restore %s'", r.LoanID),
+			})
+		}
+	}
+	for _, l := range loans {
+		if outstanding[l.ID].IsNegative() {
+			problems = append(problems, Problem{
+				Coin:    l.Coin,
+				ID:      l.ID,
+				Message: fmt.Sprintf("loan %s of %s %s is overpaid by %s", l.ID, l.Amount, l.Coin, outstanding[l.ID].Neg()),
+				Fix:     "check its repayments in 'follyo history' for a duplicate",
+			})
+		}
+	}
+	return problems, nil
+}
+
+// TrimStakes reduces the stakes of coins staked beyond their balance, newest
+// stake first, until no more of each coin is staked than held. It returns
+// the stakes changed, as they were before.
+func (p *Portfolio) TrimStakes() ([]models.Stake, error) {
+	current, err := p.GetCurrentHoldingsByCoin()
+	if err != nil {
+		return nil, err
+	}
+	staked, err := p.GetStakesByCoin()
+	if err != nil {
+		return nil, err
+	}
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(stakes, func(a, b models.Stake) int { return b.Date.Compare(a.Date.Time) })
+
+	var trimmed []models.Stake
+	for _, st := range stakes {
+		excess := staked[st.Coin].Sub(decimal.Max(current[st.Coin], decimal.Zero))
+		if !excess.IsPositive() {
+			continue
+		}
+		if _, err := p.ReduceStake(st.ID, decimal.Min(excess, st.Amount)); err != nil {
+			return trimmed, err
+		}
+		staked[st.Coin] = staked[st.Coin].Sub(decimal.Min(excess, st.Amount))
+		trimmed = append(trimmed, st)
+	}
+	return trimmed, nil
+}
@@ -0,0 +1,99 @@
+package portfolio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_Check(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	problems, err := p.Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected no problems in an empty portfolio, got %+v", problems)
+	}
+
+	// A sale from a lot whose purchase is removed afterwards
+	lot, _ := p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-01-01")
+	p.AddHolding("BTC", dec(1), dec(30000), "", "", "2023-02-01")
+	sale, err := p.AddSaleWithFee("BTC", dec(0.5), dec(50000), dec(0), "", "", "2024-01-01", []string{lot.ID})
+	if err != nil {
+		t.Fatalf("AddSaleWithFee failed: %v", err)
+	}
+	p.RemoveHolding(lot.ID)
+
+	// A sale from a lot bought after it, as left by editing dates
+	later, _ := p.AddHolding("SOL", dec(1), dec(100), "", "", "2024-06-01")
+	early := models.NewSale("SOL", dec(1), dec(150), "", "", models.NewDate(2024, 1, 1))
+	early.LotIDs = []string{later.ID}
+	p.storage.AddSale(early)
+
+	// More ETH sold than bought, with a stake left behind
+	p.AddHolding("ETH", dec(1), dec(2000), "", "", "2023-01-01")
+	p.AddStake("ETH", dec(1), "Lido", nil, "", "2023-01-02")
+	if _, err := p.AddSaleUnchecked("ETH", dec(2), dec(3000), dec(0), "", "", "2024-01-01", nil); err != nil {
+		t.Fatalf("AddSaleUnchecked failed: %v", err)
+	}
+
+	// An overpaid loan and a repayment of a removed loan
+	loan, _ := p.AddLoan("USDC", dec(100), "Nexo", nil, "", "2023-01-01")
+	p.storage.AddRepayment(models.NewRepayment(loan.ID, dec(150), "", models.Date{}))
+	p.storage.AddRepayment(models.NewRepayment("gone", dec(10), "", models.Date{}))
+
+	problems, err = p.Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []string{
+		"ETH balance is -1",
+		"1 ETH is staked but only 0 is held",
+		"sale " + sale.ID + " names lot " + lot.ID,
+		"sale " + early.ID + " on 2024-01-01 names lot " + later.ID + ", bought later",
+		"is for loan gone, which doesn't exist",
+		"loan " + loan.ID + " of 100 USDC is overpaid by 50",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %+v", len(want), problems)
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i].Message, w) {
+			t.Errorf("problem %d: expected %q, got %q", i, w, problems[i].Message)
+		}
+		if problems[i].Fix == "" {
+			t.Errorf("problem %d has no fix", i)
+		}
+	}
+}
+
+func TestPortfolio_TrimStakes(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(3), dec(2000), "", "", "2023-01-01")
+	older, _ := p.AddStake("ETH", dec(2), "Lido", nil, "", "2023-01-02")
+	newer, _ := p.AddStake("ETH", dec(1), "Kraken", nil, "", "2023-03-01")
+	if _, err := p.AddSaleUnchecked("ETH", dec(1.5), dec(3000), dec(0), "", "", "2024-01-01", nil); err != nil {
+		t.Fatalf("AddSaleUnchecked failed: %v", err)
+	}
+
+	trimmed, err := p.TrimStakes()
+	if err != nil {
+		t.Fatalf("TrimStakes failed: %v", err)
+	}
+	if len(trimmed) != 2 || trimmed[0].ID != newer.ID || trimmed[1].ID != older.ID {
+		t.Fatalf("expected the newer stake trimmed first, got %+v", trimmed)
+	}
+	stakes, _ := p.ListStakes()
+	if len(stakes) != 1 || stakes[0].ID != older.ID || !stakes[0].Amount.Equal(dec(1.5)) {
+		t.Errorf("expected 1.5 ETH left in the older stake, got %+v", stakes)
+	}
+	if problems, _ := p.Check(); len(problems) != 0 {
+		t.Errorf("expected no problems after trimming, got %+v", problems)
+	}
+}
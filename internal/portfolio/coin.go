@@ -0,0 +1,151 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
+)
+
+// CoinDetail gathers everything recorded for a single coin.
+type CoinDetail struct {
+	Coin           string
+	Transactions   []Transaction   // All records for the coin, oldest first
+	Held           decimal.Decimal // Current holdings (purchases - sales, adjusted for swaps and fees)
+	Staked         decimal.Decimal
+	Loaned         decimal.Decimal // Outstanding after repayments
+	AverageCostUSD decimal.Decimal // Average cost per coin purchased, including fees
+	CostBasisUSD   decimal.Decimal // FIFO cost basis of the coins still held
+	RealizedUSD    decimal.Decimal // Realized gain (or loss) from sales and swaps
+	BreakEven      BreakEven
+}
+
+// BreakEven is the price at which a coin's holdings would be worth the net
+// USD put into the coin.
+type BreakEven struct {
+	NetInvestedUSD decimal.Decimal // Purchases and swaps into the coin, less sales and swaps out
+	// NetInvestedUSD per coin held. Zero when nothing is held or the coin
+	// has already returned more than was put in.
+	PriceUSD decimal.Decimal
+}
+
+// DistancePercent returns how far price is above (positive) or below
+// (negative) the break-even price, in percent of it, or 0 without one.
+func (b BreakEven) DistancePercent(price float64) float64 {
+	if !b.PriceUSD.IsPositive() {
+		return 0
+	}
+	return (price/models.Float(b.PriceUSD) - 1) * 100
+}
+
+// UnrealizedUSD returns the gain (or loss) of the coins still held at price.
+func (d CoinDetail) UnrealizedUSD(price float64) decimal.Decimal {
+	return d.Held.Mul(decimal.NewFromFloat(price)).Sub(d.CostBasisUSD)
+}
+
+// GetCoinDetail returns the records, positions, and profit/loss of a coin.
+func (p *Portfolio) GetCoinDetail(coin string) (CoinDetail, error) {
+	coin = strings.ToUpper(coin)
+	detail := CoinDetail{Coin: coin}
+
+	history, err := p.GetHistory(Filter{Coin: coin})
+	if err != nil {
+		return detail, err
+	}
+	detail.Transactions = history
+
+	summary, err := p.GetSummary()
+	if err != nil {
+		return detail, err
+	}
+	detail.Held = summary.HoldingsByCoin[coin]
+	detail.Staked = summary.StakesByCoin[coin]
+	detail.Loaned = summary.LoansByCoin[coin]
+
+	dca, err := p.GetDCAStats(coin)
+	if err != nil {
+		return detail, err
+	}
+	detail.AverageCostUSD = dca.AveragePriceUSD
+
+	pl, err := p.GetProfitLoss(models.Date{})
+	if err != nil {
+		return detail, err
+	}
+	detail.CostBasisUSD = pl.CostBasisByCoin[coin]
+	detail.RealizedUSD = pl.RealizedByCoin[coin]
+
+	breakEven, err := p.GetBreakEven()
+	if err != nil {
+		return detail, err
+	}
+	detail.BreakEven = breakEven[coin]
+	return detail, nil
+}
+
+// GetBreakEven returns the break-even price of each coin held. Swaps count
+// at their USD value, as a sale of one coin and a purchase of the other.
+func (p *Portfolio) GetBreakEven() (map[string]BreakEven, error) {
+	summary, err := p.GetSummary()
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+
+	netInvested := make(models.Totals)
+	for _, h := range holdings {
+		netInvested.Add(h.Coin, h.CostUSD())
+	}
+	for _, s := range sales {
+		netInvested.Sub(s.Coin, s.ProceedsUSD())
+	}
+	for _, s := range swaps {
+		netInvested.Sub(s.FromCoin, s.ValueUSD)
+		netInvested.Add(s.ToCoin, s.ValueUSD)
+	}
+
+	breakEven := make(map[string]BreakEven)
+	for coin, held := range summary.HoldingsByCoin {
+		if !held.IsPositive() {
+			continue
+		}
+		b := BreakEven{NetInvestedUSD: netInvested[coin]}
+		if b.NetInvestedUSD.IsPositive() {
+			b.PriceUSD = models.Quo(b.NetInvestedUSD, held)
+		}
+		breakEven[coin] = b
+	}
+	return breakEven, nil
+}
+
+// MigrateCoin moves every record of oldCoin to newCoin, e.g. after MATIC
+// was rebranded POL. Ratio is the newCoin received for each oldCoin:
+// amounts are multiplied by it and prices per coin divided by it, so
+// dates, cost basis, and proceeds are kept. It returns the number of
+// records changed.
+func (p *Portfolio) MigrateCoin(oldCoin, newCoin string, ratio decimal.Decimal) (int, error) {
+	oldCoin = strings.ToUpper(strings.TrimSpace(oldCoin))
+	newCoin = strings.ToUpper(strings.TrimSpace(newCoin))
+	if oldCoin == "" || newCoin == "" {
+		return 0, invalidf("coin names must not be empty")
+	}
+	if oldCoin == newCoin {
+		return 0, invalidf("cannot migrate %s to itself", oldCoin)
+	}
+	if !ratio.IsPositive() {
+		return 0, invalidf("ratio must be positive")
+	}
+	return p.storage.MigrateCoin(storage.CoinMigration{From: oldCoin, To: newCoin, Ratio: ratio})
+}
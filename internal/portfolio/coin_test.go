@@ -0,0 +1,132 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_GetCoinDetail(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "Binance", "", "2024-01-01")
+	p.AddHolding("ETH", dec(2), dec(2000), "Binance", "", "2024-02-01")
+	p.AddHolding("BTC", dec(1), dec(40000), "Binance", "", "2024-01-01")
+	p.AddSale("ETH", dec(1), dec(3000), "Binance", "", "2024-03-01")
+	p.AddStake("ETH", dec(1), "Lido", nil, "", "2024-03-02")
+	p.AddLoan("ETH", dec(0.5), "Nexo", nil, "", "2024-03-03")
+
+	detail, err := p.GetCoinDetail("eth")
+	if err != nil {
+		t.Fatalf("GetCoinDetail failed: %v", err)
+	}
+	if detail.Coin != "ETH" || len(detail.Transactions) != 5 {
+		t.Fatalf("expected 5 ETH transactions, got %+v", detail.Transactions)
+	}
+	if !detail.Held.Equal(dec(3)) || !detail.Staked.Equal(dec(1)) || !detail.Loaned.Equal(dec(0.5)) {
+		t.Errorf("expected 3 held, 1 staked, 0.5 loaned, got %+v", detail)
+	}
+	if !detail.AverageCostUSD.Equal(dec(1500)) {
+		t.Errorf("expected average cost 1500, got %v", detail.AverageCostUSD)
+	}
+	// FIFO: the sale consumes 1 ETH of the $1000 lot
+	if !detail.CostBasisUSD.Equal(dec(5000)) || !detail.RealizedUSD.Equal(dec(2000)) {
+		t.Errorf("expected cost basis 5000 and realized 2000, got %+v", detail)
+	}
+	if u := detail.UnrealizedUSD(2500); !u.Equal(dec(2500)) {
+		t.Errorf("expected unrealized 2500, got %v", u)
+	}
+}
+
+func TestPortfolio_GetCoinDetailSwapIn(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "", "", "2024-01-01")
+	p.AddSwap("ETH", dec(1), "SOL", dec(20), dec(1500), "", "", "2024-02-01")
+
+	sol, err := p.GetCoinDetail("SOL")
+	if err != nil {
+		t.Fatalf("GetCoinDetail failed: %v", err)
+	}
+	if !sol.Held.Equal(dec(20)) || !sol.CostBasisUSD.Equal(dec(1500)) {
+		t.Errorf("expected 20 SOL with $1500 basis, got %+v", sol)
+	}
+
+	eth, _ := p.GetCoinDetail("ETH")
+	if !eth.Held.Equal(dec(1)) || !eth.CostBasisUSD.Equal(dec(1000)) || !eth.RealizedUSD.Equal(dec(500)) {
+		t.Errorf("expected 1 ETH with $1000 basis and $500 realized, got %+v", eth)
+	}
+}
+
+func TestPortfolio_GetBreakEven(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "", "", "2024-01-01")
+	p.AddHolding("ETH", dec(2), dec(2000), "", "", "2024-02-01")
+	p.AddSale("ETH", dec(1), dec(3000), "", "", "2024-03-01")
+	p.AddSwap("ETH", dec(1), "SOL", dec(20), dec(1500), "", "", "2024-04-01")
+	p.AddHolding("BTC", dec(1), dec(10000), "", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.5), dec(30000), "", "", "2024-05-01")
+
+	breakEven, err := p.GetBreakEven()
+	if err != nil {
+		t.Fatalf("GetBreakEven failed: %v", err)
+	}
+	// ETH: $6000 in, $3000 sold, $1500 swapped out, 2 held
+	if eth := breakEven["ETH"]; !eth.NetInvestedUSD.Equal(dec(1500)) || !eth.PriceUSD.Equal(dec(750)) {
+		t.Errorf("expected ETH break-even $750 on $1500, got %+v", eth)
+	}
+	if sol := breakEven["SOL"]; !sol.PriceUSD.Equal(dec(75)) {
+		t.Errorf("expected SOL break-even $75, got %+v", sol)
+	}
+	// BTC has returned more than was put in
+	if btc := breakEven["BTC"]; !btc.NetInvestedUSD.Equal(dec(-5000)) || !btc.PriceUSD.IsZero() {
+		t.Errorf("expected no BTC break-even price, got %+v", btc)
+	}
+	if d := breakEven["ETH"].DistancePercent(900); math.Abs(d-20) > 1e-9 {
+		t.Errorf("expected 20%% above break-even, got %f", d)
+	}
+
+	detail, _ := p.GetCoinDetail("SOL")
+	if !detail.BreakEven.PriceUSD.Equal(dec(75)) {
+		t.Errorf("expected SOL detail break-even $75, got %+v", detail.BreakEven)
+	}
+}
+
+func TestPortfolio_MigrateCoin(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("FTM", dec(1000), dec(0.5), "", "", "2024-01-01")
+	p.AddHolding("FTM", dec(1000), dec(1), "", "", "2024-02-01")
+	p.AddSale("FTM", dec(500), dec(0.8), "", "", "2024-03-01")
+	before, _ := p.GetProfitLoss(models.Today())
+
+	for _, args := range [][2]string{{"", "S"}, {"FTM", "ftm"}} {
+		if _, err := p.MigrateCoin(args[0], args[1], dec(1)); err == nil {
+			t.Errorf("expected an error migrating %q to %q", args[0], args[1])
+		}
+	}
+	if _, err := p.MigrateCoin("FTM", "S", dec(0)); err == nil {
+		t.Error("expected an error for a zero ratio")
+	}
+
+	changed, err := p.MigrateCoin("ftm", "s", dec(1))
+	if err != nil || changed != 3 {
+		t.Fatalf("expected 3 records changed, got %d, %v", changed, err)
+	}
+	after, _ := p.GetProfitLoss(models.Today())
+	if !after.HeldByCoin["S"].Equal(dec(1500)) || !after.CostBasisByCoin["S"].Equal(before.CostBasisByCoin["FTM"]) {
+		t.Errorf("expected 1500 S on the FTM cost basis %v, got %+v", before.CostBasisByCoin["FTM"], after)
+	}
+	if !after.RealizedByCoin["S"].Equal(before.RealizedByCoin["FTM"]) {
+		t.Errorf("expected realized gain %v kept, got %v", before.RealizedByCoin["FTM"], after.RealizedByCoin["S"])
+	}
+	if _, ok := after.HeldByCoin["FTM"]; ok {
+		t.Errorf("expected no FTM left, got %+v", after.HeldByCoin)
+	}
+}
@@ -0,0 +1,85 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// DCAStats summarizes the dollar-cost averaging of a coin's purchases.
+type DCAStats struct {
+	Coin            string
+	Buys            int
+	Amount          decimal.Decimal // Total coins purchased
+	InvestedUSD     decimal.Decimal // Total cost of purchases, including fees
+	AveragePriceUSD decimal.Decimal // Average cost per coin purchased
+	SoldAmount      decimal.Decimal
+	ProceedsUSD     decimal.Decimal // Total received from sales, after fees
+	Held            decimal.Decimal // Amount purchased - sold
+	BreakEvenUSD    decimal.Decimal // Price at which the remaining coins recover the net cost
+	FirstBuy        models.Date
+	LastBuy         models.Date
+}
+
+// DistanceFromAverage returns how far price is from the average purchase
+// price, in percent. Positive means the price is above the average.
+func (s DCAStats) DistanceFromAverage(price float64) float64 {
+	if s.AveragePriceUSD.IsZero() {
+		return 0
+	}
+	average := models.Float(s.AveragePriceUSD)
+	return (price - average) / average * 100
+}
+
+// GetDCAStats returns purchase statistics for a coin. Buys is zero when the
+// coin was never purchased.
+func (p *Portfolio) GetDCAStats(coin string) (DCAStats, error) {
+	coin = strings.ToUpper(coin)
+	stats := DCAStats{Coin: coin}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return stats, err
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return stats, err
+	}
+
+	totals := make(models.Totals)
+	for _, h := range holdings {
+		if h.Coin != coin {
+			continue
+		}
+		stats.Buys++
+		totals.Add("amount", h.Amount)
+		totals.Add("invested", h.CostUSD())
+		if stats.FirstBuy.IsZero() || h.Date.Before(stats.FirstBuy) {
+			stats.FirstBuy = h.Date
+		}
+		if h.Date.After(stats.LastBuy) {
+			stats.LastBuy = h.Date
+		}
+	}
+	for _, s := range sales {
+		if s.Coin != coin {
+			continue
+		}
+		totals.Add("sold", s.Amount)
+		totals.Add("proceeds", s.ProceedsUSD())
+	}
+
+	stats.Amount = totals["amount"]
+	stats.InvestedUSD = totals["invested"]
+	stats.SoldAmount = totals["sold"]
+	stats.ProceedsUSD = totals["proceeds"]
+	stats.Held = stats.Amount.Sub(stats.SoldAmount)
+	if stats.Amount.IsPositive() {
+		stats.AveragePriceUSD = models.Quo(stats.InvestedUSD, stats.Amount)
+	}
+	if stats.Held.IsPositive() {
+		stats.BreakEvenUSD = decimal.Max(decimal.Zero, models.Quo(stats.InvestedUSD.Sub(stats.ProceedsUSD), stats.Held))
+	}
+	return stats, nil
+}
@@ -0,0 +1,48 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPortfolio_GetDCAStats(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHoldingWithFee("BTC", dec(1), dec(30000), dec(100), "", "", "2024-03-01")
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-01-15")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-02-01")
+	p.AddSaleWithFee("BTC", dec(0.5), dec(60000), dec(0), "", "", "2024-04-01", nil)
+
+	stats, err := p.GetDCAStats("btc")
+	if err != nil {
+		t.Fatalf("GetDCAStats failed: %v", err)
+	}
+	if stats.Coin != "BTC" || stats.Buys != 2 || !stats.Amount.Equal(dec(2)) {
+		t.Errorf("expected 2 buys of 2 BTC, got %+v", stats)
+	}
+	if !stats.InvestedUSD.Equal(dec(80100)) || !stats.AveragePriceUSD.Equal(dec(40050)) {
+		t.Errorf("expected $80,100 invested at $40,050 average, got %+v", stats)
+	}
+	if stats.FirstBuy.String() != "2024-01-15" || stats.LastBuy.String() != "2024-03-01" {
+		t.Errorf("expected buys from 2024-01-15 to 2024-03-01, got %s to %s", stats.FirstBuy, stats.LastBuy)
+	}
+	if !stats.Held.Equal(dec(1.5)) || !stats.ProceedsUSD.Equal(dec(30000)) {
+		t.Errorf("expected 1.5 BTC held after $30,000 of sales, got %+v", stats)
+	}
+	// (80100 - 30000) / 1.5
+	if !stats.BreakEvenUSD.Equal(dec(33400)) {
+		t.Errorf("expected break-even $33,400, got %v", stats.BreakEvenUSD)
+	}
+	if d := stats.DistanceFromAverage(44055); math.Abs(d-10) > 1e-9 {
+		t.Errorf("expected 10%% above average, got %f", d)
+	}
+
+	none, err := p.GetDCAStats("SOL")
+	if err != nil {
+		t.Fatalf("GetDCAStats failed: %v", err)
+	}
+	if none.Buys != 0 || none.DistanceFromAverage(100) != 0 {
+		t.Errorf("expected no SOL buys, got %+v", none)
+	}
+}
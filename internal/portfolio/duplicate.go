@@ -0,0 +1,45 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// FindDuplicateHolding returns an existing holding with the same coin,
+// amount, price, platform, and date as h, ignoring case in coin and platform.
+// Fees, notes, and IDs are not compared.
+func (p *Portfolio) FindDuplicateHolding(h models.Holding) (models.Holding, bool, error) {
+	holdings, err := p.storage.GetHoldings()
+	if err != nil {
+		return models.Holding{}, false, err
+	}
+	for _, existing := range holdings {
+		if existing.ID != h.ID && isDuplicate(existing.Coin, h.Coin, existing.Platform, h.Platform, existing.Date, h.Date) &&
+			existing.Amount.Equal(h.Amount) && existing.PurchasePriceUSD.Equal(h.PurchasePriceUSD) {
+			return existing, true, nil
+		}
+	}
+	return models.Holding{}, false, nil
+}
+
+// FindDuplicateSale returns an existing sale with the same coin, amount,
+// price, platform, and date as s, like FindDuplicateHolding.
+func (p *Portfolio) FindDuplicateSale(s models.Sale) (models.Sale, bool, error) {
+	sales, err := p.storage.GetSales()
+	if err != nil {
+		return models.Sale{}, false, err
+	}
+	for _, existing := range sales {
+		if existing.ID != s.ID && isDuplicate(existing.Coin, s.Coin, existing.Platform, s.Platform, existing.Date, s.Date) &&
+			existing.Amount.Equal(s.Amount) && existing.SellPriceUSD.Equal(s.SellPriceUSD) {
+			return existing, true, nil
+		}
+	}
+	return models.Sale{}, false, nil
+}
+
+// isDuplicate compares the fields shared by holdings and sales
+func isDuplicate(coinA, coinB, platformA, platformB string, dateA, dateB models.Date) bool {
+	return strings.EqualFold(coinA, coinB) && strings.EqualFold(platformA, platformB) && dateA.Equal(dateB.Time)
+}
@@ -0,0 +1,50 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_FindDuplicateHolding(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	existing, _ := p.AddHolding("BTC", dec(0.5), dec(40000), "Binance", "first", "2024-01-01")
+
+	dup, found, err := p.FindDuplicateHolding(models.NewHolding("btc", dec(0.5), dec(40000), "binance", "other notes", models.NewDate(2024, 1, 1)))
+	if err != nil {
+		t.Fatalf("FindDuplicateHolding failed: %v", err)
+	}
+	if !found || dup.ID != existing.ID {
+		t.Errorf("Expected duplicate of %s, got %+v, %v", existing.ID, dup, found)
+	}
+
+	for _, h := range []models.Holding{
+		models.NewHolding("BTC", dec(0.6), dec(40000), "Binance", "", models.NewDate(2024, 1, 1)),
+		models.NewHolding("BTC", dec(0.5), dec(41000), "Binance", "", models.NewDate(2024, 1, 1)),
+		models.NewHolding("BTC", dec(0.5), dec(40000), "Kraken", "", models.NewDate(2024, 1, 1)),
+		models.NewHolding("BTC", dec(0.5), dec(40000), "Binance", "", models.NewDate(2024, 1, 2)),
+		models.NewHolding("ETH", dec(0.5), dec(40000), "Binance", "", models.NewDate(2024, 1, 1)),
+		existing, // A record is not its own duplicate
+	} {
+		if _, found, _ := p.FindDuplicateHolding(h); found {
+			t.Errorf("Expected no duplicate for %+v", h)
+		}
+	}
+}
+
+func TestPortfolio_FindDuplicateSale(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(40000), "Binance", "", "2024-01-01")
+	existing, _ := p.AddSale("BTC", dec(0.5), dec(50000), "Binance", "", "2024-02-01")
+
+	if dup, found, _ := p.FindDuplicateSale(models.NewSale("BTC", dec(0.5), dec(50000), "Binance", "", models.NewDate(2024, 2, 1))); !found || dup.ID != existing.ID {
+		t.Errorf("Expected duplicate of %s, got %+v, %v", existing.ID, dup, found)
+	}
+	if _, found, _ := p.FindDuplicateSale(models.NewSale("BTC", dec(0.5), dec(50000), "", "", models.NewDate(2024, 2, 1))); found {
+		t.Error("Expected platform to be compared")
+	}
+}
@@ -0,0 +1,24 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when no record has the requested ID.
+var ErrNotFound = errors.New("not found")
+
+// ValidationError reports input rejected by a portfolio rule, such as a
+// non-positive amount or more than the available balance.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+// invalidf returns a ValidationError with a formatted message.
+func invalidf(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,93 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/exchange"
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// ImportProposal holds the records proposed from an exchange's trades.
+type ImportProposal struct {
+	Holdings []models.Holding
+	Sales    []models.Sale
+	Skipped  int // Trades already imported
+}
+
+// IsEmpty reports whether there is nothing to import.
+func (ip ImportProposal) IsEmpty() bool {
+	return len(ip.Holdings) == 0 && len(ip.Sales) == 0
+}
+
+// ProposeImports turns exchange trades into holdings and sales on platform.
+// Each record's notes carry the trade reference, and trades whose reference
+// already appears in an existing record's notes are skipped. Records are
+// dated on the local calendar day the trade executed.
+func (p *Portfolio) ProposeImports(trades []exchange.Trade, platform string) (ImportProposal, error) {
+	var proposal ImportProposal
+
+	imported, err := p.importedRefs()
+	if err != nil {
+		return proposal, err
+	}
+
+	for _, t := range trades {
+		ref := t.Ref()
+		if imported[ref] {
+			proposal.Skipped++
+			continue
+		}
+		imported[ref] = true
+
+		coin := strings.ToUpper(t.Coin)
+		date := models.DateOf(t.Time.Local())
+		switch t.Side {
+		case exchange.SideBuy:
+			h := models.NewHolding(coin, t.Amount, t.PriceUSD, platform, ref, date)
+			h.FeeUSD = t.FeeUSD
+			proposal.Holdings = append(proposal.Holdings, h)
+		case exchange.SideSell:
+			s := models.NewSale(coin, t.Amount, t.PriceUSD, platform, ref, date)
+			s.FeeUSD = t.FeeUSD
+			proposal.Sales = append(proposal.Sales, s)
+		}
+	}
+	return proposal, nil
+}
+
+// ImportRecords saves the records of a proposal.
+func (p *Portfolio) ImportRecords(proposal ImportProposal) error {
+	for _, h := range proposal.Holdings {
+		if err := p.storage.AddHolding(h); err != nil {
+			return err
+		}
+	}
+	for _, s := range proposal.Sales {
+		if err := p.storage.AddSale(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importedRefs returns the notes of all holdings and sales, which hold the
+// trade references of imported records.
+func (p *Portfolio) importedRefs() (map[string]bool, error) {
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool)
+	for _, h := range holdings {
+		refs[h.Notes] = true
+	}
+	for _, s := range sales {
+		refs[s.Notes] = true
+	}
+	return refs, nil
+}
@@ -0,0 +1,49 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/exchange"
+)
+
+func TestPortfolio_ProposeImports(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	trades := []exchange.Trade{
+		{Exchange: "binance", ID: "1", Coin: "btc", Side: exchange.SideBuy, Amount: dec(1), PriceUSD: dec(40000), FeeUSD: dec(10), Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Exchange: "binance", ID: "2", Coin: "BTC", Side: exchange.SideSell, Amount: dec(0.5), PriceUSD: dec(50000), Time: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	proposal, err := p.ProposeImports(trades, "Binance")
+	if err != nil {
+		t.Fatalf("ProposeImports failed: %v", err)
+	}
+	if len(proposal.Holdings) != 1 || len(proposal.Sales) != 1 || proposal.Skipped != 0 {
+		t.Fatalf("expected 1 holding and 1 sale, got %+v", proposal)
+	}
+	h := proposal.Holdings[0]
+	if h.Coin != "BTC" || !h.FeeUSD.Equal(dec(10)) || h.Platform != "Binance" || h.Notes != "binance trade 1" || h.Date.String() != "2024-01-01" {
+		t.Errorf("unexpected holding: %+v", h)
+	}
+
+	if err := p.ImportRecords(proposal); err != nil {
+		t.Fatalf("ImportRecords failed: %v", err)
+	}
+	holdings, _ := p.ListHoldings()
+	sales, _ := p.ListSales()
+	if len(holdings) != 1 || len(sales) != 1 {
+		t.Errorf("expected records saved, got %d holdings and %d sales", len(holdings), len(sales))
+	}
+
+	// A second sync only proposes new trades
+	trades = append(trades, exchange.Trade{Exchange: "binance", ID: "3", Coin: "BTC", Side: exchange.SideBuy, Amount: dec(0.1), PriceUSD: dec(60000), Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)})
+	proposal, err = p.ProposeImports(trades, "Binance")
+	if err != nil {
+		t.Fatalf("ProposeImports failed: %v", err)
+	}
+	if len(proposal.Holdings) != 1 || len(proposal.Sales) != 0 || proposal.Skipped != 2 {
+		t.Errorf("expected only the new trade, got %+v", proposal)
+	}
+}
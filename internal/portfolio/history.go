@@ -0,0 +1,203 @@
+package portfolio
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Transaction types in the history ledger.
+const (
+	TypeBuy      = "buy"
+	TypeSell     = "sell"
+	TypeLoan     = "loan"
+	TypeRepay    = "repay"
+	TypeStake    = "stake"
+	TypeSwapOut  = "swap out"
+	TypeSwapIn   = "swap in"
+	TypeTransfer = "transfer"
+)
+
+// Filter restricts records by coin, platform, date range, and tag.
+// Empty fields match everything; Since and Until are inclusive dates.
+// Search matches a case-insensitive substring of either the coin or the platform.
+type Filter struct {
+	Coin     string
+	Platform string
+	Since    models.Date
+	Until    models.Date
+	Search   string
+	Tag      string
+}
+
+// IsZero reports whether the filter matches everything.
+func (f Filter) IsZero() bool {
+	return f == Filter{}
+}
+
+// Matches reports whether a record with the given fields passes the filter.
+func (f Filter) Matches(coin, platform string, date models.Date) bool {
+	if f.Coin != "" && !strings.EqualFold(f.Coin, coin) {
+		return false
+	}
+	if f.Platform != "" && !strings.EqualFold(f.Platform, platform) {
+		return false
+	}
+	if !f.Since.IsZero() && date.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && date.After(f.Until) {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(coin), search) && !strings.Contains(strings.ToLower(platform), search) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesTags reports whether a record with the given tags passes the
+// filter's tag, compared without case.
+func (f Filter) MatchesTags(tags []string) bool {
+	return f.Tag == "" || models.HasTag(tags, f.Tag)
+}
+
+// Transaction is a single entry in the unified history ledger.
+type Transaction struct {
+	ID         string
+	Type       string
+	Coin       string
+	Amount     decimal.Decimal
+	PriceUSD   decimal.Decimal // Zero for loans, repayments, stakes, and transfers
+	Platform   string          // Source platform for transfers
+	ToPlatform string          // Destination platform for transfers
+	Date       models.Date
+	Notes      string
+	Tags       []string
+	Fee        decimal.Decimal // Transfer fee in coin units
+	Balance    decimal.Decimal // Coin holdings (purchases - sales - transfer fees) after this transaction
+}
+
+// GetHistory returns holdings, sales, loans, loan repayments, stakes, swaps,
+// and transfers merged into a single chronological ledger. Each swap appears
+// as an outgoing and an incoming entry. Running balances are computed over all
+// records before the filter is applied, so they stay accurate for filtered
+// views. Transfers match a platform filter on either end.
+func (p *Portfolio) GetHistory(filter Filter) ([]Transaction, error) {
+	var ledger []Transaction
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		ledger = append(ledger, Transaction{
+			ID: h.ID, Type: TypeBuy, Coin: h.Coin, Amount: h.Amount, PriceUSD: h.PurchasePriceUSD,
+			Platform: h.Platform, Date: h.Date, Notes: h.Notes, Tags: h.Tags,
+		})
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sales {
+		ledger = append(ledger, Transaction{
+			ID: s.ID, Type: TypeSell, Coin: s.Coin, Amount: s.Amount, PriceUSD: s.SellPriceUSD,
+			Platform: s.Platform, Date: s.Date, Notes: s.Notes, Tags: s.Tags,
+		})
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+	loansByID := make(map[string]models.Loan)
+	for _, l := range loans {
+		loansByID[l.ID] = l
+		ledger = append(ledger, Transaction{
+			ID: l.ID, Type: TypeLoan, Coin: l.Coin, Amount: l.Amount,
+			Platform: l.Platform, Date: l.Date, Notes: l.Notes, Tags: l.Tags,
+		})
+	}
+
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repayments {
+		l := loansByID[r.LoanID]
+		ledger = append(ledger, Transaction{
+			ID: r.ID, Type: TypeRepay, Coin: l.Coin, Amount: r.Amount,
+			Platform: l.Platform, Date: r.Date, Notes: r.Notes, Tags: r.Tags,
+		})
+	}
+
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stakes {
+		ledger = append(ledger, Transaction{
+			ID: st.ID, Type: TypeStake, Coin: st.Coin, Amount: st.Amount,
+			Platform: st.Platform, Date: st.Date, Notes: st.Notes, Tags: st.Tags,
+		})
+	}
+
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+	for _, sw := range swaps {
+		sale, holding := swapLegs(sw)
+		ledger = append(ledger,
+			Transaction{
+				ID: sw.ID, Type: TypeSwapOut, Coin: sale.Coin, Amount: sale.Amount, PriceUSD: sale.SellPriceUSD,
+				Platform: sw.Platform, Date: sw.Date, Notes: sw.Notes, Tags: sw.Tags,
+			},
+			Transaction{
+				ID: sw.ID, Type: TypeSwapIn, Coin: holding.Coin, Amount: holding.Amount, PriceUSD: holding.PurchasePriceUSD,
+				Platform: sw.Platform, Date: sw.Date, Notes: sw.Notes, Tags: sw.Tags,
+			})
+	}
+
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		ledger = append(ledger, Transaction{
+			ID: t.ID, Type: TypeTransfer, Coin: t.Coin, Amount: t.Amount, Fee: t.Fee,
+			Platform: t.FromPlatform, ToPlatform: t.ToPlatform, Date: t.Date, Notes: t.Notes, Tags: t.Tags,
+		})
+	}
+
+	sort.SliceStable(ledger, func(i, j int) bool { return ledger[i].Date.Before(ledger[j].Date) })
+
+	balances := make(models.Totals)
+	filtered := make([]Transaction, 0, len(ledger))
+	for _, tx := range ledger {
+		switch tx.Type {
+		case TypeBuy, TypeSwapIn:
+			balances.Add(tx.Coin, tx.Amount)
+		case TypeSell, TypeSwapOut:
+			balances.Sub(tx.Coin, tx.Amount)
+		case TypeTransfer:
+			balances.Sub(tx.Coin, tx.Fee)
+		}
+		tx.Balance = balances[tx.Coin]
+
+		if !filter.MatchesTags(tx.Tags) {
+			continue
+		}
+		if filter.Matches(tx.Coin, tx.Platform, tx.Date) ||
+			(tx.ToPlatform != "" && filter.Matches(tx.Coin, tx.ToPlatform, tx.Date)) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,107 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	f := Filter{Coin: "btc", Platform: "coinbase", Since: models.NewDate(2024, 1, 1), Until: models.NewDate(2024, 12, 31)}
+
+	tests := []struct {
+		coin, platform, date string
+		want                 bool
+	}{
+		{"BTC", "Coinbase", "2024-06-01", true},
+		{"BTC", "Coinbase", "2024-01-01", true},
+		{"BTC", "Coinbase", "2024-12-31", true},
+		{"ETH", "Coinbase", "2024-06-01", false},
+		{"BTC", "Binance", "2024-06-01", false},
+		{"BTC", "Coinbase", "2023-12-31", false},
+		{"BTC", "Coinbase", "2025-01-01", false},
+	}
+	for _, tt := range tests {
+		date, _ := models.ParseDate(tt.date)
+		if got := f.Matches(tt.coin, tt.platform, date); got != tt.want {
+			t.Errorf("Matches(%s, %s, %s) = %v, want %v", tt.coin, tt.platform, tt.date, got, tt.want)
+		}
+	}
+
+	if !(Filter{}).Matches("ANY", "", models.Date{}) {
+		t.Error("expected empty filter to match everything")
+	}
+}
+
+func TestFilter_Search(t *testing.T) {
+	f := Filter{Search: "bin"}
+
+	tests := []struct {
+		coin, platform string
+		want           bool
+	}{
+		{"BTC", "Binance", true},
+		{"BNB", "Coinbase", false},
+		{"WBIN", "", true},
+		{"ETH", "Ledger", false},
+	}
+	for _, tt := range tests {
+		if got := f.Matches(tt.coin, tt.platform, models.NewDate(2024, 1, 1)); got != tt.want {
+			t.Errorf("Matches(%s, %s) with search %q = %v, want %v", tt.coin, tt.platform, f.Search, got, tt.want)
+		}
+	}
+
+	if f.IsZero() {
+		t.Error("expected search filter not to be zero")
+	}
+	if !(Filter{}).IsZero() {
+		t.Error("expected empty filter to be zero")
+	}
+}
+
+func TestPortfolio_GetHistory(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "Binance", "", "2024-01-05")
+	p.AddSale("BTC", dec(0.25), dec(50000), "Coinbase", "", "2024-03-01")
+	p.AddHolding("BTC", dec(0.5), dec(40000), "Binance", "", "2024-02-01")
+	p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "2024-02-15")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "2024-02-20")
+
+	history, err := p.GetHistory(Filter{})
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history) != 6 {
+		t.Fatalf("expected 6 transactions, got %d", len(history))
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Date.Before(history[i-1].Date) {
+			t.Errorf("expected chronological order, got %s after %s", history[i].Date, history[i-1].Date)
+		}
+	}
+
+	last := history[len(history)-1]
+	if last.Type != TypeSell || !last.Balance.Equal(dec(1.25)) {
+		t.Errorf("expected final BTC sale with balance 1.25, got %s balance %v", last.Type, last.Balance)
+	}
+
+	// Balances account for records excluded by the filter
+	btc, err := p.GetHistory(Filter{Coin: "BTC", Platform: "Coinbase", Since: models.NewDate(2024, 2, 1)})
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(btc) != 1 {
+		t.Fatalf("expected 1 filtered transaction, got %d", len(btc))
+	}
+	if !btc[0].Balance.Equal(dec(1.25)) {
+		t.Errorf("expected running balance 1.25, got %v", btc[0].Balance)
+	}
+
+	stake := history[4]
+	if stake.Type != TypeStake || !stake.Balance.Equal(dec(10)) {
+		t.Errorf("expected stake to leave ETH balance at 10, got %s balance %v", stake.Type, stake.Balance)
+	}
+}
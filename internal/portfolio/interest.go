@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// Interest accrual methods.
+const (
+	InterestSimple   = "simple"
+	InterestCompound = "compound" // Compounded daily
+)
+
+// SetInterestMethod sets how loan interest accrues. Unknown methods fall back to simple.
+func (p *Portfolio) SetInterestMethod(method string) {
+	p.interestMethod = method
+}
+
+// AccruedInterest returns the interest accrued on a loan, in the loan's coin,
+// from the loan date to asOf. Repayments reduce the principal from their
+// date onward. Loans without a rate accrue nothing.
+func AccruedInterest(loan models.Loan, repayments []models.Repayment, asOf models.Date, method string) float64 {
+	if loan.InterestRate == nil || *loan.InterestRate == 0 {
+		return 0
+	}
+	if !loan.Date.Before(asOf) {
+		return 0
+	}
+
+	sorted := make([]models.Repayment, 0, len(repayments))
+	for _, r := range repayments {
+		if r.LoanID == loan.ID {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	dailyRate := *loan.InterestRate / 100 / 365
+	principal := models.Float(loan.Amount)
+	interest := 0.0
+	accrue := func(from, to models.Date) {
+		days := to.DaysSince(from)
+		if days <= 0 || principal <= 0 {
+			return
+		}
+		if method == InterestCompound {
+			interest = (principal+interest)*math.Pow(1+dailyRate, days) - principal
+		} else {
+			interest += principal * dailyRate * days
+		}
+	}
+
+	from := loan.Date
+	for _, r := range sorted {
+		if r.Date.After(asOf) {
+			continue
+		}
+		if r.Date.After(from) {
+			accrue(from, r.Date)
+			from = r.Date
+		}
+		principal -= models.Float(r.Amount)
+	}
+	accrue(from, asOf)
+	return interest
+}
+
+// GetAccruedInterestByLoan returns the interest accrued on each loan up to
+// asOf (zero means today), keyed by loan ID.
+func (p *Portfolio) GetAccruedInterestByLoan(asOf models.Date) (map[string]float64, error) {
+	if asOf.IsZero() {
+		asOf = models.Today()
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return nil, err
+	}
+
+	interest := make(map[string]float64)
+	for _, l := range loans {
+		interest[l.ID] = AccruedInterest(l, repayments, asOf, p.interestMethod)
+	}
+	return interest, nil
+}
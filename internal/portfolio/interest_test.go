@@ -0,0 +1,72 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestAccruedInterest(t *testing.T) {
+	rate := 10.0
+	loan := models.Loan{ID: "loan1", Coin: "USDT", Amount: dec(3650), Date: models.NewDate(2024, 1, 1), InterestRate: &rate}
+
+	// 3650 at 10% for 365 days, simple: 365
+	if got := AccruedInterest(loan, nil, models.NewDate(2024, 12, 31), InterestSimple); math.Abs(got-365) > 1e-9 {
+		t.Errorf("simple interest = %f, want 365", got)
+	}
+
+	// Daily compounding over a year yields slightly more
+	want := 3650*math.Pow(1+0.1/365, 365) - 3650
+	if got := AccruedInterest(loan, nil, models.NewDate(2024, 12, 31), InterestCompound); math.Abs(got-want) > 1e-6 {
+		t.Errorf("compound interest = %f, want %f", got, want)
+	}
+
+	// Repaying half after 73 days halves the principal for the rest of the period
+	repayments := []models.Repayment{
+		{ID: "r1", LoanID: "loan1", Amount: dec(1825), Date: models.NewDate(2024, 3, 14)},
+		{ID: "r2", LoanID: "other", Amount: dec(1000), Date: models.NewDate(2024, 1, 2)},
+	}
+	want = 3650*0.1/365*73 + 1825*0.1/365*292
+	if got := AccruedInterest(loan, repayments, models.NewDate(2024, 12, 31), InterestSimple); math.Abs(got-want) > 1e-9 {
+		t.Errorf("interest with repayment = %f, want %f", got, want)
+	}
+
+	if got := AccruedInterest(loan, nil, models.NewDate(2023, 12, 1), InterestSimple); got != 0 {
+		t.Errorf("expected no interest before the loan date, got %f", got)
+	}
+
+	loan.InterestRate = nil
+	if got := AccruedInterest(loan, nil, models.NewDate(2024, 12, 31), InterestSimple); got != 0 {
+		t.Errorf("expected no interest without a rate, got %f", got)
+	}
+}
+
+func TestPortfolio_InterestInSnapshot(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	rate := 10.0
+	p.AddLoan("USDT", dec(3650), "Nexo", &rate, "", "2024-01-01")
+
+	interest, err := p.GetAccruedInterestByLoan(models.NewDate(2024, 1, 11))
+	if err != nil {
+		t.Fatalf("GetAccruedInterestByLoan failed: %v", err)
+	}
+	for _, v := range interest {
+		if math.Abs(v-10) > 1e-9 {
+			t.Errorf("expected 10 USDT interest after 10 days, got %f", v)
+		}
+	}
+
+	snap, err := p.CaptureSnapshot(models.NewDate(2024, 1, 11), map[string]float64{"USDT": 1}, nil)
+	if err != nil {
+		t.Fatalf("CaptureSnapshot failed: %v", err)
+	}
+	if math.Abs(snap.InterestValue-10) > 1e-9 || math.Abs(snap.LoansValue-3660) > 1e-9 {
+		t.Errorf("expected loans value 3660 incl. 10 interest, got %f incl. %f", snap.LoansValue, snap.InterestValue)
+	}
+	if math.Abs(snap.NetValue+3660) > 1e-9 {
+		t.Errorf("expected net value -3660, got %f", snap.NetValue)
+	}
+}
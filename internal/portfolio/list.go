@@ -0,0 +1,137 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Sort fields accepted by ListOptions.SortBy.
+const (
+	SortByDate   = "date"
+	SortByCoin   = "coin"
+	SortByAmount = "amount"
+	SortByValue  = "value" // Only for purchases and sales
+)
+
+// ListOptions controls filtering and ordering of list results.
+type ListOptions struct {
+	Filter
+	SortBy  string // Defaults to date
+	Reverse bool
+}
+
+// sortKey holds the sortable fields of a record.
+type sortKey struct {
+	date     models.Date
+	coin     string
+	amount   decimal.Decimal
+	value    decimal.Decimal
+	hasValue bool
+}
+
+// sortRecords stably sorts records by the given field.
+func sortRecords[T any](records []T, opts ListOptions, keyOf func(T) sortKey) error {
+	var less func(a, b sortKey) bool
+	switch opts.SortBy {
+	case "", SortByDate:
+		less = func(a, b sortKey) bool { return a.date.Before(b.date) }
+	case SortByCoin:
+		less = func(a, b sortKey) bool { return a.coin < b.coin }
+	case SortByAmount:
+		less = func(a, b sortKey) bool { return a.amount.LessThan(b.amount) }
+	case SortByValue:
+		if len(records) > 0 && !keyOf(records[0]).hasValue {
+			return invalidf("cannot sort by value: records have no USD value")
+		}
+		less = func(a, b sortKey) bool { return a.value.LessThan(b.value) }
+	default:
+		return invalidf("invalid sort field %q: use date, coin, amount, or value", opts.SortBy)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := keyOf(records[i]), keyOf(records[j])
+		if opts.Reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
+
+// ListHoldingsFiltered lists holdings matching the options' filter, sorted.
+func (p *Portfolio) ListHoldingsFiltered(opts ListOptions) ([]models.Holding, error) {
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.Holding, 0, len(holdings))
+	for _, h := range holdings {
+		if opts.Matches(h.Coin, h.Platform, h.Date) && opts.MatchesTags(h.Tags) {
+			filtered = append(filtered, h)
+		}
+	}
+	err = sortRecords(filtered, opts, func(h models.Holding) sortKey {
+		return sortKey{date: h.Date, coin: h.Coin, amount: h.Amount, value: h.TotalValueUSD(), hasValue: true}
+	})
+	return filtered, err
+}
+
+// ListSalesFiltered lists sales matching the options' filter, sorted.
+func (p *Portfolio) ListSalesFiltered(opts ListOptions) ([]models.Sale, error) {
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.Sale, 0, len(sales))
+	for _, s := range sales {
+		if opts.Matches(s.Coin, s.Platform, s.Date) && opts.MatchesTags(s.Tags) {
+			filtered = append(filtered, s)
+		}
+	}
+	err = sortRecords(filtered, opts, func(s models.Sale) sortKey {
+		return sortKey{date: s.Date, coin: s.Coin, amount: s.Amount, value: s.TotalValueUSD(), hasValue: true}
+	})
+	return filtered, err
+}
+
+// ListLoansFiltered lists loans matching the options' filter, sorted.
+func (p *Portfolio) ListLoansFiltered(opts ListOptions) ([]models.Loan, error) {
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.Loan, 0, len(loans))
+	for _, l := range loans {
+		if opts.Matches(l.Coin, l.Platform, l.Date) && opts.MatchesTags(l.Tags) {
+			filtered = append(filtered, l)
+		}
+	}
+	err = sortRecords(filtered, opts, func(l models.Loan) sortKey {
+		return sortKey{date: l.Date, coin: l.Coin, amount: l.Amount}
+	})
+	return filtered, err
+}
+
+// ListStakesFiltered lists stakes matching the options' filter, sorted.
+func (p *Portfolio) ListStakesFiltered(opts ListOptions) ([]models.Stake, error) {
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.Stake, 0, len(stakes))
+	for _, st := range stakes {
+		if opts.Matches(st.Coin, st.Platform, st.Date) && opts.MatchesTags(st.Tags) {
+			filtered = append(filtered, st)
+		}
+	}
+	err = sortRecords(filtered, opts, func(st models.Stake) sortKey {
+		return sortKey{date: st.Date, coin: st.Coin, amount: st.Amount}
+	})
+	return filtered, err
+}
@@ -0,0 +1,114 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_ListHoldingsFiltered(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "Coinbase", "", "2024-01-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "Binance", "", "2024-01-05")
+	p.AddHolding("BTC", dec(0.5), dec(40000), "Binance", "", "2024-02-01")
+
+	holdings, err := p.ListHoldingsFiltered(ListOptions{Filter: Filter{Coin: "btc"}})
+	if err != nil {
+		t.Fatalf("ListHoldingsFiltered failed: %v", err)
+	}
+	if len(holdings) != 2 {
+		t.Fatalf("expected 2 BTC holdings, got %d", len(holdings))
+	}
+
+	holdings, err = p.ListHoldingsFiltered(ListOptions{Filter: Filter{Platform: "Binance", Since: models.NewDate(2024, 1, 10)}})
+	if err != nil {
+		t.Fatalf("ListHoldingsFiltered failed: %v", err)
+	}
+	if len(holdings) != 1 || !holdings[0].Amount.Equal(dec(0.5)) {
+		t.Errorf("expected the 0.5 BTC Binance holding, got %+v", holdings)
+	}
+
+	holdings, err = p.ListHoldingsFiltered(ListOptions{SortBy: SortByValue, Reverse: true})
+	if err != nil {
+		t.Fatalf("ListHoldingsFiltered failed: %v", err)
+	}
+	want := []float64{30000, 20000, 20000}
+	for i, h := range holdings {
+		if !h.TotalValueUSD().Equal(dec(want[i])) {
+			t.Errorf("holdings[%d] value = %v, want %v", i, h.TotalValueUSD(), want[i])
+		}
+	}
+	// Stable sort keeps date order for equal values
+	if holdings[1].Coin != "ETH" {
+		t.Errorf("expected ETH before later BTC for equal value, got %s", holdings[1].Coin)
+	}
+}
+
+func TestPortfolio_ListSalesFiltered(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(2000), "Kraken", "", "2024-01-01")
+	p.AddHolding("BTC", dec(0.1), dec(40000), "Kraken", "", "2024-01-01")
+	p.AddSale("ETH", dec(2), dec(3000), "Kraken", "", "2024-03-01")
+	p.AddSale("BTC", dec(0.1), dec(60000), "Kraken", "", "2024-02-01")
+
+	sales, err := p.ListSalesFiltered(ListOptions{SortBy: SortByCoin})
+	if err != nil {
+		t.Fatalf("ListSalesFiltered failed: %v", err)
+	}
+	if len(sales) != 2 || sales[0].Coin != "BTC" {
+		t.Errorf("expected BTC first when sorting by coin, got %+v", sales)
+	}
+
+	sales, err = p.ListSalesFiltered(ListOptions{Filter: Filter{Until: models.NewDate(2024, 2, 15)}})
+	if err != nil {
+		t.Fatalf("ListSalesFiltered failed: %v", err)
+	}
+	if len(sales) != 1 || sales[0].Coin != "BTC" {
+		t.Errorf("expected only the BTC sale, got %+v", sales)
+	}
+}
+
+func TestPortfolio_ListLoansAndStakesFiltered(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "2024-01-01")
+	p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "2024-02-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "Binance", "", "2024-01-01")
+	p.AddHolding("SOL", dec(100), dec(100), "Coinbase", "", "2024-01-01")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "2024-01-01")
+	p.AddStake("SOL", dec(100), "Coinbase", nil, "", "2024-02-01")
+
+	loans, err := p.ListLoansFiltered(ListOptions{SortBy: SortByAmount, Reverse: true})
+	if err != nil {
+		t.Fatalf("ListLoansFiltered failed: %v", err)
+	}
+	if len(loans) != 2 || loans[0].Coin != "USDT" {
+		t.Errorf("expected USDT first when sorting by amount descending, got %+v", loans)
+	}
+
+	if _, err := p.ListLoansFiltered(ListOptions{SortBy: SortByValue}); err == nil {
+		t.Error("expected error sorting loans by value")
+	}
+
+	stakes, err := p.ListStakesFiltered(ListOptions{Filter: Filter{Platform: "lido"}})
+	if err != nil {
+		t.Fatalf("ListStakesFiltered failed: %v", err)
+	}
+	if len(stakes) != 1 || stakes[0].Coin != "ETH" {
+		t.Errorf("expected only the Lido stake, got %+v", stakes)
+	}
+}
+
+func TestPortfolio_ListInvalidSort(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	if _, err := p.ListHoldingsFiltered(ListOptions{SortBy: "size"}); err == nil {
+		t.Error("expected error for invalid sort field")
+	}
+}
@@ -0,0 +1,70 @@
+package portfolio
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Lot is a purchase, or the coin received in a swap, with the amount not
+// yet matched to a sale.
+type Lot struct {
+	Holding   models.Holding // For a swap, the coin received, with the swap's ID
+	Remaining decimal.Decimal
+}
+
+// GetOpenLots returns the lots of coin that have not been sold in full,
+// oldest first. Their IDs can be given when adding a sale to choose which
+// coins are sold.
+func (p *Portfolio) GetOpenLots(coin string) ([]Lot, error) {
+	_, lotsByCoin, err := p.matchLots()
+	if err != nil {
+		return nil, err
+	}
+	var open []Lot
+	for _, l := range lotsByCoin[strings.ToUpper(coin)] {
+		if l.remaining.IsPositive() {
+			open = append(open, Lot{Holding: l.holding, Remaining: l.remaining})
+		}
+	}
+	return open, nil
+}
+
+// validateLots checks that the lots a sale of amount coin on date names are
+// purchases of that coin made by then. With checkBalance, the lots must also
+// have enough left to cover the sale.
+func (p *Portfolio) validateLots(coin string, amount decimal.Decimal, date models.Date, lotIDs []string, checkBalance bool) error {
+	if len(lotIDs) == 0 {
+		return nil
+	}
+	_, lotsByCoin, err := p.matchLots()
+	if err != nil {
+		return err
+	}
+	if date.IsZero() {
+		date = models.Today()
+	}
+
+	var remaining []decimal.Decimal
+	for i, id := range lotIDs {
+		if slices.Contains(lotIDs[:i], id) {
+			return invalidf("lot %s is given more than once", id)
+		}
+		idx := slices.IndexFunc(lotsByCoin[coin], func(l *lot) bool { return l.holding.ID == id })
+		if idx < 0 {
+			return invalidf("no %s purchase with ID %s", coin, id)
+		}
+		l := lotsByCoin[coin][idx]
+		if l.holding.Date.After(date) {
+			return invalidf("lot %s was bought on %s, after the sale", id, l.holding.Date)
+		}
+		remaining = append(remaining, l.remaining)
+	}
+
+	if left := decimal.Sum(decimal.Zero, remaining...); checkBalance && amount.GreaterThan(left) {
+		return invalidf("cannot sell %s %s from the given lots: only %s %s left in them", amount, coin, left, coin)
+	}
+	return nil
+}
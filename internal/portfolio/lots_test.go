@@ -0,0 +1,97 @@
+package portfolio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPortfolio_SaleFromLots(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	old, _ := p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-01-01")
+	recent, _ := p.AddHolding("BTC", dec(1), dec(60000), "", "", "2024-01-01")
+
+	// Selling from the recent lot instead of the FIFO one realizes a loss
+	sale, err := p.AddSaleWithFee("BTC", dec(0.5), dec(50000), dec(0), "", "", "2024-06-01", []string{recent.ID})
+	if err != nil {
+		t.Fatalf("AddSaleWithFee failed: %v", err)
+	}
+	if len(sale.LotIDs) != 1 || sale.LotIDs[0] != recent.ID {
+		t.Errorf("expected sale linked to lot %s, got %v", recent.ID, sale.LotIDs)
+	}
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 1 || disposals[0].HoldingID != recent.ID || !disposals[0].GainUSD().Equal(dec(-5000)) {
+		t.Fatalf("expected 0.5 BTC disposed from lot %s at a 5000 loss, got %+v", recent.ID, disposals)
+	}
+
+	lots, err := p.GetOpenLots("btc")
+	if err != nil {
+		t.Fatalf("GetOpenLots failed: %v", err)
+	}
+	if len(lots) != 2 || lots[0].Holding.ID != old.ID || !lots[0].Remaining.Equal(dec(1)) || !lots[1].Remaining.Equal(dec(0.5)) {
+		t.Errorf("expected the old lot whole and half the recent lot open, got %+v", lots)
+	}
+}
+
+func TestPortfolio_SaleFromLotsKeepsClaim(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	first, _ := p.AddHolding("ETH", dec(1), dec(1000), "", "", "2024-01-01")
+	second, _ := p.AddHolding("ETH", dec(1), dec(2000), "", "", "2024-02-01")
+	p.AddSaleWithFee("ETH", dec(1), dec(3000), dec(0), "", "", "2024-05-01", []string{first.ID})
+	// Backdated FIFO sale: the first lot is already claimed by the linked sale
+	p.AddSale("ETH", dec(1), dec(2500), "", "", "2024-03-01")
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 2 || disposals[0].HoldingID != second.ID || disposals[1].HoldingID != first.ID {
+		t.Errorf("expected the FIFO sale matched to lot %s, got %+v", second.ID, disposals)
+	}
+}
+
+func TestPortfolio_SaleFromLotsValidation(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	btc, _ := p.AddHolding("BTC", dec(1), dec(20000), "", "", "2024-01-01")
+	eth, _ := p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-01-01")
+	later, _ := p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-06-01")
+
+	tests := []struct {
+		name   string
+		amount float64
+		lots   []string
+	}{
+		{"unknown lot", 0.5, []string{"missing"}},
+		{"other coin", 0.5, []string{eth.ID}},
+		{"bought after the sale", 0.5, []string{later.ID}},
+		{"repeated lot", 0.5, []string{btc.ID, btc.ID}},
+		{"more than the lot holds", 1.5, []string{btc.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.AddSaleWithFee("BTC", dec(tt.amount), dec(50000), dec(0), "", "", "2024-03-01", tt.lots)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+
+	// Unchecked sales may exceed the lot, with the rest matched FIFO
+	if _, err := p.AddSaleUnchecked("BTC", dec(1.5), dec(50000), dec(0), "", "", "2024-07-01", []string{later.ID}); err != nil {
+		t.Fatalf("AddSaleUnchecked failed: %v", err)
+	}
+	disposals, _ := p.GetDisposals()
+	if len(disposals) != 2 || disposals[0].HoldingID != later.ID || disposals[1].HoldingID != btc.ID || !disposals[1].Amount.Equal(dec(0.5)) {
+		t.Errorf("expected 1 BTC from lot %s and 0.5 from lot %s, got %+v", later.ID, btc.ID, disposals)
+	}
+}
@@ -0,0 +1,77 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// MergeSummaries combines the summaries of several portfolios into one, for
+// an "all portfolios" view. Counts, USD totals, and per-coin amounts are
+// added together.
+func MergeSummaries(summaries ...Summary) Summary {
+	holdings := make(models.Totals)
+	loans := make(models.Totals)
+	stakes := make(models.Totals)
+	available := make(models.Totals)
+	net := make(models.Totals)
+	var invested, sold []decimal.Decimal
+
+	var merged Summary
+	for _, s := range summaries {
+		merged.TotalHoldingsCount += s.TotalHoldingsCount
+		merged.TotalSalesCount += s.TotalSalesCount
+		merged.TotalLoansCount += s.TotalLoansCount
+		merged.TotalStakesCount += s.TotalStakesCount
+		invested = append(invested, s.TotalInvestedUSD)
+		sold = append(sold, s.TotalSoldUSD)
+
+		addByCoin(holdings, s.HoldingsByCoin)
+		addByCoin(loans, s.LoansByCoin)
+		addByCoin(stakes, s.StakesByCoin)
+		addByCoin(available, s.AvailableByCoin)
+		addByCoin(net, s.NetByCoin)
+	}
+
+	merged.TotalInvestedUSD = decimal.Sum(decimal.Zero, invested...)
+	merged.TotalSoldUSD = decimal.Sum(decimal.Zero, sold...)
+	merged.HoldingsByCoin = holdings
+	merged.LoansByCoin = loans
+	merged.StakesByCoin = stakes
+	merged.AvailableByCoin = available
+	merged.NetByCoin = net
+	return merged
+}
+
+// MergeYield combines the projected staking yields of several portfolios by
+// coin, sorted by coin.
+func MergeYield(yields ...[]YieldEntry) []YieldEntry {
+	byCoin := make(map[string]*YieldEntry)
+	for _, entries := range yields {
+		for _, y := range entries {
+			entry, ok := byCoin[y.Coin]
+			if !ok {
+				entry = &YieldEntry{Coin: y.Coin}
+				byCoin[y.Coin] = entry
+			}
+			entry.Staked = models.Add(entry.Staked, y.Staked)
+			entry.Annual = models.Add(entry.Annual, y.Annual)
+		}
+	}
+
+	merged := make([]YieldEntry, 0, len(byCoin))
+	for _, entry := range byCoin {
+		entry.APY = entry.Annual / entry.Staked * 100
+		entry.Monthly = entry.Annual / 12
+		merged = append(merged, *entry)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Coin < merged[j].Coin })
+	return merged
+}
+
+func addByCoin(totals models.Totals, byCoin map[string]decimal.Decimal) {
+	for coin, amount := range byCoin {
+		totals.Add(coin, amount)
+	}
+}
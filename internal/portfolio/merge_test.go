@@ -0,0 +1,61 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMergeSummaries(t *testing.T) {
+	a := Summary{
+		TotalHoldingsCount: 2,
+		TotalInvestedUSD:   dec(0.1),
+		HoldingsByCoin:     map[string]decimal.Decimal{"BTC": dec(0.1), "ETH": dec(2)},
+		LoansByCoin:        map[string]decimal.Decimal{"USDT": dec(100)},
+		NetByCoin:          map[string]decimal.Decimal{"BTC": dec(0.1)},
+	}
+	b := Summary{
+		TotalHoldingsCount: 1,
+		TotalSalesCount:    1,
+		TotalInvestedUSD:   dec(0.2),
+		TotalSoldUSD:       dec(50),
+		HoldingsByCoin:     map[string]decimal.Decimal{"BTC": dec(0.2)},
+		StakesByCoin:       map[string]decimal.Decimal{"ETH": dec(1)},
+	}
+
+	merged := MergeSummaries(a, b)
+	if merged.TotalHoldingsCount != 3 || merged.TotalSalesCount != 1 {
+		t.Errorf("expected counts added, got %+v", merged)
+	}
+	if !merged.TotalInvestedUSD.Equal(dec(0.3)) || !merged.TotalSoldUSD.Equal(dec(50)) {
+		t.Errorf("expected invested 0.3 and sold 50, got %v and %v", merged.TotalInvestedUSD, merged.TotalSoldUSD)
+	}
+	if !merged.HoldingsByCoin["BTC"].Equal(dec(0.3)) || !merged.HoldingsByCoin["ETH"].Equal(dec(2)) {
+		t.Errorf("expected holdings merged by coin, got %v", merged.HoldingsByCoin)
+	}
+	if !merged.LoansByCoin["USDT"].Equal(dec(100)) || !merged.StakesByCoin["ETH"].Equal(dec(1)) {
+		t.Errorf("expected loans and stakes merged, got %v, %v", merged.LoansByCoin, merged.StakesByCoin)
+	}
+	if len(merged.AvailableByCoin) != 0 {
+		t.Errorf("expected no available coins, got %v", merged.AvailableByCoin)
+	}
+}
+
+func TestMergeYield(t *testing.T) {
+	merged := MergeYield(
+		[]YieldEntry{{Coin: "ETH", Staked: 10, APY: 4, Annual: 0.4}},
+		[]YieldEntry{{Coin: "ETH", Staked: 10, APY: 6, Annual: 0.6}, {Coin: "DOT", Staked: 100, APY: 12, Annual: 12}},
+	)
+
+	if len(merged) != 2 || merged[0].Coin != "DOT" {
+		t.Fatalf("expected DOT and ETH sorted, got %+v", merged)
+	}
+	eth := merged[1]
+	if eth.Staked != 20 || eth.Annual != 1 {
+		t.Errorf("expected 20 ETH staked earning 1/yr, got %+v", eth)
+	}
+	if math.Abs(eth.APY-5) > 1e-9 {
+		t.Errorf("expected weighted APY 5%%, got %f", eth.APY)
+	}
+}
@@ -2,10 +2,12 @@ package portfolio
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
 )
 
 // Summary contains portfolio summary data.
@@ -14,35 +16,58 @@ type Summary struct {
 	TotalSalesCount    int
 	TotalLoansCount    int
 	TotalStakesCount   int
-	TotalInvestedUSD   float64
-	TotalSoldUSD       float64
-	HoldingsByCoin     map[string]float64 // Current holdings: purchases - sales
-	LoansByCoin        map[string]float64
-	StakesByCoin       map[string]float64
-	AvailableByCoin    map[string]float64 // Holdings - staked
-	NetByCoin          map[string]float64 // Holdings - loans
+	TotalInvestedUSD   decimal.Decimal
+	TotalSoldUSD       decimal.Decimal
+	HoldingsByCoin     map[string]decimal.Decimal // Current holdings: purchases - sales
+	LoansByCoin        map[string]decimal.Decimal
+	StakesByCoin       map[string]decimal.Decimal
+	AvailableByCoin    map[string]decimal.Decimal // Holdings - staked
+	NetByCoin          map[string]decimal.Decimal // Holdings - loans
 }
 
 // Portfolio manages crypto holdings, sales, and loans.
 type Portfolio struct {
-	storage *storage.Storage
+	storage          storage.Backend
+	interestMethod   string
+	allowFutureDates bool
 }
 
 // New creates a new Portfolio instance.
-func New(s *storage.Storage) *Portfolio {
+func New(s storage.Backend) *Portfolio {
 	return &Portfolio{storage: s}
 }
 
 // Holdings
 
-// AddHolding adds a new coin holding.
-func (p *Portfolio) AddHolding(coin string, amount, purchasePriceUSD float64, platform, notes, date string) (models.Holding, error) {
-	holding := models.NewHolding(strings.ToUpper(coin), amount, purchasePriceUSD, platform, notes, date)
-	err := p.storage.AddHolding(holding)
+// AddHolding adds a new coin holding. Each tag may hold several
+// comma-separated labels, see models.ParseTags.
+func (p *Portfolio) AddHolding(coin string, amount, purchasePriceUSD decimal.Decimal, platform, notes, date string, tags ...string) (models.Holding, error) {
+	return p.AddHoldingWithFee(coin, amount, purchasePriceUSD, decimal.Zero, platform, notes, date, tags...)
+}
+
+// AddHoldingWithFee adds a new coin holding with a purchase fee in USD.
+func (p *Portfolio) AddHoldingWithFee(coin string, amount, purchasePriceUSD, feeUSD decimal.Decimal, platform, notes, date string, tags ...string) (models.Holding, error) {
+	if err := validatePositive("amount", amount); err != nil {
+		return models.Holding{}, err
+	}
+	if err := validatePositive("price", purchasePriceUSD); err != nil {
+		return models.Holding{}, err
+	}
+	if feeUSD.IsNegative() {
+		return models.Holding{}, invalidf("fee cannot be negative")
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Holding{}, err
+	}
+	holding := models.NewHolding(strings.ToUpper(coin), amount, purchasePriceUSD, platform, notes, parsed)
+	holding.FeeUSD = feeUSD
+	holding.Tags = models.ParseTags(tags...)
+	err = p.storage.AddHolding(holding)
 	return holding, err
 }
 
-// RemoveHolding removes a holding by ID.
+// RemoveHolding moves a holding to the trash by ID.
 func (p *Portfolio) RemoveHolding(id string) (bool, error) {
 	return p.storage.RemoveHolding(id)
 }
@@ -55,13 +80,21 @@ func (p *Portfolio) ListHoldings() ([]models.Holding, error) {
 // Loans
 
 // AddLoan adds a new loan.
-func (p *Portfolio) AddLoan(coin string, amount float64, platform string, interestRate *float64, notes, date string) (models.Loan, error) {
-	loan := models.NewLoan(strings.ToUpper(coin), amount, platform, interestRate, notes, date)
-	err := p.storage.AddLoan(loan)
+func (p *Portfolio) AddLoan(coin string, amount decimal.Decimal, platform string, interestRate *float64, notes, date string, tags ...string) (models.Loan, error) {
+	if err := validatePositive("amount", amount); err != nil {
+		return models.Loan{}, err
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Loan{}, err
+	}
+	loan := models.NewLoan(strings.ToUpper(coin), amount, platform, interestRate, notes, parsed)
+	loan.Tags = models.ParseTags(tags...)
+	err = p.storage.AddLoan(loan)
 	return loan, err
 }
 
-// RemoveLoan removes a loan by ID.
+// RemoveLoan moves a loan and its repayments to the trash by ID.
 func (p *Portfolio) RemoveLoan(id string) (bool, error) {
 	return p.storage.RemoveLoan(id)
 }
@@ -71,16 +104,145 @@ func (p *Portfolio) ListLoans() ([]models.Loan, error) {
 	return p.storage.GetLoans()
 }
 
+// RepayLoan records a repayment against a loan. The amount cannot exceed
+// the loan's outstanding balance.
+func (p *Portfolio) RepayLoan(loanID string, amount decimal.Decimal, notes, date string, tags ...string) (models.Repayment, error) {
+	if !amount.IsPositive() {
+		return models.Repayment{}, invalidf("repayment amount must be positive")
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Repayment{}, err
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return models.Repayment{}, err
+	}
+	var loan *models.Loan
+	for i := range loans {
+		if loans[i].ID == loanID {
+			loan = &loans[i]
+			break
+		}
+	}
+	if loan == nil {
+		return models.Repayment{}, fmt.Errorf("loan %s %w", loanID, ErrNotFound)
+	}
+
+	outstanding, err := p.GetOutstandingByLoan()
+	if err != nil {
+		return models.Repayment{}, err
+	}
+	if amount.GreaterThan(outstanding[loanID]) {
+		return models.Repayment{}, invalidf("cannot repay %s %s: loan %s only has %s %s outstanding", amount, loan.Coin, loanID, outstanding[loanID], loan.Coin)
+	}
+
+	repayment := models.NewRepayment(loanID, amount, notes, parsed)
+	repayment.Tags = models.ParseTags(tags...)
+	err = p.storage.AddRepayment(repayment)
+	return repayment, err
+}
+
+// RemoveRepayment removes a loan repayment by ID.
+func (p *Portfolio) RemoveRepayment(id string) (bool, error) {
+	return p.storage.RemoveRepayment(id)
+}
+
+// ListRepayments lists all loan repayments.
+func (p *Portfolio) ListRepayments() ([]models.Repayment, error) {
+	return p.storage.GetRepayments()
+}
+
+// GetOutstandingByLoan returns each loan's remaining balance (amount - repayments) by loan ID.
+func (p *Portfolio) GetOutstandingByLoan() (map[string]decimal.Decimal, error) {
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding := make(models.Totals)
+	for _, l := range loans {
+		outstanding.Add(l.ID, l.Amount)
+	}
+	for _, r := range repayments {
+		if _, ok := outstanding[r.LoanID]; ok {
+			outstanding.Sub(r.LoanID, r.Amount)
+		}
+	}
+	return outstanding, nil
+}
+
 // Sales
 
 // AddSale adds a new sale.
-func (p *Portfolio) AddSale(coin string, amount, sellPriceUSD float64, platform, notes, date string) (models.Sale, error) {
-	sale := models.NewSale(strings.ToUpper(coin), amount, sellPriceUSD, platform, notes, date)
-	err := p.storage.AddSale(sale)
+func (p *Portfolio) AddSale(coin string, amount, sellPriceUSD decimal.Decimal, platform, notes, date string, tags ...string) (models.Sale, error) {
+	return p.AddSaleWithFee(coin, amount, sellPriceUSD, decimal.Zero, platform, notes, date, nil, tags...)
+}
+
+// AddSaleWithFee adds a new sale with a sale fee in USD, with validation
+// that you can only sell what you have available. lotIDs optionally names
+// the purchases (or swaps) the coins came from, see GetDisposals.
+func (p *Portfolio) AddSaleWithFee(coin string, amount, sellPriceUSD, feeUSD decimal.Decimal, platform, notes, date string, lotIDs []string, tags ...string) (models.Sale, error) {
+	return p.addSale(coin, amount, sellPriceUSD, feeUSD, platform, notes, date, lotIDs, tags, true)
+}
+
+// AddSaleUnchecked adds a new sale without checking the available balance,
+// for coins that came from wallets not tracked in the portfolio. Any amount
+// not covered by lotIDs is matched first-in, first-out.
+func (p *Portfolio) AddSaleUnchecked(coin string, amount, sellPriceUSD, feeUSD decimal.Decimal, platform, notes, date string, lotIDs []string, tags ...string) (models.Sale, error) {
+	return p.addSale(coin, amount, sellPriceUSD, feeUSD, platform, notes, date, lotIDs, tags, false)
+}
+
+func (p *Portfolio) addSale(coin string, amount, sellPriceUSD, feeUSD decimal.Decimal, platform, notes, date string, lotIDs, tags []string, checkBalance bool) (models.Sale, error) {
+	coin = strings.ToUpper(coin)
+	if err := validatePositive("amount", amount); err != nil {
+		return models.Sale{}, err
+	}
+	if err := validatePositive("price", sellPriceUSD); err != nil {
+		return models.Sale{}, err
+	}
+	if feeUSD.IsNegative() {
+		return models.Sale{}, invalidf("fee cannot be negative")
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	if checkBalance {
+		// Staked coins can't be sold until they are unstaked, and a
+		// backdated sale can only sell what was held by its date
+		availableAmount, err := p.GetAvailableOn(coin, parsed)
+		if err != nil {
+			return models.Sale{}, err
+		}
+		if amount.GreaterThan(availableAmount) {
+			if !availableAmount.IsPositive() {
+				return models.Sale{}, invalidf("cannot sell %s %s: you have no available %s to sell%s", amount, coin, coin, onDate(parsed))
+			}
+			return models.Sale{}, invalidf("cannot sell %s %s: only %s %s available%s (holdings - sales - staked)", amount, coin, availableAmount, coin, onDate(parsed))
+		}
+	}
+
+	if err := p.validateLots(coin, amount, parsed, lotIDs, checkBalance); err != nil {
+		return models.Sale{}, err
+	}
+
+	sale := models.NewSale(coin, amount, sellPriceUSD, platform, notes, parsed)
+	sale.FeeUSD = feeUSD
+	sale.LotIDs = lotIDs
+	sale.Tags = models.ParseTags(tags...)
+	err = p.storage.AddSale(sale)
 	return sale, err
 }
 
-// RemoveSale removes a sale by ID.
+// RemoveSale moves a sale to the trash by ID.
 func (p *Portfolio) RemoveSale(id string) (bool, error) {
 	return p.storage.RemoveSale(id)
 }
@@ -93,8 +255,15 @@ func (p *Portfolio) ListSales() ([]models.Sale, error) {
 // Stakes
 
 // AddStake adds a new stake with validation that you can only stake what you own.
-func (p *Portfolio) AddStake(coin string, amount float64, platform string, apy *float64, notes, date string) (models.Stake, error) {
+func (p *Portfolio) AddStake(coin string, amount decimal.Decimal, platform string, apy *float64, notes, date string, tags ...string) (models.Stake, error) {
 	coin = strings.ToUpper(coin)
+	if err := validatePositive("amount", amount); err != nil {
+		return models.Stake{}, err
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Stake{}, err
+	}
 
 	// Calculate available balance for this coin
 	available, err := p.GetAvailableByCoin()
@@ -103,23 +272,55 @@ func (p *Portfolio) AddStake(coin string, amount float64, platform string, apy *
 	}
 
 	availableAmount := available[coin]
-	if amount > availableAmount {
-		if availableAmount <= 0 {
-			return models.Stake{}, fmt.Errorf("cannot stake %.8g %s: you have no available %s to stake", amount, coin, coin)
+	if amount.GreaterThan(availableAmount) {
+		if !availableAmount.IsPositive() {
+			return models.Stake{}, invalidf("cannot stake %s %s: you have no available %s to stake", amount, coin, coin)
 		}
-		return models.Stake{}, fmt.Errorf("cannot stake %.8g %s: only %.8g %s available (holdings - sales - already staked)", amount, coin, availableAmount, coin)
+		return models.Stake{}, invalidf("cannot stake %s %s: only %s %s available (holdings - sales - already staked)", amount, coin, availableAmount, coin)
 	}
 
-	stake := models.NewStake(coin, amount, platform, apy, notes, date)
+	stake := models.NewStake(coin, amount, platform, apy, notes, parsed)
+	stake.Tags = models.ParseTags(tags...)
 	err = p.storage.AddStake(stake)
 	return stake, err
 }
 
-// RemoveStake removes a stake by ID.
+// RemoveStake moves a stake to the trash by ID.
 func (p *Portfolio) RemoveStake(id string) (bool, error) {
 	return p.storage.RemoveStake(id)
 }
 
+// ReduceStake unstakes part of a stake, keeping its date, APY, and notes.
+// Reducing by the full amount removes the stake. The updated stake is returned.
+func (p *Portfolio) ReduceStake(id string, amount decimal.Decimal) (models.Stake, error) {
+	if !amount.IsPositive() {
+		return models.Stake{}, invalidf("amount to unstake must be positive")
+	}
+
+	stakes, err := p.storage.GetStakes()
+	if err != nil {
+		return models.Stake{}, err
+	}
+
+	for _, st := range stakes {
+		if st.ID != id {
+			continue
+		}
+		if amount.GreaterThan(st.Amount) {
+			return models.Stake{}, invalidf("cannot unstake %s %s: stake %s only has %s %s", amount, st.Coin, id, st.Amount, st.Coin)
+		}
+
+		st.Amount = st.Amount.Sub(amount)
+		if st.Amount.IsZero() {
+			_, err = p.storage.RemoveStake(id)
+		} else {
+			_, err = p.storage.UpdateStake(st)
+		}
+		return st, err
+	}
+	return models.Stake{}, fmt.Errorf("stake %s %w", id, ErrNotFound)
+}
+
 // ListStakes lists all stakes.
 func (p *Portfolio) ListStakes() ([]models.Stake, error) {
 	return p.storage.GetStakes()
@@ -128,50 +329,59 @@ func (p *Portfolio) ListStakes() ([]models.Stake, error) {
 // Summary methods
 
 // GetHoldingsByCoin returns total holdings aggregated by coin.
-func (p *Portfolio) GetHoldingsByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetHoldingsByCoin() (map[string]decimal.Decimal, error) {
 	holdings, err := p.ListHoldings()
 	if err != nil {
 		return nil, err
 	}
 
-	byCoin := make(map[string]float64)
+	byCoin := make(models.Totals)
 	for _, h := range holdings {
-		byCoin[h.Coin] += h.Amount
+		byCoin.Add(h.Coin, h.Amount)
 	}
 	return byCoin, nil
 }
 
-// GetLoansByCoin returns total loans aggregated by coin.
-func (p *Portfolio) GetLoansByCoin() (map[string]float64, error) {
+// GetLoansByCoin returns total outstanding loans aggregated by coin.
+// Fully repaid loans are not included.
+func (p *Portfolio) GetLoansByCoin() (map[string]decimal.Decimal, error) {
 	loans, err := p.ListLoans()
 	if err != nil {
 		return nil, err
 	}
 
-	byCoin := make(map[string]float64)
+	outstanding, err := p.GetOutstandingByLoan()
+	if err != nil {
+		return nil, err
+	}
+
+	byCoin := make(models.Totals)
 	for _, l := range loans {
-		byCoin[l.Coin] += l.Amount
+		if outstanding[l.ID].IsPositive() {
+			byCoin.Add(l.Coin, outstanding[l.ID])
+		}
 	}
 	return byCoin, nil
 }
 
 // GetSalesByCoin returns total sales aggregated by coin.
-func (p *Portfolio) GetSalesByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetSalesByCoin() (map[string]decimal.Decimal, error) {
 	sales, err := p.ListSales()
 	if err != nil {
 		return nil, err
 	}
 
-	byCoin := make(map[string]float64)
+	byCoin := make(models.Totals)
 	for _, s := range sales {
-		byCoin[s.Coin] += s.Amount
+		byCoin.Add(s.Coin, s.Amount)
 	}
 	return byCoin, nil
 }
 
-// GetCurrentHoldingsByCoin returns current holdings (purchases - sales) by coin.
+// GetCurrentHoldingsByCoin returns current holdings (purchases - sales - transfer fees,
+// adjusted for swaps) by coin.
 // This represents what you actually own right now.
-func (p *Portfolio) GetCurrentHoldingsByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetCurrentHoldingsByCoin() (map[string]decimal.Decimal, error) {
 	purchases, err := p.GetHoldingsByCoin()
 	if err != nil {
 		return nil, err
@@ -182,6 +392,16 @@ func (p *Portfolio) GetCurrentHoldingsByCoin() (map[string]float64, error) {
 		return nil, err
 	}
 
+	fees, err := p.GetTransferFeesByCoin()
+	if err != nil {
+		return nil, err
+	}
+
+	swaps, err := p.GetSwapsByCoin()
+	if err != nil {
+		return nil, err
+	}
+
 	// Collect all coins
 	allCoins := make(map[string]bool)
 	for coin := range purchases {
@@ -190,31 +410,34 @@ func (p *Portfolio) GetCurrentHoldingsByCoin() (map[string]float64, error) {
 	for coin := range sales {
 		allCoins[coin] = true
 	}
+	for coin := range swaps {
+		allCoins[coin] = true
+	}
 
-	current := make(map[string]float64)
+	current := make(map[string]decimal.Decimal)
 	for coin := range allCoins {
-		current[coin] = purchases[coin] - sales[coin]
+		current[coin] = purchases[coin].Sub(sales[coin]).Sub(fees[coin]).Add(swaps[coin])
 	}
 	return current, nil
 }
 
 // GetStakesByCoin returns total stakes aggregated by coin.
-func (p *Portfolio) GetStakesByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetStakesByCoin() (map[string]decimal.Decimal, error) {
 	stakes, err := p.ListStakes()
 	if err != nil {
 		return nil, err
 	}
 
-	byCoin := make(map[string]float64)
+	byCoin := make(models.Totals)
 	for _, st := range stakes {
-		byCoin[st.Coin] += st.Amount
+		byCoin.Add(st.Coin, st.Amount)
 	}
 	return byCoin, nil
 }
 
 // GetAvailableByCoin returns available coins (current holdings - staked) by coin.
 // This represents coins that you own and are not currently staked.
-func (p *Portfolio) GetAvailableByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetAvailableByCoin() (map[string]decimal.Decimal, error) {
 	currentHoldings, err := p.GetCurrentHoldingsByCoin()
 	if err != nil {
 		return nil, err
@@ -234,16 +457,95 @@ func (p *Portfolio) GetAvailableByCoin() (map[string]float64, error) {
 		allCoins[coin] = true
 	}
 
-	available := make(map[string]float64)
+	available := make(map[string]decimal.Decimal)
 	for coin := range allCoins {
-		available[coin] = currentHoldings[coin] - stakes[coin]
+		available[coin] = currentHoldings[coin].Sub(stakes[coin])
 	}
 	return available, nil
 }
 
+// GetAvailableOn returns how much of coin can be sold or swapped on date:
+// the balance from records dated on or before it, less staked coins. Later
+// records count too when they leave less, so a backdated sale can't take
+// coins a later sale already used. A zero date gives the current balance,
+// as GetAvailableByCoin.
+func (p *Portfolio) GetAvailableOn(coin string, date models.Date) (decimal.Decimal, error) {
+	if date.IsZero() {
+		available, err := p.GetAvailableByCoin()
+		return available[coin], err
+	}
+
+	type change struct {
+		date   models.Date
+		amount decimal.Decimal
+	}
+	var changes []change
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, h := range holdings {
+		if h.Coin == coin {
+			changes = append(changes, change{h.Date, h.Amount})
+		}
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, s := range sales {
+		if s.Coin == coin {
+			changes = append(changes, change{s.Date, s.Amount.Neg()})
+		}
+	}
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, t := range transfers {
+		if t.Coin == coin {
+			changes = append(changes, change{t.Date, t.Fee.Neg()})
+		}
+	}
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, sw := range swaps {
+		if sw.FromCoin == coin {
+			changes = append(changes, change{sw.Date, sw.FromAmount.Neg()})
+		}
+		if sw.ToCoin == coin {
+			changes = append(changes, change{sw.Date, sw.ToAmount})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].date.Before(changes[j].date) })
+
+	// The balance on date, then the lowest it falls to on any later day
+	balance := decimal.Zero
+	i := 0
+	for ; i < len(changes) && !changes[i].date.After(date); i++ {
+		balance = balance.Add(changes[i].amount)
+	}
+	lowest := balance
+	for i < len(changes) {
+		day := changes[i].date
+		for ; i < len(changes) && changes[i].date.Equal(day.Time); i++ {
+			balance = balance.Add(changes[i].amount)
+		}
+		lowest = decimal.Min(lowest, balance)
+	}
+
+	stakes, err := p.GetStakesByCoin()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return lowest.Sub(stakes[coin]), nil
+}
+
 // GetNetHoldingsByCoin returns net holdings (current holdings - loans) by coin.
 // This represents what you'd have if all loans were paid back.
-func (p *Portfolio) GetNetHoldingsByCoin() (map[string]float64, error) {
+func (p *Portfolio) GetNetHoldingsByCoin() (map[string]decimal.Decimal, error) {
 	currentHoldings, err := p.GetCurrentHoldingsByCoin()
 	if err != nil {
 		return nil, err
@@ -263,37 +565,81 @@ func (p *Portfolio) GetNetHoldingsByCoin() (map[string]float64, error) {
 		allCoins[coin] = true
 	}
 
-	net := make(map[string]float64)
+	net := make(map[string]decimal.Decimal)
 	for coin := range allCoins {
-		net[coin] = currentHoldings[coin] - loans[coin]
+		net[coin] = currentHoldings[coin].Sub(loans[coin])
 	}
 	return net, nil
 }
 
-// GetTotalInvestedUSD returns total USD invested in holdings.
-func (p *Portfolio) GetTotalInvestedUSD() (float64, error) {
+// GetCoins returns the sorted unique coins across all holdings, sales, loans, and stakes.
+func (p *Portfolio) GetCoins() ([]string, error) {
+	seen := make(map[string]bool)
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		seen[h.Coin] = true
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sales {
+		seen[s.Coin] = true
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		seen[l.Coin] = true
+	}
+
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stakes {
+		seen[st.Coin] = true
+	}
+
+	coins := make([]string, 0, len(seen))
+	for coin := range seen {
+		coins = append(coins, coin)
+	}
+	sort.Strings(coins)
+	return coins, nil
+}
+
+// GetTotalInvestedUSD returns total USD invested in holdings, including fees.
+func (p *Portfolio) GetTotalInvestedUSD() (decimal.Decimal, error) {
 	holdings, err := p.ListHoldings()
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
-	var total float64
+	total := decimal.Zero
 	for _, h := range holdings {
-		total += h.TotalValueUSD()
+		total = total.Add(h.CostUSD())
 	}
 	return total, nil
 }
 
-// GetTotalSoldUSD returns total USD received from sales.
-func (p *Portfolio) GetTotalSoldUSD() (float64, error) {
+// GetTotalSoldUSD returns total USD received from sales, after fees.
+func (p *Portfolio) GetTotalSoldUSD() (decimal.Decimal, error) {
 	sales, err := p.ListSales()
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
-	var total float64
+	total := decimal.Zero
 	for _, s := range sales {
-		total += s.TotalValueUSD()
+		total = total.Add(s.ProceedsUSD())
 	}
 	return total, nil
 }
@@ -370,3 +716,65 @@ func (p *Portfolio) GetSummary() (Summary, error) {
 		NetByCoin:          netByCoin,
 	}, nil
 }
+
+// GetSummaryBetween returns a summary whose record counts and invested and
+// sold totals only include records dated from since through until. A zero
+// date leaves that end of the range open. Balances are current, as in
+// GetSummary.
+func (p *Portfolio) GetSummaryBetween(since, until models.Date) (Summary, error) {
+	summary, err := p.GetSummary()
+	if err != nil || since.IsZero() && until.IsZero() {
+		return summary, err
+	}
+	inRange := Filter{Since: since, Until: until}.Matches
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return Summary{}, err
+	}
+	var costs []decimal.Decimal
+	for _, h := range holdings {
+		if inRange(h.Coin, h.Platform, h.Date) {
+			costs = append(costs, h.CostUSD())
+		}
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return Summary{}, err
+	}
+	var proceeds []decimal.Decimal
+	for _, s := range sales {
+		if inRange(s.Coin, s.Platform, s.Date) {
+			proceeds = append(proceeds, s.ProceedsUSD())
+		}
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.TotalLoansCount = 0
+	for _, l := range loans {
+		if inRange(l.Coin, l.Platform, l.Date) {
+			summary.TotalLoansCount++
+		}
+	}
+
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.TotalStakesCount = 0
+	for _, st := range stakes {
+		if inRange(st.Coin, st.Platform, st.Date) {
+			summary.TotalStakesCount++
+		}
+	}
+
+	summary.TotalHoldingsCount = len(costs)
+	summary.TotalSalesCount = len(proceeds)
+	summary.TotalInvestedUSD = decimal.Sum(decimal.Zero, costs...)
+	summary.TotalSoldUSD = decimal.Sum(decimal.Zero, proceeds...)
+	return summary, nil
+}
@@ -1,13 +1,22 @@
 package portfolio
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/pretty-andrechal/follyo/internal/models"
 	"github.com/pretty-andrechal/follyo/internal/storage"
+	"github.com/shopspring/decimal"
 )
 
+// dec returns v as a decimal, for writing record amounts as literals
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
 func setupTestPortfolio(t *testing.T) (*Portfolio, func()) {
 	t.Helper()
 
@@ -37,7 +46,7 @@ func TestPortfolio_Holdings(t *testing.T) {
 	defer cleanup()
 
 	// Add holdings
-	h1, err := p.AddHolding("btc", 1.0, 50000, "Binance", "test", "2024-01-01")
+	h1, err := p.AddHolding("btc", dec(1.0), dec(50000), "Binance", "test", "2024-01-01")
 	if err != nil {
 		t.Fatalf("AddHolding failed: %v", err)
 	}
@@ -45,7 +54,7 @@ func TestPortfolio_Holdings(t *testing.T) {
 		t.Errorf("expected coin to be uppercased to BTC, got %s", h1.Coin)
 	}
 
-	h2, err := p.AddHolding("ETH", 10, 3000, "", "", "")
+	h2, err := p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
 	if err != nil {
 		t.Fatalf("AddHolding failed: %v", err)
 	}
@@ -94,7 +103,7 @@ func TestPortfolio_Loans(t *testing.T) {
 
 	// Add loans
 	rate := 6.9
-	l1, err := p.AddLoan("usdt", 5000, "Nexo", &rate, "credit", "2024-01-01")
+	l1, err := p.AddLoan("usdt", dec(5000), "Nexo", &rate, "credit", "2024-01-01")
 	if err != nil {
 		t.Fatalf("AddLoan failed: %v", err)
 	}
@@ -102,7 +111,7 @@ func TestPortfolio_Loans(t *testing.T) {
 		t.Errorf("expected coin to be uppercased to USDT, got %s", l1.Coin)
 	}
 
-	l2, err := p.AddLoan("BTC", 0.5, "Celsius", nil, "", "")
+	l2, err := p.AddLoan("BTC", dec(0.5), "Celsius", nil, "", "")
 	if err != nil {
 		t.Fatalf("AddLoan failed: %v", err)
 	}
@@ -140,8 +149,11 @@ func TestPortfolio_Sales(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2023-12-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2023-12-01")
+
 	// Add sales
-	s1, err := p.AddSale("btc", 0.5, 55000, "Binance", "profit", "2024-01-01")
+	s1, err := p.AddSale("btc", dec(0.5), dec(55000), "Binance", "profit", "2024-01-01")
 	if err != nil {
 		t.Fatalf("AddSale failed: %v", err)
 	}
@@ -149,7 +161,7 @@ func TestPortfolio_Sales(t *testing.T) {
 		t.Errorf("expected coin to be uppercased to BTC, got %s", s1.Coin)
 	}
 
-	s2, err := p.AddSale("ETH", 5, 3500, "", "", "")
+	s2, err := p.AddSale("ETH", dec(5), dec(3500), "", "", "")
 	if err != nil {
 		t.Fatalf("AddSale failed: %v", err)
 	}
@@ -187,20 +199,20 @@ func TestPortfolio_GetHoldingsByCoin(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
-	p.AddHolding("BTC", 1.0, 50000, "", "", "")
-	p.AddHolding("BTC", 0.5, 55000, "", "", "")
-	p.AddHolding("ETH", 10, 3000, "", "", "")
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")
+	p.AddHolding("BTC", dec(0.5), dec(55000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
 
 	byCoin, err := p.GetHoldingsByCoin()
 	if err != nil {
 		t.Fatalf("GetHoldingsByCoin failed: %v", err)
 	}
 
-	if byCoin["BTC"] != 1.5 {
-		t.Errorf("expected BTC holdings 1.5, got %f", byCoin["BTC"])
+	if !byCoin["BTC"].Equal(dec(1.5)) {
+		t.Errorf("expected BTC holdings 1.5, got %v", byCoin["BTC"])
 	}
-	if byCoin["ETH"] != 10 {
-		t.Errorf("expected ETH holdings 10, got %f", byCoin["ETH"])
+	if !byCoin["ETH"].Equal(dec(10)) {
+		t.Errorf("expected ETH holdings 10, got %v", byCoin["ETH"])
 	}
 }
 
@@ -208,20 +220,20 @@ func TestPortfolio_GetLoansByCoin(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
-	p.AddLoan("USDT", 5000, "Nexo", nil, "", "")
-	p.AddLoan("USDT", 3000, "Celsius", nil, "", "")
-	p.AddLoan("BTC", 0.1, "Nexo", nil, "", "")
+	p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "")
+	p.AddLoan("USDT", dec(3000), "Celsius", nil, "", "")
+	p.AddLoan("BTC", dec(0.1), "Nexo", nil, "", "")
 
 	byCoin, err := p.GetLoansByCoin()
 	if err != nil {
 		t.Fatalf("GetLoansByCoin failed: %v", err)
 	}
 
-	if byCoin["USDT"] != 8000 {
-		t.Errorf("expected USDT loans 8000, got %f", byCoin["USDT"])
+	if !byCoin["USDT"].Equal(dec(8000)) {
+		t.Errorf("expected USDT loans 8000, got %v", byCoin["USDT"])
 	}
-	if byCoin["BTC"] != 0.1 {
-		t.Errorf("expected BTC loans 0.1, got %f", byCoin["BTC"])
+	if !byCoin["BTC"].Equal(dec(0.1)) {
+		t.Errorf("expected BTC loans 0.1, got %v", byCoin["BTC"])
 	}
 }
 
@@ -229,20 +241,22 @@ func TestPortfolio_GetSalesByCoin(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
-	p.AddSale("BTC", 0.3, 55000, "", "", "")
-	p.AddSale("BTC", 0.2, 60000, "", "", "")
-	p.AddSale("ETH", 5, 3500, "", "", "")
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "")
+	p.AddSale("BTC", dec(0.3), dec(55000), "", "", "")
+	p.AddSale("BTC", dec(0.2), dec(60000), "", "", "")
+	p.AddSale("ETH", dec(5), dec(3500), "", "", "")
 
 	byCoin, err := p.GetSalesByCoin()
 	if err != nil {
 		t.Fatalf("GetSalesByCoin failed: %v", err)
 	}
 
-	if byCoin["BTC"] != 0.5 {
-		t.Errorf("expected BTC sales 0.5, got %f", byCoin["BTC"])
+	if !byCoin["BTC"].Equal(dec(0.5)) {
+		t.Errorf("expected BTC sales 0.5, got %v", byCoin["BTC"])
 	}
-	if byCoin["ETH"] != 5 {
-		t.Errorf("expected ETH sales 5, got %f", byCoin["ETH"])
+	if !byCoin["ETH"].Equal(dec(5)) {
+		t.Errorf("expected ETH sales 5, got %v", byCoin["ETH"])
 	}
 }
 
@@ -251,11 +265,11 @@ func TestPortfolio_GetCurrentHoldingsByCoin(t *testing.T) {
 	defer cleanup()
 
 	// Add holdings
-	p.AddHolding("BTC", 2.0, 50000, "", "", "")
-	p.AddHolding("ETH", 10, 3000, "", "", "")
+	p.AddHolding("BTC", dec(2.0), dec(50000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
 
 	// Add sales
-	p.AddSale("BTC", 0.5, 55000, "", "", "")
+	p.AddSale("BTC", dec(0.5), dec(55000), "", "", "")
 
 	current, err := p.GetCurrentHoldingsByCoin()
 	if err != nil {
@@ -263,13 +277,13 @@ func TestPortfolio_GetCurrentHoldingsByCoin(t *testing.T) {
 	}
 
 	// BTC: 2.0 - 0.5 = 1.5
-	if current["BTC"] != 1.5 {
-		t.Errorf("expected BTC current holdings 1.5, got %f", current["BTC"])
+	if !current["BTC"].Equal(dec(1.5)) {
+		t.Errorf("expected BTC current holdings 1.5, got %v", current["BTC"])
 	}
 
 	// ETH: 10 - 0 = 10
-	if current["ETH"] != 10 {
-		t.Errorf("expected ETH current holdings 10, got %f", current["ETH"])
+	if !current["ETH"].Equal(dec(10)) {
+		t.Errorf("expected ETH current holdings 10, got %v", current["ETH"])
 	}
 }
 
@@ -278,15 +292,15 @@ func TestPortfolio_GetNetHoldingsByCoin(t *testing.T) {
 	defer cleanup()
 
 	// Add holdings
-	p.AddHolding("BTC", 1.0, 50000, "", "", "")
-	p.AddHolding("ETH", 10, 3000, "", "", "")
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
 
 	// Add sales
-	p.AddSale("BTC", 0.3, 55000, "", "", "")
+	p.AddSale("BTC", dec(0.3), dec(55000), "", "", "")
 
 	// Add loans
-	p.AddLoan("BTC", 0.1, "Nexo", nil, "", "")
-	p.AddLoan("USDT", 5000, "Nexo", nil, "", "")
+	p.AddLoan("BTC", dec(0.1), "Nexo", nil, "", "")
+	p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "")
 
 	net, err := p.GetNetHoldingsByCoin()
 	if err != nil {
@@ -294,18 +308,43 @@ func TestPortfolio_GetNetHoldingsByCoin(t *testing.T) {
 	}
 
 	// BTC: 1.0 - 0.3 - 0.1 = 0.6
-	if net["BTC"] != 0.6 {
-		t.Errorf("expected BTC net 0.6, got %f", net["BTC"])
+	if !net["BTC"].Equal(dec(0.6)) {
+		t.Errorf("expected BTC net 0.6, got %v", net["BTC"])
 	}
 
 	// ETH: 10 - 0 - 0 = 10
-	if net["ETH"] != 10 {
-		t.Errorf("expected ETH net 10, got %f", net["ETH"])
+	if !net["ETH"].Equal(dec(10)) {
+		t.Errorf("expected ETH net 10, got %v", net["ETH"])
 	}
 
 	// USDT: 0 - 0 - 5000 = -5000
-	if net["USDT"] != -5000 {
-		t.Errorf("expected USDT net -5000, got %f", net["USDT"])
+	if !net["USDT"].Equal(dec(-5000)) {
+		t.Errorf("expected USDT net -5000, got %v", net["USDT"])
+	}
+}
+
+func TestPortfolio_GetCoins(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "")
+	p.AddSale("BTC", dec(0.5), dec(60000), "", "", "")
+	p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "")
+
+	coins, err := p.GetCoins()
+	if err != nil {
+		t.Fatalf("GetCoins failed: %v", err)
+	}
+	expected := []string{"BTC", "ETH", "USDC"}
+	if len(coins) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, coins)
+	}
+	for i, coin := range expected {
+		if coins[i] != coin {
+			t.Errorf("expected %v, got %v", expected, coins)
+		}
 	}
 }
 
@@ -313,16 +352,16 @@ func TestPortfolio_GetTotalInvestedUSD(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
-	p.AddHolding("BTC", 1.0, 50000, "", "", "")   // 50000
-	p.AddHolding("ETH", 10, 3000, "", "", "")     // 30000
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")   // 50000
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")     // 30000
 
 	total, err := p.GetTotalInvestedUSD()
 	if err != nil {
 		t.Fatalf("GetTotalInvestedUSD failed: %v", err)
 	}
 
-	if total != 80000 {
-		t.Errorf("expected total invested 80000, got %f", total)
+	if !total.Equal(dec(80000)) {
+		t.Errorf("expected total invested 80000, got %v", total)
 	}
 }
 
@@ -330,16 +369,62 @@ func TestPortfolio_GetTotalSoldUSD(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
-	p.AddSale("BTC", 0.5, 55000, "", "", "")   // 27500
-	p.AddSale("ETH", 5, 3500, "", "", "")      // 17500
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "")
+	p.AddSale("BTC", dec(0.5), dec(55000), "", "", "")   // 27500
+	p.AddSale("ETH", dec(5), dec(3500), "", "", "")      // 17500
 
 	total, err := p.GetTotalSoldUSD()
 	if err != nil {
 		t.Fatalf("GetTotalSoldUSD failed: %v", err)
 	}
 
-	if total != 45000 {
-		t.Errorf("expected total sold 45000, got %f", total)
+	if !total.Equal(dec(45000)) {
+		t.Errorf("expected total sold 45000, got %v", total)
+	}
+}
+
+func TestPortfolio_Fees(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHoldingWithFee("BTC", dec(1.0), dec(50000), dec(100), "", "", "")
+	p.AddSaleWithFee("BTC", dec(0.5), dec(60000), dec(50), "", "", "", nil)
+
+	invested, _ := p.GetTotalInvestedUSD()
+	if !invested.Equal(dec(50100)) {
+		t.Errorf("expected invested 50100 including fee, got %v", invested)
+	}
+	sold, _ := p.GetTotalSoldUSD()
+	if !sold.Equal(dec(29950)) {
+		t.Errorf("expected sold 29950 after fee, got %v", sold)
+	}
+
+	if _, err := p.AddHoldingWithFee("BTC", dec(1.0), dec(50000), dec(-1), "", "", ""); err == nil {
+		t.Error("expected error for negative fee")
+	}
+}
+
+func TestPortfolio_DecimalTotals(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	// Each of these sums drifts when added as binary floats
+	p.AddHolding("BTC", dec(0.1), dec(0.1), "", "", "")
+	p.AddHolding("BTC", dec(0.2), dec(0.2), "", "", "")
+	p.AddSale("BTC", dec(0.3), dec(1), "", "", "")
+
+	purchases, _ := p.GetHoldingsByCoin()
+	if !purchases["BTC"].Equal(dec(0.3)) {
+		t.Errorf("expected BTC purchases exactly 0.3, got %v", purchases["BTC"])
+	}
+	current, _ := p.GetCurrentHoldingsByCoin()
+	if !current["BTC"].IsZero() {
+		t.Errorf("expected BTC holdings exactly 0, got %v", current["BTC"])
+	}
+	invested, _ := p.GetTotalInvestedUSD()
+	if !invested.Equal(dec(0.05)) {
+		t.Errorf("expected invested exactly 0.05, got %v", invested)
 	}
 }
 
@@ -348,10 +433,10 @@ func TestPortfolio_GetSummary(t *testing.T) {
 	defer cleanup()
 
 	// Add some data
-	p.AddHolding("BTC", 1.0, 50000, "", "", "")
-	p.AddHolding("ETH", 10, 3000, "", "", "")
-	p.AddSale("BTC", 0.3, 55000, "", "", "")
-	p.AddLoan("USDT", 5000, "Nexo", nil, "", "")
+	p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddSale("BTC", dec(0.3), dec(55000), "", "", "")
+	p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "")
 
 	summary, err := p.GetSummary()
 	if err != nil {
@@ -367,28 +452,28 @@ func TestPortfolio_GetSummary(t *testing.T) {
 	if summary.TotalLoansCount != 1 {
 		t.Errorf("expected 1 loan, got %d", summary.TotalLoansCount)
 	}
-	if summary.TotalInvestedUSD != 80000 {
-		t.Errorf("expected invested 80000, got %f", summary.TotalInvestedUSD)
+	if !summary.TotalInvestedUSD.Equal(dec(80000)) {
+		t.Errorf("expected invested 80000, got %v", summary.TotalInvestedUSD)
 	}
-	if summary.TotalSoldUSD != 16500 {
-		t.Errorf("expected sold 16500, got %f", summary.TotalSoldUSD)
+	if !summary.TotalSoldUSD.Equal(dec(16500)) {
+		t.Errorf("expected sold 16500, got %v", summary.TotalSoldUSD)
 	}
 
 	// Check holdings by coin (current holdings = purchases - sales)
 	// BTC: 1.0 purchased - 0.3 sold = 0.7 current holdings
-	if summary.HoldingsByCoin["BTC"] != 0.7 {
-		t.Errorf("expected BTC holdings 0.7, got %f", summary.HoldingsByCoin["BTC"])
+	if !summary.HoldingsByCoin["BTC"].Equal(dec(0.7)) {
+		t.Errorf("expected BTC holdings 0.7, got %v", summary.HoldingsByCoin["BTC"])
 	}
 
 	// Check loans by coin
-	if summary.LoansByCoin["USDT"] != 5000 {
-		t.Errorf("expected USDT loans 5000, got %f", summary.LoansByCoin["USDT"])
+	if !summary.LoansByCoin["USDT"].Equal(dec(5000)) {
+		t.Errorf("expected USDT loans 5000, got %v", summary.LoansByCoin["USDT"])
 	}
 
 	// Check net by coin (holdings - loans)
 	// BTC: 0.7 holdings - 0 loans = 0.7
-	if summary.NetByCoin["BTC"] != 0.7 {
-		t.Errorf("expected BTC net 0.7, got %f", summary.NetByCoin["BTC"])
+	if !summary.NetByCoin["BTC"].Equal(dec(0.7)) {
+		t.Errorf("expected BTC net 0.7, got %v", summary.NetByCoin["BTC"])
 	}
 }
 
@@ -401,17 +486,67 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestPortfolio_RepayLoan(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	l1, _ := p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "2024-01-01")
+	l2, _ := p.AddLoan("USDT", dec(1000), "Aave", nil, "", "2024-01-01")
+
+	if _, err := p.RepayLoan(l1.ID, dec(2000), "", "2024-02-01"); err != nil {
+		t.Fatalf("RepayLoan failed: %v", err)
+	}
+	if _, err := p.RepayLoan(l2.ID, dec(1000), "", "2024-02-01"); err != nil {
+		t.Fatalf("RepayLoan failed: %v", err)
+	}
+
+	outstanding, err := p.GetOutstandingByLoan()
+	if err != nil {
+		t.Fatalf("GetOutstandingByLoan failed: %v", err)
+	}
+	if !outstanding[l1.ID].Equal(dec(3000)) || !outstanding[l2.ID].IsZero() {
+		t.Errorf("expected outstanding 3000 and 0, got %v", outstanding)
+	}
+
+	loansByCoin, _ := p.GetLoansByCoin()
+	if !loansByCoin["USDT"].Equal(dec(3000)) {
+		t.Errorf("expected 3000 USDT outstanding, got %v", loansByCoin["USDT"])
+	}
+
+	// Cannot repay more than is outstanding
+	var validationErr *ValidationError
+	if _, err := p.RepayLoan(l1.ID, dec(3001), "", ""); !errors.As(err, &validationErr) {
+		t.Errorf("expected validation error repaying more than outstanding, got %v", err)
+	}
+	if _, err := p.RepayLoan(l2.ID, dec(1), "", ""); err == nil {
+		t.Error("expected error repaying a fully repaid loan")
+	}
+	if _, err := p.RepayLoan("nonexistent", dec(1), "", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for unknown loan, got %v", err)
+	}
+
+	// Positions only count repayments made by the date
+	pos, _ := p.GetPositionsAt(models.NewDate(2024, 1, 15))
+	if !pos.LoansByCoin["USDT"].Equal(dec(6000)) {
+		t.Errorf("expected 6000 USDT owed before repayments, got %v", pos.LoansByCoin["USDT"])
+	}
+	pos, _ = p.GetPositionsAt(models.Date{})
+	if !pos.LoansByCoin["USDT"].Equal(dec(3000)) {
+		t.Errorf("expected 3000 USDT owed after repayments, got %v", pos.LoansByCoin["USDT"])
+	}
+}
+
 func TestPortfolio_Stakes(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
 	// First, add some holdings
-	p.AddHolding("ETH", 10, 3000, "", "", "")
-	p.AddHolding("SOL", 100, 100, "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddHolding("SOL", dec(100), dec(100), "", "", "")
 
 	// Add stakes
 	apy := 4.5
-	st1, err := p.AddStake("eth", 5, "Lido", &apy, "staking", "2024-03-01")
+	st1, err := p.AddStake("eth", dec(5), "Lido", &apy, "staking", "2024-03-01")
 	if err != nil {
 		t.Fatalf("AddStake failed: %v", err)
 	}
@@ -419,7 +554,7 @@ func TestPortfolio_Stakes(t *testing.T) {
 		t.Errorf("expected coin to be uppercased to ETH, got %s", st1.Coin)
 	}
 
-	st2, err := p.AddStake("SOL", 50, "Coinbase", nil, "", "")
+	st2, err := p.AddStake("SOL", dec(50), "Coinbase", nil, "", "")
 	if err != nil {
 		t.Fatalf("AddStake failed: %v", err)
 	}
@@ -453,45 +588,88 @@ func TestPortfolio_Stakes(t *testing.T) {
 	_ = st2
 }
 
+func TestPortfolio_ReduceStake(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	apy := 4.5
+	st, _ := p.AddStake("ETH", dec(5), "Lido", &apy, "staking", "2024-03-01")
+
+	reduced, err := p.ReduceStake(st.ID, dec(2))
+	if err != nil {
+		t.Fatalf("ReduceStake failed: %v", err)
+	}
+	if !reduced.Amount.Equal(dec(3)) {
+		t.Errorf("expected 3 remaining, got %v", reduced.Amount)
+	}
+
+	stakes, _ := p.ListStakes()
+	if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(3)) || stakes[0].Date.String() != "2024-03-01" || *stakes[0].APY != 4.5 {
+		t.Errorf("expected stake reduced in place with date and APY kept, got %+v", stakes)
+	}
+
+	// Cannot unstake more than is staked
+	var validationErr *ValidationError
+	if _, err := p.ReduceStake(st.ID, dec(4)); !errors.As(err, &validationErr) {
+		t.Errorf("expected validation error reducing by more than the staked amount, got %v", err)
+	}
+	if _, err := p.ReduceStake(st.ID, dec(0)); err == nil {
+		t.Error("expected error for non-positive amount")
+	}
+	if _, err := p.ReduceStake("nonexistent", dec(1)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for unknown stake, got %v", err)
+	}
+
+	// Reducing by the full amount removes the stake
+	if _, err := p.ReduceStake(st.ID, dec(3)); err != nil {
+		t.Fatalf("ReduceStake failed: %v", err)
+	}
+	stakes, _ = p.ListStakes()
+	if len(stakes) != 0 {
+		t.Errorf("expected stake to be removed, got %d stakes", len(stakes))
+	}
+}
+
 func TestPortfolio_StakeValidation(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
 	// Try to stake without any holdings - should fail
-	_, err := p.AddStake("BTC", 1, "Lido", nil, "", "")
+	_, err := p.AddStake("BTC", dec(1), "Lido", nil, "", "")
 	if err == nil {
 		t.Error("expected error when staking coin with no holdings")
 	}
 
 	// Add some holdings
-	p.AddHolding("ETH", 10, 3000, "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
 
 	// Try to stake more than holdings - should fail
-	_, err = p.AddStake("ETH", 15, "Lido", nil, "", "")
+	_, err = p.AddStake("ETH", dec(15), "Lido", nil, "", "")
 	if err == nil {
 		t.Error("expected error when staking more than holdings")
 	}
 
 	// Stake within limit - should succeed
-	_, err = p.AddStake("ETH", 5, "Lido", nil, "", "")
+	_, err = p.AddStake("ETH", dec(5), "Lido", nil, "", "")
 	if err != nil {
 		t.Fatalf("AddStake should succeed: %v", err)
 	}
 
 	// Try to stake more than remaining available - should fail
-	_, err = p.AddStake("ETH", 6, "Coinbase", nil, "", "")
+	_, err = p.AddStake("ETH", dec(6), "Coinbase", nil, "", "")
 	if err == nil {
 		t.Error("expected error when staking more than available (after previous stake)")
 	}
 
 	// Stake remaining - should succeed
-	_, err = p.AddStake("ETH", 5, "Coinbase", nil, "", "")
+	_, err = p.AddStake("ETH", dec(5), "Coinbase", nil, "", "")
 	if err != nil {
 		t.Fatalf("AddStake should succeed for remaining: %v", err)
 	}
 
 	// Now nothing left to stake - should fail
-	_, err = p.AddStake("ETH", 0.1, "Kraken", nil, "", "")
+	_, err = p.AddStake("ETH", dec(0.1), "Kraken", nil, "", "")
 	if err == nil {
 		t.Error("expected error when no available balance left")
 	}
@@ -502,46 +680,117 @@ func TestPortfolio_StakeValidationWithSales(t *testing.T) {
 	defer cleanup()
 
 	// Add holdings and sales
-	p.AddHolding("BTC", 2.0, 50000, "", "", "")
-	p.AddSale("BTC", 0.5, 55000, "", "", "")
+	p.AddHolding("BTC", dec(2.0), dec(50000), "", "", "")
+	p.AddSale("BTC", dec(0.5), dec(55000), "", "", "")
 
 	// Available: 2.0 - 0.5 = 1.5 BTC
 	// Try to stake 2.0 - should fail
-	_, err := p.AddStake("BTC", 2.0, "Kraken", nil, "", "")
+	_, err := p.AddStake("BTC", dec(2.0), "Kraken", nil, "", "")
 	if err == nil {
 		t.Error("expected error when staking more than available after sales")
 	}
 
 	// Stake 1.5 - should succeed
-	_, err = p.AddStake("BTC", 1.5, "Kraken", nil, "", "")
+	_, err = p.AddStake("BTC", dec(1.5), "Kraken", nil, "", "")
 	if err != nil {
 		t.Fatalf("AddStake should succeed: %v", err)
 	}
 }
 
+func TestPortfolio_SaleValidation(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	// Try to sell without any holdings - should fail
+	_, err := p.AddSale("BTC", dec(1), dec(50000), "", "", "")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("expected a validation error when selling coin with no holdings, got %v", err)
+	}
+
+	// Available: 10 - 4 staked = 6 ETH
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddStake("ETH", dec(4), "Lido", nil, "", "")
+
+	_, err = p.AddSale("ETH", dec(7), dec(3500), "", "", "")
+	if err == nil {
+		t.Error("expected error when selling staked coins")
+	}
+
+	// Sell within limit - should succeed
+	_, err = p.AddSale("ETH", dec(5), dec(3500), "", "", "")
+	if err != nil {
+		t.Fatalf("AddSale should succeed: %v", err)
+	}
+
+	// Prior sales count against the balance
+	_, err = p.AddSale("ETH", dec(2), dec(3500), "", "", "")
+	if err == nil {
+		t.Error("expected error when selling more than available after sales")
+	}
+
+	// Unchecked sales skip the balance check
+	sale, err := p.AddSaleUnchecked("btc", dec(1), dec(50000), dec(0), "", "", "", nil)
+	if err != nil {
+		t.Fatalf("AddSaleUnchecked should succeed: %v", err)
+	}
+	if sale.Coin != "BTC" {
+		t.Errorf("expected coin to be uppercased to BTC, got %s", sale.Coin)
+	}
+}
+
+func TestPortfolio_BackdatedSaleValidation(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-06-01")
+
+	// Nothing was held yet on the sale date
+	_, err := p.AddSale("BTC", dec(1), dec(40000), "", "", "2024-01-01")
+	if err == nil || !strings.Contains(err.Error(), "no available BTC to sell on 2024-01-01") {
+		t.Errorf("expected a sale before the purchase refused, got %v", err)
+	}
+	if _, err := p.AddSwap("BTC", dec(1), "ETH", dec(20), dec(40000), "", "", "2024-01-01"); err == nil {
+		t.Error("expected a swap before the purchase refused")
+	}
+
+	// A backdated sale can't take coins a later sale already sold
+	p.AddHolding("BTC", dec(1), dec(60000), "", "", "2024-08-01")
+	if _, err := p.AddSale("BTC", dec(1), dec(70000), "", "", "2024-09-01"); err != nil {
+		t.Fatalf("AddSale failed: %v", err)
+	}
+	if _, err := p.AddSale("BTC", dec(1), dec(65000), "", "", "2024-07-01"); err != nil {
+		t.Fatalf("expected 1 BTC available on 2024-07-01, got %v", err)
+	}
+	_, err = p.AddSale("BTC", dec(0.5), dec(65000), "", "", "2024-07-15")
+	if err == nil || !strings.Contains(err.Error(), "no available BTC") {
+		t.Errorf("expected nothing left to sell after the later sale, got %v", err)
+	}
+}
+
 func TestPortfolio_GetStakesByCoin(t *testing.T) {
 	p, cleanup := setupTestPortfolio(t)
 	defer cleanup()
 
 	// Add holdings first
-	p.AddHolding("ETH", 20, 3000, "", "", "")
-	p.AddHolding("SOL", 200, 100, "", "", "")
+	p.AddHolding("ETH", dec(20), dec(3000), "", "", "")
+	p.AddHolding("SOL", dec(200), dec(100), "", "", "")
 
 	// Add stakes
-	p.AddStake("ETH", 5, "Lido", nil, "", "")
-	p.AddStake("ETH", 3, "Coinbase", nil, "", "")
-	p.AddStake("SOL", 100, "Marinade", nil, "", "")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "")
+	p.AddStake("ETH", dec(3), "Coinbase", nil, "", "")
+	p.AddStake("SOL", dec(100), "Marinade", nil, "", "")
 
 	byCoin, err := p.GetStakesByCoin()
 	if err != nil {
 		t.Fatalf("GetStakesByCoin failed: %v", err)
 	}
 
-	if byCoin["ETH"] != 8 {
-		t.Errorf("expected ETH stakes 8, got %f", byCoin["ETH"])
+	if !byCoin["ETH"].Equal(dec(8)) {
+		t.Errorf("expected ETH stakes 8, got %v", byCoin["ETH"])
 	}
-	if byCoin["SOL"] != 100 {
-		t.Errorf("expected SOL stakes 100, got %f", byCoin["SOL"])
+	if !byCoin["SOL"].Equal(dec(100)) {
+		t.Errorf("expected SOL stakes 100, got %v", byCoin["SOL"])
 	}
 }
 
@@ -550,14 +799,14 @@ func TestPortfolio_GetAvailableByCoin(t *testing.T) {
 	defer cleanup()
 
 	// Add holdings
-	p.AddHolding("ETH", 10, 3000, "", "", "")
-	p.AddHolding("BTC", 2, 50000, "", "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddHolding("BTC", dec(2), dec(50000), "", "", "")
 
 	// Add sales
-	p.AddSale("BTC", 0.5, 55000, "", "", "")
+	p.AddSale("BTC", dec(0.5), dec(55000), "", "", "")
 
 	// Add stakes
-	p.AddStake("ETH", 4, "Lido", nil, "", "")
+	p.AddStake("ETH", dec(4), "Lido", nil, "", "")
 
 	available, err := p.GetAvailableByCoin()
 	if err != nil {
@@ -565,13 +814,13 @@ func TestPortfolio_GetAvailableByCoin(t *testing.T) {
 	}
 
 	// ETH: 10 - 0 - 4 = 6
-	if available["ETH"] != 6 {
-		t.Errorf("expected ETH available 6, got %f", available["ETH"])
+	if !available["ETH"].Equal(dec(6)) {
+		t.Errorf("expected ETH available 6, got %v", available["ETH"])
 	}
 
 	// BTC: 2 - 0.5 - 0 = 1.5
-	if available["BTC"] != 1.5 {
-		t.Errorf("expected BTC available 1.5, got %f", available["BTC"])
+	if !available["BTC"].Equal(dec(1.5)) {
+		t.Errorf("expected BTC available 1.5, got %v", available["BTC"])
 	}
 }
 
@@ -580,10 +829,10 @@ func TestPortfolio_GetSummaryWithStakes(t *testing.T) {
 	defer cleanup()
 
 	// Add some data including stakes
-	p.AddHolding("ETH", 10, 3000, "", "", "")
-	p.AddSale("ETH", 2, 3500, "", "", "")
-	p.AddStake("ETH", 3, "Lido", nil, "", "")
-	p.AddLoan("USDT", 1000, "Nexo", nil, "", "")
+	p.AddHolding("ETH", dec(10), dec(3000), "", "", "")
+	p.AddSale("ETH", dec(2), dec(3500), "", "", "")
+	p.AddStake("ETH", dec(3), "Lido", nil, "", "")
+	p.AddLoan("USDT", dec(1000), "Nexo", nil, "", "")
 
 	summary, err := p.GetSummary()
 	if err != nil {
@@ -594,12 +843,77 @@ func TestPortfolio_GetSummaryWithStakes(t *testing.T) {
 		t.Errorf("expected 1 stake, got %d", summary.TotalStakesCount)
 	}
 
-	if summary.StakesByCoin["ETH"] != 3 {
-		t.Errorf("expected ETH staked 3, got %f", summary.StakesByCoin["ETH"])
+	if !summary.StakesByCoin["ETH"].Equal(dec(3)) {
+		t.Errorf("expected ETH staked 3, got %v", summary.StakesByCoin["ETH"])
 	}
 
 	// Available: 10 - 2 - 3 = 5
-	if summary.AvailableByCoin["ETH"] != 5 {
-		t.Errorf("expected ETH available 5, got %f", summary.AvailableByCoin["ETH"])
+	if !summary.AvailableByCoin["ETH"].Equal(dec(5)) {
+		t.Errorf("expected ETH available 5, got %v", summary.AvailableByCoin["ETH"])
+	}
+}
+
+func TestPortfolio_PurgeTrash(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	h, _ := p.AddHolding("BTC", dec(1.0), dec(50000), "", "", "")
+	p.RemoveHolding(h.ID)
+
+	// Removed just now, so nothing is old enough to purge
+	if purged, _ := p.PurgeTrash(30); purged != 0 {
+		t.Errorf("expected nothing purged, got %d", purged)
+	}
+	if purged, _ := p.PurgeTrash(0); purged != 0 {
+		t.Errorf("expected retention 0 to keep everything, got %d purged", purged)
+	}
+
+	trash, _ := p.ListTrash()
+	if len(trash) != 1 {
+		t.Fatalf("expected 1 trash item, got %d", len(trash))
+	}
+	if _, restored, _ := p.RestoreTrash(h.ID); !restored {
+		t.Error("expected holding restored")
+	}
+}
+
+func TestPortfolio_GetSummaryBetween(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-12-01")
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-02-01")
+	p.AddSale("BTC", dec(1), dec(50000), "", "", "2024-03-01")
+	p.AddSale("BTC", dec(0.5), dec(60000), "", "", "2024-08-01")
+	p.AddLoan("USDT", dec(5000), "Nexo", nil, "", "2024-01-15")
+	p.AddStake("BTC", dec(0.1), "Lido", nil, "", "2023-12-02")
+
+	summary, err := p.GetSummaryBetween(models.NewDate(2024, 1, 1), models.NewDate(2024, 6, 30))
+	if err != nil {
+		t.Fatalf("GetSummaryBetween failed: %v", err)
+	}
+	if summary.TotalHoldingsCount != 1 || summary.TotalSalesCount != 1 || summary.TotalLoansCount != 1 || summary.TotalStakesCount != 0 {
+		t.Errorf("expected 1 holding, sale, and loan and no stakes in the range, got %+v", summary)
+	}
+	if !summary.TotalInvestedUSD.Equal(dec(40000)) || !summary.TotalSoldUSD.Equal(dec(50000)) {
+		t.Errorf("expected invested 40000 and sold 50000, got %v and %v", summary.TotalInvestedUSD, summary.TotalSoldUSD)
+	}
+	if !summary.HoldingsByCoin["BTC"].Equal(dec(0.5)) {
+		t.Errorf("expected current BTC balance 0.5, got %v", summary.HoldingsByCoin["BTC"])
+	}
+
+	// The FIFO sale in the range used the $20000 lot bought before it
+	realized, err := p.GetRealizedBetween(models.NewDate(2024, 1, 1), models.NewDate(2024, 6, 30))
+	if err != nil {
+		t.Fatalf("GetRealizedBetween failed: %v", err)
+	}
+	if !realized.Equal(dec(30000)) {
+		t.Errorf("expected realized 30000, got %v", realized)
+	}
+
+	// An open start includes everything up to until
+	summary, _ = p.GetSummaryBetween(models.Date{}, models.NewDate(2023, 12, 31))
+	if summary.TotalHoldingsCount != 1 || summary.TotalStakesCount != 1 || !summary.TotalSoldUSD.IsZero() {
+		t.Errorf("expected only the 2023 records, got %+v", summary)
 	}
 }
@@ -0,0 +1,121 @@
+package portfolio
+
+import (
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// ProfitLoss separates gains locked in by sales and swaps from gains still
+// riding on the coins held. Loans are not included.
+type ProfitLoss struct {
+	RealizedByCoin  map[string]decimal.Decimal // Proceeds less FIFO cost basis of disposals
+	HeldByCoin      map[string]decimal.Decimal
+	CostBasisByCoin map[string]decimal.Decimal // FIFO cost basis of the coins still held
+}
+
+// RealizedUSD returns the total realized gain (or loss).
+func (pl ProfitLoss) RealizedUSD() decimal.Decimal {
+	total := decimal.Zero
+	for _, gain := range pl.RealizedByCoin {
+		total = total.Add(gain)
+	}
+	return total
+}
+
+// AverageCostUSD returns the average purchase price of a coin's remaining
+// holdings, their FIFO cost basis per coin, or 0 if none are held.
+func (pl ProfitLoss) AverageCostUSD(coin string) decimal.Decimal {
+	held := pl.HeldByCoin[coin]
+	if !held.IsPositive() {
+		return decimal.Zero
+	}
+	return models.Quo(pl.CostBasisByCoin[coin], held)
+}
+
+// UnrealizedUSD returns the gain (or loss) of the coins still held, valued
+// at USD prices keyed by coin. Coins without a price are left out.
+func (pl ProfitLoss) UnrealizedUSD(prices map[string]float64) decimal.Decimal {
+	total := decimal.Zero
+	for coin, amount := range pl.HeldByCoin {
+		price, ok := prices[coin]
+		if !ok || amount.IsZero() {
+			continue
+		}
+		total = total.Add(amount.Mul(decimal.NewFromFloat(price)).Sub(pl.CostBasisByCoin[coin]))
+	}
+	return total
+}
+
+// GetProfitLoss returns realized and unrealized profit/loss from records
+// dated on or before asOf. A zero asOf includes all records.
+func (p *Portfolio) GetProfitLoss(asOf models.Date) (ProfitLoss, error) {
+	included := func(date models.Date) bool {
+		return asOf.IsZero() || !date.After(asOf)
+	}
+
+	pos, err := p.GetPositionsAt(asOf)
+	if err != nil {
+		return ProfitLoss{}, err
+	}
+
+	// Cost basis left after FIFO matching: every acquisition's cost less
+	// the cost basis consumed by disposals
+	basis := make(models.Totals)
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return ProfitLoss{}, err
+	}
+	for _, h := range holdings {
+		if included(h.Date) {
+			basis.Add(h.Coin, h.CostUSD())
+		}
+	}
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return ProfitLoss{}, err
+	}
+	for _, sw := range swaps {
+		if _, h := swapLegs(sw); included(h.Date) {
+			basis.Add(h.Coin, h.CostUSD())
+		}
+	}
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		return ProfitLoss{}, err
+	}
+	realized := make(models.Totals)
+	for _, d := range disposals {
+		if included(d.DisposedDate) {
+			basis.Sub(d.Coin, d.CostBasisUSD)
+			realized.Add(d.Coin, d.GainUSD())
+		}
+	}
+
+	costBasis := make(map[string]decimal.Decimal, len(basis))
+	for coin, cost := range basis {
+		costBasis[coin] = decimal.Max(decimal.Zero, cost)
+	}
+	return ProfitLoss{
+		RealizedByCoin:  realized,
+		HeldByCoin:      pos.HoldingsByCoin,
+		CostBasisByCoin: costBasis,
+	}, nil
+}
+
+// GetRealizedBetween returns the realized gain (or loss) of disposals dated
+// from since through until. A zero date leaves that end of the range open.
+func (p *Portfolio) GetRealizedBetween(since, until models.Date) (decimal.Decimal, error) {
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	inRange := Filter{Since: since, Until: until}.Matches
+	total := decimal.Zero
+	for _, d := range disposals {
+		if inRange(d.Coin, "", d.DisposedDate) {
+			total = total.Add(d.GainUSD())
+		}
+	}
+	return total, nil
+}
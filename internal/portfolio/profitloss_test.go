@@ -0,0 +1,55 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestPortfolio_GetProfitLoss(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "", "", "2024-01-01")
+	p.AddHolding("ETH", dec(2), dec(2000), "", "", "2024-02-01")
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-01-01")
+	p.AddSale("ETH", dec(1), dec(3000), "", "", "2024-03-01")
+	p.AddSale("BTC", dec(1), dec(30000), "", "", "2024-04-01")
+
+	pl, err := p.GetProfitLoss(models.Date{})
+	if err != nil {
+		t.Fatalf("GetProfitLoss failed: %v", err)
+	}
+	// FIFO: ETH sold from the $1000 lot, BTC sold at a loss
+	if r := pl.RealizedUSD(); !r.Equal(dec(-8000)) {
+		t.Errorf("expected realized -8000, got %v", r)
+	}
+	if !pl.CostBasisByCoin["ETH"].Equal(dec(5000)) || !pl.CostBasisByCoin["BTC"].IsZero() {
+		t.Errorf("expected remaining basis ETH 5000 and BTC 0, got %v", pl.CostBasisByCoin)
+	}
+	if u := pl.UnrealizedUSD(map[string]float64{"ETH": 2500}); !u.Equal(dec(2500)) {
+		t.Errorf("expected unrealized 2500, got %v", u)
+	}
+	if u := pl.UnrealizedUSD(nil); !u.IsZero() {
+		t.Errorf("expected unpriced coins to be left out, got %v", u)
+	}
+	if avg := pl.AverageCostUSD("ETH"); !avg.Equal(decimal.RequireFromString("1666.666666666667")) {
+		t.Errorf("expected ETH average cost 1666.67, got %v", avg)
+	}
+	if avg := pl.AverageCostUSD("BTC"); !avg.IsZero() {
+		t.Errorf("expected no average cost for BTC sold out, got %v", avg)
+	}
+
+	// Before the sales, everything is unrealized
+	pl, err = p.GetProfitLoss(models.NewDate(2024, 2, 15))
+	if err != nil {
+		t.Fatalf("GetProfitLoss failed: %v", err)
+	}
+	if !pl.RealizedUSD().IsZero() {
+		t.Errorf("expected nothing realized by 2024-02-15, got %v", pl.RealizedUSD())
+	}
+	if u := pl.UnrealizedUSD(map[string]float64{"ETH": 2000, "BTC": 40000}); !u.Equal(dec(2000)) {
+		t.Errorf("expected unrealized 2000, got %v", u)
+	}
+}
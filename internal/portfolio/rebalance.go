@@ -0,0 +1,86 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// RebalanceEntry is the trade that moves a coin to its target share of the
+// total holdings value.
+type RebalanceEntry struct {
+	Coin           string
+	ValueUSD       float64
+	CurrentPercent float64
+	TargetPercent  float64
+	TradeUSD       float64 // Value to buy, or to sell when negative
+	TradeAmount    float64 // Coins to buy, or to sell when negative
+}
+
+// ValidateTargets checks that target allocation percentages by coin are
+// not negative and add up to 100.
+func ValidateTargets(targets map[string]float64) error {
+	if len(targets) == 0 {
+		return invalidf("no target allocation given")
+	}
+	var total []float64
+	for coin, percent := range targets {
+		if percent < 0 {
+			return invalidf("target for %s cannot be negative", coin)
+		}
+		total = append(total, percent)
+	}
+	if sum := models.Add(total...); math.Abs(sum-100) > 0.01 {
+		return invalidf("target allocation adds up to %g%%, not 100%%", sum)
+	}
+	return nil
+}
+
+// CalculateRebalance returns the trades that bring the given holdings to
+// the target allocation (percent of total value by coin) at the given
+// prices. Held coins without a target are sold. Coins without a price are
+// left out, as their value is unknown. Entries are sorted by target share,
+// largest first.
+func CalculateRebalance(holdingsByCoin map[string]decimal.Decimal, prices, targets map[string]float64) []RebalanceEntry {
+	values := make(map[string]float64)
+	var total float64
+	for coin, amount := range holdingsByCoin {
+		price, ok := prices[coin]
+		if !ok || !amount.IsPositive() {
+			continue
+		}
+		values[coin] = models.Float(models.AtPrice(amount, price))
+		total = models.Add(total, values[coin])
+	}
+	// Targeted coins are listed even when not held
+	for coin := range targets {
+		if _, held := values[coin]; !held {
+			if _, ok := prices[coin]; ok {
+				values[coin] = 0
+			}
+		}
+	}
+
+	var entries []RebalanceEntry
+	for coin, value := range values {
+		entry := RebalanceEntry{Coin: coin, ValueUSD: value, TargetPercent: targets[coin]}
+		if total > 0 {
+			entry.CurrentPercent = value / total * 100
+		}
+		entry.TradeUSD = models.Sub(total*entry.TargetPercent/100, value)
+		if price := prices[coin]; price > 0 {
+			entry.TradeAmount = entry.TradeUSD / price
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].TargetPercent != entries[j].TargetPercent {
+			return entries[i].TargetPercent > entries[j].TargetPercent
+		}
+		return entries[i].Coin < entries[j].Coin
+	})
+	return entries
+}
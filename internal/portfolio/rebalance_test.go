@@ -0,0 +1,81 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets map[string]float64
+		wantErr bool
+	}{
+		{"valid", map[string]float64{"BTC": 60, "ETH": 30, "SOL": 10}, false},
+		{"rounded", map[string]float64{"BTC": 33.33, "ETH": 33.33, "SOL": 33.34}, false},
+		{"empty", map[string]float64{}, true},
+		{"under 100", map[string]float64{"BTC": 50, "ETH": 30}, true},
+		{"negative", map[string]float64{"BTC": 110, "ETH": -10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTargets(tt.targets)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculateRebalance(t *testing.T) {
+	holdings := map[string]decimal.Decimal{"BTC": dec(1), "ETH": dec(10), "DOGE": dec(1000)}
+	prices := map[string]float64{"BTC": 60000, "ETH": 2000, "SOL": 100}
+	targets := map[string]float64{"BTC": 50, "ETH": 30, "SOL": 20}
+
+	// Total priced value is 80,000; DOGE has no price and is left out
+	entries := CalculateRebalance(holdings, prices, targets)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+
+	want := []struct {
+		coin     string
+		current  float64
+		tradeUSD float64
+		amount   float64
+	}{
+		{"BTC", 75, -20000, -20000.0 / 60000},
+		{"ETH", 25, 4000, 2},
+		{"SOL", 0, 16000, 160},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Coin != w.coin {
+			t.Fatalf("entry %d: expected %s, got %s", i, w.coin, e.Coin)
+		}
+		if math.Abs(e.CurrentPercent-w.current) > 0.001 {
+			t.Errorf("%s: expected %.1f%% now, got %.3f%%", w.coin, w.current, e.CurrentPercent)
+		}
+		if math.Abs(e.TradeUSD-w.tradeUSD) > 0.001 {
+			t.Errorf("%s: expected trade of %.2f, got %.2f", w.coin, w.tradeUSD, e.TradeUSD)
+		}
+		if math.Abs(e.TradeAmount-w.amount) > 1e-9 {
+			t.Errorf("%s: expected %.8f coins, got %.8f", w.coin, w.amount, e.TradeAmount)
+		}
+	}
+}
+
+func TestCalculateRebalance_UntargetedCoinIsSold(t *testing.T) {
+	holdings := map[string]decimal.Decimal{"BTC": dec(1), "ETH": dec(10)}
+	prices := map[string]float64{"BTC": 60000, "ETH": 2000}
+
+	entries := CalculateRebalance(holdings, prices, map[string]float64{"BTC": 100})
+	if len(entries) != 2 || entries[1].Coin != "ETH" {
+		t.Fatalf("expected BTC then ETH, got %+v", entries)
+	}
+	if entries[1].TradeUSD != -20000 || entries[1].TradeAmount != -10 {
+		t.Errorf("expected to sell all ETH, got %+v", entries[1])
+	}
+}
@@ -0,0 +1,154 @@
+package portfolio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Report period lengths
+const (
+	PeriodMonthly   = "monthly"
+	PeriodQuarterly = "quarterly"
+)
+
+// PeriodReport is the activity of one calendar month or quarter.
+type PeriodReport struct {
+	Label       string // e.g. "2024-03" or "2024-Q1"
+	Start       models.Date
+	End         models.Date
+	InvestedUSD decimal.Decimal
+	SoldUSD     decimal.Decimal
+	RealizedUSD decimal.Decimal
+	// Net value of the last snapshot in the period less that of the last
+	// snapshot before it, or of the first in it. HasValueChange is false
+	// when there are no two snapshots to compare.
+	ValueChangeUSD float64
+	HasValueChange bool
+}
+
+// GetPeriodReport breaks invested, sold, and realized P/L down by calendar
+// month or quarter, with the change in net value taken from snapshots,
+// oldest period first. With a year, the report covers that year's periods
+// up to today; with year 0, every period from the first record or snapshot.
+func (p *Portfolio) GetPeriodReport(period string, year int, snapshots []models.Snapshot) ([]PeriodReport, error) {
+	var months int
+	switch period {
+	case PeriodMonthly:
+		months = 1
+	case PeriodQuarterly:
+		months = 3
+	default:
+		return nil, invalidf("unknown period %q: use %s or %s", period, PeriodMonthly, PeriodQuarterly)
+	}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := models.NewDate(year, time.January, 1), models.Today()
+	if year == 0 {
+		first = last
+		for _, h := range holdings {
+			first = earliest(first, h.Date)
+		}
+		for _, d := range disposals {
+			first = earliest(first, d.DisposedDate)
+		}
+		for _, s := range snapshots {
+			first = earliest(first, snapshotDay(s))
+		}
+	} else if end := models.NewDate(year, time.December, 31); end.Before(last) {
+		last = end
+	}
+
+	var report []PeriodReport
+	start := models.NewDate(first.Year(), first.Month()-(first.Month()-1)%time.Month(months), 1)
+	for !start.After(last) {
+		next := models.Date{Time: start.AddDate(0, months, 0)}
+		r := PeriodReport{Start: start, End: next.AddDays(-1)}
+		if months == 1 {
+			r.Label = start.Format("2006-01")
+		} else {
+			r.Label = fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())+2)/3)
+		}
+		inPeriod := func(d models.Date) bool { return !d.Before(r.Start) && !d.After(r.End) }
+
+		var invested, sold, realized []decimal.Decimal
+		for _, h := range holdings {
+			if inPeriod(h.Date) {
+				invested = append(invested, h.CostUSD())
+			}
+		}
+		for _, s := range sales {
+			if inPeriod(s.Date) {
+				sold = append(sold, s.ProceedsUSD())
+			}
+		}
+		for _, d := range disposals {
+			if inPeriod(d.DisposedDate) {
+				realized = append(realized, d.GainUSD())
+			}
+		}
+		r.InvestedUSD = decimal.Sum(decimal.Zero, invested...)
+		r.SoldUSD = decimal.Sum(decimal.Zero, sold...)
+		r.RealizedUSD = decimal.Sum(decimal.Zero, realized...)
+		r.ValueChangeUSD, r.HasValueChange = valueChange(snapshots, r.Start, r.End)
+
+		report = append(report, r)
+		start = next
+	}
+	return report, nil
+}
+
+// valueChange returns the change in net value from the last snapshot before
+// start, or the first from start on, to the last snapshot through end.
+// Snapshots must be sorted oldest first.
+func valueChange(snapshots []models.Snapshot, start, end models.Date) (float64, bool) {
+	base, last := -1, -1
+	for i, s := range snapshots {
+		day := snapshotDay(s)
+		switch {
+		case day.Before(start):
+			base = i
+		case !day.After(end):
+			if base == -1 {
+				base = i
+			}
+			last = i
+		}
+	}
+	if last == -1 || base == last {
+		return 0, false
+	}
+	return models.Sub(snapshots[last].NetValue, snapshots[base].NetValue), true
+}
+
+// snapshotDay returns the day a snapshot was taken on. Backfilled snapshots
+// are at midnight UTC on their day; others are in local time.
+func snapshotDay(s models.Snapshot) models.Date {
+	t := s.Timestamp
+	if t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return models.DateOf(t)
+	}
+	return models.DateOf(t.Local())
+}
+
+// earliest returns the earlier of two dates, ignoring a zero date
+func earliest(a, b models.Date) models.Date {
+	if !b.IsZero() && b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_GetPeriodReport(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(20000), "", "", "2023-01-10")
+	p.AddHolding("BTC", dec(1), dec(30000), "", "", "2023-02-10")
+	p.AddSale("BTC", dec(1), dec(40000), "", "", "2023-05-20")
+
+	snapshot := func(day string, net float64) models.Snapshot {
+		ts, _ := time.Parse("2006-01-02", day)
+		return models.Snapshot{Timestamp: ts, NetValue: net}
+	}
+	snapshots := []models.Snapshot{
+		snapshot("2023-01-31", 21000),
+		snapshot("2023-03-31", 60000),
+		snapshot("2023-06-30", 45000),
+	}
+
+	report, err := p.GetPeriodReport(PeriodQuarterly, 2023, snapshots)
+	if err != nil {
+		t.Fatalf("GetPeriodReport failed: %v", err)
+	}
+	if len(report) != 4 {
+		t.Fatalf("expected 4 quarters, got %d", len(report))
+	}
+	q1, q2, q3 := report[0], report[1], report[2]
+	if q1.Label != "2023-Q1" || q1.End != models.NewDate(2023, 3, 31) {
+		t.Errorf("expected 2023-Q1 ending 2023-03-31, got %s ending %s", q1.Label, q1.End)
+	}
+	if !q1.InvestedUSD.Equal(dec(50000)) || !q1.SoldUSD.IsZero() || !q1.HasValueChange || q1.ValueChangeUSD != 39000 {
+		t.Errorf("unexpected Q1: %+v", q1)
+	}
+	// FIFO sells the $20000 lot
+	if !q2.SoldUSD.Equal(dec(40000)) || !q2.RealizedUSD.Equal(dec(20000)) || q2.ValueChangeUSD != -15000 {
+		t.Errorf("unexpected Q2: %+v", q2)
+	}
+	if q3.HasValueChange {
+		t.Errorf("expected no value change without snapshots in Q3, got %+v", q3)
+	}
+
+	// Without a year, months run from the first record to today
+	report, err = p.GetPeriodReport(PeriodMonthly, 0, nil)
+	if err != nil {
+		t.Fatalf("GetPeriodReport failed: %v", err)
+	}
+	if report[0].Label != "2023-01" || report[len(report)-1].Label != models.Today().Format("2006-01") {
+		t.Errorf("expected months 2023-01 through this month, got %s to %s", report[0].Label, report[len(report)-1].Label)
+	}
+
+	if _, err := p.GetPeriodReport("weekly", 0, nil); err == nil {
+		t.Error("expected an error for an unknown period")
+	}
+}
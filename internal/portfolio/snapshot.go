@@ -0,0 +1,396 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Positions holds per-coin balances and cash flows as of a date.
+type Positions struct {
+	HoldingsByCoin map[string]decimal.Decimal // Purchases - sales - transfer fees, adjusted for swaps
+	LoansByCoin    map[string]decimal.Decimal // Outstanding principal
+	InterestByCoin map[string]float64         // Accrued loan interest, an estimate
+	InvestedUSD    decimal.Decimal
+	SoldUSD        decimal.Decimal
+}
+
+// Coins returns the sorted coins with a non-zero holding or loan.
+func (pos Positions) Coins() []string {
+	seen := make(map[string]bool)
+	for coin, amount := range pos.HoldingsByCoin {
+		if !amount.IsZero() {
+			seen[coin] = true
+		}
+	}
+	for coin, amount := range pos.LoansByCoin {
+		if !amount.IsZero() {
+			seen[coin] = true
+		}
+	}
+	coins := make([]string, 0, len(seen))
+	for coin := range seen {
+		coins = append(coins, coin)
+	}
+	sort.Strings(coins)
+	return coins
+}
+
+// GetPositionsAt returns positions built from records dated on or before
+// asOf. A zero asOf includes all records.
+func (p *Portfolio) GetPositionsAt(asOf models.Date) (Positions, error) {
+	holdingsByCoin := make(models.Totals)
+	loansByCoin := make(models.Totals)
+	interestByCoin := make(models.Totals)
+	invested, sold := decimal.Zero, decimal.Zero
+	included := func(date models.Date) bool {
+		return asOf.IsZero() || !date.After(asOf)
+	}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return Positions{}, err
+	}
+	for _, h := range holdings {
+		if included(h.Date) {
+			holdingsByCoin.Add(h.Coin, h.Amount)
+			invested = invested.Add(h.CostUSD())
+		}
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return Positions{}, err
+	}
+	for _, s := range sales {
+		if included(s.Date) {
+			holdingsByCoin.Sub(s.Coin, s.Amount)
+			sold = sold.Add(s.ProceedsUSD())
+		}
+	}
+
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return Positions{}, err
+	}
+	for _, t := range transfers {
+		if included(t.Date) {
+			holdingsByCoin.Sub(t.Coin, t.Fee)
+		}
+	}
+
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return Positions{}, err
+	}
+	for _, sw := range swaps {
+		if included(sw.Date) {
+			holdingsByCoin.Sub(sw.FromCoin, sw.FromAmount)
+			holdingsByCoin.Add(sw.ToCoin, sw.ToAmount)
+		}
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return Positions{}, err
+	}
+	loanCoins := make(map[string]string)
+	for _, l := range loans {
+		if included(l.Date) {
+			loansByCoin.Add(l.Coin, l.Amount)
+			loanCoins[l.ID] = l.Coin
+		}
+	}
+
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return Positions{}, err
+	}
+	for _, r := range repayments {
+		if coin, ok := loanCoins[r.LoanID]; ok && included(r.Date) {
+			loansByCoin.Sub(coin, r.Amount)
+		}
+	}
+
+	interestDate := asOf
+	if interestDate.IsZero() {
+		interestDate = models.Today()
+	}
+	for _, l := range loans {
+		if included(l.Date) {
+			interestByCoin.Add(l.Coin, decimal.NewFromFloat(AccruedInterest(l, repayments, interestDate, p.interestMethod)))
+		}
+	}
+
+	return Positions{
+		HoldingsByCoin: holdingsByCoin,
+		LoansByCoin:    loansByCoin,
+		InterestByCoin: interestByCoin.Floats(),
+		InvestedUSD:    invested,
+		SoldUSD:        sold,
+	}, nil
+}
+
+// CaptureSnapshot values the portfolio as of asOf (see GetPositionsAt) using
+// USD prices keyed by coin. geckoIDs records the price mapping used for each
+// coin so later mapping changes can be detected. Coins without a price are
+// recorded with a zero value.
+func (p *Portfolio) CaptureSnapshot(asOf models.Date, prices map[string]float64, geckoIDs map[string]string) (models.Snapshot, error) {
+	pos, err := p.GetPositionsAt(asOf)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	snap := models.NewSnapshot(time.Time{}, "")
+	// Values are summed in decimal and only rounded to float64, the
+	// precision of market prices, when stored in the snapshot
+	holdingsValue, loansValue, interestValue := decimal.Zero, decimal.Zero, decimal.Zero
+	for coin, amount := range pos.HoldingsByCoin {
+		if amount.IsZero() {
+			continue
+		}
+		price := prices[coin]
+		value := amount.Mul(decimal.NewFromFloat(price))
+		snap.CoinValues[coin] = models.CoinSnapshot{
+			Amount:   models.Float(amount),
+			PriceUSD: price,
+			ValueUSD: models.Float(value),
+			GeckoID:  geckoIDs[coin],
+		}
+		holdingsValue = holdingsValue.Add(value)
+	}
+	for coin, amount := range pos.LoansByCoin {
+		loansValue = loansValue.Add(amount.Mul(decimal.NewFromFloat(prices[coin])))
+	}
+	for coin, amount := range pos.InterestByCoin {
+		interestValue = interestValue.Add(decimal.NewFromFloat(amount).Mul(decimal.NewFromFloat(prices[coin])))
+	}
+	loansValue = loansValue.Add(interestValue)
+	netValue := holdingsValue.Sub(loansValue)
+	profitLoss := netValue.Sub(pos.InvestedUSD).Add(pos.SoldUSD)
+
+	snap.HoldingsValue = models.Float(holdingsValue)
+	snap.InterestValue = models.Float(interestValue)
+	snap.LoansValue = models.Float(loansValue)
+	snap.NetValue = models.Float(netValue)
+	snap.TotalInvested = models.Float(pos.InvestedUSD)
+	snap.TotalSold = models.Float(pos.SoldUSD)
+	snap.ProfitLoss = models.Float(profitLoss)
+	if !pos.InvestedUSD.IsZero() {
+		snap.ProfitLossPercent = models.Float(models.Quo(profitLoss, pos.InvestedUSD)) * 100
+	}
+
+	pl, err := p.GetProfitLoss(asOf)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	snap.RealizedPL = models.Float(pl.RealizedUSD())
+	snap.UnrealizedPL = models.Float(pl.UnrealizedUSD(prices))
+	return snap, nil
+}
+
+// CoinDiff is the change in a single coin between two snapshots.
+type CoinDiff struct {
+	Coin         string
+	From, To     models.CoinSnapshot // Zero when the coin is absent from that snapshot
+	AmountChange float64
+	PriceChange  float64
+	ValueChange  float64
+}
+
+// PriceChangePercent returns the price change relative to the earlier price.
+func (d CoinDiff) PriceChangePercent() float64 {
+	if d.From.PriceUSD == 0 {
+		return 0
+	}
+	return d.PriceChange / d.From.PriceUSD * 100
+}
+
+// SnapshotDiff is the change in portfolio value between two snapshots.
+type SnapshotDiff struct {
+	From, To            models.Snapshot // From is the earlier snapshot
+	HoldingsValueChange float64
+	LoansValueChange    float64
+	NetValueChange      float64
+	ProfitLossChange    float64
+	Coins               []CoinDiff // Sorted by coin
+}
+
+// NetValueChangePercent returns the net value change relative to the
+// earlier snapshot's net value.
+func (d SnapshotDiff) NetValueChangePercent() float64 {
+	if d.From.NetValue == 0 {
+		return 0
+	}
+	return d.NetValueChange / d.From.NetValue * 100
+}
+
+// CompareSnapshots returns the changes between two snapshots, ordered so the
+// earlier snapshot is the baseline.
+func CompareSnapshots(a, b models.Snapshot) SnapshotDiff {
+	if b.Timestamp.Before(a.Timestamp) {
+		a, b = b, a
+	}
+
+	diff := SnapshotDiff{
+		From:                a,
+		To:                  b,
+		HoldingsValueChange: models.Sub(b.HoldingsValue, a.HoldingsValue),
+		LoansValueChange:    models.Sub(b.LoansValue, a.LoansValue),
+		NetValueChange:      models.Sub(b.NetValue, a.NetValue),
+		ProfitLossChange:    models.Sub(b.ProfitLoss, a.ProfitLoss),
+	}
+
+	seen := make(map[string]bool)
+	for coin := range a.CoinValues {
+		seen[coin] = true
+	}
+	for coin := range b.CoinValues {
+		seen[coin] = true
+	}
+	for coin := range seen {
+		from, to := a.CoinValues[coin], b.CoinValues[coin]
+		cd := CoinDiff{
+			Coin:         coin,
+			From:         from,
+			To:           to,
+			AmountChange: models.Sub(to.Amount, from.Amount),
+			ValueChange:  models.Sub(to.ValueUSD, from.ValueUSD),
+		}
+		// A price change is only meaningful when both snapshots priced the coin
+		if from.PriceUSD != 0 && to.PriceUSD != 0 {
+			cd.PriceChange = models.Sub(to.PriceUSD, from.PriceUSD)
+		}
+		diff.Coins = append(diff.Coins, cd)
+	}
+	sort.Slice(diff.Coins, func(i, j int) bool { return diff.Coins[i].Coin < diff.Coins[j].Coin })
+	return diff
+}
+
+// ProfitLossSince is the profit or loss made after a baseline snapshot,
+// e.g. since January 1st rather than since the first purchase.
+type ProfitLossSince struct {
+	Baseline   models.Snapshot
+	ProfitLoss float64
+	Realized   float64
+	Unrealized float64
+	// ProfitLoss relative to the baseline's net value plus the amount
+	// invested since, in percent
+	Percent float64
+}
+
+// GetProfitLossSince returns the profit or loss of now, a valuation such as
+// one from CaptureSnapshot, made since base. Money invested or withdrawn in
+// between is not counted as profit or loss.
+func GetProfitLossSince(base, now models.Snapshot) ProfitLossSince {
+	since := ProfitLossSince{
+		Baseline:   base,
+		ProfitLoss: models.Sub(now.ProfitLoss, base.ProfitLoss),
+		Realized:   models.Sub(now.RealizedPL, base.RealizedPL),
+		Unrealized: models.Sub(now.UnrealizedPL, base.UnrealizedPL),
+	}
+	if capital := models.Add(base.NetValue, now.TotalInvested, -base.TotalInvested); capital > 0 {
+		since.Percent = since.ProfitLoss / capital * 100
+	}
+	return since
+}
+
+// DayChange is the change in net value over a day: from the last snapshot
+// on an earlier day to the last snapshot on Date.
+type DayChange struct {
+	Date   models.Date
+	Change float64
+}
+
+// SnapshotStats summarizes how net value moved across snapshots.
+type SnapshotStats struct {
+	BestDay, WorstDay DayChange // Zero without snapshots on two days
+	// Standard deviation of the daily returns, in percent, over ReturnDays
+	// returns. It needs at least two returns.
+	Volatility float64
+	ReturnDays int
+	// Largest fall in net value from a high to a later low
+	MaxDrawdown        float64
+	MaxDrawdownPercent float64 // Relative to the high
+	DrawdownPeak       models.Snapshot
+	DrawdownTrough     models.Snapshot
+}
+
+// GetSnapshotStats returns the best and worst day, the volatility of daily
+// returns, and the maximum drawdown of the net value in snapshots, which
+// must be sorted oldest first. A day's change is measured from the last
+// snapshot of the previous day with one; days starting from a net value of
+// zero or less have no return.
+func GetSnapshotStats(snapshots []models.Snapshot) SnapshotStats {
+	var stats SnapshotStats
+	if len(snapshots) == 0 {
+		return stats
+	}
+
+	// Last snapshot of each day
+	var daily []models.Snapshot
+	var days []models.Date
+	for _, s := range snapshots {
+		day := snapshotDay(s)
+		if n := len(days); n > 0 && days[n-1].Equal(day.Time) {
+			daily[n-1] = s
+			continue
+		}
+		daily = append(daily, s)
+		days = append(days, day)
+	}
+	var returns []float64
+	for i := 1; i < len(daily); i++ {
+		change := DayChange{Date: days[i], Change: models.Sub(daily[i].NetValue, daily[i-1].NetValue)}
+		if daily[i-1].NetValue > 0 {
+			returns = append(returns, change.Change/daily[i-1].NetValue*100)
+		}
+		if i == 1 || change.Change > stats.BestDay.Change {
+			stats.BestDay = change
+		}
+		if i == 1 || change.Change < stats.WorstDay.Change {
+			stats.WorstDay = change
+		}
+	}
+
+	stats.ReturnDays = len(returns)
+	stats.Volatility = stdDev(returns)
+
+	peak := snapshots[0]
+	for _, s := range snapshots {
+		if s.NetValue > peak.NetValue {
+			peak = s
+			continue
+		}
+		if drawdown := models.Sub(peak.NetValue, s.NetValue); drawdown > stats.MaxDrawdown {
+			stats.MaxDrawdown = drawdown
+			stats.DrawdownPeak = peak
+			stats.DrawdownTrough = s
+			if peak.NetValue > 0 {
+				stats.MaxDrawdownPercent = drawdown / peak.NetValue * 100
+			}
+		}
+	}
+	return stats
+}
+
+// stdDev returns the sample standard deviation of values, or 0 for fewer
+// than two
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
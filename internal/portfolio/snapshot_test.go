@@ -0,0 +1,175 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_GetPositionsAt(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "", "", "2024-01-01")
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-03-01")
+	p.AddSale("BTC", dec(0.5), dec(50000), "", "", "2024-02-01")
+	p.AddLoan("USDC", dec(1000), "Nexo", nil, "", "2024-02-15")
+
+	pos, err := p.GetPositionsAt(models.NewDate(2024, 2, 1))
+	if err != nil {
+		t.Fatalf("GetPositionsAt failed: %v", err)
+	}
+	if !pos.HoldingsByCoin["BTC"].Equal(dec(0.5)) {
+		t.Errorf("expected 0.5 BTC as of 2024-02-01, got %v", pos.HoldingsByCoin["BTC"])
+	}
+	if !pos.InvestedUSD.Equal(dec(30000)) || !pos.SoldUSD.Equal(dec(25000)) {
+		t.Errorf("expected invested 30000 and sold 25000, got %v and %v", pos.InvestedUSD, pos.SoldUSD)
+	}
+	if len(pos.LoansByCoin) != 0 {
+		t.Errorf("expected no loans yet, got %v", pos.LoansByCoin)
+	}
+
+	all, _ := p.GetPositionsAt(models.Date{})
+	if !all.HoldingsByCoin["BTC"].Equal(dec(1.5)) || !all.LoansByCoin["USDC"].Equal(dec(1000)) {
+		t.Errorf("unexpected positions with all records: %+v", all)
+	}
+	coins := all.Coins()
+	if len(coins) != 2 || coins[0] != "BTC" || coins[1] != "USDC" {
+		t.Errorf("expected [BTC USDC], got %v", coins)
+	}
+}
+
+func TestPortfolio_CaptureSnapshot(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "", "", "2024-01-01")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-01-01")
+	p.AddLoan("USDC", dec(5000), "Nexo", nil, "", "2024-01-01")
+
+	prices := map[string]float64{"BTC": 50000, "USDC": 1}
+	snap, err := p.CaptureSnapshot(models.Date{}, prices, map[string]string{"BTC": "bitcoin"})
+	if err != nil {
+		t.Fatalf("CaptureSnapshot failed: %v", err)
+	}
+
+	if snap.HoldingsValue != 50000 {
+		t.Errorf("expected holdings value 50000 (ETH unpriced), got %f", snap.HoldingsValue)
+	}
+	if snap.LoansValue != 5000 || snap.NetValue != 45000 {
+		t.Errorf("expected loans 5000 and net 45000, got %f and %f", snap.LoansValue, snap.NetValue)
+	}
+	if snap.ProfitLoss != -5000 {
+		t.Errorf("expected P/L -5000, got %f", snap.ProfitLoss)
+	}
+	// Unpriced ETH is left out of unrealized P/L rather than valued at zero
+	if snap.RealizedPL != 0 || snap.UnrealizedPL != 20000 {
+		t.Errorf("expected realized 0 and unrealized 20000, got %f and %f", snap.RealizedPL, snap.UnrealizedPL)
+	}
+	if snap.CoinValues["BTC"].GeckoID != "bitcoin" {
+		t.Errorf("expected BTC mapping to be recorded, got %q", snap.CoinValues["BTC"].GeckoID)
+	}
+	if cv := snap.CoinValues["ETH"]; cv.Amount != 10 || cv.ValueUSD != 0 {
+		t.Errorf("expected unpriced ETH recorded at zero value, got %+v", cv)
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	older := models.NewSnapshot(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "")
+	older.HoldingsValue, older.NetValue = 50000, 50000
+	older.CoinValues["BTC"] = models.CoinSnapshot{Amount: 1, PriceUSD: 40000, ValueUSD: 40000}
+	older.CoinValues["ETH"] = models.CoinSnapshot{Amount: 5, PriceUSD: 2000, ValueUSD: 10000}
+
+	newer := models.NewSnapshot(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "")
+	newer.HoldingsValue, newer.LoansValue, newer.NetValue = 70000, 5000, 65000
+	newer.CoinValues["BTC"] = models.CoinSnapshot{Amount: 1.5, PriceUSD: 44000, ValueUSD: 66000}
+	newer.CoinValues["SOL"] = models.CoinSnapshot{Amount: 40, PriceUSD: 100, ValueUSD: 4000}
+
+	// Argument order does not matter; the earlier snapshot is the baseline
+	diff := CompareSnapshots(newer, older)
+	if diff.From.ID != older.ID || diff.To.ID != newer.ID {
+		t.Fatalf("expected older snapshot as baseline")
+	}
+	if diff.NetValueChange != 15000 || diff.LoansValueChange != 5000 || diff.NetValueChangePercent() != 30 {
+		t.Errorf("unexpected totals: %+v", diff)
+	}
+	if len(diff.Coins) != 3 {
+		t.Fatalf("expected 3 coins, got %+v", diff.Coins)
+	}
+
+	btc, eth, sol := diff.Coins[0], diff.Coins[1], diff.Coins[2]
+	if btc.Coin != "BTC" || btc.AmountChange != 0.5 || btc.PriceChange != 4000 || btc.ValueChange != 26000 || btc.PriceChangePercent() != 10 {
+		t.Errorf("unexpected BTC diff: %+v", btc)
+	}
+	if eth.Coin != "ETH" || eth.AmountChange != -5 || eth.ValueChange != -10000 || eth.PriceChange != 0 {
+		t.Errorf("expected ETH fully removed, got %+v", eth)
+	}
+	if sol.Coin != "SOL" || sol.AmountChange != 40 || sol.PriceChangePercent() != 0 {
+		t.Errorf("expected SOL newly added, got %+v", sol)
+	}
+}
+
+func TestGetProfitLossSince(t *testing.T) {
+	base := models.NewSnapshot(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "")
+	base.NetValue, base.TotalInvested, base.ProfitLoss = 12000, 10000, 2000
+	base.RealizedPL, base.UnrealizedPL = 500, 1500
+
+	// $8,000 more invested since, and the portfolio is now worth $23,000
+	now := models.NewSnapshot(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "")
+	now.NetValue, now.TotalInvested, now.ProfitLoss = 23000, 18000, 5000
+	now.RealizedPL, now.UnrealizedPL = 500, 4500
+
+	since := GetProfitLossSince(base, now)
+	if since.ProfitLoss != 3000 || since.Realized != 0 || since.Unrealized != 3000 {
+		t.Errorf("unexpected P/L since the baseline: %+v", since)
+	}
+	if since.Percent != 15 {
+		t.Errorf("expected 15%% of $20,000, got %v", since.Percent)
+	}
+	if since.Baseline.ID != base.ID {
+		t.Errorf("expected the baseline kept")
+	}
+
+	// No capital at the baseline has no percentage
+	if since := GetProfitLossSince(models.Snapshot{}, models.Snapshot{ProfitLoss: 10}); since.Percent != 0 {
+		t.Errorf("expected no percentage without capital, got %v", since.Percent)
+	}
+}
+
+func TestGetSnapshotStats(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.Local) }
+	snapshots := []models.Snapshot{
+		{Timestamp: day(1, 9), NetValue: 1000},
+		{Timestamp: day(1, 18), NetValue: 1100},
+		{Timestamp: day(2, 12), NetValue: 1500}, // +400
+		{Timestamp: day(3, 12), NetValue: 900},  // -600
+		{Timestamp: day(5, 12), NetValue: 1200}, // +300
+		{Timestamp: day(6, 12), NetValue: 750},  // -450
+	}
+
+	stats := GetSnapshotStats(snapshots)
+	if stats.BestDay.Date.String() != "2024-03-02" || stats.BestDay.Change != 400 {
+		t.Errorf("BestDay = %v %v, want 2024-03-02 400", stats.BestDay.Date, stats.BestDay.Change)
+	}
+	if stats.WorstDay.Date.String() != "2024-03-03" || stats.WorstDay.Change != -600 {
+		t.Errorf("WorstDay = %v %v, want 2024-03-03 -600", stats.WorstDay.Date, stats.WorstDay.Change)
+	}
+	// Daily returns of 36.4%, -40%, 33.3%, and -37.5%
+	if stats.ReturnDays != 4 || math.Abs(stats.Volatility-42.52) > 0.01 {
+		t.Errorf("Volatility = %.2f over %d days, want 42.52 over 4", stats.Volatility, stats.ReturnDays)
+	}
+	if stats.MaxDrawdown != 750 || stats.MaxDrawdownPercent != 50 {
+		t.Errorf("MaxDrawdown = %v (%v%%), want 750 (50%%)", stats.MaxDrawdown, stats.MaxDrawdownPercent)
+	}
+	if !stats.DrawdownPeak.Timestamp.Equal(day(2, 12)) || !stats.DrawdownTrough.Timestamp.Equal(day(6, 12)) {
+		t.Errorf("Drawdown from %v to %v, want day 2 to day 6", stats.DrawdownPeak.Timestamp, stats.DrawdownTrough.Timestamp)
+	}
+
+	// Snapshots on one day have no daily changes
+	stats = GetSnapshotStats(snapshots[:2])
+	if !stats.BestDay.Date.IsZero() || stats.MaxDrawdown != 0 {
+		t.Errorf("Expected no day changes or drawdown, got %+v", stats)
+	}
+}
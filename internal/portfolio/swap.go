@@ -0,0 +1,100 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// AddSwap records exchanging fromAmount of fromCoin for toAmount of toCoin.
+// valueUSD is the implied USD value of the exchange. You can only swap coins
+// that are available (holdings - sales - staked).
+func (p *Portfolio) AddSwap(fromCoin string, fromAmount decimal.Decimal, toCoin string, toAmount, valueUSD decimal.Decimal, platform, notes, date string, tags ...string) (models.Swap, error) {
+	fromCoin = strings.ToUpper(fromCoin)
+	toCoin = strings.ToUpper(toCoin)
+
+	if !fromAmount.IsPositive() || !toAmount.IsPositive() {
+		return models.Swap{}, invalidf("swap amounts must be positive")
+	}
+	if valueUSD.IsNegative() {
+		return models.Swap{}, invalidf("swap value cannot be negative")
+	}
+	if fromCoin == toCoin {
+		return models.Swap{}, invalidf("cannot swap %s for itself", fromCoin)
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Swap{}, err
+	}
+
+	available, err := p.GetAvailableOn(fromCoin, parsed)
+	if err != nil {
+		return models.Swap{}, err
+	}
+	if fromAmount.GreaterThan(available) {
+		return models.Swap{}, invalidf("cannot swap %s %s: only %s %s available%s (holdings - sales - staked)", fromAmount, fromCoin, available, fromCoin, onDate(parsed))
+	}
+
+	swap := models.NewSwap(fromCoin, fromAmount, toCoin, toAmount, valueUSD, platform, notes, parsed)
+	swap.Tags = models.ParseTags(tags...)
+	err = p.storage.AddSwap(swap)
+	return swap, err
+}
+
+// RemoveSwap removes a swap by ID.
+func (p *Portfolio) RemoveSwap(id string) (bool, error) {
+	return p.storage.RemoveSwap(id)
+}
+
+// ListSwaps lists all swaps.
+func (p *Portfolio) ListSwaps() ([]models.Swap, error) {
+	return p.storage.GetSwaps()
+}
+
+// GetSwapsByCoin returns the net change in holdings from swaps by coin
+// (received - given up).
+func (p *Portfolio) GetSwapsByCoin() (map[string]decimal.Decimal, error) {
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+
+	byCoin := make(models.Totals)
+	for _, sw := range swaps {
+		byCoin.Sub(sw.FromCoin, sw.FromAmount)
+		byCoin.Add(sw.ToCoin, sw.ToAmount)
+	}
+	return byCoin, nil
+}
+
+// swapLegs returns a swap as a sale of the coin given up and a purchase of
+// the coin received, both at the swap's implied USD value, so swaps carry
+// cost basis through FIFO matching.
+func swapLegs(sw models.Swap) (models.Sale, models.Holding) {
+	sale := models.Sale{
+		ID:           sw.ID,
+		Coin:         sw.FromCoin,
+		Amount:       sw.FromAmount,
+		SellPriceUSD: perCoin(sw.ValueUSD, sw.FromAmount),
+		Date:         sw.Date,
+		Platform:     sw.Platform,
+	}
+	holding := models.Holding{
+		ID:               sw.ID,
+		Coin:             sw.ToCoin,
+		Amount:           sw.ToAmount,
+		PurchasePriceUSD: perCoin(sw.ValueUSD, sw.ToAmount),
+		Date:             sw.Date,
+		Platform:         sw.Platform,
+	}
+	return sale, holding
+}
+
+// perCoin returns value divided by amount, or zero for a zero amount
+func perCoin(value, amount decimal.Decimal) decimal.Decimal {
+	if amount.IsZero() {
+		return decimal.Zero
+	}
+	return models.Quo(value, amount)
+}
@@ -0,0 +1,103 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_Swaps(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(10), dec(2000), "Binance", "", "2024-01-01")
+
+	sw, err := p.AddSwap("eth", dec(4), "sol", dec(100), dec(12000), "Binance", "", "2024-03-01")
+	if err != nil {
+		t.Fatalf("AddSwap failed: %v", err)
+	}
+	if sw.FromCoin != "ETH" || sw.ToCoin != "SOL" {
+		t.Errorf("expected coins to be uppercased, got %s -> %s", sw.FromCoin, sw.ToCoin)
+	}
+
+	current, _ := p.GetCurrentHoldingsByCoin()
+	if !current["ETH"].Equal(dec(6)) || !current["SOL"].Equal(dec(100)) {
+		t.Errorf("expected 6 ETH and 100 SOL, got %v", current)
+	}
+
+	pos, _ := p.GetPositionsAt(models.NewDate(2024, 2, 1))
+	if !pos.HoldingsByCoin["ETH"].Equal(dec(10)) || !pos.HoldingsByCoin["SOL"].IsZero() {
+		t.Errorf("expected swap not applied before its date, got %v", pos.HoldingsByCoin)
+	}
+
+	byPlatform, _ := p.GetHoldingsByPlatform()
+	if !byPlatform["Binance"]["SOL"].Equal(dec(100)) {
+		t.Errorf("expected 100 SOL on Binance, got %v", byPlatform["Binance"])
+	}
+
+	// Invested is unchanged by swaps
+	invested, _ := p.GetTotalInvestedUSD()
+	if !invested.Equal(dec(20000)) {
+		t.Errorf("expected invested 20000, got %v", invested)
+	}
+
+	history, _ := p.GetHistory(Filter{})
+	if len(history) != 3 || history[1].Type != TypeSwapOut || history[2].Type != TypeSwapIn {
+		t.Fatalf("expected buy, swap out, swap in, got %+v", history)
+	}
+	if !history[1].Balance.Equal(dec(6)) || !history[2].Balance.Equal(dec(100)) {
+		t.Errorf("expected balances 6 ETH and 100 SOL, got %v and %v", history[1].Balance, history[2].Balance)
+	}
+
+	removed, err := p.RemoveSwap(sw.ID)
+	if err != nil || !removed {
+		t.Errorf("expected swap to be removed, err: %v", err)
+	}
+}
+
+func TestPortfolio_SwapCostBasis(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(2), dec(1000), "", "", "2024-01-01")
+	p.AddSwap("ETH", dec(2), "SOL", dec(50), dec(5000), "", "", "2024-02-01")
+	p.AddSale("SOL", dec(50), dec(150), "", "", "2024-03-01")
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 2 {
+		t.Fatalf("expected 2 disposals, got %d", len(disposals))
+	}
+
+	// The swap disposes of ETH at the swap value
+	if d := disposals[0]; d.Coin != "ETH" || !d.ProceedsUSD.Equal(dec(5000)) || !d.CostBasisUSD.Equal(dec(2000)) {
+		t.Errorf("expected ETH disposal with proceeds 5000 and cost 2000, got %+v", d)
+	}
+	// The SOL sale uses the swap value as cost basis
+	if d := disposals[1]; d.Coin != "SOL" || !d.CostBasisUSD.Equal(dec(5000)) || !d.ProceedsUSD.Equal(dec(7500)) {
+		t.Errorf("expected SOL disposal with cost 5000 and proceeds 7500, got %+v", d)
+	}
+}
+
+func TestPortfolio_SwapValidation(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(5), dec(2000), "", "", "")
+	p.AddStake("ETH", dec(3), "Lido", nil, "", "")
+
+	if _, err := p.AddSwap("ETH", dec(3), "SOL", dec(10), dec(1000), "", "", ""); err == nil {
+		t.Error("expected error swapping more than available (staked coins excluded)")
+	}
+	if _, err := p.AddSwap("ETH", dec(1), "eth", dec(1), dec(1000), "", "", ""); err == nil {
+		t.Error("expected error swapping a coin for itself")
+	}
+	if _, err := p.AddSwap("ETH", dec(1), "SOL", dec(0), dec(1000), "", "", ""); err == nil {
+		t.Error("expected error for non-positive amount")
+	}
+	if _, err := p.AddSwap("ETH", dec(1), "SOL", dec(10), dec(-1), "", "", ""); err == nil {
+		t.Error("expected error for negative value")
+	}
+}
@@ -0,0 +1,102 @@
+package portfolio
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// TagSummary totals the records carrying a tag.
+type TagSummary struct {
+	Tag         string
+	Count       int     // Tagged records of every type
+	InvestedUSD float64 // Cost of tagged purchases, including fees
+	SoldUSD     float64 // Proceeds of tagged sales, after fees
+}
+
+// GetTagSummary returns a summary of each tag in use, sorted by tag. Tags
+// that differ only in case are counted together.
+func (p *Portfolio) GetTagSummary() ([]TagSummary, error) {
+	summaries := make(map[string]*TagSummary)
+	invested := make(models.Totals)
+	sold := make(models.Totals)
+	count := func(tags []string) {
+		for _, tag := range tags {
+			key := strings.ToLower(tag)
+			if summaries[key] == nil {
+				summaries[key] = &TagSummary{Tag: tag}
+			}
+			summaries[key].Count++
+		}
+	}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		count(h.Tags)
+		for _, tag := range h.Tags {
+			invested.Add(strings.ToLower(tag), h.CostUSD())
+		}
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sales {
+		count(s.Tags)
+		for _, tag := range s.Tags {
+			sold.Add(strings.ToLower(tag), s.ProceedsUSD())
+		}
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		count(l.Tags)
+	}
+	repayments, err := p.ListRepayments()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repayments {
+		count(r.Tags)
+	}
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stakes {
+		count(st.Tags)
+	}
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+	for _, sw := range swaps {
+		count(sw.Tags)
+	}
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		count(t.Tags)
+	}
+
+	result := make([]TagSummary, 0, len(summaries))
+	for key, summary := range summaries {
+		summary.InvestedUSD = invested.Get(key)
+		summary.SoldUSD = sold.Get(key)
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Tag) < strings.ToLower(result[j].Tag)
+	})
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package portfolio
+
+import "testing"
+
+func TestPortfolio_Tags(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(40000), "", "", "2024-01-01", "DCA")
+	p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-02-01", "dca, gift")
+	p.AddHolding("ETH", dec(10), dec(2000), "", "", "2024-02-01")
+	p.AddSale("BTC", dec(0.5), dec(60000), "", "", "2024-03-01", "gift")
+	p.AddStake("ETH", dec(5), "Lido", nil, "", "2024-03-02", "yield")
+
+	holdings, err := p.ListHoldingsFiltered(ListOptions{Filter: Filter{Tag: "dca"}})
+	if err != nil {
+		t.Fatalf("ListHoldingsFiltered failed: %v", err)
+	}
+	if len(holdings) != 2 {
+		t.Errorf("expected 2 DCA purchases, got %d", len(holdings))
+	}
+	if tags := holdings[1].Tags; len(tags) != 2 || tags[0] != "dca" || tags[1] != "gift" {
+		t.Errorf("expected tags [dca gift], got %q", tags)
+	}
+
+	history, err := p.GetHistory(Filter{Tag: "GIFT"})
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Type != TypeBuy || history[1].Type != TypeSell {
+		t.Errorf("expected the gift purchase and sale, got %+v", history)
+	}
+
+	summaries, err := p.GetTagSummary()
+	if err != nil {
+		t.Fatalf("GetTagSummary failed: %v", err)
+	}
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 tags, got %+v", summaries)
+	}
+	dca, gift, yield := summaries[0], summaries[1], summaries[2]
+	if dca.Tag != "DCA" || dca.Count != 2 || dca.InvestedUSD != 90000 || dca.SoldUSD != 0 {
+		t.Errorf("unexpected DCA summary %+v", dca)
+	}
+	if gift.Count != 2 || gift.InvestedUSD != 50000 || gift.SoldUSD != 30000 {
+		t.Errorf("unexpected gift summary %+v", gift)
+	}
+	if yield.Tag != "yield" || yield.Count != 1 {
+		t.Errorf("unexpected yield summary %+v", yield)
+	}
+}
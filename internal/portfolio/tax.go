@@ -0,0 +1,165 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Disposal is the portion of a sale matched against a single purchase lot.
+type Disposal struct {
+	SaleID       string
+	HoldingID    string // Empty when the sale exceeded all recorded purchases
+	Coin         string
+	Amount       decimal.Decimal
+	AcquiredDate models.Date
+	DisposedDate models.Date
+	ProceedsUSD  decimal.Decimal
+	CostBasisUSD decimal.Decimal
+	LongTerm     bool
+}
+
+// GainUSD returns the realized gain (or loss) of the disposal.
+func (d Disposal) GainUSD() decimal.Decimal {
+	return d.ProceedsUSD.Sub(d.CostBasisUSD)
+}
+
+// lot tracks the unsold remainder of a purchase during matching.
+type lot struct {
+	holding   models.Holding
+	remaining decimal.Decimal
+}
+
+// GetDisposals matches every sale against purchase lots of the same coin
+// and returns the resulting disposals. A sale is matched first against the
+// lots it names (specific identification), then using first-in, first-out
+// ordering, only ever against lots acquired on or before its date. Swaps count as a sale of the coin given up and a purchase of the
+// coin received, so their SaleID or HoldingID is the swap ID.
+func (p *Portfolio) GetDisposals() ([]Disposal, error) {
+	disposals, _, err := p.matchLots()
+	return disposals, err
+}
+
+// matchLots matches sales against purchase lots as described for
+// GetDisposals, returning the disposals and each coin's lots, oldest first,
+// with their unsold remainder.
+func (p *Portfolio) matchLots() ([]Disposal, map[string][]*lot, error) {
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Swaps dispose of one coin and acquire another at the swap value
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sw := range swaps {
+		sale, holding := swapLegs(sw)
+		sales = append(sales, sale)
+		holdings = append(holdings, holding)
+	}
+
+	sort.SliceStable(holdings, func(i, j int) bool { return holdings[i].Date.Before(holdings[j].Date) })
+	sort.SliceStable(sales, func(i, j int) bool { return sales[i].Date.Before(sales[j].Date) })
+
+	lotsByCoin := make(map[string][]*lot)
+	for _, h := range holdings {
+		lotsByCoin[h.Coin] = append(lotsByCoin[h.Coin], &lot{holding: h, remaining: h.Amount})
+	}
+
+	// Named lots are claimed before any FIFO matching, so an earlier sale
+	// can't use up a lot a later sale was linked to
+	matched := make([][]Disposal, len(sales))
+	unmatched := make([]decimal.Decimal, len(sales))
+	for i, s := range sales {
+		unmatched[i] = s.Amount
+		for _, id := range s.LotIDs {
+			for _, l := range lotsByCoin[s.Coin] {
+				if l.holding.ID == id && l.remaining.IsPositive() && unmatched[i].IsPositive() && l.heldBy(s.Date) {
+					matched[i] = append(matched[i], dispose(s, l, &unmatched[i]))
+				}
+			}
+		}
+	}
+
+	var disposals []Disposal
+	for i, s := range sales {
+		disposals = append(disposals, matched[i]...)
+		for _, l := range lotsByCoin[s.Coin] {
+			if !unmatched[i].IsPositive() {
+				break
+			}
+			if !l.remaining.IsPositive() || !l.heldBy(s.Date) {
+				continue
+			}
+			disposals = append(disposals, dispose(s, l, &unmatched[i]))
+		}
+
+		// Sold more than was recorded as purchased by the sale date: no
+		// known cost basis
+		if unmatched[i].IsPositive() {
+			disposals = append(disposals, Disposal{
+				SaleID:       s.ID,
+				Coin:         s.Coin,
+				Amount:       unmatched[i],
+				DisposedDate: s.Date,
+				ProceedsUSD:  unmatched[i].Mul(s.ProceedsPerUnitUSD()),
+			})
+		}
+	}
+	return disposals, lotsByCoin, nil
+}
+
+// heldBy reports whether the lot was acquired on or before date, so a sale
+// on that day can be matched against it. A sale without a date may use
+// any lot.
+func (l *lot) heldBy(date models.Date) bool {
+	return date.IsZero() || !l.holding.Date.After(date)
+}
+
+// dispose matches as much of a sale's unmatched amount as l has left,
+// reducing both.
+func dispose(s models.Sale, l *lot, unmatched *decimal.Decimal) Disposal {
+	amount := decimal.Min(*unmatched, l.remaining)
+	l.remaining = l.remaining.Sub(amount)
+	*unmatched = unmatched.Sub(amount)
+	return Disposal{
+		SaleID:       s.ID,
+		HoldingID:    l.holding.ID,
+		Coin:         s.Coin,
+		Amount:       amount,
+		AcquiredDate: l.holding.Date,
+		DisposedDate: s.Date,
+		ProceedsUSD:  amount.Mul(s.ProceedsPerUnitUSD()),
+		CostBasisUSD: amount.Mul(l.holding.CostPerUnitUSD()),
+		LongTerm:     isLongTerm(l.holding.Date, s.Date),
+	}
+}
+
+// GetTaxReport returns the disposals whose sale date falls in the given year.
+func (p *Portfolio) GetTaxReport(year int) ([]Disposal, error) {
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		return nil, err
+	}
+
+	var report []Disposal
+	for _, d := range disposals {
+		if d.DisposedDate.Year() == year {
+			report = append(report, d)
+		}
+	}
+	return report, nil
+}
+
+// isLongTerm reports whether an asset was held for more than one year.
+func isLongTerm(acquired, disposed models.Date) bool {
+	return disposed.Time.After(acquired.AddDate(1, 0, 0))
+}
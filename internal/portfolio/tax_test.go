@@ -0,0 +1,127 @@
+package portfolio
+
+import "testing"
+
+func TestPortfolio_GetDisposalsFIFO(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	// Added out of order to verify chronological matching
+	p.AddHolding("BTC", dec(1.0), dec(40000), "", "", "2023-06-01")
+	p.AddHolding("BTC", dec(0.5), dec(20000), "", "", "2022-01-01")
+	p.AddSale("BTC", dec(1.0), dec(60000), "", "", "2024-03-01")
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 2 {
+		t.Fatalf("expected 2 disposals, got %d", len(disposals))
+	}
+
+	first := disposals[0]
+	if first.AcquiredDate.String() != "2022-01-01" || !first.Amount.Equal(dec(0.5)) {
+		t.Errorf("expected oldest lot (0.5 from 2022-01-01) first, got %v from %s", first.Amount, first.AcquiredDate)
+	}
+	if !first.CostBasisUSD.Equal(dec(10000)) || !first.ProceedsUSD.Equal(dec(30000)) {
+		t.Errorf("expected cost 10000 and proceeds 30000, got %v and %v", first.CostBasisUSD, first.ProceedsUSD)
+	}
+	if !first.LongTerm {
+		t.Error("expected lot held over a year to be long-term")
+	}
+
+	second := disposals[1]
+	if !second.Amount.Equal(dec(0.5)) || !second.CostBasisUSD.Equal(dec(20000)) {
+		t.Errorf("expected 0.5 at cost 20000, got %v at %v", second.Amount, second.CostBasisUSD)
+	}
+	if second.LongTerm {
+		t.Error("expected lot held under a year to be short-term")
+	}
+	if !second.GainUSD().Equal(dec(10000)) {
+		t.Errorf("expected gain 10000, got %v", second.GainUSD())
+	}
+}
+
+func TestPortfolio_GetDisposalsWithFees(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHoldingWithFee("ETH", dec(2), dec(1000), dec(20), "", "", "2024-01-01")
+	p.AddSaleWithFee("ETH", dec(1), dec(1500), dec(15), "", "", "2024-06-01", nil)
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 1 {
+		t.Fatalf("expected 1 disposal, got %d", len(disposals))
+	}
+	// Half the purchase fee goes into the cost basis; the sale fee reduces proceeds
+	if d := disposals[0]; !d.CostBasisUSD.Equal(dec(1010)) || !d.ProceedsUSD.Equal(dec(1485)) {
+		t.Errorf("expected cost 1010 and proceeds 1485, got %v and %v", d.CostBasisUSD, d.ProceedsUSD)
+	}
+}
+
+func TestPortfolio_GetDisposalsOversold(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(1), dec(2000), "", "", "2024-01-01")
+	p.AddSaleUnchecked("ETH", dec(3), dec(3000), dec(0), "", "", "2024-02-01", nil)
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 2 {
+		t.Fatalf("expected 2 disposals, got %d", len(disposals))
+	}
+	unmatched := disposals[1]
+	if unmatched.HoldingID != "" || !unmatched.Amount.Equal(dec(2)) || !unmatched.CostBasisUSD.IsZero() {
+		t.Errorf("expected unmatched remainder of 2 with no cost basis, got %+v", unmatched)
+	}
+}
+
+func TestPortfolio_GetDisposalsSaleBeforePurchase(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(20000), "", "", "2024-01-01")
+	p.AddHolding("BTC", dec(1), dec(60000), "", "", "2024-06-01")
+	p.AddSaleUnchecked("BTC", dec(2), dec(50000), dec(0), "", "", "2024-03-01", nil)
+
+	disposals, err := p.GetDisposals()
+	if err != nil {
+		t.Fatalf("GetDisposals failed: %v", err)
+	}
+	if len(disposals) != 2 {
+		t.Fatalf("expected 2 disposals, got %+v", disposals)
+	}
+	if d := disposals[0]; d.AcquiredDate.String() != "2024-01-01" || !d.Amount.Equal(dec(1)) {
+		t.Errorf("expected 1 BTC from the January lot, got %+v", d)
+	}
+	// The June purchase came after the sale, so it can't be what was sold
+	if d := disposals[1]; d.HoldingID != "" || !d.Amount.Equal(dec(1)) || !d.CostBasisUSD.IsZero() {
+		t.Errorf("expected 1 BTC unmatched, got %+v", d)
+	}
+}
+
+func TestPortfolio_GetTaxReport(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("SOL", dec(10), dec(20), "", "", "2023-01-01")
+	p.AddSale("SOL", dec(2), dec(100), "", "", "2023-12-31")
+	p.AddSale("SOL", dec(3), dec(150), "", "", "2024-05-01")
+
+	report, err := p.GetTaxReport(2024)
+	if err != nil {
+		t.Fatalf("GetTaxReport failed: %v", err)
+	}
+	if len(report) != 1 {
+		t.Fatalf("expected 1 disposal in 2024, got %d", len(report))
+	}
+	if !report[0].Amount.Equal(dec(3)) || !report[0].LongTerm {
+		t.Errorf("expected long-term disposal of 3 SOL, got %+v", report[0])
+	}
+}
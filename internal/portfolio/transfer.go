@@ -0,0 +1,236 @@
+package portfolio
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// AddTransfer records moving coins between platforms. The source platform
+// must hold at least the transferred amount, and the fee (in coin units)
+// cannot exceed it.
+func (p *Portfolio) AddTransfer(coin string, amount decimal.Decimal, fromPlatform, toPlatform string, fee decimal.Decimal, notes, date string, tags ...string) (models.Transfer, error) {
+	coin = strings.ToUpper(coin)
+
+	if !amount.IsPositive() {
+		return models.Transfer{}, invalidf("transfer amount must be positive")
+	}
+	if fee.IsNegative() || fee.GreaterThan(amount) {
+		return models.Transfer{}, invalidf("transfer fee must be between 0 and the transferred amount")
+	}
+	if strings.EqualFold(fromPlatform, toPlatform) {
+		return models.Transfer{}, invalidf("cannot transfer %s from %s to itself", coin, fromPlatform)
+	}
+	parsed, err := p.validateDate(date)
+	if err != nil {
+		return models.Transfer{}, err
+	}
+
+	byPlatform, err := p.GetHoldingsByPlatform()
+	if err != nil {
+		return models.Transfer{}, err
+	}
+	available := byPlatform[platformKey(byPlatform, fromPlatform)][coin]
+	if amount.GreaterThan(available) {
+		return models.Transfer{}, invalidf("cannot transfer %s %s: only %s %s held on %s", amount, coin, available, coin, fromPlatform)
+	}
+
+	transfer := models.NewTransfer(coin, amount, fromPlatform, toPlatform, fee, notes, parsed)
+	transfer.Tags = models.ParseTags(tags...)
+	err = p.storage.AddTransfer(transfer)
+	return transfer, err
+}
+
+// RemoveTransfer removes a transfer by ID.
+func (p *Portfolio) RemoveTransfer(id string) (bool, error) {
+	return p.storage.RemoveTransfer(id)
+}
+
+// ListTransfers lists all transfers.
+func (p *Portfolio) ListTransfers() ([]models.Transfer, error) {
+	return p.storage.GetTransfers()
+}
+
+// GetTransferFeesByCoin returns total transfer fees paid aggregated by coin.
+func (p *Portfolio) GetTransferFeesByCoin() (map[string]decimal.Decimal, error) {
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+
+	byCoin := make(models.Totals)
+	for _, t := range transfers {
+		if !t.Fee.IsZero() {
+			byCoin.Add(t.Coin, t.Fee)
+		}
+	}
+	return byCoin, nil
+}
+
+// GetHoldingsByPlatform returns current holdings (purchases - sales, adjusted
+// for swaps and transfers) by platform and coin. Records without a platform are grouped
+// under the empty string.
+func (p *Portfolio) GetHoldingsByPlatform() (map[string]map[string]decimal.Decimal, error) {
+	byPlatform := make(map[string]map[string]decimal.Decimal)
+	add := func(platform, coin string, amount decimal.Decimal) {
+		platform = platformKey(byPlatform, platform)
+		if byPlatform[platform] == nil {
+			byPlatform[platform] = make(map[string]decimal.Decimal)
+		}
+		byPlatform[platform][coin] = byPlatform[platform][coin].Add(amount)
+	}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		add(h.Platform, h.Coin, h.Amount)
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sales {
+		add(s.Platform, s.Coin, s.Amount.Neg())
+	}
+
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+	for _, sw := range swaps {
+		add(sw.Platform, sw.FromCoin, sw.FromAmount.Neg())
+		add(sw.Platform, sw.ToCoin, sw.ToAmount)
+	}
+
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		add(t.FromPlatform, t.Coin, t.Amount.Neg())
+		add(t.ToPlatform, t.Coin, t.Received())
+	}
+	return byPlatform, nil
+}
+
+// PlatformCount is a platform and the number of records using it.
+type PlatformCount struct {
+	Platform string
+	Records  int
+}
+
+// GetPlatforms returns the sorted unique platform names used by any record.
+// Names differing only in case are listed once, spelled as first seen.
+func (p *Portfolio) GetPlatforms() ([]string, error) {
+	counts, err := p.GetPlatformCounts()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(counts))
+	for i, c := range counts {
+		names[i] = c.Platform
+	}
+	return names, nil
+}
+
+// GetPlatformCounts returns the platforms used by any record with the
+// number of records using each, sorted by name. Names differing only in
+// case are counted together, spelled as first seen; a transfer between
+// two platforms counts for both.
+func (p *Portfolio) GetPlatformCounts() ([]PlatformCount, error) {
+	var counts []PlatformCount
+	index := make(map[string]int)
+	add := func(platform string) {
+		if platform == "" {
+			return
+		}
+		key := strings.ToLower(platform)
+		if i, ok := index[key]; ok {
+			counts[i].Records++
+			return
+		}
+		index[key] = len(counts)
+		counts = append(counts, PlatformCount{Platform: platform, Records: 1})
+	}
+
+	holdings, err := p.ListHoldings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		add(h.Platform)
+	}
+
+	sales, err := p.ListSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sales {
+		add(s.Platform)
+	}
+
+	loans, err := p.ListLoans()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		add(l.Platform)
+	}
+
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stakes {
+		add(st.Platform)
+	}
+
+	swaps, err := p.ListSwaps()
+	if err != nil {
+		return nil, err
+	}
+	for _, sw := range swaps {
+		add(sw.Platform)
+	}
+
+	transfers, err := p.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		add(t.FromPlatform)
+		if !strings.EqualFold(t.ToPlatform, t.FromPlatform) {
+			add(t.ToPlatform)
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Platform < counts[j].Platform })
+	return counts, nil
+}
+
+// RenamePlatform sets the platform of every record on oldName, ignoring
+// case, to newName, e.g. to merge "coinbase" and "CB" into "Coinbase".
+// It returns the number of records changed.
+func (p *Portfolio) RenamePlatform(oldName, newName string) (int, error) {
+	oldName, newName = strings.TrimSpace(oldName), strings.TrimSpace(newName)
+	if oldName == "" || newName == "" {
+		return 0, invalidf("platform names must not be empty")
+	}
+	return p.storage.RenamePlatform(oldName, newName)
+}
+
+// platformKey returns the existing key matching platform case-insensitively,
+// or platform itself if there is none.
+func platformKey(byPlatform map[string]map[string]decimal.Decimal, platform string) string {
+	for key := range byPlatform {
+		if strings.EqualFold(key, platform) {
+			return key
+		}
+	}
+	return platform
+}
@@ -0,0 +1,142 @@
+package portfolio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestPortfolio_Transfers(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "Binance", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.2), dec(40000), "Binance", "", "2024-02-01")
+
+	tr, err := p.AddTransfer("btc", dec(0.5), "binance", "Ledger", dec(0.001), "cold storage", "2024-03-01")
+	if err != nil {
+		t.Fatalf("AddTransfer failed: %v", err)
+	}
+	if tr.Coin != "BTC" {
+		t.Errorf("expected coin to be uppercased to BTC, got %s", tr.Coin)
+	}
+
+	byPlatform, err := p.GetHoldingsByPlatform()
+	if err != nil {
+		t.Fatalf("GetHoldingsByPlatform failed: %v", err)
+	}
+	if got := byPlatform["Binance"]["BTC"]; !got.Equal(dec(0.3)) {
+		t.Errorf("expected 0.3 BTC left on Binance, got %v", got)
+	}
+	if got := byPlatform["Ledger"]["BTC"]; !got.Equal(dec(0.499)) {
+		t.Errorf("expected 0.499 BTC on Ledger, got %v", got)
+	}
+
+	// Fees reduce total holdings
+	current, _ := p.GetCurrentHoldingsByCoin()
+	if got := current["BTC"]; !got.Equal(dec(0.799)) {
+		t.Errorf("expected 0.799 BTC held after fee, got %v", got)
+	}
+	pos, _ := p.GetPositionsAt(models.NewDate(2024, 2, 15))
+	if got := pos.HoldingsByCoin["BTC"]; !got.Equal(dec(0.8)) {
+		t.Errorf("expected fee not counted before transfer date, got %v", got)
+	}
+
+	// History includes the transfer and matches either platform
+	history, _ := p.GetHistory(Filter{Platform: "ledger"})
+	if len(history) != 1 || history[0].Type != TypeTransfer {
+		t.Errorf("expected transfer to match destination platform filter, got %+v", history)
+	}
+
+	removed, err := p.RemoveTransfer(tr.ID)
+	if err != nil || !removed {
+		t.Errorf("expected transfer to be removed, err: %v", err)
+	}
+}
+
+func TestPortfolio_TransferValidation(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(5), dec(3000), "Coinbase", "", "")
+
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		fee      float64
+	}{
+		{"more than held on platform", 6, "Coinbase", "Ledger", 0},
+		{"coin not on platform", 1, "Kraken", "Ledger", 0},
+		{"same platform", 1, "Coinbase", "coinbase", 0},
+		{"non-positive amount", 0, "Coinbase", "Ledger", 0},
+		{"fee above amount", 1, "Coinbase", "Ledger", 2},
+	}
+	for _, tt := range tests {
+		if _, err := p.AddTransfer("ETH", dec(tt.amount), tt.from, tt.to, dec(tt.fee), "", ""); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestPortfolio_GetPlatforms(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "Binance", "", "2024-01-01")
+	p.AddHolding("ETH", dec(2), dec(2000), "", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.1), dec(40000), "binance", "", "2024-02-01")
+	p.AddLoan("USDT", dec(500), "Nexo", nil, "", "2024-01-01")
+	p.AddStake("ETH", dec(1), "Lido", nil, "", "2024-01-01")
+	p.AddTransfer("BTC", dec(0.5), "Binance", "Ledger", dec(0), "", "2024-03-01")
+
+	platforms, err := p.GetPlatforms()
+	if err != nil {
+		t.Fatalf("GetPlatforms failed: %v", err)
+	}
+	want := []string{"Binance", "Ledger", "Lido", "Nexo"}
+	if len(platforms) != len(want) {
+		t.Fatalf("expected %v, got %v", want, platforms)
+	}
+	for i := range want {
+		if platforms[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, platforms)
+			break
+		}
+	}
+
+	counts, err := p.GetPlatformCounts()
+	if err != nil {
+		t.Fatalf("GetPlatformCounts failed: %v", err)
+	}
+	wantCounts := []PlatformCount{{"Binance", 3}, {"Ledger", 1}, {"Lido", 1}, {"Nexo", 1}}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("expected %v, got %v", wantCounts, counts)
+	}
+}
+
+func TestPortfolio_RenamePlatform(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("BTC", dec(1), dec(30000), "Binance", "", "2024-01-01")
+	p.AddSale("BTC", dec(0.1), dec(40000), "binance", "", "2024-02-01")
+	p.AddStake("BTC", dec(0.2), "BNB", nil, "", "2024-02-01")
+
+	if _, err := p.RenamePlatform(" ", "Binance"); err == nil {
+		t.Error("expected an error for an empty platform name")
+	}
+	changed, err := p.RenamePlatform("BNB", "Binance")
+	if err != nil || changed != 1 {
+		t.Fatalf("expected 1 record changed, got %d, %v", changed, err)
+	}
+	if changed, _ := p.RenamePlatform("binance", "Binance"); changed != 1 {
+		t.Errorf("expected the sale respelled, got %d changed", changed)
+	}
+
+	counts, _ := p.GetPlatformCounts()
+	if len(counts) != 1 || counts[0] != (PlatformCount{"Binance", 3}) {
+		t.Errorf("expected all 3 records on Binance, got %v", counts)
+	}
+}
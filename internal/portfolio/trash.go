@@ -0,0 +1,28 @@
+package portfolio
+
+import (
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// ListTrash lists removed holdings, sales, loans, and stakes, oldest removal
+// first.
+func (p *Portfolio) ListTrash() ([]models.TrashItem, error) {
+	return p.storage.GetTrash()
+}
+
+// RestoreTrash restores a removed record by ID. It returns false if the
+// trash has no record with the ID.
+func (p *Portfolio) RestoreTrash(id string) (models.TrashItem, bool, error) {
+	return p.storage.RestoreTrash(id)
+}
+
+// PurgeTrash permanently deletes records removed more than retentionDays
+// ago and returns how many were deleted. Zero or less keeps them forever.
+func (p *Portfolio) PurgeTrash(retentionDays int) (int, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	return p.storage.PurgeTrash(time.Now().AddDate(0, 0, -retentionDays))
+}
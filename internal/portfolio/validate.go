@@ -0,0 +1,45 @@
+package portfolio
+
+import (
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// SetAllowFutureDates sets whether records may be dated after today.
+// Future dates are rejected by default, since they are usually typos.
+func (p *Portfolio) SetAllowFutureDates(allow bool) {
+	p.allowFutureDates = allow
+}
+
+// validateDate parses a record date. An empty date returns the zero Date,
+// so the record defaults to today.
+func (p *Portfolio) validateDate(date string) (models.Date, error) {
+	if date == "" {
+		return models.Date{}, nil
+	}
+	parsed, err := models.ParseDate(date)
+	if err != nil {
+		return models.Date{}, invalidf("invalid date %q: expected YYYY-MM-DD", date)
+	}
+	if !p.allowFutureDates && parsed.After(models.Today()) {
+		return models.Date{}, invalidf("date %s is in the future", parsed)
+	}
+	return parsed, nil
+}
+
+// validatePositive rejects a zero or negative value, naming it in the error
+func validatePositive(name string, value decimal.Decimal) error {
+	if !value.IsPositive() {
+		return invalidf("%s must be positive", name)
+	}
+	return nil
+}
+
+// onDate returns " on DATE" for a date before today, to say which day a
+// balance is from, or "" otherwise
+func onDate(date models.Date) string {
+	if date.IsZero() || !date.Before(models.Today()) {
+		return ""
+	}
+	return " on " + date.String()
+}
@@ -0,0 +1,59 @@
+package portfolio
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPortfolio_RejectsInvalidInput(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	tests := []struct {
+		name string
+		add  func() error
+	}{
+		{"zero amount", func() error { _, err := p.AddHolding("BTC", dec(0), dec(50000), "", "", ""); return err }},
+		{"negative price", func() error { _, err := p.AddHolding("BTC", dec(1), dec(-5), "", "", ""); return err }},
+		{"impossible date", func() error { _, err := p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024-13-45"); return err }},
+		{"future date", func() error { _, err := p.AddHolding("BTC", dec(1), dec(50000), "", "", tomorrow); return err }},
+		{"zero sale price", func() error { _, err := p.AddSale("BTC", dec(1), dec(0), "", "", ""); return err }},
+		{"negative loan", func() error { _, err := p.AddLoan("USDC", dec(-100), "Nexo", nil, "", ""); return err }},
+		{"future loan", func() error { _, err := p.AddLoan("USDC", dec(100), "Nexo", nil, "", tomorrow); return err }},
+		{"zero stake", func() error { _, err := p.AddStake("BTC", dec(0), "Lido", nil, "", ""); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *ValidationError
+			if err := tt.add(); !errors.As(err, &validationErr) {
+				t.Errorf("Expected ValidationError, got %v", err)
+			}
+		})
+	}
+
+	if holdings, _ := p.ListHoldings(); len(holdings) != 0 {
+		t.Errorf("Expected nothing stored, got %d holdings", len(holdings))
+	}
+}
+
+func TestPortfolio_NormalizesDates(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	h, err := p.AddHolding("BTC", dec(1), dec(50000), "", "", "2024/1/5")
+	if err != nil {
+		t.Fatalf("AddHolding failed: %v", err)
+	}
+	if h.Date.String() != "2024-01-05" {
+		t.Errorf("Expected 2024-01-05, got %s", h.Date)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	p.SetAllowFutureDates(true)
+	if _, err := p.AddHolding("BTC", dec(1), dec(50000), "", "", tomorrow); err != nil {
+		t.Errorf("Expected future date to be allowed, got %v", err)
+	}
+}
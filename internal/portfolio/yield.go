@@ -0,0 +1,49 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// YieldEntry is the projected staking yield for a coin, in coin units.
+type YieldEntry struct {
+	Coin    string
+	Staked  float64 // Amount staked with an APY
+	APY     float64 // Stake-weighted average APY (%)
+	Annual  float64
+	Monthly float64
+}
+
+// GetProjectedYield returns the projected annual and monthly staking yield
+// per coin, based on each stake's amount and APY, sorted by coin. Stakes
+// without an APY are not included.
+func (p *Portfolio) GetProjectedYield() ([]YieldEntry, error) {
+	stakes, err := p.ListStakes()
+	if err != nil {
+		return nil, err
+	}
+
+	byCoin := make(map[string]*YieldEntry)
+	for _, st := range stakes {
+		if st.APY == nil || *st.APY == 0 {
+			continue
+		}
+		entry, ok := byCoin[st.Coin]
+		if !ok {
+			entry = &YieldEntry{Coin: st.Coin}
+			byCoin[st.Coin] = entry
+		}
+		entry.Staked = models.Add(entry.Staked, models.Float(st.Amount))
+		entry.Annual = models.Add(entry.Annual, models.Mul(models.Float(st.Amount), *st.APY)/100)
+	}
+
+	entries := make([]YieldEntry, 0, len(byCoin))
+	for _, entry := range byCoin {
+		entry.APY = entry.Annual / entry.Staked * 100
+		entry.Monthly = entry.Annual / 12
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Coin < entries[j].Coin })
+	return entries, nil
+}
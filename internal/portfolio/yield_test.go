@@ -0,0 +1,43 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPortfolio_GetProjectedYield(t *testing.T) {
+	p, cleanup := setupTestPortfolio(t)
+	defer cleanup()
+
+	p.AddHolding("ETH", dec(20), dec(3000), "", "", "")
+	p.AddHolding("SOL", dec(100), dec(100), "", "", "")
+
+	apy4, apy6, apy7 := 4.0, 6.0, 7.0
+	p.AddStake("ETH", dec(10), "Lido", &apy4, "", "")
+	p.AddStake("ETH", dec(5), "Coinbase", &apy6, "", "")
+	p.AddStake("ETH", dec(5), "Kraken", nil, "", "")
+	p.AddStake("SOL", dec(60), "Marinade", &apy7, "", "")
+
+	yield, err := p.GetProjectedYield()
+	if err != nil {
+		t.Fatalf("GetProjectedYield failed: %v", err)
+	}
+	if len(yield) != 2 {
+		t.Fatalf("expected 2 coins, got %d", len(yield))
+	}
+
+	eth := yield[0]
+	if eth.Coin != "ETH" || eth.Staked != 15 {
+		t.Errorf("expected 15 ETH staked with an APY, got %+v", eth)
+	}
+	if math.Abs(eth.Annual-0.7) > 1e-9 || math.Abs(eth.Monthly-0.7/12) > 1e-9 {
+		t.Errorf("expected 0.7 ETH annual yield, got %+v", eth)
+	}
+	if math.Abs(eth.APY-0.7/15*100) > 1e-9 {
+		t.Errorf("expected weighted APY %f, got %f", 0.7/15*100, eth.APY)
+	}
+
+	if sol := yield[1]; sol.Coin != "SOL" || math.Abs(sol.Annual-4.2) > 1e-9 {
+		t.Errorf("expected 4.2 SOL annual yield, got %+v", sol)
+	}
+}
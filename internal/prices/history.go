@@ -0,0 +1,91 @@
+package prices
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Candle is a coin's opening, highest, lowest, and closing price over a
+// period, in the service currency.
+type Candle struct {
+	Time  time.Time // End of the period
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+// HistoryDays are the ranges of price history that can be fetched, in days.
+var HistoryDays = []int{7, 30, 90, 365}
+
+// GetPriceHistory fetches a coin's price candles over the last days from
+// CoinGecko, oldest first. CoinGecko has 4-hour candles for up to 30 days,
+// which are merged into daily ones, and 4-day candles for longer ranges.
+// A coin CoinGecko does not know has no candles. Price history is not
+// cached since it is only used for display.
+func (ps *PriceService) GetPriceHistory(ticker string, days int) ([]Candle, error) {
+	geckoID, ok := ps.coinIDMap[strings.ToUpper(ticker)]
+	if !ok {
+		geckoID = strings.ToLower(ticker)
+	}
+
+	params := url.Values{}
+	params.Set("vs_currency", ps.currency)
+	params.Set("days", strconv.Itoa(days))
+
+	resp, err := ps.get("https://api.coingecko.com/api/v3/coins/" + url.PathEscape(geckoID) + "/ohlc?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: [[1704067200000, 42000, 42500, 41800, 42300], ...],
+	// each candle's close time in milliseconds and its prices
+	var data [][5]float64
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse price history response: %w", err)
+	}
+
+	candles := make([]Candle, len(data))
+	for i, d := range data {
+		candles[i] = Candle{Time: time.UnixMilli(int64(d[0])).UTC(), Open: d[1], High: d[2], Low: d[3], Close: d[4]}
+	}
+	if days <= 30 {
+		candles = mergeDaily(candles)
+	}
+	return candles, nil
+}
+
+// mergeDaily merges candles ending on the same UTC day into one. A candle
+// closing at midnight belongs to the day before.
+func mergeDaily(candles []Candle) []Candle {
+	dayOf := func(c Candle) time.Time {
+		return c.Time.Add(-time.Millisecond).Truncate(24 * time.Hour)
+	}
+
+	var daily []Candle
+	for _, c := range candles {
+		if n := len(daily); n > 0 && dayOf(daily[n-1]).Equal(dayOf(c)) {
+			d := &daily[n-1]
+			d.Time = c.Time
+			d.High = max(d.High, c.High)
+			d.Low = min(d.Low, c.Low)
+			d.Close = c.Close
+			continue
+		}
+		daily = append(daily, c)
+	}
+	return daily
+}
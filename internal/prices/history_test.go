@@ -0,0 +1,56 @@
+package prices
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetPriceHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/coins/nope/ohlc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path != "/api/v3/coins/bitcoin/ohlc" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("days"); got != "7" && got != "90" {
+			t.Errorf("Unexpected days=%s", got)
+		}
+		// 4-hour candles closing 2024-01-01 20:00, 2024-01-02 00:00, and 2024-01-02 04:00 UTC
+		w.Write([]byte(`[[1704139200000, 100, 110, 95, 105], [1704153600000, 105, 120, 100, 115], [1704168000000, 115, 118, 90, 92]]`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+
+	candles, err := ps.GetPriceHistory("btc", 7)
+	if err != nil {
+		t.Fatalf("GetPriceHistory failed: %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("Expected candles merged into 2 days, got %+v", candles)
+	}
+	jan1 := candles[0]
+	if jan1.Open != 100 || jan1.High != 120 || jan1.Low != 95 || jan1.Close != 115 {
+		t.Errorf("Unexpected 2024-01-01 candle: %+v", jan1)
+	}
+	if !jan1.Time.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the day's candle to end at midnight, got %s", jan1.Time)
+	}
+	if candles[1].Open != 115 || candles[1].Close != 92 {
+		t.Errorf("Unexpected 2024-01-02 candle: %+v", candles[1])
+	}
+
+	// Longer ranges keep CoinGecko's candles
+	if candles, _ := ps.GetPriceHistory("BTC", 90); len(candles) != 3 {
+		t.Errorf("Expected 3 candles for 90 days, got %d", len(candles))
+	}
+
+	candles, err = ps.GetPriceHistory("NOPE", 7)
+	if err != nil || len(candles) != 0 {
+		t.Errorf("Expected no candles for an unknown coin, got %v, %v", candles, err)
+	}
+}
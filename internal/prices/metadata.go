@@ -0,0 +1,222 @@
+package prices
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MetadataTTL is how long coin metadata is cached before it is refreshed.
+// Names and categories rarely change, so a week is plenty.
+const MetadataTTL = 7 * 24 * time.Hour
+
+// CoinMetadata describes a coin beyond its price.
+type CoinMetadata struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Symbol     string    `json:"symbol"`
+	Rank       int       `json:"rank,omitempty"`       // Market cap rank; 0 if unranked
+	Image      string    `json:"image,omitempty"`      // URL of the coin's icon
+	Categories []string  `json:"categories,omitempty"` // Only set by GetCoinCategories
+	FetchedAt  time.Time `json:"fetched_at"`
+
+	categoriesFetchedAt time.Time
+}
+
+// persistedMetadata is the on-disk form of a metadata cache entry
+type persistedMetadata struct {
+	CoinMetadata
+	CategoriesFetchedAt time.Time `json:"categories_fetched_at,omitempty"`
+}
+
+// SetMetadataCacheFile loads cached coin metadata from path and persists
+// fetched metadata there. A missing file is not an error.
+func (ps *PriceService) SetMetadataCacheFile(path string) error {
+	ps.metaFile = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries map[string]persistedMetadata
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse coin metadata cache: %w", err)
+	}
+
+	ps.metaMu.Lock()
+	for id, e := range entries {
+		md := e.CoinMetadata
+		md.categoriesFetchedAt = e.CategoriesFetchedAt
+		ps.meta[id] = md
+	}
+	ps.metaMu.Unlock()
+	return nil
+}
+
+// saveMetadata writes the metadata cache to its file, if one is set
+func (ps *PriceService) saveMetadata() error {
+	if ps.metaFile == "" {
+		return nil
+	}
+
+	ps.metaMu.Lock()
+	entries := make(map[string]persistedMetadata, len(ps.meta))
+	for id, md := range ps.meta {
+		entries[id] = persistedMetadata{CoinMetadata: md, CategoriesFetchedAt: md.categoriesFetchedAt}
+	}
+	ps.metaMu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ps.metaFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ps.metaFile, data, 0644)
+}
+
+// GetCoinMetadata returns the name, rank, and icon of each ticker, keyed by
+// upper-case ticker. Metadata is cached for MetadataTTL; expired or missing
+// entries are fetched in batches. If fetching fails, cached entries are
+// still returned, however old, along with the error. Tickers CoinGecko
+// doesn't know are left out.
+func (ps *PriceService) GetCoinMetadata(tickers []string) (map[string]CoinMetadata, error) {
+	tickerToGeckoID := make(map[string]string)
+	var toFetch []string
+	ps.metaMu.Lock()
+	for _, ticker := range tickers {
+		upperTicker := strings.ToUpper(ticker)
+		geckoID := ps.GetCoinGeckoID(upperTicker)
+		tickerToGeckoID[upperTicker] = geckoID
+		if md, ok := ps.meta[geckoID]; !ok || time.Since(md.FetchedAt) >= MetadataTTL {
+			toFetch = append(toFetch, geckoID)
+		}
+	}
+	ps.metaMu.Unlock()
+
+	var fetchErr error
+	if len(toFetch) > 0 {
+		for _, chunk := range chunkIDs(toFetch, ps.chunkSize, maxIDsLength) {
+			if err := ps.fetchMetadata(chunk); err != nil {
+				fetchErr = err
+				break
+			}
+		}
+		// Persisting the cache is best-effort; a failed write only costs a refetch
+		_ = ps.saveMetadata()
+	}
+
+	result := make(map[string]CoinMetadata)
+	ps.metaMu.Lock()
+	for ticker, geckoID := range tickerToGeckoID {
+		if md, ok := ps.meta[geckoID]; ok {
+			result[ticker] = md
+		}
+	}
+	ps.metaMu.Unlock()
+	return result, fetchErr
+}
+
+// fetchMetadata fetches the metadata of coins by CoinGecko ID into the
+// cache, keeping any categories already cached
+func (ps *PriceService) fetchMetadata(geckoIDs []string) error {
+	params := url.Values{}
+	params.Set("vs_currency", "usd")
+	params.Set("ids", strings.Join(geckoIDs, ","))
+	params.Set("per_page", "250")
+
+	resp, err := ps.get("https://api.coingecko.com/api/v3/coins/markets?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to fetch coin metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: [{"id":"bitcoin","symbol":"btc","name":"Bitcoin",
+	// "image":"https://...","market_cap_rank":1},...]
+	var data []struct {
+		ID     string `json:"id"`
+		Symbol string `json:"symbol"`
+		Name   string `json:"name"`
+		Image  string `json:"image"`
+		Rank   int    `json:"market_cap_rank"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("failed to parse coin metadata response: %w", err)
+	}
+
+	now := time.Now()
+	ps.metaMu.Lock()
+	defer ps.metaMu.Unlock()
+	for _, d := range data {
+		md := ps.meta[d.ID]
+		md.ID, md.Symbol, md.Name, md.Image, md.Rank = d.ID, strings.ToUpper(d.Symbol), d.Name, d.Image, d.Rank
+		md.FetchedAt = now
+		ps.meta[d.ID] = md
+	}
+	return nil
+}
+
+// GetCoinCategories returns CoinGecko's categories for a ticker, e.g.
+// "Smart Contract Platform". Categories take a request per coin, so they
+// are cached for MetadataTTL separately from the rest of the metadata.
+func (ps *PriceService) GetCoinCategories(ticker string) ([]string, error) {
+	geckoID := ps.GetCoinGeckoID(ticker)
+	ps.metaMu.Lock()
+	md, ok := ps.meta[geckoID]
+	ps.metaMu.Unlock()
+	if ok && time.Since(md.categoriesFetchedAt) < MetadataTTL {
+		return md.Categories, nil
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"localization", "tickers", "market_data", "community_data", "developer_data"} {
+		params.Set(name, "false")
+	}
+	resp, err := ps.get("https://api.coingecko.com/api/v3/coins/" + url.PathEscape(geckoID) + "?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coin categories: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: {"id":"bitcoin","categories":["Cryptocurrency","Layer 1 (L1)"],...}
+	var data struct {
+		Categories []string `json:"categories"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse coin response: %w", err)
+	}
+	var categories []string
+	for _, c := range data.Categories {
+		if c != "" {
+			categories = append(categories, c)
+		}
+	}
+
+	ps.metaMu.Lock()
+	md = ps.meta[geckoID]
+	md.ID = geckoID
+	md.Categories = categories
+	md.categoriesFetchedAt = time.Now()
+	ps.meta[geckoID] = md
+	ps.metaMu.Unlock()
+	_ = ps.saveMetadata()
+	return categories, nil
+}
@@ -0,0 +1,105 @@
+package prices
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetCoinMetadata(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !strings.HasSuffix(r.URL.Path, "/coins/markets") {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`[{"id": "bitcoin", "symbol": "btc", "name": "Bitcoin", "image": "https://example.com/btc.png", "market_cap_rank": 1}]`))
+	}))
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "coin-metadata.json")
+	ps := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+	if err := ps.SetMetadataCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetMetadataCacheFile failed: %v", err)
+	}
+
+	md, err := ps.GetCoinMetadata([]string{"btc", "XYZ"})
+	if err != nil {
+		t.Fatalf("GetCoinMetadata failed: %v", err)
+	}
+	btc, ok := md["BTC"]
+	if !ok || btc.Name != "Bitcoin" || btc.Rank != 1 || btc.Symbol != "BTC" || btc.Image == "" {
+		t.Errorf("Unexpected BTC metadata: %+v", btc)
+	}
+	if _, ok := md["XYZ"]; ok {
+		t.Error("Expected unknown coin to be left out")
+	}
+
+	// A fresh entry is served from the cache, also after reloading it
+	ps2 := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+	if err := ps2.SetMetadataCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetMetadataCacheFile failed: %v", err)
+	}
+	if md, _ := ps2.GetCoinMetadata([]string{"BTC"}); md["BTC"].Name != "Bitcoin" {
+		t.Errorf("Expected cached BTC metadata, got %+v", md["BTC"])
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestGetCoinMetadataExpired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+	ps.SetRetryPolicy(1, 0)
+	ps.meta["bitcoin"] = CoinMetadata{ID: "bitcoin", Name: "Bitcoin", FetchedAt: time.Now().Add(-2 * MetadataTTL)}
+
+	// Refreshing fails, so the expired entry is returned with the error
+	md, err := ps.GetCoinMetadata([]string{"BTC"})
+	if err == nil {
+		t.Error("Expected refresh error")
+	}
+	if md["BTC"].Name != "Bitcoin" {
+		t.Errorf("Expected expired entry as fallback, got %+v", md)
+	}
+}
+
+func TestGetCoinCategories(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !strings.HasSuffix(r.URL.Path, "/coins/bitcoin") {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"id": "bitcoin", "categories": ["Cryptocurrency", "", "Layer 1 (L1)"]}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+	ps.meta["bitcoin"] = CoinMetadata{ID: "bitcoin", Name: "Bitcoin", FetchedAt: time.Now()}
+
+	want := []string{"Cryptocurrency", "Layer 1 (L1)"}
+	for range 2 {
+		categories, err := ps.GetCoinCategories("BTC")
+		if err != nil {
+			t.Fatalf("GetCoinCategories failed: %v", err)
+		}
+		if !reflect.DeepEqual(categories, want) {
+			t.Errorf("Expected %v, got %v", want, categories)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected categories to be cached, got %d requests", requests)
+	}
+	if ps.meta["bitcoin"].Name != "Bitcoin" {
+		t.Error("Fetching categories should keep the rest of the metadata")
+	}
+}
@@ -3,8 +3,14 @@ package prices
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,7 +22,37 @@ type PriceService struct {
 	cache     map[string]cachedPrice
 	cacheMu   sync.RWMutex
 	cacheTTL  time.Duration
-	coinIDMap map[string]string // maps ticker (BTC) to CoinGecko ID (bitcoin)
+	coinIDMap map[string]string  // maps ticker (BTC) to CoinGecko ID (bitcoin)
+	manual    map[string]float64 // USD prices for tickers CoinGecko has no price for
+	currency  string             // CoinGecko vs_currency code prices are quoted in
+	cacheFile string             // optional path where the cache is persisted
+
+	meta     map[string]CoinMetadata // Coin metadata by CoinGecko ID
+	metaMu   sync.Mutex
+	metaFile string // optional path where the metadata cache is persisted
+
+	maxAttempts int           // Tries per request before giving up
+	retryDelay  time.Duration // Wait before the first retry, doubled for each one after
+	onRetry     func(RetryStatus)
+	sleep       func(time.Duration)
+	chunkSize   int // Most IDs fetched in one request
+}
+
+// Retry defaults for rate limited and failed requests. Retry-After waits
+// longer than MaxRetryWait are not waited out; the request fails instead.
+const (
+	DefaultMaxAttempts = 4
+	DefaultRetryDelay  = time.Second
+	MaxRetryWait       = time.Minute
+)
+
+// RetryStatus describes a request about to be retried.
+type RetryStatus struct {
+	Attempt     int           // The attempt that failed, from 1
+	MaxAttempts int           // Attempts made before giving up
+	Wait        time.Duration // Time until the next attempt
+	RateLimited bool          // Whether CoinGecko answered 429 Too Many Requests
+	Err         error         // Why the attempt failed
 }
 
 type cachedPrice struct {
@@ -24,6 +60,23 @@ type cachedPrice struct {
 	fetchedAt time.Time
 }
 
+// persistedPrice is the on-disk form of a cached price
+type persistedPrice struct {
+	Price     float64   `json:"price"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// stablecoins are the tickers of USD stablecoins, pegged to $1
+var stablecoins = map[string]bool{
+	"USDT": true, "USDC": true, "DAI": true, "BUSD": true, "TUSD": true, "USDP": true,
+	"FDUSD": true, "PYUSD": true, "USDE": true, "FRAX": true, "GUSD": true, "LUSD": true,
+}
+
+// IsStablecoin reports whether a ticker is a USD stablecoin, pegged to $1
+func IsStablecoin(ticker string) bool {
+	return stablecoins[strings.ToUpper(ticker)]
+}
+
 // Common ticker to CoinGecko ID mappings
 var defaultCoinIDMap = map[string]string{
 	"BTC":   "bitcoin",
@@ -37,6 +90,7 @@ var defaultCoinIDMap = map[string]string{
 	"SOL":   "solana",
 	"DOT":   "polkadot",
 	"MATIC": "matic-network",
+	"POL":   "polygon-ecosystem-token",
 	"LTC":   "litecoin",
 	"SHIB":  "shiba-inu",
 	"TRX":   "tron",
@@ -49,6 +103,7 @@ var defaultCoinIDMap = map[string]string{
 	"ALGO":  "algorand",
 	"NEAR":  "near",
 	"FTM":   "fantom",
+	"S":     "sonic-3",
 	"APE":   "apecoin",
 	"MANA":  "decentraland",
 	"SAND":  "the-sandbox",
@@ -102,32 +157,251 @@ func New() *PriceService {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		cache:     make(map[string]cachedPrice),
-		cacheTTL:  2 * time.Minute,
-		coinIDMap: defaultCoinIDMap,
+		cache:       make(map[string]cachedPrice),
+		cacheTTL:    2 * time.Minute,
+		coinIDMap:   GetDefaultMappings(),
+		manual:      make(map[string]float64),
+		meta:        make(map[string]CoinMetadata),
+		currency:    "usd",
+		maxAttempts: DefaultMaxAttempts,
+		retryDelay:  DefaultRetryDelay,
+		sleep:       time.Sleep,
+		chunkSize:   maxIDsPerRequest,
 	}
 }
 
 // NewWithClient creates a PriceService with a custom HTTP client (for testing)
 func NewWithClient(client *http.Client) *PriceService {
 	return &PriceService{
-		client:    client,
-		cache:     make(map[string]cachedPrice),
-		cacheTTL:  2 * time.Minute,
-		coinIDMap: defaultCoinIDMap,
+		client:      client,
+		cache:       make(map[string]cachedPrice),
+		cacheTTL:    2 * time.Minute,
+		coinIDMap:   GetDefaultMappings(),
+		manual:      make(map[string]float64),
+		meta:        make(map[string]CoinMetadata),
+		currency:    "usd",
+		maxAttempts: DefaultMaxAttempts,
+		retryDelay:  DefaultRetryDelay,
+		sleep:       time.Sleep,
+		chunkSize:   maxIDsPerRequest,
 	}
 }
 
+// SetRetryPolicy sets how many times a request is tried when CoinGecko rate
+// limits it (429) or fails with a server error, and the wait before the
+// first retry. Waits double with each retry, with jitter, unless the
+// response gives a Retry-After. Fewer than 1 attempt means 1.
+func (ps *PriceService) SetRetryPolicy(maxAttempts int, retryDelay time.Duration) {
+	ps.maxAttempts = max(1, maxAttempts)
+	ps.retryDelay = retryDelay
+}
+
+// SetRetryNotifier sets a function called before each retry, e.g. to show
+// that prices are rate limited.
+func (ps *PriceService) SetRetryNotifier(fn func(RetryStatus)) {
+	ps.onRetry = fn
+}
+
+// get requests url, retrying rate limited and failed requests according to
+// the retry policy. Other responses are returned as is for the caller to
+// check. Network errors are not retried, so going offline fails fast.
+func (ps *PriceService) get(url string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := ps.client.Get(url)
+		if err != nil {
+			slog.Info("price request failed", "url", url, "attempt", attempt, "err", err)
+			return nil, err
+		}
+		slog.Info("price request", "url", url, "status", resp.StatusCode,
+			"duration", time.Since(start).Round(time.Millisecond), "attempt", attempt)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		status := RetryStatus{
+			Attempt:     attempt,
+			MaxAttempts: ps.maxAttempts,
+			RateLimited: resp.StatusCode == http.StatusTooManyRequests,
+			Err:         fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode),
+		}
+		if attempt >= ps.maxAttempts {
+			return nil, status.Err
+		}
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			status.Wait = wait
+		} else {
+			// Exponential backoff with jitter, so clients don't retry in step
+			backoff := ps.retryDelay << (attempt - 1)
+			status.Wait = backoff/2 + rand.N(backoff/2+1)
+		}
+		if status.Wait > MaxRetryWait {
+			return nil, fmt.Errorf("%w (retry after %s)", status.Err, status.Wait.Round(time.Second))
+		}
+		if ps.onRetry != nil {
+			ps.onRetry(status)
+		}
+		ps.sleep(status.Wait)
+	}
+}
+
+// Ping checks that the CoinGecko API can be reached. It tries once, without
+// the retries of other requests.
+func (ps *PriceService) Ping() error {
+	resp, err := ps.client.Get("https://api.coingecko.com/api/v3/ping")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It reports false if the header is missing or invalid.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
 // SetCacheTTL sets the cache time-to-live duration
 func (ps *PriceService) SetCacheTTL(ttl time.Duration) {
 	ps.cacheTTL = ttl
 }
 
+// SupportedCurrencies lists the fiat currencies prices can be displayed in
+var SupportedCurrencies = []string{"USD", "EUR", "GBP", "JPY", "CHF", "CAD", "AUD"}
+
+// IsSupportedCurrency checks if a currency code is supported for display
+func IsSupportedCurrency(currency string) bool {
+	for _, c := range SupportedCurrencies {
+		if strings.EqualFold(c, currency) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCurrency sets the currency prices are quoted in (e.g. "EUR")
+func (ps *PriceService) SetCurrency(currency string) {
+	ps.currency = strings.ToLower(currency)
+}
+
+// Currency returns the upper-case currency code prices are quoted in
+func (ps *PriceService) Currency() string {
+	return strings.ToUpper(ps.currency)
+}
+
+// SetCacheFile enables a disk-backed cache at path so cached prices survive
+// across process runs. Existing entries are loaded immediately and still
+// honor the cache TTL; a missing file is not an error.
+func (ps *PriceService) SetCacheFile(path string) error {
+	ps.cacheFile = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries map[string]persistedPrice
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse price cache: %w", err)
+	}
+
+	ps.cacheMu.Lock()
+	for key, e := range entries {
+		ps.cache[key] = cachedPrice{price: e.Price, fetchedAt: e.FetchedAt}
+	}
+	ps.cacheMu.Unlock()
+	return nil
+}
+
+// saveCache writes the cache to disk if a cache file is configured
+func (ps *PriceService) saveCache() error {
+	if ps.cacheFile == "" {
+		return nil
+	}
+
+	ps.cacheMu.RLock()
+	entries := make(map[string]persistedPrice, len(ps.cache))
+	for key, c := range ps.cache {
+		entries[key] = persistedPrice{Price: c.price, FetchedAt: c.fetchedAt}
+	}
+	ps.cacheMu.RUnlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ps.cacheFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ps.cacheFile, data, 0644)
+}
+
+// cacheKey returns the cache key for a ticker in the current currency.
+// Prices are keyed by CoinGecko ID, so mapping a ticker to another coin
+// doesn't reuse the old coin's price.
+func (ps *PriceService) cacheKey(ticker string) string {
+	return ps.currency + ":" + ps.geckoID(ticker)
+}
+
+// geckoID returns the CoinGecko ID a ticker is priced by: its mapping, or
+// the lowercase ticker if it has none
+func (ps *PriceService) geckoID(ticker string) string {
+	upperTicker := strings.ToUpper(ticker)
+	if geckoID, ok := ps.coinIDMap[upperTicker]; ok {
+		return geckoID
+	}
+	return strings.ToLower(upperTicker)
+}
+
 // AddCoinMapping adds a custom ticker to CoinGecko ID mapping
 func (ps *PriceService) AddCoinMapping(ticker, geckoID string) {
 	ps.coinIDMap[strings.ToUpper(ticker)] = geckoID
 }
 
+// GetLastKnownPrices returns the last fetched price of each ticker in the
+// current currency, however old, and when the oldest of them was fetched.
+// Tickers with a manual price get it instead; others never priced are left
+// out. It makes no requests, so it serves as a fallback when fetching fails.
+func (ps *PriceService) GetLastKnownPrices(tickers []string) (map[string]float64, time.Time) {
+	result := make(map[string]float64)
+	ps.applyManualPrices(tickers, result)
+	var oldest time.Time
+	ps.cacheMu.RLock()
+	defer ps.cacheMu.RUnlock()
+	for _, ticker := range tickers {
+		upperTicker := strings.ToUpper(ticker)
+		if _, manual := result[upperTicker]; manual {
+			continue
+		}
+		cached, ok := ps.cache[ps.cacheKey(upperTicker)]
+		if !ok {
+			continue
+		}
+		result[upperTicker] = cached.price
+		if oldest.IsZero() || cached.fetchedAt.Before(oldest) {
+			oldest = cached.fetchedAt
+		}
+	}
+	return result, oldest
+}
+
 // GetPrice fetches the current USD price for a single coin
 func (ps *PriceService) GetPrice(ticker string) (float64, error) {
 	prices, err := ps.GetPrices([]string{ticker})
@@ -141,29 +415,32 @@ func (ps *PriceService) GetPrice(ticker string) (float64, error) {
 	return price, nil
 }
 
-// GetPrices fetches current USD prices for multiple coins
-// Returns a map of ticker -> price
+// GetPrices fetches current prices for multiple coins in the service currency (USD by default)
+// Returns a map of ticker -> price. Tickers with a manual price get it and
+// are not fetched.
 func (ps *PriceService) GetPrices(tickers []string) (map[string]float64, error) {
 	result := make(map[string]float64)
 	var toFetch []string
 	tickerToGeckoID := make(map[string]string)
 
-	// Check cache first
+	// Manual prices first, then the cache
+	ps.applyManualPrices(tickers, result)
 	ps.cacheMu.RLock()
 	for _, ticker := range tickers {
 		upperTicker := strings.ToUpper(ticker)
-		if cached, ok := ps.cache[upperTicker]; ok {
+		if _, manual := result[upperTicker]; manual {
+			continue
+		}
+		if cached, ok := ps.cache[ps.cacheKey(upperTicker)]; ok {
 			if time.Since(cached.fetchedAt) < ps.cacheTTL {
+				slog.Debug("price cache hit", "ticker", upperTicker)
 				result[upperTicker] = cached.price
 				continue
 			}
 		}
+		slog.Debug("price cache miss", "ticker", upperTicker)
 		// Need to fetch this one
-		geckoID, ok := ps.coinIDMap[upperTicker]
-		if !ok {
-			// Try lowercase ticker as gecko ID
-			geckoID = strings.ToLower(upperTicker)
-		}
+		geckoID := ps.geckoID(upperTicker)
 		toFetch = append(toFetch, geckoID)
 		tickerToGeckoID[upperTicker] = geckoID
 	}
@@ -185,7 +462,7 @@ func (ps *PriceService) GetPrices(tickers []string) (map[string]float64, error)
 	for ticker, geckoID := range tickerToGeckoID {
 		if price, ok := prices[geckoID]; ok {
 			result[ticker] = price
-			ps.cache[ticker] = cachedPrice{
+			ps.cache[ps.cacheKey(ticker)] = cachedPrice{
 				price:     price,
 				fetchedAt: time.Now(),
 			}
@@ -193,25 +470,147 @@ func (ps *PriceService) GetPrices(tickers []string) (map[string]float64, error)
 	}
 	ps.cacheMu.Unlock()
 
+	// Persisting the cache is best-effort; a failed write only costs a refetch
+	if err := ps.saveCache(); err != nil {
+		slog.Debug("saving price cache failed", "err", err)
+	}
+
 	return result, nil
 }
 
-// fetchFromCoinGecko fetches prices from the CoinGecko API
+// SetManualPrice sets the USD price used for a ticker instead of fetching
+// one, e.g. for a pre-launch token or delisted asset CoinGecko has no price
+// for.
+func (ps *PriceService) SetManualPrice(ticker string, usdPrice float64) {
+	ps.manual[strings.ToUpper(ticker)] = usdPrice
+}
+
+// applyManualPrices adds the manual price of each ticker that has one and
+// is missing from result, converted to the current currency. Manual prices are left out
+// if the exchange rate can't be fetched.
+func (ps *PriceService) applyManualPrices(tickers []string, result map[string]float64) {
+	rate := 0.0
+	for _, ticker := range tickers {
+		upperTicker := strings.ToUpper(ticker)
+		price, ok := ps.manual[upperTicker]
+		if _, priced := result[upperTicker]; priced || !ok {
+			continue
+		}
+		if rate == 0 {
+			var err error
+			if rate, err = ps.GetExchangeRate(ps.currency); err != nil {
+				return
+			}
+		}
+		result[upperTicker] = price * rate
+	}
+}
+
+// Limits on a single simple/price request. CoinGecko rejects or truncates
+// requests with too many IDs or too long a URL, so larger lists are split
+// into chunks fetched by up to fetchWorkers requests at a time.
+const (
+	maxIDsPerRequest = 100
+	maxIDsLength     = 1500 // Bytes of the comma-joined ids parameter
+	fetchWorkers     = 4
+)
+
+// fetchFromCoinGecko fetches prices from the CoinGecko API, splitting long
+// ID lists into chunks fetched concurrently. It fails if any chunk fails.
 func (ps *PriceService) fetchFromCoinGecko(geckoIDs []string) (map[string]float64, error) {
-	if len(geckoIDs) == 0 {
+	chunks := chunkIDs(geckoIDs, ps.chunkSize, maxIDsLength)
+	switch len(chunks) {
+	case 0:
 		return make(map[string]float64), nil
+	case 1:
+		return ps.fetchChunk(chunks[0])
 	}
 
+	type chunkResult struct {
+		prices map[string]float64
+		err    error
+	}
+	jobs := make(chan []string)
+	results := make(chan chunkResult)
+	var wg sync.WaitGroup
+	for range min(fetchWorkers, len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				prices, err := ps.fetchChunk(chunk)
+				results <- chunkResult{prices, err}
+			}
+		}()
+	}
+	go func() {
+		for _, chunk := range chunks {
+			jobs <- chunk
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	result := make(map[string]float64)
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		for id, price := range r.prices {
+			result[id] = price
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// chunkIDs splits IDs, without duplicates, into chunks of at most maxCount
+// IDs whose comma-joined length stays within maxLength
+func chunkIDs(geckoIDs []string, maxCount, maxLength int) [][]string {
+	var chunks [][]string
+	var chunk []string
+	length := 0
+	seen := make(map[string]bool)
+	for _, id := range geckoIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if len(chunk) > 0 && (len(chunk) == maxCount || length+1+len(id) > maxLength) {
+			chunks = append(chunks, chunk)
+			chunk, length = nil, 0
+		}
+		if len(chunk) > 0 {
+			length++
+		}
+		chunk = append(chunk, id)
+		length += len(id)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// fetchChunk fetches prices for IDs in a single simple/price request
+func (ps *PriceService) fetchChunk(geckoIDs []string) (map[string]float64, error) {
 	// Build URL
 	baseURL := "https://api.coingecko.com/api/v3/simple/price"
 	params := url.Values{}
 	params.Set("ids", strings.Join(geckoIDs, ","))
-	params.Set("vs_currencies", "usd")
+	params.Set("vs_currencies", ps.currency)
 
 	reqURL := baseURL + "?" + params.Encode()
 
 	// Make request
-	resp, err := ps.client.Get(reqURL)
+	resp, err := ps.get(reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch prices: %w", err)
 	}
@@ -228,22 +627,193 @@ func (ps *PriceService) fetchFromCoinGecko(geckoIDs []string) (map[string]float6
 		return nil, fmt.Errorf("failed to parse price response: %w", err)
 	}
 
-	// Extract USD prices
+	// Extract prices in the requested currency
 	result := make(map[string]float64)
 	for geckoID, currencies := range data {
-		if usdPrice, ok := currencies["usd"]; ok {
-			result[geckoID] = usdPrice
+		if price, ok := currencies[ps.currency]; ok {
+			result[geckoID] = price
 		}
 	}
 
 	return result, nil
 }
 
+// MarketData is a coin's current market figures in the service currency.
+type MarketData struct {
+	Price     float64
+	Change24h float64 // Percent price change over the last 24 hours
+	Change7d  float64 // Percent price change over the last 7 days
+	MarketCap float64
+}
+
+// GetMarketData fetches current price, 24h and 7d change, and market cap for multiple
+// coins. Market data is not cached since it is only used for display.
+// Coins CoinGecko does not know are omitted from the result.
+func (ps *PriceService) GetMarketData(tickers []string) (map[string]MarketData, error) {
+	result := make(map[string]MarketData)
+	if len(tickers) == 0 {
+		return result, nil
+	}
+
+	tickerToGeckoID := make(map[string]string)
+	var geckoIDs []string
+	for _, ticker := range tickers {
+		upperTicker := strings.ToUpper(ticker)
+		geckoID := ps.geckoID(upperTicker)
+		tickerToGeckoID[upperTicker] = geckoID
+		geckoIDs = append(geckoIDs, geckoID)
+	}
+
+	params := url.Values{}
+	params.Set("vs_currency", ps.currency)
+	params.Set("ids", strings.Join(geckoIDs, ","))
+	params.Set("price_change_percentage", "24h,7d")
+
+	resp, err := ps.get("https://api.coingecko.com/api/v3/coins/markets?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: [{"id":"bitcoin","current_price":97000,"market_cap":1.9e12,
+	// "price_change_percentage_24h_in_currency":-1.2,"price_change_percentage_7d_in_currency":3.4},...]
+	var data []struct {
+		ID           string   `json:"id"`
+		CurrentPrice float64  `json:"current_price"`
+		MarketCap    float64  `json:"market_cap"`
+		Change24h    *float64 `json:"price_change_percentage_24h_in_currency"`
+		Change7d     *float64 `json:"price_change_percentage_7d_in_currency"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse market data response: %w", err)
+	}
+
+	byID := make(map[string]MarketData, len(data))
+	for _, d := range data {
+		md := MarketData{Price: d.CurrentPrice, MarketCap: d.MarketCap}
+		if d.Change24h != nil {
+			md.Change24h = *d.Change24h
+		}
+		if d.Change7d != nil {
+			md.Change7d = *d.Change7d
+		}
+		byID[d.ID] = md
+	}
+	for ticker, geckoID := range tickerToGeckoID {
+		if md, ok := byID[geckoID]; ok {
+			result[ticker] = md
+		}
+	}
+	return result, nil
+}
+
+// GetHistoricalPrices fetches prices for multiple coins on a past date in the
+// service currency, using CoinGecko's daily (00:00 UTC) history.
+// Coins CoinGecko has no data for on that date are omitted from the result.
+func (ps *PriceService) GetHistoricalPrices(date time.Time, tickers []string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	for _, ticker := range tickers {
+		upperTicker := strings.ToUpper(ticker)
+		geckoID := ps.geckoID(upperTicker)
+
+		price, found, err := ps.fetchHistoricalPrice(geckoID, date)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			result[upperTicker] = price
+		}
+	}
+	return result, nil
+}
+
+// fetchHistoricalPrice fetches a single coin's price on a date from the CoinGecko API
+func (ps *PriceService) fetchHistoricalPrice(geckoID string, date time.Time) (float64, bool, error) {
+	params := url.Values{}
+	params.Set("date", date.Format("02-01-2006"))
+	params.Set("localization", "false")
+
+	reqURL := "https://api.coingecko.com/api/v3/coins/" + url.PathEscape(geckoID) + "/history?" + params.Encode()
+
+	resp, err := ps.get(reqURL)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to fetch historical price: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: {"id":"bitcoin","market_data":{"current_price":{"usd":42000}}}
+	// market_data is absent when there is no data for the date
+	var data struct {
+		MarketData *struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, false, fmt.Errorf("failed to parse historical price response: %w", err)
+	}
+	if data.MarketData == nil {
+		return 0, false, nil
+	}
+	price, ok := data.MarketData.CurrentPrice[ps.currency]
+	return price, ok, nil
+}
+
+// GetExchangeRate returns how many units of the given fiat currency one USD buys
+func (ps *PriceService) GetExchangeRate(currency string) (float64, error) {
+	currency = strings.ToLower(currency)
+	if currency == "usd" {
+		return 1, nil
+	}
+
+	resp, err := ps.get("https://api.coingecko.com/api/v3/exchange_rates")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("CoinGecko API returned status %d", resp.StatusCode)
+	}
+
+	// Response format: {"rates":{"usd":{"value":97000},"eur":{"value":89000}}}
+	// All rates are relative to BTC
+	var data struct {
+		Rates map[string]struct {
+			Value float64 `json:"value"`
+		} `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+
+	usd, ok := data.Rates["usd"]
+	if !ok || usd.Value == 0 {
+		return 0, fmt.Errorf("exchange rate not found for USD")
+	}
+	target, ok := data.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("exchange rate not found for %s", strings.ToUpper(currency))
+	}
+	return target.Value / usd.Value, nil
+}
+
 // ClearCache clears the price cache
 func (ps *PriceService) ClearCache() {
 	ps.cacheMu.Lock()
 	ps.cache = make(map[string]cachedPrice)
 	ps.cacheMu.Unlock()
+	_ = ps.saveCache()
 }
 
 // GetCoinGeckoID returns the CoinGecko ID for a ticker, or empty string if unknown
@@ -257,6 +827,12 @@ func (ps *PriceService) HasMapping(ticker string) bool {
 	return ok
 }
 
+// HasManualPrice checks if a ticker has a manual price
+func (ps *PriceService) HasManualPrice(ticker string) bool {
+	_, ok := ps.manual[strings.ToUpper(ticker)]
+	return ok
+}
+
 // GetUnmappedTickers returns tickers that don't have a CoinGecko mapping
 func (ps *PriceService) GetUnmappedTickers(tickers []string) []string {
 	var unmapped []string
@@ -268,6 +844,46 @@ func (ps *PriceService) GetUnmappedTickers(tickers []string) []string {
 	return unmapped
 }
 
+// FindTickersForID returns the sorted tickers mapped to the given CoinGecko ID
+func (ps *PriceService) FindTickersForID(geckoID string) []string {
+	var tickers []string
+	for ticker, id := range ps.coinIDMap {
+		if id == geckoID {
+			tickers = append(tickers, ticker)
+		}
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+// GetDuplicateMappings returns CoinGecko IDs that more than one of the given
+// tickers resolve to, mapped to the sorted tickers sharing each ID.
+// Duplicates make both tickers report the same price, which silently
+// produces wrong valuations when one of the mappings is a mistake.
+func (ps *PriceService) GetDuplicateMappings(tickers []string) map[string][]string {
+	byID := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, ticker := range tickers {
+		upper := strings.ToUpper(ticker)
+		if seen[upper] {
+			continue
+		}
+		seen[upper] = true
+		if id, ok := ps.coinIDMap[upper]; ok {
+			byID[id] = append(byID[id], upper)
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for id, ids := range byID {
+		if len(ids) > 1 {
+			sort.Strings(ids)
+			duplicates[id] = ids
+		}
+	}
+	return duplicates
+}
+
 // GetDefaultMappings returns a copy of the default ticker mappings
 func GetDefaultMappings() map[string]string {
 	result := make(map[string]string)
@@ -293,7 +909,7 @@ func (ps *PriceService) SearchCoins(query string) ([]SearchResult, error) {
 
 	reqURL := baseURL + "?" + params.Encode()
 
-	resp, err := ps.client.Get(reqURL)
+	resp, err := ps.get(reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search coins: %w", err)
 	}
@@ -318,3 +934,24 @@ func (ps *PriceService) SearchCoins(query string) ([]SearchResult, error) {
 
 	return data.Coins, nil
 }
+
+// ResolveTicker searches CoinGecko for the coin a ticker stands for. The
+// match is only confident, and ok true, when exactly one search result has
+// the ticker as its symbol; otherwise the user has to choose.
+func (ps *PriceService) ResolveTicker(ticker string) (match SearchResult, ok bool, err error) {
+	results, err := ps.SearchCoins(ticker)
+	if err != nil {
+		return SearchResult{}, false, err
+	}
+	matches := 0
+	for _, r := range results {
+		if strings.EqualFold(r.Symbol, ticker) {
+			match = r
+			matches++
+		}
+	}
+	if matches != 1 {
+		return SearchResult{}, false, nil
+	}
+	return match, true, nil
+}
@@ -1,8 +1,16 @@
 package prices
 
 import (
+	"encoding/json"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -136,7 +144,9 @@ func TestCacheExpiry(t *testing.T) {
 }
 
 func TestAPIError(t *testing.T) {
+	requests := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
 		w.WriteHeader(http.StatusTooManyRequests)
 	}))
 	defer server.Close()
@@ -144,11 +154,302 @@ func TestAPIError(t *testing.T) {
 	ps := NewWithClient(&http.Client{
 		Transport: &mockTransport{server.URL},
 	})
+	ps.SetRetryPolicy(3, time.Millisecond)
+	var retries []RetryStatus
+	ps.SetRetryNotifier(func(s RetryStatus) { retries = append(retries, s) })
 
 	_, err := ps.GetPrice("BTC")
 	if err == nil {
 		t.Error("Expected error for 429 response")
 	}
+	if requests != 3 {
+		t.Errorf("Expected 3 attempts, got %d", requests)
+	}
+	if len(retries) != 2 || !retries[0].RateLimited || retries[1].Attempt != 2 || retries[1].MaxAttempts != 3 {
+		t.Errorf("Unexpected retry notifications: %+v", retries)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	var slept []time.Duration
+	ps.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	price, err := ps.GetPrice("BTC")
+	if err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+	if price != 50000 {
+		t.Errorf("Expected 50000, got %v", price)
+	}
+	if len(slept) != 1 || slept[0] != 7*time.Second {
+		t.Errorf("Expected one 7s wait, got %v", slept)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.sleep = func(time.Duration) { t.Error("Should not wait out a long Retry-After") }
+
+	if _, err := ps.GetPrice("BTC"); err == nil {
+		t.Error("Expected error when Retry-After is too long")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 attempt, got %d", requests)
+	}
+}
+
+func TestRetryAfterParsing(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 12:00:45 GMT", 45 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetPricesChunksLargeLists(t *testing.T) {
+	var mu sync.Mutex
+	var requested [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := strings.Split(r.URL.Query().Get("ids"), ",")
+		mu.Lock()
+		requested = append(requested, ids)
+		mu.Unlock()
+		prices := make(map[string]map[string]float64)
+		for i, id := range ids {
+			prices[id] = map[string]float64{"usd": float64(i + 1)}
+		}
+		json.NewEncoder(w).Encode(prices)
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.chunkSize = 2
+
+	tickers := []string{"AAA", "BBB", "CCC", "DDD", "EEE"}
+	result, err := ps.GetPrices(tickers)
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if len(requested) != 3 {
+		t.Errorf("Expected 3 requests, got %d: %v", len(requested), requested)
+	}
+	for _, ids := range requested {
+		if len(ids) > 2 {
+			t.Errorf("Request with %d IDs exceeds chunk size: %v", len(ids), ids)
+		}
+	}
+	for _, ticker := range tickers {
+		if _, ok := result[ticker]; !ok {
+			t.Errorf("Missing price for %s", ticker)
+		}
+	}
+}
+
+func TestGetPricesChunkFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("ids"), "ccc") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"aaa": {"usd": 1}, "bbb": {"usd": 2}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.chunkSize = 2
+
+	if _, err := ps.GetPrices([]string{"AAA", "BBB", "CCC"}); err == nil {
+		t.Error("Expected error when a chunk fails")
+	}
+}
+
+func TestChunkIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       []string
+		maxCount  int
+		maxLength int
+		want      [][]string
+	}{
+		{"empty", nil, 2, 100, nil},
+		{"by count", []string{"a", "b", "c"}, 2, 100, [][]string{{"a", "b"}, {"c"}}},
+		{"by length", []string{"aaaa", "bbbb", "cc"}, 10, 9, [][]string{{"aaaa", "bbbb"}, {"cc"}}},
+		{"duplicates", []string{"a", "b", "a"}, 10, 100, [][]string{{"a", "b"}}},
+		{"long id", []string{"aaaaaaaaaa", "b"}, 10, 5, [][]string{{"aaaaaaaaaa"}, {"b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkIDs(tt.ids, tt.maxCount, tt.maxLength)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLastKnownPrices(t *testing.T) {
+	ps := New()
+	old := time.Now().Add(-3 * time.Hour)
+	ps.cache[ps.cacheKey("BTC")] = cachedPrice{price: 50000, fetchedAt: old}
+	ps.cache[ps.cacheKey("ETH")] = cachedPrice{price: 3000, fetchedAt: time.Now()}
+
+	result, fetchedAt := ps.GetLastKnownPrices([]string{"btc", "ETH", "SOL"})
+	if len(result) != 2 || result["BTC"] != 50000 || result["ETH"] != 3000 {
+		t.Errorf("Unexpected prices: %v", result)
+	}
+	if !fetchedAt.Equal(old) {
+		t.Errorf("Expected oldest fetch time %v, got %v", old, fetchedAt)
+	}
+
+	ps.SetCurrency("EUR")
+	if result, fetchedAt := ps.GetLastKnownPrices([]string{"BTC"}); len(result) != 0 || !fetchedAt.IsZero() {
+		t.Errorf("Expected no EUR prices, got %v at %v", result, fetchedAt)
+	}
+}
+
+func TestManualPrices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/exchange_rates") {
+			w.Write([]byte(`{"rates": {"usd": {"value": 100}, "eur": {"value": 90}}}`))
+			return
+		}
+		// CoinGecko leaves out IDs it doesn't know
+		w.Write([]byte(`{"bitcoin": {"usd": 50000, "eur": 45000}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.SetManualPrice("xyz", 0.5)
+	ps.SetManualPrice("BTC", 1) // Manual prices take precedence
+
+	result, err := ps.GetPrices([]string{"BTC", "XYZ", "ABC"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if result["BTC"] != 1 || result["XYZ"] != 0.5 || len(result) != 2 {
+		t.Errorf("Unexpected prices: %v", result)
+	}
+
+	// Manual prices are in USD and converted to the current currency
+	ps.SetCurrency("EUR")
+	result, err = ps.GetPrices([]string{"XYZ"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if math.Abs(result["XYZ"]-0.45) > 1e-9 {
+		t.Errorf("Expected XYZ at 0.45 EUR, got %v", result["XYZ"])
+	}
+}
+
+func TestManualPricesNotFetched(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Write([]byte(`{"bitcoin": {"usd": 50000}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	if _, err := ps.GetPrice("BTC"); err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+	ps.SetManualPrice("XYZ", 0.5)
+
+	// BTC is cached and XYZ is manual, so nothing is fetched
+	result, err := ps.GetPrices([]string{"BTC", "XYZ"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if result["BTC"] != 50000 || result["XYZ"] != 0.5 {
+		t.Errorf("Unexpected prices: %v", result)
+	}
+	if callCount != 1 {
+		t.Errorf("Expected only the first BTC request, got %d", callCount)
+	}
+}
+
+func TestIsStablecoin(t *testing.T) {
+	for ticker, want := range map[string]bool{"USDC": true, "usdt": true, "DAI": true, "BTC": false, "": false} {
+		if got := IsStablecoin(ticker); got != want {
+			t.Errorf("IsStablecoin(%q) = %v, want %v", ticker, got, want)
+		}
+	}
+}
+
+func TestResolveTicker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "MUTE":
+			w.Write([]byte(`{"coins": [{"id": "mute", "name": "Mute", "symbol": "MUTE", "market_cap_rank": 900},
+				{"id": "mute-wrapped", "name": "Wrapped Mute", "symbol": "WMUTE"}]}`))
+		case "DUP":
+			w.Write([]byte(`{"coins": [{"id": "dup-one", "symbol": "dup"}, {"id": "dup-two", "symbol": "DUP"}]}`))
+		default:
+			w.Write([]byte(`{"coins": []}`))
+		}
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+
+	match, ok, err := ps.ResolveTicker("MUTE")
+	if err != nil || !ok || match.ID != "mute" {
+		t.Errorf("ResolveTicker(MUTE) = %+v, %v, %v; want mute", match, ok, err)
+	}
+	for _, ticker := range []string{"DUP", "NONE"} {
+		if _, ok, err := ps.ResolveTicker(ticker); err != nil || ok {
+			t.Errorf("ResolveTicker(%s) = %v, %v; want no confident match", ticker, ok, err)
+		}
+	}
 }
 
 func TestAddCoinMapping(t *testing.T) {
@@ -169,6 +470,286 @@ func TestAddCoinMapping(t *testing.T) {
 	}
 }
 
+func TestAddCoinMappingDoesNotLeak(t *testing.T) {
+	ps := New()
+	ps.AddCoinMapping("LEAKY", "leaky-coin")
+
+	if _, ok := GetDefaultMappings()["LEAKY"]; ok {
+		t.Error("Expected custom mapping not to modify default mappings")
+	}
+	if New().HasMapping("LEAKY") {
+		t.Error("Expected custom mapping not to be visible in a new PriceService")
+	}
+}
+
+func TestCacheFollowsMapping(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Write([]byte(`{"old-coin": {"usd": 1}, "new-coin": {"usd": 2}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.AddCoinMapping("XYZ", "old-coin")
+	if price, _ := ps.GetPrice("XYZ"); price != 1 {
+		t.Fatalf("Expected old-coin price 1, got %v", price)
+	}
+
+	// Remapping the ticker must not reuse the old coin's cached price
+	ps.AddCoinMapping("XYZ", "new-coin")
+	if price, _ := ps.GetPrice("XYZ"); price != 2 {
+		t.Errorf("Expected new-coin price 2, got %v", price)
+	}
+	if callCount != 2 {
+		t.Errorf("Expected a fetch after remapping, got %d calls", callCount)
+	}
+}
+
+func TestDuplicateMappings(t *testing.T) {
+	ps := New()
+	ps.AddCoinMapping("WBTC", "bitcoin")
+
+	tickers := ps.FindTickersForID("bitcoin")
+	if len(tickers) != 2 || tickers[0] != "BTC" || tickers[1] != "WBTC" {
+		t.Errorf("Expected [BTC WBTC], got %v", tickers)
+	}
+
+	duplicates := ps.GetDuplicateMappings([]string{"btc", "WBTC", "ETH", "UNKNOWN"})
+	if len(duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate ID, got %v", duplicates)
+	}
+	if got := duplicates["bitcoin"]; len(got) != 2 {
+		t.Errorf("Expected bitcoin to be shared by 2 tickers, got %v", got)
+	}
+
+	// Only tickers passed in are considered
+	if len(ps.GetDuplicateMappings([]string{"BTC", "ETH"})) != 0 {
+		t.Error("Expected no duplicates when only one ticker per ID is given")
+	}
+}
+
+func TestDefaultMappingsHaveNoDuplicates(t *testing.T) {
+	ps := New()
+	var tickers []string
+	for ticker := range GetDefaultMappings() {
+		tickers = append(tickers, ticker)
+	}
+	if duplicates := ps.GetDuplicateMappings(tickers); len(duplicates) != 0 {
+		t.Errorf("Expected no duplicate default mappings, got %v", duplicates)
+	}
+}
+
+func TestSetCurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("vs_currencies"); got != "eur" {
+			t.Errorf("Expected vs_currencies=eur, got %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin":{"eur":88000}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+	ps.SetCurrency("EUR")
+
+	if ps.Currency() != "EUR" {
+		t.Errorf("Expected currency EUR, got %s", ps.Currency())
+	}
+
+	price, err := ps.GetPrice("BTC")
+	if err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+	if price != 88000 {
+		t.Errorf("Expected EUR price 88000, got %f", price)
+	}
+}
+
+func TestGetExchangeRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/exchange_rates" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"rates":{"btc":{"value":1},"usd":{"value":100000},"eur":{"value":90000}}}`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+
+	rate, err := ps.GetExchangeRate("EUR")
+	if err != nil {
+		t.Fatalf("GetExchangeRate failed: %v", err)
+	}
+	if rate != 0.9 {
+		t.Errorf("Expected rate 0.9, got %f", rate)
+	}
+
+	if _, err := ps.GetExchangeRate("XYZ"); err == nil {
+		t.Error("Expected error for unknown currency")
+	}
+
+	// USD needs no request
+	rate, err = ps.GetExchangeRate("usd")
+	if err != nil || rate != 1 {
+		t.Errorf("Expected USD rate 1, got %f (err: %v)", rate, err)
+	}
+}
+
+func TestGetHistoricalPrices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("date"); got != "01-01-2024" {
+			t.Errorf("Expected date=01-01-2024, got %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/coins/bitcoin/history":
+			w.Write([]byte(`{"id":"bitcoin","market_data":{"current_price":{"usd":42000,"eur":38000}}}`))
+		case "/api/v3/coins/ethereum/history":
+			// No market data for the date
+			w.Write([]byte(`{"id":"ethereum"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices, err := ps.GetHistoricalPrices(date, []string{"btc", "ETH", "NOPE"})
+	if err != nil {
+		t.Fatalf("GetHistoricalPrices failed: %v", err)
+	}
+	if prices["BTC"] != 42000 {
+		t.Errorf("Expected BTC price 42000, got %f", prices["BTC"])
+	}
+	if _, ok := prices["ETH"]; ok {
+		t.Error("Expected ETH to be omitted without market data")
+	}
+	if _, ok := prices["NOPE"]; ok {
+		t.Error("Expected unknown coin to be omitted")
+	}
+}
+
+func TestGetMarketData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/coins/markets" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("vs_currency"); got != "usd" {
+			t.Errorf("Expected vs_currency=usd, got %s", got)
+		}
+		if got := r.URL.Query().Get("price_change_percentage"); got != "24h,7d" {
+			t.Errorf("Expected price_change_percentage=24h,7d, got %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"solana","current_price":150.5,"market_cap":70000000000,"price_change_percentage_24h_in_currency":-2.5,"price_change_percentage_7d_in_currency":8.25},` +
+			`{"id":"bitcoin","current_price":97000,"market_cap":1900000000000,"price_change_percentage_24h_in_currency":null}]`))
+	}))
+	defer server.Close()
+
+	ps := NewWithClient(&http.Client{
+		Transport: &mockTransport{server.URL},
+	})
+
+	data, err := ps.GetMarketData([]string{"sol", "BTC", "NOPE"})
+	if err != nil {
+		t.Fatalf("GetMarketData failed: %v", err)
+	}
+	sol := data["SOL"]
+	if sol.Price != 150.5 || sol.Change24h != -2.5 || sol.Change7d != 8.25 || sol.MarketCap != 70000000000 {
+		t.Errorf("Unexpected SOL market data: %+v", sol)
+	}
+	if data["BTC"].Price != 97000 || data["BTC"].Change24h != 0 {
+		t.Errorf("Unexpected BTC market data: %+v", data["BTC"])
+	}
+	if _, ok := data["NOPE"]; ok {
+		t.Error("Expected unknown coin to be omitted")
+	}
+}
+
+func TestIsSupportedCurrency(t *testing.T) {
+	if !IsSupportedCurrency("eur") {
+		t.Error("Expected EUR to be supported")
+	}
+	if IsSupportedCurrency("XYZ") {
+		t.Error("Expected XYZ to be unsupported")
+	}
+}
+
+func TestPersistentCache(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin":{"usd":97000}}`))
+	}))
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "cache", "price-cache.json")
+	client := &http.Client{Transport: &mockTransport{server.URL}}
+
+	ps1 := NewWithClient(client)
+	if err := ps1.SetCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetCacheFile failed: %v", err)
+	}
+	if _, err := ps1.GetPrice("BTC"); err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+
+	// A new service (i.e. a new process) reuses the persisted quote
+	ps2 := NewWithClient(client)
+	if err := ps2.SetCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetCacheFile failed: %v", err)
+	}
+	price, err := ps2.GetPrice("BTC")
+	if err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+	if price != 97000 {
+		t.Errorf("Expected cached price 97000, got %f", price)
+	}
+	if callCount != 1 {
+		t.Errorf("Expected 1 API call with persisted cache, got %d", callCount)
+	}
+
+	// Persisted entries still expire
+	ps3 := NewWithClient(client)
+	ps3.SetCacheTTL(0)
+	ps3.SetCacheFile(cacheFile)
+	ps3.GetPrice("BTC")
+	if callCount != 2 {
+		t.Errorf("Expected expired persisted entry to be refetched, got %d calls", callCount)
+	}
+
+	// Quotes in another currency are cached separately
+	ps2.SetCurrency("EUR")
+	ps2.GetPrices([]string{"BTC"})
+	if callCount != 3 {
+		t.Errorf("Expected a fetch for a different currency, got %d calls", callCount)
+	}
+}
+
+func TestPersistentCacheInvalidFile(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "price-cache.json")
+	os.WriteFile(cacheFile, []byte("not json"), 0644)
+
+	ps := New()
+	if err := ps.SetCacheFile(cacheFile); err == nil {
+		t.Error("Expected error for corrupt cache file")
+	}
+}
+
 func TestClearCache(t *testing.T) {
 	callCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -233,3 +814,32 @@ func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 	return http.DefaultTransport.RoundTrip(testReq)
 }
+
+func TestGetPricesLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"bitcoin":{"usd":97000.50}}`))
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	oldLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(oldLogger)
+
+	ps := NewWithClient(&http.Client{Transport: &mockTransport{server.URL}})
+	for range 2 {
+		if _, err := ps.GetPrices([]string{"BTC"}); err != nil {
+			t.Fatalf("GetPrices failed: %v", err)
+		}
+	}
+
+	out := logs.String()
+	for _, want := range []string{"price cache miss", "msg=\"price request\"", "status=200", "price cache hit"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in log, got: %s", want, out)
+		}
+	}
+	if n := strings.Count(out, "msg=\"price request\""); n != 1 {
+		t.Errorf("Expected 1 logged request, got %d", n)
+	}
+}
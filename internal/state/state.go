@@ -0,0 +1,241 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersion is the version of the archive layout itself.
+const FormatVersion = 1
+
+// manifestName is the archive entry holding the manifest.
+const manifestName = "manifest.json"
+
+// Entry describes a file to include in, or restore from, a state archive.
+type Entry struct {
+	Name          string // Name inside the archive (e.g. "portfolio.json")
+	Path          string // Location on disk
+	SchemaVersion int    // Version of the file's content format
+}
+
+// FileInfo describes an archived file in the manifest.
+type FileInfo struct {
+	Name          string `json:"name"`
+	SchemaVersion int    `json:"schema_version"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+}
+
+// Manifest describes the contents of a state archive.
+type Manifest struct {
+	FormatVersion int        `json:"format_version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Files         []FileInfo `json:"files"`
+}
+
+// File returns the manifest entry for name, if present.
+func (m Manifest) File(name string) (FileInfo, bool) {
+	for _, f := range m.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FileInfo{}, false
+}
+
+// Export writes the given entries into a gzipped tar archive at archivePath,
+// preceded by a manifest with schema versions and checksums.
+// Entries whose file does not exist are skipped. The archive is written
+// owner-only, like the files it holds.
+func Export(archivePath string, entries []Entry) (Manifest, error) {
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	contents := make(map[string][]byte)
+	for _, e := range entries {
+		data, err := os.ReadFile(e.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Manifest{}, err
+		}
+		contents[e.Name] = data
+		manifest.Files = append(manifest.Files, FileInfo{
+			Name:          e.Name,
+			SchemaVersion: e.SchemaVersion,
+			Size:          int64(len(data)),
+			SHA256:        checksum(data),
+		})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	if err := writeTarFile(tw, manifestName, manifestData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+	for _, f := range manifest.Files {
+		if err := writeTarFile(tw, f.Name, contents[f.Name], manifest.CreatedAt); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return Manifest{}, err
+	}
+
+	if dir := filepath.Dir(archivePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return manifest, writePrivate(archivePath, buf.Bytes())
+}
+
+// Read parses a state archive and verifies every file against the manifest.
+// It returns the manifest and the verified file contents keyed by name.
+func Read(archivePath string) (Manifest, map[string][]byte, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("not a state archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest Manifest
+	var haveManifest bool
+	contents := make(map[string][]byte)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("corrupt state archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("corrupt state archive: %w", err)
+		}
+		if hdr.Name == manifestName {
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return Manifest{}, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			haveManifest = true
+			continue
+		}
+		contents[hdr.Name] = data
+	}
+
+	if !haveManifest {
+		return Manifest{}, nil, fmt.Errorf("state archive has no manifest")
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return Manifest{}, nil, fmt.Errorf("state archive format version %d is newer than supported version %d", manifest.FormatVersion, FormatVersion)
+	}
+
+	for _, info := range manifest.Files {
+		data, ok := contents[info.Name]
+		if !ok {
+			return Manifest{}, nil, fmt.Errorf("state archive is missing %s", info.Name)
+		}
+		if checksum(data) != info.SHA256 {
+			return Manifest{}, nil, fmt.Errorf("checksum mismatch for %s", info.Name)
+		}
+	}
+	return manifest, contents, nil
+}
+
+// Import restores the given entries from a state archive. All files are
+// verified before anything is written, and files with a schema version newer
+// than the entry's are rejected. Existing files are kept as <path>.bak.
+// Restored files and backups are written owner-only.
+// It returns the manifest and the names of the restored entries.
+func Import(archivePath string, entries []Entry) (Manifest, []string, error) {
+	manifest, contents, err := Read(archivePath)
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	for _, e := range entries {
+		if info, ok := manifest.File(e.Name); ok && info.SchemaVersion > e.SchemaVersion {
+			return Manifest{}, nil, fmt.Errorf("%s has schema version %d, newer than supported version %d", e.Name, info.SchemaVersion, e.SchemaVersion)
+		}
+	}
+
+	var restored []string
+	for _, e := range entries {
+		data, ok := contents[e.Name]
+		if !ok {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+			return manifest, restored, err
+		}
+		if existing, err := os.ReadFile(e.Path); err == nil {
+			if err := writePrivate(e.Path+".bak", existing); err != nil {
+				return manifest, restored, err
+			}
+		}
+		if err := writePrivate(e.Path, data); err != nil {
+			return manifest, restored, err
+		}
+		restored = append(restored, e.Name)
+	}
+	return manifest, restored, nil
+}
+
+// writePrivate writes data to path readable only by the owner, tightening
+// the mode of an existing file, which WriteFile keeps.
+func writePrivate(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
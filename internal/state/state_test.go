@@ -0,0 +1,94 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	os.WriteFile(filepath.Join(src, "portfolio.json"), []byte(`{"holdings":[]}`), 0644)
+	os.WriteFile(filepath.Join(src, "config.json"), []byte(`{"ticker_mappings":{}}`), 0644)
+
+	archive := filepath.Join(t.TempDir(), "state.follyo")
+	manifest, err := Export(archive, []Entry{
+		{Name: "portfolio.json", Path: filepath.Join(src, "portfolio.json"), SchemaVersion: 1},
+		{Name: "config.json", Path: filepath.Join(src, "config.json"), SchemaVersion: 1},
+		{Name: "missing.json", Path: filepath.Join(src, "missing.json"), SchemaVersion: 1},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("Expected 2 files in manifest (missing skipped), got %d", len(manifest.Files))
+	}
+
+	// Existing file at destination should be backed up
+	os.WriteFile(filepath.Join(dst, "portfolio.json"), []byte("old"), 0644)
+
+	_, restored, err := Import(archive, []Entry{
+		{Name: "portfolio.json", Path: filepath.Join(dst, "portfolio.json"), SchemaVersion: 1},
+		{Name: "config.json", Path: filepath.Join(dst, "sub", "config.json"), SchemaVersion: 1},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Errorf("Expected 2 restored files, got %v", restored)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dst, "portfolio.json"))
+	if string(data) != `{"holdings":[]}` {
+		t.Errorf("Unexpected restored portfolio: %s", data)
+	}
+	backup, _ := os.ReadFile(filepath.Join(dst, "portfolio.json.bak"))
+	if string(backup) != "old" {
+		t.Errorf("Expected backup of previous file, got: %s", backup)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "sub", "config.json")); err != nil {
+		t.Errorf("Expected config to be restored: %v", err)
+	}
+
+	// Archives, restored files and backups are owner-only
+	for _, path := range []string{archive, filepath.Join(dst, "portfolio.json"), filepath.Join(dst, "portfolio.json.bak")} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected %s mode 0600, got %o", filepath.Base(path), perm)
+		}
+	}
+}
+
+func TestImportRejectsNewerSchema(t *testing.T) {
+	src := t.TempDir()
+	path := filepath.Join(src, "portfolio.json")
+	os.WriteFile(path, []byte(`{}`), 0644)
+
+	archive := filepath.Join(src, "state.follyo")
+	if _, err := Export(archive, []Entry{{Name: "portfolio.json", Path: path, SchemaVersion: 2}}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "portfolio.json")
+	_, _, err := Import(archive, []Entry{{Name: "portfolio.json", Path: dest, SchemaVersion: 1}})
+	if err == nil {
+		t.Fatal("Expected error for newer schema version")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written on rejected import")
+	}
+}
+
+func TestReadInvalidArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bogus.follyo")
+	os.WriteFile(path, []byte("not an archive"), 0644)
+
+	if _, _, err := Read(path); err == nil {
+		t.Error("Expected error for invalid archive")
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// MaxBackups is the number of previous versions kept next to a data file as
+// <file>.bak.1 (most recent) through <file>.bak.N.
+const MaxBackups = 3
+
+// BackupPath returns the path of the nth backup of a data file.
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// writeFileAtomic replaces path with data so that a crash leaves either the
+// old or the new contents, never a partial file. The data is written to a
+// temporary file in the same directory, synced, and renamed over path. The
+// previous contents are rotated into the backups first.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	if err := rotateBackups(path); err != nil {
+		return fmt.Errorf("rotating backups: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	slog.Info("wrote file", "path", path, "bytes", len(data))
+	return nil
+}
+
+// rotateBackups shifts existing backups up by one, dropping the oldest, and
+// saves the current contents of path as backup 1.
+func rotateBackups(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	for n := MaxBackups - 1; n >= 1; n-- {
+		err := os.Rename(BackupPath(path, n), BackupPath(path, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	backup := BackupPath(path, 1)
+	os.Remove(backup)
+	// A hard link keeps the old contents once path is replaced by rename
+	if err := os.Link(path, backup); err == nil {
+		return nil
+	}
+	return copyFile(path, backup)
+}
+
+// copyFile copies src to dst, for filesystems without hard links.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir flushes a directory entry so a rename survives a crash. It is best
+// effort: some platforms cannot open or sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestWriteFileAtomic_RotatesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "portfolio.json")
+
+	for i := 1; i <= MaxBackups+2; i++ {
+		if err := writeFileAtomic(path, []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	want := MaxBackups + 2
+	got, _ := os.ReadFile(path)
+	if string(got) != strconv.Itoa(want) {
+		t.Errorf("expected current contents %d, got %s", want, got)
+	}
+	for n := 1; n <= MaxBackups; n++ {
+		got, err := os.ReadFile(BackupPath(path, n))
+		if err != nil {
+			t.Fatalf("expected backup %d: %v", n, err)
+		}
+		if string(got) != strconv.Itoa(want-n) {
+			t.Errorf("expected backup %d to contain %d, got %s", n, want-n, got)
+		}
+	}
+	if _, err := os.Stat(BackupPath(path, MaxBackups+1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d backups", MaxBackups)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != MaxBackups+1 {
+		t.Errorf("expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestStorage_SaveKeepsBackup(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := s.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "", "", models.Date{})); err != nil {
+		t.Fatalf("AddHolding failed: %v", err)
+	}
+
+	// The backup holds the empty portfolio from before the add
+	backup := &Storage{dataPath: BackupPath(s.dataPath, 1)}
+	holdings, err := backup.GetHoldings()
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if len(holdings) != 0 {
+		t.Errorf("expected backup without holdings, got %d", len(holdings))
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// Record kinds, used as the SQLite record kind and the trash item type.
+const (
+	kindHolding   = "holding"
+	kindLoan      = "loan"
+	kindRepayment = "repayment"
+	kindSale      = "sale"
+	kindStake     = "stake"
+	kindSwap      = "swap"
+	kindTransfer  = "transfer"
+)
+
+// Backend persists portfolio records. Storage keeps them in a JSON file and
+// SQLiteStorage in a SQLite database. Removed holdings, sales, loans, and
+// stakes are moved to a trash from which they can be restored.
+type Backend interface {
+	GetHoldings() ([]models.Holding, error)
+	AddHolding(holding models.Holding) error
+	RemoveHolding(id string) (bool, error)
+
+	GetLoans() ([]models.Loan, error)
+	AddLoan(loan models.Loan) error
+	RemoveLoan(id string) (bool, error)
+
+	GetRepayments() ([]models.Repayment, error)
+	AddRepayment(repayment models.Repayment) error
+	RemoveRepayment(id string) (bool, error)
+
+	GetSales() ([]models.Sale, error)
+	AddSale(sale models.Sale) error
+	RemoveSale(id string) (bool, error)
+
+	GetStakes() ([]models.Stake, error)
+	AddStake(stake models.Stake) error
+	UpdateStake(stake models.Stake) (bool, error)
+	RemoveStake(id string) (bool, error)
+
+	GetSwaps() ([]models.Swap, error)
+	AddSwap(swap models.Swap) error
+	RemoveSwap(id string) (bool, error)
+
+	GetTransfers() ([]models.Transfer, error)
+	AddTransfer(transfer models.Transfer) error
+	RemoveTransfer(id string) (bool, error)
+
+	// RenamePlatform sets every platform matching oldName, ignoring case,
+	// to newName and returns the number of records changed.
+	RenamePlatform(oldName, newName string) (int, error)
+
+	// MigrateCoin moves every record of m.From to m.To and returns the
+	// number of records changed.
+	MigrateCoin(m CoinMigration) (int, error)
+
+	GetTrash() ([]models.TrashItem, error)
+	RestoreTrash(id string) (models.TrashItem, bool, error)
+	PurgeTrash(before time.Time) (int, error)
+}
+
+// SnapshotBackend persists portfolio snapshots. SnapshotStore keeps them in
+// a JSON file and SQLiteSnapshotStore in a SQLite database.
+type SnapshotBackend interface {
+	List() ([]models.Snapshot, error)
+	Get(id string) (models.Snapshot, bool, error)
+	Add(snapshot models.Snapshot) error
+	Update(snapshot models.Snapshot) (bool, error)
+	Remove(id string) (bool, error)
+}
+
+var (
+	_ Backend         = (*Storage)(nil)
+	_ Backend         = (*SQLiteStorage)(nil)
+	_ SnapshotBackend = (*SnapshotStore)(nil)
+	_ SnapshotBackend = (*SQLiteSnapshotStore)(nil)
+)
+
+// Copy adds every record from src to dst, e.g. to move an existing JSON
+// portfolio into a new SQLite database.
+func Copy(dst, src Backend) error {
+	holdings, err := src.GetHoldings()
+	if err != nil {
+		return err
+	}
+	for _, h := range holdings {
+		if err := dst.AddHolding(h); err != nil {
+			return err
+		}
+	}
+
+	loans, err := src.GetLoans()
+	if err != nil {
+		return err
+	}
+	for _, l := range loans {
+		if err := dst.AddLoan(l); err != nil {
+			return err
+		}
+	}
+
+	repayments, err := src.GetRepayments()
+	if err != nil {
+		return err
+	}
+	for _, r := range repayments {
+		if err := dst.AddRepayment(r); err != nil {
+			return err
+		}
+	}
+
+	sales, err := src.GetSales()
+	if err != nil {
+		return err
+	}
+	for _, s := range sales {
+		if err := dst.AddSale(s); err != nil {
+			return err
+		}
+	}
+
+	stakes, err := src.GetStakes()
+	if err != nil {
+		return err
+	}
+	for _, st := range stakes {
+		if err := dst.AddStake(st); err != nil {
+			return err
+		}
+	}
+
+	swaps, err := src.GetSwaps()
+	if err != nil {
+		return err
+	}
+	for _, sw := range swaps {
+		if err := dst.AddSwap(sw); err != nil {
+			return err
+		}
+	}
+
+	transfers, err := src.GetTransfers()
+	if err != nil {
+		return err
+	}
+	for _, t := range transfers {
+		if err := dst.AddTransfer(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopySnapshots adds every snapshot from src to dst.
+func CopySnapshots(dst, src SnapshotBackend) error {
+	snapshots, err := src.List()
+	if err != nil {
+		return err
+	}
+	for _, snap := range snapshots {
+		if err := dst.Add(snap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// CoinMigration moves records from one coin to another, e.g. after a
+// rebrand such as MATIC to POL. Amounts are multiplied by Ratio, the new
+// coins received for each old coin, and prices per coin are divided by it,
+// so cost basis and proceeds are unchanged.
+type CoinMigration struct {
+	From  string
+	To    string
+	Ratio decimal.Decimal
+}
+
+// amount converts an amount of the old coin to the new coin
+func (m CoinMigration) amount(v decimal.Decimal) decimal.Decimal {
+	return v.Mul(m.Ratio)
+}
+
+// price converts a price per old coin to a price per new coin
+func (m CoinMigration) price(v decimal.Decimal) decimal.Decimal {
+	return models.Quo(v, m.Ratio)
+}
+
+func (m CoinMigration) holding(h *models.Holding) bool {
+	if h.Coin != m.From {
+		return false
+	}
+	h.Coin = m.To
+	h.Amount = m.amount(h.Amount)
+	h.PurchasePriceUSD = m.price(h.PurchasePriceUSD)
+	return true
+}
+
+func (m CoinMigration) sale(s *models.Sale) bool {
+	if s.Coin != m.From {
+		return false
+	}
+	s.Coin = m.To
+	s.Amount = m.amount(s.Amount)
+	s.SellPriceUSD = m.price(s.SellPriceUSD)
+	return true
+}
+
+func (m CoinMigration) loan(l *models.Loan) bool {
+	if l.Coin != m.From {
+		return false
+	}
+	l.Coin = m.To
+	l.Amount = m.amount(l.Amount)
+	return true
+}
+
+// repayment converts the repayment of a loan in the old coin, given the
+// IDs of those loans
+func (m CoinMigration) repayment(r *models.Repayment, loanIDs map[string]bool) bool {
+	if !loanIDs[r.LoanID] {
+		return false
+	}
+	r.Amount = m.amount(r.Amount)
+	return true
+}
+
+func (m CoinMigration) stake(st *models.Stake) bool {
+	if st.Coin != m.From {
+		return false
+	}
+	st.Coin = m.To
+	st.Amount = m.amount(st.Amount)
+	return true
+}
+
+func (m CoinMigration) swap(sw *models.Swap) bool {
+	changed := false
+	if sw.FromCoin == m.From {
+		sw.FromCoin = m.To
+		sw.FromAmount = m.amount(sw.FromAmount)
+		changed = true
+	}
+	if sw.ToCoin == m.From {
+		sw.ToCoin = m.To
+		sw.ToAmount = m.amount(sw.ToAmount)
+		changed = true
+	}
+	return changed
+}
+
+func (m CoinMigration) transfer(t *models.Transfer) bool {
+	if t.Coin != m.From {
+		return false
+	}
+	t.Coin = m.To
+	t.Amount = m.amount(t.Amount)
+	t.Fee = m.amount(t.Fee)
+	return true
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// Damage is a problem in the data file found by Fsck: something that keeps
+// the file from loading, or makes a record unreliable
+type Damage struct {
+	Kind    string // Type of the damaged record, e.g. "holding"
+	ID      string
+	Message string
+	Repair  string // What Repair does about it
+}
+
+// recordSections are the record lists in the data file, with the type of
+// their records. The trash is left alone: its records are checked when
+// restored.
+var recordSections = []struct{ key, kind string }{
+	{"holdings", kindHolding},
+	{"loans", kindLoan},
+	{"repayments", kindRepayment},
+	{"sales", kindSale},
+	{"stakes", kindStake},
+	{"swaps", kindSwap},
+	{"transfers", kindTransfer},
+}
+
+// optionalNumbers are the numeric fields a record is valid without
+var optionalNumbers = map[string]bool{"fee_usd": true, "fee": true, "interest_rate": true, "apy": true}
+
+// nonFinite marks the NaN and infinite numbers replaced by markNonFinite
+const nonFinite = "\x00"
+
+// Fsck checks the data file for duplicate IDs, dates that can't be parsed,
+// and numbers that are NaN or infinite. Follyo never writes these, but they
+// can be left by editing the file by hand or by other tools. Fsck changes
+// nothing; see Repair.
+func (s *Storage) Fsck() ([]Damage, error) {
+	raw, err := os.ReadFile(s.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	damage, _, err := fsck(raw)
+	return damage, err
+}
+
+// Repair fixes the damage found by Fsck and saves the data file, so the
+// damaged version becomes its most recent backup. It returns the damage
+// repaired.
+func (s *Storage) Repair() ([]Damage, error) {
+	l, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	raw, err := os.ReadFile(s.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	s.version.seen(raw)
+
+	damage, repaired, err := fsck(raw)
+	if err != nil || len(damage) == 0 {
+		return damage, err
+	}
+	repaired, err = migrate(repaired, SchemaVersion, portfolioMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("data file can't be read after repair: %w", err)
+	}
+	var data PortfolioData
+	if err := json.Unmarshal(repaired, &data); err != nil {
+		return nil, fmt.Errorf("data file can't be read after repair: %w", err)
+	}
+	return damage, s.saveData(data)
+}
+
+// fsck finds the damage in raw portfolio JSON and returns it with the
+// repaired JSON. Records that can't be repaired are dropped.
+func fsck(raw []byte) ([]Damage, []byte, error) {
+	var data map[string]any
+	if err := json.Unmarshal(markNonFinite(raw), &data); err != nil {
+		return nil, nil, fmt.Errorf("data file is not valid JSON: %w", err)
+	}
+
+	var damage []Damage
+	seen := make(map[string]map[string]any)
+	for _, section := range recordSections {
+		records, ok := data[section.key].([]any)
+		if !ok {
+			continue
+		}
+		kept := records[:0]
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				kept = append(kept, r)
+				continue
+			}
+			id, _ := record["id"].(string)
+			add := func(message, repair string) {
+				damage = append(damage, Damage{Kind: section.kind, ID: id, Message: message, Repair: repair})
+			}
+
+			keep := true
+			for _, field := range slices.Sorted(maps.Keys(record)) {
+				value, ok := record[field].(string)
+				if !ok || !strings.HasPrefix(value, nonFinite) {
+					continue
+				}
+				message := fmt.Sprintf("%s %s: %s is %s", section.kind, id, field, value[len(nonFinite):])
+				if optionalNumbers[field] {
+					delete(record, field)
+					add(message, "remove "+field)
+				} else {
+					keep = false
+					add(message, fmt.Sprintf("remove the %s; the damaged file is kept as a backup", section.kind))
+				}
+			}
+			if date, ok := record["date"].(string); ok && date != "" {
+				if _, err := models.ParseDate(date); err != nil {
+					record["date"] = ""
+					add(fmt.Sprintf("%s %s: invalid date %q", section.kind, id, date), "clear the date")
+				}
+			}
+
+			if keep && id != "" {
+				if first, dup := seen[id]; !dup {
+					seen[id] = record
+				} else if reflect.DeepEqual(first, record) {
+					keep = false
+					add(fmt.Sprintf("%s %s: copy of an earlier record", section.kind, id), "remove the copy")
+				} else {
+					record["id"] = uuid.New().String()[:8]
+					add(fmt.Sprintf("%s %s: ID is used by another record", section.kind, id), "give it a new ID")
+				}
+			}
+			if keep {
+				kept = append(kept, record)
+			}
+		}
+		data[section.key] = kept
+	}
+
+	repaired, err := json.Marshal(data)
+	return damage, repaired, err
+}
+
+// markNonFinite replaces the NaN, Infinity, and -Infinity literals written
+// by some JSON encoders, which are not valid JSON, with strings starting
+// with nonFinite, so that the file can be decoded and the values found.
+func markNonFinite(raw []byte) []byte {
+	var out bytes.Buffer
+	inString, escaped := false, false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out.WriteByte(c)
+			continue
+		}
+		if c == '"' {
+			inString = true
+			out.WriteByte(c)
+			continue
+		}
+
+		literal := ""
+		for _, l := range []string{"NaN", "Infinity", "-Infinity"} {
+			if bytes.HasPrefix(raw[i:], []byte(l)) {
+				literal = l
+				break
+			}
+		}
+		if literal == "" {
+			out.WriteByte(c)
+			continue
+		}
+		out.WriteString(`"\u0000` + literal + `"`)
+		i += len(literal) - 1
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFsckAndRepair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	damaged := `{"version": 3,
+  "holdings": [
+    {"id": "h1", "coin": "BTC", "amount": 1, "purchase_price_usd": 20000, "fee_usd": NaN, "date": "2024-01-01"},
+    {"id": "h1", "coin": "BTC", "amount": 1, "purchase_price_usd": 20000, "date": "2024-01-01"},
+    {"id": "h2", "coin": "ETH", "amount": Infinity, "purchase_price_usd": 2000, "date": "2024-01-01", "notes": "NaN in a note"},
+    {"id": "h3", "coin": "SOL", "amount": 5, "purchase_price_usd": 100, "date": "yesterday"}
+  ],
+  "loans": [],
+  "sales": [
+    {"id": "h3", "coin": "SOL", "amount": 1, "sell_price_usd": 150, "date": "2024-02-01"}
+  ],
+  "stakes": []
+}`
+	if err := os.WriteFile(path, []byte(damaged), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	damage, err := s.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	want := []string{
+		"holding h1: fee_usd is NaN",
+		"holding h1: copy of an earlier record",
+		"holding h2: amount is Infinity",
+		`holding h3: invalid date "yesterday"`,
+		"sale h3: ID is used by another record",
+	}
+	if len(damage) != len(want) {
+		t.Fatalf("expected %d problems, got %+v", len(want), damage)
+	}
+	for i, d := range damage {
+		if d.Message != want[i] {
+			t.Errorf("expected %q, got %q", want[i], d.Message)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != damaged {
+		t.Error("Fsck changed the data file")
+	}
+
+	if _, err := s.Repair(); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if damage, err := s.Fsck(); err != nil || len(damage) != 0 {
+		t.Errorf("expected no damage after repair, got %+v, %v", damage, err)
+	}
+	holdings, err := s.GetHoldings()
+	if err != nil {
+		t.Fatalf("GetHoldings failed: %v", err)
+	}
+	if len(holdings) != 2 || !holdings[0].FeeUSD.IsZero() || !holdings[1].Date.IsZero() {
+		t.Errorf("expected the BTC holding without its fee and SOL without a date, got %+v", holdings)
+	}
+	sales, _ := s.GetSales()
+	if len(sales) != 1 || sales[0].ID == "h3" {
+		t.Errorf("expected the sale to get a new ID, got %+v", sales)
+	}
+	if backup, _ := os.ReadFile(BackupPath(path, 1)); string(backup) != damaged {
+		t.Error("expected the damaged file to be kept as a backup")
+	}
+}
+
+func TestMarkNonFinite(t *testing.T) {
+	got := string(markNonFinite([]byte(`{"a": NaN, "b": -Infinity, "c": "NaN \"Infinity\""}`)))
+	want := `{"a": "\u0000NaN", "b": "\u0000-Infinity", "c": "NaN \"Infinity\""}`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if _, _, err := fsck([]byte("{not json")); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("expected invalid JSON error, got %v", err)
+	}
+}
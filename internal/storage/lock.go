@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
+
+// Lock is an advisory inter-process lock backed by a file.
+type Lock struct {
+	file *os.File
+}
+
+// AcquireLock takes the lock at path, waiting until it is available.
+func AcquireLock(path string) (*Lock, error) {
+	f, err := lockFile(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{file: f}, nil
+}
+
+// TryLock takes the lock at path, returning ErrLocked if it is held elsewhere.
+func TryLock(path string) (*Lock, error) {
+	f, err := lockFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{file: f}, nil
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	return unlockFile(l.file)
+}
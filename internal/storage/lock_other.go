@@ -0,0 +1,16 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// lockFile opens path without locking; advisory locks are only
+// supported on unix systems.
+func lockFile(path string, block bool) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+}
+
+// unlockFile releases a lock taken by lockFile.
+func unlockFile(f *os.File) error {
+	return f.Close()
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	l1, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked while held, got %v", err)
+	}
+
+	if err := l1.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	l2, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("expected lock to be available after release: %v", err)
+	}
+	l2.Release()
+}
+
+// Separate Storage instances stand in for separate processes: each opens
+// its own lock file descriptor, and flock excludes them from one another.
+func TestStorage_ConcurrentWritersKeepAllRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	const writers, perWriter = 4, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := New(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for range perWriter {
+				errs <- s.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "", "", models.Date{}))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent write failed: %v", err)
+		}
+	}
+
+	s, _ := New(path)
+	holdings, err := s.GetHoldings()
+	if err != nil {
+		t.Fatalf("GetHoldings failed: %v", err)
+	}
+	if len(holdings) != writers*perWriter {
+		t.Errorf("expected %d holdings, got %d", writers*perWriter, len(holdings))
+	}
+}
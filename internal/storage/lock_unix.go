@@ -0,0 +1,37 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on path, creating it if needed.
+// With block false it returns ErrLocked instead of waiting.
+func lockFile(path string, block bool) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_EX
+	if !block {
+		how |= syscall.LOCK_NB
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// unlockFile releases a lock taken by lockFile.
+func unlockFile(f *os.File) error {
+	defer f.Close()
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
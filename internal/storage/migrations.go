@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// A migration upgrades a data file from schema version From to From+1. It
+// works on the decoded JSON rather than the Go types so that it can handle
+// renamed and removed fields.
+type migration struct {
+	From        int
+	Description string
+	Apply       func(data map[string]any) error
+}
+
+// portfolioMigrations upgrade portfolio.json. Add an entry here and bump
+// SchemaVersion whenever the file format changes.
+var portfolioMigrations = []migration{
+	{From: 1, Description: "round float noise in amounts and prices", Apply: normalizeAmounts},
+	{From: 2, Description: "normalize record dates to YYYY-MM-DD", Apply: normalizeDates},
+	{From: 3, Description: "store amounts and prices as decimal strings", Apply: decimalAmounts},
+}
+
+// snapshotMigrations upgrade snapshots.json. Add an entry here and bump
+// SnapshotSchemaVersion whenever the file format changes.
+var snapshotMigrations = []migration{}
+
+// migrate upgrades raw JSON data to the target schema version by applying
+// the registered migrations in order. Files without a version field are
+// treated as version 1, the format before versioning was added. Data that
+// is already current is returned unchanged.
+func migrate(raw []byte, target int, migrations []migration) ([]byte, error) {
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if v, ok := data["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > target {
+		return nil, fmt.Errorf("data file has schema version %d, newer than supported version %d", version, target)
+	}
+	if version == target {
+		return raw, nil
+	}
+
+	for ; version < target; version++ {
+		m, ok := findMigration(migrations, version)
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := m.Apply(data); err != nil {
+			return nil, fmt.Errorf("migrating from schema version %d (%s): %w", version, m.Description, err)
+		}
+	}
+	data["version"] = version
+	return json.Marshal(data)
+}
+
+func findMigration(migrations []migration, from int) (migration, bool) {
+	for _, m := range migrations {
+		if m.From == from {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
+
+// normalizeAmounts rounds away float noise in the numbers of every record,
+// left by versions that did their arithmetic in binary floating point (e.g.
+// a stake reduced to 0.30000000000000004).
+func normalizeAmounts(data map[string]any) error {
+	for _, value := range data {
+		records, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			for field, v := range record {
+				if f, ok := v.(float64); ok {
+					record[field] = models.Normalize(f)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeDates rewrites record dates in the canonical YYYY-MM-DD format,
+// including those of trashed records. Versions before dates were validated
+// stored them as typed, so dates are read as ParseNaturalDate reads them.
+// A date that can't be read at all is kept as it is, for fsck to report,
+// so one bad record doesn't stop the whole file from upgrading.
+func normalizeDates(data map[string]any) error {
+	for key, value := range data {
+		records, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			if key == "trash" {
+				normalizeTrashDates(record)
+				continue
+			}
+			normalizeDate(record)
+		}
+	}
+	return nil
+}
+
+// normalizeTrashDates normalizes the dates of a trashed record and its
+// repayments
+func normalizeTrashDates(item map[string]any) {
+	if record, ok := item["record"].(map[string]any); ok {
+		normalizeDate(record)
+	}
+	repayments, _ := item["repayments"].([]any)
+	for _, r := range repayments {
+		if repayment, ok := r.(map[string]any); ok {
+			normalizeDate(repayment)
+		}
+	}
+}
+
+// normalizeDate rewrites the "date" field of a single record, leaving a
+// date that can't be parsed unchanged
+func normalizeDate(record map[string]any) {
+	date, ok := record["date"].(string)
+	if !ok || date == "" {
+		return
+	}
+	if parsed, err := models.ParseNaturalDate(date); err == nil {
+		record["date"] = parsed.String()
+	}
+}
+
+// decimalFields are the record fields holding amounts, prices and fees,
+// which are stored as decimal strings from schema version 4. Rates such as
+// interest_rate and apy stay numbers.
+var decimalFields = []string{
+	"amount", "purchase_price_usd", "sell_price_usd", "fee_usd", "fee",
+	"from_amount", "to_amount", "value_usd",
+}
+
+// decimalAmounts rewrites the amounts, prices and fees of every record as
+// decimal strings, including those of trashed records and their repayments.
+// Each number is written in its shortest form, the digits it was entered
+// with.
+func decimalAmounts(data map[string]any) error {
+	for key, value := range data {
+		records, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, r := range records {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			if key == "trash" {
+				decimalTrashAmounts(record)
+				continue
+			}
+			decimalRecord(record)
+		}
+	}
+	return nil
+}
+
+// decimalTrashAmounts converts a trashed record, its repayments, and the
+// amount kept on the trash item itself
+func decimalTrashAmounts(item map[string]any) {
+	decimalRecord(item)
+	if record, ok := item["record"].(map[string]any); ok {
+		decimalRecord(record)
+	}
+	repayments, _ := item["repayments"].([]any)
+	for _, r := range repayments {
+		if repayment, ok := r.(map[string]any); ok {
+			decimalRecord(repayment)
+		}
+	}
+}
+
+// decimalRecord converts the decimal fields of a single record, leaving
+// fields that are missing or already strings unchanged
+func decimalRecord(record map[string]any) {
+	for _, field := range decimalFields {
+		if f, ok := record[field].(float64); ok {
+			record[field] = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+}
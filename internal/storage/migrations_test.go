@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMigrate_AppliesStepsInOrder(t *testing.T) {
+	var applied []int
+	step := func(from int) migration {
+		return migration{From: from, Description: "test", Apply: func(data map[string]any) error {
+			applied = append(applied, from)
+			data["step"] = float64(from)
+			return nil
+		}}
+	}
+	// Registered out of order on purpose
+	migrations := []migration{step(2), step(1), step(3)}
+
+	out, err := migrate([]byte(`{"holdings":[]}`), 4, migrations)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if len(applied) != 3 || applied[0] != 1 || applied[1] != 2 || applied[2] != 3 {
+		t.Errorf("expected steps 1, 2, 3, got %v", applied)
+	}
+
+	var data map[string]any
+	json.Unmarshal(out, &data)
+	if data["version"] != float64(4) {
+		t.Errorf("expected version 4, got %v", data["version"])
+	}
+	if data["step"] != float64(3) {
+		t.Errorf("expected last step to run last, got %v", data["step"])
+	}
+}
+
+func TestMigrate_CurrentVersionUnchanged(t *testing.T) {
+	raw := []byte(`{"version":2,"holdings":[{"amount":0.30000000000000004}]}`)
+	out, err := migrate(raw, 2, portfolioMigrations)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if string(out) != string(raw) {
+		t.Errorf("expected current data unchanged, got %s", out)
+	}
+}
+
+func TestMigrate_Errors(t *testing.T) {
+	if _, err := migrate([]byte(`{"version":5}`), 2, portfolioMigrations); err == nil ||
+		!strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("expected error for newer schema version, got %v", err)
+	}
+
+	if _, err := migrate([]byte(`{}`), SchemaVersion+1, portfolioMigrations); err == nil ||
+		!strings.Contains(err.Error(), fmt.Sprintf("no migration from schema version %d", SchemaVersion)) {
+		t.Errorf("expected error for missing migration, got %v", err)
+	}
+
+	failing := []migration{{From: 1, Description: "broken", Apply: func(map[string]any) error {
+		return errors.New("boom")
+	}}}
+	if _, err := migrate([]byte(`{}`), 2, failing); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected migration error, got %v", err)
+	}
+}
+
+func TestMigration_V1NormalizeAmounts(t *testing.T) {
+	raw := []byte(`{"holdings":[{"id":"h1","coin":"BTC","amount":0.30000000000000004,"purchase_price_usd":1234.5600000000002}],` +
+		`"stakes":[{"id":"s1","amount":0.19999999999999998,"apy":4.5}]}`)
+
+	out, err := migrate(raw, 2, portfolioMigrations)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	var data PortfolioData
+	if err := json.Unmarshal(out, &data); err != nil {
+		t.Fatalf("failed to decode migrated data: %v", err)
+	}
+	if data.Version != 2 {
+		t.Errorf("expected version 2, got %d", data.Version)
+	}
+	if !data.Holdings[0].Amount.Equal(dec(0.3)) {
+		t.Errorf("expected amount 0.3, got %v", data.Holdings[0].Amount)
+	}
+	if !data.Holdings[0].PurchasePriceUSD.Equal(dec(1234.56)) {
+		t.Errorf("expected price rounded to 12 places, got %v", data.Holdings[0].PurchasePriceUSD)
+	}
+	if data.Holdings[0].Coin != "BTC" {
+		t.Errorf("expected other fields preserved, got coin %q", data.Holdings[0].Coin)
+	}
+	if !data.Stakes[0].Amount.Equal(dec(0.2)) || *data.Stakes[0].APY != 4.5 {
+		t.Errorf("expected stake 0.2 at 4.5%%, got %+v", data.Stakes[0])
+	}
+}
+
+func TestMigration_V2NormalizeDates(t *testing.T) {
+	raw := []byte(`{"version":2,"holdings":[{"id":"h1","amount":1,"date":"2024-1-5"}],` +
+		`"sales":[{"id":"s1","amount":1,"date":"2024/02/01"}],` +
+		`"trash":[{"id":"l1","type":"loan","record":{"id":"l1","date":"2024-3-1"},"repayments":[{"id":"r1","date":"2024-03-02T10:00:00Z"}]}]}`)
+
+	out, err := migrate(raw, 3, portfolioMigrations)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	var data map[string]any
+	json.Unmarshal(out, &data)
+	date := func(record any) any { return record.(map[string]any)["date"] }
+	trashed := data["trash"].([]any)[0].(map[string]any)
+
+	for name, got := range map[string]any{
+		"holding":   date(data["holdings"].([]any)[0]),
+		"sale":      date(data["sales"].([]any)[0]),
+		"trashed":   date(trashed["record"]),
+		"repayment": date(trashed["repayments"].([]any)[0]),
+	} {
+		want := map[string]string{"holding": "2024-01-05", "sale": "2024-02-01", "trashed": "2024-03-01", "repayment": "2024-03-02"}[name]
+		if got != want {
+			t.Errorf("expected %s date %s, got %v", name, want, got)
+		}
+	}
+
+	// Dates as typed are read like the date flags; others are kept for fsck
+	legacy := []byte(`{"version":2,"holdings":[{"id":"h1","date":"2024-13-45"},{"id":"h2","date":"5 January 2024"}]}`)
+	out, err = migrate(legacy, 3, portfolioMigrations)
+	if err != nil {
+		t.Fatalf("migrate failed on an invalid date: %v", err)
+	}
+	json.Unmarshal(out, &data)
+	holdings := data["holdings"].([]any)
+	if got := date(holdings[0]); got != "2024-13-45" {
+		t.Errorf("expected the invalid date kept, got %v", got)
+	}
+	if got := date(holdings[1]); got != "2024-01-05" {
+		t.Errorf("expected 2024-01-05, got %v", got)
+	}
+}
+
+func TestMigration_V3DecimalAmounts(t *testing.T) {
+	raw := []byte(`{"version":3,"holdings":[{"id":"h1","amount":0.1,"purchase_price_usd":65000.12,"fee_usd":1.5}],` +
+		`"stakes":[{"id":"s1","amount":32,"apy":3.5}],` +
+		`"swaps":[{"id":"w1","from_amount":1e-8,"to_amount":2,"value_usd":"10.5"}],` +
+		`"trash":[{"id":"l1","type":"loan","amount":500,"record":{"id":"l1","amount":500},"repayments":[{"id":"r1","amount":0.25}]}]}`)
+
+	out, err := migrate(raw, 4, portfolioMigrations)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	var data map[string]any
+	json.Unmarshal(out, &data)
+	record := func(v any) map[string]any { return v.(map[string]any) }
+	holding := record(data["holdings"].([]any)[0])
+	stake := record(data["stakes"].([]any)[0])
+	swap := record(data["swaps"].([]any)[0])
+	trashed := record(data["trash"].([]any)[0])
+
+	for name, got := range map[string]any{
+		"amount":           holding["amount"],
+		"price":            holding["purchase_price_usd"],
+		"fee":              holding["fee_usd"],
+		"stake amount":     stake["amount"],
+		"from amount":      swap["from_amount"],
+		"value":            swap["value_usd"],
+		"trash amount":     trashed["amount"],
+		"trashed amount":   record(trashed["record"])["amount"],
+		"repayment amount": record(trashed["repayments"].([]any)[0])["amount"],
+	} {
+		want := map[string]string{"amount": "0.1", "price": "65000.12", "fee": "1.5", "stake amount": "32",
+			"from amount": "0.00000001", "value": "10.5", "trash amount": "500", "trashed amount": "500",
+			"repayment amount": "0.25"}[name]
+		if got != want {
+			t.Errorf("expected %s %q, got %v", name, want, got)
+		}
+	}
+	// Rates stay numbers
+	if stake["apy"] != 3.5 {
+		t.Errorf("expected apy kept as a number, got %v", stake["apy"])
+	}
+
+	var decoded PortfolioData
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("failed to decode migrated data: %v", err)
+	}
+	if !decoded.Holdings[0].Amount.Equal(decimal.RequireFromString("0.1")) {
+		t.Errorf("expected amount 0.1, got %v", decoded.Holdings[0].Amount)
+	}
+}
+
+func TestStorage_InvalidLegacyDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	legacy := `{"version":2,"holdings":[{"id":"h1","coin":"BTC","amount":1,"purchase_price_usd":20000,"date":"2024-13-45"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := s.GetHoldings(); err == nil || !strings.Contains(err.Error(), "follyo fsck") {
+		t.Errorf("expected an error pointing to fsck, got %v", err)
+	}
+	damage, err := s.Fsck()
+	if err != nil || len(damage) != 1 || damage[0].Message != `holding h1: invalid date "2024-13-45"` {
+		t.Fatalf("expected the invalid date reported, got %+v, %v", damage, err)
+	}
+	if _, err := s.Repair(); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if holdings, err := s.GetHoldings(); err != nil || len(holdings) != 1 {
+		t.Errorf("expected the holding to load after repair, got %+v, %v", holdings, err)
+	}
+}
+
+func TestStorage_SavesSchemaVersion(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	raw, err := os.ReadFile(s.dataPath)
+	if err != nil {
+		t.Fatalf("failed to read data file: %v", err)
+	}
+	var data PortfolioData
+	json.Unmarshal(raw, &data)
+	if data.Version != SchemaVersion {
+		t.Errorf("expected new file at version %d, got %d", SchemaVersion, data.Version)
+	}
+}
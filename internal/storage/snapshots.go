@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// SnapshotSchemaVersion is the version of the snapshot data file format.
+const SnapshotSchemaVersion = 1
+
+// SnapshotData represents the structure of the snapshots JSON file.
+type SnapshotData struct {
+	Version   int               `json:"version"`
+	Snapshots []models.Snapshot `json:"snapshots"`
+}
+
+// SnapshotStore handles persistence of portfolio snapshots to JSON.
+type SnapshotStore struct {
+	dataPath string
+	version  fileVersion
+}
+
+// NewSnapshotStore creates a new SnapshotStore instance.
+func NewSnapshotStore(dataPath string) (*SnapshotStore, error) {
+	ss := &SnapshotStore{dataPath: dataPath}
+	if err := os.MkdirAll(filepath.Dir(dataPath), 0755); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func (ss *SnapshotStore) loadData() (SnapshotData, error) {
+	data := SnapshotData{Snapshots: []models.Snapshot{}}
+
+	file, err := os.ReadFile(ss.dataPath)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	ss.version.seen(file)
+
+	file, err = migrate(file, SnapshotSchemaVersion, snapshotMigrations)
+	if err != nil {
+		return data, err
+	}
+
+	err = json.Unmarshal(file, &data)
+	return data, err
+}
+
+func (ss *SnapshotStore) saveData(data SnapshotData) error {
+	data.Version = SnapshotSchemaVersion
+	file, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ss.version.check(ss.dataPath); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(ss.dataPath, file, 0644); err != nil {
+		return err
+	}
+	ss.version.seen(file)
+	return nil
+}
+
+// List returns all snapshots sorted by timestamp, oldest first.
+func (ss *SnapshotStore) List() ([]models.Snapshot, error) {
+	data, err := ss.loadData()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(data.Snapshots, func(i, j int) bool {
+		return data.Snapshots[i].Timestamp.Before(data.Snapshots[j].Timestamp)
+	})
+	return data.Snapshots, nil
+}
+
+// Get returns a snapshot by ID.
+func (ss *SnapshotStore) Get(id string) (models.Snapshot, bool, error) {
+	data, err := ss.loadData()
+	if err != nil {
+		return models.Snapshot{}, false, err
+	}
+	for _, snap := range data.Snapshots {
+		if snap.ID == id {
+			return snap, true, nil
+		}
+	}
+	return models.Snapshot{}, false, nil
+}
+
+// lock serializes read-modify-write cycles across processes, so a
+// background daemon and interactive commands don't lose each other's writes.
+func (ss *SnapshotStore) lock() (*Lock, error) {
+	return AcquireLock(ss.dataPath + ".lock")
+}
+
+// Add adds a new snapshot.
+func (ss *SnapshotStore) Add(snapshot models.Snapshot) error {
+	l, err := ss.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	data, err := ss.loadData()
+	if err != nil {
+		return err
+	}
+	data.Snapshots = append(data.Snapshots, snapshot)
+	return ss.saveData(data)
+}
+
+// Update replaces the snapshot with the same ID.
+func (ss *SnapshotStore) Update(snapshot models.Snapshot) (bool, error) {
+	l, err := ss.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := ss.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	for i, snap := range data.Snapshots {
+		if snap.ID == snapshot.ID {
+			data.Snapshots[i] = snapshot
+			return true, ss.saveData(data)
+		}
+	}
+	return false, nil
+}
+
+// Remove removes a snapshot by ID.
+func (ss *SnapshotStore) Remove(id string) (bool, error) {
+	l, err := ss.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := ss.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	originalLen := len(data.Snapshots)
+	filtered := make([]models.Snapshot, 0, len(data.Snapshots))
+	for _, snap := range data.Snapshots {
+		if snap.ID != id {
+			filtered = append(filtered, snap)
+		}
+	}
+	data.Snapshots = filtered
+
+	if len(data.Snapshots) < originalLen {
+		return true, ss.saveData(data)
+	}
+	return false, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	ss, err := NewSnapshotStore(filepath.Join(t.TempDir(), "data", "snapshots.json"))
+	if err != nil {
+		t.Fatalf("NewSnapshotStore failed: %v", err)
+	}
+
+	// Empty store before anything is saved
+	snapshots, err := ss.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("expected 0 snapshots, got %d", len(snapshots))
+	}
+
+	newer := models.NewSnapshot(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "")
+	newer.NetValue = 2000
+	older := models.NewSnapshot(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "start")
+	older.NetValue = 1000
+
+	if err := ss.Add(newer); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := ss.Add(older); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	snapshots, err = ss.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+	if snapshots[0].ID != older.ID {
+		t.Error("expected snapshots to be sorted oldest first")
+	}
+
+	got, found, err := ss.Get(older.ID)
+	if err != nil || !found {
+		t.Fatalf("Get failed: found=%v err=%v", found, err)
+	}
+	if got.NetValue != 1000 || got.Note != "start" {
+		t.Errorf("unexpected snapshot: %+v", got)
+	}
+
+	older.Note = "first snapshot"
+	updated, err := ss.Update(older)
+	if err != nil || !updated {
+		t.Fatalf("Update failed: updated=%v err=%v", updated, err)
+	}
+	got, _, _ = ss.Get(older.ID)
+	if got.Note != "first snapshot" || got.NetValue != 1000 {
+		t.Errorf("expected updated note, got %+v", got)
+	}
+	if updated, _ := ss.Update(models.NewSnapshot(time.Now(), "")); updated {
+		t.Error("expected update of unknown snapshot to return false")
+	}
+
+	removed, err := ss.Remove(older.ID)
+	if err != nil || !removed {
+		t.Fatalf("Remove failed: removed=%v err=%v", removed, err)
+	}
+	removed, _ = ss.Remove("nonexistent")
+	if removed {
+		t.Error("expected remove of nonexistent snapshot to return false")
+	}
+
+	_, found, _ = ss.Get(older.ID)
+	if found {
+		t.Error("expected removed snapshot to be gone")
+	}
+}
@@ -0,0 +1,711 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// Records are stored as their JSON encoding, so model fields can be added
+// without altering tables. Coin and date are copied into indexed columns for
+// queries. seq preserves insertion order, matching the JSON backend.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS records (
+	seq  INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL,
+	id   TEXT NOT NULL,
+	coin TEXT NOT NULL DEFAULT '',
+	date TEXT NOT NULL DEFAULT '',
+	data TEXT NOT NULL,
+	UNIQUE (kind, id)
+);
+CREATE INDEX IF NOT EXISTS records_kind_coin ON records (kind, coin);
+CREATE INDEX IF NOT EXISTS records_kind_date ON records (kind, date);
+
+CREATE TABLE IF NOT EXISTS trash (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	id         TEXT NOT NULL,
+	deleted_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS trash_id ON trash (id);
+
+CREATE TABLE IF NOT EXISTS snapshots (
+	id        TEXT PRIMARY KEY,
+	timestamp INTEGER NOT NULL,
+	data      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS snapshots_timestamp ON snapshots (timestamp);
+`
+
+// openSQLite opens (creating if needed) the database at path. WAL mode and a
+// busy timeout let the daemon and interactive commands use it concurrently.
+func openSQLite(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing database: %w", err)
+	}
+	return db, nil
+}
+
+// SQLiteStorage handles persistence of portfolio data to a SQLite database.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLite creates a new SQLiteStorage backed by the database at path.
+func NewSQLite(path string) (*SQLiteStorage, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStorage{db: db}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+func getRecords[T any](db *sql.DB, kind string) ([]T, error) {
+	rows, err := db.Query(`SELECT data FROM records WHERE kind = ? ORDER BY seq`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []T{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var record T
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", kind, err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertRecord(ex execer, kind, id, coin, date string, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = ex.Exec(`INSERT INTO records (kind, id, coin, date, data) VALUES (?, ?, ?, ?, ?)`,
+		kind, id, coin, date, string(data))
+	return err
+}
+
+func (s *SQLiteStorage) addRecord(kind, id, coin, date string, record any) error {
+	slog.Info("sqlite insert", "kind", kind, "id", id)
+	return insertRecord(s.db, kind, id, coin, date, record)
+}
+
+func (s *SQLiteStorage) updateRecord(kind, id, coin, date string, record any) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	slog.Info("sqlite update", "kind", kind, "id", id)
+	res, err := s.db.Exec(`UPDATE records SET coin = ?, date = ?, data = ? WHERE kind = ? AND id = ?`,
+		coin, date, string(data), kind, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLiteStorage) removeRecord(kind, id string) (bool, error) {
+	slog.Info("sqlite delete", "kind", kind, "id", id)
+	res, err := s.db.Exec(`DELETE FROM records WHERE kind = ? AND id = ?`, kind, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// trashRecord moves a record from the records table to the trash. A loan's
+// repayments are moved with it.
+func (s *SQLiteStorage) trashRecord(kind, id string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRow(`SELECT data FROM records WHERE kind = ? AND id = ?`, kind, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	item, err := models.NewTrashItem(kind, id, json.RawMessage(data))
+	if err != nil {
+		return false, err
+	}
+
+	if kind == kindLoan {
+		rows, err := tx.Query(`SELECT data FROM records WHERE kind = ? AND json_extract(data, '$.loan_id') = ? ORDER BY seq`,
+			kindRepayment, id)
+		if err != nil {
+			return false, err
+		}
+		for rows.Next() {
+			var r models.Repayment
+			var rdata string
+			if err := rows.Scan(&rdata); err != nil {
+				rows.Close()
+				return false, err
+			}
+			if err := json.Unmarshal([]byte(rdata), &r); err != nil {
+				rows.Close()
+				return false, err
+			}
+			item.Repayments = append(item.Repayments, r)
+		}
+		rows.Close()
+		if _, err := tx.Exec(`DELETE FROM records WHERE kind = ? AND json_extract(data, '$.loan_id') = ?`,
+			kindRepayment, id); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM records WHERE kind = ? AND id = ?`, kind, id); err != nil {
+		return false, err
+	}
+	itemData, err := json.Marshal(item)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`INSERT INTO trash (id, deleted_at, data) VALUES (?, ?, ?)`,
+		id, item.DeletedAt.UnixNano(), string(itemData)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Holdings operations
+
+// GetHoldings returns all holdings.
+func (s *SQLiteStorage) GetHoldings() ([]models.Holding, error) {
+	return getRecords[models.Holding](s.db, kindHolding)
+}
+
+// AddHolding adds a new holding.
+func (s *SQLiteStorage) AddHolding(holding models.Holding) error {
+	return s.addRecord(kindHolding, holding.ID, holding.Coin, holding.Date.String(), holding)
+}
+
+// RemoveHolding moves a holding to the trash by ID.
+func (s *SQLiteStorage) RemoveHolding(id string) (bool, error) {
+	return s.trashRecord(kindHolding, id)
+}
+
+// Loans operations
+
+// GetLoans returns all loans.
+func (s *SQLiteStorage) GetLoans() ([]models.Loan, error) {
+	return getRecords[models.Loan](s.db, kindLoan)
+}
+
+// AddLoan adds a new loan.
+func (s *SQLiteStorage) AddLoan(loan models.Loan) error {
+	return s.addRecord(kindLoan, loan.ID, loan.Coin, loan.Date.String(), loan)
+}
+
+// RemoveLoan moves a loan to the trash by ID, along with its repayments.
+func (s *SQLiteStorage) RemoveLoan(id string) (bool, error) {
+	return s.trashRecord(kindLoan, id)
+}
+
+// Repayments operations
+
+// GetRepayments returns all loan repayments.
+func (s *SQLiteStorage) GetRepayments() ([]models.Repayment, error) {
+	return getRecords[models.Repayment](s.db, kindRepayment)
+}
+
+// AddRepayment adds a new loan repayment.
+func (s *SQLiteStorage) AddRepayment(repayment models.Repayment) error {
+	return s.addRecord(kindRepayment, repayment.ID, "", repayment.Date.String(), repayment)
+}
+
+// RemoveRepayment removes a loan repayment by ID.
+func (s *SQLiteStorage) RemoveRepayment(id string) (bool, error) {
+	return s.removeRecord(kindRepayment, id)
+}
+
+// Sales operations
+
+// GetSales returns all sales.
+func (s *SQLiteStorage) GetSales() ([]models.Sale, error) {
+	return getRecords[models.Sale](s.db, kindSale)
+}
+
+// AddSale adds a new sale.
+func (s *SQLiteStorage) AddSale(sale models.Sale) error {
+	return s.addRecord(kindSale, sale.ID, sale.Coin, sale.Date.String(), sale)
+}
+
+// RemoveSale moves a sale to the trash by ID.
+func (s *SQLiteStorage) RemoveSale(id string) (bool, error) {
+	return s.trashRecord(kindSale, id)
+}
+
+// Stakes operations
+
+// GetStakes returns all stakes.
+func (s *SQLiteStorage) GetStakes() ([]models.Stake, error) {
+	return getRecords[models.Stake](s.db, kindStake)
+}
+
+// AddStake adds a new stake.
+func (s *SQLiteStorage) AddStake(stake models.Stake) error {
+	return s.addRecord(kindStake, stake.ID, stake.Coin, stake.Date.String(), stake)
+}
+
+// UpdateStake replaces the stake with the same ID.
+func (s *SQLiteStorage) UpdateStake(stake models.Stake) (bool, error) {
+	return s.updateRecord(kindStake, stake.ID, stake.Coin, stake.Date.String(), stake)
+}
+
+// RemoveStake moves a stake to the trash by ID.
+func (s *SQLiteStorage) RemoveStake(id string) (bool, error) {
+	return s.trashRecord(kindStake, id)
+}
+
+// Swaps operations
+
+// GetSwaps returns all swaps.
+func (s *SQLiteStorage) GetSwaps() ([]models.Swap, error) {
+	return getRecords[models.Swap](s.db, kindSwap)
+}
+
+// AddSwap adds a new swap.
+func (s *SQLiteStorage) AddSwap(swap models.Swap) error {
+	return s.addRecord(kindSwap, swap.ID, swap.FromCoin, swap.Date.String(), swap)
+}
+
+// RemoveSwap removes a swap by ID.
+func (s *SQLiteStorage) RemoveSwap(id string) (bool, error) {
+	return s.removeRecord(kindSwap, id)
+}
+
+// Transfers operations
+
+// GetTransfers returns all transfers.
+func (s *SQLiteStorage) GetTransfers() ([]models.Transfer, error) {
+	return getRecords[models.Transfer](s.db, kindTransfer)
+}
+
+// AddTransfer adds a new transfer.
+func (s *SQLiteStorage) AddTransfer(transfer models.Transfer) error {
+	return s.addRecord(kindTransfer, transfer.ID, transfer.Coin, transfer.Date.String(), transfer)
+}
+
+// RemoveTransfer removes a transfer by ID.
+func (s *SQLiteStorage) RemoveTransfer(id string) (bool, error) {
+	return s.removeRecord(kindTransfer, id)
+}
+
+// Platform operations
+
+// platformFields are the JSON fields holding a platform, by record kind
+var platformFields = map[string][]string{
+	kindHolding:  {"platform"},
+	kindSale:     {"platform"},
+	kindLoan:     {"platform"},
+	kindStake:    {"platform"},
+	kindSwap:     {"platform"},
+	kindTransfer: {"from_platform", "to_platform"},
+}
+
+// RenamePlatform sets every platform matching oldName, ignoring case, to newName
+// across holdings, sales, loans, stakes, swaps, and transfers, and returns
+// the number of records changed.
+func (s *SQLiteStorage) RenamePlatform(oldName, newName string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type update struct{ kind, id, data string }
+	var updates []update
+	for kind, fields := range platformFields {
+		rows, err := tx.Query(`SELECT id, data FROM records WHERE kind = ?`, kind)
+		if err != nil {
+			return 0, err
+		}
+		for rows.Next() {
+			var id, data string
+			if err := rows.Scan(&id, &data); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			renamed, ok, err := renamePlatformFields(data, fields, oldName, newName)
+			if err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("decoding %s: %w", kind, err)
+			}
+			if ok {
+				updates = append(updates, update{kind, id, renamed})
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, u := range updates {
+		slog.Info("sqlite update", "kind", u.kind, "id", u.id)
+		if _, err := tx.Exec(`UPDATE records SET data = ? WHERE kind = ? AND id = ?`, u.data, u.kind, u.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), tx.Commit()
+}
+
+// renamePlatformFields sets the fields of a JSON record that match oldName,
+// ignoring case, to newName. Other fields are kept as they are encoded.
+func renamePlatformFields(data string, fields []string, oldName, newName string) (string, bool, error) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return "", false, err
+	}
+	changed := false
+	for _, field := range fields {
+		var platform string
+		if raw, ok := record[field]; !ok || json.Unmarshal(raw, &platform) != nil {
+			continue
+		}
+		if strings.EqualFold(platform, oldName) && platform != newName {
+			encoded, err := json.Marshal(newName)
+			if err != nil {
+				return "", false, err
+			}
+			record[field] = encoded
+			changed = true
+		}
+	}
+	if !changed {
+		return data, false, nil
+	}
+	encoded, err := json.Marshal(record)
+	return string(encoded), err == nil, err
+}
+
+// Coin operations
+
+// recordUpdate is a record rewritten within a transaction
+type recordUpdate struct {
+	kind, id, coin, data string
+}
+
+// migrateRows decodes the records of a kind within tx and returns updates
+// for those that migrate changes, with coin giving a record's coin column
+func migrateRows[T any](tx *sql.Tx, kind string, migrate func(*T) bool, coin func(T) string) ([]recordUpdate, error) {
+	rows, err := tx.Query(`SELECT id, data FROM records WHERE kind = ? ORDER BY seq`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updates []recordUpdate
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var record T
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", kind, err)
+		}
+		if !migrate(&record) {
+			continue
+		}
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, recordUpdate{kind, id, coin(record), string(encoded)})
+	}
+	return updates, rows.Err()
+}
+
+// MigrateCoin moves every record of m.From to m.To, converting amounts and
+// prices by m.Ratio, and returns the number of records changed. Repayments
+// of loans in m.From are converted too.
+func (s *SQLiteStorage) MigrateCoin(m CoinMigration) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	loans, err := migrateRows(tx, kindLoan, m.loan, func(l models.Loan) string { return l.Coin })
+	if err != nil {
+		return 0, err
+	}
+	loanIDs := make(map[string]bool)
+	for _, u := range loans {
+		loanIDs[u.id] = true
+	}
+
+	updates := loans
+	migrations := []func() ([]recordUpdate, error){
+		func() ([]recordUpdate, error) {
+			return migrateRows(tx, kindHolding, m.holding, func(h models.Holding) string { return h.Coin })
+		},
+		func() ([]recordUpdate, error) {
+			return migrateRows(tx, kindSale, m.sale, func(s models.Sale) string { return s.Coin })
+		},
+		func() ([]recordUpdate, error) {
+			repayment := func(r *models.Repayment) bool { return m.repayment(r, loanIDs) }
+			return migrateRows(tx, kindRepayment, repayment, func(models.Repayment) string { return "" })
+		},
+		func() ([]recordUpdate, error) {
+			return migrateRows(tx, kindStake, m.stake, func(st models.Stake) string { return st.Coin })
+		},
+		func() ([]recordUpdate, error) {
+			return migrateRows(tx, kindSwap, m.swap, func(sw models.Swap) string { return sw.FromCoin })
+		},
+		func() ([]recordUpdate, error) {
+			return migrateRows(tx, kindTransfer, m.transfer, func(t models.Transfer) string { return t.Coin })
+		},
+	}
+	for _, migrate := range migrations {
+		u, err := migrate()
+		if err != nil {
+			return 0, err
+		}
+		updates = append(updates, u...)
+	}
+
+	for _, u := range updates {
+		slog.Info("sqlite update", "kind", u.kind, "id", u.id)
+		if _, err := tx.Exec(`UPDATE records SET coin = ?, data = ? WHERE kind = ? AND id = ?`, u.coin, u.data, u.kind, u.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), tx.Commit()
+}
+
+// Trash operations
+
+// GetTrash returns all trashed records, oldest removal first.
+func (s *SQLiteStorage) GetTrash() ([]models.TrashItem, error) {
+	rows, err := s.db.Query(`SELECT data FROM trash ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.TrashItem{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var item models.TrashItem
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("decoding trash item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RestoreTrash moves a trashed record back by ID. It returns false if the
+// trash has no record with the ID.
+func (s *SQLiteStorage) RestoreTrash(id string) (models.TrashItem, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.TrashItem{}, false, err
+	}
+	defer tx.Rollback()
+
+	var seq int64
+	var data string
+	err = tx.QueryRow(`SELECT seq, data FROM trash WHERE id = ? ORDER BY seq LIMIT 1`, id).Scan(&seq, &data)
+	if err == sql.ErrNoRows {
+		return models.TrashItem{}, false, nil
+	}
+	if err != nil {
+		return models.TrashItem{}, false, err
+	}
+
+	var item models.TrashItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		return models.TrashItem{}, false, fmt.Errorf("decoding trash item: %w", err)
+	}
+	switch item.Type {
+	case kindHolding, kindLoan, kindSale, kindStake:
+	default:
+		return models.TrashItem{}, false, fmt.Errorf("cannot restore record of type %q", item.Type)
+	}
+
+	var fields struct {
+		Date string `json:"date"`
+	}
+	if err := json.Unmarshal(item.Record, &fields); err != nil {
+		return models.TrashItem{}, false, err
+	}
+	if err := insertRecord(tx, item.Type, item.ID, item.Coin, fields.Date, item.Record); err != nil {
+		return models.TrashItem{}, false, err
+	}
+	for _, r := range item.Repayments {
+		if err := insertRecord(tx, kindRepayment, r.ID, "", r.Date.String(), r); err != nil {
+			return models.TrashItem{}, false, err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM trash WHERE seq = ?`, seq); err != nil {
+		return models.TrashItem{}, false, err
+	}
+	return item, true, tx.Commit()
+}
+
+// PurgeTrash permanently deletes records trashed before the given time and
+// returns how many were deleted.
+func (s *SQLiteStorage) PurgeTrash(before time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM trash WHERE deleted_at < ?`, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// SQLiteSnapshotStore handles persistence of portfolio snapshots to a SQLite
+// database.
+type SQLiteSnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSnapshotStore creates a new SQLiteSnapshotStore backed by the
+// database at path. It may share the database with SQLiteStorage.
+func NewSQLiteSnapshotStore(path string) (*SQLiteSnapshotStore, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteSnapshotStore{db: db}, nil
+}
+
+// Close closes the database.
+func (ss *SQLiteSnapshotStore) Close() error {
+	return ss.db.Close()
+}
+
+// List returns all snapshots sorted by timestamp, oldest first.
+func (ss *SQLiteSnapshotStore) List() ([]models.Snapshot, error) {
+	rows, err := ss.db.Query(`SELECT data FROM snapshots ORDER BY timestamp, rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []models.Snapshot{}
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, rows.Err()
+}
+
+// Get returns a snapshot by ID.
+func (ss *SQLiteSnapshotStore) Get(id string) (models.Snapshot, bool, error) {
+	snap, err := scanSnapshot(ss.db.QueryRow(`SELECT data FROM snapshots WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return models.Snapshot{}, false, nil
+	}
+	if err != nil {
+		return models.Snapshot{}, false, err
+	}
+	return snap, true, nil
+}
+
+// Add adds a new snapshot.
+func (ss *SQLiteSnapshotStore) Add(snapshot models.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = ss.db.Exec(`INSERT INTO snapshots (id, timestamp, data) VALUES (?, ?, ?)`,
+		snapshot.ID, snapshot.Timestamp.UnixNano(), string(data))
+	return err
+}
+
+// Update replaces the snapshot with the same ID.
+func (ss *SQLiteSnapshotStore) Update(snapshot models.Snapshot) (bool, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return false, err
+	}
+	res, err := ss.db.Exec(`UPDATE snapshots SET timestamp = ?, data = ? WHERE id = ?`,
+		snapshot.Timestamp.UnixNano(), string(data), snapshot.ID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Remove removes a snapshot by ID.
+func (ss *SQLiteSnapshotStore) Remove(id string) (bool, error) {
+	res, err := ss.db.Exec(`DELETE FROM snapshots WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanSnapshot(row interface{ Scan(...any) error }) (models.Snapshot, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		return models.Snapshot{}, err
+	}
+	var snap models.Snapshot
+	if err := json.Unmarshal([]byte(data), &snap); err != nil {
+		return models.Snapshot{}, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func setupTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "portfolio.db"))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStorage_Holdings(t *testing.T) {
+	s := setupTestSQLite(t)
+
+	holdings, err := s.GetHoldings()
+	if err != nil {
+		t.Fatalf("GetHoldings failed: %v", err)
+	}
+	if len(holdings) != 0 {
+		t.Errorf("expected 0 holdings, got %d", len(holdings))
+	}
+
+	h1 := models.NewHolding("BTC", dec(1.5), dec(50000), "Binance", "DCA", models.NewDate(2024, 1, 15))
+	h2 := models.NewHolding("ETH", dec(10), dec(3000), "", "", models.NewDate(2024, 1, 10))
+	h2.FeeUSD = dec(12.5)
+	for _, h := range []models.Holding{h1, h2} {
+		if err := s.AddHolding(h); err != nil {
+			t.Fatalf("AddHolding failed: %v", err)
+		}
+	}
+
+	holdings, _ = s.GetHoldings()
+	if len(holdings) != 2 {
+		t.Fatalf("expected 2 holdings, got %d", len(holdings))
+	}
+	// Insertion order is kept, not date order
+	if !sameJSON(holdings[0], h1) || !sameJSON(holdings[1], h2) {
+		t.Errorf("expected holdings round-tripped in order, got %+v", holdings)
+	}
+
+	if err := s.AddHolding(h1); err == nil {
+		t.Error("expected error adding a duplicate ID")
+	}
+
+	removed, err := s.RemoveHolding(h1.ID)
+	if err != nil {
+		t.Fatalf("RemoveHolding failed: %v", err)
+	}
+	if !removed {
+		t.Error("expected holding to be removed")
+	}
+	removed, _ = s.RemoveHolding("nonexistent")
+	if removed {
+		t.Error("expected holding not to be removed")
+	}
+
+	holdings, _ = s.GetHoldings()
+	if len(holdings) != 1 || holdings[0].ID != h2.ID {
+		t.Errorf("expected only %s left, got %+v", h2.ID, holdings)
+	}
+}
+
+func TestSQLiteStorage_RemoveLoanCascades(t *testing.T) {
+	s := setupTestSQLite(t)
+
+	rate := 6.9
+	loan := models.NewLoan("USDT", dec(5000), "Nexo", &rate, "", models.NewDate(2024, 1, 1))
+	other := models.NewLoan("USDC", dec(1000), "Aave", nil, "", models.NewDate(2024, 1, 1))
+	s.AddLoan(loan)
+	s.AddLoan(other)
+	s.AddRepayment(models.NewRepayment(loan.ID, dec(1000), "", models.NewDate(2024, 2, 1)))
+	kept := models.NewRepayment(other.ID, dec(500), "", models.NewDate(2024, 2, 1))
+	s.AddRepayment(kept)
+
+	loans, _ := s.GetLoans()
+	if len(loans) != 2 || *loans[0].InterestRate != 6.9 {
+		t.Fatalf("expected 2 loans with rate kept, got %+v", loans)
+	}
+
+	removed, err := s.RemoveLoan(loan.ID)
+	if err != nil {
+		t.Fatalf("RemoveLoan failed: %v", err)
+	}
+	if !removed {
+		t.Error("expected loan to be removed")
+	}
+
+	repayments, _ := s.GetRepayments()
+	if len(repayments) != 1 || repayments[0].ID != kept.ID {
+		t.Errorf("expected only the other loan's repayment left, got %+v", repayments)
+	}
+
+	removed, _ = s.RemoveLoan("nonexistent")
+	if removed {
+		t.Error("expected loan not to be removed")
+	}
+}
+
+func TestSQLiteStorage_UpdateStake(t *testing.T) {
+	s := setupTestSQLite(t)
+
+	st := models.NewStake("ETH", dec(10), "Lido", nil, "", models.NewDate(2024, 3, 1))
+	s.AddStake(st)
+
+	st.Amount = dec(4)
+	updated, err := s.UpdateStake(st)
+	if err != nil {
+		t.Fatalf("UpdateStake failed: %v", err)
+	}
+	if !updated {
+		t.Error("expected stake to be updated")
+	}
+
+	stakes, _ := s.GetStakes()
+	if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(4)) {
+		t.Errorf("expected stake of 4, got %+v", stakes)
+	}
+
+	updated, _ = s.UpdateStake(models.Stake{ID: "nonexistent"})
+	if updated {
+		t.Error("expected unknown stake not to be updated")
+	}
+}
+
+func TestSQLiteStorage_RenamePlatform(t *testing.T) {
+	testRenamePlatform(t, setupTestSQLite(t))
+}
+
+func TestSQLiteStorage_MigrateCoin(t *testing.T) {
+	testMigrateCoin(t, setupTestSQLite(t))
+}
+
+func TestSQLiteStorage_OtherRecords(t *testing.T) {
+	s := setupTestSQLite(t)
+
+	sale := models.NewSale("BTC", dec(0.5), dec(60000), "Kraken", "", models.NewDate(2024, 3, 1))
+	sale.LotIDs = []string{"lot1"}
+	swap := models.NewSwap("ETH", dec(1), "BTC", dec(0.05), dec(3000), "Binance", "", models.NewDate(2024, 3, 2))
+	transfer := models.NewTransfer("BTC", dec(0.1), "Binance", "Ledger", dec(0.0001), "", models.NewDate(2024, 3, 3))
+	s.AddSale(sale)
+	s.AddSwap(swap)
+	s.AddTransfer(transfer)
+
+	sales, _ := s.GetSales()
+	swaps, _ := s.GetSwaps()
+	transfers, _ := s.GetTransfers()
+	if len(sales) != 1 || !sameJSON(sales[0], sale) {
+		t.Errorf("expected sale round-tripped, got %+v", sales)
+	}
+	if len(swaps) != 1 || !sameJSON(swaps[0], swap) {
+		t.Errorf("expected swap round-tripped, got %+v", swaps)
+	}
+	if len(transfers) != 1 || !sameJSON(transfers[0], transfer) {
+		t.Errorf("expected transfer round-tripped, got %+v", transfers)
+	}
+
+	for name, remove := range map[string]func(string) (bool, error){
+		sale.ID: s.RemoveSale, swap.ID: s.RemoveSwap, transfer.ID: s.RemoveTransfer,
+	} {
+		if removed, err := remove(name); err != nil || !removed {
+			t.Errorf("expected %s to be removed, got %v, %v", name, removed, err)
+		}
+	}
+}
+
+func TestSQLiteSnapshotStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.db")
+	ss, err := NewSQLiteSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("failed to create snapshot store: %v", err)
+	}
+	defer ss.Close()
+
+	later := models.NewSnapshot(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "later")
+	later.NetValue = 2000
+	later.CoinValues["BTC"] = models.CoinSnapshot{Amount: 1, PriceUSD: 2000, ValueUSD: 2000}
+	earlier := models.NewSnapshot(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "earlier")
+	ss.Add(later)
+	ss.Add(earlier)
+
+	snapshots, err := ss.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != earlier.ID {
+		t.Fatalf("expected snapshots oldest first, got %+v", snapshots)
+	}
+
+	got, found, err := ss.Get(later.ID)
+	if err != nil || !found {
+		t.Fatalf("expected to find snapshot, got %v, %v", found, err)
+	}
+	if got.NetValue != 2000 || got.CoinValues["BTC"].ValueUSD != 2000 || !got.Timestamp.Equal(later.Timestamp) {
+		t.Errorf("expected snapshot round-tripped, got %+v", got)
+	}
+	if _, found, _ := ss.Get("nonexistent"); found {
+		t.Error("expected unknown snapshot not to be found")
+	}
+
+	later.Note = "edited"
+	if updated, err := ss.Update(later); err != nil || !updated {
+		t.Fatalf("Update failed: updated=%v err=%v", updated, err)
+	}
+	if got, _, _ := ss.Get(later.ID); got.Note != "edited" || got.NetValue != 2000 {
+		t.Errorf("expected updated note, got %+v", got)
+	}
+	if updated, _ := ss.Update(models.NewSnapshot(time.Now(), "")); updated {
+		t.Error("expected update of unknown snapshot to return false")
+	}
+
+	removed, _ := ss.Remove(later.ID)
+	if !removed {
+		t.Error("expected snapshot to be removed")
+	}
+	snapshots, _ = ss.List()
+	if len(snapshots) != 1 {
+		t.Errorf("expected 1 snapshot left, got %d", len(snapshots))
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	src.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "", "", models.NewDate(2024, 1, 1)))
+	loan := models.NewLoan("USDT", dec(1000), "", nil, "", models.NewDate(2024, 1, 1))
+	src.AddLoan(loan)
+	src.AddRepayment(models.NewRepayment(loan.ID, dec(100), "", models.NewDate(2024, 2, 1)))
+	src.AddStake(models.NewStake("BTC", dec(0.5), "", nil, "", models.NewDate(2024, 1, 2)))
+
+	dst := setupTestSQLite(t)
+	if err := Copy(dst, src); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	holdings, _ := dst.GetHoldings()
+	loans, _ := dst.GetLoans()
+	repayments, _ := dst.GetRepayments()
+	stakes, _ := dst.GetStakes()
+	if len(holdings) != 1 || len(loans) != 1 || len(repayments) != 1 || len(stakes) != 1 {
+		t.Errorf("expected every record copied, got %d holdings, %d loans, %d repayments, %d stakes",
+			len(holdings), len(loans), len(repayments), len(stakes))
+	}
+}
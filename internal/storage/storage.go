@@ -2,23 +2,34 @@ package storage
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pretty-andrechal/follyo/internal/models"
 )
 
+// SchemaVersion is the version of the portfolio data file format.
+const SchemaVersion = 4
+
 // PortfolioData represents the structure of the JSON file.
 type PortfolioData struct {
-	Holdings []models.Holding `json:"holdings"`
-	Loans    []models.Loan    `json:"loans"`
-	Sales    []models.Sale    `json:"sales"`
-	Stakes   []models.Stake   `json:"stakes"`
+	Version    int                `json:"version"`
+	Holdings   []models.Holding   `json:"holdings"`
+	Loans      []models.Loan      `json:"loans"`
+	Repayments []models.Repayment `json:"repayments,omitempty"`
+	Sales      []models.Sale      `json:"sales"`
+	Stakes     []models.Stake     `json:"stakes"`
+	Swaps      []models.Swap      `json:"swaps,omitempty"`
+	Transfers  []models.Transfer  `json:"transfers,omitempty"`
+	Trash      []models.TrashItem `json:"trash,omitempty"`
 }
 
 // Storage handles persistence of portfolio data to JSON.
 type Storage struct {
 	dataPath string
+	version  fileVersion
 }
 
 // New creates a new Storage instance.
@@ -30,13 +41,27 @@ func New(dataPath string) (*Storage, error) {
 	return s, nil
 }
 
-// DefaultDataPath returns the default path for portfolio data.
-func DefaultDataPath() string {
-	exe, err := os.Executable()
+// DataDir returns the default data directory: $FOLLYO_DATA_DIR, or
+// "follyo" in the XDG data directory ($XDG_DATA_HOME, ~/.local/share by
+// default).
+func DataDir() string {
+	if dir := os.Getenv("FOLLYO_DATA_DIR"); dir != "" {
+		return dir
+	}
+	// The XDG spec says relative paths are invalid and should be ignored
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "follyo")
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return "data/portfolio.json"
+		return "data"
 	}
-	return filepath.Join(filepath.Dir(exe), "data", "portfolio.json")
+	return filepath.Join(home, ".local", "share", "follyo")
+}
+
+// DefaultDataPath returns the default path for portfolio data.
+func DefaultDataPath() string {
+	return filepath.Join(DataDir(), "portfolio.json")
 }
 
 func (s *Storage) ensureDataFile() error {
@@ -45,6 +70,12 @@ func (s *Storage) ensureDataFile() error {
 		return err
 	}
 
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	if _, err := os.Stat(s.dataPath); os.IsNotExist(err) {
 		data := PortfolioData{
 			Holdings: []models.Holding{},
@@ -64,17 +95,40 @@ func (s *Storage) loadData() (PortfolioData, error) {
 	if err != nil {
 		return data, err
 	}
+	s.version.seen(file)
+
+	file, err = migrate(file, SchemaVersion, portfolioMigrations)
+	if err != nil {
+		return data, err
+	}
 
-	err = json.Unmarshal(file, &data)
-	return data, err
+	if err := json.Unmarshal(file, &data); err != nil {
+		return data, fmt.Errorf("%s: %w; run 'follyo fsck' to repair it", s.dataPath, err)
+	}
+	return data, nil
 }
 
 func (s *Storage) saveData(data PortfolioData) error {
+	data.Version = SchemaVersion
 	file, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.dataPath, file, 0644)
+	if err := s.version.check(s.dataPath); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.dataPath, file, 0644); err != nil {
+		return err
+	}
+	s.version.seen(file)
+	return nil
+}
+
+// lock serializes read-modify-write cycles across processes, so concurrent
+// commands and the daemon don't lose each other's writes. Every change loads
+// the file after taking the lock and saves it before releasing it.
+func (s *Storage) lock() (*Lock, error) {
+	return AcquireLock(s.dataPath + ".lock")
 }
 
 // Holdings operations
@@ -90,6 +144,12 @@ func (s *Storage) GetHoldings() ([]models.Holding, error) {
 
 // AddHolding adds a new holding.
 func (s *Storage) AddHolding(holding models.Holding) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return err
@@ -98,26 +158,25 @@ func (s *Storage) AddHolding(holding models.Holding) error {
 	return s.saveData(data)
 }
 
-// RemoveHolding removes a holding by ID.
+// RemoveHolding moves a holding to the trash by ID.
 func (s *Storage) RemoveHolding(id string) (bool, error) {
-	data, err := s.loadData()
+	l, err := s.lock()
 	if err != nil {
 		return false, err
 	}
+	defer l.Release()
 
-	originalLen := len(data.Holdings)
-	filtered := make([]models.Holding, 0, len(data.Holdings))
-	for _, h := range data.Holdings {
-		if h.ID != id {
-			filtered = append(filtered, h)
-		}
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
 	}
-	data.Holdings = filtered
 
-	if len(data.Holdings) < originalLen {
-		return true, s.saveData(data)
+	item, found, err := moveToTrash(&data.Holdings, kindHolding, id, func(h models.Holding) string { return h.ID })
+	if err != nil || !found {
+		return false, err
 	}
-	return false, nil
+	data.Trash = append(data.Trash, item)
+	return true, s.saveData(data)
 }
 
 // Loans operations
@@ -133,6 +192,12 @@ func (s *Storage) GetLoans() ([]models.Loan, error) {
 
 // AddLoan adds a new loan.
 func (s *Storage) AddLoan(loan models.Loan) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return err
@@ -141,23 +206,88 @@ func (s *Storage) AddLoan(loan models.Loan) error {
 	return s.saveData(data)
 }
 
-// RemoveLoan removes a loan by ID.
+// RemoveLoan moves a loan to the trash by ID, along with its repayments.
 func (s *Storage) RemoveLoan(id string) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	item, found, err := moveToTrash(&data.Loans, kindLoan, id, func(l models.Loan) string { return l.ID })
+	if err != nil || !found {
+		return false, err
+	}
+
+	repayments := make([]models.Repayment, 0, len(data.Repayments))
+	for _, r := range data.Repayments {
+		if r.LoanID == id {
+			item.Repayments = append(item.Repayments, r)
+		} else {
+			repayments = append(repayments, r)
+		}
+	}
+	data.Repayments = repayments
+
+	data.Trash = append(data.Trash, item)
+	return true, s.saveData(data)
+}
+
+// Repayments operations
+
+// GetRepayments returns all loan repayments.
+func (s *Storage) GetRepayments() ([]models.Repayment, error) {
+	data, err := s.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Repayments, nil
+}
+
+// AddRepayment adds a new loan repayment.
+func (s *Storage) AddRepayment(repayment models.Repayment) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return err
+	}
+	data.Repayments = append(data.Repayments, repayment)
+	return s.saveData(data)
+}
+
+// RemoveRepayment removes a loan repayment by ID.
+func (s *Storage) RemoveRepayment(id string) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return false, err
 	}
 
-	originalLen := len(data.Loans)
-	filtered := make([]models.Loan, 0, len(data.Loans))
-	for _, l := range data.Loans {
-		if l.ID != id {
-			filtered = append(filtered, l)
+	originalLen := len(data.Repayments)
+	filtered := make([]models.Repayment, 0, len(data.Repayments))
+	for _, r := range data.Repayments {
+		if r.ID != id {
+			filtered = append(filtered, r)
 		}
 	}
-	data.Loans = filtered
+	data.Repayments = filtered
 
-	if len(data.Loans) < originalLen {
+	if len(data.Repayments) < originalLen {
 		return true, s.saveData(data)
 	}
 	return false, nil
@@ -176,6 +306,12 @@ func (s *Storage) GetSales() ([]models.Sale, error) {
 
 // AddSale adds a new sale.
 func (s *Storage) AddSale(sale models.Sale) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return err
@@ -184,26 +320,25 @@ func (s *Storage) AddSale(sale models.Sale) error {
 	return s.saveData(data)
 }
 
-// RemoveSale removes a sale by ID.
+// RemoveSale moves a sale to the trash by ID.
 func (s *Storage) RemoveSale(id string) (bool, error) {
-	data, err := s.loadData()
+	l, err := s.lock()
 	if err != nil {
 		return false, err
 	}
+	defer l.Release()
 
-	originalLen := len(data.Sales)
-	filtered := make([]models.Sale, 0, len(data.Sales))
-	for _, sl := range data.Sales {
-		if sl.ID != id {
-			filtered = append(filtered, sl)
-		}
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
 	}
-	data.Sales = filtered
 
-	if len(data.Sales) < originalLen {
-		return true, s.saveData(data)
+	item, found, err := moveToTrash(&data.Sales, kindSale, id, func(sl models.Sale) string { return sl.ID })
+	if err != nil || !found {
+		return false, err
 	}
-	return false, nil
+	data.Trash = append(data.Trash, item)
+	return true, s.saveData(data)
 }
 
 // Stakes operations
@@ -219,6 +354,12 @@ func (s *Storage) GetStakes() ([]models.Stake, error) {
 
 // AddStake adds a new stake.
 func (s *Storage) AddStake(stake models.Stake) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return err
@@ -230,24 +371,265 @@ func (s *Storage) AddStake(stake models.Stake) error {
 	return s.saveData(data)
 }
 
-// RemoveStake removes a stake by ID.
+// UpdateStake replaces the stake with the same ID.
+func (s *Storage) UpdateStake(stake models.Stake) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	for i, st := range data.Stakes {
+		if st.ID == stake.ID {
+			data.Stakes[i] = stake
+			return true, s.saveData(data)
+		}
+	}
+	return false, nil
+}
+
+// RemoveStake moves a stake to the trash by ID.
 func (s *Storage) RemoveStake(id string) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	item, found, err := moveToTrash(&data.Stakes, kindStake, id, func(st models.Stake) string { return st.ID })
+	if err != nil || !found {
+		return false, err
+	}
+	data.Trash = append(data.Trash, item)
+	return true, s.saveData(data)
+}
+
+// Swaps operations
+
+// GetSwaps returns all swaps.
+func (s *Storage) GetSwaps() ([]models.Swap, error) {
+	data, err := s.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Swaps, nil
+}
+
+// AddSwap adds a new swap.
+func (s *Storage) AddSwap(swap models.Swap) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return err
+	}
+	data.Swaps = append(data.Swaps, swap)
+	return s.saveData(data)
+}
+
+// RemoveSwap removes a swap by ID.
+func (s *Storage) RemoveSwap(id string) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
 	data, err := s.loadData()
 	if err != nil {
 		return false, err
 	}
 
-	originalLen := len(data.Stakes)
-	filtered := make([]models.Stake, 0, len(data.Stakes))
-	for _, st := range data.Stakes {
-		if st.ID != id {
-			filtered = append(filtered, st)
+	originalLen := len(data.Swaps)
+	filtered := make([]models.Swap, 0, len(data.Swaps))
+	for _, sw := range data.Swaps {
+		if sw.ID != id {
+			filtered = append(filtered, sw)
 		}
 	}
-	data.Stakes = filtered
+	data.Swaps = filtered
 
-	if len(data.Stakes) < originalLen {
+	if len(data.Swaps) < originalLen {
 		return true, s.saveData(data)
 	}
 	return false, nil
 }
+
+// Transfers operations
+
+// GetTransfers returns all transfers.
+func (s *Storage) GetTransfers() ([]models.Transfer, error) {
+	data, err := s.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Transfers, nil
+}
+
+// AddTransfer adds a new transfer.
+func (s *Storage) AddTransfer(transfer models.Transfer) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return err
+	}
+	data.Transfers = append(data.Transfers, transfer)
+	return s.saveData(data)
+}
+
+// RemoveTransfer removes a transfer by ID.
+func (s *Storage) RemoveTransfer(id string) (bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return false, err
+	}
+
+	originalLen := len(data.Transfers)
+	filtered := make([]models.Transfer, 0, len(data.Transfers))
+	for _, t := range data.Transfers {
+		if t.ID != id {
+			filtered = append(filtered, t)
+		}
+	}
+	data.Transfers = filtered
+
+	if len(data.Transfers) < originalLen {
+		return true, s.saveData(data)
+	}
+	return false, nil
+}
+
+// Platform operations
+
+// RenamePlatform sets every platform matching oldName, ignoring case, to newName
+// across holdings, sales, loans, stakes, swaps, and transfers, and returns
+// the number of records changed.
+func (s *Storage) RenamePlatform(oldName, newName string) (int, error) {
+	l, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	rename := func(platforms ...*string) {
+		matched := false
+		for _, platform := range platforms {
+			if strings.EqualFold(*platform, oldName) && *platform != newName {
+				*platform = newName
+				matched = true
+			}
+		}
+		if matched {
+			changed++
+		}
+	}
+	for i := range data.Holdings {
+		rename(&data.Holdings[i].Platform)
+	}
+	for i := range data.Sales {
+		rename(&data.Sales[i].Platform)
+	}
+	for i := range data.Loans {
+		rename(&data.Loans[i].Platform)
+	}
+	for i := range data.Stakes {
+		rename(&data.Stakes[i].Platform)
+	}
+	for i := range data.Swaps {
+		rename(&data.Swaps[i].Platform)
+	}
+	for i := range data.Transfers {
+		rename(&data.Transfers[i].FromPlatform, &data.Transfers[i].ToPlatform)
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, s.saveData(data)
+}
+
+// Coin operations
+
+// MigrateCoin moves every record of m.From to m.To, converting amounts and
+// prices by m.Ratio, and returns the number of records changed. Repayments
+// of loans in m.From are converted too.
+func (s *Storage) MigrateCoin(m CoinMigration) (int, error) {
+	l, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	count := func(ok bool) {
+		if ok {
+			changed++
+		}
+	}
+	loanIDs := make(map[string]bool)
+	for i := range data.Holdings {
+		count(m.holding(&data.Holdings[i]))
+	}
+	for i := range data.Sales {
+		count(m.sale(&data.Sales[i]))
+	}
+	for i := range data.Loans {
+		if m.loan(&data.Loans[i]) {
+			loanIDs[data.Loans[i].ID] = true
+			changed++
+		}
+	}
+	for i := range data.Repayments {
+		count(m.repayment(&data.Repayments[i], loanIDs))
+	}
+	for i := range data.Stakes {
+		count(m.stake(&data.Stakes[i]))
+	}
+	for i := range data.Swaps {
+		count(m.swap(&data.Swaps[i]))
+	}
+	for i := range data.Transfers {
+		count(m.transfer(&data.Transfers[i]))
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, s.saveData(data)
+}
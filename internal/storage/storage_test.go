@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/pretty-andrechal/follyo/internal/models"
+	"github.com/shopspring/decimal"
 )
 
 func setupTestStorage(t *testing.T) (*Storage, func()) {
@@ -53,7 +56,7 @@ func TestStorage_Holdings(t *testing.T) {
 	}
 
 	// Add a holding
-	h1 := models.NewHolding("BTC", 1.0, 50000, "Binance", "test", "2024-01-01")
+	h1 := models.NewHolding("BTC", dec(1.0), dec(50000), "Binance", "test", models.NewDate(2024, 1, 1))
 	err = s.AddHolding(h1)
 	if err != nil {
 		t.Fatalf("AddHolding failed: %v", err)
@@ -72,7 +75,7 @@ func TestStorage_Holdings(t *testing.T) {
 	}
 
 	// Add another holding
-	h2 := models.NewHolding("ETH", 10, 3000, "Ledger", "", "2024-01-02")
+	h2 := models.NewHolding("ETH", dec(10), dec(3000), "Ledger", "", models.NewDate(2024, 1, 2))
 	err = s.AddHolding(h2)
 	if err != nil {
 		t.Fatalf("AddHolding failed: %v", err)
@@ -131,7 +134,7 @@ func TestStorage_Loans(t *testing.T) {
 
 	// Add a loan
 	rate := 6.9
-	l1 := models.NewLoan("USDT", 5000, "Nexo", &rate, "credit line", "2024-01-01")
+	l1 := models.NewLoan("USDT", dec(5000), "Nexo", &rate, "credit line", models.NewDate(2024, 1, 1))
 	err = s.AddLoan(l1)
 	if err != nil {
 		t.Fatalf("AddLoan failed: %v", err)
@@ -153,7 +156,7 @@ func TestStorage_Loans(t *testing.T) {
 	}
 
 	// Add another loan
-	l2 := models.NewLoan("BTC", 0.5, "Celsius", nil, "", "2024-01-02")
+	l2 := models.NewLoan("BTC", dec(0.5), "Celsius", nil, "", models.NewDate(2024, 1, 2))
 	err = s.AddLoan(l2)
 	if err != nil {
 		t.Fatalf("AddLoan failed: %v", err)
@@ -194,6 +197,45 @@ func TestStorage_Loans(t *testing.T) {
 	}
 }
 
+func TestStorage_Repayments(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	l := models.NewLoan("USDT", dec(5000), "Nexo", nil, "", models.NewDate(2024, 1, 1))
+	s.AddLoan(l)
+
+	r1 := models.NewRepayment(l.ID, dec(1000), "", models.NewDate(2024, 2, 1))
+	if err := s.AddRepayment(r1); err != nil {
+		t.Fatalf("AddRepayment failed: %v", err)
+	}
+	if err := s.AddRepayment(models.NewRepayment(l.ID, dec(500), "", models.NewDate(2024, 3, 1))); err != nil {
+		t.Fatalf("AddRepayment failed: %v", err)
+	}
+
+	repayments, err := s.GetRepayments()
+	if err != nil {
+		t.Fatalf("GetRepayments failed: %v", err)
+	}
+	if len(repayments) != 2 {
+		t.Fatalf("expected 2 repayments, got %d", len(repayments))
+	}
+
+	removed, err := s.RemoveRepayment(r1.ID)
+	if err != nil {
+		t.Fatalf("RemoveRepayment failed: %v", err)
+	}
+	if !removed {
+		t.Error("expected repayment to be removed")
+	}
+
+	// Removing the loan removes its remaining repayments
+	s.RemoveLoan(l.ID)
+	repayments, _ = s.GetRepayments()
+	if len(repayments) != 0 {
+		t.Errorf("expected 0 repayments after removing loan, got %d", len(repayments))
+	}
+}
+
 func TestStorage_Sales(t *testing.T) {
 	s, cleanup := setupTestStorage(t)
 	defer cleanup()
@@ -208,7 +250,7 @@ func TestStorage_Sales(t *testing.T) {
 	}
 
 	// Add a sale
-	s1 := models.NewSale("BTC", 0.5, 55000, "Binance", "profit taking", "2024-01-01")
+	s1 := models.NewSale("BTC", dec(0.5), dec(55000), "Binance", "profit taking", models.NewDate(2024, 1, 1))
 	err = s.AddSale(s1)
 	if err != nil {
 		t.Fatalf("AddSale failed: %v", err)
@@ -227,7 +269,7 @@ func TestStorage_Sales(t *testing.T) {
 	}
 
 	// Add another sale
-	s2 := models.NewSale("ETH", 5, 3500, "Kraken", "", "2024-01-02")
+	s2 := models.NewSale("ETH", dec(5), dec(3500), "Kraken", "", models.NewDate(2024, 1, 2))
 	err = s.AddSale(s2)
 	if err != nil {
 		t.Fatalf("AddSale failed: %v", err)
@@ -283,7 +325,7 @@ func TestStorage_Stakes(t *testing.T) {
 
 	// Add a stake
 	apy := 4.5
-	st1 := models.NewStake("ETH", 10, "Lido", &apy, "staking rewards", "2024-03-01")
+	st1 := models.NewStake("ETH", dec(10), "Lido", &apy, "staking rewards", models.NewDate(2024, 3, 1))
 	err = s.AddStake(st1)
 	if err != nil {
 		t.Fatalf("AddStake failed: %v", err)
@@ -305,7 +347,7 @@ func TestStorage_Stakes(t *testing.T) {
 	}
 
 	// Add another stake
-	st2 := models.NewStake("SOL", 100, "Coinbase", nil, "", "2024-03-02")
+	st2 := models.NewStake("SOL", dec(100), "Coinbase", nil, "", models.NewDate(2024, 3, 2))
 	err = s.AddStake(st2)
 	if err != nil {
 		t.Fatalf("AddStake failed: %v", err)
@@ -346,9 +388,272 @@ func TestStorage_Stakes(t *testing.T) {
 	}
 }
 
+func TestStorage_UpdateStake(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	st := models.NewStake("ETH", dec(10), "Lido", nil, "", models.NewDate(2024, 3, 1))
+	if err := s.AddStake(st); err != nil {
+		t.Fatalf("AddStake failed: %v", err)
+	}
+
+	st.Amount = dec(4)
+	updated, err := s.UpdateStake(st)
+	if err != nil {
+		t.Fatalf("UpdateStake failed: %v", err)
+	}
+	if !updated {
+		t.Error("expected stake to be updated")
+	}
+
+	stakes, _ := s.GetStakes()
+	if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(4)) {
+		t.Errorf("expected updated amount 4, got %+v", stakes)
+	}
+
+	updated, err = s.UpdateStake(models.NewStake("SOL", dec(1), "", nil, "", models.Date{}))
+	if err != nil {
+		t.Fatalf("UpdateStake failed: %v", err)
+	}
+	if updated {
+		t.Error("expected unknown stake not to be updated")
+	}
+}
+
+func TestStorage_Swaps(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	sw := models.NewSwap("ETH", dec(2), "SOL", dec(50), dec(6000), "Binance", "", models.NewDate(2024, 3, 1))
+	if err := s.AddSwap(sw); err != nil {
+		t.Fatalf("AddSwap failed: %v", err)
+	}
+
+	swaps, err := s.GetSwaps()
+	if err != nil {
+		t.Fatalf("GetSwaps failed: %v", err)
+	}
+	if len(swaps) != 1 || swaps[0].ToCoin != "SOL" {
+		t.Fatalf("expected 1 swap to SOL, got %+v", swaps)
+	}
+
+	removed, err := s.RemoveSwap(sw.ID)
+	if err != nil {
+		t.Fatalf("RemoveSwap failed: %v", err)
+	}
+	if !removed {
+		t.Error("expected swap to be removed")
+	}
+}
+
+func TestStorage_Transfers(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	tr := models.NewTransfer("BTC", dec(0.5), "Binance", "Ledger", dec(0.0001), "", models.NewDate(2024, 3, 1))
+	if err := s.AddTransfer(tr); err != nil {
+		t.Fatalf("AddTransfer failed: %v", err)
+	}
+
+	transfers, err := s.GetTransfers()
+	if err != nil {
+		t.Fatalf("GetTransfers failed: %v", err)
+	}
+	if len(transfers) != 1 || transfers[0].ToPlatform != "Ledger" {
+		t.Fatalf("expected 1 transfer to Ledger, got %+v", transfers)
+	}
+
+	removed, err := s.RemoveTransfer(tr.ID)
+	if err != nil {
+		t.Fatalf("RemoveTransfer failed: %v", err)
+	}
+	if !removed {
+		t.Error("expected transfer to be removed")
+	}
+
+	removed, _ = s.RemoveTransfer("nonexistent")
+	if removed {
+		t.Error("expected transfer not to be removed")
+	}
+}
+
+func TestStorage_RenamePlatform(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	testRenamePlatform(t, s)
+}
+
+// testRenamePlatform checks that a backend renames platforms in every kind
+// of record, ignoring case, and counts each changed record once
+func testRenamePlatform(t *testing.T, s Backend) {
+	t.Helper()
+
+	date := models.NewDate(2024, 3, 1)
+	s.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "coinbase", "", date))
+	s.AddHolding(models.NewHolding("ETH", dec(1), dec(2000), "Kraken", "", date))
+	s.AddSale(models.NewSale("BTC", dec(0.1), dec(60000), "CB", "", date))
+	s.AddLoan(models.NewLoan("USDT", dec(500), "COINBASE", nil, "", date))
+	s.AddStake(models.NewStake("ETH", dec(1), "Coinbase", nil, "", date))
+	s.AddSwap(models.NewSwap("ETH", dec(1), "SOL", dec(20), dec(2000), "coinbase", "", date))
+	s.AddTransfer(models.NewTransfer("BTC", dec(0.5), "coinbase", "Coinbase", dec(0), "", date))
+
+	changed, err := s.RenamePlatform("coinbase", "Coinbase")
+	if err != nil {
+		t.Fatalf("RenamePlatform failed: %v", err)
+	}
+	// The stake is already spelled Coinbase
+	if changed != 4 {
+		t.Errorf("expected 4 records changed, got %d", changed)
+	}
+	if changed, _ := s.RenamePlatform("CB", "Coinbase"); changed != 1 {
+		t.Errorf("expected 1 record changed, got %d", changed)
+	}
+	if changed, _ := s.RenamePlatform("Nowhere", "Coinbase"); changed != 0 {
+		t.Errorf("expected nothing changed, got %d", changed)
+	}
+
+	holdings, _ := s.GetHoldings()
+	sales, _ := s.GetSales()
+	loans, _ := s.GetLoans()
+	swaps, _ := s.GetSwaps()
+	transfers, _ := s.GetTransfers()
+	if holdings[0].Platform != "Coinbase" || holdings[1].Platform != "Kraken" {
+		t.Errorf("expected holdings on Coinbase and Kraken, got %+v", holdings)
+	}
+	if sales[0].Platform != "Coinbase" || loans[0].Platform != "Coinbase" || swaps[0].Platform != "Coinbase" {
+		t.Errorf("expected sale, loan, and swap on Coinbase, got %+v, %+v, %+v", sales, loans, swaps)
+	}
+	if transfers[0].FromPlatform != "Coinbase" || transfers[0].ToPlatform != "Coinbase" {
+		t.Errorf("expected transfer from and to Coinbase, got %+v", transfers)
+	}
+	if !holdings[0].Amount.Equal(dec(1)) || !holdings[0].PurchasePriceUSD.Equal(dec(50000)) {
+		t.Errorf("expected other fields kept, got %+v", holdings[0])
+	}
+}
+
+func TestStorage_MigrateCoin(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	testMigrateCoin(t, s)
+}
+
+// testMigrateCoin checks that a backend converts every kind of record of
+// the old coin, including repayments of its loans, and leaves others alone
+func testMigrateCoin(t *testing.T, s Backend) {
+	t.Helper()
+
+	date := models.NewDate(2024, 3, 1)
+	s.AddHolding(models.NewHolding("MATIC", dec(1000), dec(0.9), "Binance", "", date))
+	s.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "Binance", "", date))
+	s.AddSale(models.NewSale("MATIC", dec(100), dec(1.2), "Binance", "", date))
+	loan := models.NewLoan("MATIC", dec(200), "Nexo", nil, "", date)
+	s.AddLoan(loan)
+	s.AddRepayment(models.NewRepayment(loan.ID, dec(50), "", date))
+	s.AddStake(models.NewStake("MATIC", dec(300), "Lido", nil, "", date))
+	s.AddSwap(models.NewSwap("ETH", dec(1), "MATIC", dec(2000), dec(2000), "Binance", "", date))
+	s.AddTransfer(models.NewTransfer("MATIC", dec(400), "Binance", "Ledger", dec(1), "", date))
+
+	changed, err := s.MigrateCoin(CoinMigration{From: "MATIC", To: "POL", Ratio: dec(2)})
+	if err != nil {
+		t.Fatalf("MigrateCoin failed: %v", err)
+	}
+	if changed != 7 {
+		t.Errorf("expected 7 records changed, got %d", changed)
+	}
+
+	holdings, _ := s.GetHoldings()
+	if h := holdings[0]; h.Coin != "POL" || !h.Amount.Equal(dec(2000)) || !h.PurchasePriceUSD.Equal(dec(0.45)) {
+		t.Errorf("expected 2000 POL @ 0.45, got %+v", h)
+	}
+	if holdings[1].Coin != "BTC" || !holdings[1].Amount.Equal(dec(1)) {
+		t.Errorf("expected BTC untouched, got %+v", holdings[1])
+	}
+	sales, _ := s.GetSales()
+	if sale := sales[0]; sale.Coin != "POL" || !sale.Amount.Equal(dec(200)) || !sale.SellPriceUSD.Equal(dec(0.6)) {
+		t.Errorf("expected 200 POL sold @ 0.6, got %+v", sale)
+	}
+	loans, _ := s.GetLoans()
+	repayments, _ := s.GetRepayments()
+	if loans[0].Coin != "POL" || !loans[0].Amount.Equal(dec(400)) || !repayments[0].Amount.Equal(dec(100)) {
+		t.Errorf("expected a 400 POL loan with 100 repaid, got %+v, %+v", loans, repayments)
+	}
+	stakes, _ := s.GetStakes()
+	if stakes[0].Coin != "POL" || !stakes[0].Amount.Equal(dec(600)) {
+		t.Errorf("expected 600 POL staked, got %+v", stakes)
+	}
+	swaps, _ := s.GetSwaps()
+	if sw := swaps[0]; sw.FromCoin != "ETH" || sw.ToCoin != "POL" || !sw.ToAmount.Equal(dec(4000)) || !sw.ValueUSD.Equal(dec(2000)) {
+		t.Errorf("expected ETH swapped for 4000 POL worth $2000, got %+v", sw)
+	}
+	transfers, _ := s.GetTransfers()
+	if tr := transfers[0]; tr.Coin != "POL" || !tr.Amount.Equal(dec(800)) || !tr.Fee.Equal(dec(2)) {
+		t.Errorf("expected 800 POL transferred with a fee of 2, got %+v", tr)
+	}
+
+	if changed, _ := s.MigrateCoin(CoinMigration{From: "MATIC", To: "POL", Ratio: dec(2)}); changed != 0 {
+		t.Errorf("expected nothing left to migrate, got %d", changed)
+	}
+}
+
+func TestStorage_NormalizesAmounts(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// Written by a version that did float arithmetic on amounts
+	data := `{"holdings":[{"id":"h1","coin":"BTC","amount":0.30000000000000004,"purchase_price_usd":50000,"date":"2024-01-01"}],` +
+		`"loans":[],"sales":[],"stakes":[{"id":"s1","coin":"BTC","amount":0.19999999999999998,"date":"2024-01-01"}]}`
+	if err := os.WriteFile(s.dataPath, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+
+	holdings, err := s.GetHoldings()
+	if err != nil {
+		t.Fatalf("GetHoldings failed: %v", err)
+	}
+	if !holdings[0].Amount.Equal(dec(0.3)) {
+		t.Errorf("expected amount 0.3, got %v", holdings[0].Amount)
+	}
+	stakes, _ := s.GetStakes()
+	if !stakes[0].Amount.Equal(dec(0.2)) {
+		t.Errorf("expected stake amount 0.2, got %v", stakes[0].Amount)
+	}
+}
+
 func TestDefaultDataPath(t *testing.T) {
-	path := DefaultDataPath()
-	if path == "" {
-		t.Error("expected non-empty default data path")
+	t.Setenv("FOLLYO_DATA_DIR", "")
+	t.Setenv("HOME", "/home/alice")
+	t.Setenv("XDG_DATA_HOME", "")
+	if got, want := DefaultDataPath(), filepath.Join("/home/alice", ".local", "share", "follyo", "portfolio.json"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "relative/is/ignored")
+	if got, want := DataDir(), filepath.Join("/home/alice", ".local", "share", "follyo"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
 	}
+
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	if got, want := DataDir(), filepath.Join("/xdg/data", "follyo"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	t.Setenv("FOLLYO_DATA_DIR", "/srv/follyo")
+	if got := DataDir(); got != "/srv/follyo" {
+		t.Errorf("expected FOLLYO_DATA_DIR to win, got %s", got)
+	}
+}
+
+// dec returns v as a decimal, for writing record amounts as literals
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+// sameJSON reports whether a and b encode to the same JSON, which compares
+// records by their decimal values rather than their representation
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
 }
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// moveToTrash removes the record with the given ID from records and returns
+// it wrapped as a trash item. found is false if no record has the ID.
+func moveToTrash[T any](records *[]T, kind, id string, idOf func(T) string) (item models.TrashItem, found bool, err error) {
+	for i, r := range *records {
+		if idOf(r) != id {
+			continue
+		}
+		item, err = models.NewTrashItem(kind, id, r)
+		if err != nil {
+			return models.TrashItem{}, false, err
+		}
+		*records = append((*records)[:i], (*records)[i+1:]...)
+		return item, true, nil
+	}
+	return models.TrashItem{}, false, nil
+}
+
+// restoreInto decodes a trashed record and appends it to records.
+func restoreInto[T any](records *[]T, raw json.RawMessage) error {
+	var record T
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return err
+	}
+	*records = append(*records, record)
+	return nil
+}
+
+// Trash operations
+
+// GetTrash returns all trashed records, oldest removal first.
+func (s *Storage) GetTrash() ([]models.TrashItem, error) {
+	data, err := s.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Trash, nil
+}
+
+// RestoreTrash moves a trashed record back by ID. It returns false if the
+// trash has no record with the ID.
+func (s *Storage) RestoreTrash(id string) (models.TrashItem, bool, error) {
+	l, err := s.lock()
+	if err != nil {
+		return models.TrashItem{}, false, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return models.TrashItem{}, false, err
+	}
+
+	for i, item := range data.Trash {
+		if item.ID != id {
+			continue
+		}
+
+		switch item.Type {
+		case kindHolding:
+			err = restoreInto(&data.Holdings, item.Record)
+		case kindLoan:
+			err = restoreInto(&data.Loans, item.Record)
+			data.Repayments = append(data.Repayments, item.Repayments...)
+		case kindSale:
+			err = restoreInto(&data.Sales, item.Record)
+		case kindStake:
+			err = restoreInto(&data.Stakes, item.Record)
+		default:
+			err = fmt.Errorf("cannot restore record of type %q", item.Type)
+		}
+		if err != nil {
+			return models.TrashItem{}, false, err
+		}
+
+		data.Trash = append(data.Trash[:i], data.Trash[i+1:]...)
+		return item, true, s.saveData(data)
+	}
+	return models.TrashItem{}, false, nil
+}
+
+// PurgeTrash permanently deletes records trashed before the given time and
+// returns how many were deleted.
+func (s *Storage) PurgeTrash(before time.Time) (int, error) {
+	l, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer l.Release()
+
+	data, err := s.loadData()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make([]models.TrashItem, 0, len(data.Trash))
+	for _, item := range data.Trash {
+		if !item.DeletedAt.Before(before) {
+			kept = append(kept, item)
+		}
+	}
+	purged := len(data.Trash) - len(kept)
+	if purged == 0 {
+		return 0, nil
+	}
+	data.Trash = kept
+	return purged, s.saveData(data)
+}
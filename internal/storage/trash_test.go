@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+// testBackends returns a fresh JSON and SQLite backend for tests that must
+// behave the same on both.
+func testBackends(t *testing.T) map[string]Backend {
+	t.Helper()
+	s, cleanup := setupTestStorage(t)
+	t.Cleanup(cleanup)
+	return map[string]Backend{"json": s, "sqlite": setupTestSQLite(t)}
+}
+
+func TestTrash_RemoveAndRestore(t *testing.T) {
+	for name, b := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			h := models.NewHolding("BTC", dec(1.5), dec(50000), "", "", models.NewDate(2024, 1, 1))
+			sale := models.NewSale("BTC", dec(0.5), dec(60000), "", "", models.NewDate(2024, 2, 1))
+			st := models.NewStake("BTC", dec(0.25), "Lido", nil, "", models.NewDate(2024, 1, 2))
+			b.AddHolding(h)
+			b.AddSale(sale)
+			b.AddStake(st)
+
+			b.RemoveHolding(h.ID)
+			b.RemoveSale(sale.ID)
+			b.RemoveStake(st.ID)
+
+			holdings, _ := b.GetHoldings()
+			sales, _ := b.GetSales()
+			stakes, _ := b.GetStakes()
+			if len(holdings) != 0 || len(sales) != 0 || len(stakes) != 0 {
+				t.Fatalf("expected records removed, got %d holdings, %d sales, %d stakes", len(holdings), len(sales), len(stakes))
+			}
+
+			trash, err := b.GetTrash()
+			if err != nil {
+				t.Fatalf("GetTrash failed: %v", err)
+			}
+			if len(trash) != 3 {
+				t.Fatalf("expected 3 trash items, got %d", len(trash))
+			}
+			if trash[0].ID != h.ID || trash[0].Type != "holding" || trash[0].Coin != "BTC" || !trash[0].Amount.Equal(dec(1.5)) {
+				t.Errorf("expected holding trash item, got %+v", trash[0])
+			}
+
+			for _, id := range []string{h.ID, sale.ID, st.ID} {
+				if _, restored, err := b.RestoreTrash(id); err != nil || !restored {
+					t.Fatalf("expected %s restored, got %v, %v", id, restored, err)
+				}
+			}
+
+			holdings, _ = b.GetHoldings()
+			sales, _ = b.GetSales()
+			stakes, _ = b.GetStakes()
+			if len(holdings) != 1 || !sameJSON(holdings[0], h) {
+				t.Errorf("expected holding restored unchanged, got %+v", holdings)
+			}
+			if len(sales) != 1 || !sameJSON(sales[0], sale) {
+				t.Errorf("expected sale restored unchanged, got %+v", sales)
+			}
+			if len(stakes) != 1 || !stakes[0].Amount.Equal(dec(0.25)) {
+				t.Errorf("expected stake restored, got %+v", stakes)
+			}
+
+			trash, _ = b.GetTrash()
+			if len(trash) != 0 {
+				t.Errorf("expected empty trash, got %d items", len(trash))
+			}
+			if _, restored, _ := b.RestoreTrash(h.ID); restored {
+				t.Error("expected nothing to restore")
+			}
+		})
+	}
+}
+
+func TestTrash_LoanWithRepayments(t *testing.T) {
+	for name, b := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			loan := models.NewLoan("USDT", dec(5000), "Nexo", nil, "", models.NewDate(2024, 1, 1))
+			b.AddLoan(loan)
+			b.AddRepayment(models.NewRepayment(loan.ID, dec(1000), "", models.NewDate(2024, 2, 1)))
+
+			b.RemoveLoan(loan.ID)
+			repayments, _ := b.GetRepayments()
+			if len(repayments) != 0 {
+				t.Fatalf("expected repayments removed with loan, got %d", len(repayments))
+			}
+
+			item, restored, err := b.RestoreTrash(loan.ID)
+			if err != nil || !restored {
+				t.Fatalf("expected loan restored, got %v, %v", restored, err)
+			}
+			if len(item.Repayments) != 1 {
+				t.Errorf("expected 1 repayment in trash item, got %d", len(item.Repayments))
+			}
+
+			loans, _ := b.GetLoans()
+			repayments, _ = b.GetRepayments()
+			if len(loans) != 1 || len(repayments) != 1 || repayments[0].LoanID != loan.ID {
+				t.Errorf("expected loan and repayment restored, got %+v, %+v", loans, repayments)
+			}
+		})
+	}
+}
+
+func TestTrash_Purge(t *testing.T) {
+	for name, b := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			h := models.NewHolding("BTC", dec(1), dec(50000), "", "", models.Date{})
+			b.AddHolding(h)
+			b.RemoveHolding(h.ID)
+
+			purged, err := b.PurgeTrash(time.Now().Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("PurgeTrash failed: %v", err)
+			}
+			if purged != 0 {
+				t.Errorf("expected recent items kept, purged %d", purged)
+			}
+
+			purged, _ = b.PurgeTrash(time.Now().Add(time.Hour))
+			if purged != 1 {
+				t.Errorf("expected 1 item purged, got %d", purged)
+			}
+			trash, _ := b.GetTrash()
+			if len(trash) != 0 {
+				t.Errorf("expected empty trash, got %d items", len(trash))
+			}
+		})
+	}
+}
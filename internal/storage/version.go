@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+)
+
+// ErrConflict is returned when saving a data file that was changed since it
+// was last read by something that doesn't take the storage locks, such as an
+// editor or a file sync client. Reload and retry the change.
+var ErrConflict = errors.New("data file was changed by another process")
+
+// fileVersion remembers the contents last read from or written to a data
+// file, so a save can detect that the file changed in between. Follyo's own
+// processes can't cause this, since they load and save under the same lock.
+type fileVersion struct {
+	sum   [sha256.Size]byte
+	known bool
+}
+
+// seen records data as the current contents of the file.
+func (v *fileVersion) seen(data []byte) {
+	v.sum = sha256.Sum256(data)
+	v.known = true
+}
+
+// check returns ErrConflict if the file at path no longer matches the
+// contents last seen.
+func (v *fileVersion) check(path string) error {
+	if !v.known {
+		return nil
+	}
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(current); !bytes.Equal(sum[:], v.sum[:]) {
+		return ErrConflict
+	}
+	return nil
+}
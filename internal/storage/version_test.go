@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pretty-andrechal/follyo/internal/models"
+)
+
+func TestStorage_SaveDetectsConflict(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	data, err := s.loadData()
+	if err != nil {
+		t.Fatalf("loadData failed: %v", err)
+	}
+
+	// Another process writes between this load and save
+	other := &Storage{dataPath: s.dataPath}
+	if err := other.AddHolding(models.NewHolding("ETH", dec(1), dec(3000), "", "", models.Date{})); err != nil {
+		t.Fatalf("AddHolding failed: %v", err)
+	}
+
+	data.Holdings = append(data.Holdings, models.NewHolding("BTC", dec(1), dec(50000), "", "", models.Date{}))
+	if err := s.saveData(data); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	// The other process's write is kept, and a fresh read-modify-write succeeds
+	if err := s.AddHolding(models.NewHolding("BTC", dec(1), dec(50000), "", "", models.Date{})); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	holdings, _ := s.GetHoldings()
+	if len(holdings) != 2 {
+		t.Errorf("expected 2 holdings, got %d", len(holdings))
+	}
+}
+
+func TestSnapshotStore_SaveDetectsConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	ss, _ := NewSnapshotStore(path)
+	ss.Add(models.NewSnapshot(time.Time{}, ""))
+
+	data, _ := ss.loadData()
+	if err := os.WriteFile(path, []byte(`{"snapshots":[]}`), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := ss.saveData(data); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+}